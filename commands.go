@@ -0,0 +1,169 @@
+package main
+
+import (
+	"basement/main/internal/database"
+	"basement/main/internal/env"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// runCommand executes a command-line subcommand and returns the exit code.
+//
+//	basement migrate                apply all pending migrations
+//	basement migrate -dry-run       run pending migrations and roll them back
+//	basement migrate -status        print current schema version and pending migrations
+//	basement backup                 write a snapshot into the backup directory
+//	basement backup -list           print all snapshots in the backup directory
+//	basement backup -restore F      validate snapshot F and replace the database with it
+//	basement blobs -gc              remove picture files that nothing refers to
+//	basement blobs -strip-metadata  remove GPS coordinates and other metadata from stored pictures
+func runCommand(db *database.DB, args []string) int {
+	switch args[0] {
+	case "migrate":
+		return migrateCommand(db, args[1:])
+	case "backup":
+		return backupCommand(db, args[1:])
+	case "blobs":
+		return blobsCommand(db, args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command \"%s\"\n", args[0])
+		return 2
+	}
+}
+
+func migrateCommand(db *database.DB, args []string) int {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "run pending migrations inside a transaction and roll back")
+	status := fs.Bool("status", false, "print current schema version and pending migrations")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	db.Open()
+	defer db.Sql.Close()
+
+	if *status {
+		s, err := db.MigrationStatus()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Println(s)
+		for _, m := range s.Applied {
+			fmt.Printf("  applied %3d %s (%s)\n", m.Version, m.Name, m.AppliedAt)
+		}
+		for _, m := range s.Pending {
+			fmt.Printf("  pending %3d %s\n", m.Version, m.Name)
+		}
+		return 0
+	}
+
+	applied, err := db.Migrate(*dryRun)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	verb := "applied"
+	if *dryRun {
+		verb = "would apply"
+	}
+	if len(applied) == 0 {
+		fmt.Println("database is up to date")
+	}
+	for _, m := range applied {
+		fmt.Printf("%s %3d %s\n", verb, m.Version, m.Name)
+	}
+	return 0
+}
+
+func backupCommand(db *database.DB, args []string) int {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	list := fs.Bool("list", false, "print all snapshots in the backup directory")
+	restore := fs.String("restore", "", "snapshot name in the backup directory or path to a snapshot file")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	db.Open()
+	defer db.Sql.Close()
+
+	if *list {
+		snapshots, err := db.Backups()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if len(snapshots) == 0 {
+			fmt.Println("no backups in " + env.CurrentConfig().BackupPath())
+		}
+		for _, s := range snapshots {
+			fmt.Printf("%s %10d bytes  %s\n", s.Created.Local().Format("2006-01-02 15:04:05"), s.Size, s.Name)
+		}
+		return 0
+	}
+
+	if *restore != "" {
+		var err error
+		if strings.ContainsRune(*restore, filepath.Separator) {
+			err = db.RestoreBackupFile(*restore)
+		} else {
+			err = db.RestoreBackup(*restore)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Println("restored " + *restore)
+		return 0
+	}
+
+	s, err := db.Backup()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Println("wrote " + filepath.Join(env.CurrentConfig().BackupPath(), s.Name))
+	return 0
+}
+
+func blobsCommand(db *database.DB, args []string) int {
+	fs := flag.NewFlagSet("blobs", flag.ContinueOnError)
+	gc := fs.Bool("gc", false, "remove picture files that neither the database nor a snapshot refers to")
+	strip := fs.Bool("strip-metadata", false, "remove EXIF, GPS and other metadata from stored pictures, before -gc")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if !*gc && !*strip {
+		fs.Usage()
+		return 2
+	}
+
+	db.Open()
+	defer db.Sql.Close()
+
+	if *strip {
+		stripped, err := db.StripPictureMetadata()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Printf("removed the metadata of %d pictures\n", stripped)
+		if stripped > 0 {
+			fmt.Println("snapshots keep the original pictures until they are rotated")
+		}
+	}
+	if !*gc {
+		return 0
+	}
+
+	removed, err := db.CollectBlobGarbage()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("removed %d unreferenced blobs from %s\n", removed, env.CurrentConfig().BlobPath())
+	return 0
+}
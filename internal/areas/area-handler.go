@@ -46,13 +46,13 @@ func AreaHandler(db AreaDatabase) http.HandlerFunc {
 func createArea(w http.ResponseWriter, r *http.Request, db AreaDatabase) {
 	area := NewArea()
 	logg.Debug("create area: ", area)
-	id, err := db.CreateArea(area)
+	id, err := db.CreateArea(r.Context(), area)
 	if err != nil {
 		server.WriteNotFoundError("error while creating the area", err, w, r)
 		return
 	}
 	if server.WantsTemplateData(r) {
-		area, err := db.AreaListRowByID(id)
+		area, err := db.AreaListRowByID(r.Context(), id)
 		logg.Debug(area)
 		if err != nil {
 			server.WriteNotFoundError("error while fetching the area based on Id", err, w, r)
@@ -74,7 +74,7 @@ func updateArea(w http.ResponseWriter, r *http.Request, db AreaDatabase) {
 	if err != nil {
 		if err == validator.Err() {
 			logg.Warning("validation error while updating the Area: %v", validator.Messages.Map())
-			templates.Render(w, "area-details", validator.AreaFormData(true))
+			templates.Render(w, "area-details", common.WithCustomFieldInputs(validator.AreaFormData(true)))
 		} else {
 			logg.Debugf("error happened while updating the Area: %v", err)
 			server.TriggerSingleErrorNotification(w, "Error while updating the Area, please come back later")
@@ -91,7 +91,7 @@ func updateArea(w http.ResponseWriter, r *http.Request, db AreaDatabase) {
 		}
 	}
 
-	err = db.UpdateArea(area, ignorePicture, pictureFormat)
+	err = db.UpdateArea(r.Context(), area, ignorePicture, pictureFormat)
 	logg.Debugf("this is the area: %v", area.Map())
 	if err != nil {
 		server.WriteNotFoundError(errMsgForUser+" "+logg.CleanLastError(err), err, w, r)
@@ -99,7 +99,7 @@ func updateArea(w http.ResponseWriter, r *http.Request, db AreaDatabase) {
 	}
 
 	// @TODO: Find a better solution. Picture is not included in request if ignorePicture is true and will be missing in response.
-	area, err = db.AreaById(area.ID)
+	area, err = db.AreaById(r.Context(), area.ID)
 	if err != nil {
 		server.WriteNotFoundError("no area found with id: "+area.ID.String(), err, w, r)
 		return
@@ -124,7 +124,7 @@ func deleteArea(w http.ResponseWriter, r *http.Request, db AreaDatabase) {
 	if id.IsNil() {
 		return
 	}
-	err := db.DeleteArea(id)
+	err := db.DeleteArea(r.Context(), id)
 	if err != nil {
 		server.WriteNotFoundError(errMsgForUser, err, w, r)
 		return
@@ -170,6 +170,12 @@ func createPage(db AreaDatabase) http.HandlerFunc {
 
 		data := NewAreaDetailsPageData()
 		area := NewArea()
+		var err error
+		area.CustomFields, err = common.EmptyCustomFieldValues(r.Context(), "area")
+		if err != nil {
+			server.WriteInternalServerError("can't load the custom fields of areas please comeback later", err, w, r)
+			return
+		}
 		data.Area = area
 
 		data.Title = "area - " + area.Label
@@ -191,7 +197,7 @@ func createAreaWithID(w http.ResponseWriter, r *http.Request, db AreaDatabase, i
 	if err != nil {
 		if err == validator.Err() {
 			logg.Warning("validation error while creating the Area: %v", validator.Messages.Map())
-			templates.Render(w, "area-details", validator.AreaFormData(false))
+			templates.Render(w, "area-details", common.WithCustomFieldInputs(validator.AreaFormData(false)))
 		} else {
 			logg.Debugf("error happened while creating the Area: %v", err)
 			server.TriggerSingleErrorNotification(w, "Error while creating the Area, please come back later")
@@ -200,7 +206,7 @@ func createAreaWithID(w http.ResponseWriter, r *http.Request, db AreaDatabase, i
 	}
 
 	logg.Debug("create area: ", area)
-	id, err = db.CreateArea(area)
+	id, err = db.CreateArea(r.Context(), area)
 	if err != nil {
 		server.WriteNotFoundError("error while creating the area", err, w, r)
 		return
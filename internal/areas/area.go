@@ -2,27 +2,29 @@ package areas
 
 import (
 	"basement/main/internal/common"
+	"basement/main/internal/logg"
 	"basement/main/internal/server"
 	"basement/main/internal/validate"
+	"context"
 	"net/http"
 
 	"github.com/gofrs/uuid/v5"
 )
 
 type AreaDatabase interface {
-	CreateArea(newArea Area) (uuid.UUID, error)
-	UpdateArea(area Area, ignorePicture bool, pictureFormat string) error
-	DeleteArea(id uuid.UUID) error
-	AreaById(id uuid.UUID) (Area, error)
-	AreaIDs() ([]uuid.UUID, error)
-	AreaListRows(query string, limit int, page int) ([]common.ListRow, error)
-	AreaListRowByID(id uuid.UUID) (common.ListRow, error)
-	AreaListCounter(searchString string) (count int, err error)
-	BoxListCounter(searchQuery string) (count int, err error)
-	ShelfListCounter(searchQuery string) (count int, err error)
-	BoxListRows(searchQuery string, limit int, page int) ([]common.ListRow, error)
-	ShelfListRows(searchQuery string, limit int, page int) (shelfRows []common.ListRow, err error)
-	InnerListRowsFrom2(belongsToTable string, belongsToTableID uuid.UUID, listRowsTable string) ([]common.ListRow, error)
+	CreateArea(ctx context.Context, newArea Area) (uuid.UUID, error)
+	UpdateArea(ctx context.Context, area Area, ignorePicture bool, pictureFormat string) error
+	DeleteArea(ctx context.Context, id uuid.UUID) error
+	AreaById(ctx context.Context, id uuid.UUID) (Area, error)
+	AreaIDs(ctx context.Context) ([]uuid.UUID, error)
+	AreaListRows(ctx context.Context, query string, limit int, page int) ([]common.ListRow, error)
+	AreaListRowByID(ctx context.Context, id uuid.UUID) (common.ListRow, error)
+	AreaListCounter(ctx context.Context, searchString string) (count int, err error)
+	BoxListCounter(ctx context.Context, searchQuery string) (count int, err error)
+	ShelfListCounter(ctx context.Context, searchQuery string) (count int, err error)
+	BoxListRows(ctx context.Context, searchQuery string, limit int, page int) ([]common.ListRow, error)
+	ShelfListRows(ctx context.Context, searchQuery string, limit int, page int) (shelfRows []common.ListRow, err error)
+	InnerListRowsFrom2(ctx context.Context, belongsToTable string, belongsToTableID uuid.UUID, listRowsTable string) ([]common.ListRow, error)
 }
 
 const (
@@ -70,6 +72,11 @@ func ValidateArea(w http.ResponseWriter, r *http.Request) (area Area, validator
 		},
 	}
 
+	varea.CustomFields, err = common.ParseCustomFields(r, "area")
+	if err != nil {
+		return area, validate.Validate{}, logg.WrapErr(err)
+	}
+
 	validator = validate.Validate{Area: varea}
 	if err = validator.ValidateArea(w, varea); err != nil {
 		return area, validator, err
@@ -87,6 +94,8 @@ func ValidateArea(w http.ResponseWriter, r *http.Request) (area Area, validator
 			Picture:        varea.Picture.String(),
 			PreviewPicture: varea.PreviewPicture.String(),
 			QRCode:         varea.QRCode.String(),
+			Tags:           common.ParseTags(r),
+			CustomFields:   common.CustomFieldValues(varea.CustomFields),
 		},
 	}
 
@@ -41,7 +41,7 @@ func DetailsPage(db AreaDatabase) http.HandlerFunc {
 		logg.Debug(id)
 
 		notFound := false
-		area, err := db.AreaById(id)
+		area, err := db.AreaById(r.Context(), id)
 		if err != nil {
 			logg.Errf("%s", err)
 			notFound = true
@@ -80,7 +80,7 @@ func APIArea(db AreaDatabase) http.HandlerFunc {
 			return
 		}
 		logg.Debug(id)
-		area, err := db.AreaById(id)
+		area, err := db.AreaById(r.Context(), id)
 		if err != nil {
 			server.WriteNotFoundError("", err, w, r)
 			return
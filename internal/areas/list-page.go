@@ -39,7 +39,7 @@ func listPage(db AreaDatabase) http.HandlerFunc {
 		listTmpl.SearchInputLabel = "Search areas"
 		listTmpl.SearchInputValue = searchString
 
-		count, err := db.AreaListCounter(searchString)
+		count, err := db.AreaListCounter(r.Context(), searchString)
 		if err != nil {
 			server.WriteInternalServerError("cant query areas", err, w, r)
 			return
@@ -69,7 +69,7 @@ func listPage(db AreaDatabase) http.HandlerFunc {
 				HideShelfLabel: true,
 				HideAreaLabel:  true,
 			}
-			rows, err = common.FilledRows(db.AreaListRows, searchString, limit, pageNr, count, rowTemplateOptions)
+			rows, err = common.FilledRows(r.Context(), db.AreaListRows, searchString, limit, pageNr, count, rowTemplateOptions)
 			if err != nil {
 				server.WriteInternalServerError("cant query areas", err, w, r)
 				return
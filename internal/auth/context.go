@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// DEVELOPMENT_USER_ID is the user id of every request in development mode.
+const DEVELOPMENT_USER_ID string = "10000000-0000-0000-0000-000000000001"
+
+type contextKey int
+
+const userIDKey contextKey = iota
+
+// WithUserID returns a copy of ctx that carries the id of the logged in user.
+// Database functions use it to only access things owned by this user.
+func WithUserID(ctx context.Context, id uuid.UUID) context.Context {
+	return context.WithValue(ctx, userIDKey, id)
+}
+
+// UserID returns the id stored with WithUserID.
+// ok is false if ctx has no user id.
+func UserID(ctx context.Context) (id uuid.UUID, ok bool) {
+	id, ok = ctx.Value(userIDKey).(uuid.UUID)
+	if id == uuid.Nil {
+		return uuid.Nil, false
+	}
+	return id, ok
+}
+
+// WithSessionUser returns a shallow copy of r whose context carries the user id from the session.
+// Returns r unchanged if the session has no valid user id.
+func WithSessionUser(r *http.Request) *http.Request {
+	_, idStr := UserSessionData(r)
+	id := uuid.FromStringOrNil(idStr)
+	if id == uuid.Nil {
+		return r
+	}
+	return r.WithContext(WithUserID(r.Context(), id))
+}
+
+// ActiveHouseholdID returns the household the user switched to.
+// Returns uuid.Nil if no household was selected.
+func ActiveHouseholdID(r *http.Request) uuid.UUID {
+	session, _ := store.Get(r, COOKIE_NAME)
+	id, _ := session.Values["household_id"].(string)
+	return uuid.FromStringOrNil(id)
+}
+
+// SetActiveHouseholdID stores the household the user works in inside the session cookie.
+func SetActiveHouseholdID(w http.ResponseWriter, r *http.Request, id uuid.UUID) error {
+	session, _ := store.Get(r, COOKIE_NAME)
+	session.Values["household_id"] = id.String()
+	return session.Save(r, w)
+}
@@ -4,17 +4,33 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
+	"unicode"
 
 	"basement/main/internal/env"
 	"basement/main/internal/logg"
 	"basement/main/internal/server"
 	"basement/main/internal/templates"
+
+	"github.com/gofrs/uuid/v5"
 )
 
 const (
 	LOGIN_FAILED_MESSAGE string = "Login failed"
+	// DEFAULT_LOGIN_REDIRECT is the page users land on after they logged in, unless the login page was opened with "next".
+	DEFAULT_LOGIN_REDIRECT string = "/items"
 )
 
+// loginNotifiers add notifications that are shown after a user logged in.
+var loginNotifiers []func(ctx context.Context, userID uuid.UUID, notifications *server.Notifications)
+
+// RegisterLoginNotifier adds notify to the functions that are called after a user logged in.
+// It lets packages that import auth show notifications on login. Must be called before the server starts.
+func RegisterLoginNotifier(notify func(ctx context.Context, userID uuid.UUID, notifications *server.Notifications)) {
+	loginNotifiers = append(loginNotifiers, notify)
+}
+
 func LoginHandler(db AuthDatabase) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPost {
@@ -90,7 +106,12 @@ func loginUser(w http.ResponseWriter, r *http.Request, db AuthDatabase) {
 
 	logg.Info("login successful")
 
-	server.RedirectWithSuccessNotification(w, "/items", fmt.Sprintf("Welcome %s", user.Username))
+	notifications := server.Notifications{}
+	notifications.AddSuccess(fmt.Sprintf("Welcome %s", user.Username))
+	for _, notify := range loginNotifiers {
+		notify(ctx, user.Id, &notifications)
+	}
+	server.RedirectWithNotifications(w, nextPath(r.FormValue("next")), notifications)
 	fmt.Fprintf(w, "Welcome %v\n", username)
 }
 
@@ -100,7 +121,9 @@ func loginPage(w http.ResponseWriter, r *http.Request) {
 	data.Title = "login"
 	data.Authenticated = authenticated
 	logg.Debug(data)
-	err := templates.Render(w, templates.TEMPLATE_LOGIN_PAGE, data)
+	values := data.Map()
+	values["Next"] = nextPath(r.URL.Query().Get("next"))
+	err := templates.Render(w, templates.TEMPLATE_LOGIN_PAGE, values)
 	if err != nil {
 		templates.RenderErrorNotification(w, err.Error())
 		logg.Err(err)
@@ -113,8 +136,10 @@ func LoginForm(w http.ResponseWriter, r *http.Request) {
 	data := templates.NewPageTemplate()
 	data.Title = "login"
 	data.Authenticated = authenticated
+	values := data.Map()
+	values["Next"] = nextPath(r.URL.Query().Get("next"))
 
-	err := templates.SafeRender(w, templates.TEMPLATE_LOGIN_FORM, data)
+	err := templates.SafeRender(w, templates.TEMPLATE_LOGIN_FORM, values)
 	if err != nil {
 		logg.Debug(http.StatusText(http.StatusInternalServerError))
 		w.WriteHeader(http.StatusInternalServerError)
@@ -122,6 +147,29 @@ func LoginForm(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// LoginPath returns the path of the login page that redirects to next after the user logged in.
+func LoginPath(next string) string {
+	return "/login?next=" + url.QueryEscape(next)
+}
+
+// nextPath returns next if it is a path on this server, otherwise DEFAULT_LOGIN_REDIRECT.
+// Other hosts are rejected, so a link to the login page can't send users to another website.
+// Browsers remove control characters and read "\\" as "/", so "/\t/evil.com" would become "//evil.com".
+func nextPath(next string) string {
+	unsafe := func(r rune) bool { return unicode.IsControl(r) || r == '\\' || r == '"' }
+	if strings.ContainsFunc(next, unsafe) {
+		return DEFAULT_LOGIN_REDIRECT
+	}
+	u, err := url.Parse(next)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.User != nil {
+		return DEFAULT_LOGIN_REDIRECT
+	}
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || !strings.HasPrefix(u.Path, "/") {
+		return DEFAULT_LOGIN_REDIRECT
+	}
+	return next
+}
+
 // Authenticated shows if user is authenticated and has "authenticated" value in session cookie.
 func Authenticated(r *http.Request) (authenticated bool, hasAuthenticatedCookieValue bool) {
 	if env.CurrentConfig().AlwaysAuthorized() { // Always authenticated.
@@ -141,10 +189,20 @@ func saveSession(w http.ResponseWriter, r *http.Request, user User) {
 	session.Save(r, w)
 }
 
+// ServerAdmin returns true if the user of r is authenticated and configured as server admin.
+func ServerAdmin(r *http.Request) bool {
+	authenticated, _ := Authenticated(r)
+	if !authenticated {
+		return false
+	}
+	username, _ := UserSessionData(r)
+	return env.CurrentConfig().IsServerAdmin(username)
+}
+
 // UserSessionData returns username and id from stored session.
 func UserSessionData(r *http.Request) (string, string) {
 	if env.Development() {
-		return "Development User", "10000000-0000-0000-0000-000000000001"
+		return "Development User", DEVELOPMENT_USER_ID
 	}
 
 	session, _ := store.Get(r, COOKIE_NAME)
@@ -187,3 +187,31 @@ func runLoginHandlerMethodNotAllowed(method string, t *testing.T) {
 // 		t.Fail()
 // 	}
 // }
+
+func TestNextPath(t *testing.T) {
+	tests := map[string]string{
+		"/q/7kx2m9qh4c":            "/q/7kx2m9qh4c",
+		"/box/1?tab=items":         "/box/1?tab=items",
+		"":                         DEFAULT_LOGIN_REDIRECT,
+		"https://example.com":      DEFAULT_LOGIN_REDIRECT,
+		"//example.com":            DEFAULT_LOGIN_REDIRECT,
+		`/\example.com`:            DEFAULT_LOGIN_REDIRECT,
+		`/items", "target":"#evil`: DEFAULT_LOGIN_REDIRECT,
+		"/\t/evil.com":             DEFAULT_LOGIN_REDIRECT,
+		"/\n/evil.com":             DEFAULT_LOGIN_REDIRECT,
+		"/\r\n/evil.com":           DEFAULT_LOGIN_REDIRECT,
+		"/items\x00":               DEFAULT_LOGIN_REDIRECT,
+		"/items\x7f":               DEFAULT_LOGIN_REDIRECT,
+		"\t//evil.com":             DEFAULT_LOGIN_REDIRECT,
+		"javascript:alert(1)":      DEFAULT_LOGIN_REDIRECT,
+		"/%2F/evil.com":            "/%2F/evil.com",
+	}
+	for next, want := range tests {
+		if got := nextPath(next); got != want {
+			t.Errorf("nextPath(%q) = %q, want %q", next, got, want)
+		}
+	}
+	if got := LoginPath("/q/abc?x=1"); got != "/login?next=%2Fq%2Fabc%3Fx%3D1" {
+		t.Errorf("LoginPath = %q", got)
+	}
+}
@@ -8,6 +8,10 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/mail"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/gofrs/uuid/v5"
 	"github.com/gorilla/sessions"
@@ -109,14 +113,15 @@ func registerUser(w http.ResponseWriter, r *http.Request, db AuthDatabase) {
 	}
 	logg.Debugf("register input values %v", inputFromPost)
 
-	// validInputUser, err := validateRegisterInput(inputFromPost)
-	// if err != nil {
-	// 	RenderValidateErrorMessages(w, inputFromPost)
-	// 	return
-	// }
+	validInputUser, err := validateRegisterInput(inputFromPost)
+	if err != nil {
+		*errorMessages = append(*errorMessages, logg.CleanLastError(err))
+		RenderValidateErrorMessages(w, inputFromPost)
+		return
+	}
 
 	// 2. Put the data into struct from type user
-	newUser, err := user(inputFromPost)
+	newUser, err := user(validInputUser)
 	if err != nil {
 		logg.Err(err)
 		templates.RenderErrorNotification(w, FAILED_MESSAGE)
@@ -147,6 +152,48 @@ func registerUser(w http.ResponseWriter, r *http.Request, db AuthDatabase) {
 	}
 }
 
+// validateRegisterInput checks inputUser against the rules in the tags of InputUser.
+// Returns an empty InputUser and an error that lists all broken rules if one is broken.
+func validateRegisterInput(inputUser InputUser) (InputUser, error) {
+	var broken []string
+	if length := utf8.RuneCountInString(inputUser.Username); length < 6 || length > 20 {
+		broken = append(broken, "the username must have 6 to 20 characters")
+	}
+	if utf8.RuneCountInString(inputUser.Password) < 8 {
+		broken = append(broken, "the password must have at least 8 characters")
+	} else if !strongPassword(inputUser.Password) {
+		broken = append(broken, "the password needs a letter, a number and a symbol")
+	}
+	if inputUser.PasswordConfirm != inputUser.Password {
+		broken = append(broken, "the passwords don't match")
+	}
+	if inputUser.Email != "" {
+		if _, err := mail.ParseAddress(inputUser.Email); err != nil {
+			broken = append(broken, "the email address is invalid")
+		}
+	}
+	if len(broken) > 0 {
+		return InputUser{}, logg.NewError(strings.Join(broken, ", "))
+	}
+	return inputUser, nil
+}
+
+// strongPassword returns true if password has a letter, a number and a symbol.
+func strongPassword(password string) bool {
+	var letter, number, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			letter = true
+		case unicode.IsNumber(r):
+			number = true
+		case !unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	return letter && number && symbol
+}
+
 // return filled struct from type user
 // generate new id in case of register new user
 func user(inputUser InputUser) (User, error) {
@@ -0,0 +1,43 @@
+package backup
+
+import (
+	"regexp"
+	"time"
+)
+
+type BackupDatabase interface {
+	Backup() (Snapshot, error)
+	Backups() ([]Snapshot, error)
+	RestoreBackup(name string) error
+}
+
+// Snapshot is a copy of the whole database inside of the configured backup directory.
+type Snapshot struct {
+	Name    string // file name inside of the backup directory
+	Size    int64  // in bytes
+	Created time.Time
+}
+
+// FILE_NAME_TIME_FORMAT is the UTC creation time inside of snapshot file names.
+const FILE_NAME_TIME_FORMAT = "20060102-150405.000"
+
+var fileNamePattern = regexp.MustCompile(`^basement-(\d{8}-\d{6}\.\d{3})\.db$`)
+
+// FileName returns the file name of a snapshot created at t.
+func FileName(t time.Time) string {
+	return "basement-" + t.UTC().Format(FILE_NAME_TIME_FORMAT) + ".db"
+}
+
+// ParseFileName returns the creation time of a snapshot file name.
+// ok is false if name isn't a snapshot file name.
+func ParseFileName(name string) (created time.Time, ok bool) {
+	match := fileNamePattern.FindStringSubmatch(name)
+	if match == nil {
+		return time.Time{}, false
+	}
+	created, err := time.Parse(FILE_NAME_TIME_FORMAT, match[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return created, true
+}
@@ -70,7 +70,7 @@ func readBoxFromRequest(w http.ResponseWriter, r *http.Request, db BoxDatabase)
 		return box, err
 	}
 
-	box, err = db.BoxById(id)
+	box, err = db.BoxById(r.Context(), id)
 	if err != nil {
 		return box, logg.WrapErr(err)
 		// server.WriteNotFoundError(errMsgForUser, err, w, r)
@@ -104,6 +104,12 @@ func createPage() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		box := NewBox()
+		var err error
+		box.CustomFields, err = common.EmptyCustomFieldValues(r.Context(), "box")
+		if err != nil {
+			server.WriteInternalServerError("can't load the custom fields of boxes please comeback later", err, w, r)
+			return
+		}
 		renderBoxTemplate(w, r, box.Map(), common.CreateMode)
 
 	}
@@ -113,13 +119,13 @@ func createPage() http.HandlerFunc {
 func createBox(w http.ResponseWriter, r *http.Request, db BoxDatabase) {
 	box := NewBox()
 	logg.Debug("create box: ", box)
-	id, err := db.CreateBox(&box)
+	id, err := db.CreateBox(r.Context(), &box)
 	if err != nil {
 		server.WriteNotFoundError("error while creating the box", err, w, r)
 		return
 	}
 	if server.WantsTemplateData(r) {
-		box, err := db.BoxListRowByID(id)
+		box, err := db.BoxListRowByID(r.Context(), id)
 		logg.Debug(box)
 		if err != nil {
 			server.WriteNotFoundError("error while fetching the box based on Id", err, w, r)
@@ -139,7 +145,7 @@ func createBoxFrom(w http.ResponseWriter, r *http.Request, db BoxDatabase) {
 	if err != nil {
 		if err == validator.Err() {
 			logg.Debugf("validation error while creating the Box: %v", validator.Messages.Map())
-			renderBoxTemplate(w, r, validator.BoxFormData(), common.CreateMode)
+			renderBoxTemplate(w, r, common.WithCustomFieldInputs(validator.BoxFormData()), common.CreateMode)
 		} else {
 			logg.Debugf("error happened while creating the Box: %v", err)
 			server.TriggerSingleErrorNotification(w, "Error while creating the Box please comeback later")
@@ -148,7 +154,7 @@ func createBoxFrom(w http.ResponseWriter, r *http.Request, db BoxDatabase) {
 	}
 
 	logg.Debug("create box: ", box)
-	_, err = db.CreateBox(&box)
+	_, err = db.CreateBox(r.Context(), &box)
 	server.RedirectWithSuccessNotification(w, "/boxes", "Created new box: "+box.Label)
 }
 
@@ -161,7 +167,7 @@ func updateBox(w http.ResponseWriter, r *http.Request, db BoxDatabase) {
 	if err != nil {
 		if err == validator.Err() {
 			logg.Debugf("validation error while updating the Box: %v", validator.Messages.Map())
-			renderBoxTemplate(w, r, validator.BoxFormData(), common.EditMode)
+			renderBoxTemplate(w, r, common.WithCustomFieldInputs(validator.BoxFormData()), common.EditMode)
 		} else {
 			logg.Debugf("error happened while updating the Box: %v", err)
 			server.WriteNotFoundError("error while creating the box", err, w, r)
@@ -182,7 +188,7 @@ func updateBox(w http.ResponseWriter, r *http.Request, db BoxDatabase) {
 	}
 
 	// ── Update in DB ────────────────────────────────────────────────
-	if err = db.UpdateBox(box, ignorePicture, pictureFormat); err != nil {
+	if err = db.UpdateBox(r.Context(), box, ignorePicture, pictureFormat); err != nil {
 		server.WriteNotFoundError(errMsgForUser+" "+logg.CleanLastError(err), err, w, r)
 		return
 	}
@@ -198,7 +204,7 @@ func deleteBox(w http.ResponseWriter, r *http.Request, db BoxDatabase) {
 	if id.IsNil() {
 		return
 	}
-	err := db.DeleteBox(id)
+	err := db.DeleteBox(r.Context(), id)
 	if err != nil {
 		server.WriteNotFoundError(errMsgForUser, err, w, r)
 		return
@@ -3,9 +3,11 @@ package boxes
 import (
 	"basement/main/internal/common"
 	"basement/main/internal/env"
+	"basement/main/internal/lending"
 	"basement/main/internal/logg"
 	"basement/main/internal/templates"
 	"basement/main/internal/validate"
+	"context"
 	"encoding/json"
 	"fmt"
 	"maps"
@@ -31,22 +33,23 @@ const (
 )
 
 type BoxDatabase interface {
-	CreateBox(newBox *Box) (uuid.UUID, error)
-	MoveBoxToBox(box1 uuid.UUID, box2 uuid.UUID) error
-	MoveBoxToShelf(boxID uuid.UUID, toShelfID uuid.UUID) error
-	MoveBoxToArea(boxID uuid.UUID, toAreaID uuid.UUID) error
-	UpdateBox(box Box, ignorePicture bool, pictureFormat string) error
-	DeleteBox(boxId uuid.UUID) error
-	BoxById(id uuid.UUID) (Box, error)
-	BoxIDs() ([]uuid.UUID, error)
-	BoxListRows(searchQuery string, limit int, page int) ([]common.ListRow, error)
-	BoxListRowByID(id uuid.UUID) (common.ListRow, error)
-	// InnerListRowsFrom2(belongsToTable string, belongsToTableID uuid.UUID, listRowsTable string) ([]common.ListRow, error)
-	BoxListCounter(searchQuery string) (count int, err error)
-	ShelfListCounter(searchQuery string) (count int, err error)
-	ShelfListRows(searchQuery string, limit int, page int) (shelfRows []common.ListRow, err error)
-	AreaListCounter(searchQuery string) (count int, err error)
-	AreaListRows(searchQuery string, limit int, page int) (rows []common.ListRow, err error)
+	CreateBox(ctx context.Context, newBox *Box) (uuid.UUID, error)
+	MoveBoxToBox(ctx context.Context, box1 uuid.UUID, box2 uuid.UUID) error
+	MoveBoxToShelf(ctx context.Context, boxID uuid.UUID, toShelfID uuid.UUID) error
+	MoveBoxToArea(ctx context.Context, boxID uuid.UUID, toAreaID uuid.UUID) error
+	UpdateBox(ctx context.Context, box Box, ignorePicture bool, pictureFormat string) error
+	DeleteBox(ctx context.Context, boxId uuid.UUID) error
+	BoxById(ctx context.Context, id uuid.UUID) (Box, error)
+	BoxIDs(ctx context.Context) ([]uuid.UUID, error)
+	BoxListRows(ctx context.Context, searchQuery string, limit int, page int) ([]common.ListRow, error)
+	BoxListRowByID(ctx context.Context, id uuid.UUID) (common.ListRow, error)
+	// InnerListRowsFrom2(ctx context.Context, belongsToTable string, belongsToTableID uuid.UUID, listRowsTable string) ([]common.ListRow, error)
+	BoxListCounter(ctx context.Context, searchQuery string) (count int, err error)
+	ShelfListCounter(ctx context.Context, searchQuery string) (count int, err error)
+	ShelfListRows(ctx context.Context, searchQuery string, limit int, page int) (shelfRows []common.ListRow, err error)
+	AreaListCounter(ctx context.Context, searchQuery string) (count int, err error)
+	AreaListRows(ctx context.Context, searchQuery string, limit int, page int) (rows []common.ListRow, err error)
+	ThingLoan(ctx context.Context, thing string, id uuid.UUID) (*lending.Loan, error)
 }
 
 type Box struct {
@@ -190,6 +193,10 @@ func ValidateBox(w http.ResponseWriter, r *http.Request) (box Box, validator val
 		OuterBoxID: validate.NewUUIDField(r.PostFormValue(BOX_ID)),
 		AreaID:     validate.NewUUIDField(r.PostFormValue(AREA_ID)),
 	}
+	vbox.CustomFields, err = common.ParseCustomFields(r, "box")
+	if err != nil {
+		return box, validate.Validate{}, logg.WrapErr(err)
+	}
 	logg.DebugJSON(vbox, 50)
 
 	validator = validate.Validate{Box: vbox}
@@ -212,6 +219,8 @@ func ValidateBox(w http.ResponseWriter, r *http.Request) (box Box, validator val
 			Description:    vbox.Description.Value,
 			Picture:        vbox.Picture.Value,
 			PreviewPicture: vbox.PreviewPicture.Value,
+			Tags:           common.ParseTags(r),
+			CustomFields:   common.CustomFieldValues(vbox.CustomFields),
 		},
 		ShelfID:    vbox.ShelfID.Value,
 		OuterBoxID: vbox.OuterBoxID.Value,
@@ -12,7 +12,7 @@ func BoxesHandler(db BoxDatabase) http.HandlerFunc {
 
 		case http.MethodGet:
 			if !server.WantsTemplateData(r) {
-				boxs, err := db.BoxListRows("", 100, 1)
+				boxs, err := db.BoxListRows(r.Context(), "", 100, 1)
 				if err != nil {
 					server.WriteNotFoundError("Can't find boxes", err, w, r)
 					return
@@ -36,7 +36,7 @@ func DetailsPage(db BoxDatabase) http.HandlerFunc {
 		}
 		logg.Debug(id)
 
-		box, err := db.BoxById(id)
+		box, err := db.BoxById(r.Context(), id)
 		if err != nil {
 			logg.Errf("%s", err)
 			notFound = true
@@ -57,6 +57,10 @@ func DetailsPage(db BoxDatabase) http.HandlerFunc {
 		if err != nil {
 			notifications.AddError("could not load inner items")
 		}
+		data.TypeMap["Loan"], err = db.ThingLoan(r.Context(), "box", id)
+		if err != nil {
+			notifications.AddError("could not load the loan of the box")
+		}
 		if len(notifications.ServerNotificationEvents) > 0 {
 			server.TriggerNotifications(w, notifications)
 		}
@@ -74,7 +78,7 @@ func RenderBoxDetailsForm(db BoxDatabase) http.HandlerFunc {
 		}
 		logg.Debug(id)
 
-		box, err := db.BoxById(id)
+		box, err := db.BoxById(r.Context(), id)
 		if err != nil {
 			logg.Errf("%s", err)
 			notFound = true
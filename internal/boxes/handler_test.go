@@ -3,8 +3,10 @@ package boxes
 import (
 	"basement/main/internal/common"
 	"basement/main/internal/env"
+	"basement/main/internal/lending"
 	"basement/main/internal/logg"
 	"basement/main/internal/templates"
+	"context"
 	"errors"
 	"io"
 	"net/http"
@@ -42,27 +44,31 @@ var ErrMock = errors.New("mock error")
 // boxDatabaseError returns errors on every function.
 type boxDatabaseError struct{}
 
-func (db *boxDatabaseError) CreateBox(newBox *Box) (uuid.UUID, error) {
+func (db *boxDatabaseError) CreateBox(ctx context.Context, newBox *Box) (uuid.UUID, error) {
 	return uuid.Nil, ErrMock
 }
 
-func (db *boxDatabaseError) BoxById(id uuid.UUID) (Box, error) {
+func (db *boxDatabaseError) BoxById(ctx context.Context, id uuid.UUID) (Box, error) {
 	return Box{BasicInfo: common.BasicInfo{ID: uuid.Nil}}, ErrMock
 }
 
-func (db *boxDatabaseError) BoxIDs() ([]uuid.UUID, error) {
+func (db *boxDatabaseError) BoxIDs(ctx context.Context) ([]uuid.UUID, error) {
 	return nil, ErrMock
 }
 
-func (db *boxDatabaseError) MoveBoxToBox(id1 uuid.UUID, id2 uuid.UUID) error {
+func (db *boxDatabaseError) ThingLoan(ctx context.Context, thing string, id uuid.UUID) (*lending.Loan, error) {
+	return nil, ErrMock
+}
+
+func (db *boxDatabaseError) MoveBoxToBox(ctx context.Context, id1 uuid.UUID, id2 uuid.UUID) error {
 	return ErrMock
 }
 
-func (db *boxDatabaseError) BoxByField(field string, value string) (*Box, error) {
+func (db *boxDatabaseError) BoxByField(ctx context.Context, field string, value string) (*Box, error) {
 	return &Box{}, ErrMock
 }
 
-func (db *boxDatabaseError) BoxExistById(id uuid.UUID) bool {
+func (db *boxDatabaseError) BoxExistById(ctx context.Context, id uuid.UUID) bool {
 	return false
 }
 
@@ -70,106 +76,114 @@ func (db *boxDatabaseError) ErrorExist() error {
 	return ErrMock
 }
 
-func (db *boxDatabaseError) UpdateBox(box Box, updatePicture bool, pictureFormat string) error {
+func (db *boxDatabaseError) UpdateBox(ctx context.Context, box Box, updatePicture bool, pictureFormat string) error {
 	return errors.New("AAAAA")
 }
 
-func (db *boxDatabaseError) DeleteBox(boxId uuid.UUID) error {
+func (db *boxDatabaseError) DeleteBox(ctx context.Context, boxId uuid.UUID) error {
 	return errors.New("AAAAA")
 }
 
-func (db *boxDatabaseError) BoxListRows(query string, limit int, page int) ([]common.ListRow, error) {
+func (db *boxDatabaseError) BoxListRows(ctx context.Context, query string, limit int, page int) ([]common.ListRow, error) {
 	return make([]common.ListRow, 0), ErrMock
 }
 
-func (db *boxDatabaseError) BoxListRowByID(id uuid.UUID) (common.ListRow, error) {
+func (db *boxDatabaseError) BoxListRowByID(ctx context.Context, id uuid.UUID) (common.ListRow, error) {
 	return common.ListRow{}, ErrMock
 }
 
-func (db *boxDatabaseError) BoxListCounter(searchString string) (count int, err error) {
+func (db *boxDatabaseError) BoxListCounter(ctx context.Context, searchString string) (count int, err error) {
 	return count, err
 }
 
-func (db *boxDatabaseError) MoveBoxToShelf(boxID uuid.UUID, toShelfID uuid.UUID) error {
+func (db *boxDatabaseError) MoveBoxToShelf(ctx context.Context, boxID uuid.UUID, toShelfID uuid.UUID) error {
 	return ErrMock
 }
 
-func (db *boxDatabaseError) MoveBoxToArea(boxID uuid.UUID, toAreaID uuid.UUID) error {
+func (db *boxDatabaseError) MoveBoxToArea(ctx context.Context, boxID uuid.UUID, toAreaID uuid.UUID) error {
 	return ErrMock
 }
 
-func (db *boxDatabaseError) ShelfListCounter(queryString string) (count int, err error) {
+func (db *boxDatabaseError) ShelfListCounter(ctx context.Context, queryString string) (count int, err error) {
 	return 0, ErrMock
 }
 
-func (db *boxDatabaseError) ShelfListRows(searchString string, limit int, pageNr int) (shelfRows []common.ListRow, err error) {
+func (db *boxDatabaseError) ShelfListRows(ctx context.Context, searchString string, limit int, pageNr int) (shelfRows []common.ListRow, err error) {
 	return shelfRows, ErrMock
 }
 
-func (db *boxDatabaseError) AreaListCounter(searchQuery string) (count int, err error) {
+func (db *boxDatabaseError) AreaListCounter(ctx context.Context, searchQuery string) (count int, err error) {
 	return 0, ErrMock
 }
 
-func (db *boxDatabaseError) AreaListRows(searchQuery string, limit int, pageNr int) (rows []common.ListRow, err error) {
+func (db *boxDatabaseError) AreaListRows(ctx context.Context, searchQuery string, limit int, pageNr int) (rows []common.ListRow, err error) {
 	return rows, ErrMock
 }
 
-func (db *boxDatabaseError) InnerListRowsFrom2(belongsToTable string, belongsToTableID uuid.UUID, listRowsTable string) ([]common.ListRow, error) {
+func (db *boxDatabaseError) InnerListRowsFrom2(ctx context.Context, belongsToTable string, belongsToTableID uuid.UUID, listRowsTable string) ([]common.ListRow, error) {
 	return nil, ErrMock
 }
 
-func (db *boxDatabaseError) DeleteItem(itemID uuid.UUID) error {
+func (db *boxDatabaseError) DeleteItem(ctx context.Context, itemID uuid.UUID) error {
 	return ErrMock
 }
 
-func (db *boxDatabaseError) DeleteShelf(id uuid.UUID) (string, error) {
+func (db *boxDatabaseError) DeleteShelf(ctx context.Context, id uuid.UUID) (string, error) {
 	return "", ErrMock
 }
 
-func (db *boxDatabaseError) DeleteShelf2(id uuid.UUID) error {
+func (db *boxDatabaseError) DeleteShelf2(ctx context.Context, id uuid.UUID) error {
 	return ErrMock
 }
 
-func (db *boxDatabaseError) DeleteArea(areaID uuid.UUID) error {
+func (db *boxDatabaseError) DeleteArea(ctx context.Context, areaID uuid.UUID) error {
 	return ErrMock
 }
 
-func (db *boxDatabaseError) InnerBoxInBoxListCounter(searchString string, inTable string, inTableID uuid.UUID) (count int, err error) {
+func (db *boxDatabaseError) CustomFields(ctx context.Context, thing string) ([]common.CustomField, error) {
+	return nil, ErrMock
+}
+
+func (db *boxDatabaseError) InnerBoxInBoxListCounter(ctx context.Context, searchString string, inTable string, inTableID uuid.UUID) (count int, err error) {
 	return 0, ErrMock
 }
 
-func (db *boxDatabaseError) InnerListRowsPaginatedFrom(belongsToTable string, belongsToTableID uuid.UUID, listRowsTable string, searchQuery string, limit int, page int) (listRows []common.ListRow, err error) {
+func (db *boxDatabaseError) InnerListRowsPaginatedFrom(ctx context.Context, belongsToTable string, belongsToTableID uuid.UUID, listRowsTable string, searchQuery string, limit int, page int) (listRows []common.ListRow, err error) {
 	return listRows, ErrMock
 }
 
-func (db *boxDatabaseError) InnerShelfInTableListCounter(searchString string, inTable string, inTableID uuid.UUID) (count int, err error) {
+func (db *boxDatabaseError) InnerShelfInTableListCounter(ctx context.Context, searchString string, inTable string, inTableID uuid.UUID) (count int, err error) {
 	return 0, ErrMock
 }
 
-func (db *boxDatabaseError) InnerThingInTableListCounter(searchString string, thing int, inTable string, inTableID uuid.UUID) (count int, err error) {
+func (db *boxDatabaseError) InnerThingInTableListCounter(ctx context.Context, searchString string, thing int, inTable string, inTableID uuid.UUID) (count int, err error) {
 	return 0, ErrMock
 }
 
 // boxDatabaseSuccess never returns errors.
 type boxDatabaseSuccess struct{}
 
-func (db *boxDatabaseSuccess) CreateBox(newBox *Box) (uuid.UUID, error) {
+func (db *boxDatabaseSuccess) CreateBox(ctx context.Context, newBox *Box) (uuid.UUID, error) {
 	return uuid.Must(uuid.FromString(BOX_ID_VALID)), nil
 }
 
-func (db *boxDatabaseSuccess) BoxById(id uuid.UUID) (Box, error) {
+func (db *boxDatabaseSuccess) BoxById(ctx context.Context, id uuid.UUID) (Box, error) {
 	return Box{BasicInfo: common.BasicInfo{ID: uuid.Must(uuid.FromString(BOX_ID_VALID))}}, nil
 }
 
-func (db *boxDatabaseSuccess) BoxIDs() ([]uuid.UUID, error) {
+func (db *boxDatabaseSuccess) BoxIDs(ctx context.Context) ([]uuid.UUID, error) {
 	return []uuid.UUID{uuid.FromStringOrNil(BOX_ID_VALID)}, nil
 }
 
-func (db *boxDatabaseSuccess) MoveBoxToBox(id1 uuid.UUID, id2 uuid.UUID) error {
+func (db *boxDatabaseSuccess) ThingLoan(ctx context.Context, thing string, id uuid.UUID) (*lending.Loan, error) {
+	return nil, nil
+}
+
+func (db *boxDatabaseSuccess) MoveBoxToBox(ctx context.Context, id1 uuid.UUID, id2 uuid.UUID) error {
 	return nil
 }
 
-func (db *boxDatabaseSuccess) BoxExistById(id uuid.UUID) bool {
+func (db *boxDatabaseSuccess) BoxExistById(ctx context.Context, id uuid.UUID) bool {
 	return true
 }
 
@@ -177,83 +191,87 @@ func (db *boxDatabaseSuccess) ErrorExist() error {
 	return nil
 }
 
-func (db *boxDatabaseSuccess) UpdateBox(box Box, updatePicture bool, pictureFormat string) error {
+func (db *boxDatabaseSuccess) UpdateBox(ctx context.Context, box Box, updatePicture bool, pictureFormat string) error {
 	return nil
 }
 
-func (db *boxDatabaseSuccess) BoxListRows(query string, limit int, page int) ([]common.ListRow, error) {
+func (db *boxDatabaseSuccess) BoxListRows(ctx context.Context, query string, limit int, page int) ([]common.ListRow, error) {
 	return make([]common.ListRow, 0), nil
 }
 
-func (db *boxDatabaseSuccess) BoxListRowByID(id uuid.UUID) (common.ListRow, error) {
+func (db *boxDatabaseSuccess) BoxListRowByID(ctx context.Context, id uuid.UUID) (common.ListRow, error) {
 	return common.ListRow{}, nil
 }
 
-func (db *boxDatabaseSuccess) BoxListCounter(searchString string) (count int, err error) {
+func (db *boxDatabaseSuccess) BoxListCounter(ctx context.Context, searchString string) (count int, err error) {
 	return 1, nil
 }
 
-func (db *boxDatabaseSuccess) MoveBoxToShelf(boxID uuid.UUID, toShelfID uuid.UUID) error {
+func (db *boxDatabaseSuccess) MoveBoxToShelf(ctx context.Context, boxID uuid.UUID, toShelfID uuid.UUID) error {
 	return nil
 }
 
-func (db *boxDatabaseSuccess) MoveBoxToArea(boxID uuid.UUID, toAreaID uuid.UUID) error {
+func (db *boxDatabaseSuccess) MoveBoxToArea(ctx context.Context, boxID uuid.UUID, toAreaID uuid.UUID) error {
 	return nil
 }
 
-func (db *boxDatabaseSuccess) ShelfListCounter(queryString string) (count int, err error) {
+func (db *boxDatabaseSuccess) ShelfListCounter(ctx context.Context, queryString string) (count int, err error) {
 	return 1, nil
 }
 
-func (db *boxDatabaseSuccess) ShelfListRows(searchString string, limit int, pageNr int) (shelfRows []common.ListRow, err error) {
+func (db *boxDatabaseSuccess) ShelfListRows(ctx context.Context, searchString string, limit int, pageNr int) (shelfRows []common.ListRow, err error) {
 	return shelfRows, nil
 }
 
-func (db *boxDatabaseSuccess) AreaListCounter(searchQuery string) (count int, err error) {
+func (db *boxDatabaseSuccess) AreaListCounter(ctx context.Context, searchQuery string) (count int, err error) {
 	return 1, nil
 }
 
-func (db *boxDatabaseSuccess) AreaListRows(searchQuery string, limit int, pageNr int) (rows []common.ListRow, err error) {
+func (db *boxDatabaseSuccess) AreaListRows(ctx context.Context, searchQuery string, limit int, pageNr int) (rows []common.ListRow, err error) {
 	return rows, nil
 }
 
-func (db *boxDatabaseSuccess) InnerListRowsFrom2(belongsToTable string, belongsToTableID uuid.UUID, listRowsTable string) ([]common.ListRow, error) {
+func (db *boxDatabaseSuccess) InnerListRowsFrom2(ctx context.Context, belongsToTable string, belongsToTableID uuid.UUID, listRowsTable string) ([]common.ListRow, error) {
 	return []common.ListRow{}, nil
 }
 
-func (db *boxDatabaseSuccess) DeleteItem(itemID uuid.UUID) error {
+func (db *boxDatabaseSuccess) DeleteItem(ctx context.Context, itemID uuid.UUID) error {
 	return nil
 }
 
-func (db *boxDatabaseSuccess) DeleteBox(boxId uuid.UUID) error {
+func (db *boxDatabaseSuccess) DeleteBox(ctx context.Context, boxId uuid.UUID) error {
 	return nil
 }
 
-func (db *boxDatabaseSuccess) DeleteShelf(id uuid.UUID) (string, error) {
+func (db *boxDatabaseSuccess) DeleteShelf(ctx context.Context, id uuid.UUID) (string, error) {
 	return "ShelfLabel", nil
 }
 
-func (db *boxDatabaseSuccess) DeleteShelf2(id uuid.UUID) error {
+func (db *boxDatabaseSuccess) DeleteShelf2(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
-func (db *boxDatabaseSuccess) DeleteArea(areaID uuid.UUID) error {
+func (db *boxDatabaseSuccess) DeleteArea(ctx context.Context, areaID uuid.UUID) error {
 	return nil
 }
 
-func (db *boxDatabaseSuccess) InnerBoxInBoxListCounter(searchString string, inTable string, inTableID uuid.UUID) (count int, err error) {
+func (db *boxDatabaseSuccess) CustomFields(ctx context.Context, thing string) ([]common.CustomField, error) {
+	return []common.CustomField{}, nil
+}
+
+func (db *boxDatabaseSuccess) InnerBoxInBoxListCounter(ctx context.Context, searchString string, inTable string, inTableID uuid.UUID) (count int, err error) {
 	return 1, nil
 }
 
-func (db *boxDatabaseSuccess) InnerListRowsPaginatedFrom(belongsToTable string, belongsToTableID uuid.UUID, listRowsTable string, searchQuery string, limit int, page int) (listRows []common.ListRow, err error) {
+func (db *boxDatabaseSuccess) InnerListRowsPaginatedFrom(ctx context.Context, belongsToTable string, belongsToTableID uuid.UUID, listRowsTable string, searchQuery string, limit int, page int) (listRows []common.ListRow, err error) {
 	return
 }
 
-func (db *boxDatabaseSuccess) InnerShelfInTableListCounter(searchString string, inTable string, inTableID uuid.UUID) (count int, err error) {
+func (db *boxDatabaseSuccess) InnerShelfInTableListCounter(ctx context.Context, searchString string, inTable string, inTableID uuid.UUID) (count int, err error) {
 	return
 }
 
-func (db *boxDatabaseSuccess) InnerThingInTableListCounter(searchString string, thing int, inTable string, inTableID uuid.UUID) (count int, err error) {
+func (db *boxDatabaseSuccess) InnerThingInTableListCounter(ctx context.Context, searchString string, thing int, inTable string, inTableID uuid.UUID) (count int, err error) {
 	return
 }
 
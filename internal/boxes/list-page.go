@@ -38,7 +38,7 @@ func listPage(db BoxDatabase) http.HandlerFunc {
 		listTmpl.SearchInputLabel = "Search boxes"
 		listTmpl.SearchInputValue = searchString
 
-		count, err := db.BoxListCounter(searchString)
+		count, err := db.BoxListCounter(r.Context(), searchString)
 		if err != nil {
 			server.WriteInternalServerError("cant query boxes", err, w, r)
 			return
@@ -57,7 +57,7 @@ func listPage(db BoxDatabase) http.HandlerFunc {
 
 		// Boxes found
 		if count > 0 {
-			boxes, err = common.FilledRows(db.BoxListRows, searchString, limit, pageNr, count, common.ListRowTemplateOptions{RowHXGet: "/box"})
+			boxes, err = common.FilledRows(r.Context(), db.BoxListRows, searchString, limit, pageNr, count, common.ListRowTemplateOptions{RowHXGet: "/box"})
 			if err != nil {
 				server.WriteInternalServerError("cant query boxes", err, w, r)
 				return
@@ -52,7 +52,7 @@ func BoxPicker(pickerType int, db BoxDatabase) http.HandlerFunc {
 		switch thing {
 		case "box":
 			data.SetRowHXGet("/box")
-			count, err = db.BoxListCounter("")
+			count, err = db.BoxListCounter(r.Context(), "")
 			if err != nil {
 				server.WriteInternalServerError("no box list counter", err, w, r)
 				return
@@ -69,7 +69,7 @@ func BoxPicker(pickerType int, db BoxDatabase) http.HandlerFunc {
 					RowActionName:         actionName,
 					RowActionHXPostWithID: post,
 				}
-				rows, err = common.FilledRows(db.BoxListRows, data.GetSearchInputValue(), data.GetLimit(), data.GetPageNumber(), count, rowOptions)
+				rows, err = common.FilledRows(r.Context(), db.BoxListRows, data.GetSearchInputValue(), data.GetLimit(), data.GetPageNumber(), count, rowOptions)
 				if err != nil {
 					server.WriteInternalServerError("cant query "+thing+" please comeback later", err, w, r)
 				}
@@ -78,7 +78,7 @@ func BoxPicker(pickerType int, db BoxDatabase) http.HandlerFunc {
 
 		case "shelf":
 			data.SetRowHXGet("/shelves")
-			count, err = db.ShelfListCounter("")
+			count, err = db.ShelfListCounter(r.Context(), "")
 			if err != nil {
 				server.WriteInternalServerError("no shelf list counter", err, w, r)
 				return
@@ -95,7 +95,7 @@ func BoxPicker(pickerType int, db BoxDatabase) http.HandlerFunc {
 					RowActionName:         actionName,
 					RowActionHXPostWithID: post,
 				}
-				rows, err = common.FilledRows(db.ShelfListRows, data.GetSearchInputValue(), data.GetLimit(), data.GetPageNumber(), count, rowOptions)
+				rows, err = common.FilledRows(r.Context(), db.ShelfListRows, data.GetSearchInputValue(), data.GetLimit(), data.GetPageNumber(), count, rowOptions)
 				if err != nil {
 					server.WriteInternalServerError("cant query "+thing+" please comeback later", err, w, r)
 				}
@@ -105,7 +105,7 @@ func BoxPicker(pickerType int, db BoxDatabase) http.HandlerFunc {
 
 		case "area":
 			data.SetRowHXGet("/area")
-			count, err = db.AreaListCounter("")
+			count, err = db.AreaListCounter(r.Context(), "")
 			if err != nil {
 				server.WriteInternalServerError("no area list counter", err, w, r)
 				return
@@ -122,7 +122,7 @@ func BoxPicker(pickerType int, db BoxDatabase) http.HandlerFunc {
 					RowActionName:         actionName,
 					RowActionHXPostWithID: post,
 				}
-				rows, err = common.FilledRows(db.AreaListRows, data.GetSearchInputValue(), data.GetLimit(), data.GetPageNumber(), count, rowOptions)
+				rows, err = common.FilledRows(r.Context(), db.AreaListRows, data.GetSearchInputValue(), data.GetLimit(), data.GetPageNumber(), count, rowOptions)
 				if err != nil {
 					server.WriteInternalServerError("cant query "+thing+" please comeback later", err, w, r)
 				}
@@ -179,10 +179,10 @@ func BoxPickerConfirm(pickerType int, db BoxDatabase) http.HandlerFunc {
 			if pickerType == PICKER_TYPE_ADDTO {
 				err1 = nil
 			} else if pickerType == PICKER_TYPE_MOVE {
-				err1 = db.MoveBoxToBox(boxID, uuid.FromStringOrNil(moveToThingID))
+				err1 = db.MoveBoxToBox(r.Context(), boxID, uuid.FromStringOrNil(moveToThingID))
 			}
 
-			outerbox, err2 = db.BoxById(uuid.FromStringOrNil(moveToThingID))
+			outerbox, err2 = db.BoxById(r.Context(), uuid.FromStringOrNil(moveToThingID))
 			if err2 == nil {
 				otherThingLabel = outerbox.Label
 				otherThingElementID = "outerbox-link"
@@ -194,7 +194,7 @@ func BoxPickerConfirm(pickerType int, db BoxDatabase) http.HandlerFunc {
 			if pickerType == PICKER_TYPE_ADDTO {
 				err1 = nil
 			} else if pickerType == PICKER_TYPE_MOVE {
-				err1 = db.MoveBoxToShelf(boxID, uuid.FromStringOrNil(moveToThingID))
+				err1 = db.MoveBoxToShelf(r.Context(), boxID, uuid.FromStringOrNil(moveToThingID))
 			}
 
 			if err1 == nil {
@@ -207,7 +207,7 @@ func BoxPickerConfirm(pickerType int, db BoxDatabase) http.HandlerFunc {
 			if pickerType == PICKER_TYPE_ADDTO {
 				err1 = nil
 			} else if pickerType == PICKER_TYPE_MOVE {
-				err1 = db.MoveBoxToArea(boxID, uuid.FromStringOrNil(moveToThingID))
+				err1 = db.MoveBoxToArea(r.Context(), boxID, uuid.FromStringOrNil(moveToThingID))
 			}
 			if err1 == nil {
 				otherThingLabel = moveToThingID
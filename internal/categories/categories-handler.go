@@ -0,0 +1,298 @@
+package categories
+
+import (
+	"basement/main/internal/auth"
+	"basement/main/internal/common"
+	"basement/main/internal/logg"
+	"basement/main/internal/server"
+	"basement/main/internal/templates"
+	"basement/main/internal/validate"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+const (
+	maxNameLength = 50
+	maxUnitLength = 20
+)
+
+// CategoryDetails is the JSON response of a single category.
+type CategoryDetails struct {
+	Category
+	Defaults []common.CustomFieldValue `json:"defaults"`
+}
+
+// CategoriesHandler lists and creates the item categories of the active household.
+//
+//	GET  /categories        = category tree with item counts
+//	GET  /api/v1/categories = all categories in tree order as JSON
+//	POST                    = create category from form values "name", "parent_id" and "unit"
+func CategoriesHandler(db CategoryDatabase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			categories, err := db.Categories(r.Context())
+			if err != nil {
+				server.WriteInternalServerError("can't query categories", err, w, r)
+				return
+			}
+			if !server.WantsTemplateData(r) {
+				if categories == nil {
+					categories = []Category{}
+				}
+				server.WriteJSON(w, categories)
+				return
+			}
+			pageHandler(w, r, categories)
+			break
+
+		case http.MethodPost:
+			category, ok := validCategory(w, r)
+			if !ok {
+				return
+			}
+			id, err := db.CreateCategory(r.Context(), category)
+			if errors.Is(err, ErrCategoryExists) {
+				writeCategoryExists(w, r, category, err)
+				return
+			}
+			if err != nil {
+				server.WriteNotFoundError("can't create category", err, w, r)
+				return
+			}
+			category.ID = id
+			if !server.WantsTemplateData(r) {
+				w.WriteHeader(http.StatusCreated)
+				server.WriteJSON(w, category)
+				return
+			}
+			server.RedirectWithSuccessNotification(w, "/categories", `Created category "`+category.Name+`"`)
+			break
+
+		default:
+			w.Header().Add("Allow", http.MethodGet)
+			w.Header().Add("Allow", http.MethodPost)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+		}
+	}
+}
+
+// CategoryHandler shows, changes and deletes the category with the path value "id".
+//
+//	GET    /categories/{id}        = page to edit the category and its default custom field values
+//	GET    /api/v1/categories/{id} = category with its default values as JSON
+//	PUT    = change name, parent and unit to the form values "name", "parent_id" and "unit",
+//	         the default values are changed if the form contains the custom field inputs
+//	DELETE = delete category, its items and subcategories move to its parent
+func CategoryHandler(db CategoryDatabase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			id := server.ValidID(w, r, "can't find category, invalid id")
+			if id == uuid.Nil {
+				return
+			}
+			category, err := db.Category(r.Context(), id)
+			if err != nil {
+				server.WriteNotFoundError("can't find category", err, w, r)
+				return
+			}
+			defaults, err := db.CategoryDefaults(r.Context(), id)
+			if err != nil {
+				server.WriteInternalServerError("can't query the defaults of the category", err, w, r)
+				return
+			}
+			if !server.WantsTemplateData(r) {
+				server.WriteJSON(w, CategoryDetails{Category: category, Defaults: defaults})
+				return
+			}
+			categories, err := db.Categories(r.Context())
+			if err != nil {
+				server.WriteInternalServerError("can't query categories", err, w, r)
+				return
+			}
+			categoryPageHandler(w, r, category, categories, common.CustomFieldInputs(defaults, nil))
+			break
+
+		case http.MethodPut:
+			id := server.ValidID(w, r, "can't change category, invalid id")
+			if id == uuid.Nil {
+				return
+			}
+			category, ok := validCategory(w, r)
+			if !ok {
+				return
+			}
+			category.ID = id
+			defaults, ok := validDefaults(w, r)
+			if !ok {
+				return
+			}
+			err := db.UpdateCategory(r.Context(), category)
+			if errors.Is(err, ErrCategoryExists) {
+				writeCategoryExists(w, r, category, err)
+				return
+			}
+			if errors.Is(err, ErrCategoryCycle) {
+				writeInvalid(w, r, "A category can't be moved into itself or one of its subcategories.")
+				return
+			}
+			if err != nil {
+				server.WriteNotFoundError("can't change category", err, w, r)
+				return
+			}
+			err = db.SetCategoryDefaults(r.Context(), id, defaults)
+			if err != nil {
+				server.WriteInternalServerError("can't change the defaults of the category", err, w, r)
+				return
+			}
+			if !server.WantsTemplateData(r) {
+				server.WriteJSON(w, category)
+				return
+			}
+			server.RedirectWithSuccessNotification(w, "/categories", `Saved category "`+category.Name+`"`)
+			break
+
+		case http.MethodDelete:
+			id := server.ValidID(w, r, "can't delete category, invalid id")
+			if id == uuid.Nil {
+				return
+			}
+			err := db.DeleteCategory(r.Context(), id)
+			if err != nil {
+				server.WriteNotFoundError("can't delete category", err, w, r)
+				return
+			}
+			if !server.WantsTemplateData(r) {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			server.RedirectWithSuccessNotification(w, "/categories", "Deleted category")
+			break
+
+		default:
+			w.Header().Add("Allow", http.MethodGet)
+			w.Header().Add("Allow", http.MethodPut)
+			w.Header().Add("Allow", http.MethodDelete)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+		}
+	}
+}
+
+func pageHandler(w http.ResponseWriter, r *http.Request, categories []Category) {
+	authenticated, _ := auth.Authenticated(r)
+	username, _ := auth.UserSessionData(r)
+	page := templates.NewPageTemplate()
+	page.Title = "Categories"
+	page.RequestOrigin = "Categories"
+	page.Authenticated = authenticated
+	page.User = username
+
+	data := page.Map()
+	data["Categories"] = categories
+	server.MustRender(w, r, "categories-page", data)
+}
+
+func categoryPageHandler(w http.ResponseWriter, r *http.Request, category Category, categories []Category, defaults []common.DataInput) {
+	authenticated, _ := auth.Authenticated(r)
+	username, _ := auth.UserSessionData(r)
+	page := templates.NewPageTemplate()
+	page.Title = category.PathLabel()
+	page.RequestOrigin = "Categories"
+	page.Authenticated = authenticated
+	page.User = username
+
+	// A category can't be moved into itself or one of its subcategories.
+	parents := []Category{}
+	for _, c := range categories {
+		if len(c.Path) < len(category.Path) || !slices.Equal(c.Path[:len(category.Path)], category.Path) {
+			parents = append(parents, c)
+		}
+	}
+
+	data := page.Map()
+	data["Category"] = category
+	data["Parents"] = parents
+	data["DefaultInputs"] = defaults
+	server.MustRender(w, r, "category-page", data)
+}
+
+// validCategory returns the category described by the form values "name", "parent_id" and "unit".
+// Spaces in the name and unit are collapsed. Writes an error and returns false if the category is invalid.
+func validCategory(w http.ResponseWriter, r *http.Request) (Category, bool) {
+	category := Category{
+		Name: strings.Join(strings.Fields(r.FormValue("name")), " "),
+		Unit: strings.Join(strings.Fields(r.FormValue("unit")), " "),
+	}
+	message := ""
+	if category.Name == "" {
+		message = "The category needs a name."
+	} else if utf8.RuneCountInString(category.Name) > maxNameLength {
+		message = "The name of a category can't be longer than 50 characters."
+	} else if strings.Contains(category.Name, `"`) {
+		message = "The name of a category can't contain quotes."
+	} else if utf8.RuneCountInString(category.Unit) > maxUnitLength {
+		message = "The unit can't be longer than 20 characters."
+	}
+	if parent := r.FormValue("parent_id"); message == "" && parent != "" {
+		parentID := validate.NewUUIDField(parent)
+		if parentID.IsValid() != nil {
+			message = "The parent category is invalid."
+		}
+		category.ParentID = parentID.UUID()
+	}
+	if message != "" {
+		writeInvalid(w, r, message)
+		return Category{}, false
+	}
+	return category, true
+}
+
+// validDefaults returns the default custom field values of items from the form.
+// Returns nil if the form has no custom field inputs. Writes an error and returns false if a value is invalid.
+func validDefaults(w http.ResponseWriter, r *http.Request) ([]common.CustomFieldValue, bool) {
+	fields, err := common.ParseCustomFields(r, "item")
+	if err != nil {
+		server.WriteInternalServerError("can't read the default values", err, w, r)
+		return nil, false
+	}
+	validator := validate.Validate{}
+	validator.ValidateCustomFields(fields)
+	if len(validator.Messages.CustomFieldErrors) > 0 {
+		var messages []string
+		for _, f := range fields {
+			if message := validator.Messages.CustomFieldErrors[f.ID]; message != "" {
+				messages = append(messages, message)
+			}
+		}
+		writeInvalid(w, r, strings.Join(messages, ". ")+".")
+		return nil, false
+	}
+	return common.CustomFieldValues(fields), true
+}
+
+func writeInvalid(w http.ResponseWriter, r *http.Request, message string) {
+	if server.WantsTemplateData(r) {
+		server.TriggerSingleErrorNotification(w, message)
+	} else {
+		server.WriteBadRequestError(message, nil, w, r)
+	}
+}
+
+func writeCategoryExists(w http.ResponseWriter, r *http.Request, category Category, err error) {
+	message := `The parent category already has a category named "` + category.Name + `".`
+	if server.WantsTemplateData(r) {
+		logg.Err(err)
+		server.TriggerSingleErrorNotification(w, message)
+		return
+	}
+	w.WriteHeader(http.StatusConflict)
+	server.WriteFprint(w, message)
+}
@@ -0,0 +1,52 @@
+package categories
+
+import (
+	"basement/main/internal/common"
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+var ErrCategoryExists = errors.New("category already exists")
+var ErrCategoryCycle = errors.New("category can't be moved into itself")
+
+type CategoryDatabase interface {
+	Categories(ctx context.Context) ([]Category, error)
+	Category(ctx context.Context, id uuid.UUID) (Category, error)
+	CreateCategory(ctx context.Context, category Category) (uuid.UUID, error)
+	UpdateCategory(ctx context.Context, category Category) error
+	DeleteCategory(ctx context.Context, id uuid.UUID) error
+	CategoryDefaults(ctx context.Context, id uuid.UUID) ([]common.CustomFieldValue, error)
+	SetCategoryDefaults(ctx context.Context, id uuid.UUID, defaults []common.CustomFieldValue) error
+}
+
+// Category of items. Categories form a tree, like Tools > Power Tools > Drills.
+type Category struct {
+	ID       uuid.UUID `json:"id"`
+	ParentID uuid.UUID `json:"parentId"` // uuid.Nil for root categories
+	Name     string    `json:"name"`
+	Unit     string    `json:"unit"` // unit hint for quantities, "" to use the unit of the parent
+
+	// Filled when reading categories.
+	Path          []string `json:"path"`          // names from the root category to this one
+	InheritedUnit string   `json:"inheritedUnit"` // Unit or the unit of the closest parent that has one
+	ItemCount     int      `json:"itemCount"`     // items in this category and all subcategories
+	TotalQuantity int64    `json:"totalQuantity"` // summed quantity of these items
+}
+
+// PathLabel returns the names from the root category to this one, like "Tools > Power Tools > Drills".
+func (c Category) PathLabel() string {
+	return strings.Join(c.Path, " > ")
+}
+
+// Depth returns 0 for root categories, 1 for their subcategories and so on.
+func (c Category) Depth() int {
+	return max(len(c.Path)-1, 0)
+}
+
+// ItemsURL returns the items page filtered by the category and its subcategories.
+func (c Category) ItemsURL() string {
+	return `/items?query=category:"` + c.Name + `"`
+}
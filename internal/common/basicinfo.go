@@ -4,6 +4,7 @@ import (
 	"basement/main/internal/logg"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gofrs/uuid/v5"
@@ -17,19 +18,28 @@ type BasicInfo struct {
 	Picture        string
 	PreviewPicture string
 	QRCode         string
+	Tags           []string           // nil keeps the current tags of the thing on updates
+	CustomFields   []CustomFieldValue // nil keeps the current values of the thing on updates
 }
 
 func (b BasicInfo) Map() map[string]any {
 	return map[string]any{
-		"ID":             b.ID,
-		"Label":          b.Label,
-		"Description":    b.Description,
-		"Picture":        b.Picture,
-		"PreviewPicture": b.PreviewPicture,
-		"QRCode":         b.QRCode,
+		"ID":                b.ID,
+		"Label":             b.Label,
+		"Description":       b.Description,
+		"Picture":           b.Picture,
+		"PreviewPicture":    b.PreviewPicture,
+		"QRCode":            b.QRCode,
+		"Tags":              b.Tags,
+		"CustomFieldInputs": b.CustomFieldInputs(),
 	}
 }
 
+// CustomFieldInputs returns the inputs of the "custom-field-inputs" template.
+func (b BasicInfo) CustomFieldInputs() []DataInput {
+	return CustomFieldInputs(b.CustomFields, nil)
+}
+
 func NewBasicInfo() BasicInfo {
 	return BasicInfo{ID: uuid.Must(uuid.NewV4())}.MakeLabelWithTime("thing")
 }
@@ -55,5 +65,40 @@ func BasicInfoFromPostFormValue(id uuid.UUID, r *http.Request, ignorePicture boo
 	}
 
 	info.QRCode = r.PostFormValue("qrcode")
+	info.Tags = ParseTags(r)
 	return info
 }
+
+// MAX_TAG_LENGTH is the maximum amount of characters of a tag name.
+const MAX_TAG_LENGTH = 50
+
+// ParseTags returns the comma separated tag names of the form value "tags".
+// Returns nil if the form has no "tags" value, so updates keep the current tags.
+// Names are trimmed and shortened to MAX_TAG_LENGTH, duplicates are removed ignoring case.
+// The form value of a thing without tags is an empty string.
+func ParseTags(r *http.Request) []string {
+	value := r.PostFormValue("tags")
+	if !r.PostForm.Has("tags") {
+		return nil
+	}
+	return SplitTags(value)
+}
+
+// SplitTags splits comma separated tag names, see ParseTags.
+func SplitTags(value string) []string {
+	tags := []string{}
+	seen := map[string]bool{}
+	for _, name := range strings.Split(value, ",") {
+		// Quotes are removed so every tag can be searched with tag:"name".
+		name = strings.Join(strings.Fields(strings.ReplaceAll(name, `"`, "")), " ")
+		if len([]rune(name)) > MAX_TAG_LENGTH {
+			name = string([]rune(name)[:MAX_TAG_LENGTH])
+		}
+		if name == "" || seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		tags = append(tags, name)
+	}
+	return tags
+}
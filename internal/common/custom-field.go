@@ -0,0 +1,121 @@
+package common
+
+import (
+	"basement/main/internal/logg"
+	"basement/main/internal/validate"
+	"context"
+	"net/http"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// CUSTOM_FIELDS_KEY is sent with every form that shows custom fields.
+// Unchecked checkboxes send nothing, so it tells an empty form apart from a form without custom fields.
+const CUSTOM_FIELDS_KEY = "custom-fields"
+
+// CUSTOM_FIELD_KEY_PREFIX is followed by the id of the field in the name of its form input.
+const CUSTOM_FIELD_KEY_PREFIX = "field:"
+
+// CustomField is a user defined field of a type of thing, like the serial number of items.
+type CustomField struct {
+	ID      uuid.UUID `json:"id"`
+	Thing   string    `json:"thing"` // "item", "box", "shelf" or "area"
+	Name    string    `json:"name"`
+	Type    string    `json:"type"`    // one of validate.FieldTypes
+	Options []string  `json:"options"` // choices of validate.FIELD_TYPE_ENUM
+}
+
+// CustomFieldValue is the value of a custom field of a single thing.
+// Value is "" if the thing has no value for the field.
+type CustomFieldValue struct {
+	CustomField
+	Value string `json:"value"`
+}
+
+// inputType returns the type of the HTML input of a field.
+func (f CustomField) inputType() string {
+	switch f.Type {
+	case validate.FIELD_TYPE_NUMBER:
+		return "number"
+	case validate.FIELD_TYPE_DATE:
+		return "date"
+	case validate.FIELD_TYPE_BOOLEAN:
+		return "checkbox"
+	case validate.FIELD_TYPE_ENUM:
+		return "select"
+	}
+	return "text"
+}
+
+// CustomFieldInputs returns the form inputs of values for the "custom-field-inputs" template.
+// errors are the validation messages by field id.
+func CustomFieldInputs(values []CustomFieldValue, errors map[string]string) []DataInput {
+	inputs := make([]DataInput, len(values))
+	for i, v := range values {
+		inputs[i] = DataInput{
+			Key:     CUSTOM_FIELD_KEY_PREFIX + v.ID.String(),
+			Value:   v.Value,
+			Label:   v.Name,
+			Type:    v.inputType(),
+			Options: v.Options,
+			Error:   errors[v.ID.String()],
+		}
+	}
+	return inputs
+}
+
+// WithCustomFieldInputs adds "CustomFieldInputs" to the form data of a validate.Validate,
+// so the custom fields are shown again with their input and validation errors.
+func WithCustomFieldInputs(data map[string]any) map[string]any {
+	fields, _ := data["CustomFields"].([]validate.CustomFieldValidate)
+	errors, _ := data["CustomFieldErrors"].(map[string]string)
+	data["CustomFieldInputs"] = CustomFieldInputs(CustomFieldValues(fields), errors)
+	return data
+}
+
+// ParseCustomFields returns the custom fields of thing from the form of r, ready to be validated.
+// Returns nil if the form has no custom fields, so updates keep the current values.
+func ParseCustomFields(r *http.Request, thing string) ([]validate.CustomFieldValidate, error) {
+	r.PostFormValue(CUSTOM_FIELDS_KEY)
+	if !r.PostForm.Has(CUSTOM_FIELDS_KEY) {
+		return nil, nil
+	}
+	fields, err := commonDB.CustomFields(r.Context(), thing)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	inputs := make([]validate.CustomFieldValidate, len(fields))
+	for i, f := range fields {
+		inputs[i] = validate.NewCustomField(f.ID.String(), f.Name, f.Type, f.Options, r.PostFormValue(CUSTOM_FIELD_KEY_PREFIX+f.ID.String()))
+	}
+	return inputs, nil
+}
+
+// CustomFieldValues returns validated custom fields in the form they are stored.
+// Returns nil if fields is nil.
+func CustomFieldValues(fields []validate.CustomFieldValidate) []CustomFieldValue {
+	if fields == nil {
+		return nil
+	}
+	values := make([]CustomFieldValue, len(fields))
+	for i, f := range fields {
+		values[i] = CustomFieldValue{
+			CustomField: CustomField{ID: uuid.FromStringOrNil(f.ID), Name: f.Name, Type: f.Type, Options: f.Options},
+			Value:       f.Value(),
+		}
+	}
+	return values
+}
+
+// EmptyCustomFieldValues returns all custom fields of thing without values, used for create forms.
+func EmptyCustomFieldValues(ctx context.Context, thing string) ([]CustomFieldValue, error) {
+	fields, err := commonDB.CustomFields(ctx, thing)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	values := make([]CustomFieldValue, len(fields))
+	for i, f := range fields {
+		values[i] = CustomFieldValue{CustomField: f}
+	}
+	return values, nil
+}
@@ -200,6 +200,7 @@ func (data *Data) GetSearchInputLabel() (string, error) {
 
 func (data *Data) SetSearchInputValue(value string) {
 	data.TypeMap["SearchInputValue"] = value
+	data.TypeMap["SearchInputError"] = SearchQueryErrorMessage(value)
 }
 
 func (data *Data) GetSearchInputValue() string {
@@ -5,6 +5,7 @@ import (
 	"basement/main/internal/logg"
 	"encoding/json"
 	"fmt"
+	"html/template"
 	"maps"
 
 	"github.com/gofrs/uuid/v5"
@@ -13,6 +14,7 @@ import (
 // ListRow is a single row entry used for list templates.
 type ListRow struct {
 	ID             uuid.UUID
+	Thing          string // "item", "box", "shelf" or "area"
 	Label          string
 	Description    string
 	BoxID          uuid.UUID
@@ -22,6 +24,11 @@ type ListRow struct {
 	AreaID         uuid.UUID
 	AreaLabel      string
 	PreviewPicture string
+	LabelHighlight template.HTML // Label with the matches of a search inside of <mark>.
+	Snippet        template.HTML // Part of the description with the matches of a search inside of <mark>.
+	Tags           []string
+	LentTo         string // borrower if the thing or a box it is in is lent out
+	LentUntil      string // due date of the loan
 
 	ListRowTemplateOptions
 }
@@ -29,6 +36,7 @@ type ListRow struct {
 func (row ListRow) Map() map[string]any {
 	m := map[string]interface{}{
 		"ID":             row.ID,
+		"Thing":          row.Thing,
 		"Label":          row.Label,
 		"Description":    row.Description,
 		"BoxID":          row.BoxID,
@@ -38,6 +46,11 @@ func (row ListRow) Map() map[string]any {
 		"AreaID":         row.AreaID,
 		"AreaLabel":      row.AreaLabel,
 		"PreviewPicture": row.PreviewPicture,
+		"LabelHighlight": row.LabelHighlight,
+		"Snippet":        row.Snippet,
+		"Tags":           row.Tags,
+		"LentTo":         row.LentTo,
+		"LentUntil":      row.LentUntil,
 	}
 	maps.Copy(row.ListRowTemplateOptions.Map(), m)
 	return m
@@ -3,8 +3,10 @@ package common
 import (
 	"basement/main/internal/env"
 	"basement/main/internal/logg"
+	"basement/main/internal/moves"
 	"basement/main/internal/server"
 	"basement/main/internal/templates"
+	"context"
 	"fmt"
 	"net/http"
 
@@ -67,6 +69,7 @@ func (tmpl ListTemplate) Map() map[string]any {
 		"SearchInput":          tmpl.SearchInput,
 		"SearchInputLabel":     tmpl.SearchInputLabel,
 		"SearchInputValue":     tmpl.SearchInputValue,
+		"SearchInputError":     tmpl.SearchInputError(),
 		"Pagination":           tmpl.Pagination,
 		"CurrentPageNumber":    tmpl.CurrentPageNumber,
 		"Limit":                tmpl.Limit,
@@ -88,6 +91,12 @@ func (tmpl ListTemplate) Map() map[string]any {
 	}
 }
 
+// SearchInputError returns why the search input can't be parsed, so it can be shown under the input.
+// It is a method so pages that render the ListTemplate itself instead of its Map can use it too.
+func (tmpl ListTemplate) SearchInputError() string {
+	return SearchQueryErrorMessage(tmpl.SearchInputValue)
+}
+
 func (tmpl ListTemplate) AddRowOptions(opts ListRowTemplateOptions) {
 	for i := range tmpl.Rows {
 		tmpl.Rows[i].ListRowTemplateOptions = opts
@@ -111,8 +120,12 @@ type listTemplate2 struct {
 }
 
 type DataInput struct {
-	Key   string
-	Value any
+	Key     string
+	Value   any
+	Label   string   // Label of a visible input, hidden inputs have none.
+	Type    string   // Type of a visible input: "text", "number", "date", "checkbox" or "select".
+	Options []string // Options of a "select" input.
+	Error   string   // Validation error shown above the input.
 }
 
 func (tmpl ListTemplate) Render(w http.ResponseWriter) error {
@@ -123,14 +136,14 @@ func (tmpl ListTemplate) Render(w http.ResponseWriter) error {
 //
 // listRowsFunc is a DB function like "db.BoxListRows()" and will be called like this internally:
 //
-//	rows, err := listRowsFunc(searchString, limit, count)
+//	rows, err := listRowsFunc(ctx, searchString, limit, count)
 //
 // count - The total number of records found from the search query.
-func FilledRows(listRowsFunc func(query string, limit int, page int) ([]ListRow, error), searchString string, limit int, pageNr int, count int, listRowOptions ListRowTemplateOptions) ([]ListRow, error) {
+func FilledRows(ctx context.Context, listRowsFunc func(ctx context.Context, query string, limit int, page int) ([]ListRow, error), searchString string, limit int, pageNr int, count int, listRowOptions ListRowTemplateOptions) ([]ListRow, error) {
 	filledRows := make([]ListRow, limit)
 
 	// Fetch the Records from the Database and pack it into map
-	rows, err := listRowsFunc(searchString, limit, pageNr)
+	rows, err := listRowsFunc(ctx, searchString, limit, pageNr)
 	if err != nil {
 		return nil, logg.WrapErr(err)
 	}
@@ -173,22 +186,23 @@ func PickerInputElements(iID string, iValue string, aID string, aHref string, aL
 
 // Database implements common Database functions across different things.
 type Database interface {
-	BoxListCounter(searchQuery string) (count int, err error)
-	ShelfListCounter(searchQuery string) (count int, err error)
-	AreaListCounter(searchQuery string) (count int, err error)
-	BoxListRows(searchQuery string, limit int, page int) ([]ListRow, error)
-	ShelfListRows(searchQuery string, limit int, page int) (shelfRows []ListRow, err error)
-	AreaListRows(searchQuery string, limit int, page int) (areaRows []ListRow, err error)
-	InnerListRowsFrom2(belongsToTable string, belongsToTableID uuid.UUID, listRowsTable string) ([]ListRow, error)
-	InnerListRowsPaginatedFrom(belongsToTable string, belongsToTableID uuid.UUID, listRowsTable string, searchQuery string, limit int, page int) (listRows []ListRow, err error)
-	InnerBoxInBoxListCounter(searchString string, inTable string, inTableID uuid.UUID) (count int, err error)
-	InnerShelfInTableListCounter(searchString string, inTable string, inTableID uuid.UUID) (count int, err error)
-	InnerThingInTableListCounter(searchString string, thing int, inTable string, inTableID uuid.UUID) (count int, err error)
-	DeleteItem(itemID uuid.UUID) error
-	DeleteBox(boxID uuid.UUID) error
-	DeleteShelf(id uuid.UUID) (label string, err error)
-	DeleteShelf2(id uuid.UUID) error
-	DeleteArea(areaID uuid.UUID) error
+	BoxListCounter(ctx context.Context, searchQuery string) (count int, err error)
+	ShelfListCounter(ctx context.Context, searchQuery string) (count int, err error)
+	AreaListCounter(ctx context.Context, searchQuery string) (count int, err error)
+	BoxListRows(ctx context.Context, searchQuery string, limit int, page int) ([]ListRow, error)
+	ShelfListRows(ctx context.Context, searchQuery string, limit int, page int) (shelfRows []ListRow, err error)
+	AreaListRows(ctx context.Context, searchQuery string, limit int, page int) (areaRows []ListRow, err error)
+	InnerListRowsFrom2(ctx context.Context, belongsToTable string, belongsToTableID uuid.UUID, listRowsTable string) ([]ListRow, error)
+	InnerListRowsPaginatedFrom(ctx context.Context, belongsToTable string, belongsToTableID uuid.UUID, listRowsTable string, searchQuery string, limit int, page int) (listRows []ListRow, err error)
+	InnerBoxInBoxListCounter(ctx context.Context, searchString string, inTable string, inTableID uuid.UUID) (count int, err error)
+	InnerShelfInTableListCounter(ctx context.Context, searchString string, inTable string, inTableID uuid.UUID) (count int, err error)
+	InnerThingInTableListCounter(ctx context.Context, searchString string, thing int, inTable string, inTableID uuid.UUID) (count int, err error)
+	DeleteItem(ctx context.Context, itemID uuid.UUID) error
+	DeleteBox(ctx context.Context, boxID uuid.UUID) error
+	DeleteShelf(ctx context.Context, id uuid.UUID) (label string, err error)
+	DeleteShelf2(ctx context.Context, id uuid.UUID) error
+	DeleteArea(ctx context.Context, areaID uuid.UUID) error
+	CustomFields(ctx context.Context, thing string) ([]CustomField, error)
 }
 
 // fromThingPage: From which page is this requested (THING_ITEM / THING_BOX / THING_SHELF)
@@ -316,19 +330,19 @@ func ListPageMovePicker(fromThingPage int, db Database) http.HandlerFunc {
 		switch moveToThing {
 		case THING_BOX:
 			rowHXGet = "/box"
-			count, err = db.BoxListCounter(searchString)
+			count, err = db.BoxListCounter(r.Context(), searchString)
 			break
 
 		case THING_SHELF:
 			rowHXGet = "/shelf"
-			count, err = db.ShelfListCounter(searchString)
+			count, err = db.ShelfListCounter(r.Context(), searchString)
 			listTmpl.HideBoxLabel = true
 			listTmpl.HideShelfLabel = true
 			break
 
 		case THING_AREA:
 			rowHXGet = "/area"
-			count, err = db.AreaListCounter(searchString)
+			count, err = db.AreaListCounter(r.Context(), searchString)
 			listTmpl.HideBoxLabel = true
 			listTmpl.HideShelfLabel = true
 			listTmpl.HideAreaLabel = true
@@ -357,13 +371,13 @@ func ListPageMovePicker(fromThingPage int, db Database) http.HandlerFunc {
 			}
 			switch moveTo {
 			case "box":
-				rows, err = FilledRows(db.BoxListRows, searchString, limit, page, count, rowOptions)
+				rows, err = FilledRows(r.Context(), db.BoxListRows, searchString, limit, page, count, rowOptions)
 				break
 			case "shelf":
-				rows, err = FilledRows(db.ShelfListRows, searchString, limit, page, count, rowOptions)
+				rows, err = FilledRows(r.Context(), db.ShelfListRows, searchString, limit, page, count, rowOptions)
 				break
 			case "area":
-				rows, err = FilledRows(db.AreaListRows, searchString, limit, page, count, rowOptions)
+				rows, err = FilledRows(r.Context(), db.AreaListRows, searchString, limit, page, count, rowOptions)
 				break
 			}
 
@@ -384,15 +398,42 @@ func ListPageMovePicker(fromThingPage int, db Database) http.HandlerFunc {
 	}
 }
 
-func ListPageMovePickerConfirm(DBMoveToThing func(thing1 uuid.UUID, thing2 uuid.UUID) error, redirectURL string) http.HandlerFunc {
+func ListPageMovePickerConfirm(DBMoveToThing func(ctx context.Context, thing1 uuid.UUID, thing2 uuid.UUID) error, redirectURL string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var notifications server.Notifications
-		notifications = server.MoveThingToThing(w, r, DBMoveToThing)
+		notifications = MoveThings(w, r, DBMoveToThing)
 		params := ListPageParams(r)
 		server.RedirectWithNotifications(w, redirectURL+params, notifications)
 	}
 }
 
+// MoveThings moves all selected things like server.MoveThingToThing as a single move operation.
+// If anything was moved, a notification with an "Undo" button for the whole operation is added.
+func MoveThings(w http.ResponseWriter, r *http.Request, moveFunc func(ctx context.Context, id1 uuid.UUID, id2 uuid.UUID) error) server.Notifications {
+	operationID, err := uuid.NewV4()
+	if err != nil {
+		logg.Err(err)
+		return server.MoveThingToThing(w, r, moveFunc)
+	}
+	r = r.WithContext(moves.WithOperation(r.Context(), operationID))
+	notifications := server.MoveThingToThing(w, r, moveFunc)
+
+	moved := 0
+	for _, n := range notifications.ServerNotificationEvents {
+		if n.Type == "success" {
+			moved++
+		}
+	}
+	if moved > 0 {
+		notifications.AddSuccessWithAction(fmt.Sprintf("moved %d things", moved), server.NotificationAction{
+			Label:  "Undo",
+			Method: http.MethodPost,
+			URL:    "/api/v1/moves/" + operationID.String() + "/undo",
+		})
+	}
+	return notifications
+}
+
 // THING_ITEM, THING_BOX, THING_SHELF, THING_AREA
 func ListTemplateInnerThingsFrom(innerThings int, from int, w http.ResponseWriter, r *http.Request) (listTmpl ListTemplate, err error) {
 	id := server.ValidID(w, r, "")
@@ -426,7 +467,7 @@ func ListTemplateInnerThingsFrom(innerThings int, from int, w http.ResponseWrite
 
 		// pagination
 		var count int
-		count, err = commonDB.InnerThingInTableListCounter(searchString, THING_ITEM, fromTable, id)
+		count, err = commonDB.InnerThingInTableListCounter(r.Context(), searchString, THING_ITEM, fromTable, id)
 		if err != nil {
 			return listTmpl, err
 		}
@@ -443,7 +484,7 @@ func ListTemplateInnerThingsFrom(innerThings int, from int, w http.ResponseWrite
 
 		if count > 0 {
 			// rows, err = commonDB.InnerListRowsFrom2(fromTable, id, "item_fts")
-			rows, err = commonDB.InnerListRowsPaginatedFrom(fromTable+"_fts", id, "item", searchString, limit, pageNr)
+			rows, err = commonDB.InnerListRowsPaginatedFrom(r.Context(), fromTable+"_fts", id, "item", searchString, limit, pageNr)
 
 			if err != nil {
 				server.WriteInternalServerError("cant query items", err, w, r)
@@ -479,7 +520,7 @@ func ListTemplateInnerThingsFrom(innerThings int, from int, w http.ResponseWrite
 
 		// pagination
 		var count int
-		count, err = commonDB.InnerThingInTableListCounter(searchString, THING_BOX, fromTable, id)
+		count, err = commonDB.InnerThingInTableListCounter(r.Context(), searchString, THING_BOX, fromTable, id)
 		if err != nil {
 			return listTmpl, err
 		}
@@ -498,7 +539,7 @@ func ListTemplateInnerThingsFrom(innerThings int, from int, w http.ResponseWrite
 			// boxes, err = FilledRows(boxDB.BoxListRows, searchString, limit, pageNr, count, ListRowTemplateOptions{RowHXGet: "/box"})
 			// logg.Debug("commonDB.InnerListRowsFrom2(" + fromTable + ", id, " + fromTable + "_fts)")
 			// boxes, err = commonDB.InnerListRowsFrom2(fromTable, id, "box_fts")
-			rows, err = commonDB.InnerListRowsPaginatedFrom(fromTable+"_fts", id, "box", searchString, limit, pageNr)
+			rows, err = commonDB.InnerListRowsPaginatedFrom(r.Context(), fromTable+"_fts", id, "box", searchString, limit, pageNr)
 
 			if err != nil {
 				server.WriteInternalServerError("cant query boxes", err, w, r)
@@ -531,7 +572,7 @@ func ListTemplateInnerThingsFrom(innerThings int, from int, w http.ResponseWrite
 		// pagination
 		var count int
 		// count, err = commonDB.InnerShelfInTableListCounter(searchString, fromTable, id)
-		count, err = commonDB.InnerThingInTableListCounter(searchString, THING_SHELF, fromTable, id)
+		count, err = commonDB.InnerThingInTableListCounter(r.Context(), searchString, THING_SHELF, fromTable, id)
 		if err != nil {
 			return listTmpl, err
 		}
@@ -548,7 +589,7 @@ func ListTemplateInnerThingsFrom(innerThings int, from int, w http.ResponseWrite
 		// rows, err = commonDB.InnerListRowsFrom2(fromTable, id, "shelf_fts")
 
 		if count > 0 {
-			rows, err = commonDB.InnerListRowsPaginatedFrom(fromTable+"_fts", id, "shelf", searchString, limit, pageNr)
+			rows, err = commonDB.InnerListRowsPaginatedFrom(r.Context(), fromTable+"_fts", id, "shelf", searchString, limit, pageNr)
 			if err != nil {
 				server.WriteInternalServerError("cant query shelfs", err, w, r)
 				return
@@ -584,7 +625,7 @@ func HandleListTemplateInnerThingsData(innerThings int, from int) http.HandlerFu
 			break
 
 		case http.MethodDelete:
-			var deleteFunc func(id uuid.UUID) error
+			var deleteFunc func(ctx context.Context, id uuid.UUID) error
 
 			switch innerThings {
 			case THING_ITEM:
@@ -6,16 +6,14 @@ import (
 	"fmt"
 	"math"
 	"net/http"
-	"regexp"
 	"strconv"
 	"strings"
 )
 
-// retrieve the query Value from the Request and filter, sanitize it
+// SearchString returns the search query of the request as it was typed by the user.
+// The database parses it with ParseSearchQuery, SearchQueryErrorMessage describes invalid syntax.
 func SearchString(r *http.Request) string {
-	searchTrimmed := strings.TrimSpace(r.FormValue("query"))
-	re := regexp.MustCompile(`\s+`)
-	return re.ReplaceAllString(searchTrimmed, "*")
+	return strings.TrimSpace(r.FormValue("query"))
 }
 
 // Generates pagination data for a given dataset.
@@ -158,15 +156,20 @@ func ParsePageNumber(r *http.Request) int {
 	return pageNr
 }
 
+// MAX_LIMIT is the most things a list or search returns at once.
+const MAX_LIMIT = 100
+
+// ParseLimit returns the "limit" of r, the default table size if it is missing or not positive
+// and at most MAX_LIMIT.
 func ParseLimit(r *http.Request) int {
 	limit, err := strconv.Atoi(r.FormValue("limit"))
 	if err != nil {
 		limit = env.CurrentConfig().DefaultTableSize()
 	}
-	if limit == 0 {
+	if limit < 1 {
 		limit = env.CurrentConfig().DefaultTableSize()
 	}
-	return limit
+	return min(limit, MAX_LIMIT)
 }
 
 func ParseOrigin(r *http.Request) (origin string) {
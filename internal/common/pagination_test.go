@@ -0,0 +1,31 @@
+package common
+
+import (
+	"basement/main/internal/env"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestParseLimit(t *testing.T) {
+	defaultSize := env.CurrentConfig().DefaultTableSize()
+	testCases := []struct {
+		name     string
+		query    string
+		expected int
+	}{
+		{"Missing", "", defaultSize},
+		{"Not A Number", "?limit=all", defaultSize},
+		{"Negative", "?limit=-5", defaultSize},
+		{"Valid", "?limit=25", 25},
+		{"Too Large", "?limit=100000", MAX_LIMIT},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/items"+tc.query, nil)
+			assert.Equal(t, ParseLimit(r), tc.expected)
+		})
+	}
+}
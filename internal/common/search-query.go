@@ -0,0 +1,261 @@
+package common
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Fields of a search query.
+const (
+	SEARCH_ANY         = "" // label, description or any location label
+	SEARCH_LABEL       = "label"
+	SEARCH_DESCRIPTION = "description"
+	SEARCH_BOX         = "box"
+	SEARCH_SHELF       = "shelf"
+	SEARCH_AREA        = "area"
+	SEARCH_TAG         = "tag"
+	SEARCH_CATEGORY    = "category"
+	SEARCH_QUANTITY    = "qty"
+	SEARCH_WEIGHT      = "weight"
+	SEARCH_CUSTOM      = "custom" // user defined field, see CustomField
+	SEARCH_SORT        = "sort"   // not a filter, sorts the results
+)
+
+// searchFieldAliases maps every name that can be typed in front of a filter to its field.
+var searchFieldAliases = map[string]string{
+	"label":       SEARCH_LABEL,
+	"description": SEARCH_DESCRIPTION,
+	"desc":        SEARCH_DESCRIPTION,
+	"box":         SEARCH_BOX,
+	"shelf":       SEARCH_SHELF,
+	"area":        SEARCH_AREA,
+	"tag":         SEARCH_TAG,
+	"tags":        SEARCH_TAG,
+	"category":    SEARCH_CATEGORY,
+	"cat":         SEARCH_CATEGORY,
+	"qty":         SEARCH_QUANTITY,
+	"quantity":    SEARCH_QUANTITY,
+	"weight":      SEARCH_WEIGHT,
+	"sort":        SEARCH_SORT,
+}
+
+// searchSortFields are the fields that results can be sorted by, besides custom fields.
+var searchSortFields = []string{SEARCH_LABEL, SEARCH_DESCRIPTION, SEARCH_BOX, SEARCH_SHELF, SEARCH_AREA, SEARCH_QUANTITY, SEARCH_WEIGHT}
+
+// searchOperators are ordered so that the longer operators are found first.
+var searchOperators = []string{">=", "<=", ":", "=", ">", "<"}
+
+// SearchQuery is a parsed search input like
+//
+//	drill box:garage area:"Basement" qty>2 -broken
+type SearchQuery struct {
+	Terms []SearchTerm
+}
+
+// SearchTerm is a single word, phrase or filter of a SearchQuery.
+type SearchTerm struct {
+	Field    string  // one of the SEARCH_ constants
+	Name     string  // custom field name of SEARCH_CUSTOM and SEARCH_SORT, spaces are written as "_"
+	Operator string  // ":" for text fields, "=", ">", ">=", "<" or "<=" for numeric and custom fields
+	Value    string  // text to look for, is never empty
+	Number   float64 // value of numeric fields
+	Phrase   bool    // Value was quoted and must match as a whole
+	Negated  bool    // things that match the term are excluded, sorts descending for SEARCH_SORT
+}
+
+// Numeric returns true if the term compares a number.
+func (t SearchTerm) Numeric() bool {
+	return t.Field == SEARCH_QUANTITY || t.Field == SEARCH_WEIGHT
+}
+
+// SortField returns the field a SEARCH_SORT term sorts by.
+// Returns SEARCH_CUSTOM for custom fields, their name is in Name.
+func (t SearchTerm) SortField() string {
+	field, ok := searchFieldAliases[strings.ToLower(t.Value)]
+	if ok && slices.Contains(searchSortFields, field) {
+		return field
+	}
+	return SEARCH_CUSTOM
+}
+
+// IsSearchField returns true if name is used by a built-in filter, so custom fields can't be named like it.
+func IsSearchField(name string) bool {
+	_, ok := searchFieldAliases[strings.ToLower(strings.ReplaceAll(name, " ", "_"))]
+	return ok
+}
+
+// CustomFieldFilterName returns how a custom field is named in search queries.
+func CustomFieldFilterName(name string) string {
+	return strings.ReplaceAll(name, " ", "_")
+}
+
+// SearchQueryError describes invalid syntax in a search query.
+// The message is meant to be shown to the user as is.
+type SearchQueryError struct {
+	Message string
+}
+
+func (e *SearchQueryError) Error() string {
+	return e.Message
+}
+
+func searchQueryErrorf(format string, a ...any) *SearchQueryError {
+	return &SearchQueryError{Message: fmt.Sprintf(format, a...)}
+}
+
+// ParseSearchQuery parses a search input.
+//
+// Words and "quoted phrases" match the label, description or any location label.
+// Filters only match a single field:
+//
+//	label:hammer description:"claw hammer" box:garage shelf:top area:basement tag:camping
+//	category:"power tools" qty>2 qty<=10 weight=1.5
+//
+// Tags and categories have to match the whole name, ignoring case.
+// Categories also match the items of their subcategories.
+// Every other filter name is a custom field, with spaces written as "_":
+//
+//	serial_number:AB12 voltage>=230 bought<2024-01-01
+//
+// "sort:" sorts by a field, "-sort:" sorts descending:
+//
+//	sort:label -sort:voltage
+//
+// A "-" in front of a word, phrase or filter excludes things that match it.
+// Words without letters or digits are ignored.
+// Returns a *SearchQueryError for invalid syntax.
+func ParseSearchQuery(s string) (SearchQuery, error) {
+	var q SearchQuery
+	rest := strings.TrimSpace(s)
+	for rest != "" {
+		var term SearchTerm
+		var keep bool
+		var err error
+		term, keep, rest, err = parseSearchTerm(rest)
+		if err != nil {
+			return SearchQuery{}, err
+		}
+		if keep {
+			q.Terms = append(q.Terms, term)
+		}
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+	}
+	return q, nil
+}
+
+// parseSearchTerm parses the term at the start of s and returns the unparsed rest.
+// keep is false for terms that can't match anything, like a single "-".
+func parseSearchTerm(s string) (term SearchTerm, keep bool, rest string, err error) {
+	if strings.HasPrefix(s, "-") {
+		term.Negated = true
+		s = s[1:]
+	}
+
+	// A filter starts with a field name directly followed by an operator.
+	// Names start with a letter, so times like 12:30 are words.
+	name := ""
+	if r, _ := utf8.DecodeRuneInString(s); unicode.IsLetter(r) {
+		name = s[:strings.IndexFunc(s+" ", func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '_' })]
+	}
+	afterName := s[len(name):]
+	for _, op := range searchOperators {
+		if name == "" || !strings.HasPrefix(afterName, op) {
+			continue
+		}
+		field, ok := searchFieldAliases[strings.ToLower(name)]
+		if !ok {
+			field = SEARCH_CUSTOM
+			term.Name = name
+		}
+		term.Field = field
+		term.Operator = op
+		s = afterName[len(op):]
+		break
+	}
+
+	term.Value, term.Phrase, rest, err = parseSearchValue(s)
+	if err != nil {
+		return term, false, "", err
+	}
+
+	if term.Field == SEARCH_ANY {
+		return term, containsWord(term.Value), rest, nil
+	}
+	example := term.Field + ":garage"
+	if term.Numeric() {
+		example = term.Field + ">2"
+	} else if term.Field == SEARCH_CATEGORY {
+		example = "category:tools"
+	} else if term.Field == SEARCH_CUSTOM {
+		example = name + ":value"
+	} else if term.Field == SEARCH_SORT {
+		example = "sort:label"
+	}
+	if term.Value == "" {
+		return term, false, "", searchQueryErrorf(`"%s%s" needs a value, for example %s.`, name, term.Operator, example)
+	}
+	if term.Field == SEARCH_CUSTOM {
+		term.Number, _ = strconv.ParseFloat(term.Value, 64)
+		return term, true, rest, nil
+	}
+	if term.Field == SEARCH_SORT {
+		if term.Operator != ":" {
+			return term, false, "", searchQueryErrorf(`"%s" can't be compared with "%s", use %s.`, name, term.Operator, example)
+		}
+		term.Name = CustomFieldFilterName(term.Value)
+		if IsSearchField(term.Name) && term.SortField() == SEARCH_CUSTOM {
+			return term, false, "", searchQueryErrorf(`Can't sort by "%s". Use label, description, box, shelf, area, qty, weight or a custom field.`, term.Value)
+		}
+		return term, true, rest, nil
+	}
+	if !term.Numeric() {
+		if term.Operator != ":" {
+			return term, false, "", searchQueryErrorf(`"%s" can't be compared with "%s", use %s.`, name, term.Operator, example)
+		}
+		return term, containsWord(term.Value), rest, nil
+	}
+
+	if term.Operator == ":" {
+		term.Operator = "="
+	}
+	term.Number, err = strconv.ParseFloat(term.Value, 64)
+	if err != nil || term.Phrase {
+		return term, false, "", searchQueryErrorf(`"%s" is not a number, for example %s.`, term.Value, example)
+	}
+	return term, true, rest, nil
+}
+
+// parseSearchValue parses a word or a quoted phrase at the start of s.
+func parseSearchValue(s string) (value string, phrase bool, rest string, err error) {
+	if !strings.HasPrefix(s, `"`) {
+		end := strings.IndexFunc(s, unicode.IsSpace)
+		if end == -1 {
+			return s, false, "", nil
+		}
+		return s[:end], false, s[end:], nil
+	}
+
+	end := strings.Index(s[1:], `"`)
+	if end == -1 {
+		return "", false, "", searchQueryErrorf(`The quote in %s is never closed.`, s)
+	}
+	return strings.TrimSpace(s[1 : end+1]), true, s[end+2:], nil
+}
+
+func containsWord(s string) bool {
+	return strings.IndexFunc(s, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsNumber(r) }) != -1
+}
+
+// SearchQueryErrorMessage returns the reason why search is not a valid search query.
+// Returns "" if it is valid.
+func SearchQueryErrorMessage(search string) string {
+	_, err := ParseSearchQuery(search)
+	if err != nil {
+		return err.Error()
+	}
+	return ""
+}
@@ -0,0 +1,74 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	query, err := ParseSearchQuery(`drill box:garage Area:"Basement" qty>2 -broken weight<=1.5 -`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, query.Terms, []SearchTerm{
+		{Field: SEARCH_ANY, Value: "drill"},
+		{Field: SEARCH_BOX, Operator: ":", Value: "garage"},
+		{Field: SEARCH_AREA, Operator: ":", Value: "Basement", Phrase: true},
+		{Field: SEARCH_QUANTITY, Operator: ">", Value: "2", Number: 2},
+		{Field: SEARCH_ANY, Value: "broken", Negated: true},
+		{Field: SEARCH_WEIGHT, Operator: "<=", Value: "1.5", Number: 1.5},
+	})
+
+	query, err = ParseSearchQuery(`quantity:3 desc:claw 12:30 -tag:"winter tires"`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, query.Terms, []SearchTerm{
+		{Field: SEARCH_QUANTITY, Operator: "=", Value: "3", Number: 3},
+		{Field: SEARCH_DESCRIPTION, Operator: ":", Value: "claw"},
+		{Field: SEARCH_ANY, Value: "12:30"},
+		{Field: SEARCH_TAG, Operator: ":", Value: "winter tires", Phrase: true, Negated: true},
+	})
+
+	query, err = ParseSearchQuery(`category:"Power Tools" -cat:drills`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, query.Terms, []SearchTerm{
+		{Field: SEARCH_CATEGORY, Operator: ":", Value: "Power Tools", Phrase: true},
+		{Field: SEARCH_CATEGORY, Operator: ":", Value: "drills", Negated: true},
+	})
+
+	query, err = ParseSearchQuery(`color:red voltage>=230 serial_number:"AB 12" -sort:voltage sort:"serial number" sort:qty`)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, query.Terms, []SearchTerm{
+		{Field: SEARCH_CUSTOM, Name: "color", Operator: ":", Value: "red"},
+		{Field: SEARCH_CUSTOM, Name: "voltage", Operator: ">=", Value: "230", Number: 230},
+		{Field: SEARCH_CUSTOM, Name: "serial_number", Operator: ":", Value: "AB 12", Phrase: true},
+		{Field: SEARCH_SORT, Name: "voltage", Operator: ":", Value: "voltage", Negated: true},
+		{Field: SEARCH_SORT, Name: "serial_number", Operator: ":", Value: "serial number", Phrase: true},
+		{Field: SEARCH_SORT, Name: "qty", Operator: ":", Value: "qty"},
+	})
+	assert.Equal(t, query.Terms[3].SortField(), SEARCH_CUSTOM)
+	assert.Equal(t, query.Terms[5].SortField(), SEARCH_QUANTITY)
+}
+
+func TestParseSearchQueryErrors(t *testing.T) {
+	testCases := []struct {
+		search   string
+		expected string
+	}{
+		{`area:"Basement`, `The quote in "Basement is never closed.`},
+		{"color:", `"color:" needs a value, for example color:value.`},
+		{"sort:tag", `Can't sort by "tag". Use label, description, box, shelf, area, qty, weight or a custom field.`},
+		{"sort>label", `"sort" can't be compared with ">", use sort:label.`},
+		{"box:", `"box:" needs a value, for example box:garage.`},
+		{"cat:", `"cat:" needs a value, for example category:tools.`},
+		{"qty>many", `"many" is not a number, for example qty>2.`},
+		{"label>2", `"label" can't be compared with ">", use label:garage.`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.search, func(t *testing.T) {
+			_, err := ParseSearchQuery(tc.search)
+			assert.NotEqual(t, err, nil)
+			assert.Equal(t, err.Error(), tc.expected)
+			assert.Equal(t, SearchQueryErrorMessage(tc.search), tc.expected)
+		})
+	}
+}
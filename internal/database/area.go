@@ -3,7 +3,10 @@ package database
 import (
 	"basement/main/internal/areas"
 	"basement/main/internal/common"
+	"basement/main/internal/env"
+	"basement/main/internal/history"
 	"basement/main/internal/logg"
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -40,15 +43,17 @@ func (s SQLArea) ToArea() (areas.Area, error) {
 	return area, nil
 }
 
-// Create New Item Record
-func (db *DB) CreateArea(newArea areas.Area) (uuid.UUID, error) {
-	exists := db.AreaExists(newArea.ID)
-
+// Create New Item Record owned by the user in ctx.
+func (db *DB) CreateArea(ctx context.Context, newArea areas.Area) (uuid.UUID, error) {
+	exists, err := db.idTaken("area", newArea.ID)
+	if err != nil {
+		return uuid.Nil, logg.WrapErr(err)
+	}
 	if exists {
 		return uuid.Nil, db.ErrorExist()
 	}
 
-	id, err := db.insertNewArea(newArea)
+	id, err := db.insertNewArea(ctx, newArea)
 	if err != nil {
 		return uuid.Nil, logg.Errorf("error while creating new Area: %v", err)
 	}
@@ -56,8 +61,12 @@ func (db *DB) CreateArea(newArea areas.Area) (uuid.UUID, error) {
 }
 
 // check if the Area Exist based on given Field
-func (db *DB) AreaExists(id uuid.UUID) bool {
-	exists, err := db.Exists("area", id)
+func (db *DB) AreaExists(ctx context.Context, id uuid.UUID) bool {
+	exists, err := db.Exists(ctx, "area", id)
+	if errors.Is(err, ErrNoOwner) {
+		logg.Err(err)
+		return false
+	}
 	if err != nil {
 		logg.Fatal(err.Error())
 	}
@@ -66,9 +75,13 @@ func (db *DB) AreaExists(id uuid.UUID) bool {
 }
 
 // AreaIDs returns IDs of all areas.
-func (db *DB) AreaIDs() (ids []uuid.UUID, err error) {
-	sqlStatement := `SELECT id FROM area`
-	rows, err := db.Sql.Query(sqlStatement)
+func (db *DB) AreaIDs(ctx context.Context) (ids []uuid.UUID, err error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return ids, logg.WrapErr(err)
+	}
+	sqlStatement := `SELECT id FROM area WHERE ` + OWNER_ID + ` = ? AND ` + NOT_DELETED
+	rows, err := db.Sql.Query(sqlStatement, owner)
 	if err != nil {
 		return ids, logg.Errorf("Error while executing Area ids: %w", err)
 	}
@@ -85,20 +98,38 @@ func (db *DB) AreaIDs() (ids []uuid.UUID, err error) {
 }
 
 // update area data
-func (db *DB) UpdateArea(area areas.Area, ignorePicture bool, pictureFormat string) error {
-	exist := db.AreaExists(area.ID)
+func (db *DB) UpdateArea(ctx context.Context, area areas.Area, ignorePicture bool, pictureFormat string) error {
+	exist := db.AreaExists(ctx, area.ID)
 	if !exist {
 		return logg.Errorf("the area does not exist")
 	}
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	tx, err := db.Sql.BeginTx(ctx, nil)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	defer tx.Rollback()
+
+	before, err := thingStateOf(ctx, tx, "area", area.ID)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
 
-	var err error
 	var stmt string
 	var result sql.Result
+	var picture, preview string
 	if ignorePicture {
-		stmt = "UPDATE area SET label = ?, description = ?, qrcode = ? WHERE id = ?"
-		result, err = db.Sql.Exec(stmt, area.Label, area.Description, area.QRCode, area.ID)
+		stmt = "UPDATE area SET label = ?, description = ?, qrcode = COALESCE(NULLIF(?, ''), qrcode) WHERE id = ? AND owner_id = ? AND " + NOT_DELETED
+		result, err = tx.ExecContext(ctx, stmt, area.Label, area.Description, area.QRCode, area.ID, owner)
 	} else {
-		area.PreviewPicture, err = ResizeImage(area.Picture, 50, pictureFormat)
+		area.Picture, err = stripMetadataBase64(area.Picture)
+		if err != nil {
+			return logg.Errorf("Error while removing the metadata of the picture of area '%s' %w", area.Label, err)
+		}
+		area.PreviewPicture, err = ResizeImage(area.Picture, env.CurrentConfig().PreviewPictureSide(), pictureFormat)
 		if err != nil {
 			if errors.Is(err, UnsupportedImageFormat) {
 				return logg.NewError(logg.CleanLastError(err) + err.Error())
@@ -106,8 +137,12 @@ func (db *DB) UpdateArea(area areas.Area, ignorePicture bool, pictureFormat stri
 				return logg.Errorf("Error while resizing picture of item '%s' to create a preview picture %w", area.Label, err)
 			}
 		}
-		stmt = "UPDATE area SET label = ?, description = ?, picture = ?, preview_picture = ?, qrcode = ? WHERE id = ?"
-		result, err = db.Sql.Exec(stmt, area.Label, area.Description, area.Picture, area.PreviewPicture, area.QRCode, area.ID)
+		picture, preview, err = storeBlobs(area.Picture, area.PreviewPicture)
+		if err != nil {
+			return logg.WrapErr(err)
+		}
+		stmt = "UPDATE area SET label = ?, description = ?, picture = ?, preview_picture = ?, qrcode = COALESCE(NULLIF(?, ''), qrcode) WHERE id = ? AND owner_id = ? AND " + NOT_DELETED
+		result, err = tx.ExecContext(ctx, stmt, area.Label, area.Description, picture, preview, area.QRCode, area.ID, owner)
 	}
 
 	if err != nil {
@@ -123,19 +158,37 @@ func (db *DB) UpdateArea(area areas.Area, ignorePicture bool, pictureFormat stri
 	} else if rowsAffected != 1 {
 		return logg.Errorf("the id: %s has an unexpected number of rows affected (more than one or less than 0)", area.ID.String())
 	}
-	return nil
+	if !ignorePicture {
+		err = updateCoverPicture(ctx, tx, "area", area.ID, picture, preview)
+		if err != nil {
+			return logg.WrapErr(err)
+		}
+	}
+	err = setTags(ctx, tx, "area", area.ID, area.Tags)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	err = setCustomFields(ctx, tx, "area", area.ID, area.CustomFields)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	err = recordHistory(ctx, tx, history.ACTION_UPDATE, "area", area.ID, before)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	return tx.Commit()
 }
 
 // delete Area
-func (db *DB) DeleteArea(areaId uuid.UUID) error {
+func (db *DB) DeleteArea(ctx context.Context, areaId uuid.UUID) error {
 	id := areaId.String()
 
-	areaExist := db.AreaExists(areaId)
+	areaExist := db.AreaExists(ctx, areaId)
 	if !areaExist {
 		return logg.Errorf(`the area with id="` + id + `" doesn't exist`)
 	}
 
-	err := db.deleteFrom("area", areaId)
+	err := db.deleteFrom(ctx, "area", areaId)
 	if err != nil {
 		return logg.WrapErr(err)
 	}
@@ -144,12 +197,12 @@ func (db *DB) DeleteArea(areaId uuid.UUID) error {
 
 // Get Area based on his ID
 // Wrapper function for AreaByField
-func (db *DB) AreaById(id uuid.UUID) (areas.Area, error) {
+func (db *DB) AreaById(ctx context.Context, id uuid.UUID) (areas.Area, error) {
 	area := areas.Area{}
-	if !db.AreaExists(id) {
+	if !db.AreaExists(ctx, id) {
 		return area, logg.Errorf("area is not exist \n")
 	}
-	area, err := db.areaByField("id", id.String())
+	area, err := db.areaByField(ctx, "id", id.String())
 	if err != nil {
 		return area, logg.WrapErr(err)
 	}
@@ -157,11 +210,15 @@ func (db *DB) AreaById(id uuid.UUID) (areas.Area, error) {
 }
 
 // Get Area based on given Field
-func (db *DB) areaByField(field string, value string) (areas.Area, error) {
+func (db *DB) areaByField(ctx context.Context, field string, value string) (areas.Area, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return areas.Area{}, logg.WrapErr(err)
+	}
 	var sqlArea SQLArea
-	stmt := "SELECT " + ALL_AREA_COLS + " FROM area WHERE " + field + " = ?;"
+	stmt := "SELECT " + ALL_AREA_COLS + " FROM area WHERE " + field + " = ? AND " + OWNER_ID + " = ? AND " + NOT_DELETED + ";"
 
-	err := db.Sql.QueryRow(stmt, value).Scan(sqlArea.RowsToScan()...)
+	err = db.Sql.QueryRow(stmt, value, owner).Scan(sqlArea.RowsToScan()...)
 	if err != nil {
 		return areas.Area{}, logg.WrapErr(err)
 	}
@@ -170,21 +227,50 @@ func (db *DB) areaByField(field string, value string) (areas.Area, error) {
 	if err != nil {
 		return areas.Area{}, logg.WrapErr(err)
 	}
+	area.Tags, err = db.tagsOf(ctx, "area", area.ID)
+	if err != nil {
+		return areas.Area{}, logg.WrapErr(err)
+	}
+	area.CustomFields, err = db.customFieldValues(ctx, "area", area.ID)
+	if err != nil {
+		return areas.Area{}, logg.WrapErr(err)
+	}
 
 	return area, nil
 }
 
 // insert new Area record in the Database
-func (db *DB) insertNewArea(area areas.Area) (uuid.UUID, error) {
-	if db.AreaExists(area.ID) {
+func (db *DB) insertNewArea(ctx context.Context, area areas.Area) (uuid.UUID, error) {
+	taken, err := db.idTaken("area", area.ID)
+	if err != nil {
+		return uuid.Nil, logg.WrapErr(err)
+	}
+	if taken {
 		return uuid.Nil, db.ErrorExist()
 	}
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return uuid.Nil, logg.WrapErr(err)
+	}
 
-	sqlStatement := "INSERT INTO area (" + ALL_AREA_COLS + ") VALUES (?,?,?,?,?,?)"
+	sqlStatement := "INSERT INTO area (" + ALL_AREA_COLS + "," + OWNER_ID + ") VALUES (?,?,?,?,?,?,?)"
 
-	updatePicture(&area.Picture, &area.PreviewPicture)
+	err = updatePicture(&area.Picture, &area.PreviewPicture)
+	if err != nil {
+		return uuid.Nil, logg.Errorf("Error while processing the picture of area '%s' %w", area.Label, err)
+	}
+	picture, preview, err := storeBlobs(area.Picture, area.PreviewPicture)
+	if err != nil {
+		return uuid.Nil, logg.WrapErr(err)
+	}
 
-	result, err := db.Sql.Exec(sqlStatement, area.ID.String(), area.Label, area.Description, area.Picture, area.PreviewPicture, area.QRCode)
+	tx, err := db.Sql.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, logg.WrapErr(err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, sqlStatement, area.ID.String(), area.Label, area.Description, picture, preview, qrCodeOrNew(area.QRCode), owner)
 	if err != nil {
 		return uuid.Nil, logg.Errorf("Error while executing create new area statement: %w", err)
 	}
@@ -196,13 +282,35 @@ func (db *DB) insertNewArea(area areas.Area) (uuid.UUID, error) {
 		return uuid.Nil, logg.Errorf("unexpected number of effected rows, check insirtNewArea")
 	}
 
+	if picture != "" {
+		err = updateCoverPicture(ctx, tx, "area", area.ID, picture, preview)
+		if err != nil {
+			return uuid.Nil, logg.WrapErr(err)
+		}
+	}
+	err = setTags(ctx, tx, "area", area.ID, area.Tags)
+	if err != nil {
+		return uuid.Nil, logg.WrapErr(err)
+	}
+	err = setCustomFields(ctx, tx, "area", area.ID, area.CustomFields)
+	if err != nil {
+		return uuid.Nil, logg.WrapErr(err)
+	}
+	err = recordHistory(ctx, tx, history.ACTION_CREATE, "area", area.ID, nil)
+	if err != nil {
+		return uuid.Nil, logg.WrapErr(err)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return uuid.Nil, logg.WrapErr(err)
+	}
 	return area.ID, nil
 }
 
 // AreaListRows retrieves virtual areas by label.
 // If the query is empty or contains only spaces, it returns default results.
-func (db *DB) AreaListRows(searchQuery string, limit int, page int) (listRows []common.ListRow, err error) {
-	listRows, err = db.listRowsPaginatedFrom("area_fts", searchQuery, limit, page)
+func (db *DB) AreaListRows(ctx context.Context, searchQuery string, limit int, page int) (listRows []common.ListRow, err error) {
+	listRows, err = db.listRowsPaginatedFrom(ctx, "area_fts", searchQuery, limit, page)
 	if err != nil {
 		return listRows, logg.WrapErr(err)
 	}
@@ -210,8 +318,8 @@ func (db *DB) AreaListRows(searchQuery string, limit int, page int) (listRows []
 }
 
 // Get the virtual Area based on his ID
-func (db *DB) AreaListRowByID(id uuid.UUID) (listRow common.ListRow, err error) {
-	exists := db.AreaExists(id)
+func (db *DB) AreaListRowByID(ctx context.Context, id uuid.UUID) (listRow common.ListRow, err error) {
+	exists := db.AreaExists(ctx, id)
 	if !exists {
 		return listRow, logg.NewError("the Area Id does not exsist in the virtual table")
 	}
@@ -234,19 +342,22 @@ func (db *DB) AreaListRowByID(id uuid.UUID) (listRow common.ListRow, err error)
 	if err != nil {
 		return common.ListRow{}, err
 	}
+	vArea.Thing = "area"
 	return *vArea, nil
 }
 
 // returns the count of rows in the area_fts table that match the specified searchString.
 // If queryString is empty, it returns the count of all rows in the table.
-func (db *DB) AreaListCounter(searchString string) (count int, err error) {
-	countQuery := `SELECT COUNT(*) FROM area_fts;`
-
-	if searchString != "" {
-		countQuery = ` SELECT COUNT(*) FROM area_fts WHERE label MATCH '` + searchString + `*'`
+func (db *DB) AreaListCounter(ctx context.Context, searchString string) (count int, err error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return 0, logg.WrapErr(err)
 	}
+	ownedBy, _ := ownerCondition("area_fts")
+	filter := newSearchFilter("area_fts", searchString, owner)
+	countQuery := `SELECT COUNT(*) FROM area_fts WHERE ` + filter.where(ownedBy) + `;`
 
-	err = db.Sql.QueryRow(countQuery).Scan(&count)
+	err = db.Sql.QueryRow(countQuery, filter.argsWith(owner)...).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("error while fetching the number of area from the database: %v", err)
 	}
@@ -254,6 +365,6 @@ func (db *DB) AreaListCounter(searchString string) (count int, err error) {
 }
 
 // check if the area row  exist
-func (db *DB) AreaRowExist(id uuid.UUID) (bool, error) {
-	return db.Exists("area_fts", id)
+func (db *DB) AreaRowExist(ctx context.Context, id uuid.UUID) (bool, error) {
+	return db.Exists(ctx, "area_fts", id)
 }
@@ -15,18 +15,18 @@ func TestCreateNewArea(t *testing.T) {
 	testArea := AREA_1
 
 	// Testing creation of a new area that does not already exist
-	_, err := dbTest.CreateArea(*testArea)
+	_, err := dbTest.CreateArea(testCtx, *testArea)
 	assert.Equal(t, nil, err)
 	if err != nil {
 		t.Fatalf("Failed to create new area: %v", err)
 	}
 
 	// Verify area was created
-	exists := dbTest.AreaExists(testArea.ID)
+	exists := dbTest.AreaExists(testCtx, testArea.ID)
 	assert.Equal(t, true, exists)
 
 	// Test creating the same area again to trigger an error
-	_, err = dbTest.CreateArea(*testArea)
+	_, err = dbTest.CreateArea(testCtx, *testArea)
 	assert.NotEqual(t, nil, err)
 }
 
@@ -38,13 +38,13 @@ func TestInsertNewArea(t *testing.T) {
 
 	// Step 2: Insert boxes
 	for _, area := range testAreas() {
-		_, err := dbTest.insertNewArea(area)
+		_, err := dbTest.insertNewArea(testCtx, area)
 		if err != nil {
 			t.Fatalf("insertNewArea failed: %v", err)
 		}
 	}
 
-	fetchedArea, err := dbTest.AreaById(testArea.ID)
+	fetchedArea, err := dbTest.AreaById(testCtx, testArea.ID)
 	if err != nil {
 		t.Fatalf(" the function AreaByfield not working properly : %v %v", err.Error(), testArea)
 	}
@@ -56,7 +56,7 @@ func TestInsertNewArea(t *testing.T) {
 
 	duplicateArea := *AREA_1
 
-	_, err = dbTest.insertNewArea(duplicateArea)
+	_, err = dbTest.insertNewArea(testCtx, duplicateArea)
 	if err == nil {
 		t.Errorf("Expected an error when inserting a area with an existing ID, got none")
 	}
@@ -67,10 +67,10 @@ func TestAreaByField(t *testing.T) {
 	resetAreas()
 
 	testArea := AREA_1
-	dbTest.insertNewArea(*testArea)
+	dbTest.insertNewArea(testCtx, *testArea)
 
 	// Testing retrieval by a field that should exist
-	fetchedArea, err := dbTest.AreaById(testArea.ID)
+	fetchedArea, err := dbTest.AreaById(testCtx, testArea.ID)
 	assert.Equal(t, err, nil)
 	if err != nil {
 		t.Fatalf("Failed to retrieve area by id: %v", err)
@@ -78,7 +78,7 @@ func TestAreaByField(t *testing.T) {
 	assert.Equal(t, fetchedArea.ID.String(), testArea.ID.String())
 
 	// Testing retrieval by a non-existent field
-	_, err = dbTest.areaByField("non_existent_field", "some_value")
+	_, err = dbTest.areaByField(testCtx, "non_existent_field", "some_value")
 	assert.NotEqual(t, err, nil)
 }
 
@@ -90,14 +90,14 @@ func TestAreaIDs(t *testing.T) {
 
 	// Insert test boxes into the database
 	for _, testArea := range testAreas() {
-		_, err := dbTest.insertNewArea(testArea)
+		_, err := dbTest.insertNewArea(testCtx, testArea)
 		if err != nil {
 			t.Fatalf("Failed to insert test area: %v", err)
 		}
 	}
 
 	// Call the AreaIDs function
-	actualIDs, err := dbTest.AreaIDs()
+	actualIDs, err := dbTest.AreaIDs(testCtx)
 	if err != nil {
 		t.Fatalf("AreaIDs function returned an error: %v", err)
 	}
@@ -113,7 +113,7 @@ func TestUpdateArea(t *testing.T) {
 	resetAreas()
 
 	testArea := AREA_1
-	_, err := dbTest.insertNewArea(*testArea)
+	_, err := dbTest.insertNewArea(testCtx, *testArea)
 	if err != nil {
 		t.Fatalf("error while inserting the area: %v", err)
 	}
@@ -126,18 +126,19 @@ func TestUpdateArea(t *testing.T) {
 	assert.NotEqual(t, oldDescr, testArea.Description)
 	assert.NotEqual(t, oldLabel, testArea.Label)
 
-	err = dbTest.UpdateArea(*testArea, false, "image/png")
+	err = dbTest.UpdateArea(testCtx, *testArea, false, "image/png")
 	assert.Equal(t, err, nil)
 
 	// Retrieve the updated area from the database
-	updatedArea, err := dbTest.AreaById(testArea.ID)
+	updatedArea, err := dbTest.AreaById(testCtx, testArea.ID)
 	assert.Equal(t, err, nil)
 
 	// Assert that the area was updated correctly (using individual asserts)
 	assert.Equal(t, testArea.Label, updatedArea.Label)
 	assert.Equal(t, testArea.Description, updatedArea.Description)
 	assert.Equal(t, testArea.Picture, updatedArea.Picture)
-	assert.Equal(t, testArea.QRCode, updatedArea.QRCode)
+	// the area got a QR code when it was created, the empty form value keeps it
+	assert.Equal(t, isPayload(updatedArea.QRCode), true)
 
 	assert.NotEqual(t, oldLabel, updatedArea.Label)
 	assert.NotEqual(t, oldDescr, updatedArea.Description)
@@ -150,14 +151,14 @@ func TestDeleteArea(t *testing.T) {
 	resetAreas()
 
 	for _, area := range testAreas() {
-		_, err := dbTest.insertNewArea(area)
+		_, err := dbTest.insertNewArea(testCtx, area)
 		if err != nil {
 			t.Fatalf("insertNewArea failed: %v", err)
 		}
 	}
 	var err error
 
-	err = dbTest.DeleteArea(AREA_1.ID)
+	err = dbTest.DeleteArea(testCtx, AREA_1.ID)
 	assert.Equal(t, err, nil)
 }
 
@@ -166,20 +167,20 @@ func TestAreaListRowByID(t *testing.T) {
 	resetAreas()
 
 	for _, area := range testAreas() {
-		_, err := dbTest.insertNewArea(area)
+		_, err := dbTest.insertNewArea(testCtx, area)
 		if err != nil {
 			t.Fatalf("insertNewArea failed: %v", err)
 		}
 	}
 	var err error
 
-	area_1, err := dbTest.AreaListRowByID(AREA_1.ID)
+	area_1, err := dbTest.AreaListRowByID(testCtx, AREA_1.ID)
 	assert.Equal(t, err, nil)
 	assert.Equal(t, area_1.ID, AREA_1.ID)
 	assert.Equal(t, area_1.Label, AREA_1.Label)
 	assert.Equal(t, area_1.Description, AREA_1.Description)
 
-	area_3, err := dbTest.AreaListRowByID(AREA_3.ID)
+	area_3, err := dbTest.AreaListRowByID(testCtx, AREA_3.ID)
 	assert.Equal(t, err, nil)
 	assert.Equal(t, area_3.ID, AREA_3.ID)
 	assert.Equal(t, area_3.Label, AREA_3.Label)
@@ -191,30 +192,30 @@ func TestAreaListCounter(t *testing.T) {
 	resetAreas()
 
 	for _, area := range testAreas() {
-		_, err := dbTest.insertNewArea(area)
+		_, err := dbTest.insertNewArea(testCtx, area)
 		if err != nil {
 			t.Fatalf("insertNewArea failed: %v", err)
 		}
 	}
 	var err error
 
-	count, err := dbTest.AreaListCounter("")
+	count, err := dbTest.AreaListCounter(testCtx, "")
 	assert.Equal(t, err, nil)
 	assert.Equal(t, count, 6)
 
-	count, err = dbTest.AreaListCounter("Area")
+	count, err = dbTest.AreaListCounter(testCtx, "Area")
 	assert.Equal(t, err, nil)
 	assert.Equal(t, count, 4)
 
-	count, err = dbTest.AreaListCounter("A")
+	count, err = dbTest.AreaListCounter(testCtx, "A")
 	assert.Equal(t, err, nil)
 	assert.Equal(t, count, 5)
 
-	count, err = dbTest.AreaListCounter("B")
+	count, err = dbTest.AreaListCounter(testCtx, "B")
 	assert.Equal(t, err, nil)
 	assert.Equal(t, count, 1)
 
-	count, err = dbTest.AreaListCounter("Test")
+	count, err = dbTest.AreaListCounter(testCtx, "Test")
 	assert.Equal(t, err, nil)
 	assert.Equal(t, count, 3)
 
@@ -222,7 +223,7 @@ func TestAreaListCounter(t *testing.T) {
 	// assert.Equal(t, err, nil)
 	// assert.Equal(t, count, 1)
 
-	count, err = dbTest.AreaListCounter("")
+	count, err = dbTest.AreaListCounter(testCtx, "")
 	assert.Equal(t, err, nil)
 	assert.Equal(t, count, 6)
 
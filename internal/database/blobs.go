@@ -0,0 +1,303 @@
+package database
+
+import (
+	"basement/main/internal/env"
+	"basement/main/internal/logg"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// blobGracePeriod protects blobs that were just written from the garbage collection,
+// because the row that refers to them might not be committed yet.
+const blobGracePeriod = time.Hour
+
+// blobColumns are the tables and columns that refer to blobs by their key.
+var blobColumns = map[string][]string{
+	"item":    {BASIC_INFO_PICTURE, BASIC_INFO_PREVIEW_PICTURE},
+	"box":     {BASIC_INFO_PICTURE, BASIC_INFO_PREVIEW_PICTURE},
+	"shelf":   {BASIC_INFO_PICTURE, BASIC_INFO_PREVIEW_PICTURE},
+	"area":    {BASIC_INFO_PICTURE, BASIC_INFO_PREVIEW_PICTURE},
+	"picture": {BASIC_INFO_PICTURE, BASIC_INFO_PREVIEW_PICTURE},
+}
+
+// storeBlob writes the base64 encoded picture into the blob directory and returns its key,
+// the hex encoded SHA-256 of the decoded bytes. A picture that is already stored isn't written again.
+// Returns "" for an empty picture.
+func storeBlob(picture string) (string, error) {
+	if picture == "" {
+		return "", nil
+	}
+	key, b, err := blobKey(picture)
+	if err != nil {
+		return "", logg.WrapErr(err)
+	}
+	path := blobFile(key)
+	if fileExists(path) {
+		// Refresh the time so the garbage collection doesn't remove it before the row is committed.
+		now := time.Now()
+		os.Chtimes(path, now, now)
+		return key, nil
+	}
+
+	err = writeBlobFile(path, b)
+	if err != nil {
+		return "", logg.WrapErr(err)
+	}
+	return key, nil
+}
+
+// blobKey returns the key storeBlob stores the base64 encoded picture with, and its decoded bytes.
+func blobKey(picture string) (string, []byte, error) {
+	b, err := Base64StringToByte(picture)
+	if err != nil {
+		return "", nil, logg.Errorf("invalid base64 in picture %w", err)
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), b, nil
+}
+
+// writeBlobFile writes b to path inside of the blob directory.
+// It writes next to path first so a reader never sees a partial file.
+func writeBlobFile(path string, b []byte) error {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return logg.Errorf(`can't create blob directory "%s" %w`, filepath.Dir(path), err)
+	}
+	// The name doesn't start with a key, so the garbage collection removes it if it is left behind.
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(b)
+	if err != nil {
+		tmp.Close()
+		return logg.Errorf(`can't write "%s" %w`, path, err)
+	}
+	err = tmp.Close()
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	err = os.Rename(tmp.Name(), path)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	return nil
+}
+
+// storeBlobs stores the picture and its preview and returns their keys.
+func storeBlobs(picture string, preview string) (string, string, error) {
+	pictureKey, err := storeBlob(picture)
+	if err != nil {
+		return "", "", logg.WrapErr(err)
+	}
+	previewKey, err := storeBlob(preview)
+	if err != nil {
+		return "", "", logg.WrapErr(err)
+	}
+	return pictureKey, previewKey, nil
+}
+
+// blobBase64 returns the blob with the key base64 encoded or "" if it doesn't exist.
+// Values that aren't a key are returned unchanged.
+func blobBase64(key string) string {
+	if !isBlobKey(key) {
+		return key
+	}
+	b, err := os.ReadFile(blobFile(key))
+	if err != nil {
+		logg.Errorf(`can't read blob "%s" %w`, key, err)
+		return ""
+	}
+	return ByteToBase64String(b)
+}
+
+// blobFromNullString is blobBase64 for a scanned column.
+func blobFromNullString(key sql.NullString) string {
+	return blobBase64(ifNullString(key))
+}
+
+// isBlobKey reports if s is a hex encoded SHA-256.
+func isBlobKey(s string) bool {
+	if len(s) != sha256.Size*2 {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// renditionFile returns the path of the picture with the key in a size that is made from it.
+func renditionFile(key string, size string) string {
+	return blobFile(key) + "." + size
+}
+
+// blobFile returns the path of the blob with the key.
+// Blobs are spread over directories named after the first two characters of their key.
+func blobFile(key string) string {
+	return filepath.Join(env.CurrentConfig().BlobPath(), key[:2], key)
+}
+
+// moveBlobs replaces the base64 encoded pictures in all blob columns with blob keys.
+// A dry run only computes the keys, the files are written when the migration is applied.
+func moveBlobs(tx *sql.Tx, dryRun bool) error {
+	for table, columns := range blobColumns {
+		for _, column := range columns {
+			rows, err := tx.Query(`SELECT rowid, ` + column + ` FROM ` + table + ` WHERE ` + column + ` IS NOT NULL AND ` + column + ` != '';`)
+			if err != nil {
+				return logg.Errorf(`can't read pictures of "%s" %w`, table, err)
+			}
+			keys := map[int64]string{}
+			for rows.Next() {
+				var rowid int64
+				var picture string
+				err = rows.Scan(&rowid, &picture)
+				if err != nil {
+					rows.Close()
+					return logg.WrapErr(err)
+				}
+				if isBlobKey(picture) {
+					continue
+				}
+				if dryRun {
+					keys[rowid], _, err = blobKey(picture)
+				} else {
+					keys[rowid], err = storeBlob(picture)
+				}
+				if err != nil {
+					// A broken picture is dropped instead of failing the migration.
+					logg.Errorf(`dropping picture of %s %d %w`, table, rowid, err)
+					keys[rowid] = ""
+				}
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return logg.WrapErr(err)
+			}
+			for rowid, key := range keys {
+				_, err = tx.Exec(`UPDATE `+table+` SET `+column+` = ? WHERE rowid = ?;`, key, rowid)
+				if err != nil {
+					return logg.Errorf(`can't replace picture of %s %d %w`, table, rowid, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// CollectBlobGarbage removes blobs that neither the database nor one of its snapshots refer to,
+// together with the sizes made from them. Blobs written within the last hour are kept.
+// Returns the amount of removed files.
+func (db *DB) CollectBlobGarbage() (int, error) {
+	referenced, err := blobKeys(db.Sql)
+	if err != nil {
+		return 0, logg.WrapErr(err)
+	}
+	// Restoring a snapshot must not lose its pictures.
+	snapshots, err := db.Backups()
+	if err != nil {
+		return 0, logg.WrapErr(err)
+	}
+	for _, s := range snapshots {
+		path := filepath.Join(env.CurrentConfig().BackupPath(), s.Name)
+		snapshot, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+		if err != nil {
+			return 0, logg.WrapErr(err)
+		}
+		keys, err := blobKeys(snapshot)
+		snapshot.Close()
+		if err != nil {
+			return 0, logg.Errorf(`can't read pictures of snapshot "%s" %w`, s.Name, err)
+		}
+		for key := range keys {
+			referenced[key] = true
+		}
+	}
+
+	removed := 0
+	deadline := time.Now().Add(-blobGracePeriod)
+	err = filepath.WalkDir(env.CurrentConfig().BlobPath(), func(path string, entry os.DirEntry, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		key, _, _ := strings.Cut(entry.Name(), ".")
+		if referenced[key] || info.ModTime().After(deadline) {
+			return nil
+		}
+		err = os.Remove(path)
+		if err != nil {
+			return err
+		}
+		removed++
+		return nil
+	})
+	if err != nil {
+		return removed, logg.WrapErr(err)
+	}
+	return removed, nil
+}
+
+// blobKeys returns the keys of all blobs the database refers to, including those of things in the trash.
+// Tables that don't exist, like in snapshots with an older schema, are skipped.
+func blobKeys(conn *sql.DB) (map[string]bool, error) {
+	keys := map[string]bool{}
+	for table, columns := range blobColumns {
+		exists, err := tableExists(conn, table)
+		if err != nil {
+			return nil, logg.WrapErr(err)
+		}
+		if !exists {
+			continue
+		}
+		for _, column := range columns {
+			rows, err := conn.Query(`SELECT DISTINCT ` + column + ` FROM ` + table + ` WHERE ` + column + ` IS NOT NULL;`)
+			if err != nil {
+				return nil, logg.Errorf(`can't read blob keys of "%s" %w`, table, err)
+			}
+			for rows.Next() {
+				var key string
+				err = rows.Scan(&key)
+				if err != nil {
+					rows.Close()
+					return nil, logg.WrapErr(err)
+				}
+				if isBlobKey(key) {
+					keys[key] = true
+				}
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return nil, logg.WrapErr(err)
+			}
+		}
+	}
+	return keys, nil
+}
+
+// tableExists reports if the database has the table.
+func tableExists(conn *sql.DB, table string) (bool, error) {
+	var name string
+	err := conn.QueryRow(`SELECT name FROM sqlite_master WHERE type = 'table' AND name = ?;`, table).Scan(&name)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, logg.WrapErr(err)
+	}
+	return true, nil
+}
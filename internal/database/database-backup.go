@@ -0,0 +1,265 @@
+package database
+
+import (
+	"basement/main/internal/backup"
+	"basement/main/internal/env"
+	"basement/main/internal/logg"
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"modernc.org/sqlite"
+)
+
+// Backup writes an online snapshot of the database into the configured backup directory.
+// It is safe while the server is running because "VACUUM INTO" reads a consistent state of the database.
+// Older snapshots are removed afterwards according to the configured retention.
+func (db *DB) Backup() (backup.Snapshot, error) {
+	snapshot, err := db.snapshot()
+	if err != nil {
+		return backup.Snapshot{}, logg.WrapErr(err)
+	}
+	err = db.pruneBackups(env.CurrentConfig().BackupRetention())
+	if err != nil {
+		return backup.Snapshot{}, logg.WrapErr(err)
+	}
+	return snapshot, nil
+}
+
+// snapshot writes an online snapshot of the database into the configured backup directory without pruning older ones.
+func (db *DB) snapshot() (backup.Snapshot, error) {
+	dir := env.CurrentConfig().BackupPath()
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return backup.Snapshot{}, logg.Errorf(`can't create backup directory "%s" %w`, dir, err)
+	}
+
+	created := time.Now()
+	name := backup.FileName(created)
+	path := filepath.Join(dir, name)
+	for fileExists(path) {
+		// Two snapshots within the same millisecond.
+		created = created.Add(time.Millisecond)
+		name = backup.FileName(created)
+		path = filepath.Join(dir, name)
+	}
+	_, err = db.Sql.Exec(`VACUUM INTO ?;`, path)
+	if err != nil {
+		return backup.Snapshot{}, logg.Errorf(`can't write snapshot "%s" %w`, path, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return backup.Snapshot{}, logg.WrapErr(err)
+	}
+	return backup.Snapshot{Name: name, Size: info.Size(), Created: created}, nil
+}
+
+// Backups returns all snapshots inside of the configured backup directory, the newest first.
+func (db *DB) Backups() ([]backup.Snapshot, error) {
+	dir := env.CurrentConfig().BackupPath()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, logg.WrapErr(err)
+	}
+
+	var snapshots []backup.Snapshot
+	for _, entry := range entries {
+		created, ok := backup.ParseFileName(entry.Name())
+		if !ok || entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, logg.WrapErr(err)
+		}
+		snapshots = append(snapshots, backup.Snapshot{Name: entry.Name(), Size: info.Size(), Created: created})
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Created.After(snapshots[j].Created)
+	})
+	return snapshots, nil
+}
+
+// pruneBackups removes all but the newest keep snapshots. keep=0 keeps all snapshots.
+func (db *DB) pruneBackups(keep int) error {
+	if keep == 0 {
+		return nil
+	}
+	snapshots, err := db.Backups()
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	for i := keep; i < len(snapshots); i++ {
+		err = os.Remove(filepath.Join(env.CurrentConfig().BackupPath(), snapshots[i].Name))
+		if err != nil {
+			return logg.WrapErr(err)
+		}
+	}
+	return nil
+}
+
+// ValidateBackup checks that the file at path is an intact SQLite database
+// with a schema that is not newer than the one known by this program.
+func ValidateBackup(path string) error {
+	_, err := os.Stat(path)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	snapshot, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	defer snapshot.Close()
+
+	var integrity string
+	err = snapshot.QueryRow(`PRAGMA integrity_check;`).Scan(&integrity)
+	if err != nil {
+		return logg.Errorf(`"%s" is not a database %w`, path, err)
+	}
+	if integrity != "ok" {
+		return logg.NewError(fmt.Sprintf(`integrity check of "%s" failed: %s`, path, integrity))
+	}
+
+	for table := range *mainTables {
+		var name string
+		err = snapshot.QueryRow(`SELECT name FROM sqlite_master WHERE type = 'table' AND name = ?;`, table).Scan(&name)
+		if err != nil {
+			return logg.Errorf(`table "%s" is missing in "%s" %w`, table, path, err)
+		}
+	}
+
+	var version int
+	err = snapshot.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_version;`).Scan(&version)
+	if err != nil {
+		return logg.Errorf(`can't read schema version of "%s" %w`, path, err)
+	}
+	latest := migrations[len(migrations)-1].version
+	if version > latest {
+		return logg.NewError(fmt.Sprintf(`schema version %d of "%s" is newer than the latest known version %d`, version, path, latest))
+	}
+	return nil
+}
+
+// RestoreBackup replaces the live database with the snapshot name from the backup directory.
+func (db *DB) RestoreBackup(name string) error {
+	if _, ok := backup.ParseFileName(name); !ok || filepath.Base(name) != name {
+		return logg.NewError(fmt.Sprintf(`"%s" is not a snapshot`, name))
+	}
+	return db.RestoreBackupFile(filepath.Join(env.CurrentConfig().BackupPath(), name))
+}
+
+// RestoreBackupFile replaces the live database with the snapshot at path.
+//
+// The snapshot is validated first. The current database is saved as a new snapshot
+// before it is replaced, so a restore can be undone by restoring that snapshot.
+// Older snapshots are only pruned after the restore, so even the oldest one can be restored.
+// The pages are copied with SQLite's backup API into the open database,
+// so requests running at the same time keep a valid connection.
+// Pending migrations are applied to the restored database.
+func (db *DB) RestoreBackupFile(path string) error {
+	err := ValidateBackup(path)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+
+	current, err := db.snapshot()
+	if err != nil {
+		return logg.Errorf("can't save the current database before restoring %w", err)
+	}
+	logg.Infof(`saved current database as "%s" before restoring "%s"`, current.Name, path)
+
+	err = db.restoreFrom(path)
+	if err != nil {
+		return logg.Errorf(`can't restore "%s" %w`, path, err)
+	}
+	applied, err := db.Migrate(false)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	for _, m := range applied {
+		logg.Infof(`applied migration %d "%s" to restored database`, m.Version, m.Name)
+	}
+	logg.Infof(`restored database from "%s"`, path)
+
+	err = db.pruneBackups(env.CurrentConfig().BackupRetention())
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	return nil
+}
+
+// restorer is implemented by the connections of the SQLite driver.
+type restorer interface {
+	NewRestore(srcUri string) (*sqlite.Backup, error)
+}
+
+// restoreFrom copies all pages of the database file at path into the open database.
+// SQLite locks the database while copying, other connections see the restored content afterwards.
+func (db *DB) restoreFrom(path string) error {
+	conn, err := db.Sql.Conn(context.Background())
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		r, ok := driverConn.(restorer)
+		if !ok {
+			return logg.NewError("the database driver doesn't support restoring snapshots")
+		}
+		restore, err := r.NewRestore("file:" + path + "?mode=ro")
+		if err != nil {
+			return logg.WrapErr(err)
+		}
+		_, err = restore.Step(-1)
+		if err != nil {
+			restore.Finish()
+			return logg.WrapErr(err)
+		}
+		return restore.Finish()
+	})
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// BackupEvery checks every interval if the newest snapshot is older than the configured
+// backup interval and writes a new snapshot if it is. The interval is read from the config on every run.
+// It never returns.
+func (db *DB) BackupEvery(interval time.Duration) {
+	for {
+		hours := env.CurrentConfig().BackupIntervalHours()
+		if hours > 0 && !env.CurrentConfig().UseMemoryDB() {
+			err := db.backupIfOlderThan(time.Duration(hours) * time.Hour)
+			if err != nil {
+				logg.Err(err)
+			}
+		}
+		time.Sleep(interval)
+	}
+}
+
+func (db *DB) backupIfOlderThan(age time.Duration) error {
+	snapshots, err := db.Backups()
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	if len(snapshots) > 0 && time.Since(snapshots[0].Created) < age {
+		return nil
+	}
+	snapshot, err := db.Backup()
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	logg.Infof(`wrote scheduled snapshot "%s"`, snapshot.Name)
+	return nil
+}
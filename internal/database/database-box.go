@@ -3,7 +3,10 @@ package database
 import (
 	"basement/main/internal/boxes"
 	"basement/main/internal/common"
+	"basement/main/internal/env"
+	"basement/main/internal/history"
 	"basement/main/internal/logg"
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -55,13 +58,12 @@ func (s *SQLBox) ToBox() (*boxes.Box, error) {
 	}, nil
 }
 
-// Create New Item Record
-func (db *DB) CreateBox(newBox *boxes.Box) (uuid.UUID, error) {
-	if db.BoxExistById(newBox.ID) {
+// Create New Item Record owned by the user in ctx.
+func (db *DB) CreateBox(ctx context.Context, newBox *boxes.Box) (uuid.UUID, error) {
+	id, err := db.insertNewBox(ctx, newBox)
+	if err == ErrExist {
 		return uuid.Nil, db.ErrorExist()
 	}
-
-	id, err := db.insertNewBox(newBox)
 	if err != nil {
 		return uuid.Nil, logg.Errorf("error while creating new Box: %v", err)
 	}
@@ -70,15 +72,20 @@ func (db *DB) CreateBox(newBox *boxes.Box) (uuid.UUID, error) {
 
 // check if the Box Exist based on Id
 // wrapper function for boxExist,
-func (db *DB) BoxExistById(id uuid.UUID) bool {
-	return db.BoxExist("id", id.String())
+func (db *DB) BoxExistById(ctx context.Context, id uuid.UUID) bool {
+	return db.BoxExist(ctx, "id", id.String())
 }
 
 // check if the Box Exist based on given Field
-func (db *DB) BoxExist(field string, value string) bool {
-	query := "SELECT COUNT(*) FROM box WHERE " + field + " = ?"
+func (db *DB) BoxExist(ctx context.Context, field string, value string) bool {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		logg.Err(err)
+		return false
+	}
+	query := "SELECT COUNT(*) FROM box WHERE " + field + " = ? AND " + OWNER_ID + " = ? AND " + NOT_DELETED
 	var count int
-	err := db.Sql.QueryRow(query, value).Scan(&count)
+	err = db.Sql.QueryRow(query, value, owner).Scan(&count)
 	if err != nil {
 		logg.Errf("Error checking item existence: %v", err)
 		return false
@@ -87,9 +94,13 @@ func (db *DB) BoxExist(field string, value string) bool {
 }
 
 // BoxIDs returns IDs of all boxes.
-func (db *DB) BoxIDs() (ids []uuid.UUID, err error) {
-	sqlStatement := `SELECT id FROM BOX`
-	rows, err := db.Sql.Query(sqlStatement)
+func (db *DB) BoxIDs(ctx context.Context) (ids []uuid.UUID, err error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return ids, logg.WrapErr(err)
+	}
+	sqlStatement := `SELECT id FROM BOX WHERE ` + OWNER_ID + ` = ? AND ` + NOT_DELETED
+	rows, err := db.Sql.Query(sqlStatement, owner)
 	if err != nil {
 		return ids, logg.Errorf("Error while executing Box ids: %w", err)
 	}
@@ -106,8 +117,8 @@ func (db *DB) BoxIDs() (ids []uuid.UUID, err error) {
 }
 
 // update box data
-func (db *DB) UpdateBox(box boxes.Box, ignorePicture bool, pictureFormat string) error {
-	exist := db.BoxExistById(box.ID)
+func (db *DB) UpdateBox(ctx context.Context, box boxes.Box, ignorePicture bool, pictureFormat string) error {
+	exist := db.BoxExistById(ctx, box.ID)
 	if !exist {
 		return logg.Errorf("the box does not exist")
 	}
@@ -115,15 +126,38 @@ func (db *DB) UpdateBox(box boxes.Box, ignorePicture bool, pictureFormat string)
 	if err != nil {
 		return logg.Errorf("Can't have \""+box.Label+"\" in itself %w", err)
 	}
+	err = db.ownedBoxContainers(ctx, box)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	tx, err := db.Sql.BeginTx(ctx, nil)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	defer tx.Rollback()
+
+	before, err := thingStateOf(ctx, tx, "box", box.ID)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
 
 	var stmt string
 	var result sql.Result
+	var picture, preview string
 	if ignorePicture {
-		stmt = "UPDATE box SET label = ?, description = ?, qrcode = ?, box_id = ?, shelf_id = ?, area_id = ? WHERE id = ?"
-		result, err = db.Sql.Exec(stmt, box.Label, box.Description, box.QRCode, box.OuterBoxID, box.ShelfID, box.AreaID, box.ID)
+		stmt = "UPDATE box SET label = ?, description = ?, qrcode = COALESCE(NULLIF(?, ''), qrcode), box_id = ?, shelf_id = ?, area_id = ? WHERE id = ? AND owner_id = ? AND " + NOT_DELETED
+		result, err = tx.ExecContext(ctx, stmt, box.Label, box.Description, box.QRCode, box.OuterBoxID, box.ShelfID, box.AreaID, box.ID, owner)
 	} else {
-		stmt = "UPDATE box SET label = ?, description = ?, picture = ?, preview_picture = ?, qrcode = ?, box_id = ?, shelf_id = ?, area_id = ? WHERE id = ?"
-		box.PreviewPicture, err = ResizeImage(box.Picture, 50, pictureFormat)
+		stmt = "UPDATE box SET label = ?, description = ?, picture = ?, preview_picture = ?, qrcode = COALESCE(NULLIF(?, ''), qrcode), box_id = ?, shelf_id = ?, area_id = ? WHERE id = ? AND owner_id = ? AND " + NOT_DELETED
+		box.Picture, err = stripMetadataBase64(box.Picture)
+		if err != nil {
+			return logg.Errorf("Error while removing the metadata of the picture of box '%s' %w", box.Label, err)
+		}
+		box.PreviewPicture, err = ResizeImage(box.Picture, env.CurrentConfig().PreviewPictureSide(), pictureFormat)
 		if err != nil {
 			if errors.Is(err, UnsupportedImageFormat) {
 				return logg.NewError(logg.CleanLastError(err) + err.Error())
@@ -131,7 +165,11 @@ func (db *DB) UpdateBox(box boxes.Box, ignorePicture bool, pictureFormat string)
 				return logg.Errorf("Error while resizing picture of box '%s' to create a preview picture %w", box.Label, err)
 			}
 		}
-		result, err = db.Sql.Exec(stmt, box.Label, box.Description, box.Picture, box.PreviewPicture, box.QRCode, box.OuterBoxID, box.ShelfID, box.AreaID, box.ID)
+		picture, preview, err = storeBlobs(box.Picture, box.PreviewPicture)
+		if err != nil {
+			return logg.WrapErr(err)
+		}
+		result, err = tx.ExecContext(ctx, stmt, box.Label, box.Description, picture, preview, box.QRCode, box.OuterBoxID, box.ShelfID, box.AreaID, box.ID, owner)
 	}
 
 	if err != nil {
@@ -147,21 +185,39 @@ func (db *DB) UpdateBox(box boxes.Box, ignorePicture bool, pictureFormat string)
 	} else if rowsAffected != 1 {
 		return logg.Errorf("the id: %s has an unexpected number of rows affected (more than one or less than 0)", box.ID.String())
 	}
-	return nil
+	if !ignorePicture {
+		err = updateCoverPicture(ctx, tx, "box", box.ID, picture, preview)
+		if err != nil {
+			return logg.WrapErr(err)
+		}
+	}
+	err = setTags(ctx, tx, "box", box.ID, box.Tags)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	err = setCustomFields(ctx, tx, "box", box.ID, box.CustomFields)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	err = recordHistory(ctx, tx, history.ACTION_UPDATE, "box", box.ID, before)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	return tx.Commit()
 }
 
 // delete Box
-func (db *DB) DeleteBox(boxId uuid.UUID) error {
+func (db *DB) DeleteBox(ctx context.Context, boxId uuid.UUID) error {
 	id := boxId.String()
 
 	// check if box is not Empty
-	itemExist := db.ItemExist("box_id", id)
-	boxExist := db.BoxExist("box_id", boxId.String())
+	itemExist := db.ItemExist(ctx, "box_id", id)
+	boxExist := db.BoxExist(ctx, "box_id", boxId.String())
 	if itemExist || boxExist {
 		return logg.Errorf(`the box with id="%s" is not empty`, id)
 	}
 
-	err := db.deleteFrom("box", boxId)
+	err := db.deleteFrom(ctx, "box", boxId)
 	if err != nil {
 		return logg.WrapErr(err)
 	}
@@ -170,12 +226,12 @@ func (db *DB) DeleteBox(boxId uuid.UUID) error {
 
 // Get Box based on his ID
 // Wrapper function for BoxByField
-func (db *DB) BoxById(id uuid.UUID) (boxes.Box, error) {
+func (db *DB) BoxById(ctx context.Context, id uuid.UUID) (boxes.Box, error) {
 	box := boxes.Box{}
-	if !db.BoxExistById(id) {
+	if !db.BoxExistById(ctx, id) {
 		return box, logg.Errorf("box does not exist \n")
 	}
-	b, err := db.BoxByField("id", id.String())
+	b, err := db.BoxByField(ctx, "id", id.String())
 	if err != nil {
 		return box, logg.WrapErr(err)
 	}
@@ -184,11 +240,15 @@ func (db *DB) BoxById(id uuid.UUID) (boxes.Box, error) {
 }
 
 // Get Box  based on given Field
-func (db *DB) BoxByField(field string, value string) (*boxes.Box, error) {
+func (db *DB) BoxByField(ctx context.Context, field string, value string) (*boxes.Box, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
 	var sqlBox SQLBox
 	stmt := fetchBoxQuery(true, field)
 
-	err := db.Sql.QueryRow(stmt, value).Scan(sqlBox.RowsToScan()...)
+	err = db.Sql.QueryRow(stmt, value, owner).Scan(sqlBox.RowsToScan()...)
 	if err != nil {
 		return nil, logg.WrapErr(err)
 	}
@@ -197,14 +257,22 @@ func (db *DB) BoxByField(field string, value string) (*boxes.Box, error) {
 	if err != nil {
 		return nil, logg.WrapErr(err)
 	}
+	box.Tags, err = db.tagsOf(ctx, "box", box.ID)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	box.CustomFields, err = db.customFieldValues(ctx, "box", box.ID)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
 
-	items, err := db.InnerListRowsFrom2("box", box.ID, "item_fts")
+	items, err := db.InnerListRowsFrom2(ctx, "box", box.ID, "item_fts")
 	if err != nil {
 		return nil, logg.WrapErr(err)
 	}
 	box.Items = items
 
-	boxes, err := db.InnerListRowsFrom2("box", box.ID, "box_fts")
+	boxes, err := db.InnerListRowsFrom2(ctx, "box", box.ID, "box_fts")
 	if err != nil {
 		return nil, logg.WrapErr(err)
 	}
@@ -212,7 +280,7 @@ func (db *DB) BoxByField(field string, value string) (*boxes.Box, error) {
 	logg.Debug(boxes)
 
 	if box.OuterBoxID != uuid.Nil {
-		outerbox, err := db.BoxListRowByID(box.OuterBoxID)
+		outerbox, err := db.BoxListRowByID(ctx, box.OuterBoxID)
 		if err != nil {
 			return nil, logg.WrapErr(err)
 		}
@@ -223,18 +291,43 @@ func (db *DB) BoxByField(field string, value string) (*boxes.Box, error) {
 }
 
 // insert new Box record in the Database
-func (db *DB) insertNewBox(box *boxes.Box) (uuid.UUID, error) {
-	if db.BoxExistById(box.ID) {
-		return uuid.Nil, db.ErrorExist()
+func (db *DB) insertNewBox(ctx context.Context, box *boxes.Box) (uuid.UUID, error) {
+	taken, err := db.idTaken("box", box.ID)
+	if err != nil {
+		return uuid.Nil, logg.WrapErr(err)
+	}
+	if taken {
+		return uuid.Nil, ErrExist
+	}
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return uuid.Nil, logg.WrapErr(err)
+	}
+	err = db.ownedBoxContainers(ctx, *box)
+	if err != nil {
+		return uuid.Nil, logg.WrapErr(err)
 	}
 
-	sqlStatement := "INSERT INTO box (" + ALL_BOX_COLS + ") VALUES (?,?,?,?,?,?,?,?,?)"
+	sqlStatement := "INSERT INTO box (" + ALL_BOX_COLS + "," + OWNER_ID + ") VALUES (?,?,?,?,?,?,?,?,?,?)"
 
-	updatePicture(&box.Picture, &box.PreviewPicture)
+	err = updatePicture(&box.Picture, &box.PreviewPicture)
+	if err != nil {
+		return uuid.Nil, logg.Errorf("Error while processing the picture of box '%s' %w", box.Label, err)
+	}
+	picture, preview, err := storeBlobs(box.Picture, box.PreviewPicture)
+	if err != nil {
+		return uuid.Nil, logg.WrapErr(err)
+	}
+
+	tx, err := db.Sql.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, logg.WrapErr(err)
+	}
+	defer tx.Rollback()
 
-	result, err := db.Sql.Exec(sqlStatement, box.ID.String(), box.Label, box.Description,
-		box.Picture, box.PreviewPicture, box.QRCode, box.OuterBoxID.String(),
-		box.ShelfID.String(), box.AreaID.String())
+	result, err := tx.ExecContext(ctx, sqlStatement, box.ID.String(), box.Label, box.Description,
+		picture, preview, qrCodeOrNew(box.QRCode), box.OuterBoxID.String(),
+		box.ShelfID.String(), box.AreaID.String(), owner)
 	if err != nil {
 		return uuid.Nil, logg.Errorf("Error while executing create new box statement: %w", err)
 	}
@@ -246,14 +339,49 @@ func (db *DB) insertNewBox(box *boxes.Box) (uuid.UUID, error) {
 		return uuid.Nil, logg.Errorf("unexpected number of effected rows, check insirtNewBox")
 	}
 
+	if picture != "" {
+		err = updateCoverPicture(ctx, tx, "box", box.ID, picture, preview)
+		if err != nil {
+			return uuid.Nil, logg.WrapErr(err)
+		}
+	}
+	err = setTags(ctx, tx, "box", box.ID, box.Tags)
+	if err != nil {
+		return uuid.Nil, logg.WrapErr(err)
+	}
+	err = setCustomFields(ctx, tx, "box", box.ID, box.CustomFields)
+	if err != nil {
+		return uuid.Nil, logg.WrapErr(err)
+	}
+	err = recordHistory(ctx, tx, history.ACTION_CREATE, "box", box.ID, nil)
+	if err != nil {
+		return uuid.Nil, logg.WrapErr(err)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return uuid.Nil, logg.WrapErr(err)
+	}
 	return box.ID, nil
 }
 
+// ownedBoxContainers returns an error if the outer box, shelf or area of box doesn't belong to the user in ctx.
+func (db *DB) ownedBoxContainers(ctx context.Context, box boxes.Box) error {
+	err := db.notOwnedByOthers(ctx, "box", box.OuterBoxID)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	err = db.notOwnedByOthers(ctx, "shelf", box.ShelfID)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	return db.notOwnedByOthers(ctx, "area", box.AreaID)
+}
+
 // MoveBoxToBox moves box1 to another box2.
 // To move box out of box2 set
 //
 //	box1 = uuid.Nil
-func (db *DB) MoveBoxToBox(box1 uuid.UUID, box2 uuid.UUID) error {
+func (db *DB) MoveBoxToBox(ctx context.Context, box1 uuid.UUID, box2 uuid.UUID) error {
 	// Check if toBoxID is inside boxID.
 	// Can't move if if this is the case.
 	stmt := "SELECT box_id FROM box WHERE id = ?;"
@@ -266,7 +394,7 @@ func (db *DB) MoveBoxToBox(box1 uuid.UUID, box2 uuid.UUID) error {
 		)
 	}
 
-	err := db.moveTo("box", box1, "box", box2)
+	err := db.moveTo(ctx, "box", box1, "box", box2)
 	if err != nil {
 		return logg.WrapErr(err)
 	}
@@ -277,8 +405,8 @@ func (db *DB) MoveBoxToBox(box1 uuid.UUID, box2 uuid.UUID) error {
 // To move box out of a shelf set
 //
 //	toShelfID = uuid.Nil
-func (db *DB) MoveBoxToShelf(boxID uuid.UUID, toShelfID uuid.UUID) error {
-	err := db.moveTo("box", boxID, "shelf", toShelfID)
+func (db *DB) MoveBoxToShelf(ctx context.Context, boxID uuid.UUID, toShelfID uuid.UUID) error {
+	err := db.moveTo(ctx, "box", boxID, "shelf", toShelfID)
 	if err != nil {
 		return logg.WrapErr(err)
 	}
@@ -289,8 +417,8 @@ func (db *DB) MoveBoxToShelf(boxID uuid.UUID, toShelfID uuid.UUID) error {
 // To move box out of an area set
 //
 //	toAreaID = uuid.Nil
-func (db *DB) MoveBoxToArea(boxID uuid.UUID, toAreaID uuid.UUID) error {
-	err := db.moveTo("box", boxID, "area", toAreaID)
+func (db *DB) MoveBoxToArea(ctx context.Context, boxID uuid.UUID, toAreaID uuid.UUID) error {
+	err := db.moveTo(ctx, "box", boxID, "area", toAreaID)
 	if err != nil {
 		return logg.WrapErr(err)
 	}
@@ -299,8 +427,8 @@ func (db *DB) MoveBoxToArea(boxID uuid.UUID, toAreaID uuid.UUID) error {
 
 // BoxListRows retrieves virtual boxes by label.
 // If the query is empty or contains only spaces, it returns default results.
-func (db *DB) BoxListRows(searchQuery string, limit int, page int) (listRows []common.ListRow, err error) {
-	listRows, err = db.listRowsPaginatedFrom("box_fts", searchQuery, limit, page)
+func (db *DB) BoxListRows(ctx context.Context, searchQuery string, limit int, page int) (listRows []common.ListRow, err error) {
+	listRows, err = db.listRowsPaginatedFrom(ctx, "box_fts", searchQuery, limit, page)
 	if err != nil {
 		return listRows, logg.WrapErr(err)
 	}
@@ -308,16 +436,16 @@ func (db *DB) BoxListRows(searchQuery string, limit int, page int) (listRows []c
 }
 
 // Get the virtual Box based on his ID
-func (db *DB) BoxListRowByID(id uuid.UUID) (common.ListRow, error) {
-	row, err := db.listRowByID("box_fts", id)
+func (db *DB) BoxListRowByID(ctx context.Context, id uuid.UUID) (common.ListRow, error) {
+	row, err := db.listRowByID(ctx, "box_fts", id)
 	if err != nil {
 		return row, logg.WrapErr(err)
 	}
 	return row, nil
 }
 
-func (db *DB) InnerBoxListRows(id uuid.UUID) (listRows []common.ListRow, err error) {
-	listRows, err = db.InnerListRowsFrom2("box", id, "box_fts")
+func (db *DB) InnerBoxListRows(ctx context.Context, id uuid.UUID) (listRows []common.ListRow, err error) {
+	listRows, err = db.InnerListRowsFrom2(ctx, "box", id, "box_fts")
 	if err != nil {
 		return listRows, logg.WrapErr(err)
 	}
@@ -326,14 +454,16 @@ func (db *DB) InnerBoxListRows(id uuid.UUID) (listRows []common.ListRow, err err
 
 // returns the count of rows in the box_fts table that match the specified searchString.
 // If queryString is empty, it returns the count of all rows in the table.
-func (db *DB) BoxListCounter(searchString string) (count int, err error) {
-	countQuery := `SELECT COUNT(*) FROM box_fts;`
-
-	if searchString != "" {
-		countQuery = ` SELECT COUNT(*) FROM box_fts WHERE label MATCH '` + searchString + `*'`
+func (db *DB) BoxListCounter(ctx context.Context, searchString string) (count int, err error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return 0, logg.WrapErr(err)
 	}
+	ownedBy, _ := ownerCondition("box_fts")
+	filter := newSearchFilter("box_fts", searchString, owner)
+	countQuery := `SELECT COUNT(*) FROM box_fts WHERE ` + filter.where(ownedBy) + `;`
 
-	err = db.Sql.QueryRow(countQuery).Scan(&count)
+	err = db.Sql.QueryRow(countQuery, filter.argsWith(owner)...).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("error while fetching the number of box from the database: %v", err)
 	}
@@ -342,14 +472,16 @@ func (db *DB) BoxListCounter(searchString string) (count int, err error) {
 
 // returns the count of rows in the box_fts table that match the specified searchString.
 // If queryString is empty, it returns the count of all rows in the table.
-func (db *DB) InnerBoxInBoxListCounter(searchString string, inTable string, inTableID uuid.UUID) (count int, err error) {
-	countQuery := `SELECT COUNT(*) FROM box_fts WHERE ` + inTable + `_id = ?;`
-
-	if searchString != "" {
-		countQuery = ` SELECT COUNT(*) FROM box_fts WHERE label MATCH '` + searchString + `*' AND ` + inTable + `_id = ?`
+func (db *DB) InnerBoxInBoxListCounter(ctx context.Context, searchString string, inTable string, inTableID uuid.UUID) (count int, err error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return 0, logg.WrapErr(err)
 	}
+	ownedBy, _ := ownerCondition("box_fts")
+	filter := newSearchFilter("box_fts", searchString, owner)
+	countQuery := `SELECT COUNT(*) FROM box_fts WHERE ` + filter.where(inTable+"_id = ?", ownedBy) + `;`
 
-	err = db.Sql.QueryRow(countQuery, inTableID.String()).Scan(&count)
+	err = db.Sql.QueryRow(countQuery, filter.argsWith(inTableID.String(), owner)...).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("error while fetching the number of box from the database: %v", err)
 	}
@@ -357,6 +489,6 @@ func (db *DB) InnerBoxInBoxListCounter(searchString string, inTable string, inTa
 }
 
 // check if the box row  exist
-func (db *DB) BoxRowExist(id uuid.UUID) (bool, error) {
-	return db.Exists("box_fts", id)
+func (db *DB) BoxRowExist(ctx context.Context, id uuid.UUID) (bool, error) {
+	return db.Exists(ctx, "box_fts", id)
 }
@@ -0,0 +1,458 @@
+package database
+
+import (
+	"basement/main/internal/categories"
+	"basement/main/internal/common"
+	"basement/main/internal/logg"
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// Categories returns all categories of the household in tree order:
+// every category is followed by its subcategories, siblings are sorted by name.
+func (db *DB) Categories(ctx context.Context) ([]categories.Category, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	rows, err := db.Sql.QueryContext(ctx, `SELECT id, COALESCE(parent_id, ''), name, unit FROM category
+		WHERE `+OWNER_ID+` = ? ORDER BY name COLLATE NOCASE;`, owner)
+	if err != nil {
+		return nil, logg.Errorf("can't query categories %w", err)
+	}
+	defer rows.Close()
+
+	var list []categories.Category
+	for rows.Next() {
+		var id, parentID string
+		var category categories.Category
+		err := rows.Scan(&id, &parentID, &category.Name, &category.Unit)
+		if err != nil {
+			return nil, logg.WrapErr(err)
+		}
+		category.ID = uuid.FromStringOrNil(id)
+		category.ParentID = uuid.FromStringOrNil(parentID)
+		list = append(list, category)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, logg.WrapErr(err)
+	}
+
+	counts, err := db.categoryItemCounts(ctx)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	return categoryTree(list, counts), nil
+}
+
+// categoryCount is the amount and summed quantity of the items directly in a category.
+type categoryCount struct {
+	items    int
+	quantity int64
+}
+
+func (db *DB) categoryItemCounts(ctx context.Context) (map[uuid.UUID]categoryCount, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	stmt := `SELECT ` + ITEM_CATEGORY_ID + `, COUNT(*), COALESCE(SUM(` + ITEM_QUANTITY + `), 0) FROM item
+		WHERE ` + OWNER_ID + ` = ? AND ` + NOT_DELETED + ` AND ` + ITEM_CATEGORY_ID + ` IS NOT NULL
+		GROUP BY ` + ITEM_CATEGORY_ID + `;`
+	rows, err := db.Sql.QueryContext(ctx, stmt, owner)
+	if err != nil {
+		return nil, logg.Errorf("can't count items of categories %w", err)
+	}
+	defer rows.Close()
+
+	counts := map[uuid.UUID]categoryCount{}
+	for rows.Next() {
+		var id string
+		var count categoryCount
+		err := rows.Scan(&id, &count.items, &count.quantity)
+		if err != nil {
+			return nil, logg.WrapErr(err)
+		}
+		counts[uuid.FromStringOrNil(id)] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	return counts, nil
+}
+
+// categoryTree sorts list, which is sorted by name, in tree order and fills the path, inherited unit and counts.
+// Categories with a parent that doesn't exist are treated as root categories.
+func categoryTree(list []categories.Category, counts map[uuid.UUID]categoryCount) []categories.Category {
+	exists := map[uuid.UUID]bool{}
+	for _, category := range list {
+		exists[category.ID] = true
+	}
+	children := map[uuid.UUID][]categories.Category{}
+	for _, category := range list {
+		parent := category.ParentID
+		if !exists[parent] {
+			parent = uuid.Nil
+		}
+		children[parent] = append(children[parent], category)
+	}
+
+	tree := make([]categories.Category, 0, len(list))
+	// add appends category and its subcategories to tree and returns the item count and quantity of all of them.
+	var add func(category categories.Category, path []string, unit string) categoryCount
+	add = func(category categories.Category, path []string, unit string) categoryCount {
+		category.Path = append(path[:len(path):len(path)], category.Name)
+		category.InheritedUnit = unit
+		if category.Unit != "" {
+			category.InheritedUnit = category.Unit
+		}
+		index := len(tree)
+		tree = append(tree, category)
+
+		total := counts[category.ID]
+		for _, child := range children[category.ID] {
+			count := add(child, category.Path, category.InheritedUnit)
+			total.items += count.items
+			total.quantity += count.quantity
+		}
+		tree[index].ItemCount = total.items
+		tree[index].TotalQuantity = total.quantity
+		return total
+	}
+	for _, root := range children[uuid.Nil] {
+		add(root, nil, "")
+	}
+	return tree
+}
+
+// Category returns the category with id together with its path, unit and counts.
+func (db *DB) Category(ctx context.Context, id uuid.UUID) (categories.Category, error) {
+	list, err := db.Categories(ctx)
+	if err != nil {
+		return categories.Category{}, logg.WrapErr(err)
+	}
+	for _, category := range list {
+		if category.ID == id {
+			return category, nil
+		}
+	}
+	return categories.Category{}, logg.Errorf(`category "%s" %w`, id, ErrNotExist)
+}
+
+// categoryOwned returns an error if the category with id isn't one of the household.
+// uuid.Nil means no category and is always fine.
+func (db *DB) categoryOwned(ctx context.Context, id uuid.UUID) error {
+	if id == uuid.Nil {
+		return nil
+	}
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	var exists int
+	err = db.Sql.QueryRowContext(ctx, `SELECT COUNT(*) FROM category WHERE id = ? AND `+OWNER_ID+` = ?;`, id.String(), owner).Scan(&exists)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	if exists == 0 {
+		return logg.Errorf(`category "%s" %w`, id, ErrNotExist)
+	}
+	return nil
+}
+
+// siblingNamed returns an error if another category with the same parent already has the name, ignoring case.
+func (db *DB) siblingNamed(ctx context.Context, category categories.Category) error {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	var taken int
+	stmt := `SELECT COUNT(*) FROM category WHERE ` + OWNER_ID + ` = ? AND COALESCE(parent_id, '') = ? AND name = ? COLLATE NOCASE AND id != ?;`
+	err = db.Sql.QueryRowContext(ctx, stmt, owner, nullUUID(category.ParentID).String, category.Name, category.ID.String()).Scan(&taken)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	if taken > 0 {
+		return logg.Errorf(`"%s" %w`, category.Name, categories.ErrCategoryExists)
+	}
+	return nil
+}
+
+// nullUUID returns NULL for uuid.Nil.
+func nullUUID(id uuid.UUID) sql.NullString {
+	if id == uuid.Nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: id.String(), Valid: true}
+}
+
+// CreateCategory adds a category below category.ParentID, or a root category if it is uuid.Nil.
+// Returns categories.ErrCategoryExists if the parent already has a category with this name, ignoring case.
+func (db *DB) CreateCategory(ctx context.Context, category categories.Category) (uuid.UUID, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return uuid.Nil, logg.WrapErr(err)
+	}
+	err = db.categoryOwned(ctx, category.ParentID)
+	if err != nil {
+		return uuid.Nil, logg.WrapErr(err)
+	}
+	category.ID, err = uuid.NewV4()
+	if err != nil {
+		return uuid.Nil, logg.WrapErr(err)
+	}
+	err = db.siblingNamed(ctx, category)
+	if err != nil {
+		return uuid.Nil, logg.WrapErr(err)
+	}
+	_, err = db.Sql.ExecContext(ctx, `INSERT INTO category (id, owner_id, parent_id, name, unit, created_at) VALUES (?, ?, ?, ?, ?, ?);`,
+		category.ID.String(), owner, nullUUID(category.ParentID), category.Name, category.Unit, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return uuid.Nil, logg.Errorf(`can't create category "%s" %w`, category.Name, err)
+	}
+	return category.ID, nil
+}
+
+// UpdateCategory changes the name, parent and unit of a category.
+// Returns categories.ErrCategoryCycle if the new parent is the category itself or one of its subcategories.
+func (db *DB) UpdateCategory(ctx context.Context, category categories.Category) error {
+	list, err := db.Categories(ctx)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	byID := map[uuid.UUID]categories.Category{}
+	for _, c := range list {
+		byID[c.ID] = c
+	}
+	if _, ok := byID[category.ID]; !ok {
+		return logg.Errorf(`category "%s" %w`, category.ID, ErrNotExist)
+	}
+	if category.ParentID != uuid.Nil {
+		parent, ok := byID[category.ParentID]
+		if !ok {
+			return logg.Errorf(`category "%s" %w`, category.ParentID, ErrNotExist)
+		}
+		if parent.ID == category.ID || isBelow(byID, parent.ID, category.ID) {
+			return logg.WrapErr(categories.ErrCategoryCycle)
+		}
+	}
+	err = db.siblingNamed(ctx, category)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	_, err = db.Sql.ExecContext(ctx, `UPDATE category SET name = ?, parent_id = ?, unit = ? WHERE id = ? AND `+OWNER_ID+` = ?;`,
+		category.Name, nullUUID(category.ParentID), category.Unit, category.ID.String(), owner)
+	if err != nil {
+		return logg.Errorf(`can't update category "%s" %w`, category.ID, err)
+	}
+	return nil
+}
+
+// isBelow returns true if the category with id is a subcategory of ancestor, at any depth.
+func isBelow(byID map[uuid.UUID]categories.Category, id uuid.UUID, ancestor uuid.UUID) bool {
+	for seen := 0; id != uuid.Nil && seen <= len(byID); seen++ {
+		category, ok := byID[id]
+		if !ok {
+			return false
+		}
+		if category.ParentID == ancestor {
+			return true
+		}
+		id = category.ParentID
+	}
+	return false
+}
+
+// DeleteCategory deletes a category. Its items and subcategories are moved to its parent,
+// or have no category anymore if it was a root category.
+func (db *DB) DeleteCategory(ctx context.Context, id uuid.UUID) error {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	var parentID sql.NullString
+	err = db.Sql.QueryRowContext(ctx, `SELECT parent_id FROM category WHERE id = ? AND `+OWNER_ID+` = ?;`, id.String(), owner).Scan(&parentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return logg.Errorf(`category "%s" %w`, id, ErrNotExist)
+	}
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+
+	tx, err := db.Sql.BeginTx(ctx, nil)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	defer tx.Rollback()
+
+	statements := []string{
+		`UPDATE item SET ` + ITEM_CATEGORY_ID + ` = ? WHERE ` + ITEM_CATEGORY_ID + ` = ? AND ` + OWNER_ID + ` = ?;`,
+		`UPDATE category SET parent_id = ? WHERE parent_id = ? AND ` + OWNER_ID + ` = ?;`,
+	}
+	for _, stmt := range statements {
+		_, err = tx.ExecContext(ctx, stmt, parentID, id.String(), owner)
+		if err != nil {
+			return logg.Errorf(`can't move things out of category "%s" %w`, id, err)
+		}
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM category_default WHERE category_id = ?;`, id.String())
+	if err != nil {
+		return logg.Errorf(`can't delete defaults of category "%s" %w`, id, err)
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM category WHERE id = ? AND `+OWNER_ID+` = ?;`, id.String(), owner)
+	if err != nil {
+		return logg.Errorf(`can't delete category "%s" %w`, id, err)
+	}
+	return tx.Commit()
+}
+
+// CategoryDefaults returns all custom fields of items with the default values of the category.
+// Values inherited from parent categories are not included.
+func (db *DB) CategoryDefaults(ctx context.Context, id uuid.UUID) ([]common.CustomFieldValue, error) {
+	err := db.categoryOwned(ctx, id)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	fields, err := db.CustomFields(ctx, "item")
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	rows, err := db.Sql.QueryContext(ctx, `SELECT field_id, value FROM category_default WHERE category_id = ?;`, id.String())
+	if err != nil {
+		return nil, logg.Errorf(`can't query defaults of category "%s" %w`, id, err)
+	}
+	defer rows.Close()
+
+	values := map[string]string{}
+	for rows.Next() {
+		var fieldID, value string
+		err := rows.Scan(&fieldID, &value)
+		if err != nil {
+			return nil, logg.WrapErr(err)
+		}
+		values[fieldID] = value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, logg.WrapErr(err)
+	}
+
+	defaults := make([]common.CustomFieldValue, len(fields))
+	for i, field := range fields {
+		defaults[i] = common.CustomFieldValue{CustomField: field, Value: values[field.ID.String()]}
+	}
+	return defaults, nil
+}
+
+// SetCategoryDefaults replaces the default custom field values of a category.
+// Empty values are removed. If defaults is nil the values are not changed.
+func (db *DB) SetCategoryDefaults(ctx context.Context, id uuid.UUID, defaults []common.CustomFieldValue) error {
+	if defaults == nil {
+		return nil
+	}
+	err := db.categoryOwned(ctx, id)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+
+	tx, err := db.Sql.BeginTx(ctx, nil)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM category_default WHERE category_id = ?;`, id.String())
+	if err != nil {
+		return logg.Errorf(`can't remove defaults of category "%s" %w`, id, err)
+	}
+	for _, d := range defaults {
+		if d.Value == "" {
+			continue
+		}
+		stmt := `INSERT INTO category_default (category_id, field_id, value)
+			SELECT ?, id, ? FROM custom_field WHERE id = ? AND ` + OWNER_ID + ` = ? AND thing = 'item';`
+		_, err = tx.ExecContext(ctx, stmt, id.String(), d.Value, d.ID.String(), owner)
+		if err != nil {
+			return logg.Errorf(`can't set default of "%s" of category "%s" %w`, d.Name, id, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// inheritedDefaults returns the default custom field values of a category by field id,
+// including the defaults of its parents. Defaults of closer categories win.
+func (db *DB) inheritedDefaults(ctx context.Context, id uuid.UUID) (map[string]string, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	stmt := `WITH RECURSIVE chain(id, depth) AS (
+			SELECT id, 0 FROM category WHERE id = ? AND ` + OWNER_ID + ` = ?
+			UNION ALL
+			SELECT category.parent_id, chain.depth + 1 FROM category JOIN chain ON category.id = chain.id
+			WHERE category.parent_id IS NOT NULL AND chain.depth < 100
+		)
+		SELECT d.field_id, d.value FROM category_default AS d JOIN chain ON chain.id = d.category_id
+		ORDER BY chain.depth DESC;`
+	rows, err := db.Sql.QueryContext(ctx, stmt, id.String(), owner)
+	if err != nil {
+		return nil, logg.Errorf(`can't query inherited defaults of category "%s" %w`, id, err)
+	}
+	defer rows.Close()
+
+	defaults := map[string]string{}
+	for rows.Next() {
+		var fieldID, value string
+		err := rows.Scan(&fieldID, &value)
+		if err != nil {
+			return nil, logg.WrapErr(err)
+		}
+		defaults[fieldID] = value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	return defaults, nil
+}
+
+// withCategoryDefaults fills the empty custom field values of a new item with the defaults of its category.
+// values are the submitted values, nil if the item was created without custom fields.
+func (db *DB) withCategoryDefaults(ctx context.Context, categoryID uuid.UUID, values []common.CustomFieldValue) ([]common.CustomFieldValue, error) {
+	if categoryID == uuid.Nil {
+		return values, nil
+	}
+	defaults, err := db.inheritedDefaults(ctx, categoryID)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	if len(defaults) == 0 {
+		return values, nil
+	}
+	if values == nil {
+		values, err = db.customFieldValues(ctx, "item", uuid.Nil)
+		if err != nil {
+			return nil, logg.WrapErr(err)
+		}
+	}
+	filled := make([]common.CustomFieldValue, len(values))
+	for i, v := range values {
+		filled[i] = v
+		if strings.TrimSpace(v.Value) == "" {
+			filled[i].Value = defaults[v.ID.String()]
+		}
+	}
+	return filled, nil
+}
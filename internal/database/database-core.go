@@ -1,14 +1,19 @@
 package database
 
 import (
+	"basement/main/internal/auth"
 	"basement/main/internal/env"
+	"basement/main/internal/households"
 	"basement/main/internal/logg"
+	"context"
 	"database/sql"
 	"errors"
 	"os"
 	"path/filepath"
 
-	_ "modernc.org/sqlite"
+	"github.com/gofrs/uuid/v5"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
 )
 
 const DATABASE_FILE_PATH = "./internal/database/sqlite-database.db"
@@ -18,8 +23,16 @@ var ErrNotExist = errors.New("does not exist")
 var ErrNotEmpty = errors.New("not empty")
 var ErrNotImplemented = errors.New("is not implemented")
 var ErrIdenticalThing = errors.New("Thing IDs are the same")
+var ErrNoOwner = errors.New("no owner in context")
 
-// add statement to create new table
+// uniqueViolation returns true if err was caused by a UNIQUE constraint or index.
+func uniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+}
+
+// add statement to create new table.
+// These statements describe schema version 0, changes to existing tables belong in migrations.
 var mainTables = &map[string]string{
 	"user":  CREATE_USER_TABLE_STMT,
 	"item":  CREATE_ITEM_TABLE_STMT,
@@ -55,8 +68,22 @@ type DB struct {
 	fileExist bool
 }
 
-// Connect creates the database file if it doesn't exist and opens it.
+// Connect creates the database file if it doesn't exist, opens it and applies pending migrations.
 func (db *DB) Connect() {
+	db.Open()
+	db.mustMigrate()
+
+	db.PrintItemRecords()
+	// add dummy data
+	if !db.fileExist && env.Development() {
+		devID := uuid.FromStringOrNil(auth.DEVELOPMENT_USER_ID)
+		ctx := households.WithMembership(context.Background(), households.Membership{HouseholdID: devID, UserID: devID, Role: households.ROLE_OWNER})
+		db.insertDummyData(ctx)
+	}
+}
+
+// Open creates the database file if it doesn't exist and opens it without applying migrations.
+func (db *DB) Open() {
 	if !env.CurrentConfig().UseMemoryDB() {
 		// create the database File and open it
 		db.createFile(env.CurrentConfig().DbPath())
@@ -67,12 +94,6 @@ func (db *DB) Connect() {
 	db.createTable(*mainTables)
 	db.createTable(*virtualTables)
 	db.createTable(*triggers)
-
-	db.PrintItemRecords()
-	// add dummy data
-	if !db.fileExist && env.Development() {
-		db.insertDummyData()
-	}
 }
 
 // createFile creates only db file if it doesn't exist, no tables.
@@ -107,6 +128,10 @@ func (db *DB) open(dbFile string) {
 	if err != nil {
 		logg.Fatalf("Failed to open database: %v", err)
 	}
+	if dbFile == ":memory:" {
+		// Every new connection would get its own empty in-memory database.
+		db.Sql.SetMaxOpenConns(1)
+	}
 	logg.Debugf("opened '%s'", dbFile)
 	logg.Info("Database Connection established")
 }
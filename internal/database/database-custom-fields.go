@@ -0,0 +1,282 @@
+package database
+
+import (
+	"basement/main/internal/common"
+	"basement/main/internal/fields"
+	"basement/main/internal/logg"
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// CustomFields returns the custom fields of thing in the order they were created.
+func (db *DB) CustomFields(ctx context.Context, thing string) ([]common.CustomField, error) {
+	err := ValidTable(thing)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	stmt := `SELECT id, thing, name, type, options FROM custom_field
+		WHERE ` + OWNER_ID + ` = ? AND thing = ?
+		ORDER BY created_at, rowid;`
+	rows, err := db.Sql.QueryContext(ctx, stmt, owner, thing)
+	if err != nil {
+		return nil, logg.Errorf("can't query custom fields of %s %w", thing, err)
+	}
+	defer rows.Close()
+
+	list := []common.CustomField{}
+	for rows.Next() {
+		field, err := scanCustomField(rows)
+		if err != nil {
+			return nil, logg.WrapErr(err)
+		}
+		list = append(list, field)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	return list, nil
+}
+
+// CustomField returns the custom field with id.
+func (db *DB) CustomField(ctx context.Context, id uuid.UUID) (common.CustomField, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return common.CustomField{}, logg.WrapErr(err)
+	}
+	stmt := `SELECT id, thing, name, type, options FROM custom_field WHERE id = ? AND ` + OWNER_ID + ` = ?;`
+	field, err := scanCustomField(db.Sql.QueryRowContext(ctx, stmt, id.String(), owner))
+	if errors.Is(err, sql.ErrNoRows) {
+		return common.CustomField{}, logg.Errorf(`custom field "%s" %w`, id, ErrNotExist)
+	}
+	if err != nil {
+		return common.CustomField{}, logg.WrapErr(err)
+	}
+	return field, nil
+}
+
+func scanCustomField(row interface{ Scan(dest ...any) error }) (common.CustomField, error) {
+	var id, options string
+	var field common.CustomField
+	err := row.Scan(&id, &field.Thing, &field.Name, &field.Type, &options)
+	if err != nil {
+		return common.CustomField{}, err
+	}
+	field.ID = uuid.FromStringOrNil(id)
+	if options != "" {
+		field.Options = strings.Split(options, "\n")
+	}
+	return field, nil
+}
+
+// CreateCustomField adds a field to all things of field.Thing.
+// Returns fields.ErrFieldExists if the thing already has a field with this name, ignoring case.
+func (db *DB) CreateCustomField(ctx context.Context, field common.CustomField) (uuid.UUID, error) {
+	err := ValidTable(field.Thing)
+	if err != nil {
+		return uuid.Nil, logg.WrapErr(err)
+	}
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return uuid.Nil, logg.WrapErr(err)
+	}
+	id, err := uuid.NewV4()
+	if err != nil {
+		return uuid.Nil, logg.WrapErr(err)
+	}
+	stmt := `INSERT OR IGNORE INTO custom_field (id, owner_id, thing, name, type, options, created_at) VALUES (?, ?, ?, ?, ?, ?, ?);`
+	result, err := db.Sql.ExecContext(ctx, stmt, id.String(), owner, field.Thing, field.Name, field.Type,
+		strings.Join(field.Options, "\n"), time.Now().UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return uuid.Nil, logg.Errorf(`can't create custom field "%s" %w`, field.Name, err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return uuid.Nil, logg.WrapErr(err)
+	}
+	if n == 0 {
+		return uuid.Nil, logg.Errorf(`"%s" %w`, field.Name, fields.ErrFieldExists)
+	}
+	return id, nil
+}
+
+// fieldThingsStmt selects the thing and id of every thing with a value of the custom field.
+const fieldThingsStmt = `SELECT f.thing, v.thing_id FROM custom_field_value AS v
+	JOIN custom_field AS f ON f.id = v.field_id WHERE v.field_id = ?;`
+
+// UpdateCustomField changes the name and options of a field.
+// The type can't be changed, because the stored values might not fit the new type.
+// Values that are no longer an option of an enum field are kept.
+func (db *DB) UpdateCustomField(ctx context.Context, field common.CustomField) error {
+	current, err := db.CustomField(ctx, field.ID)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	var taken int
+	stmt := `SELECT COUNT(*) FROM custom_field WHERE ` + OWNER_ID + ` = ? AND thing = ? AND name = ? COLLATE NOCASE AND id != ?;`
+	err = db.Sql.QueryRowContext(ctx, stmt, owner, current.Thing, field.Name, field.ID.String()).Scan(&taken)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	if taken > 0 {
+		return logg.Errorf(`"%s" %w`, field.Name, fields.ErrFieldExists)
+	}
+
+	tx, err := db.Sql.BeginTx(ctx, nil)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	defer tx.Rollback()
+
+	err = recordUpdates(ctx, tx, func() error {
+		_, err := tx.ExecContext(ctx, `UPDATE custom_field SET name = ?, options = ? WHERE id = ? AND `+OWNER_ID+` = ?;`,
+			field.Name, strings.Join(field.Options, "\n"), field.ID.String(), owner)
+		if err != nil {
+			return logg.Errorf(`can't update custom field "%s" %w`, field.ID, err)
+		}
+		return nil
+	}, fieldThingsStmt, field.ID.String())
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	return tx.Commit()
+}
+
+// DeleteCustomField deletes a field together with its values.
+func (db *DB) DeleteCustomField(ctx context.Context, id uuid.UUID) error {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	tx, err := db.Sql.BeginTx(ctx, nil)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	defer tx.Rollback()
+
+	err = recordUpdates(ctx, tx, func() error {
+		result, err := tx.ExecContext(ctx, `DELETE FROM custom_field WHERE id = ? AND `+OWNER_ID+` = ?;`, id.String(), owner)
+		if err != nil {
+			return logg.Errorf(`can't delete custom field "%s" %w`, id, err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return logg.WrapErr(err)
+		}
+		if n == 0 {
+			return logg.Errorf(`custom field "%s" %w`, id, ErrNotExist)
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM custom_field_value WHERE field_id = ?;`, id.String())
+		if err != nil {
+			return logg.Errorf(`can't delete values of custom field "%s" %w`, id, err)
+		}
+		return nil
+	}, fieldThingsStmt, id.String())
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM category_default WHERE field_id = ?;`, id.String())
+	if err != nil {
+		return logg.Errorf(`can't delete category defaults of custom field "%s" %w`, id, err)
+	}
+	return tx.Commit()
+}
+
+// customFieldValues returns all custom fields of thing with the values of the thing with id.
+func (db *DB) customFieldValues(ctx context.Context, thing string, id uuid.UUID) ([]common.CustomFieldValue, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	stmt := `SELECT f.id, f.thing, f.name, f.type, f.options, COALESCE(v.value, '')
+		FROM custom_field AS f
+		LEFT JOIN custom_field_value AS v ON v.field_id = f.id AND v.thing_id = ?
+		WHERE f.` + OWNER_ID + ` = ? AND f.thing = ?
+		ORDER BY f.created_at, f.rowid;`
+	rows, err := db.Sql.QueryContext(ctx, stmt, id.String(), owner, thing)
+	if err != nil {
+		return nil, logg.Errorf(`can't query custom fields of %s "%s" %w`, thing, id, err)
+	}
+	defer rows.Close()
+
+	values := []common.CustomFieldValue{}
+	for rows.Next() {
+		var fieldID, options string
+		var value common.CustomFieldValue
+		err := rows.Scan(&fieldID, &value.Thing, &value.Name, &value.Type, &options, &value.Value)
+		if err != nil {
+			return nil, logg.WrapErr(err)
+		}
+		value.ID = uuid.FromStringOrNil(fieldID)
+		if options != "" {
+			value.Options = strings.Split(options, "\n")
+		}
+		values = append(values, value)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	return values, nil
+}
+
+// setCustomFields replaces the custom field values of a thing in the transaction of the change of the thing.
+// Empty values are removed. If values is nil the values are not changed.
+func setCustomFields(ctx context.Context, tx *sql.Tx, thing string, id uuid.UUID, values []common.CustomFieldValue) error {
+	if values == nil {
+		return nil
+	}
+	err := ValidTable(thing)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+
+	stmt := `DELETE FROM custom_field_value WHERE thing_id = ?
+		AND field_id IN (SELECT id FROM custom_field WHERE ` + OWNER_ID + ` = ? AND thing = ?);`
+	_, err = tx.ExecContext(ctx, stmt, id.String(), owner, thing)
+	if err != nil {
+		return logg.Errorf(`can't remove custom fields of %s "%s" %w`, thing, id, err)
+	}
+	for _, v := range values {
+		if v.Value == "" {
+			continue
+		}
+		// Only fields of the household and type of thing can be set.
+		stmt := `INSERT INTO custom_field_value (field_id, thing_id, value)
+			SELECT id, ?, ? FROM custom_field WHERE id = ? AND ` + OWNER_ID + ` = ? AND thing = ?;`
+		_, err = tx.ExecContext(ctx, stmt, id.String(), v.Value, v.ID.String(), owner, thing)
+		if err != nil {
+			return logg.Errorf(`can't set custom field "%s" of %s "%s" %w`, v.Name, thing, id, err)
+		}
+	}
+	return nil
+}
+
+// deleteOrphanedCustomFieldValues removes the custom field values of purged things.
+func (db *DB) deleteOrphanedCustomFieldValues(ctx context.Context) error {
+	for _, thing := range fields.Things {
+		stmt := `DELETE FROM custom_field_value
+			WHERE field_id IN (SELECT id FROM custom_field WHERE thing = ?)
+			AND thing_id NOT IN (SELECT id FROM ` + thing + `);`
+		_, err := db.Sql.ExecContext(ctx, stmt, thing)
+		if err != nil {
+			return logg.Errorf(`can't delete custom fields of purged %s %w`, thing, err)
+		}
+	}
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"basement/main/internal/items"
 	"basement/main/internal/logg"
 	"basement/main/internal/shelves"
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
@@ -271,7 +272,7 @@ func (db *DB) PrintShelvesRecords() {
 
 const SEED = 1234
 
-func (db *DB) InsertSampleItems() {
+func (db *DB) InsertSampleItems(ctx context.Context) {
 	gofakeit.Seed(SEED)
 
 	for i := range 10 {
@@ -287,7 +288,7 @@ func (db *DB) InsertSampleItems() {
 			Weight:   gofakeit.Float64Range(0, 100),
 		}
 
-		err := db.insertNewItem(newItem)
+		err := db.insertNewItem(ctx, newItem)
 		if err != nil {
 			logg.Errf("error while adding dummyData %v", err)
 			return
@@ -296,7 +297,7 @@ func (db *DB) InsertSampleItems() {
 	return
 }
 
-func (db *DB) InsertSampleBoxes() {
+func (db *DB) InsertSampleBoxes(ctx context.Context) {
 	gofakeit.Seed(SEED + 1)
 
 	for i := range 10 {
@@ -309,7 +310,7 @@ func (db *DB) InsertSampleBoxes() {
 			},
 		}
 
-		_, err := db.CreateBox(&newBox)
+		_, err := db.CreateBox(ctx, &newBox)
 		if err != nil {
 			logg.Errf("error while adding dummyData %v", err)
 			return
@@ -318,7 +319,7 @@ func (db *DB) InsertSampleBoxes() {
 	return
 }
 
-func (db *DB) InsertSampleShelves() {
+func (db *DB) InsertSampleShelves(ctx context.Context) {
 	gofakeit.Seed(SEED + 2)
 
 	for i := range 10 {
@@ -331,7 +332,7 @@ func (db *DB) InsertSampleShelves() {
 			},
 		}
 
-		err := db.CreateShelf(newShelf)
+		err := db.CreateShelf(ctx, newShelf)
 		if err != nil {
 			logg.Errf("error while adding dummyData %v", err)
 			return
@@ -340,7 +341,7 @@ func (db *DB) InsertSampleShelves() {
 	return
 }
 
-func (db *DB) InsertSampleAreas() {
+func (db *DB) InsertSampleAreas(ctx context.Context) {
 	gofakeit.Seed(SEED + 3)
 
 	for i := range 3 {
@@ -353,7 +354,7 @@ func (db *DB) InsertSampleAreas() {
 			},
 		}
 
-		_, err := db.CreateArea(newArea)
+		_, err := db.CreateArea(ctx, newArea)
 		if err != nil {
 			logg.Errf("error while adding dummyData %v", err)
 			return
@@ -367,7 +368,7 @@ func (db *DB) InsertSampleAreas() {
 // If rows=5 returns 3 results with `found=2` the last 2 rows of `shelfRows` will be nil pointers.
 //
 // `rows` and `page` must be 1 or above.
-func (db *DB) ShelfListRowsPaginated(page int, rows int) (shelfRows []*common.ListRow, foundResults int, err error) {
+func (db *DB) ShelfListRowsPaginated(ctx context.Context, page int, rows int) (shelfRows []*common.ListRow, foundResults int, err error) {
 	shelfRows = make([]*common.ListRow, 0)
 
 	if page < 1 {
@@ -383,13 +384,19 @@ func (db *DB) ShelfListRowsPaginated(page int, rows int) (shelfRows []*common.Li
 	limit := rows
 	offset := (page - 1) * rows
 
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return shelfRows, foundResults, logg.WrapErr(err)
+	}
+	ownedBy, _ := ownerCondition("shelf_fts")
 	queryNoSearch := `
 		SELECT
 			id, label, area_id, area_label, preview_picture
 		FROM shelf_fts
+		WHERE ` + ownedBy + `
 			ORDER BY label ASC
 		LIMIT ? OFFSET ?;`
-	results, err := db.Sql.Query(queryNoSearch, limit, offset)
+	results, err := db.Sql.Query(queryNoSearch, owner, limit, offset)
 	if err != nil {
 		return shelfRows, foundResults, logg.WrapErr(err)
 	}
@@ -405,6 +412,7 @@ func (db *DB) ShelfListRowsPaginated(page int, rows int) (shelfRows []*common.Li
 		if err != nil {
 			return shelfRows, foundResults, logg.WrapErr(err)
 		}
+		shelfRows[i].Thing = "shelf"
 		i += 1
 	}
 	if results.Err() != nil {
@@ -415,43 +423,44 @@ func (db *DB) ShelfListRowsPaginated(page int, rows int) (shelfRows []*common.Li
 	return shelfRows, foundResults, nil
 }
 
-func (db *DB) insertDummyData() {
-	db.InsertSampleItems()
-	db.InsertSampleBoxes()
-	db.InsertSampleShelves()
-	db.InsertSampleAreas()
-	itemIDs, err := db.ItemIDs()
+// insertDummyData inserts sample things owned by the user in ctx.
+func (db *DB) insertDummyData(ctx context.Context) {
+	db.InsertSampleItems(ctx)
+	db.InsertSampleBoxes(ctx)
+	db.InsertSampleShelves(ctx)
+	db.InsertSampleAreas(ctx)
+	itemIDs, err := db.ItemIDs(ctx)
 	if err != nil {
 		logg.WrapErr(err)
 	}
-	boxIDs, err := db.BoxIDs()
+	boxIDs, err := db.BoxIDs(ctx)
 	if err != nil {
 		logg.WrapErr(err)
 	}
-	shelfRows, _, err := db.ShelfListRowsPaginated(1, 3)
+	shelfRows, _, err := db.ShelfListRowsPaginated(ctx, 1, 3)
 	if err != nil {
 		logg.WrapErr(err)
 	}
-	areaIDs, err := db.AreaIDs()
+	areaIDs, err := db.AreaIDs(ctx)
 	if err != nil {
 		logg.WrapErr(err)
 	}
-	db.MoveItemToBox(itemIDs[0], boxIDs[0])
-	db.MoveItemToBox(itemIDs[1], boxIDs[0])
-	db.MoveItemToBox(itemIDs[2], boxIDs[0])
-	db.MoveItemToBox(itemIDs[3], boxIDs[1])
-	db.MoveItemToBox(itemIDs[4], boxIDs[1])
-	db.MoveItemToShelf(itemIDs[5], shelfRows[0].ID)
-	db.MoveItemToShelf(itemIDs[6], shelfRows[0].ID)
-	db.MoveBoxToShelf(boxIDs[0], shelfRows[1].ID)
-	db.MoveBoxToShelf(boxIDs[2], shelfRows[1].ID)
-	db.MoveBoxToBox(boxIDs[3], boxIDs[5])
-	db.MoveBoxToBox(boxIDs[3], boxIDs[5])
-	db.MoveBoxToBox(boxIDs[5], boxIDs[6])
-	db.MoveItemToArea(itemIDs[0], areaIDs[0])
-	db.MoveBoxToArea(boxIDs[0], areaIDs[0])
-	db.MoveShelfToArea(shelfRows[1].ID, areaIDs[0])
-	db.MoveShelfToArea(shelfRows[2].ID, areaIDs[0])
-	// db.MoveShelfToArea(shelfRows[3].ID, areaIDs[0])
-	// db.MoveShelfToArea(shelfRows[4].ID, areaIDs[0])
+	db.MoveItemToBox(ctx, itemIDs[0], boxIDs[0])
+	db.MoveItemToBox(ctx, itemIDs[1], boxIDs[0])
+	db.MoveItemToBox(ctx, itemIDs[2], boxIDs[0])
+	db.MoveItemToBox(ctx, itemIDs[3], boxIDs[1])
+	db.MoveItemToBox(ctx, itemIDs[4], boxIDs[1])
+	db.MoveItemToShelf(ctx, itemIDs[5], shelfRows[0].ID)
+	db.MoveItemToShelf(ctx, itemIDs[6], shelfRows[0].ID)
+	db.MoveBoxToShelf(ctx, boxIDs[0], shelfRows[1].ID)
+	db.MoveBoxToShelf(ctx, boxIDs[2], shelfRows[1].ID)
+	db.MoveBoxToBox(ctx, boxIDs[3], boxIDs[5])
+	db.MoveBoxToBox(ctx, boxIDs[3], boxIDs[5])
+	db.MoveBoxToBox(ctx, boxIDs[5], boxIDs[6])
+	db.MoveItemToArea(ctx, itemIDs[0], areaIDs[0])
+	db.MoveBoxToArea(ctx, boxIDs[0], areaIDs[0])
+	db.MoveShelfToArea(ctx, shelfRows[1].ID, areaIDs[0])
+	db.MoveShelfToArea(ctx, shelfRows[2].ID, areaIDs[0])
+	// db.MoveShelfToArea(ctx, shelfRows[3].ID, areaIDs[0])
+	// db.MoveShelfToArea(ctx, shelfRows[4].ID, areaIDs[0])
 }
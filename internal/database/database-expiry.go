@@ -0,0 +1,115 @@
+package database
+
+import (
+	"basement/main/internal/common"
+	"basement/main/internal/expiry"
+	"basement/main/internal/logg"
+	"basement/main/internal/validate"
+	"context"
+	"fmt"
+	"html/template"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// expiryCondition returns the condition for items of the item table with the expiry status on the day today.
+func expiryCondition(status string, today time.Time, warnDays int) (condition string, args []any, err error) {
+	day := today.Format(validate.DATE_LAYOUT)
+	switch status {
+	case expiry.STATUS_EXPIRED:
+		return ITEM_EXPIRY_DATE + " < ?", []any{day}, nil
+	case expiry.STATUS_EXPIRING:
+		return ITEM_EXPIRY_DATE + " BETWEEN ? AND ?", []any{day, today.AddDate(0, 0, warnDays).Format(validate.DATE_LAYOUT)}, nil
+	}
+	return "", nil, logg.NewError(fmt.Sprintf(`"%s" is not an expiry status`, status))
+}
+
+// expiryFilter returns the search filter for the item_fts rows of the user in ctx with the expiry status.
+func expiryFilter(ctx context.Context, status string, searchQuery string, warnDays int) (searchFilter, error) {
+	condition, args, err := expiryCondition(status, time.Now(), warnDays)
+	if err != nil {
+		return searchFilter{}, logg.WrapErr(err)
+	}
+	return itemListFilter(ctx, searchQuery, condition, args...)
+}
+
+// ExpiryListCounter returns the amount of items of the user in ctx with the expiry status that match searchQuery.
+// status is expiry.STATUS_EXPIRED or expiry.STATUS_EXPIRING, items expire soon within warnDays.
+func (db *DB) ExpiryListCounter(ctx context.Context, status string, searchQuery string, warnDays int) (count int, err error) {
+	filter, err := expiryFilter(ctx, status, searchQuery, warnDays)
+	if err != nil {
+		return 0, logg.WrapErr(err)
+	}
+	return db.itemListCount(ctx, filter)
+}
+
+// ExpiryListRows returns a page of the items of the user in ctx with the expiry status that match searchQuery.
+// Items are sorted by their expiry date, the earliest first, after the sort terms of the query.
+// The snippet of every row shows the dates of the item.
+func (db *DB) ExpiryListRows(ctx context.Context, status string, searchQuery string, warnDays int, limit int, page int) ([]common.ListRow, error) {
+	filter, err := expiryFilter(ctx, status, searchQuery, warnDays)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	filter.order = append(filter.order, itemColumn(ITEM_EXPIRY_DATE))
+	columns := []string{ITEM_BEST_BEFORE, ITEM_EXPIRES_AT}
+	return db.itemListRows(ctx, filter, columns, limit, page, func(values []string) template.HTML {
+		return expirySnippet(values[0], values[1])
+	})
+}
+
+// expirySnippet shows the dates of an item below its label.
+func expirySnippet(bestBefore string, expiresAt string) template.HTML {
+	snippet := ""
+	if bestBefore != "" {
+		snippet = "Best before " + bestBefore
+	}
+	if expiresAt != "" {
+		if snippet != "" {
+			snippet += ", "
+		}
+		snippet += "Expires on " + expiresAt
+	}
+	return template.HTML(template.HTMLEscapeString(snippet))
+}
+
+// ExpiryCounts returns the amount of expired items and items expiring soon within warnDays of every household.
+// Households without such items are missing from the map.
+func (db *DB) ExpiryCounts(today time.Time, warnDays int) (map[uuid.UUID]expiry.Counts, error) {
+	expired, expiredArgs, err := expiryCondition(expiry.STATUS_EXPIRED, today, warnDays)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	expiring, expiringArgs, err := expiryCondition(expiry.STATUS_EXPIRING, today, warnDays)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	query := "" +
+		"SELECT " + OWNER_ID + ", SUM(" + expired + "), SUM(" + expiring + ") " +
+		"FROM item " +
+		"WHERE " + NOT_DELETED + " AND " + ITEM_EXPIRY_DATE + " IS NOT NULL AND " + OWNER_ID + " IS NOT NULL " +
+		"GROUP BY " + OWNER_ID + ";"
+	rows, err := db.Sql.Query(query, append(expiredArgs, expiringArgs...)...)
+	if err != nil {
+		return nil, logg.Errorf("%s %w", query, err)
+	}
+	defer rows.Close()
+
+	counts := map[uuid.UUID]expiry.Counts{}
+	for rows.Next() {
+		var owner string
+		var c expiry.Counts
+		err := rows.Scan(&owner, &c.Expired, &c.Expiring)
+		if err != nil {
+			return nil, logg.WrapErr(err)
+		}
+		if c.Expired > 0 || c.Expiring > 0 {
+			counts[uuid.FromStringOrNil(owner)] = c
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	return counts, nil
+}
@@ -2,27 +2,39 @@ package database
 
 import (
 	"basement/main/internal/common"
+	"basement/main/internal/history"
+	"basement/main/internal/households"
 	"basement/main/internal/logg"
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/gofrs/uuid/v5"
 )
 
-// Exists checks existence of entity (item, box, shelf, area).
+// Exists checks existence of entity (item, box, shelf, area) owned by the user in ctx.
 // Returns error only if other internal errors happen.
-func (db *DB) Exists(entityType string, id uuid.UUID) (bool, error) {
+func (db *DB) Exists(ctx context.Context, entityType string, id uuid.UUID) (bool, error) {
 	err1 := ValidTable(entityType)
 	err2 := ValidVirtualTable(entityType)
 	// not a vaid table and not valid virtual table
 	if err1 != nil && err2 != nil {
 		return false, logg.NewError(fmt.Sprintf("no entity with type: \"%s\"", entityType))
 	}
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return false, logg.WrapErr(err)
+	}
+	ownedBy, err := ownerCondition(entityType)
+	if err != nil {
+		return false, logg.WrapErr(err)
+	}
 
 	var itemExists int
-	query := fmt.Sprintf(`SELECT EXISTS(SELECT 1 FROM %s WHERE id = ?)`, entityType)
-	err := db.Sql.QueryRow(query, id.String()).Scan(&itemExists)
+	query := fmt.Sprintf(`SELECT EXISTS(SELECT 1 FROM %s WHERE id = ? AND %s)`, entityType, ownedBy)
+	err = db.Sql.QueryRow(query, id.String(), owner).Scan(&itemExists)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return false, nil
@@ -37,14 +49,96 @@ func (db *DB) Exists(entityType string, id uuid.UUID) (bool, error) {
 	}
 }
 
-func (db *DB) deleteFrom(table string, id uuid.UUID) error {
+// idTaken checks if id is used by any owner.
+// IDs are primary keys, so they must be unique across all owners.
+func (db *DB) idTaken(table string, id uuid.UUID) (bool, error) {
 	err := ValidTable(table)
+	if err != nil {
+		return false, logg.WrapErr(err)
+	}
+
+	var taken int
+	query := fmt.Sprintf(`SELECT EXISTS(SELECT 1 FROM %s WHERE id = ?)`, table)
+	err = db.Sql.QueryRow(query, id.String()).Scan(&taken)
+	if err != nil {
+		return false, logg.WrapErr(err)
+	}
+	return taken != 0, nil
+}
+
+// ownerID returns the id of the household whose things are accessed with ctx.
+func ownerID(ctx context.Context) (string, error) {
+	m, ok := households.FromContext(ctx)
+	if !ok {
+		return "", logg.WrapErrWithSkip(ErrNoOwner, 2)
+	}
+	return m.HouseholdID.String(), nil
+}
+
+// ownerCondition returns a WHERE condition that limits table to the things of one owner
+// which are not in the trash.
+// The owner id is the only query parameter of the condition.
+//
+//	"item"     -> "owner_id = ? AND deleted_at IS NULL"
+//	"item_fts" -> "id IN (SELECT id FROM item WHERE owner_id = ? AND deleted_at IS NULL)"
+func ownerCondition(table string) (string, error) {
+	switch table {
+	case "item", "box", "shelf", "area":
+		return OWNER_ID + " = ? AND " + NOT_DELETED, nil
+	case "item_fts", "box_fts", "shelf_fts", "area_fts":
+		return "id IN (SELECT id FROM " + strings.TrimSuffix(table, "_fts") + " WHERE " + OWNER_ID + " = ? AND " + NOT_DELETED + ")", nil
+	}
+	return "", logg.NewError(fmt.Sprintf(`"%s" has no owner`, table))
+}
+
+// notOwnedByOthers returns an error if id belongs to another user than the one in ctx.
+// Used to check that things are only put into boxes, shelves or areas of the same owner.
+func (db *DB) notOwnedByOthers(ctx context.Context, table string, id uuid.UUID) error {
+	if id == uuid.Nil {
+		return nil
+	}
+	owned, err := db.Exists(ctx, table, id)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	if owned {
+		return nil
+	}
+	taken, err := db.idTaken(table, id)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	if taken {
+		return logg.Errorf(`%s "%s" %w`, table, id, ErrNotExist)
+	}
+	return nil
+}
+
+// deleteFrom moves the thing with id to the trash.
+// It can be restored with restoreFrom until it is purged.
+func (db *DB) deleteFrom(ctx context.Context, table string, id uuid.UUID) error {
+	err := ValidTable(table)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+
+	tx, err := db.Sql.BeginTx(ctx, nil)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	defer tx.Rollback()
+
+	before, err := thingStateOf(ctx, tx, table, id)
 	if err != nil {
 		return logg.WrapErr(err)
 	}
 
-	stmt := fmt.Sprintf(`DELETE FROM %s WHERE id = ?;`, table)
-	result, err := db.Sql.Exec(stmt, id.String())
+	stmt := fmt.Sprintf(`UPDATE %s SET %s = ? WHERE id = ? AND %s = ? AND %s;`, table, DELETED_AT, OWNER_ID, NOT_DELETED)
+	result, err := tx.ExecContext(ctx, stmt, time.Now().UTC().Format(time.RFC3339), id.String(), owner)
 	if err != nil {
 		return logg.Errorf(`can't delete "%s" from "%s" %w`, id, table, err)
 	}
@@ -58,7 +152,11 @@ func (db *DB) deleteFrom(table string, id uuid.UUID) error {
 	} else if rowsAffected != 1 {
 		return logg.NewError(fmt.Sprintf(`unexpected number of rows affected (%d) while deleting "%s" from "%s"`, rowsAffected, id, table))
 	}
-	return nil
+	err = recordHistory(ctx, tx, history.ACTION_DELETE, table, id, before)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	return tx.Commit()
 }
 
 func ValidTable(table string) error {
@@ -78,15 +176,16 @@ func ValidVirtualTable(table string) error {
 }
 
 // moveTo moves item/box/shelf to a box/shelf/area.
+// Both things must belong to the user in ctx.
 //
 // Example move item to a box:
 //
-//	moveTo("item", itemID, "box", boxID)
+//	moveTo(ctx, "item", itemID, "box", boxID)
 //
 // To move things out set
 //
 //	toTableID = uuid.Nil
-func (db *DB) moveTo(table string, id uuid.UUID, toTable string, toTableID uuid.UUID) error {
+func (db *DB) moveTo(ctx context.Context, table string, id uuid.UUID, toTable string, toTableID uuid.UUID) error {
 	if id == toTableID {
 		return logg.NewError(fmt.Sprintf(`can't move "%s" to itself. ID=%s`, table, id.String()))
 	}
@@ -98,10 +197,14 @@ func (db *DB) moveTo(table string, id uuid.UUID, toTable string, toTableID uuid.
 	if err != nil {
 		return logg.Errorf(`toTable: "%s" %w`, toTable, err)
 	}
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
 
 	errMsg := fmt.Sprintf(`moving "%s" "%s" to "%s" "%s"`, table, id.String(), toTable, toTableID.String())
 
-	exists, err := db.Exists(table, id)
+	exists, err := db.Exists(ctx, table, id)
 	if err != nil {
 		return logg.WrapErr(err)
 	}
@@ -111,7 +214,7 @@ func (db *DB) moveTo(table string, id uuid.UUID, toTable string, toTableID uuid.
 
 	// check if the table where the item is being moved to exists
 	if toTableID != uuid.Nil {
-		exists, err := db.Exists(toTable, toTableID)
+		exists, err := db.Exists(ctx, toTable, toTableID)
 		if err != nil {
 			return logg.WrapErr(err)
 		}
@@ -120,9 +223,26 @@ func (db *DB) moveTo(table string, id uuid.UUID, toTable string, toTableID uuid.
 		}
 	}
 
+	tx, err := db.Sql.BeginTx(ctx, nil)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	defer tx.Rollback()
+
+	before, err := thingStateOf(ctx, tx, table, id)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+
+	// Remember the previous location if the move is part of a move operation that can be undone.
+	err = db.recordMove(ctx, tx, table, id)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+
 	// Update the item's shelf_id
-	stmt := fmt.Sprintf(`UPDATE %s SET %s_id = ? WHERE id = ?`, table, toTable)
-	result, err := db.Sql.Exec(stmt, toTableID.String(), id.String())
+	stmt := fmt.Sprintf(`UPDATE %s SET %s_id = ? WHERE id = ? AND %s = ? AND %s`, table, toTable, OWNER_ID, NOT_DELETED)
+	result, err := tx.ExecContext(ctx, stmt, toTableID.String(), id.String(), owner)
 	if err != nil {
 		return logg.WrapErr(err)
 	}
@@ -133,22 +253,34 @@ func (db *DB) moveTo(table string, id uuid.UUID, toTable string, toTableID uuid.
 	if rows != 1 {
 		return logg.NewError(fmt.Sprintf("rows should be != 1 but is %d", rows))
 	}
+	err = recordHistory(ctx, tx, history.ACTION_MOVE, table, id, before)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
 	// logg.Debugf("moved %s to %s", id, toTableID)
-	return nil
+	return tx.Commit()
 }
 
 // listRowByID returns item/box/shelf/area from FTS tables item_fts, box_fts, shelf_fts, area_fts.
-func (db *DB) listRowByID(listRowsTable string, id uuid.UUID) (row common.ListRow, err error) {
+func (db *DB) listRowByID(ctx context.Context, listRowsTable string, id uuid.UUID) (row common.ListRow, err error) {
 	err = ValidVirtualTable(listRowsTable)
 	if err != nil {
 		return row, logg.WrapErr(err)
 	}
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return row, logg.WrapErr(err)
+	}
+	ownedBy, err := ownerCondition(listRowsTable)
+	if err != nil {
+		return row, logg.WrapErr(err)
+	}
 
 	stmt := "" +
 		"SELECT " + ALL_FTS_COLS + " " +
 		"FROM " + listRowsTable + " " +
-		"WHERE id = ?"
-	qrow := db.Sql.QueryRow(stmt, id.String())
+		"WHERE id = ? AND " + ownedBy
+	qrow := db.Sql.QueryRow(stmt, id.String(), owner)
 
 	sqlListRow := SQLListRow{}
 	err = qrow.Scan(sqlListRow.RowsToScan()...)
@@ -156,19 +288,31 @@ func (db *DB) listRowByID(listRowsTable string, id uuid.UUID) (row common.ListRo
 		return row, fmt.Errorf("error while scanning %s row: %w", listRowsTable, err)
 	}
 	r, err := sqlListRow.ToListRow()
-	return *r, err
+	if err != nil {
+		return row, logg.WrapErr(err)
+	}
+	r.Thing = strings.TrimSuffix(listRowsTable, "_fts")
+	return *r, nil
 }
 
 // allListRowsFrom returns all items/boxes/shelves/etc from FTS tables item_fts, box_fts, shelf_fts, area_fts.
-func (db *DB) allListRowsFrom(listRowsTable string) (listRows []common.ListRow, err error) {
+func (db *DB) allListRowsFrom(ctx context.Context, listRowsTable string) (listRows []common.ListRow, err error) {
 	err = ValidVirtualTable(listRowsTable)
 	if err != nil {
 		return nil, logg.WrapErr(err)
 	}
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	ownedBy, err := ownerCondition(listRowsTable)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
 
-	stmt := " SELECT " + ALL_FTS_COLS + " FROM " + listRowsTable + ";"
+	stmt := " SELECT " + ALL_FTS_COLS + " FROM " + listRowsTable + " WHERE " + ownedBy + ";"
 
-	rows, err := db.Sql.Query(stmt)
+	rows, err := db.Sql.Query(stmt, owner)
 	if err != nil {
 		return nil, logg.Errorf("%s %w", stmt, err)
 	}
@@ -185,6 +329,7 @@ func (db *DB) allListRowsFrom(listRowsTable string) (listRows []common.ListRow,
 		if err != nil {
 			return nil, logg.WrapErr(err)
 		}
+		lrow.Thing = strings.TrimSuffix(listRowsTable, "_fts")
 		listRows = append(listRows, lrow)
 	}
 	return listRows, nil
@@ -197,7 +342,7 @@ func (db *DB) allListRowsFrom(listRowsTable string) (listRows []common.ListRow,
 // Empty searchQuery will return all rows.
 //
 // Panics if page or limit is zero, both must be at least 1.
-func (db *DB) listRowsPaginatedFrom(listRowsTable string, searchQuery string, limit int, page int) (listRows []common.ListRow, err error) {
+func (db *DB) listRowsPaginatedFrom(ctx context.Context, listRowsTable string, searchQuery string, limit int, page int) (listRows []common.ListRow, err error) {
 	if page == 0 {
 		panic("offset starts at 1, can't be 0")
 	}
@@ -209,26 +354,25 @@ func (db *DB) listRowsPaginatedFrom(listRowsTable string, searchQuery string, li
 	if err != nil {
 		return nil, logg.WrapErr(err)
 	}
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	ownedBy, err := ownerCondition(listRowsTable)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
 
 	offset := (page - 1) * limit
 
-	var stmt string
-	var rows *sql.Rows
-
-	if strings.TrimSpace(searchQuery) != "" {
-		stmt = "" +
-			"SELECT " + ALL_FTS_COLS + " " +
-			"FROM " + listRowsTable + " " +
-			"WHERE label MATCH ?" + " " +
-			"LIMIT ? OFFSET ?;"
-		rows, err = db.Sql.Query(stmt, searchQuery+"*", limit, offset)
-	} else {
-		stmt = "" +
-			"SELECT " + ALL_FTS_COLS + " " +
-			"FROM " + listRowsTable + " " +
-			"LIMIT ? OFFSET ?;"
-		rows, err = db.Sql.Query(stmt, limit, offset)
-	}
+	filter := newSearchFilter(listRowsTable, searchQuery, owner)
+	stmt := "" +
+		"SELECT " + ALL_FTS_COLS + ", " + filter.highlights() + " " +
+		"FROM " + listRowsTable + " " +
+		"WHERE " + filter.where(ownedBy) + " " +
+		filter.orderBy("") + " " +
+		"LIMIT ? OFFSET ?;"
+	rows, err := db.Sql.Query(stmt, filter.argsWith(owner, limit, offset)...)
 
 	if err != nil {
 		return []common.ListRow{}, fmt.Errorf("error while fetching rows from %s: %w", listRowsTable, err)
@@ -238,7 +382,7 @@ func (db *DB) listRowsPaginatedFrom(listRowsTable string, searchQuery string, li
 	var sqlListRow SQLListRow
 
 	for rows.Next() {
-		err := rows.Scan(sqlListRow.RowsToScan()...)
+		err := rows.Scan(sqlListRow.RowsWithHighlightsToScan()...)
 		if err != nil {
 			return []common.ListRow{}, fmt.Errorf("error while scanning %s row: %w", listRowsTable, err)
 		}
@@ -246,13 +390,22 @@ func (db *DB) listRowsPaginatedFrom(listRowsTable string, searchQuery string, li
 		if err != nil {
 			return []common.ListRow{}, fmt.Errorf("error while converting a %s row to ListRow: %w", listRowsTable, err)
 		}
+		row.Thing = strings.TrimSuffix(listRowsTable, "_fts")
 		listRows = append(listRows, *row)
 	}
 
+	err = db.attachTags(ctx, strings.TrimSuffix(listRowsTable, "_fts"), listRows)
+	if err != nil {
+		return []common.ListRow{}, logg.WrapErr(err)
+	}
+	err = db.attachLoans(ctx, strings.TrimSuffix(listRowsTable, "_fts"), listRows)
+	if err != nil {
+		return []common.ListRow{}, logg.WrapErr(err)
+	}
 	return listRows, nil
 }
 
-func (db *DB) InnerListRowsPaginatedFrom(belongsToTable string, belongsToTableID uuid.UUID, listRowsTable string, searchQuery string, limit int, page int) (listRows []common.ListRow, err error) {
+func (db *DB) InnerListRowsPaginatedFrom(ctx context.Context, belongsToTable string, belongsToTableID uuid.UUID, listRowsTable string, searchQuery string, limit int, page int) (listRows []common.ListRow, err error) {
 	if page == 0 {
 		panic("offset starts at 1, can't be 0")
 	}
@@ -264,6 +417,14 @@ func (db *DB) InnerListRowsPaginatedFrom(belongsToTable string, belongsToTableID
 	if err != nil {
 		return nil, logg.WrapErr(err)
 	}
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	ownedBy, err := ownerCondition(listRowsTable + "_fts")
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
 
 	switch belongsToTable {
 	// case "item_fts":
@@ -282,24 +443,14 @@ func (db *DB) InnerListRowsPaginatedFrom(belongsToTable string, belongsToTableID
 
 	offset := (page - 1) * limit
 
-	var stmt string
-	var rows *sql.Rows
-
-	if strings.TrimSpace(searchQuery) != "" {
-		stmt = "" +
-			"SELECT " + ALL_FTS_COLS + " " +
-			"FROM " + listRowsTable + "_fts " +
-			"WHERE label MATCH ?" + " " + " AND " + belongsToTable + "_id = ? " +
-			"LIMIT ? OFFSET ?;"
-		rows, err = db.Sql.Query(stmt, searchQuery+"*", belongsToTableID.String(), limit, offset)
-	} else {
-		stmt = "" +
-			"SELECT " + ALL_FTS_COLS + " " +
-			"FROM " + listRowsTable + "_fts " +
-			"WHERE " + belongsToTable + "_id = ? " +
-			"LIMIT ? OFFSET ?;"
-		rows, err = db.Sql.Query(stmt, belongsToTableID.String(), limit, offset)
-	}
+	filter := newSearchFilter(listRowsTable+"_fts", searchQuery, owner)
+	stmt := "" +
+		"SELECT " + ALL_FTS_COLS + ", " + filter.highlights() + " " +
+		"FROM " + listRowsTable + "_fts " +
+		"WHERE " + filter.where(belongsToTable+"_id = ?", ownedBy) + " " +
+		filter.orderBy("") + " " +
+		"LIMIT ? OFFSET ?;"
+	rows, err := db.Sql.Query(stmt, filter.argsWith(belongsToTableID.String(), owner, limit, offset)...)
 
 	if err != nil {
 		return []common.ListRow{}, fmt.Errorf("error while fetching rows from %s: %w", belongsToTable, err)
@@ -309,7 +460,7 @@ func (db *DB) InnerListRowsPaginatedFrom(belongsToTable string, belongsToTableID
 	var sqlListRow SQLListRow
 
 	for rows.Next() {
-		err := rows.Scan(sqlListRow.RowsToScan()...)
+		err := rows.Scan(sqlListRow.RowsWithHighlightsToScan()...)
 		if err != nil {
 			return []common.ListRow{}, fmt.Errorf("error while scanning %s row: %w", belongsToTable, err)
 		}
@@ -317,9 +468,18 @@ func (db *DB) InnerListRowsPaginatedFrom(belongsToTable string, belongsToTableID
 		if err != nil {
 			return []common.ListRow{}, fmt.Errorf("error while converting a %s row to ListRow: %w", belongsToTable, err)
 		}
+		row.Thing = strings.TrimSuffix(listRowsTable, "_fts")
 		listRows = append(listRows, *row)
 	}
 
+	err = db.attachTags(ctx, listRowsTable, listRows)
+	if err != nil {
+		return []common.ListRow{}, logg.WrapErr(err)
+	}
+	err = db.attachLoans(ctx, listRowsTable, listRows)
+	if err != nil {
+		return []common.ListRow{}, logg.WrapErr(err)
+	}
 	return listRows, nil
 }
 
@@ -328,7 +488,7 @@ func (db *DB) InnerListRowsPaginatedFrom(belongsToTable string, belongsToTableID
 // Example:
 //
 //	// get all items that belongs to a shelf.
-//	innerListRowsFrom("shelf", shelf.ID, "item_fts")
+//	innerListRowsFrom(ctx, "shelf", shelf.ID, "item_fts")
 //
 // listRowsTable:
 //
@@ -345,7 +505,7 @@ func (db *DB) InnerListRowsPaginatedFrom(belongsToTable string, belongsToTableID
 //	WHERE "item"_id = ID
 //	WHERE "box"_id = ID
 //	...
-func (db *DB) innerListRowsFrom(belongsToTable string, belongsToTableID uuid.UUID, listRowsTable string) ([]*common.ListRow, error) {
+func (db *DB) innerListRowsFrom(ctx context.Context, belongsToTable string, belongsToTableID uuid.UUID, listRowsTable string) ([]*common.ListRow, error) {
 	err := ValidVirtualTable(listRowsTable)
 	if err != nil {
 		return nil, logg.WrapErr(err)
@@ -355,12 +515,20 @@ func (db *DB) innerListRowsFrom(belongsToTable string, belongsToTableID uuid.UUI
 	if err != nil {
 		return nil, logg.WrapErr(err)
 	}
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	ownedBy, err := ownerCondition(listRowsTable)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
 
 	var listRows []*common.ListRow
 
-	stmt := "SELECT " + ALL_FTS_COLS + " FROM " + listRowsTable + "	WHERE " + belongsToTable + "_id = ?;"
+	stmt := "SELECT " + ALL_FTS_COLS + " FROM " + listRowsTable + "	WHERE " + belongsToTable + "_id = ? AND " + ownedBy + ";"
 
-	rows, err := db.Sql.Query(stmt, belongsToTableID.String())
+	rows, err := db.Sql.Query(stmt, belongsToTableID.String(), owner)
 	if err != nil {
 		return nil, logg.Errorf("%s %w", stmt, err)
 	}
@@ -376,6 +544,7 @@ func (db *DB) innerListRowsFrom(belongsToTable string, belongsToTableID uuid.UUI
 		if err != nil {
 			return nil, logg.WrapErr(err)
 		}
+		lrow.Thing = strings.TrimSuffix(listRowsTable, "_fts")
 		listRows = append(listRows, lrow)
 	}
 	return listRows, nil
@@ -388,7 +557,7 @@ func (db *DB) innerListRowsFrom(belongsToTable string, belongsToTableID uuid.UUI
 // Example:
 //
 //	// get all items that belongs to a shelf.
-//	innerListRowsFrom("shelf", shelf.ID, "item_fts")
+//	innerListRowsFrom(ctx, "shelf", shelf.ID, "item_fts")
 //
 // listRowsTable:
 //
@@ -405,7 +574,7 @@ func (db *DB) innerListRowsFrom(belongsToTable string, belongsToTableID uuid.UUI
 //	WHERE "item"_id = ID
 //	WHERE "box"_id = ID
 //	...
-func (db *DB) InnerListRowsFrom2(belongsToTable string, belongsToTableID uuid.UUID, listRowsTable string) ([]common.ListRow, error) {
+func (db *DB) InnerListRowsFrom2(ctx context.Context, belongsToTable string, belongsToTableID uuid.UUID, listRowsTable string) ([]common.ListRow, error) {
 	err := ValidVirtualTable(listRowsTable)
 	if err != nil {
 		return nil, logg.WrapErr(err)
@@ -415,12 +584,20 @@ func (db *DB) InnerListRowsFrom2(belongsToTable string, belongsToTableID uuid.UU
 	if err != nil {
 		return nil, logg.WrapErr(err)
 	}
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	ownedBy, err := ownerCondition(listRowsTable)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
 
 	var listRows []common.ListRow
 
-	stmt := "SELECT " + ALL_FTS_COLS + " FROM " + listRowsTable + "	WHERE " + belongsToTable + "_id = ?;"
+	stmt := "SELECT " + ALL_FTS_COLS + " FROM " + listRowsTable + "	WHERE " + belongsToTable + "_id = ? AND " + ownedBy + ";"
 
-	rows, err := db.Sql.Query(stmt, belongsToTableID.String())
+	rows, err := db.Sql.Query(stmt, belongsToTableID.String(), owner)
 	if err != nil {
 		return nil, logg.Errorf("%s %w", stmt, err)
 	}
@@ -436,6 +613,7 @@ func (db *DB) InnerListRowsFrom2(belongsToTable string, belongsToTableID uuid.UU
 		if err != nil {
 			return nil, logg.WrapErr(err)
 		}
+		lrow.Thing = strings.TrimSuffix(listRowsTable, "_fts")
 		listRows = append(listRows, lrow)
 	}
 	return listRows, nil
@@ -446,19 +624,24 @@ func (db *DB) InnerListRowsFrom2(belongsToTable string, belongsToTableID uuid.UU
 
 // Example:
 //
-//	count, err = InnerThingInTableListCounter("box 1", THING_SHELF, fromTable, id)
-func (db *DB) InnerThingInTableListCounter(searchString string, thing int, inTable string, inTableID uuid.UUID) (count int, err error) {
+//	count, err = InnerThingInTableListCounter(ctx, "box 1", THING_SHELF, fromTable, id)
+func (db *DB) InnerThingInTableListCounter(ctx context.Context, searchString string, thing int, inTable string, inTableID uuid.UUID) (count int, err error) {
 	validThing, err := common.ValidThingString(thing)
 	if err != nil {
 		return count, logg.WrapErr(err)
 	}
-	countQuery := `SELECT COUNT(*) FROM ` + validThing + `_fts WHERE ` + inTable + `_id = ?;`
-
-	if searchString != "" {
-		countQuery = ` SELECT COUNT(*) FROM ` + validThing + `_fts WHERE label MATCH '` + searchString + `*' AND ` + inTable + `_id = ?`
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return count, logg.WrapErr(err)
+	}
+	ownedBy, err := ownerCondition(validThing + "_fts")
+	if err != nil {
+		return count, logg.WrapErr(err)
 	}
+	filter := newSearchFilter(validThing+"_fts", searchString, owner)
+	countQuery := `SELECT COUNT(*) FROM ` + validThing + `_fts WHERE ` + filter.where(inTable+"_id = ?", ownedBy) + `;`
 
-	err = db.Sql.QueryRow(countQuery, inTableID.String()).Scan(&count)
+	err = db.Sql.QueryRow(countQuery, filter.argsWith(inTableID.String(), owner)...).Scan(&count)
 	if err != nil {
 		return 0, logg.Errorf("error while fetching the number of %s from the database: %v", validThing, err)
 	}
@@ -496,6 +679,16 @@ func ifNullString(sqlStr sql.NullString) string {
 	return ""
 }
 
+// nullString returns NULL for an empty string.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// nullFloat64 returns NULL for 0.
+func nullFloat64(f float64) sql.NullFloat64 {
+	return sql.NullFloat64{Float64: f, Valid: f != 0}
+}
+
 // Helper function to check for null strings and return empty if null
 func ifNullFloat64(sqlFloat sql.NullFloat64) float64 {
 	if sqlFloat.Valid {
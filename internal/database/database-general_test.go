@@ -2,9 +2,13 @@ package database
 
 import (
 	"basement/main/internal/common"
+	"basement/main/internal/households"
+	"context"
+	"errors"
 	"testing"
 
 	"github.com/go-playground/assert/v2"
+	"github.com/gofrs/uuid/v5"
 )
 
 func TestInnerListRowsFrom2(t *testing.T) {
@@ -12,70 +16,120 @@ func TestInnerListRowsFrom2(t *testing.T) {
 	EmptyTestDatabase()
 	resetTestBoxes()
 	resetShelves()
-	dbTest.CreateNewItem(*ITEM_1)
-	dbTest.CreateBox(BOX_1)
-	dbTest.CreateBox(BOX_2)
-	dbTest.CreateShelf(SHELF_1)
-	dbTest.CreateArea(*AREA_1)
-	err = dbTest.MoveItemToBox(ITEM_1.ID, BOX_1.ID)
+	dbTest.CreateNewItem(testCtx, *ITEM_1)
+	dbTest.CreateBox(testCtx, BOX_1)
+	dbTest.CreateBox(testCtx, BOX_2)
+	dbTest.CreateShelf(testCtx, SHELF_1)
+	dbTest.CreateArea(testCtx, *AREA_1)
+	err = dbTest.MoveItemToBox(testCtx, ITEM_1.ID, BOX_1.ID)
 	assert.Equal(t, err, nil)
-	err = dbTest.MoveItemToShelf(ITEM_1.ID, SHELF_1.ID)
+	err = dbTest.MoveItemToShelf(testCtx, ITEM_1.ID, SHELF_1.ID)
 	assert.Equal(t, err, nil)
-	err = dbTest.MoveItemToArea(ITEM_1.ID, AREA_1.ID)
+	err = dbTest.MoveItemToArea(testCtx, ITEM_1.ID, AREA_1.ID)
 	assert.Equal(t, err, nil)
 
 	var rows []common.ListRow
-	rows, err = dbTest.InnerListRowsFrom2("box", BOX_1.ID, "item_fts")
+	rows, err = dbTest.InnerListRowsFrom2(testCtx, "box", BOX_1.ID, "item_fts")
 	assert.Equal(t, err, nil)
 	assert.Equal(t, len(rows), 1)
 	assert.Equal(t, rows[0].ID, ITEM_1.ID)
 
-	rows, err = dbTest.InnerListRowsFrom2("shelf", SHELF_1.ID, "item_fts")
+	rows, err = dbTest.InnerListRowsFrom2(testCtx, "shelf", SHELF_1.ID, "item_fts")
 	assert.Equal(t, err, nil)
 	assert.Equal(t, len(rows), 1)
 	assert.Equal(t, rows[0].ID, ITEM_1.ID)
 
-	rows, err = dbTest.InnerListRowsFrom2("area", AREA_1.ID, "item_fts")
+	rows, err = dbTest.InnerListRowsFrom2(testCtx, "area", AREA_1.ID, "item_fts")
 	assert.Equal(t, err, nil)
 	assert.Equal(t, len(rows), 1)
 	assert.Equal(t, rows[0].ID, ITEM_1.ID)
 
-	err = dbTest.MoveBoxToBox(BOX_1.ID, BOX_2.ID)
+	err = dbTest.MoveBoxToBox(testCtx, BOX_1.ID, BOX_2.ID)
 	assert.Equal(t, err, nil)
-	err = dbTest.MoveBoxToShelf(BOX_1.ID, SHELF_1.ID)
+	err = dbTest.MoveBoxToShelf(testCtx, BOX_1.ID, SHELF_1.ID)
 	assert.Equal(t, err, nil)
-	err = dbTest.MoveBoxToArea(BOX_1.ID, AREA_1.ID)
+	err = dbTest.MoveBoxToArea(testCtx, BOX_1.ID, AREA_1.ID)
 	assert.Equal(t, err, nil)
 
-	rows, err = dbTest.InnerListRowsFrom2("box", BOX_2.ID, "box_fts")
+	rows, err = dbTest.InnerListRowsFrom2(testCtx, "box", BOX_2.ID, "box_fts")
 	assert.Equal(t, err, nil)
 	assert.Equal(t, len(rows), 1)
 	assert.Equal(t, rows[0].ID, BOX_1.ID)
 
-	rows, err = dbTest.InnerListRowsFrom2("shelf", SHELF_1.ID, "box_fts")
+	rows, err = dbTest.InnerListRowsFrom2(testCtx, "shelf", SHELF_1.ID, "box_fts")
 	assert.Equal(t, err, nil)
 	assert.Equal(t, len(rows), 1)
 	assert.Equal(t, rows[0].ID, BOX_1.ID)
 
-	rows, err = dbTest.InnerListRowsFrom2("area", AREA_1.ID, "box_fts")
+	rows, err = dbTest.InnerListRowsFrom2(testCtx, "area", AREA_1.ID, "box_fts")
 	assert.Equal(t, err, nil)
 	assert.Equal(t, len(rows), 1)
 	assert.Equal(t, rows[0].ID, BOX_1.ID)
 
-	err = dbTest.MoveShelfToArea(SHELF_1.ID, AREA_1.ID)
+	err = dbTest.MoveShelfToArea(testCtx, SHELF_1.ID, AREA_1.ID)
 	assert.Equal(t, err, nil)
 
-	rows, err = dbTest.InnerListRowsFrom2("area", AREA_1.ID, "shelf_fts")
+	rows, err = dbTest.InnerListRowsFrom2(testCtx, "area", AREA_1.ID, "shelf_fts")
 	assert.Equal(t, err, nil)
 	assert.Equal(t, len(rows), 1)
 	assert.Equal(t, rows[0].ID, SHELF_1.ID)
 
 	// inner shelves in area
-	dbTest.CreateShelf(SHELF_2)
-	dbTest.MoveShelfToArea(SHELF_2.ID, AREA_1.ID)
-	rows, err = dbTest.InnerListRowsPaginatedFrom("area_fts", AREA_1.ID, "shelf", "", 1, 1)
+	dbTest.CreateShelf(testCtx, SHELF_2)
+	dbTest.MoveShelfToArea(testCtx, SHELF_2.ID, AREA_1.ID)
+	rows, err = dbTest.InnerListRowsPaginatedFrom(testCtx, "area_fts", AREA_1.ID, "shelf", "", 1, 1)
 	assert.Equal(t, err, nil)
 	assert.Equal(t, len(rows), 1)
 	assert.Equal(t, rows[0].ID, SHELF_1.ID)
 
 }
+
+func TestOwnerScoping(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestBoxes()
+	resetTestItems()
+	otherOwner := uuid.Must(uuid.FromString("923e4567-e89b-12d3-a456-426614174002"))
+	otherCtx := households.WithMembership(context.Background(), households.Membership{HouseholdID: otherOwner, UserID: otherOwner, Role: households.ROLE_OWNER})
+
+	err := dbTest.CreateNewItem(testCtx, *ITEM_1)
+	assert.Equal(t, err, nil)
+	_, err = dbTest.CreateBox(testCtx, BOX_1)
+	assert.Equal(t, err, nil)
+	_, err = dbTest.CreateBox(otherCtx, BOX_2)
+	assert.Equal(t, err, nil)
+
+	count, err := dbTest.BoxListCounter(testCtx, "")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, 1)
+	rows, err := dbTest.BoxListRows(otherCtx, "", 10, 1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(rows), 1)
+	assert.Equal(t, rows[0].ID, BOX_2.ID)
+	count, err = dbTest.ItemListCounter(otherCtx, "")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, 0)
+
+	_, err = dbTest.BoxById(otherCtx, BOX_1.ID)
+	assert.NotEqual(t, err, nil)
+	_, err = dbTest.ItemById(otherCtx, ITEM_1.ID)
+	assert.NotEqual(t, err, nil)
+
+	// Ids are unique across owners.
+	_, err = dbTest.CreateBox(otherCtx, BOX_1)
+	assert.NotEqual(t, err, nil)
+
+	// Can't move into or out of another owner's box.
+	err = dbTest.MoveItemToBox(testCtx, ITEM_1.ID, BOX_2.ID)
+	assert.NotEqual(t, err, nil)
+	err = dbTest.MoveBoxToBox(otherCtx, BOX_1.ID, BOX_2.ID)
+	assert.NotEqual(t, err, nil)
+
+	err = dbTest.DeleteItem(otherCtx, ITEM_1.ID)
+	assert.NotEqual(t, err, nil)
+	exists, err := dbTest.Exists(testCtx, "item", ITEM_1.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, exists, true)
+
+	_, err = dbTest.BoxListCounter(context.Background(), "")
+	assert.Equal(t, errors.Is(err, ErrNoOwner), true)
+}
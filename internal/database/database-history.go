@@ -0,0 +1,393 @@
+package database
+
+import (
+	"basement/main/internal/history"
+	"basement/main/internal/households"
+	"basement/main/internal/logg"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// columns that are not part of the before and after values of history entries.
+// The location columns are stored separately as from and to location.
+var historyIgnoredColumns = map[string]bool{
+	BASIC_INFO_ID:              true,
+	BASIC_INFO_PREVIEW_PICTURE: true,
+	OWNER_ID:                   true,
+	DELETED_AT:                 true,
+	FTS_BOX_ID:                 true,
+	FTS_SHELF_ID:               true,
+	FTS_AREA_ID:                true,
+}
+
+// querier runs statements on the database or in a transaction.
+// History entries are written with the transaction of the change so that a change is never committed without its entry.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// thingState is a snapshot of an item, box, shelf or area used to write history entries.
+type thingState struct {
+	label    string
+	values   map[string]string
+	location history.Location
+}
+
+// thingStateOf returns a snapshot of the thing with id in table read with q.
+// Returns nil if the thing doesn't exist, is in the trash or belongs to another household.
+func thingStateOf(ctx context.Context, q querier, table string, id uuid.UUID) (*thingState, error) {
+	return snapshot(ctx, q, table, id, false)
+}
+
+// trashedThingState is like thingStateOf but only returns things in the trash.
+func trashedThingState(ctx context.Context, q querier, table string, id uuid.UUID) (*thingState, error) {
+	return snapshot(ctx, q, table, id, true)
+}
+
+func snapshot(ctx context.Context, q querier, table string, id uuid.UUID, trashed bool) (*thingState, error) {
+	err := ValidTable(table)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+
+	trashCondition := NOT_DELETED
+	if trashed {
+		trashCondition = DELETED_AT + " IS NOT NULL"
+	}
+	rows, err := q.QueryContext(ctx, fmt.Sprintf(`SELECT * FROM %s WHERE id = ? AND %s = ? AND %s;`, table, OWNER_ID, trashCondition), id.String(), owner)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, logg.WrapErr(err)
+		}
+		return nil, nil
+	}
+	values := make([]sql.NullString, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	err = rows.Scan(dest...)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+
+	state := &thingState{values: map[string]string{}}
+	containers := map[string]string{}
+	for i, column := range columns {
+		value := values[i].String
+		switch {
+		case column == BASIC_INFO_LABEL:
+			state.label = value
+		case column == FTS_BOX_ID || column == FTS_SHELF_ID || column == FTS_AREA_ID:
+			containers[column] = value
+		}
+		if historyIgnoredColumns[column] {
+			continue
+		}
+		if column == BASIC_INFO_PICTURE && value != "" {
+			// Pictures are too big for the history, a checksum shows that they changed.
+			value = fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(value)))[:19]
+		}
+		state.values[column] = value
+	}
+	rows.Close()
+
+	state.location, err = innermostLocation(ctx, q, containers)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	err = relatedValues(ctx, q, table, id, owner, state.values)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	return state, nil
+}
+
+// relatedValues adds the tags, custom fields, gallery and active loan of the thing to values.
+// They are stored in tables of their own and only added if the thing has them.
+func relatedValues(ctx context.Context, q querier, table string, id uuid.UUID, owner string, values map[string]string) error {
+	var tagNames sql.NullString
+	err := q.QueryRowContext(ctx, `SELECT group_concat(name, ', ') FROM (
+		SELECT t.name FROM thing_tag AS tt JOIN tag AS t ON t.id = tt.tag_id
+		WHERE tt.thing = ? AND tt.thing_id = ? ORDER BY t.name COLLATE NOCASE);`, table, id.String()).Scan(&tagNames)
+	if err != nil {
+		return logg.Errorf(`can't read tags of %s "%s" %w`, table, id, err)
+	}
+	if tagNames.String != "" {
+		values["tags"] = tagNames.String
+	}
+
+	rows, err := q.QueryContext(ctx, `SELECT f.name, v.value FROM custom_field_value AS v
+		JOIN custom_field AS f ON f.id = v.field_id
+		WHERE v.thing_id = ? AND f.thing = ? AND f.`+OWNER_ID+` = ?;`, id.String(), table, owner)
+	if err != nil {
+		return logg.Errorf(`can't read custom fields of %s "%s" %w`, table, id, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, value string
+		err = rows.Scan(&name, &value)
+		if err != nil {
+			return logg.WrapErr(err)
+		}
+		values[fmt.Sprintf("custom field %q", name)] = value
+	}
+	if err := rows.Err(); err != nil {
+		return logg.WrapErr(err)
+	}
+	rows.Close()
+
+	// Every picture is listed with its id and caption in the order of the gallery.
+	var gallery sql.NullString
+	err = q.QueryRowContext(ctx, `SELECT group_concat(entry, ', ') FROM (
+		SELECT '#' || id || CASE WHEN caption != '' THEN ' ' || caption ELSE '' END AS entry
+		FROM picture WHERE thing = ? AND thing_id = ? ORDER BY position, id);`, table, id.String()).Scan(&gallery)
+	if err != nil {
+		return logg.Errorf(`can't read pictures of %s "%s" %w`, table, id, err)
+	}
+	if gallery.String != "" {
+		values["pictures"] = gallery.String
+	}
+
+	var borrower, dueDate string
+	err = q.QueryRowContext(ctx, `SELECT borrower, due_date FROM loan
+		WHERE thing = ? AND thing_id = ? AND returned_at IS NULL LIMIT 1;`, table, id.String()).Scan(&borrower, &dueDate)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return logg.Errorf(`can't read the loan of %s "%s" %w`, table, id, err)
+	}
+	if err == nil {
+		values["lent_to"] = borrower + " until " + dueDate
+	}
+	return nil
+}
+
+// innermostLocation returns the box, shelf or area of containers with the box being the innermost.
+func innermostLocation(ctx context.Context, q querier, containers map[string]string) (history.Location, error) {
+	for _, thing := range []string{"box", "shelf", "area"} {
+		id := uuid.FromStringOrNil(containers[thing+"_id"])
+		if id == uuid.Nil {
+			continue
+		}
+		var label sql.NullString
+		err := q.QueryRowContext(ctx, fmt.Sprintf(`SELECT label FROM %s WHERE id = ?;`, thing), id.String()).Scan(&label)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return history.Location{}, logg.WrapErr(err)
+		}
+		return history.Location{Thing: thing, ID: id, Label: label.String}, nil
+	}
+	return history.Location{}, nil
+}
+
+// recordHistory appends a history entry for the thing with id in table.
+// before is the snapshot taken with thingStateOf before the change or nil if the thing was created.
+// The state after the change and the entry are read and written with q, the transaction of the change.
+// Nothing is recorded if the thing didn't change, except for restores from the trash.
+func recordHistory(ctx context.Context, q querier, action history.Action, table string, id uuid.UUID, before *thingState) error {
+	m, ok := households.FromContext(ctx)
+	if !ok {
+		return logg.WrapErr(ErrNoOwner)
+	}
+	after, err := thingStateOf(ctx, q, table, id)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	if before == nil && after == nil {
+		return nil
+	}
+	if action != history.ACTION_RESTORE && before != nil && after != nil && before.location == after.location && maps.Equal(before.values, after.values) {
+		return nil
+	}
+
+	var label string
+	var from, to history.Location
+	var beforeJSON, afterJSON sql.NullString
+	if before != nil {
+		label = before.label
+		from = before.location
+		beforeJSON, err = historyValues(before.values)
+		if err != nil {
+			return logg.WrapErr(err)
+		}
+	}
+	if after != nil {
+		label = after.label
+		to = after.location
+		afterJSON, err = historyValues(after.values)
+		if err != nil {
+			return logg.WrapErr(err)
+		}
+	}
+
+	stmt := `INSERT INTO history (
+		owner_id, actor_id, created_at, action, thing, thing_id, label, before_values, after_values,
+		from_thing, from_id, from_label, to_thing, to_id, to_label
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+	_, err = q.ExecContext(ctx, stmt,
+		m.HouseholdID.String(), m.UserID.String(), time.Now().UTC().Format(time.RFC3339), string(action), table, id.String(), label, beforeJSON, afterJSON,
+		from.Thing, from.ID.String(), from.Label, to.Thing, to.ID.String(), to.Label,
+	)
+	if err != nil {
+		return logg.Errorf(`can't record %s of %s "%s" %w`, action, table, id, err)
+	}
+	return nil
+}
+
+// recordUpdates records an update of every thing selected by query as thing and id pairs around change,
+// which changes many things at once, like a tag they share. Everything runs in the transaction tx.
+func recordUpdates(ctx context.Context, tx *sql.Tx, change func() error, query string, args ...any) error {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	var tables []string
+	var ids []uuid.UUID
+	for rows.Next() {
+		var table, id string
+		err = rows.Scan(&table, &id)
+		if err != nil {
+			rows.Close()
+			return logg.WrapErr(err)
+		}
+		tables = append(tables, table)
+		ids = append(ids, uuid.FromStringOrNil(id))
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+
+	before := make([]*thingState, len(ids))
+	for i := range ids {
+		// Things in the trash have no state and get no entry.
+		before[i], err = thingStateOf(ctx, tx, tables[i], ids[i])
+		if err != nil {
+			return logg.WrapErr(err)
+		}
+	}
+	err = change()
+	if err != nil {
+		return err
+	}
+	for i := range ids {
+		if before[i] == nil {
+			continue
+		}
+		err = recordHistory(ctx, tx, history.ACTION_UPDATE, tables[i], ids[i], before[i])
+		if err != nil {
+			return logg.WrapErr(err)
+		}
+	}
+	return nil
+}
+
+func historyValues(values map[string]string) (sql.NullString, error) {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return sql.NullString{}, logg.WrapErr(err)
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+// History returns the changes of a single item, box, shelf or area, the latest change first.
+// Things that were deleted still have their history.
+func (db *DB) History(ctx context.Context, thing string, id uuid.UUID, limit int, page int) ([]history.Entry, error) {
+	err := ValidTable(thing)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	return db.historyEntries(ctx, "h.thing = ? AND h.thing_id = ?", []any{thing, id.String()}, limit, page)
+}
+
+// Activity returns the changes of all things of the household, the latest change first.
+func (db *DB) Activity(ctx context.Context, limit int, page int) ([]history.Entry, error) {
+	return db.historyEntries(ctx, "1 = 1", nil, limit, page)
+}
+
+func (db *DB) historyEntries(ctx context.Context, condition string, args []any, limit int, page int) ([]history.Entry, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	if limit < 1 {
+		limit = 1
+	}
+	if page < 1 {
+		page = 1
+	}
+
+	query := `
+		SELECT h.id, h.created_at, h.actor_id, COALESCE(u.username, h.actor_id), h.action, h.thing, h.thing_id, h.label,
+			h.before_values, h.after_values, h.from_thing, h.from_id, h.from_label, h.to_thing, h.to_id, h.to_label
+		FROM history AS h
+		LEFT JOIN user AS u ON u.id = h.actor_id
+		WHERE h.` + OWNER_ID + ` = ? AND ` + condition + `
+		ORDER BY h.id DESC
+		LIMIT ? OFFSET ?;`
+	params := append([]any{owner}, args...)
+	params = append(params, limit, (page-1)*limit)
+
+	rows, err := db.Sql.QueryContext(ctx, query, params...)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	defer rows.Close()
+
+	var entries []history.Entry
+	for rows.Next() {
+		var e history.Entry
+		var createdAt, actorID, action, thingID, fromID, toID string
+		var before, after sql.NullString
+		err := rows.Scan(&e.ID, &createdAt, &actorID, &e.ActorName, &action, &e.Thing, &thingID, &e.Label,
+			&before, &after, &e.From.Thing, &fromID, &e.From.Label, &e.To.Thing, &toID, &e.To.Label)
+		if err != nil {
+			return nil, logg.WrapErr(err)
+		}
+		e.Time, _ = time.Parse(time.RFC3339, createdAt)
+		e.ActorID = uuid.FromStringOrNil(actorID)
+		e.Action = history.Action(action)
+		e.ThingID = uuid.FromStringOrNil(thingID)
+		e.From.ID = uuid.FromStringOrNil(fromID)
+		e.To.ID = uuid.FromStringOrNil(toID)
+		if before.Valid {
+			err = json.Unmarshal([]byte(before.String), &e.Before)
+			if err != nil {
+				return nil, logg.WrapErr(err)
+			}
+		}
+		if after.Valid {
+			err = json.Unmarshal([]byte(after.String), &e.After)
+			if err != nil {
+				return nil, logg.WrapErr(err)
+			}
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	return entries, nil
+}
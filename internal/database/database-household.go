@@ -0,0 +1,217 @@
+package database
+
+import (
+	"basement/main/internal/households"
+	"basement/main/internal/logg"
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// PERSONAL_HOUSEHOLD_NAME is the name of the household every user owns alone.
+// The personal household has the same id as the user.
+const PERSONAL_HOUSEHOLD_NAME = "Personal"
+
+// Membership returns the role of the user in the household.
+// If householdID is uuid.Nil or the user id, the personal household of the user is returned
+// and created if it doesn't exist yet.
+// Returns households.ErrNotMember if the user is not a member of the household.
+func (db *DB) Membership(ctx context.Context, userID uuid.UUID, householdID uuid.UUID) (households.Membership, error) {
+	if householdID == uuid.Nil || householdID == userID {
+		householdID = userID
+		err := db.createPersonalHousehold(ctx, userID)
+		if err != nil {
+			return households.Membership{}, logg.WrapErr(err)
+		}
+	}
+
+	query := `
+		SELECT h.id, h.name, m.user_id, m.role
+		FROM household AS h
+		JOIN household_member AS m ON m.household_id = h.id
+		WHERE h.id = ? AND m.user_id = ?;`
+
+	var m households.Membership
+	var id, user, role string
+	err := db.Sql.QueryRowContext(ctx, query, householdID.String(), userID.String()).Scan(&id, &m.HouseholdName, &user, &role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return m, logg.Errorf(`user "%s" household "%s" %w`, userID, householdID, households.ErrNotMember)
+	}
+	if err != nil {
+		return m, logg.WrapErr(err)
+	}
+	m.HouseholdID = uuid.FromStringOrNil(id)
+	m.UserID = uuid.FromStringOrNil(user)
+	m.Role = households.Role(role)
+	return m, nil
+}
+
+// Households returns all households the user is a member of.
+func (db *DB) Households(ctx context.Context, userID uuid.UUID) ([]households.Membership, error) {
+	query := `
+		SELECT h.id, h.name, m.role
+		FROM household AS h
+		JOIN household_member AS m ON m.household_id = h.id
+		WHERE m.user_id = ?
+		ORDER BY h.name;`
+
+	rows, err := db.Sql.QueryContext(ctx, query, userID.String())
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	defer rows.Close()
+
+	var memberships []households.Membership
+	for rows.Next() {
+		var id, role string
+		m := households.Membership{UserID: userID}
+		err := rows.Scan(&id, &m.HouseholdName, &role)
+		if err != nil {
+			return nil, logg.WrapErr(err)
+		}
+		m.HouseholdID = uuid.FromStringOrNil(id)
+		m.Role = households.Role(role)
+		memberships = append(memberships, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	return memberships, nil
+}
+
+// Members returns all members of the household ordered by username.
+func (db *DB) Members(ctx context.Context, householdID uuid.UUID) ([]households.Member, error) {
+	query := `
+		SELECT m.user_id, COALESCE(u.username, m.user_id), m.role
+		FROM household_member AS m
+		LEFT JOIN user AS u ON u.id = m.user_id
+		WHERE m.household_id = ?
+		ORDER BY 2;`
+
+	rows, err := db.Sql.QueryContext(ctx, query, householdID.String())
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	defer rows.Close()
+
+	var members []households.Member
+	for rows.Next() {
+		var id, role string
+		var member households.Member
+		err := rows.Scan(&id, &member.Username, &role)
+		if err != nil {
+			return nil, logg.WrapErr(err)
+		}
+		member.UserID = uuid.FromStringOrNil(id)
+		member.Role = households.Role(role)
+		members = append(members, member)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	return members, nil
+}
+
+// CreateHousehold creates a new household with the user as owner and returns its id.
+func (db *DB) CreateHousehold(ctx context.Context, userID uuid.UUID, name string) (uuid.UUID, error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return uuid.Nil, logg.WrapErr(err)
+	}
+
+	tx, err := db.Sql.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, logg.WrapErr(err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO household (id, name) VALUES (?, ?);", id.String(), name)
+	if err != nil {
+		return uuid.Nil, logg.WrapErr(err)
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO household_member (household_id, user_id, role) VALUES (?, ?, ?);", id.String(), userID.String(), string(households.ROLE_OWNER))
+	if err != nil {
+		return uuid.Nil, logg.WrapErr(err)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return uuid.Nil, logg.WrapErr(err)
+	}
+	return id, nil
+}
+
+// AddMember adds the user with username to the household.
+// Returns ErrNotExist if there is no such user and ErrExist if the user is already a member.
+func (db *DB) AddMember(ctx context.Context, householdID uuid.UUID, username string, role households.Role) error {
+	user, err := db.UserByField(ctx, "username", username)
+	if err != nil {
+		return logg.Errorf(`user "%s" %w`, username, ErrNotExist)
+	}
+
+	result, err := db.Sql.ExecContext(ctx, `
+		INSERT OR IGNORE INTO household_member (household_id, user_id, role) VALUES (?, ?, ?);`,
+		householdID.String(), user.Id.String(), string(role))
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	if affected == 0 {
+		return logg.Errorf(`member "%s" %w`, username, ErrExist)
+	}
+	return nil
+}
+
+// RemoveMember removes the user from the household.
+// Returns households.ErrLastOwner if the user is the only owner left.
+func (db *DB) RemoveMember(ctx context.Context, householdID uuid.UUID, userID uuid.UUID) error {
+	tx, err := db.Sql.BeginTx(ctx, nil)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	defer tx.Rollback()
+
+	var owners int
+	var role string
+	err = tx.QueryRowContext(ctx, `
+		SELECT m.role, (SELECT COUNT(*) FROM household_member WHERE household_id = m.household_id AND role = ?)
+		FROM household_member AS m
+		WHERE m.household_id = ? AND m.user_id = ?;`,
+		string(households.ROLE_OWNER), householdID.String(), userID.String()).Scan(&role, &owners)
+	if errors.Is(err, sql.ErrNoRows) {
+		return logg.Errorf(`member "%s" %w`, userID, ErrNotExist)
+	}
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	if households.Role(role) == households.ROLE_OWNER && owners <= 1 {
+		return logg.WrapErr(households.ErrLastOwner)
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM household_member WHERE household_id = ? AND user_id = ?;", householdID.String(), userID.String())
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	return nil
+}
+
+// createPersonalHousehold creates the personal household of the user if it doesn't exist.
+func (db *DB) createPersonalHousehold(ctx context.Context, userID uuid.UUID) error {
+	_, err := db.Sql.ExecContext(ctx, "INSERT OR IGNORE INTO household (id, name) VALUES (?, ?);", userID.String(), PERSONAL_HOUSEHOLD_NAME)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	_, err = db.Sql.ExecContext(ctx, "INSERT OR IGNORE INTO household_member (household_id, user_id, role) VALUES (?, ?, ?);", userID.String(), userID.String(), string(households.ROLE_OWNER))
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	return nil
+}
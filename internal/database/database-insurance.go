@@ -0,0 +1,89 @@
+package database
+
+import (
+	"basement/main/internal/insurance"
+	"basement/main/internal/logg"
+	"context"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// InsuranceItems returns the items of the household of the user in ctx with a price and their purchase details.
+func (db *DB) InsuranceItems(ctx context.Context) ([]insurance.Item, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	query := `
+		SELECT id, label, COALESCE(` + ITEM_QUANTITY + `, 0), ` + ITEM_PRICE + `, COALESCE(` + ITEM_CURRENCY + `, ''),
+			COALESCE(` + ITEM_PURCHASE_DATE + `, ''), COALESCE(` + ITEM_VENDOR + `, ''), COALESCE(` + ITEM_SERIAL_NUMBER + `, ''),
+			COALESCE(` + ITEM_WARRANTY_UNTIL + `, ''), COALESCE(picture, ''), COALESCE(box_id, ''), COALESCE(shelf_id, ''), COALESCE(area_id, '')
+		FROM item
+		WHERE ` + OWNER_ID + ` = ? AND ` + NOT_DELETED + ` AND ` + ITEM_PRICE + ` > 0;`
+	rows, err := db.Sql.QueryContext(ctx, query, owner)
+	if err != nil {
+		return nil, logg.Errorf("%s %w", query, err)
+	}
+	defer rows.Close()
+
+	var items []insurance.Item
+	for rows.Next() {
+		var item insurance.Item
+		var id, boxID, shelfID, areaID string
+		err := rows.Scan(&id, &item.Label, &item.Quantity, &item.Price, &item.Currency,
+			&item.PurchaseDate, &item.Vendor, &item.SerialNumber,
+			&item.WarrantyUntil, &item.Picture, &boxID, &shelfID, &areaID)
+		if err != nil {
+			return nil, logg.WrapErr(err)
+		}
+		item.ID = uuid.FromStringOrNil(id)
+		item.BoxID = uuid.FromStringOrNil(boxID)
+		item.ShelfID = uuid.FromStringOrNil(shelfID)
+		item.AreaID = uuid.FromStringOrNil(areaID)
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	return items, nil
+}
+
+// InsurancePlaces returns the areas, shelves and boxes of the household of the user in ctx
+// with the places they are in.
+func (db *DB) InsurancePlaces(ctx context.Context) ([]insurance.Place, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	query := `
+		SELECT 'area', id, label, '', '', '' FROM area WHERE ` + OWNER_ID + ` = ? AND ` + NOT_DELETED + `
+		UNION ALL
+		SELECT 'shelf', id, label, '', '', COALESCE(area_id, '') FROM shelf WHERE ` + OWNER_ID + ` = ? AND ` + NOT_DELETED + `
+		UNION ALL
+		SELECT 'box', id, label, COALESCE(box_id, ''), COALESCE(shelf_id, ''), COALESCE(area_id, '')
+		FROM box WHERE ` + OWNER_ID + ` = ? AND ` + NOT_DELETED + `;`
+	rows, err := db.Sql.QueryContext(ctx, query, owner, owner, owner)
+	if err != nil {
+		return nil, logg.Errorf("%s %w", query, err)
+	}
+	defer rows.Close()
+
+	var places []insurance.Place
+	for rows.Next() {
+		var place insurance.Place
+		var id, boxID, shelfID, areaID string
+		err := rows.Scan(&place.Thing, &id, &place.Label, &boxID, &shelfID, &areaID)
+		if err != nil {
+			return nil, logg.WrapErr(err)
+		}
+		place.ID = uuid.FromStringOrNil(id)
+		place.BoxID = uuid.FromStringOrNil(boxID)
+		place.ShelfID = uuid.FromStringOrNil(shelfID)
+		place.AreaID = uuid.FromStringOrNil(areaID)
+		places = append(places, place)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	return places, nil
+}
@@ -3,15 +3,20 @@ package database
 import (
 	"basement/main/internal/common"
 	"basement/main/internal/env"
+	"basement/main/internal/history"
 	"basement/main/internal/items"
 	"basement/main/internal/logg"
 	"basement/main/internal/server"
+	"basement/main/internal/stock"
 	"bytes"
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"html/template"
 	"log"
 	"strings"
+	"time"
 
 	"github.com/gofrs/uuid/v5"
 )
@@ -48,22 +53,32 @@ func (s SQLBasicInfo) ToBasicInfo() (common.BasicInfo, error) {
 		ID:             id,
 		Label:          ifNullString(s.Label),
 		Description:    ifNullString(s.Description),
-		Picture:        ifNullString(s.Picture),
-		PreviewPicture: ifNullString(s.PreviewPicture),
+		Picture:        blobFromNullString(s.Picture),
+		PreviewPicture: blobFromNullString(s.PreviewPicture),
 		QRCode:         ifNullString(s.QRCode),
 	}, nil
 }
 
 type SQLItem struct {
 	SQLBasicInfo
-	Quantity   sql.NullInt64
-	Weight     sql.NullFloat64
-	BoxID      sql.NullString
-	BoxLabel   sql.NullString
-	ShelfID    sql.NullString
-	ShelfLabel sql.NullString
-	AreaID     sql.NullString
-	AreaLabel  sql.NullString
+	Quantity      sql.NullInt64
+	Weight        sql.NullFloat64
+	BoxID         sql.NullString
+	BoxLabel      sql.NullString
+	ShelfID       sql.NullString
+	ShelfLabel    sql.NullString
+	AreaID        sql.NullString
+	AreaLabel     sql.NullString
+	CategoryID    sql.NullString
+	BestBefore    sql.NullString
+	ExpiresAt     sql.NullString
+	MinStock      sql.NullInt64
+	PurchaseDate  sql.NullString
+	Price         sql.NullFloat64
+	Currency      sql.NullString
+	Vendor        sql.NullString
+	SerialNumber  sql.NullString
+	WarrantyUntil sql.NullString
 }
 
 func (i SQLItem) String() string {
@@ -80,15 +95,25 @@ func (s *SQLItem) ToItem() (*items.Item, error) {
 	}
 
 	return &items.Item{
-		BasicInfo:  info,
-		Quantity:   ifNullInt64(s.Quantity),
-		Weight:     ifNullFloat64(s.Weight),
-		BoxID:      ifNullUUID(s.BoxID),
-		BoxLabel:   ifNullString(s.BoxLabel),
-		ShelfID:    ifNullUUID(s.ShelfID),
-		ShelfLabel: ifNullString(s.ShelfLabel),
-		AreaID:     ifNullUUID(s.AreaID),
-		AreaLabel:  ifNullString(s.AreaLabel),
+		BasicInfo:     info,
+		Quantity:      ifNullInt64(s.Quantity),
+		Weight:        ifNullFloat64(s.Weight),
+		BoxID:         ifNullUUID(s.BoxID),
+		BoxLabel:      ifNullString(s.BoxLabel),
+		ShelfID:       ifNullUUID(s.ShelfID),
+		ShelfLabel:    ifNullString(s.ShelfLabel),
+		AreaID:        ifNullUUID(s.AreaID),
+		AreaLabel:     ifNullString(s.AreaLabel),
+		CategoryID:    ifNullUUID(s.CategoryID),
+		BestBefore:    ifNullString(s.BestBefore),
+		ExpiresAt:     ifNullString(s.ExpiresAt),
+		MinStock:      ifNullInt64(s.MinStock),
+		PurchaseDate:  ifNullString(s.PurchaseDate),
+		Price:         ifNullFloat64(s.Price),
+		Currency:      ifNullString(s.Currency),
+		Vendor:        ifNullString(s.Vendor),
+		SerialNumber:  ifNullString(s.SerialNumber),
+		WarrantyUntil: ifNullString(s.WarrantyUntil),
 	}, nil
 }
 
@@ -103,6 +128,8 @@ type SQLListRow struct {
 	ShelfLabel     sql.NullString
 	AreaID         sql.NullString
 	AreaLabel      sql.NullString
+	LabelHighlight sql.NullString
+	Snippet        sql.NullString
 }
 
 func (s SQLListRow) ToListRow() (*common.ListRow, error) {
@@ -115,13 +142,15 @@ func (s SQLListRow) ToListRow() (*common.ListRow, error) {
 		ID:             id,
 		Label:          ifNullString(s.Label),
 		Description:    ifNullString(s.Description),
-		PreviewPicture: ifNullString(s.PreviewPicture),
+		PreviewPicture: blobFromNullString(s.PreviewPicture),
 		BoxID:          ifNullUUID(s.BoxID),
 		BoxLabel:       ifNullString(s.BoxLabel),
 		ShelfID:        ifNullUUID(s.ShelfID),
 		ShelfLabel:     ifNullString(s.ShelfLabel),
 		AreaID:         ifNullUUID(s.AreaID),
 		AreaLabel:      ifNullString(s.AreaLabel),
+		LabelHighlight: highlightedHTML(ifNullString(s.LabelHighlight)),
+		Snippet:        highlightedHTML(ifNullString(s.Snippet)),
 	}, nil
 
 }
@@ -136,13 +165,15 @@ func (s SQLListRow) ToListRow2() (row common.ListRow, err error) {
 		ID:             id,
 		Label:          ifNullString(s.Label),
 		Description:    ifNullString(s.Description),
-		PreviewPicture: ifNullString(s.PreviewPicture),
+		PreviewPicture: blobFromNullString(s.PreviewPicture),
 		BoxID:          ifNullUUID(s.BoxID),
 		BoxLabel:       ifNullString(s.BoxLabel),
 		ShelfID:        ifNullUUID(s.ShelfID),
 		ShelfLabel:     ifNullString(s.ShelfLabel),
 		AreaID:         ifNullUUID(s.AreaID),
 		AreaLabel:      ifNullString(s.AreaLabel),
+		LabelHighlight: highlightedHTML(ifNullString(s.LabelHighlight)),
+		Snippet:        highlightedHTML(ifNullString(s.Snippet)),
 	}, nil
 
 }
@@ -157,48 +188,62 @@ func (s *SQLListRow) RowsToScan() []any {
 	}
 }
 
-// Create New Item Record
-func (db *DB) CreateNewItem(newItem items.Item) error {
-	exist, err := db.Exists("item", newItem.ID)
+// RowsWithHighlightsToScan is RowsToScan for queries that also select ftsHighlights.
+func (s *SQLListRow) RowsWithHighlightsToScan() []any {
+	return append(s.RowsToScan(), &s.LabelHighlight, &s.Snippet)
+}
+
+// Create New Item Record owned by the user in ctx.
+func (db *DB) CreateNewItem(ctx context.Context, newItem items.Item) error {
+	exist, err := db.idTaken("item", newItem.ID)
 	if exist {
 		return db.ErrorExist()
 	}
 	if err != nil {
 		return logg.WrapErr(err)
 	}
-	err = db.insertNewItem(newItem)
+	newItem.CustomFields, err = db.withCategoryDefaults(ctx, newItem.CategoryID, newItem.CustomFields)
 	if err != nil {
-		return err
+		return logg.WrapErr(err)
 	}
-	return nil
+	return db.insertNewItem(ctx, newItem)
 }
 
 // Get Item Record based on given Field
-func (db *DB) ItemByField(field string, value string) (items.Item, error) {
+func (db *DB) ItemByField(ctx context.Context, field string, value string) (items.Item, error) {
 
-	if !db.ItemExist(field, value) {
+	if !db.ItemExist(ctx, field, value) {
 		return items.Item{}, logg.WrapErr(sql.ErrNoRows)
 	}
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return items.Item{}, logg.WrapErr(err)
+	}
 
 	query := fmt.Sprintf(`
         SELECT 
           i.id, i.label, i.description, i.picture, i.preview_picture, i.quantity, COALESCE(i.weight, '') AS weight, i.qrcode, 
           COALESCE(i.box_id, '') AS box_id, COALESCE(b.label, '') AS box_label, COALESCE(i.shelf_id, '') AS shelf_id, 
-          COALESCE(s.label, '') AS shelf_label, COALESCE(i.area_id, '') AS area_id, COALESCE(a.label, '') AS area_label
+          COALESCE(s.label, '') AS shelf_label, COALESCE(i.area_id, '') AS area_id, COALESCE(a.label, '') AS area_label,
+          COALESCE(i.category_id, '') AS category_id, i.best_before, i.expires_at, i.min_stock,
+          i.purchase_date, i.price, i.currency, i.vendor, i.serial_number, i.warranty_until
         FROM item as i
         LEFT JOIN box as b ON i.box_id = b.id
         LEFT JOIN shelf as s ON i.shelf_id = s.id
         LEFT JOIN area as a ON i.area_id = a.id
-        WHERE i.%s = ?;
-      `, field)
+        WHERE i.%s = ? AND i.%s = ? AND i.%s;
+      `, field, OWNER_ID, NOT_DELETED)
 
-	row := db.Sql.QueryRow(query, value)
+	row := db.Sql.QueryRow(query, value, owner)
 
 	sqlItem := &SQLItem{}
-	err := row.Scan(
+	err = row.Scan(
 		&sqlItem.ID, &sqlItem.Label, &sqlItem.Description, &sqlItem.Picture, &sqlItem.PreviewPicture,
 		&sqlItem.Quantity, &sqlItem.Weight, &sqlItem.QRCode, &sqlItem.BoxID, &sqlItem.BoxLabel,
-		&sqlItem.ShelfID, &sqlItem.ShelfLabel, &sqlItem.AreaID, &sqlItem.AreaLabel)
+		&sqlItem.ShelfID, &sqlItem.ShelfLabel, &sqlItem.AreaID, &sqlItem.AreaLabel, &sqlItem.CategoryID,
+		&sqlItem.BestBefore, &sqlItem.ExpiresAt, &sqlItem.MinStock,
+		&sqlItem.PurchaseDate, &sqlItem.Price, &sqlItem.Currency,
+		&sqlItem.Vendor, &sqlItem.SerialNumber, &sqlItem.WarrantyUntil)
 
 	if err != nil {
 		return items.Item{}, logg.Errorf("Error while checking if the Item is available: %w ", err)
@@ -207,15 +252,36 @@ func (db *DB) ItemByField(field string, value string) (items.Item, error) {
 	if err != nil {
 		return items.Item{}, logg.WrapErr(err)
 	}
+	item.Tags, err = db.tagsOf(ctx, "item", item.ID)
+	if err != nil {
+		return items.Item{}, logg.WrapErr(err)
+	}
+	item.CustomFields, err = db.customFieldValues(ctx, "item", item.ID)
+	if err != nil {
+		return items.Item{}, logg.WrapErr(err)
+	}
+	if item.CategoryID != uuid.Nil {
+		category, err := db.Category(ctx, item.CategoryID)
+		if err != nil {
+			return items.Item{}, logg.WrapErr(err)
+		}
+		item.CategoryPath = category.PathLabel()
+		item.Unit = category.InheritedUnit
+	}
 
 	return *item, nil
 }
 
 // check if the Item exist
-func (db *DB) ItemExist(field string, value string) bool {
-	query := fmt.Sprintf("SELECT COUNT(*) FROM item WHERE %s = ?", field)
+func (db *DB) ItemExist(ctx context.Context, field string, value string) bool {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		logg.Err(err)
+		return false
+	}
+	query := fmt.Sprintf("SELECT COUNT(*) FROM item WHERE %s = ? AND %s = ? AND %s", field, OWNER_ID, NOT_DELETED)
 	var count int
-	err := db.Sql.QueryRow(query, value).Scan(&count)
+	err = db.Sql.QueryRow(query, value, owner).Scan(&count)
 	if err != nil {
 		log.Println("Error checking item existence:", err)
 		return false
@@ -224,13 +290,17 @@ func (db *DB) ItemExist(field string, value string) bool {
 }
 
 // Item returns new Item struct if id matches.
-func (db *DB) ItemById(id uuid.UUID) (*items.Item, error) {
-	item, error := db.ItemByField("id", id.String())
+func (db *DB) ItemById(ctx context.Context, id uuid.UUID) (*items.Item, error) {
+	item, error := db.ItemByField(ctx, "id", id.String())
 	return &item, error
 }
 
 // ListItemById returns a single item with less information suitable for a list row.
-func (db *DB) ItemListRowByID(id uuid.UUID) (*common.ListRow, error) {
+func (db *DB) ItemListRowByID(ctx context.Context, id uuid.UUID) (*common.ListRow, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
 	query := `
 		SELECT 
             i.id, i.label, i.preview_picture,
@@ -246,12 +316,12 @@ func (db *DB) ItemListRowByID(id uuid.UUID) (*common.ListRow, error) {
         LEFT JOIN 
             area AS a ON a.id = i.area_id 
         WHERE 
-            i.id = ?;`
-	queryRow := db.Sql.QueryRow(query, id.String())
+            i.id = ? AND i.` + OWNER_ID + ` = ? AND i.` + NOT_DELETED + `;`
+	queryRow := db.Sql.QueryRow(query, id.String(), owner)
 
 	sqlListRow := SQLListRow{}
 
-	err := queryRow.Scan(&sqlListRow.ID, &sqlListRow.Label, &sqlListRow.PreviewPicture, &sqlListRow.BoxID, &sqlListRow.BoxLabel, &sqlListRow.ShelfID, &sqlListRow.ShelfLabel, &sqlListRow.AreaID, &sqlListRow.AreaLabel)
+	err = queryRow.Scan(&sqlListRow.ID, &sqlListRow.Label, &sqlListRow.PreviewPicture, &sqlListRow.BoxID, &sqlListRow.BoxLabel, &sqlListRow.ShelfID, &sqlListRow.ShelfLabel, &sqlListRow.AreaID, &sqlListRow.AreaLabel)
 	if err != nil {
 		return nil, logg.Errorf("%s %w", query, err)
 	}
@@ -260,6 +330,7 @@ func (db *DB) ItemListRowByID(id uuid.UUID) (*common.ListRow, error) {
 	if err != nil {
 		return itemRow, logg.WrapErr(err)
 	}
+	itemRow.Thing = "item"
 
 	if env.Development() {
 		b := bytes.Buffer{}
@@ -271,9 +342,13 @@ func (db *DB) ItemListRowByID(id uuid.UUID) (*common.ListRow, error) {
 }
 
 // return items id's in array from type string
-func (db *DB) ItemIDs() (ids []uuid.UUID, err error) {
-	query := "SELECT id FROM item;"
-	rows, err := db.Sql.Query(query)
+func (db *DB) ItemIDs(ctx context.Context) (ids []uuid.UUID, err error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return ids, logg.WrapErr(err)
+	}
+	query := "SELECT id FROM item WHERE " + OWNER_ID + " = ? AND " + NOT_DELETED + ";"
+	rows, err := db.Sql.Query(query, owner)
 	if err != nil {
 		log.Printf("Error querying item records: %v", err)
 		return ids, err
@@ -300,15 +375,42 @@ func (db *DB) ItemIDs() (ids []uuid.UUID, err error) {
 
 // here we run the insert new Item query separate from the public function
 // it make the code more readable
-func (db *DB) insertNewItem(item items.Item) error {
-	updatePicture(&item.Picture, &item.PreviewPicture)
+func (db *DB) insertNewItem(ctx context.Context, item items.Item) error {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	err = db.ownedItemContainers(ctx, item)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+
+	err = updatePicture(&item.Picture, &item.PreviewPicture)
+	if err != nil {
+		return logg.Errorf("Error while processing the picture of item '%s' %w", item.Label, err)
+	}
+	picture, preview, err := storeBlobs(item.Picture, item.PreviewPicture)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
 	logg.Debug(item.Map())
+
+	tx, err := db.Sql.BeginTx(ctx, nil)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	defer tx.Rollback()
+
 	sqlStatement := `INSERT INTO item (id, label, description, picture, preview_picture, quantity, weight,
-       qrcode, box_id, shelf_id, area_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
-	result, err := db.Sql.Exec(sqlStatement, item.BasicInfo.ID.String(),
-		item.BasicInfo.Label, item.BasicInfo.Description, item.BasicInfo.Picture,
-		item.BasicInfo.PreviewPicture, item.Quantity, item.Weight, item.BasicInfo.QRCode,
-		item.BoxID.String(), item.ShelfID.String(), item.AreaID.String())
+       qrcode, box_id, shelf_id, area_id, category_id, best_before, expires_at, min_stock, purchase_date, price, currency, vendor, serial_number, warranty_until,
+       owner_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	result, err := tx.ExecContext(ctx, sqlStatement, item.BasicInfo.ID.String(),
+		item.BasicInfo.Label, item.BasicInfo.Description, picture,
+		preview, item.Quantity, item.Weight, qrCodeOrNew(item.BasicInfo.QRCode),
+		item.BoxID.String(), item.ShelfID.String(), item.AreaID.String(), nullUUID(item.CategoryID),
+		nullString(item.BestBefore), nullString(item.ExpiresAt), item.MinStock,
+		nullString(item.PurchaseDate), nullFloat64(item.Price), nullString(item.Currency),
+		nullString(item.Vendor), nullString(item.SerialNumber), nullString(item.WarrantyUntil), owner)
 	if err != nil {
 		return logg.Errorf("Error while executing create new item statement: %w", err)
 	}
@@ -320,24 +422,100 @@ func (db *DB) insertNewItem(item items.Item) error {
 	if rowsAffected != 1 {
 		return logg.NewError("item not added")
 	}
+	if picture != "" {
+		err = updateCoverPicture(ctx, tx, "item", item.ID, picture, preview)
+		if err != nil {
+			return logg.WrapErr(err)
+		}
+	}
+	err = setTags(ctx, tx, "item", item.ID, item.Tags)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	err = setCustomFields(ctx, tx, "item", item.ID, item.CustomFields)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	err = recordStockChange(ctx, tx, item.ID, item.Quantity, item.Quantity, item.MinStock, stock.REASON_CREATED)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	err = recordHistory(ctx, tx, history.ACTION_CREATE, "item", item.ID, nil)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	_, err = db.addLowStockEntries(ctx, item.ID)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
 	return nil
 }
 
+// ownedItemContainers returns an error if the box, shelf, area or category of item doesn't belong to the user in ctx.
+func (db *DB) ownedItemContainers(ctx context.Context, item items.Item) error {
+	err := db.categoryOwned(ctx, item.CategoryID)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	err = db.notOwnedByOthers(ctx, "box", item.BoxID)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	err = db.notOwnedByOthers(ctx, "shelf", item.ShelfID)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	return db.notOwnedByOthers(ctx, "area", item.AreaID)
+}
+
 // update the item based on the id
-func (db *DB) UpdateItem(item items.Item, ignorePicture bool, pictureFormat string) error {
-	var err error
+func (db *DB) UpdateItem(ctx context.Context, item items.Item, ignorePicture bool, pictureFormat string) error {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	err = db.ownedItemContainers(ctx, item)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	tx, err := db.Sql.BeginTx(ctx, nil)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	defer tx.Rollback()
+
+	before, err := thingStateOf(ctx, tx, "item", item.ID)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+
 	var sqlStatement string
 	var result sql.Result
+	var picture, preview string
 	if ignorePicture {
 		sqlStatement = `UPDATE item SET 
 			label = ?, description = ?, quantity = ?, weight = ?, 
-			qrcode = ?, box_id = ?, shelf_id = ?, area_id = ? WHERE id = ?`
+			qrcode = COALESCE(NULLIF(?, ''), qrcode), box_id = ?, shelf_id = ?, area_id = ?, category_id = ?,
+			best_before = ?, expires_at = ?, min_stock = ?, purchase_date = ?, price = ?, currency = ?,
+			vendor = ?, serial_number = ?, warranty_until = ? WHERE id = ? AND owner_id = ? AND ` + NOT_DELETED
 
-		result, err = db.Sql.Exec(sqlStatement,
+		result, err = tx.ExecContext(ctx, sqlStatement,
 			item.BasicInfo.Label, item.BasicInfo.Description, item.Quantity, item.Weight,
-			item.BasicInfo.QRCode, item.BoxID.String(), item.ShelfID.String(), item.AreaID.String(), item.BasicInfo.ID.String())
+			item.BasicInfo.QRCode, item.BoxID.String(), item.ShelfID.String(), item.AreaID.String(), nullUUID(item.CategoryID),
+			nullString(item.BestBefore), nullString(item.ExpiresAt), item.MinStock,
+			nullString(item.PurchaseDate), nullFloat64(item.Price), nullString(item.Currency),
+			nullString(item.Vendor), nullString(item.SerialNumber), nullString(item.WarrantyUntil),
+			item.BasicInfo.ID.String(), owner)
 	} else {
-		item.PreviewPicture, err = ResizeImage(item.Picture, 50, pictureFormat)
+		item.Picture, err = stripMetadataBase64(item.Picture)
+		if err != nil {
+			return logg.Errorf("Error while removing the metadata of the picture of item '%s' %w", item.Label, err)
+		}
+		item.PreviewPicture, err = ResizeImage(item.Picture, env.CurrentConfig().PreviewPictureSide(), pictureFormat)
 		if err != nil {
 			if errors.Is(err, UnsupportedImageFormat) {
 				return logg.NewError(logg.CleanLastError(err) + err.Error())
@@ -345,15 +523,25 @@ func (db *DB) UpdateItem(item items.Item, ignorePicture bool, pictureFormat stri
 				return logg.Errorf("Error while resizing picture of item '%s' to create a preview picture %w", item.Label, err)
 			}
 		}
+		picture, preview, err = storeBlobs(item.Picture, item.PreviewPicture)
+		if err != nil {
+			return logg.WrapErr(err)
+		}
 
 		sqlStatement = `UPDATE item SET 
 			label = ?, description = ?, picture = ?, preview_picture = ?, quantity = ?, 
-			weight = ?, qrcode = ?, box_id = ?, shelf_id = ?, area_id = ? WHERE id = ?`
+			weight = ?, qrcode = COALESCE(NULLIF(?, ''), qrcode), box_id = ?, shelf_id = ?, area_id = ?, category_id = ?,
+			best_before = ?, expires_at = ?, min_stock = ?, purchase_date = ?, price = ?, currency = ?,
+			vendor = ?, serial_number = ?, warranty_until = ? WHERE id = ? AND owner_id = ? AND ` + NOT_DELETED
 
-		result, err = db.Sql.Exec(sqlStatement,
-			item.BasicInfo.Label, item.BasicInfo.Description, item.BasicInfo.Picture,
-			item.BasicInfo.PreviewPicture, item.Quantity, item.Weight, item.BasicInfo.QRCode,
-			item.BoxID.String(), item.ShelfID.String(), item.AreaID.String(), item.BasicInfo.ID.String())
+		result, err = tx.ExecContext(ctx, sqlStatement,
+			item.BasicInfo.Label, item.BasicInfo.Description, picture,
+			preview, item.Quantity, item.Weight, item.BasicInfo.QRCode,
+			item.BoxID.String(), item.ShelfID.String(), item.AreaID.String(), nullUUID(item.CategoryID),
+			nullString(item.BestBefore), nullString(item.ExpiresAt), item.MinStock,
+			nullString(item.PurchaseDate), nullFloat64(item.Price), nullString(item.Currency),
+			nullString(item.Vendor), nullString(item.SerialNumber), nullString(item.WarrantyUntil),
+			item.BasicInfo.ID.String(), owner)
 	}
 
 	if err != nil {
@@ -367,13 +555,42 @@ func (db *DB) UpdateItem(item items.Item, ignorePicture bool, pictureFormat stri
 	if rowsAffected != 1 {
 		return logg.Errorf("Unexpected number of rows affected during update: %d for ID %s", rowsAffected, item.BasicInfo.ID.String())
 	}
-
+	if !ignorePicture {
+		err = updateCoverPicture(ctx, tx, "item", item.ID, picture, preview)
+		if err != nil {
+			return logg.WrapErr(err)
+		}
+	}
+	err = setTags(ctx, tx, "item", item.ID, item.Tags)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	err = setCustomFields(ctx, tx, "item", item.ID, item.CustomFields)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	err = recordStockChange(ctx, tx, item.ID, item.Quantity-snapshotQuantity(before), item.Quantity, item.MinStock, stock.REASON_EDITED)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	err = recordHistory(ctx, tx, history.ACTION_UPDATE, "item", item.ID, before)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	_, err = db.addLowStockEntries(ctx, item.ID)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
 	return nil
 }
 
 // Delete Item by Id
-func (db *DB) DeleteItem(itemId uuid.UUID) error {
-	err := db.deleteFrom("item", itemId)
+func (db *DB) DeleteItem(ctx context.Context, itemId uuid.UUID) error {
+	err := db.deleteFrom(ctx, "item", itemId)
 	if err != nil {
 		return logg.WrapErr(err)
 	}
@@ -381,9 +598,13 @@ func (db *DB) DeleteItem(itemId uuid.UUID) error {
 }
 
 // return all the available Items
-func (db *DB) Items() ([][]string, error) {
-	query := "SELECT id, label, description, picture, quantity, weight, qrcode FROM item;"
-	rows, err := db.Sql.Query(query)
+func (db *DB) Items(ctx context.Context) ([][]string, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return [][]string{}, logg.WrapErr(err)
+	}
+	query := "SELECT id, label, description, picture, quantity, weight, qrcode FROM item WHERE " + OWNER_ID + " = ? AND " + NOT_DELETED + ";"
+	rows, err := db.Sql.Query(query, owner)
 	if err != nil {
 		log.Printf("Error querying user records: %v", err)
 		return [][]string{}, err
@@ -411,25 +632,45 @@ func (db *DB) Items() ([][]string, error) {
 	return itemsArray, nil
 }
 
-// delete one item or more
-func (db *DB) DeleteItems(itemIds []uuid.UUID) error {
+// DeleteItems moves one item or more to the trash.
+func (db *DB) DeleteItems(ctx context.Context, itemIds []uuid.UUID) error {
 	if len(itemIds) == 0 {
 		return nil
 	}
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
 
 	// Create placeholders and arguments
 	placeholders := make([]string, len(itemIds))
-	args := make([]any, len(itemIds))
+	args := make([]any, 1, len(itemIds)+2)
+	args[0] = time.Now().UTC().Format(time.RFC3339)
 	for i, id := range itemIds {
 		placeholders[i] = "?"
-		args[i] = id
+		args = append(args, id)
+	}
+	args = append(args, owner)
+
+	tx, err := db.Sql.BeginTx(ctx, nil)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	defer tx.Rollback()
+
+	before := make([]*thingState, len(itemIds))
+	for i, id := range itemIds {
+		before[i], err = thingStateOf(ctx, tx, "item", id)
+		if err != nil {
+			return logg.WrapErr(err)
+		}
 	}
 
 	// Join the placeholders with commas
-	sqlStatement := `DELETE FROM item WHERE id IN (` + strings.Join(placeholders, ",") + `);`
+	sqlStatement := `UPDATE item SET ` + DELETED_AT + ` = ? WHERE id IN (` + strings.Join(placeholders, ",") + `) AND ` + OWNER_ID + ` = ? AND ` + NOT_DELETED + `;`
 
 	// Execute the query with the item IDs as arguments
-	result, err := db.Sql.Exec(sqlStatement, args...)
+	result, err := tx.ExecContext(ctx, sqlStatement, args...)
 	if err != nil {
 		logg.Err(err)
 		return err
@@ -446,15 +687,21 @@ func (db *DB) DeleteItems(itemIds []uuid.UUID) error {
 		return err
 	}
 
-	return nil
+	for i, id := range itemIds {
+		err = recordHistory(ctx, tx, history.ACTION_DELETE, "item", id, before[i])
+		if err != nil {
+			return logg.WrapErr(err)
+		}
+	}
+	return tx.Commit()
 }
 
 // MoveItemToBox moves item to a box.
 // To move item out of a box set
 //
 //	id2 = uuid.Nil
-func (db *DB) MoveItemToBox(id1 uuid.UUID, id2 uuid.UUID) error {
-	err := db.moveTo("item", id1, "box", id2)
+func (db *DB) MoveItemToBox(ctx context.Context, id1 uuid.UUID, id2 uuid.UUID) error {
+	err := db.moveTo(ctx, "item", id1, "box", id2)
 	if err != nil {
 		return logg.WrapErr(err)
 	}
@@ -465,8 +712,8 @@ func (db *DB) MoveItemToBox(id1 uuid.UUID, id2 uuid.UUID) error {
 // To move item out of a shelf set
 //
 //	toShelfID = uuid.Nil
-func (db *DB) MoveItemToShelf(itemID uuid.UUID, toShelfID uuid.UUID) error {
-	err := db.moveTo("item", itemID, "shelf", toShelfID)
+func (db *DB) MoveItemToShelf(ctx context.Context, itemID uuid.UUID, toShelfID uuid.UUID) error {
+	err := db.moveTo(ctx, "item", itemID, "shelf", toShelfID)
 	if err != nil {
 		return logg.WrapErr(err)
 	}
@@ -477,8 +724,8 @@ func (db *DB) MoveItemToShelf(itemID uuid.UUID, toShelfID uuid.UUID) error {
 // To move item out of a shelf set
 //
 //	toShelfID = uuid.Nil
-func (db *DB) MoveItemToArea(itemID uuid.UUID, toAreaID uuid.UUID) error {
-	err := db.moveTo("item", itemID, "area", toAreaID)
+func (db *DB) MoveItemToArea(ctx context.Context, itemID uuid.UUID, toAreaID uuid.UUID) error {
+	err := db.moveTo(ctx, "item", itemID, "area", toAreaID)
 	if err != nil {
 		return logg.WrapErr(err)
 	}
@@ -487,8 +734,8 @@ func (db *DB) MoveItemToArea(itemID uuid.UUID, toAreaID uuid.UUID) error {
 
 // ItemListRows retrieves items by label.
 // If the query is empty or contains only spaces, it returns default results.
-func (db *DB) ItemListRows(searchString string, limit int, pageNr int) (shelfRows []common.ListRow, err error) {
-	shelfRows, err = db.listRowsPaginatedFrom("item_fts", searchString, limit, pageNr)
+func (db *DB) ItemListRows(ctx context.Context, searchString string, limit int, pageNr int) (shelfRows []common.ListRow, err error) {
+	shelfRows, err = db.listRowsPaginatedFrom(ctx, "item_fts", searchString, limit, pageNr)
 	if err != nil {
 		return shelfRows, logg.WrapErr(err)
 	}
@@ -498,27 +745,116 @@ func (db *DB) ItemListRows(searchString string, limit int, pageNr int) (shelfRow
 // ShelfCounter returns the count of rows in the shelf_fts table that match
 // the specified queryString.
 // If queryString is empty, it returns the count of all rows in the table.
-func (db *DB) ItemListCounter(queryString string) (count int, err error) {
-	countQuery := `SELECT COUNT(*) FROM item_fts;`
-
-	if queryString != "" {
-		countQuery = fmt.Sprintf(`
-			SELECT COUNT(*)
-			FROM item_fts
-      WHERE label MATCH '%s*' `, queryString)
+func (db *DB) ItemListCounter(ctx context.Context, queryString string) (count int, err error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return 0, logg.WrapErr(err)
 	}
+	ownedBy, _ := ownerCondition("item_fts")
+	filter := newSearchFilter("item_fts", queryString, owner)
+	countQuery := `SELECT COUNT(*) FROM item_fts WHERE ` + filter.where(ownedBy) + `;`
 
-	err = db.Sql.QueryRow(countQuery).Scan(&count)
+	err = db.Sql.QueryRow(countQuery, filter.argsWith(owner)...).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("error while fetching the number of Items from the Database: %v", err)
 	}
 	return count, nil
 }
 
+// itemListFilter returns the search filter for the item_fts rows of the user in ctx
+// whose item matches the condition on the item table.
+func itemListFilter(ctx context.Context, searchQuery string, condition string, args ...any) (searchFilter, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return searchFilter{}, logg.WrapErr(err)
+	}
+	filter := newSearchFilter("item_fts", searchQuery, owner)
+	filter.conditions = append(filter.conditions, "id IN (SELECT id FROM item WHERE "+OWNER_ID+" = ? AND "+NOT_DELETED+" AND "+condition+")")
+	filter.args = append(filter.args, append([]any{owner}, args...)...)
+	return filter, nil
+}
+
+// itemColumn returns a subquery that selects a column of the item of an item_fts row.
+func itemColumn(column string) string {
+	return "(SELECT " + column + " FROM item WHERE item.id = item_fts.id)"
+}
+
+// itemListCount returns the amount of item_fts rows that match the filter.
+func (db *DB) itemListCount(ctx context.Context, filter searchFilter) (count int, err error) {
+	query := "SELECT COUNT(*) FROM item_fts WHERE " + filter.where() + ";"
+	err = db.Sql.QueryRowContext(ctx, query, filter.argsWith()...).Scan(&count)
+	if err != nil {
+		return 0, logg.Errorf("%s %w", query, err)
+	}
+	return count, nil
+}
+
+// itemListRows returns a page of the item_fts rows that match the filter.
+// The item columns are selected as text, NULL as "", and passed to snippet to fill the snippet of each row.
+//
+// Panics if page or limit is zero, both must be at least 1.
+func (db *DB) itemListRows(ctx context.Context, filter searchFilter, columns []string, limit int, page int, snippet func(values []string) template.HTML) ([]common.ListRow, error) {
+	if page == 0 {
+		panic("offset starts at 1, can't be 0")
+	}
+	if limit == 0 {
+		panic("limit starts at 1, can't be 0")
+	}
+	selected := ""
+	for _, column := range columns {
+		selected += ", COALESCE(" + itemColumn(column) + ", '')"
+	}
+	stmt := "" +
+		"SELECT " + ALL_FTS_COLS + ", " + filter.highlights() + selected + " " +
+		"FROM item_fts " +
+		"WHERE " + filter.where() + " " +
+		filter.orderBy("") + " " +
+		"LIMIT ? OFFSET ?;"
+	rows, err := db.Sql.QueryContext(ctx, stmt, filter.argsWith(limit, (page-1)*limit)...)
+	if err != nil {
+		return nil, logg.Errorf("%s %w", stmt, err)
+	}
+	defer rows.Close()
+
+	var listRows []common.ListRow
+	for rows.Next() {
+		var sqlListRow SQLListRow
+		values := make([]string, len(columns))
+		dest := sqlListRow.RowsWithHighlightsToScan()
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		err := rows.Scan(dest...)
+		if err != nil {
+			return nil, logg.WrapErr(err)
+		}
+		row, err := sqlListRow.ToListRow()
+		if err != nil {
+			return nil, logg.WrapErr(err)
+		}
+		row.Thing = "item"
+		row.Snippet = snippet(values)
+		listRows = append(listRows, *row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, logg.WrapErr(err)
+	}
+
+	err = db.attachTags(ctx, "item", listRows)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	err = db.attachLoans(ctx, "item", listRows)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	return listRows, nil
+}
+
 // @TODELETE after 01/03.2025
-func (db *DB) AddItemToArea(itemID uuid.UUID, toAreaID uuid.UUID) error {
-	item, err := db.ItemById(itemID)
-	area, err := db.AreaById(toAreaID)
+func (db *DB) AddItemToArea(ctx context.Context, itemID uuid.UUID, toAreaID uuid.UUID) error {
+	item, err := db.ItemById(ctx, itemID)
+	area, err := db.AreaById(ctx, toAreaID)
 	if err != nil {
 		return logg.WrapErr(err)
 	}
@@ -526,7 +862,7 @@ func (db *DB) AddItemToArea(itemID uuid.UUID, toAreaID uuid.UUID) error {
 	item.AreaID = area.ID
 	item.AreaLabel = area.Label
 
-	err = db.UpdateItem(*item, false, "image/png")
+	err = db.UpdateItem(ctx, *item, false, "image/png")
 	if err != nil {
 		return logg.WrapErr(err)
 	}
@@ -535,9 +871,9 @@ func (db *DB) AddItemToArea(itemID uuid.UUID, toAreaID uuid.UUID) error {
 }
 
 // @TODELETE after 01/03/2025
-func (db *DB) AddItemToShelf(itemID uuid.UUID, toShelfID uuid.UUID) error {
-	item, err := db.ItemById(itemID)
-	shelf, err := db.Shelf(toShelfID)
+func (db *DB) AddItemToShelf(ctx context.Context, itemID uuid.UUID, toShelfID uuid.UUID) error {
+	item, err := db.ItemById(ctx, itemID)
+	shelf, err := db.Shelf(ctx, toShelfID)
 	if err != nil {
 		return logg.WrapErr(err)
 	}
@@ -545,7 +881,7 @@ func (db *DB) AddItemToShelf(itemID uuid.UUID, toShelfID uuid.UUID) error {
 	item.ShelfID = shelf.ID
 	item.ShelfLabel = shelf.Label
 
-	err = db.UpdateItem(*item, false, "image/png")
+	err = db.UpdateItem(ctx, *item, false, "image/png")
 	if err != nil {
 		return logg.WrapErr(err)
 	}
@@ -554,16 +890,16 @@ func (db *DB) AddItemToShelf(itemID uuid.UUID, toShelfID uuid.UUID) error {
 }
 
 // @TODELETE after 01/03/2025
-func (db *DB) AddItemToBox(itemID uuid.UUID, boxID uuid.UUID) error {
-	item, err := db.ItemById(itemID)
-	box, err := db.BoxById(boxID)
+func (db *DB) AddItemToBox(ctx context.Context, itemID uuid.UUID, boxID uuid.UUID) error {
+	item, err := db.ItemById(ctx, itemID)
+	box, err := db.BoxById(ctx, boxID)
 	if err != nil {
 		return logg.WrapErr(err)
 	}
 	item.BoxID = box.ID
 	item.BoxLabel = box.Label
 
-	err = db.UpdateItem(*item, false, "image/png")
+	err = db.UpdateItem(ctx, *item, false, "image/png")
 	if err != nil {
 		return logg.WrapErr(err)
 	}
@@ -571,15 +907,15 @@ func (db *DB) AddItemToBox(itemID uuid.UUID, boxID uuid.UUID) error {
 }
 
 // @TODELETE after 01/03/2025
-func (db *DB) MoveItemToObject(itemID uuid.UUID, objectID uuid.UUID, objectType string) error {
-	item, err := db.ItemById(itemID)
+func (db *DB) MoveItemToObject(ctx context.Context, itemID uuid.UUID, objectID uuid.UUID, objectType string) error {
+	item, err := db.ItemById(ctx, itemID)
 	if err != nil {
 		return logg.WrapErr(err)
 	}
 
 	switch objectType {
 	case "area":
-		area, err := db.AreaById(objectID)
+		area, err := db.AreaById(ctx, objectID)
 		if err != nil {
 			return logg.WrapErr(err)
 		}
@@ -587,7 +923,7 @@ func (db *DB) MoveItemToObject(itemID uuid.UUID, objectID uuid.UUID, objectType
 		item.AreaLabel = area.Label
 
 	case "shelf":
-		shelf, err := db.Shelf(objectID)
+		shelf, err := db.Shelf(ctx, objectID)
 		if err != nil {
 			return logg.WrapErr(err)
 		}
@@ -595,7 +931,7 @@ func (db *DB) MoveItemToObject(itemID uuid.UUID, objectID uuid.UUID, objectType
 		item.ShelfLabel = shelf.Label
 
 	case "box":
-		box, err := db.BoxById(objectID)
+		box, err := db.BoxById(ctx, objectID)
 		if err != nil {
 			return logg.WrapErr(err)
 		}
@@ -606,7 +942,7 @@ func (db *DB) MoveItemToObject(itemID uuid.UUID, objectID uuid.UUID, objectType
 		return logg.WrapErr(fmt.Errorf("invalid object type: %s", objectType))
 	}
 
-	err = db.UpdateItem(*item, false, "image/png")
+	err = db.UpdateItem(ctx, *item, false, "image/png")
 	if err != nil {
 		return logg.WrapErr(err)
 	}
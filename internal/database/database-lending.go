@@ -0,0 +1,280 @@
+package database
+
+import (
+	"basement/main/internal/common"
+	"basement/main/internal/history"
+	"basement/main/internal/households"
+	"basement/main/internal/lending"
+	"basement/main/internal/logg"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// lentThingsCTE is the table "lent" with every item and box of an owner that is lent out,
+// either by its own loan or with a box it is in. The owner id is the only query parameter.
+const lentThingsCTE = `WITH RECURSIVE lent(thing, id, loan_id) AS (
+		SELECT thing, thing_id, id FROM loan WHERE ` + OWNER_ID + ` = ? AND returned_at IS NULL
+		UNION
+		SELECT 'box', box.id, lent.loan_id FROM box JOIN lent ON lent.thing = 'box' AND box.box_id = lent.id
+		UNION
+		SELECT 'item', item.id, lent.loan_id FROM item JOIN lent ON lent.thing = 'box' AND item.box_id = lent.id
+	)`
+
+// selectLoansStmt selects loans with the label of the lent thing.
+const selectLoansStmt = `
+	SELECT l.id, l.thing, l.thing_id, COALESCE(i.label, b.label, ''), l.borrower, l.lent_at, l.due_date,
+		COALESCE(l.returned_at, ''), COALESCE(l.actor_id, '')
+	FROM loan AS l
+	LEFT JOIN item AS i ON l.thing = 'item' AND i.id = l.thing_id
+	LEFT JOIN box AS b ON l.thing = 'box' AND b.id = l.thing_id`
+
+func scanLoan(row interface{ Scan(dest ...any) error }) (lending.Loan, error) {
+	var loan lending.Loan
+	var thingID, lentAt, returnedAt, actorID string
+	err := row.Scan(&loan.ID, &loan.Thing, &thingID, &loan.Label, &loan.Borrower, &lentAt, &loan.DueDate, &returnedAt, &actorID)
+	if err != nil {
+		return lending.Loan{}, err
+	}
+	loan.ThingID = uuid.FromStringOrNil(thingID)
+	loan.LentAt, _ = time.Parse(time.RFC3339, lentAt)
+	if returned, err := time.Parse(time.RFC3339, returnedAt); err == nil {
+		loan.ReturnedAt = &returned
+	}
+	loan.ActorID = uuid.FromStringOrNil(actorID)
+	return loan, nil
+}
+
+// Lend lends the item or box with the id to the borrower until the due date.
+// Returns lending.ErrAlreadyLent if the thing or a box it is in is lent out.
+func (db *DB) Lend(ctx context.Context, thing string, id uuid.UUID, borrower string, dueDate string) (lending.Loan, error) {
+	if !slices.Contains(lending.Things, thing) {
+		return lending.Loan{}, logg.NewError(fmt.Sprintf(`"%s" can't be lent out`, thing))
+	}
+	m, ok := households.FromContext(ctx)
+	if !ok {
+		return lending.Loan{}, logg.WrapErr(ErrNoOwner)
+	}
+	owned, err := db.Exists(ctx, thing, id)
+	if err != nil {
+		return lending.Loan{}, logg.WrapErr(err)
+	}
+	if !owned {
+		return lending.Loan{}, logg.Errorf(`%s "%s" %w`, thing, id, ErrNotExist)
+	}
+
+	tx, err := db.Sql.BeginTx(ctx, nil)
+	if err != nil {
+		return lending.Loan{}, logg.WrapErr(err)
+	}
+	defer tx.Rollback()
+
+	current, err := thingLoan(ctx, tx, thing, id)
+	if err != nil {
+		return lending.Loan{}, logg.WrapErr(err)
+	}
+	if current != nil {
+		return lending.Loan{}, logg.Errorf(`%s "%s" is lent to %s %w`, thing, id, current.Borrower, lending.ErrAlreadyLent)
+	}
+	before, err := thingStateOf(ctx, tx, thing, id)
+	if err != nil {
+		return lending.Loan{}, logg.WrapErr(err)
+	}
+	stmt := `INSERT INTO loan (owner_id, thing, thing_id, borrower, lent_at, due_date, actor_id) VALUES (?, ?, ?, ?, ?, ?, ?) RETURNING id;`
+	lentAt := time.Now().UTC().Truncate(time.Second)
+	var loanID int64
+	err = tx.QueryRowContext(ctx, stmt,
+		m.HouseholdID.String(), thing, id.String(), borrower, lentAt.Format(time.RFC3339), dueDate, m.UserID.String(),
+	).Scan(&loanID)
+	if uniqueViolation(err) {
+		// Lent out by a concurrent request after the check above.
+		return lending.Loan{}, logg.Errorf(`%s "%s" %w`, thing, id, lending.ErrAlreadyLent)
+	}
+	if err != nil {
+		return lending.Loan{}, logg.Errorf("can't lend %s %s %w", thing, id, err)
+	}
+	err = recordHistory(ctx, tx, history.ACTION_UPDATE, thing, id, before)
+	if err != nil {
+		return lending.Loan{}, logg.WrapErr(err)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return lending.Loan{}, logg.WrapErr(err)
+	}
+	return db.loan(ctx, loanID)
+}
+
+// ReturnLoan marks the loan with the id as returned.
+// Returns ErrNotExist if the household of the user in ctx has no such loan that isn't returned yet.
+func (db *DB) ReturnLoan(ctx context.Context, id int64) (lending.Loan, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return lending.Loan{}, logg.WrapErr(err)
+	}
+	loan, err := db.loan(ctx, id)
+	if err != nil {
+		return lending.Loan{}, logg.WrapErr(err)
+	}
+	tx, err := db.Sql.BeginTx(ctx, nil)
+	if err != nil {
+		return lending.Loan{}, logg.WrapErr(err)
+	}
+	defer tx.Rollback()
+
+	before, err := thingStateOf(ctx, tx, loan.Thing, loan.ThingID)
+	if err != nil {
+		return lending.Loan{}, logg.WrapErr(err)
+	}
+	stmt := `UPDATE loan SET returned_at = ? WHERE id = ? AND ` + OWNER_ID + ` = ? AND returned_at IS NULL;`
+	result, err := tx.ExecContext(ctx, stmt, time.Now().UTC().Format(time.RFC3339), id, owner)
+	if err != nil {
+		return lending.Loan{}, logg.Errorf("can't return loan %d %w", id, err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return lending.Loan{}, logg.WrapErr(err)
+	}
+	if rows == 0 {
+		return lending.Loan{}, logg.Errorf(`loan "%d" %w`, id, ErrNotExist)
+	}
+	err = recordHistory(ctx, tx, history.ACTION_UPDATE, loan.Thing, loan.ThingID, before)
+	if err != nil {
+		return lending.Loan{}, logg.WrapErr(err)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return lending.Loan{}, logg.WrapErr(err)
+	}
+	return db.loan(ctx, id)
+}
+
+func (db *DB) loan(ctx context.Context, id int64) (lending.Loan, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return lending.Loan{}, logg.WrapErr(err)
+	}
+	query := selectLoansStmt + ` WHERE l.id = ? AND l.` + OWNER_ID + ` = ?;`
+	loan, err := scanLoan(db.Sql.QueryRowContext(ctx, query, id, owner))
+	if errors.Is(err, sql.ErrNoRows) {
+		return lending.Loan{}, logg.Errorf(`loan "%d" %w`, id, ErrNotExist)
+	}
+	if err != nil {
+		return lending.Loan{}, logg.Errorf("%s %w", query, err)
+	}
+	return loan, nil
+}
+
+// Loans returns the loans of the household of the user in ctx that aren't returned yet, the earliest due first.
+func (db *DB) Loans(ctx context.Context) ([]lending.Loan, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	query := selectLoansStmt + ` WHERE l.` + OWNER_ID + ` = ? AND l.returned_at IS NULL ORDER BY l.due_date, l.id;`
+	rows, err := db.Sql.QueryContext(ctx, query, owner)
+	if err != nil {
+		return nil, logg.Errorf("%s %w", query, err)
+	}
+	defer rows.Close()
+
+	var loans []lending.Loan
+	for rows.Next() {
+		loan, err := scanLoan(rows)
+		if err != nil {
+			return nil, logg.WrapErr(err)
+		}
+		loans = append(loans, loan)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	return loans, nil
+}
+
+// ThingLoan returns the loan the item or box with the id is lent out with, nil if it isn't lent out.
+// This is the loan of the box it is in if the box is lent out.
+func (db *DB) ThingLoan(ctx context.Context, thing string, id uuid.UUID) (*lending.Loan, error) {
+	return thingLoan(ctx, db.Sql, thing, id)
+}
+
+func thingLoan(ctx context.Context, q querier, thing string, id uuid.UUID) (*lending.Loan, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	query := lentThingsCTE + selectLoansStmt + `
+		WHERE l.id = (SELECT loan_id FROM lent WHERE thing = ? AND id = ? ORDER BY loan_id LIMIT 1);`
+	loan, err := scanLoan(q.QueryRowContext(ctx, query, owner, thing, id.String()))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, logg.Errorf("%s %w", query, err)
+	}
+	return &loan, nil
+}
+
+// attachLoans sets the borrower and due date of all rows that are lent out, which are all of the same type of thing.
+// Only items and boxes can be lent out.
+func (db *DB) attachLoans(ctx context.Context, thing string, rows []common.ListRow) error {
+	if len(rows) == 0 || !slices.Contains(lending.Things, thing) {
+		return nil
+	}
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	args := []any{owner, thing}
+	for _, row := range rows {
+		args = append(args, row.ID.String())
+	}
+	query := lentThingsCTE + `
+		SELECT lent.id, loan.borrower, loan.due_date
+		FROM lent JOIN loan ON loan.id = lent.loan_id
+		WHERE lent.thing = ? AND lent.id IN (?` + strings.Repeat(", ?", len(rows)-1) + `)
+		ORDER BY loan.id DESC;`
+	result, err := db.Sql.QueryContext(ctx, query, args...)
+	if err != nil {
+		return logg.Errorf("can't query loans of %s %w", thing, err)
+	}
+	defer result.Close()
+
+	// The oldest loan is the last one, it wins if a thing is lent out more than once.
+	loans := map[string]lending.Loan{}
+	for result.Next() {
+		var id string
+		var loan lending.Loan
+		err := result.Scan(&id, &loan.Borrower, &loan.DueDate)
+		if err != nil {
+			return logg.WrapErr(err)
+		}
+		loans[id] = loan
+	}
+	if err := result.Err(); err != nil {
+		return logg.WrapErr(err)
+	}
+	for i := range rows {
+		if loan, ok := loans[rows[i].ID.String()]; ok {
+			rows[i].LentTo = loan.Borrower
+			rows[i].LentUntil = loan.DueDate
+		}
+	}
+	return nil
+}
+
+// deleteOrphanedLoans removes the loans of purged items and boxes.
+func (db *DB) deleteOrphanedLoans(ctx context.Context) error {
+	_, err := db.Sql.ExecContext(ctx, `DELETE FROM loan
+		WHERE (thing = 'item' AND thing_id NOT IN (SELECT id FROM item))
+			OR (thing = 'box' AND thing_id NOT IN (SELECT id FROM box));`)
+	if err != nil {
+		return logg.Errorf("can't delete loans of purged things %w", err)
+	}
+	return nil
+}
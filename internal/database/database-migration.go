@@ -0,0 +1,398 @@
+package database
+
+import (
+	"basement/main/internal/households"
+	"basement/main/internal/logg"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// migration is a single versioned schema change.
+//
+// Statements are executed in order inside one transaction together with the
+// schema_version bookkeeping, so a migration is either fully applied or not at all.
+// Changes that SQL can't express are done by data, which runs after the statements.
+// dryRun is true if tx is rolled back afterwards, then data must not change anything outside of the database.
+type migration struct {
+	version    int
+	name       string
+	statements []string
+	data       func(tx *sql.Tx, dryRun bool) error
+}
+
+// migrations holds all schema changes in ascending version order.
+//
+// The CREATE statements in mainTables, virtualTables and triggers describe version 0
+// and must never be changed. Every later change to the schema is appended here
+// with the next version number.
+var migrations = []migration{
+	{version: 1, name: "add owner to things", statements: []string{
+		// Existing things belong to the account that was registered first.
+		"ALTER TABLE item ADD COLUMN " + OWNER_ID + " TEXT REFERENCES user(id);",
+		"ALTER TABLE box ADD COLUMN " + OWNER_ID + " TEXT REFERENCES user(id);",
+		"ALTER TABLE shelf ADD COLUMN " + OWNER_ID + " TEXT REFERENCES user(id);",
+		"ALTER TABLE area ADD COLUMN " + OWNER_ID + " TEXT REFERENCES user(id);",
+		"UPDATE item SET " + OWNER_ID + " = (SELECT id FROM user ORDER BY rowid LIMIT 1);",
+		"UPDATE box SET " + OWNER_ID + " = (SELECT id FROM user ORDER BY rowid LIMIT 1);",
+		"UPDATE shelf SET " + OWNER_ID + " = (SELECT id FROM user ORDER BY rowid LIMIT 1);",
+		"UPDATE area SET " + OWNER_ID + " = (SELECT id FROM user ORDER BY rowid LIMIT 1);",
+		"CREATE INDEX item_owner_id ON item(" + OWNER_ID + ");",
+		"CREATE INDEX box_owner_id ON box(" + OWNER_ID + ");",
+		"CREATE INDEX shelf_owner_id ON shelf(" + OWNER_ID + ");",
+		"CREATE INDEX area_owner_id ON area(" + OWNER_ID + ");",
+	}},
+	{version: 2, name: "add households", statements: []string{
+		CREATE_HOUSEHOLD_TABLE_STMT,
+		CREATE_HOUSEHOLD_MEMBER_TABLE_STMT,
+		"CREATE INDEX household_member_user_id ON household_member(user_id);",
+		// Every user gets a personal household with the same id,
+		// so owner_id of existing things now refers to this household.
+		"INSERT INTO household (id, name) SELECT id, '" + PERSONAL_HOUSEHOLD_NAME + "' FROM user;",
+		"INSERT INTO household_member (household_id, user_id, role) SELECT id, id, '" + string(households.ROLE_OWNER) + "' FROM user;",
+	}},
+	{version: 3, name: "add history", statements: []string{
+		CREATE_HISTORY_TABLE_STMT,
+		CREATE_HISTORY_UPDATE_TRIGGER,
+		CREATE_HISTORY_DELETE_TRIGGER,
+		"CREATE INDEX history_owner_id ON history(" + OWNER_ID + ", id);",
+		"CREATE INDEX history_thing_id ON history(thing_id);",
+	}},
+	{version: 4, name: "add trash", statements: []string{
+		"ALTER TABLE item ADD COLUMN " + DELETED_AT + " TEXT;",
+		"ALTER TABLE box ADD COLUMN " + DELETED_AT + " TEXT;",
+		"ALTER TABLE shelf ADD COLUMN " + DELETED_AT + " TEXT;",
+		"ALTER TABLE area ADD COLUMN " + DELETED_AT + " TEXT;",
+		trashTrigger("item"),
+		trashTrigger("box"),
+		trashTrigger("shelf"),
+		trashTrigger("area"),
+		CREATE_ITEM_RESTORE_TRIGGER,
+		CREATE_BOX_RESTORE_TRIGGER,
+		CREATE_SHELF_RESTORE_TRIGGER,
+		CREATE_AREA_RESTORE_TRIGGER,
+	}},
+	{version: 5, name: "add move operations", statements: []string{
+		CREATE_MOVE_OPERATION_TABLE_STMT,
+		CREATE_MOVE_OPERATION_THING_TABLE_STMT,
+		"CREATE INDEX move_operation_thing_operation_id ON move_operation_thing(operation_id);",
+	}},
+	{version: 6, name: "add tags", statements: []string{
+		CREATE_TAG_TABLE_STMT,
+		CREATE_THING_TAG_TABLE_STMT,
+		"CREATE INDEX thing_tag_tag_id ON thing_tag(tag_id);",
+	}},
+	{version: 7, name: "add custom fields", statements: []string{
+		CREATE_CUSTOM_FIELD_TABLE_STMT,
+		CREATE_CUSTOM_FIELD_VALUE_TABLE_STMT,
+		"CREATE INDEX custom_field_value_thing_id ON custom_field_value(thing_id);",
+	}},
+	{version: 8, name: "add categories", statements: []string{
+		CREATE_CATEGORY_TABLE_STMT,
+		CREATE_CATEGORY_DEFAULT_TABLE_STMT,
+		"CREATE INDEX category_parent_id ON category(parent_id);",
+		"ALTER TABLE item ADD COLUMN " + ITEM_CATEGORY_ID + " TEXT REFERENCES category(id);",
+		"CREATE INDEX item_category_id ON item(" + ITEM_CATEGORY_ID + ");",
+	}},
+	{version: 9, name: "add expiry dates", statements: []string{
+		"ALTER TABLE item ADD COLUMN " + ITEM_BEST_BEFORE + " TEXT;",
+		"ALTER TABLE item ADD COLUMN " + ITEM_EXPIRES_AT + " TEXT;",
+		"CREATE INDEX item_expiry_date ON item(" + OWNER_ID + ", " + ITEM_EXPIRY_DATE + ");",
+	}},
+	{version: 10, name: "add stock changes", statements: []string{
+		"ALTER TABLE item ADD COLUMN " + ITEM_MIN_STOCK + " INTEGER NOT NULL DEFAULT 0;",
+		CREATE_STOCK_CHANGE_TABLE_STMT,
+		"CREATE INDEX stock_change_item_id ON stock_change(item_id, id);",
+		// The charts of existing items start with their current quantity.
+		"INSERT INTO stock_change (owner_id, item_id, created_at, delta, quantity, min_stock, reason) " +
+			"SELECT " + OWNER_ID + ", id, strftime('%Y-%m-%dT%H:%M:%SZ', 'now'), COALESCE(" + ITEM_QUANTITY + ", 0), COALESCE(" + ITEM_QUANTITY + ", 0), 0, 'Stock before the consumption log' " +
+			"FROM item WHERE " + OWNER_ID + " IS NOT NULL;",
+	}},
+	{version: 11, name: "add shopping list", statements: []string{
+		CREATE_SHOPPING_ENTRY_TABLE_STMT,
+		"CREATE INDEX shopping_entry_owner_id ON shopping_entry(" + OWNER_ID + ", item_id);",
+	}},
+	{version: 12, name: "add loans", statements: []string{
+		CREATE_LOAN_TABLE_STMT,
+		"CREATE INDEX loan_thing ON loan(" + OWNER_ID + ", thing, thing_id);",
+	}},
+	{version: 13, name: "add purchase details", statements: []string{
+		"ALTER TABLE item ADD COLUMN " + ITEM_PURCHASE_DATE + " TEXT;",
+		"ALTER TABLE item ADD COLUMN " + ITEM_PRICE + " REAL;",
+		"ALTER TABLE item ADD COLUMN " + ITEM_CURRENCY + " TEXT;",
+		"ALTER TABLE item ADD COLUMN " + ITEM_VENDOR + " TEXT;",
+		"ALTER TABLE item ADD COLUMN " + ITEM_SERIAL_NUMBER + " TEXT;",
+		"ALTER TABLE item ADD COLUMN " + ITEM_WARRANTY_UNTIL + " TEXT;",
+	}},
+	{version: 14, name: "add pictures", statements: []string{
+		CREATE_PICTURE_TABLE_STMT,
+		"CREATE INDEX picture_thing ON picture(thing, thing_id, position);",
+		// The existing pictures become the cover of their gallery.
+		copyCoverPictures("item"),
+		copyCoverPictures("box"),
+		copyCoverPictures("shelf"),
+		copyCoverPictures("area"),
+	}},
+	// Pictures were stored base64 encoded in their rows, now the rows refer to files in the blob directory.
+	{version: 15, name: "move pictures into blob store", data: moveBlobs},
+	{version: 16, name: "assign QR codes", data: assignQRCodes},
+	{version: 17, name: "lend things only once", statements: []string{
+		// The oldest loan wins if a thing was lent out more than once at the same time.
+		"UPDATE loan SET returned_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now') WHERE returned_at IS NULL " +
+			"AND id NOT IN (SELECT MIN(id) FROM loan WHERE returned_at IS NULL GROUP BY thing, thing_id);",
+		"CREATE UNIQUE INDEX loan_active_thing ON loan(thing, thing_id) WHERE returned_at IS NULL;",
+	}},
+	// Since households owner_id holds the id of a household, which only for personal households is a user id.
+	{version: 18, name: "drop user reference of owners", data: dropOwnerUserReference},
+}
+
+// copyCoverPictures returns the statement that copies the pictures of the table into the picture table as covers.
+func copyCoverPictures(table string) string {
+	return "INSERT INTO picture (owner_id, thing, thing_id, position, cover, picture, preview_picture, created_at) " +
+		"SELECT " + OWNER_ID + ", '" + table + "', id, 1, 1, picture, preview_picture, strftime('%Y-%m-%dT%H:%M:%SZ', 'now') " +
+		"FROM " + table + " WHERE " + OWNER_ID + " IS NOT NULL AND picture IS NOT NULL AND picture != '';"
+}
+
+// dropOwnerUserReference removes "REFERENCES user(id)" from the owner_id column of things that was added in migration 1.
+// SQLite can't alter a column, but removing a foreign key doesn't change the stored rows,
+// so the CREATE TABLE statements in the schema are edited as described on https://www.sqlite.org/lang_altertable.html.
+func dropOwnerUserReference(tx *sql.Tx, dryRun bool) error {
+	var version int
+	err := tx.QueryRow("PRAGMA schema_version;").Scan(&version)
+	if err != nil {
+		return logg.Errorf("can't read the schema version %w", err)
+	}
+	_, err = tx.Exec("PRAGMA writable_schema = ON;")
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	for _, table := range []string{"item", "box", "shelf", "area"} {
+		_, err = tx.Exec(`UPDATE sqlite_schema SET sql = replace(sql, ?, ?) WHERE type = 'table' AND name = ?;`,
+			OWNER_ID+" TEXT REFERENCES user(id)", OWNER_ID+" TEXT", table)
+		if err != nil {
+			return logg.Errorf(`can't drop the user reference of the owner of "%s" %w`, table, err)
+		}
+	}
+	// A new schema version makes SQLite read the edited schema.
+	_, err = tx.Exec(fmt.Sprintf("PRAGMA schema_version = %d;", version+1))
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	_, err = tx.Exec("PRAGMA writable_schema = OFF;")
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+
+	var result string
+	err = tx.QueryRow("PRAGMA integrity_check;").Scan(&result)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	if result != "ok" {
+		return logg.NewError("the schema is broken after dropping the user reference of owners: " + result)
+	}
+	return nil
+}
+
+// MigrationInfo describes a migration for reports.
+type MigrationInfo struct {
+	Version   int
+	Name      string
+	AppliedAt string
+}
+
+// MigrationStatus reports the current schema version and which migrations are still pending.
+type MigrationStatus struct {
+	CurrentVersion int
+	LatestVersion  int
+	Applied        []MigrationInfo
+	Pending        []MigrationInfo
+}
+
+func (s MigrationStatus) String() string {
+	return fmt.Sprintf("schema version %d of %d, %d pending", s.CurrentVersion, s.LatestVersion, len(s.Pending))
+}
+
+// Migrate applies all pending migrations in order.
+// Each migration runs in its own transaction.
+//
+// If dryRun is true all pending migrations are executed inside a single transaction
+// which is rolled back afterwards. This validates the statements against the
+// current database without changing it.
+//
+// Returns the migrations that were (or would have been) applied.
+func (db *DB) Migrate(dryRun bool) ([]MigrationInfo, error) {
+	return db.migrate(migrations, dryRun)
+}
+
+// mustMigrate applies all pending migrations or shuts down the program with os.Exit(1).
+func (db *DB) mustMigrate() {
+	applied, err := db.Migrate(false)
+	if err != nil {
+		logg.Fatalf("Failed to migrate database: %v", err)
+	}
+	for _, m := range applied {
+		logg.Infof(`applied migration %d "%s"`, m.Version, m.Name)
+	}
+}
+
+// SchemaVersion returns the version of the last applied migration.
+// A database without any applied migrations has version 0.
+func (db *DB) SchemaVersion() (int, error) {
+	err := db.createSchemaVersionTable()
+	if err != nil {
+		return 0, logg.WrapErr(err)
+	}
+
+	var version int
+	err = db.Sql.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_version;`).Scan(&version)
+	if err != nil {
+		return 0, logg.Errorf("can't read schema version %w", err)
+	}
+	return version, nil
+}
+
+// MigrationStatus returns the current schema version with all applied and pending migrations.
+func (db *DB) MigrationStatus() (MigrationStatus, error) {
+	return db.migrationStatus(migrations)
+}
+
+func (db *DB) migrationStatus(list []migration) (status MigrationStatus, err error) {
+	err = validMigrations(list)
+	if err != nil {
+		return status, logg.WrapErr(err)
+	}
+
+	status.CurrentVersion, err = db.SchemaVersion()
+	if err != nil {
+		return status, logg.WrapErr(err)
+	}
+	if len(list) > 0 {
+		status.LatestVersion = list[len(list)-1].version
+	}
+
+	rows, err := db.Sql.Query(`SELECT version, name, applied_at FROM schema_version ORDER BY version;`)
+	if err != nil {
+		return status, logg.WrapErr(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var info MigrationInfo
+		err = rows.Scan(&info.Version, &info.Name, &info.AppliedAt)
+		if err != nil {
+			return status, logg.WrapErr(err)
+		}
+		status.Applied = append(status.Applied, info)
+	}
+
+	for _, m := range pendingMigrations(list, status.CurrentVersion) {
+		status.Pending = append(status.Pending, m.info())
+	}
+	return status, nil
+}
+
+func (db *DB) migrate(list []migration, dryRun bool) (applied []MigrationInfo, err error) {
+	err = validMigrations(list)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+
+	current, err := db.SchemaVersion()
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	pending := pendingMigrations(list, current)
+	if len(pending) == 0 {
+		return nil, nil
+	}
+
+	if dryRun {
+		tx, err := db.Sql.Begin()
+		if err != nil {
+			return nil, logg.WrapErr(err)
+		}
+		defer tx.Rollback()
+		for _, m := range pending {
+			err = m.apply(tx, true)
+			if err != nil {
+				return applied, logg.WrapErr(err)
+			}
+			applied = append(applied, m.info())
+		}
+		return applied, nil
+	}
+
+	for _, m := range pending {
+		tx, err := db.Sql.Begin()
+		if err != nil {
+			return applied, logg.WrapErr(err)
+		}
+		err = m.apply(tx, false)
+		if err != nil {
+			tx.Rollback()
+			return applied, logg.WrapErr(err)
+		}
+		err = tx.Commit()
+		if err != nil {
+			return applied, logg.Errorf(`can't commit migration %d "%s" %w`, m.version, m.name, err)
+		}
+		applied = append(applied, m.info())
+	}
+	return applied, nil
+}
+
+// apply executes all statements of the migration and records it in schema_version.
+func (m migration) apply(tx *sql.Tx, dryRun bool) error {
+	for _, stmt := range m.statements {
+		_, err := tx.Exec(stmt)
+		if err != nil {
+			return logg.Errorf("migration %d \"%s\" failed\nSQL statement:\n\"%s\"\n%w", m.version, m.name, stmt, err)
+		}
+	}
+	if m.data != nil {
+		err := m.data(tx, dryRun)
+		if err != nil {
+			return logg.Errorf("migration %d \"%s\" failed %w", m.version, m.name, err)
+		}
+	}
+	_, err := tx.Exec(`INSERT INTO schema_version (version, name, applied_at) VALUES (?, ?, ?);`,
+		m.version, m.name, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return logg.Errorf(`can't record migration %d "%s" %w`, m.version, m.name, err)
+	}
+	return nil
+}
+
+func (m migration) info() MigrationInfo {
+	return MigrationInfo{Version: m.version, Name: m.name}
+}
+
+func (db *DB) createSchemaVersionTable() error {
+	_, err := db.Sql.Exec(CREATE_SCHEMA_VERSION_TABLE_STMT)
+	if err != nil {
+		return logg.Errorf("can't create schema_version table %w", err)
+	}
+	return nil
+}
+
+// validMigrations checks that versions start at 1 and increase by one without gaps.
+func validMigrations(list []migration) error {
+	for i, m := range list {
+		if m.version != i+1 {
+			return logg.NewError(fmt.Sprintf(`migration "%s" has version %d but should be %d`, m.name, m.version, i+1))
+		}
+		if len(m.statements) == 0 && m.data == nil {
+			return logg.NewError(fmt.Sprintf(`migration %d "%s" has no statements`, m.version, m.name))
+		}
+	}
+	return nil
+}
+
+func pendingMigrations(list []migration, currentVersion int) []migration {
+	for i, m := range list {
+		if m.version > currentVersion {
+			return list[i:]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package database
+
+import (
+	"basement/main/internal/history"
+	"basement/main/internal/households"
+	"basement/main/internal/logg"
+	"basement/main/internal/moves"
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// recordMove stores the current box, shelf and area of the thing with id
+// if ctx belongs to a move operation created with moves.WithOperation.
+// Must be called inside of the transaction that moves the thing.
+func (db *DB) recordMove(ctx context.Context, tx *sql.Tx, table string, id uuid.UUID) error {
+	operationID, ok := moves.OperationFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	m, ok := households.FromContext(ctx)
+	if !ok {
+		return logg.WrapErr(ErrNoOwner)
+	}
+
+	_, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO move_operation (id, owner_id, actor_id, created_at) VALUES (?, ?, ?, ?);`,
+		operationID.String(), m.HouseholdID.String(), m.UserID.String(), time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return logg.Errorf(`can't create move operation "%s" %w`, operationID, err)
+	}
+
+	// Shelves are only inside of areas, the missing columns stay NULL.
+	columns := containerColumns[table]
+	stmt := fmt.Sprintf(`INSERT INTO move_operation_thing (operation_id, thing, thing_id, %s) SELECT ?, ?, id, %s FROM %s WHERE id = ? AND %s = ?;`,
+		strings.Join(columns, ", "), strings.Join(columns, ", "), table, OWNER_ID)
+	_, err = tx.ExecContext(ctx, stmt, operationID.String(), table, id.String(), m.HouseholdID.String())
+	if err != nil {
+		return logg.Errorf(`can't record move of %s "%s" %w`, table, id, err)
+	}
+	return nil
+}
+
+type movedThing struct {
+	table   string
+	id      uuid.UUID
+	columns map[string]sql.NullString
+}
+
+// UndoMoves moves every thing of the move operation back to the box, shelf and area it was in before.
+// All things are moved back in a single transaction. An operation can only be undone once.
+// Returns the amount of things that were moved back.
+func (db *DB) UndoMoves(ctx context.Context, operationID uuid.UUID) (count int, err error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return 0, logg.WrapErr(err)
+	}
+
+	tx, err := db.Sql.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, logg.WrapErr(err)
+	}
+	defer tx.Rollback()
+
+	// Marking the operation as undone first makes a second undo of the same operation fail, even if both run at once.
+	result, err := tx.ExecContext(ctx, `UPDATE move_operation SET undone_at = ? WHERE id = ? AND `+OWNER_ID+` = ? AND undone_at IS NULL;`,
+		time.Now().UTC().Format(time.RFC3339), operationID.String(), owner)
+	if err != nil {
+		return 0, logg.WrapErr(err)
+	}
+	marked, err := result.RowsAffected()
+	if err != nil {
+		return 0, logg.WrapErr(err)
+	}
+	if marked == 0 {
+		var exists bool
+		err = tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM move_operation WHERE id = ? AND `+OWNER_ID+` = ?);`, operationID.String(), owner).Scan(&exists)
+		if err != nil {
+			return 0, logg.WrapErr(err)
+		}
+		if !exists {
+			return 0, logg.Errorf(`move operation "%s" %w`, operationID, ErrNotExist)
+		}
+		return 0, logg.Errorf(`move operation "%s" was already undone`, operationID)
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT thing, thing_id, box_id, shelf_id, area_id FROM move_operation_thing WHERE operation_id = ?;`, operationID.String())
+	if err != nil {
+		return 0, logg.WrapErr(err)
+	}
+	var things []movedThing
+	for rows.Next() {
+		var thingID string
+		var box, shelf, area sql.NullString
+		thing := movedThing{}
+		err := rows.Scan(&thing.table, &thingID, &box, &shelf, &area)
+		if err != nil {
+			rows.Close()
+			return 0, logg.WrapErr(err)
+		}
+		thing.id = uuid.FromStringOrNil(thingID)
+		thing.columns = map[string]sql.NullString{FTS_BOX_ID: box, FTS_SHELF_ID: shelf, FTS_AREA_ID: area}
+		things = append(things, thing)
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return 0, logg.WrapErr(err)
+	}
+
+	before := make([]*thingState, len(things))
+	for i, thing := range things {
+		before[i], err = thingStateOf(ctx, tx, thing.table, thing.id)
+		if err != nil {
+			return 0, logg.WrapErr(err)
+		}
+	}
+
+	for i, thing := range things {
+		if before[i] == nil {
+			// The thing was deleted in the meantime.
+			continue
+		}
+		columns := containerColumns[thing.table]
+		set := make([]string, len(columns))
+		args := make([]any, 0, len(columns)+2)
+		for j, column := range columns {
+			set[j] = column + " = ?"
+			args = append(args, thing.columns[column])
+		}
+		args = append(args, thing.id.String(), owner)
+		stmt := fmt.Sprintf(`UPDATE %s SET %s WHERE id = ? AND %s = ? AND %s;`, thing.table, strings.Join(set, ", "), OWNER_ID, NOT_DELETED)
+		_, err = tx.ExecContext(ctx, stmt, args...)
+		if err != nil {
+			return 0, logg.Errorf(`can't move %s "%s" back %w`, thing.table, thing.id, err)
+		}
+		count++
+	}
+
+	for i, thing := range things {
+		if before[i] == nil {
+			continue
+		}
+		err = recordHistory(ctx, tx, history.ACTION_MOVE, thing.table, thing.id, before[i])
+		if err != nil {
+			return 0, logg.WrapErr(err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, logg.WrapErr(err)
+	}
+	return count, nil
+}
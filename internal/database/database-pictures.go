@@ -0,0 +1,502 @@
+package database
+
+import (
+	"basement/main/internal/env"
+	"basement/main/internal/history"
+	"basement/main/internal/logg"
+	"basement/main/internal/pictures"
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"image"
+	"net/http"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+const selectPicturesStmt = `
+	SELECT id, thing, thing_id, position, caption, cover, picture, COALESCE(preview_picture, ''), created_at
+	FROM picture`
+
+func scanPicture(row interface{ Scan(dest ...any) error }) (pictures.Picture, error) {
+	var p pictures.Picture
+	var thingID, picture, preview, createdAt string
+	err := row.Scan(&p.ID, &p.Thing, &thingID, &p.Position, &p.Caption, &p.Cover, &picture, &preview, &createdAt)
+	if err != nil {
+		return pictures.Picture{}, err
+	}
+	p.ThingID = uuid.FromStringOrNil(thingID)
+	p.Picture = blobBase64(picture)
+	p.PreviewPicture = blobBase64(preview)
+	p.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+	return p, nil
+}
+
+// Pictures returns the pictures of the item, box, shelf or area with the id in the order of the gallery.
+func (db *DB) Pictures(ctx context.Context, thing string, thingID uuid.UUID) ([]pictures.Picture, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	query := selectPicturesStmt + ` WHERE thing = ? AND thing_id = ? AND ` + OWNER_ID + ` = ? ORDER BY position, id;`
+	rows, err := db.Sql.QueryContext(ctx, query, thing, thingID.String(), owner)
+	if err != nil {
+		return nil, logg.Errorf("%s %w", query, err)
+	}
+	defer rows.Close()
+
+	var list []pictures.Picture
+	for rows.Next() {
+		p, err := scanPicture(rows)
+		if err != nil {
+			return nil, logg.WrapErr(err)
+		}
+		list = append(list, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	return list, nil
+}
+
+// picture returns the picture with the id of the household of the user in ctx.
+func (db *DB) picture(ctx context.Context, id int64) (pictures.Picture, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return pictures.Picture{}, logg.WrapErr(err)
+	}
+	p, err := scanPicture(db.Sql.QueryRowContext(ctx, selectPicturesStmt+` WHERE id = ? AND `+OWNER_ID+` = ?;`, id, owner))
+	if errors.Is(err, sql.ErrNoRows) {
+		return pictures.Picture{}, logg.Errorf(`picture "%d" %w`, id, ErrNotExist)
+	}
+	if err != nil {
+		return pictures.Picture{}, logg.WrapErr(err)
+	}
+	return p, nil
+}
+
+// AddPicture adds the base64 encoded picture in the format, like "image/png", to the end of the gallery of the thing.
+// The first picture of a thing becomes its cover.
+func (db *DB) AddPicture(ctx context.Context, thing string, thingID uuid.UUID, picture string, format string, caption string) (pictures.Picture, error) {
+	if !slices.Contains(pictures.Things, thing) {
+		return pictures.Picture{}, logg.NewError(fmt.Sprintf(`"%s" can't have pictures`, thing))
+	}
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return pictures.Picture{}, logg.WrapErr(err)
+	}
+	exists, err := db.Exists(ctx, thing, thingID)
+	if err != nil {
+		return pictures.Picture{}, logg.WrapErr(err)
+	}
+	if !exists {
+		return pictures.Picture{}, logg.Errorf(`%s "%s" %w`, thing, thingID, ErrNotExist)
+	}
+	if picture == "" {
+		return pictures.Picture{}, logg.NewError("no picture was uploaded")
+	}
+	picture, err = stripMetadataBase64(picture)
+	if err != nil {
+		return pictures.Picture{}, logg.Errorf("can't remove the metadata of the picture %w", err)
+	}
+	preview, err := ResizeImage(picture, env.CurrentConfig().PreviewPictureSide(), format)
+	if err != nil {
+		if errors.Is(err, UnsupportedImageFormat) {
+			return pictures.Picture{}, logg.NewError(logg.CleanLastError(err) + err.Error())
+		}
+		return pictures.Picture{}, logg.Errorf("can't create a preview of the picture %w", err)
+	}
+	picture, preview, err = storeBlobs(picture, preview)
+	if err != nil {
+		return pictures.Picture{}, logg.WrapErr(err)
+	}
+
+	tx, err := db.Sql.BeginTx(ctx, nil)
+	if err != nil {
+		return pictures.Picture{}, logg.WrapErr(err)
+	}
+	defer tx.Rollback()
+
+	before, err := thingStateOf(ctx, tx, thing, thingID)
+	if err != nil {
+		return pictures.Picture{}, logg.WrapErr(err)
+	}
+	if before == nil {
+		return pictures.Picture{}, logg.Errorf(`%s "%s" %w`, thing, thingID, ErrNotExist)
+	}
+	id, err := insertPicture(ctx, tx, owner, thing, thingID, picture, preview, caption)
+	if err != nil {
+		return pictures.Picture{}, logg.WrapErr(err)
+	}
+	err = recordHistory(ctx, tx, history.ACTION_UPDATE, thing, thingID, before)
+	if err != nil {
+		return pictures.Picture{}, logg.WrapErr(err)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return pictures.Picture{}, logg.WrapErr(err)
+	}
+	return db.picture(ctx, id)
+}
+
+// insertPicture adds the picture to the end of the gallery of the thing, as cover if the thing has none.
+// The picture and preview are blob keys.
+func insertPicture(ctx context.Context, tx *sql.Tx, owner string, thing string, thingID uuid.UUID, picture string, preview string, caption string) (int64, error) {
+	var position int64
+	var hasCover bool
+	err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(position), 0) + 1, COALESCE(MAX(cover), 0) FROM picture WHERE thing = ? AND thing_id = ?;`,
+		thing, thingID.String()).Scan(&position, &hasCover)
+	if err != nil {
+		return 0, logg.WrapErr(err)
+	}
+	var id int64
+	err = tx.QueryRowContext(ctx, `INSERT INTO picture (owner_id, thing, thing_id, position, caption, cover, picture, preview_picture, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id;`,
+		owner, thing, thingID.String(), position, caption, !hasCover, picture, preview, time.Now().UTC().Format(time.RFC3339),
+	).Scan(&id)
+	if err != nil {
+		return 0, logg.Errorf("can't add a picture to %s %s %w", thing, thingID, err)
+	}
+	if !hasCover {
+		err = setThingPicture(ctx, tx, thing, thingID, picture, preview)
+		if err != nil {
+			return 0, logg.WrapErr(err)
+		}
+	}
+	return id, nil
+}
+
+// setThingPicture sets the picture and preview of the thing to the blob keys of its cover.
+func setThingPicture(ctx context.Context, tx *sql.Tx, thing string, thingID uuid.UUID, picture string, preview string) error {
+	err := ValidTable(thing)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE `+thing+` SET picture = ?, preview_picture = ? WHERE id = ?;`, picture, preview, thingID.String())
+	if err != nil {
+		return logg.Errorf("can't set the picture of %s %s %w", thing, thingID, err)
+	}
+	return nil
+}
+
+// UpdatePictureCaption changes the caption of the picture with the id.
+func (db *DB) UpdatePictureCaption(ctx context.Context, id int64, caption string) (pictures.Picture, error) {
+	p, err := db.picture(ctx, id)
+	if err != nil {
+		return pictures.Picture{}, logg.WrapErr(err)
+	}
+	tx, err := db.Sql.BeginTx(ctx, nil)
+	if err != nil {
+		return pictures.Picture{}, logg.WrapErr(err)
+	}
+	defer tx.Rollback()
+
+	before, err := thingStateOf(ctx, tx, p.Thing, p.ThingID)
+	if err != nil {
+		return pictures.Picture{}, logg.WrapErr(err)
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE picture SET caption = ? WHERE id = ?;`, caption, id)
+	if err != nil {
+		return pictures.Picture{}, logg.Errorf("can't change the caption of picture %d %w", id, err)
+	}
+	err = recordHistory(ctx, tx, history.ACTION_UPDATE, p.Thing, p.ThingID, before)
+	if err != nil {
+		return pictures.Picture{}, logg.WrapErr(err)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return pictures.Picture{}, logg.WrapErr(err)
+	}
+	p.Caption = caption
+	return p, nil
+}
+
+// MovePicture swaps the picture with the id with its neighbour in the direction pictures.DIRECTION_UP or pictures.DIRECTION_DOWN.
+// Pictures at the start or end of the gallery stay where they are.
+func (db *DB) MovePicture(ctx context.Context, id int64, direction string) (pictures.Picture, error) {
+	var neighbour string
+	switch direction {
+	case pictures.DIRECTION_UP:
+		neighbour = `position < ? ORDER BY position DESC, id DESC`
+	case pictures.DIRECTION_DOWN:
+		neighbour = `position > ? ORDER BY position, id`
+	default:
+		return pictures.Picture{}, logg.NewError(fmt.Sprintf(`"%s" is not a direction`, direction))
+	}
+	p, err := db.picture(ctx, id)
+	if err != nil {
+		return pictures.Picture{}, logg.WrapErr(err)
+	}
+
+	tx, err := db.Sql.BeginTx(ctx, nil)
+	if err != nil {
+		return pictures.Picture{}, logg.WrapErr(err)
+	}
+	defer tx.Rollback()
+
+	before, err := thingStateOf(ctx, tx, p.Thing, p.ThingID)
+	if err != nil {
+		return pictures.Picture{}, logg.WrapErr(err)
+	}
+	var otherID, otherPosition int64
+	err = tx.QueryRowContext(ctx, `SELECT id, position FROM picture WHERE thing = ? AND thing_id = ? AND `+neighbour+` LIMIT 1;`,
+		p.Thing, p.ThingID.String(), p.Position).Scan(&otherID, &otherPosition)
+	if errors.Is(err, sql.ErrNoRows) {
+		return p, nil
+	}
+	if err != nil {
+		return pictures.Picture{}, logg.WrapErr(err)
+	}
+	for _, move := range [][2]int64{{otherID, p.Position}, {p.ID, otherPosition}} {
+		_, err = tx.ExecContext(ctx, `UPDATE picture SET position = ? WHERE id = ?;`, move[1], move[0])
+		if err != nil {
+			return pictures.Picture{}, logg.Errorf("can't move picture %d %w", id, err)
+		}
+	}
+	err = recordHistory(ctx, tx, history.ACTION_UPDATE, p.Thing, p.ThingID, before)
+	if err != nil {
+		return pictures.Picture{}, logg.WrapErr(err)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return pictures.Picture{}, logg.WrapErr(err)
+	}
+	p.Position = otherPosition
+	return p, nil
+}
+
+// SetCoverPicture makes the picture with the id the cover of its thing.
+func (db *DB) SetCoverPicture(ctx context.Context, id int64) (pictures.Picture, error) {
+	p, err := db.picture(ctx, id)
+	if err != nil {
+		return pictures.Picture{}, logg.WrapErr(err)
+	}
+	tx, err := db.Sql.BeginTx(ctx, nil)
+	if err != nil {
+		return pictures.Picture{}, logg.WrapErr(err)
+	}
+	defer tx.Rollback()
+
+	before, err := thingStateOf(ctx, tx, p.Thing, p.ThingID)
+	if err != nil {
+		return pictures.Picture{}, logg.WrapErr(err)
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE picture SET cover = (id = ?) WHERE thing = ? AND thing_id = ?;`, id, p.Thing, p.ThingID.String())
+	if err != nil {
+		return pictures.Picture{}, logg.Errorf("can't change the cover of %s %s %w", p.Thing, p.ThingID, err)
+	}
+	var picture, preview string
+	err = tx.QueryRowContext(ctx, `SELECT picture, COALESCE(preview_picture, '') FROM picture WHERE id = ?;`, id).Scan(&picture, &preview)
+	if err != nil {
+		return pictures.Picture{}, logg.WrapErr(err)
+	}
+	err = setThingPicture(ctx, tx, p.Thing, p.ThingID, picture, preview)
+	if err != nil {
+		return pictures.Picture{}, logg.WrapErr(err)
+	}
+	err = recordHistory(ctx, tx, history.ACTION_UPDATE, p.Thing, p.ThingID, before)
+	if err != nil {
+		return pictures.Picture{}, logg.WrapErr(err)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return pictures.Picture{}, logg.WrapErr(err)
+	}
+	p.Cover = true
+	return p, nil
+}
+
+// DeletePicture removes the picture with the id from its gallery.
+// If it was the cover, the first of the remaining pictures becomes the cover.
+func (db *DB) DeletePicture(ctx context.Context, id int64) (pictures.Picture, error) {
+	p, err := db.picture(ctx, id)
+	if err != nil {
+		return pictures.Picture{}, logg.WrapErr(err)
+	}
+	tx, err := db.Sql.BeginTx(ctx, nil)
+	if err != nil {
+		return pictures.Picture{}, logg.WrapErr(err)
+	}
+	defer tx.Rollback()
+
+	before, err := thingStateOf(ctx, tx, p.Thing, p.ThingID)
+	if err != nil {
+		return pictures.Picture{}, logg.WrapErr(err)
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM picture WHERE id = ?;`, id)
+	if err != nil {
+		return pictures.Picture{}, logg.Errorf("can't delete picture %d %w", id, err)
+	}
+	if p.Cover {
+		err = replaceCoverPicture(ctx, tx, p.Thing, p.ThingID)
+		if err != nil {
+			return pictures.Picture{}, logg.WrapErr(err)
+		}
+	}
+	err = recordHistory(ctx, tx, history.ACTION_UPDATE, p.Thing, p.ThingID, before)
+	if err != nil {
+		return pictures.Picture{}, logg.WrapErr(err)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return pictures.Picture{}, logg.WrapErr(err)
+	}
+	return p, nil
+}
+
+// replaceCoverPicture makes the first picture of the thing its cover, the thing has no picture if it has none.
+func replaceCoverPicture(ctx context.Context, tx *sql.Tx, thing string, thingID uuid.UUID) error {
+	var id int64
+	var picture, preview string
+	err := tx.QueryRowContext(ctx, `SELECT id, picture, COALESCE(preview_picture, '') FROM picture
+		WHERE thing = ? AND thing_id = ? ORDER BY position, id LIMIT 1;`, thing, thingID.String()).Scan(&id, &picture, &preview)
+	if errors.Is(err, sql.ErrNoRows) {
+		return setThingPicture(ctx, tx, thing, thingID, "", "")
+	}
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE picture SET cover = 1 WHERE id = ?;`, id)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	return setThingPicture(ctx, tx, thing, thingID, picture, preview)
+}
+
+// updateCoverPicture replaces the cover of the thing with the picture uploaded with its form, in the transaction of the form.
+// The picture and preview are blob keys. An empty picture removes the cover and the next picture of the gallery takes its place.
+func updateCoverPicture(ctx context.Context, tx *sql.Tx, thing string, thingID uuid.UUID, picture string, preview string) error {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	if picture == "" {
+		_, err = tx.ExecContext(ctx, `DELETE FROM picture WHERE thing = ? AND thing_id = ? AND cover;`, thing, thingID.String())
+		if err != nil {
+			return logg.Errorf("can't delete the cover of %s %s %w", thing, thingID, err)
+		}
+		err = replaceCoverPicture(ctx, tx, thing, thingID)
+	} else {
+		var result sql.Result
+		result, err = tx.ExecContext(ctx, `UPDATE picture SET picture = ?, preview_picture = ? WHERE thing = ? AND thing_id = ? AND cover;`,
+			picture, preview, thing, thingID.String())
+		if err != nil {
+			return logg.Errorf("can't update the cover of %s %s %w", thing, thingID, err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			_, err = insertPicture(ctx, tx, owner, thing, thingID, picture, preview, "")
+		}
+	}
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	return nil
+}
+
+// deleteOrphanedPictures removes the pictures of purged things.
+func (db *DB) deleteOrphanedPictures(ctx context.Context) error {
+	for _, thing := range pictures.Things {
+		_, err := db.Sql.ExecContext(ctx, `DELETE FROM picture WHERE thing = ? AND thing_id NOT IN (SELECT id FROM `+thing+`);`, thing)
+		if err != nil {
+			return logg.Errorf(`can't delete pictures of purged %s %w`, thing, err)
+		}
+	}
+	return nil
+}
+
+// PictureRendition returns the cover of the thing with the id in the size, one of pictures.Sizes.
+// With thing pictures.THING_GALLERY it returns the picture of a gallery with the id.
+func (db *DB) PictureRendition(ctx context.Context, thing string, id string, size string) (pictures.Rendition, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return pictures.Rendition{}, logg.WrapErr(err)
+	}
+	table := thing
+	if thing == pictures.THING_GALLERY {
+		table = "picture"
+	} else if err := ValidTable(thing); err != nil {
+		return pictures.Rendition{}, logg.WrapErr(err)
+	}
+
+	// Things in the trash keep their picture for the trash page.
+	var picture, preview string
+	err = db.Sql.QueryRowContext(ctx, `SELECT COALESCE(picture, ''), COALESCE(preview_picture, '') FROM `+table+`
+		WHERE id = ? AND `+OWNER_ID+` = ?;`, id, owner).Scan(&picture, &preview)
+	if errors.Is(err, sql.ErrNoRows) {
+		return pictures.Rendition{}, logg.Errorf(`%s "%s" %w`, thing, id, ErrNotExist)
+	}
+	if err != nil {
+		return pictures.Rendition{}, logg.WrapErr(err)
+	}
+	if !isBlobKey(picture) {
+		return pictures.Rendition{}, logg.Errorf(`picture of %s "%s" %w`, thing, id, ErrNotExist)
+	}
+
+	path, etag := blobFile(picture), picture+"-"+size
+	switch size {
+	case pictures.SIZE_THUMBNAIL:
+		if isBlobKey(preview) {
+			picture, path = preview, blobFile(preview)
+		}
+	case pictures.SIZE_MEDIUM:
+		side := env.CurrentConfig().MediumPictureSide()
+		// A different configured size makes a new file, so caches must not keep the old one.
+		etag = fmt.Sprintf("%s-%d", etag, side)
+		path, err = mediumRendition(picture, side)
+		if err != nil {
+			return pictures.Rendition{}, logg.WrapErr(err)
+		}
+	case pictures.SIZE_ORIGINAL:
+	default:
+		return pictures.Rendition{}, logg.NewError(fmt.Sprintf(`"%s" is not a size`, size))
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return pictures.Rendition{}, logg.WrapErr(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return pictures.Rendition{}, logg.WrapErr(err)
+	}
+	return pictures.Rendition{
+		ETag:        `"` + etag + `"`,
+		ContentType: http.DetectContentType(content),
+		ModTime:     info.ModTime(),
+		Content:     content,
+	}, nil
+}
+
+// mediumRendition returns the file of the picture with the key scaled down to the longest side in pixels.
+// It is made on the first request and kept next to the picture.
+// Pictures that are small enough or can't be scaled, like those with too many pixels, are served as they are.
+func mediumRendition(key string, side int) (string, error) {
+	path := renditionFile(key, fmt.Sprintf("%s-%d", pictures.SIZE_MEDIUM, side))
+	if fileExists(path) {
+		return path, nil
+	}
+	original, err := os.ReadFile(blobFile(key))
+	if err != nil {
+		return "", logg.WrapErr(err)
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(original))
+	if err != nil || max(config.Width, config.Height) <= side || tooManyPixels(config) {
+		return blobFile(key), nil
+	}
+
+	var scaled bytes.Buffer
+	err = ResizePicture(bytes.NewReader(original), &scaled, side)
+	if err != nil {
+		return "", logg.Errorf(`can't scale picture "%s" %w`, key, err)
+	}
+	err = writeBlobFile(path, scaled.Bytes())
+	if err != nil {
+		return "", logg.WrapErr(err)
+	}
+	return path, nil
+}
@@ -0,0 +1,166 @@
+package database
+
+import (
+	"basement/main/internal/history"
+	"basement/main/internal/households"
+	"basement/main/internal/logg"
+	"basement/main/internal/qrcodes"
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// QRCodePayload returns the payload of the QR code of the thing with the id.
+func (db *DB) QRCodePayload(ctx context.Context, thing string, id uuid.UUID) (string, error) {
+	err := ValidTable(thing)
+	if err != nil {
+		return "", logg.WrapErr(err)
+	}
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return "", logg.WrapErr(err)
+	}
+	var payload string
+	err = db.Sql.QueryRowContext(ctx, `SELECT COALESCE(qrcode, '') FROM `+thing+`
+		WHERE id = ? AND `+OWNER_ID+` = ? AND `+NOT_DELETED+`;`, id.String(), owner).Scan(&payload)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", logg.Errorf(`%s "%s" %w`, thing, id, ErrNotExist)
+	}
+	if err != nil {
+		return "", logg.WrapErr(err)
+	}
+	if payload == "" {
+		return "", logg.Errorf(`QR code of %s "%s" %w`, thing, id, ErrNotExist)
+	}
+	return payload, nil
+}
+
+// ThingByQRCode returns the item, box, shelf or area with the QR code.
+// The code matches the end of a short URL payload or a QR code that was typed in.
+// Codes are looked up in all households, so a code stays on one thing on the server.
+// Returns a *qrcodes.OtherHouseholdError if the thing is not in the active household
+// and qrcodes.ErrNotAssigned if nothing has the QR code.
+func (db *DB) ThingByQRCode(ctx context.Context, code string) (thing string, id uuid.UUID, err error) {
+	m, ok := households.FromContext(ctx)
+	if !ok {
+		return "", uuid.Nil, logg.WrapErr(ErrNoOwner)
+	}
+	suffix := qrcodes.PATH_PREFIX + code
+	for _, table := range qrcodes.Things {
+		var idStr, owner string
+		err = db.Sql.QueryRowContext(ctx, `SELECT id, `+OWNER_ID+` FROM `+table+`
+			WHERE `+NOT_DELETED+` AND (qrcode = ? OR substr(qrcode, -?) = ?) LIMIT 1;`,
+			code, len(suffix), suffix).Scan(&idStr, &owner)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return "", uuid.Nil, logg.WrapErr(err)
+		}
+		household := uuid.FromStringOrNil(owner)
+		if household == m.HouseholdID {
+			return table, uuid.FromStringOrNil(idStr), nil
+		}
+		other := &qrcodes.OtherHouseholdError{}
+		membership, err := db.Membership(ctx, m.UserID, household)
+		if err == nil {
+			other.HouseholdID = membership.HouseholdID
+			other.HouseholdName = membership.HouseholdName
+		} else if !errors.Is(err, households.ErrNotMember) {
+			return "", uuid.Nil, logg.WrapErr(err)
+		}
+		return "", uuid.Nil, logg.Errorf(`QR code "%s" %w`, code, other)
+	}
+	return "", uuid.Nil, logg.Errorf(`QR code "%s" %w`, code, qrcodes.ErrNotAssigned)
+}
+
+// ClaimQRCode puts the QR code with the payload on the thing with the id.
+// Printed codes stop working when they are replaced, so if the thing already has a QR code
+// it is only replaced if replace is true, otherwise qrcodes.ErrHasQRCode is returned.
+func (db *DB) ClaimQRCode(ctx context.Context, thing string, id uuid.UUID, payload string, replace bool) error {
+	err := ValidTable(thing)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	tx, err := db.Sql.BeginTx(ctx, nil)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	defer tx.Rollback()
+
+	before, err := thingStateOf(ctx, tx, thing, id)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	if before == nil {
+		return logg.Errorf(`%s "%s" %w`, thing, id, ErrNotExist)
+	}
+	var current string
+	err = tx.QueryRowContext(ctx, `SELECT COALESCE(qrcode, '') FROM `+thing+` WHERE id = ? AND `+OWNER_ID+` = ? AND `+NOT_DELETED+`;`,
+		id.String(), owner).Scan(&current)
+	if errors.Is(err, sql.ErrNoRows) {
+		return logg.Errorf(`%s "%s" %w`, thing, id, ErrNotExist)
+	}
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	if current != "" && current != payload && !replace {
+		return logg.Errorf(`%s "%s" %w`, thing, id, qrcodes.ErrHasQRCode)
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE `+thing+` SET qrcode = ? WHERE id = ? AND `+OWNER_ID+` = ? AND `+NOT_DELETED+`;`,
+		payload, id.String(), owner)
+	if err != nil {
+		return logg.Errorf(`can't put the QR code on %s "%s" %w`, thing, id, err)
+	}
+	err = recordHistory(ctx, tx, history.ACTION_UPDATE, thing, id, before)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	return tx.Commit()
+}
+
+// qrCodeOrNew returns the payload or a new one if the thing doesn't have a QR code yet.
+func qrCodeOrNew(payload string) string {
+	if payload == "" {
+		return qrcodes.NewPayload()
+	}
+	return payload
+}
+
+// assignQRCodes gives every thing without a QR code a new one, including things in the trash.
+// QR codes that were typed in before are kept.
+func assignQRCodes(tx *sql.Tx, dryRun bool) error {
+	for _, table := range qrcodes.Things {
+		rows, err := tx.Query(`SELECT id FROM ` + table + ` WHERE qrcode IS NULL OR qrcode = '';`)
+		if err != nil {
+			return logg.Errorf(`can't read QR codes of "%s" %w`, table, err)
+		}
+		var ids []string
+		for rows.Next() {
+			var id string
+			err = rows.Scan(&id)
+			if err != nil {
+				rows.Close()
+				return logg.WrapErr(err)
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return logg.WrapErr(err)
+		}
+		for _, id := range ids {
+			_, err = tx.Exec(`UPDATE `+table+` SET qrcode = ? WHERE id = ?;`, qrcodes.NewPayload(), id)
+			if err != nil {
+				return logg.Errorf(`can't assign a QR code to %s "%s" %w`, table, id, err)
+			}
+		}
+	}
+	return nil
+}
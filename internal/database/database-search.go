@@ -3,16 +3,328 @@ package database
 import (
 	"basement/main/internal/common"
 	"basement/main/internal/logg"
-	"database/sql"
+	"basement/main/internal/search"
+	"context"
 	"fmt"
+	"html"
+	"html/template"
+	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/gofrs/uuid/v5"
 )
 
+// FTS_SEARCH_COLUMNS are the columns of the fts tables a search looks at.
+const FTS_SEARCH_COLUMNS = "{" + FTS_LABEL + " " + FTS_DESCRIPTION + " " + FTS_BOX_LABEL + " " + FTS_SHELF_LABEL + " " + FTS_AREA_LABEL + "}"
+
+// Markers around matched words in highlights and snippets.
+// They can't be typed into a search and are replaced with <mark> after the text is escaped.
+const (
+	highlightOpen  = "\x02"
+	highlightClose = "\x03"
+)
+
+// ftsColumns are the fts columns searched by each field of a search query.
+var ftsColumns = map[string]string{
+	common.SEARCH_ANY:         FTS_SEARCH_COLUMNS,
+	common.SEARCH_LABEL:       "{" + FTS_LABEL + "}",
+	common.SEARCH_DESCRIPTION: "{" + FTS_DESCRIPTION + "}",
+	common.SEARCH_BOX:         "{" + FTS_BOX_LABEL + "}",
+	common.SEARCH_SHELF:       "{" + FTS_SHELF_LABEL + "}",
+	common.SEARCH_AREA:        "{" + FTS_AREA_LABEL + "}",
+}
+
+// numericColumns are the item columns compared by the numeric fields of a search query.
+var numericColumns = map[string]string{
+	common.SEARCH_QUANTITY: ITEM_QUANTITY,
+	common.SEARCH_WEIGHT:   "CAST(" + ITEM_WEIGHT + " AS REAL)",
+}
+
+// searchFilter holds the conditions that filter a fts table by a search query typed by a user.
+type searchFilter struct {
+	table      string
+	match      string // FTS5 expression of all words, phrases and text filters that are not negated, "" if there are none
+	conditions []string
+	args       []any
+	order      []string // ORDER BY expressions of sort terms
+	owner      string   // tags, categories and custom fields are looked up in the household of owner
+}
+
+// newSearchFilter parses search with common.ParseSearchQuery and returns the conditions for the fts table.
+// Tags, categories and custom fields are only looked up in the household of owner.
+// Invalid queries are searched word by word, the list pages show the syntax error next to the search input.
+func newSearchFilter(table string, search string, owner string) searchFilter {
+	query, err := common.ParseSearchQuery(search)
+	if err != nil {
+		query = plainSearchQuery(search)
+	}
+
+	filter := searchFilter{table: table, owner: owner}
+	var match []string
+	for _, term := range query.Terms {
+		if term.Numeric() {
+			filter.addNumeric(term)
+		} else if term.Field == common.SEARCH_TAG {
+			filter.addTag(term)
+		} else if term.Field == common.SEARCH_CATEGORY {
+			filter.addCategory(term)
+		} else if term.Field == common.SEARCH_CUSTOM {
+			filter.addCustom(term)
+		} else if term.Field == common.SEARCH_SORT {
+			filter.addSort(term)
+		} else if term.Negated {
+			filter.conditions = append(filter.conditions, "id NOT IN (SELECT id FROM "+table+" WHERE "+table+" MATCH ?)")
+			filter.args = append(filter.args, ftsTerm(term))
+		} else {
+			match = append(match, ftsTerm(term))
+		}
+	}
+	if len(match) > 0 {
+		filter.match = strings.Join(match, " AND ")
+		filter.conditions = append([]string{table + " MATCH ?"}, filter.conditions...)
+		filter.args = append([]any{filter.match}, filter.args...)
+	}
+	return filter
+}
+
+// plainSearchQuery treats every word of search as a word without a field.
+func plainSearchQuery(search string) common.SearchQuery {
+	var query common.SearchQuery
+	for _, word := range strings.Fields(search) {
+		if strings.IndexFunc(word, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsNumber(r) }) == -1 {
+			continue
+		}
+		query.Terms = append(query.Terms, common.SearchTerm{Field: common.SEARCH_ANY, Value: word})
+	}
+	return query
+}
+
+// ftsTerm returns the FTS5 expression of a text term.
+// Words match as a prefix, phrases as a whole. The value is quoted, so FTS5 syntax typed by the user has no effect.
+func ftsTerm(term common.SearchTerm) string {
+	value := `"` + strings.ReplaceAll(term.Value, `"`, `""`) + `"`
+	if !term.Phrase {
+		value += "*"
+	}
+	return ftsColumns[term.Field] + " : " + value
+}
+
+// addNumeric adds a comparison of an item column. Only items have a quantity and a weight,
+// so other things never match the comparison.
+func (f *searchFilter) addNumeric(term common.SearchTerm) {
+	if f.table != "item_fts" {
+		if !term.Negated {
+			f.conditions = append(f.conditions, "0")
+		}
+		return
+	}
+	in := "IN"
+	if term.Negated {
+		in = "NOT IN"
+	}
+	f.conditions = append(f.conditions, "id "+in+" (SELECT id FROM item WHERE "+numericColumns[term.Field]+" "+term.Operator+" ?)")
+	f.args = append(f.args, term.Number)
+}
+
+// addTag adds a condition for things that have a tag with the whole name of term, ignoring case.
+func (f *searchFilter) addTag(term common.SearchTerm) {
+	in := "IN"
+	if term.Negated {
+		in = "NOT IN"
+	}
+	f.conditions = append(f.conditions, "id "+in+" (SELECT thing_tag.thing_id FROM thing_tag JOIN tag ON tag.id = thing_tag.tag_id "+
+		"WHERE tag."+OWNER_ID+" = ? AND thing_tag.thing = ? AND tag.name = ? COLLATE NOCASE)")
+	f.args = append(f.args, f.owner, strings.TrimSuffix(f.table, "_fts"), term.Value)
+}
+
+// addCategory adds a condition for items in the category with the whole name of term, ignoring case,
+// or in one of its subcategories. Only items have a category, so other things never match.
+func (f *searchFilter) addCategory(term common.SearchTerm) {
+	if f.table != "item_fts" {
+		if !term.Negated {
+			f.conditions = append(f.conditions, "0")
+		}
+		return
+	}
+	in := "IN"
+	if term.Negated {
+		in = "NOT IN"
+	}
+	f.conditions = append(f.conditions, "id "+in+" (WITH RECURSIVE matched(id) AS ("+
+		"SELECT id FROM category WHERE "+OWNER_ID+" = ? AND name = ? COLLATE NOCASE "+
+		"UNION SELECT category.id FROM category JOIN matched ON category.parent_id = matched.id WHERE category."+OWNER_ID+" = ?) "+
+		"SELECT id FROM item WHERE "+OWNER_ID+" = ? AND "+ITEM_CATEGORY_ID+" IN matched)")
+	f.args = append(f.args, f.owner, term.Value, f.owner, f.owner)
+}
+
+// addCustom adds a condition for things with a custom field named like term.Name.
+// ":" matches a part of the value, ignoring case. Booleans match "yes" and "true".
+// Comparisons with a number compare number fields numerically, everything else is compared as text,
+// which also works for dates.
+func (f *searchFilter) addCustom(term common.SearchTerm) {
+	in := "IN"
+	if term.Negated {
+		in = "NOT IN"
+	}
+	cond := "v.value " + term.Operator + " ? COLLATE NOCASE"
+	var value any = term.Value
+	if term.Operator == ":" {
+		cond = `v.value LIKE ? ESCAPE '\'`
+		value = "%" + likeEscaper.Replace(term.Value) + "%"
+		if yes, err := strconv.ParseBool(strings.ToLower(term.Value)); (err == nil && yes) || strings.EqualFold(term.Value, "yes") {
+			cond = "(" + cond + " OR (f.type = 'boolean' AND v.value = 'true'))"
+		}
+	} else if _, err := strconv.ParseFloat(term.Value, 64); err == nil && !term.Phrase {
+		cond = "(CASE f.type WHEN 'number' THEN CAST(v.value AS REAL) ELSE v.value END) " + term.Operator + " ?"
+		value = term.Number
+	}
+	f.conditions = append(f.conditions, "id "+in+" (SELECT v.thing_id FROM custom_field_value AS v JOIN custom_field AS f ON f.id = v.field_id "+
+		"WHERE f."+OWNER_ID+" = ? AND f.thing = ? AND REPLACE(f.name, ' ', '_') = ? COLLATE NOCASE AND "+cond+")")
+	f.args = append(f.args, f.owner, strings.TrimSuffix(f.table, "_fts"), term.Name, value)
+}
+
+// likeEscaper escapes the wildcards of LIKE patterns with ESCAPE '\'.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// addSort sorts by the field of term, things without a value come last.
+// Quantity and weight only sort items. The expressions don't use arguments,
+// so they can be placed after the conditions and before LIMIT.
+func (f *searchFilter) addSort(term common.SearchTerm) {
+	var expr string
+	switch field := term.SortField(); field {
+	case common.SEARCH_QUANTITY, common.SEARCH_WEIGHT:
+		if f.table != "item_fts" {
+			return
+		}
+		expr = "(SELECT " + numericColumns[field] + " FROM item WHERE item.id = " + f.table + ".id)"
+	case common.SEARCH_CUSTOM:
+		expr = "(SELECT CASE f.type WHEN 'number' THEN CAST(v.value AS REAL) ELSE v.value END " +
+			"FROM custom_field_value AS v JOIN custom_field AS f ON f.id = v.field_id " +
+			"WHERE v.thing_id = " + f.table + ".id AND f.thing = " + sqlString(strings.TrimSuffix(f.table, "_fts")) + " " +
+			"AND REPLACE(f.name, ' ', '_') = " + sqlString(term.Name) + " COLLATE NOCASE)"
+	default:
+		expr = "NULLIF(" + strings.Trim(ftsColumns[field], "{}") + ", '') COLLATE NOCASE"
+	}
+	direction := "ASC"
+	if term.Negated {
+		direction = "DESC"
+	}
+	f.order = append(f.order, expr+" IS NULL", expr+" "+direction)
+}
+
+// sqlString quotes s as an SQL string literal.
+func sqlString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// matchesAll returns true if the search has no conditions.
+func (f searchFilter) matchesAll() bool {
+	return len(f.conditions) == 0
+}
+
+// where joins the conditions of the filter and conditions with AND.
+// The arguments of the filter come first, see argsWith.
+func (f searchFilter) where(conditions ...string) string {
+	return strings.Join(append(f.conditions[:len(f.conditions):len(f.conditions)], conditions...), " AND ")
+}
+
+// argsWith returns the arguments of the filter followed by args.
+func (f searchFilter) argsWith(args ...any) []any {
+	return append(f.args[:len(f.args):len(f.args)], args...)
+}
+
+// highlights returns the columns selected for SQLListRow.RowsWithHighlightsToScan.
+func (f searchFilter) highlights() string {
+	if f.match == "" {
+		return "NULL, NULL"
+	}
+	return ftsHighlights(f.table)
+}
+
+// orderBy sorts by the sort terms of the search, then the best matches first.
+// Returns orderWithoutMatch if there are no sort terms and nothing can be ranked.
+func (f searchFilter) orderBy(orderWithoutMatch string) string {
+	order := f.order
+	if f.match != "" {
+		order = append(order[:len(order):len(order)], ftsRank(f.table))
+	}
+	if len(order) == 0 {
+		return orderWithoutMatch
+	}
+	return "ORDER BY " + strings.Join(order, ", ")
+}
+
+// ftsRank returns the bm25 rank of a row of table, a lower rank is a better match.
+// Matches in the label count more than in the description or the location labels.
+// The weights follow the column order of CREATE_FTS_BLOCK.
+func ftsRank(table string) string {
+	return "bm25(" + table + ", 0, 10, 4, 0, 0, 2, 0, 2, 0, 2)"
+}
+
+// ftsHighlights returns the label with highlighted matches and a snippet of the description.
+// Both are selected after ALL_FTS_COLS in search queries.
+func ftsHighlights(table string) string {
+	return "highlight(" + table + ", 1, char(2), char(3)), snippet(" + table + ", 2, char(2), char(3), '…', 12)"
+}
+
+// highlightedHTML escapes text and marks the matches found by ftsHighlights.
+// Returns "" if nothing in text matched.
+func highlightedHTML(text string) template.HTML {
+	if !strings.Contains(text, highlightOpen) {
+		return ""
+	}
+	escaped := html.EscapeString(text)
+	escaped = strings.ReplaceAll(escaped, highlightOpen, "<mark>")
+	escaped = strings.ReplaceAll(escaped, highlightClose, "</mark>")
+	return template.HTML(escaped)
+}
+
+// Search returns the best limit matches of query in each of items, boxes, shelves and areas
+// together with the amount of all matches of each type of thing.
+// An empty query matches nothing.
+func (db *DB) Search(ctx context.Context, query string, limit int) (search.Results, error) {
+	results := search.Results{Query: query}
+	if newSearchFilter("item_fts", query, "").matchesAll() {
+		return results, nil
+	}
+
+	counters := map[string]func(context.Context, string) (int, error){
+		"item":  db.ItemListCounter,
+		"box":   db.BoxListCounter,
+		"shelf": db.ShelfListCounter,
+		"area":  db.AreaListCounter,
+	}
+	for _, thing := range search.Things {
+		count, err := counters[thing](ctx, query)
+		if err != nil {
+			return search.Results{}, logg.WrapErr(err)
+		}
+		group := search.Group{Thing: thing, Count: count}
+		if count > 0 {
+			rows, err := db.listRowsPaginatedFrom(ctx, thing+"_fts", query, limit, 1)
+			if err != nil {
+				return search.Results{}, logg.WrapErr(err)
+			}
+			for _, row := range rows {
+				group.Results = append(group.Results, search.NewResult(thing, row))
+			}
+		}
+		results.Groups = append(results.Groups, group)
+		results.Total += count
+	}
+	return results, nil
+}
+
 // Search items based on search query, return array of virtualItems
-func (db *DB) ItemFuzzyFinder(query string) ([]common.ListRow, error) {
-	rows, err := db.Sql.Query(` SELECT id, label FROM item_fts WHERE label LIKE ? ORDER BY id; `, query+"%")
+func (db *DB) ItemFuzzyFinder(ctx context.Context, query string) ([]common.ListRow, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	ownedBy, _ := ownerCondition("item_fts")
+	filter := newSearchFilter("item_fts", query, owner)
+	rows, err := db.Sql.Query(` SELECT id, label FROM item_fts WHERE `+filter.where(ownedBy)+` `+filter.orderBy("ORDER BY id")+`; `, filter.argsWith(owner)...)
 	if err != nil {
 		return nil, fmt.Errorf("error while fetching virtual items: %w", err)
 	}
@@ -30,6 +342,7 @@ func (db *DB) ItemFuzzyFinder(query string) ([]common.ListRow, error) {
 		if err != nil {
 			return nil, err
 		}
+		vItem.Thing = "item"
 		virtualItems = append(virtualItems, *vItem)
 	}
 
@@ -37,14 +350,20 @@ func (db *DB) ItemFuzzyFinder(query string) ([]common.ListRow, error) {
 }
 
 // Search items based on search query, return limited number of results used to generate pagination
-func (db *DB) ItemFuzzyFinderWithPagination(query string, limit, offset int) ([]common.ListRow, error) {
+func (db *DB) ItemFuzzyFinderWithPagination(ctx context.Context, query string, limit, offset int) ([]common.ListRow, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	ownedBy, _ := ownerCondition("item_fts")
+	filter := newSearchFilter("item_fts", query, owner)
 	rows, err := db.Sql.Query(`
         SELECT id, label 
         FROM item_fts 
-        WHERE label LIKE ? 
-        ORDER BY id 
+        WHERE `+filter.where(ownedBy)+`
+        `+filter.orderBy("ORDER BY id")+`
         LIMIT ? OFFSET ?; 
-    `, query+"%", limit, offset)
+    `, filter.argsWith(owner, limit, offset)...)
 
 	if err != nil {
 		return nil, fmt.Errorf("error while fetching virtual items: %w", err)
@@ -63,41 +382,34 @@ func (db *DB) ItemFuzzyFinderWithPagination(query string, limit, offset int) ([]
 		if err != nil {
 			return nil, err
 		}
+		vItem.Thing = "item"
 		virtualItems = append(virtualItems, *vItem)
 	}
 
 	return virtualItems, nil
 }
 
-// BoxFuzzyFinder retrieves virtual boxes by label.
+// BoxFuzzyFinder retrieves virtual boxes by label, description and location labels, the best matches first.
 // If the query is empty or contains only spaces, it returns 10 default results.
-func (db *DB) BoxFuzzyFinder(query string, limit int, page int) ([]common.ListRow, error) {
-	var rows *sql.Rows
-	var err error
+func (db *DB) BoxFuzzyFinder(ctx context.Context, query string, limit int, page int) ([]common.ListRow, error) {
 	if page == 0 {
 		panic("page starts at 1, cant be 0")
 	}
-	queryNoSearch := `
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return []common.ListRow{}, logg.WrapErr(err)
+	}
+	ownedBy, _ := ownerCondition("box_fts")
+	filter := newSearchFilter("box_fts", query, owner)
+	stmt := `
 		SELECT
 			id, label, box_id, box_label, preview_picture
-		FROM box_fts AS b_fts
-		ORDER BY label ASC
-		LIMIT ? OFFSET ?;`
-
-	queryWithSearch := `
-		SELECT 
-			id, label, box_id, box_label, preview_picture
 		FROM box_fts
-		WHERE label LIKE ?
-		ORDER BY label ASC
+		WHERE ` + filter.where(ownedBy) + `
+		` + filter.orderBy("ORDER BY label ASC") + `
 		LIMIT ? OFFSET ?;`
 
-	if strings.TrimSpace(query) == "" {
-		rows, err = db.Sql.Query(queryNoSearch, limit, (page-1)*limit)
-	} else {
-		rows, err = db.Sql.Query(queryWithSearch, query+"%", limit, (page-1)*limit)
-	}
-
+	rows, err := db.Sql.Query(stmt, filter.argsWith(owner, limit, (page-1)*limit)...)
 	if err != nil {
 		return []common.ListRow{}, logg.Errorf("error while fetching the virtualBox from box_fts: %w", err)
 	}
@@ -121,6 +433,7 @@ func (db *DB) BoxFuzzyFinder(query string, limit int, page int) ([]common.ListRo
 		if err != nil {
 			return []common.ListRow{}, logg.WrapErr(err)
 		}
+		vBox.Thing = "box"
 		virtualBoxes = append(virtualBoxes, *vBox)
 	}
 
@@ -128,9 +441,15 @@ func (db *DB) BoxFuzzyFinder(query string, limit int, page int) ([]common.ListRo
 }
 
 // Search shelves based on search query, return array of virtueShelves
-func (db *DB) ShelfFuzzyFinder(query string) ([]common.ListRow, error) {
-	rows, err := db.Sql.Query(` SELECT id, label, area_id, area_label, preview_picture tokenize 
-                              FROM shelf_fts WHERE label LIKE ? ORDER BY id; `, query+"%")
+func (db *DB) ShelfFuzzyFinder(ctx context.Context, query string) ([]common.ListRow, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	ownedBy, _ := ownerCondition("shelf_fts")
+	filter := newSearchFilter("shelf_fts", query, owner)
+	rows, err := db.Sql.Query(` SELECT id, label, area_id, area_label, preview_picture
+                              FROM shelf_fts WHERE `+filter.where(ownedBy)+` `+filter.orderBy("ORDER BY id")+`; `, filter.argsWith(owner)...)
 	if err != nil {
 		return nil, fmt.Errorf("error while fetching virtual shelves: %w", err)
 	}
@@ -140,7 +459,7 @@ func (db *DB) ShelfFuzzyFinder(query string) ([]common.ListRow, error) {
 	var sqlShelf SQLListRow
 
 	for rows.Next() {
-		err = rows.Scan(&sqlShelf.ID, &sqlShelf.Label, &sqlShelf.ID, sqlShelf.AreaLabel, sqlShelf.PreviewPicture)
+		err = rows.Scan(&sqlShelf.ID, &sqlShelf.Label, &sqlShelf.AreaID, &sqlShelf.AreaLabel, &sqlShelf.PreviewPicture)
 		if err != nil {
 			return nil, fmt.Errorf("error while assigning the Data to the VirtualItem struct: %w", err)
 		}
@@ -148,6 +467,7 @@ func (db *DB) ShelfFuzzyFinder(query string) ([]common.ListRow, error) {
 		if err != nil {
 			return nil, err
 		}
+		vShelf.Thing = "shelf"
 		virtuaShelves = append(virtuaShelves, *vShelf)
 	}
 
@@ -155,27 +475,22 @@ func (db *DB) ShelfFuzzyFinder(query string) ([]common.ListRow, error) {
 }
 
 // check if the virtual box is empty
-func (db *DB) VirtualBoxExist(id uuid.UUID) (bool, error) {
-	return db.Exists("box_fts", id)
+func (db *DB) VirtualBoxExist(ctx context.Context, id uuid.UUID) (bool, error) {
+	return db.Exists(ctx, "box_fts", id)
 }
 
-func (db *DB) NumOfItemRecords(searchString string) (int, error) {
-	searchString = strings.TrimSpace(searchString)
-
-	var query string
-	if searchString == "" {
-		query = "SELECT COUNT(*) FROM item_fts;"
-	} else {
-		query = fmt.Sprintf("SELECT COUNT(*) FROM item_fts WHERE label LIKE ?;")
+func (db *DB) NumOfItemRecords(ctx context.Context, searchString string) (int, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return 0, logg.WrapErr(err)
 	}
+	ownedBy, _ := ownerCondition("item_fts")
+
+	filter := newSearchFilter("item_fts", searchString, owner)
+	query := "SELECT COUNT(*) FROM item_fts WHERE " + filter.where(ownedBy) + ";"
 
 	var count int
-	var err error
-	if searchString == "" {
-		err = db.Sql.QueryRow(query).Scan(&count)
-	} else {
-		err = db.Sql.QueryRow(query, searchString+"%").Scan(&count)
-	}
+	err = db.Sql.QueryRow(query, filter.argsWith(owner)...).Scan(&count)
 
 	if err != nil {
 		return 0, fmt.Errorf("Error checking the number of records %v:", err)
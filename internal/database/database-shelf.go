@@ -2,8 +2,11 @@ package database
 
 import (
 	"basement/main/internal/common"
+	"basement/main/internal/env"
+	"basement/main/internal/history"
 	"basement/main/internal/logg"
 	"basement/main/internal/shelves"
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -42,21 +45,21 @@ func (s SQLShelf) ToShelf() (*shelves.Shelf, error) {
 	}, nil
 }
 
-// CreateNewShelf creates a new empty shelf in database.
-func (db *DB) CreateNewShelf() (uuid.UUID, error) {
+// CreateNewShelf creates a new empty shelf owned by the user in ctx.
+func (db *DB) CreateNewShelf(ctx context.Context) (uuid.UUID, error) {
 	nID, err := uuid.NewV4()
 	if err != nil {
 		return uuid.Nil, logg.WrapErr(err)
 	}
-	err = db.createNewShelf(nID)
+	err = db.createNewShelf(ctx, nID)
 	if err != nil {
 		return uuid.Nil, logg.WrapErr(err)
 	}
 	return nID, err
 }
 
-func (db *DB) createNewShelf(nID uuid.UUID) error {
-	exists, err := db.Exists("shelf", nID)
+func (db *DB) createNewShelf(ctx context.Context, nID uuid.UUID) error {
+	exists, err := db.idTaken("shelf", nID)
 
 	if err != nil {
 		return logg.WrapErr(err)
@@ -64,6 +67,10 @@ func (db *DB) createNewShelf(nID uuid.UUID) error {
 	if exists {
 		return db.ErrorExist()
 	}
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
 
 	var (
 		id             string = nID.String()
@@ -71,7 +78,7 @@ func (db *DB) createNewShelf(nID uuid.UUID) error {
 		description    sql.NullString
 		picture        []byte
 		previewPicture []byte
-		qrcode         sql.NullString
+		qrcode         string = qrCodeOrNew("")
 		height         sql.NullFloat64
 		width          sql.NullFloat64
 		depth          sql.NullFloat64
@@ -90,12 +97,19 @@ func (db *DB) createNewShelf(nID uuid.UUID) error {
 		width,
 		depth,
 		rows,
-		cols) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+		cols,
+		owner_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
-	result, err := db.Sql.Exec(stmt,
+	tx, err := db.Sql.BeginTx(ctx, nil)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, stmt,
 		&id, &label, &description, &picture,
 		&previewPicture, &qrcode, &height,
-		&width, &depth, &rows, &cols,
+		&width, &depth, &rows, &cols, owner,
 	)
 
 	if err != nil {
@@ -108,12 +122,16 @@ func (db *DB) createNewShelf(nID uuid.UUID) error {
 	if rowsAffected != 1 {
 		return logg.Errorf("unexpected number of effected rows, check insertNewBox")
 	}
-
-	return nil
+	err = recordHistory(ctx, tx, history.ACTION_CREATE, "shelf", nID, nil)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	return tx.Commit()
 }
 
 // CreateShelf creates a shelf entry in database from the provided shelf.
-func (db *DB) CreateShelf(shelf *shelves.Shelf) error {
+// The shelf is owned by the user in ctx.
+func (db *DB) CreateShelf(ctx context.Context, shelf *shelves.Shelf) error {
 	// will never happen, uuid is always checked for nil
 	if shelf.ID == uuid.Nil {
 		panic(fmt.Sprintf(`CreateShelf: Provided shelf "%s" had invalid uuid "%s". This will never happen, because uuid is always checked for nil.`, shelf.Label, shelf.ID.String()))
@@ -123,9 +141,22 @@ func (db *DB) CreateShelf(shelf *shelves.Shelf) error {
 		return logg.NewError(fmt.Sprintf(`shelf "%s" has %d items and %d boxes, they must be empty while creating a new shelf`, shelf.ID, len(shelf.Items), len(shelf.Boxes)))
 	}
 
-	err := updatePicture(&shelf.Picture, &shelf.PreviewPicture)
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	err = db.notOwnedByOthers(ctx, "area", shelf.AreaID)
 	if err != nil {
-		logg.Infof("Can't update picture %v", err.Error())
+		return logg.WrapErr(err)
+	}
+
+	err = updatePicture(&shelf.Picture, &shelf.PreviewPicture)
+	if err != nil {
+		return logg.Errorf("Error while processing the picture of shelf '%s' %w", shelf.Label, err)
+	}
+	picture, preview, err := storeBlobs(shelf.Picture, shelf.PreviewPicture)
+	if err != nil {
+		return logg.WrapErr(err)
 	}
 
 	stmt := `
@@ -141,33 +172,62 @@ func (db *DB) CreateShelf(shelf *shelves.Shelf) error {
             depth,
             rows,
             cols,
-            area_id
-        ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+            area_id,
+            owner_id
+        ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
     `
 
-	_, err = db.Sql.Exec(stmt,
+	tx, err := db.Sql.BeginTx(ctx, nil)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, stmt,
 		shelf.ID.String(),
 		shelf.Label,
 		shelf.Description,
-		shelf.Picture,
-		shelf.PreviewPicture,
-		shelf.QRCode,
+		picture,
+		preview,
+		qrCodeOrNew(shelf.QRCode),
 		shelf.Height,
 		shelf.Width,
 		shelf.Depth,
 		shelf.Rows,
 		shelf.Cols,
 		shelf.AreaID,
+		owner,
 	)
 	if err != nil {
 		return logg.Errorf("CreateShelf %w", err)
 	}
-
-	return nil
+	if picture != "" {
+		err = updateCoverPicture(ctx, tx, "shelf", shelf.ID, picture, preview)
+		if err != nil {
+			return logg.WrapErr(err)
+		}
+	}
+	err = setTags(ctx, tx, "shelf", shelf.ID, shelf.Tags)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	err = setCustomFields(ctx, tx, "shelf", shelf.ID, shelf.CustomFields)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	err = recordHistory(ctx, tx, history.ACTION_CREATE, "shelf", shelf.ID, nil)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	return tx.Commit()
 }
 
 // Shelf returns shelf with provided id.
-func (db *DB) Shelf(id uuid.UUID) (*shelves.Shelf, error) {
+func (db *DB) Shelf(ctx context.Context, id uuid.UUID) (*shelves.Shelf, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
 
 	var sqlShelf SQLShelf
 	stmt := `
@@ -178,9 +238,9 @@ func (db *DB) Shelf(id uuid.UUID) (*shelves.Shelf, error) {
         shelf AS s
       LEFT JOIN area AS a ON s.area_id = a.id
       WHERE 
-        s.id = ?;`
+        s.id = ? AND s.owner_id = ? AND s.` + NOT_DELETED + `;`
 
-	err := db.Sql.QueryRow(stmt, id.String()).Scan(
+	err = db.Sql.QueryRow(stmt, id.String(), owner).Scan(
 		&sqlShelf.SQLBasicInfo.ID, &sqlShelf.SQLBasicInfo.Label, &sqlShelf.SQLBasicInfo.Description,
 		&sqlShelf.SQLBasicInfo.Picture, &sqlShelf.SQLBasicInfo.PreviewPicture,
 		&sqlShelf.SQLBasicInfo.QRCode, &sqlShelf.Height, &sqlShelf.Width, &sqlShelf.Depth,
@@ -195,12 +255,20 @@ func (db *DB) Shelf(id uuid.UUID) (*shelves.Shelf, error) {
 	if err != nil {
 		return nil, logg.WrapErr(err)
 	}
-	items, err := db.innerListRowsFrom("shelf", shelf.ID, "item_fts")
+	shelf.Tags, err = db.tagsOf(ctx, "shelf", shelf.ID)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	shelf.CustomFields, err = db.customFieldValues(ctx, "shelf", shelf.ID)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	items, err := db.innerListRowsFrom(ctx, "shelf", shelf.ID, "item_fts")
 	if err != nil {
 		return nil, logg.WrapErr(err)
 	}
 	shelf.Items = items
-	boxes, err := db.innerListRowsFrom("shelf", shelf.ID, "box_fts")
+	boxes, err := db.innerListRowsFrom(ctx, "shelf", shelf.ID, "box_fts")
 	if err != nil {
 		return nil, logg.WrapErr(err)
 	}
@@ -208,25 +276,42 @@ func (db *DB) Shelf(id uuid.UUID) (*shelves.Shelf, error) {
 	return shelf, nil
 }
 
-func (db *DB) UpdateShelf(shelf *shelves.Shelf, ignorePicture bool, pictureFormat string) error {
+func (db *DB) UpdateShelf(ctx context.Context, shelf *shelves.Shelf, ignorePicture bool, pictureFormat string) error {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	err = db.notOwnedByOthers(ctx, "area", shelf.AreaID)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	tx, err := db.Sql.BeginTx(ctx, nil)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	defer tx.Rollback()
+
+	before, err := thingStateOf(ctx, tx, "shelf", shelf.ID)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
 
-	var err error
 	var stmt string
+	var picture, preview string
 	if ignorePicture {
 		stmt = `
         UPDATE shelf SET
             label = ?,
             description = ?,
-            qrcode = ?,
+            qrcode = COALESCE(NULLIF(?, ''), qrcode),
             height = ?,
             width = ?,
             depth = ?,
             rows = ?,
             cols = ?,
             area_id = ?
-        WHERE id = ?
-    `
-		_, err = db.Sql.Exec(stmt,
+        WHERE id = ? AND owner_id = ? AND ` + NOT_DELETED
+		_, err = tx.ExecContext(ctx, stmt,
 			shelf.Label,
 			shelf.Description,
 			shelf.QRCode,
@@ -237,9 +322,14 @@ func (db *DB) UpdateShelf(shelf *shelves.Shelf, ignorePicture bool, pictureForma
 			shelf.Cols,
 			shelf.AreaID.String(),
 			shelf.ID.String(),
+			owner,
 		)
 	} else {
-		shelf.PreviewPicture, err = ResizeImage(shelf.Picture, 50, pictureFormat)
+		shelf.Picture, err = stripMetadataBase64(shelf.Picture)
+		if err != nil {
+			return logg.Errorf("Error while removing the metadata of the picture of shelf '%s' %w", shelf.Label, err)
+		}
+		shelf.PreviewPicture, err = ResizeImage(shelf.Picture, env.CurrentConfig().PreviewPictureSide(), pictureFormat)
 		if err != nil {
 			if errors.Is(err, UnsupportedImageFormat) {
 				return logg.NewError(logg.CleanLastError(err) + err.Error())
@@ -247,6 +337,10 @@ func (db *DB) UpdateShelf(shelf *shelves.Shelf, ignorePicture bool, pictureForma
 				return logg.Errorf("Error while resizing picture of shelf '%s' to create a preview picture %w", shelf.Label, err)
 			}
 		}
+		picture, preview, err = storeBlobs(shelf.Picture, shelf.PreviewPicture)
+		if err != nil {
+			return logg.WrapErr(err)
+		}
 
 		stmt := `
         UPDATE shelf SET
@@ -254,20 +348,19 @@ func (db *DB) UpdateShelf(shelf *shelves.Shelf, ignorePicture bool, pictureForma
             description = ?,
             picture = ?,
             preview_picture = ?,
-            qrcode = ?,
+            qrcode = COALESCE(NULLIF(?, ''), qrcode),
             height = ?,
             width = ?,
             depth = ?,
             rows = ?,
             cols = ?,
             area_id = ?
-        WHERE id = ?
-    `
-		_, err = db.Sql.Exec(stmt,
+        WHERE id = ? AND owner_id = ? AND ` + NOT_DELETED
+		_, err = tx.ExecContext(ctx, stmt,
 			shelf.Label,
 			shelf.Description,
-			shelf.Picture,
-			shelf.PreviewPicture,
+			picture,
+			preview,
 			shelf.QRCode,
 			shelf.Height,
 			shelf.Width,
@@ -276,40 +369,59 @@ func (db *DB) UpdateShelf(shelf *shelves.Shelf, ignorePicture bool, pictureForma
 			shelf.Cols,
 			shelf.AreaID.String(),
 			shelf.ID.String(),
+			owner,
 		)
 	}
 
 	if err != nil {
 		return logg.WrapErr(err)
 	}
-	return nil
+	if !ignorePicture {
+		err = updateCoverPicture(ctx, tx, "shelf", shelf.ID, picture, preview)
+		if err != nil {
+			return logg.WrapErr(err)
+		}
+	}
+	err = setTags(ctx, tx, "shelf", shelf.ID, shelf.Tags)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	err = setCustomFields(ctx, tx, "shelf", shelf.ID, shelf.CustomFields)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	err = recordHistory(ctx, tx, history.ACTION_UPDATE, "shelf", shelf.ID, before)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	return tx.Commit()
 }
 
 // DeleteShelf deletes a single shelf.
-func (db *DB) DeleteShelf(id uuid.UUID) (label string, err error) {
-	shelf, err := db.Shelf(id)
+func (db *DB) DeleteShelf(ctx context.Context, id uuid.UUID) (label string, err error) {
+	shelf, err := db.Shelf(ctx, id)
 	if err != nil {
 		return label, logg.WrapErr(err)
 	}
 	if shelf.Items != nil || shelf.Boxes != nil {
 		return shelf.Label, logg.WrapErr(db.ErrorNotEmpty())
 	}
-	err = db.deleteFrom("shelf", id)
+	err = db.deleteFrom(ctx, "shelf", id)
 	if err != nil {
 		return label, logg.WrapErr(err)
 	}
 	return shelf.Label, nil
 }
 
-func (db *DB) DeleteShelf2(id uuid.UUID) error {
-	shelf, err := db.Shelf(id)
+func (db *DB) DeleteShelf2(ctx context.Context, id uuid.UUID) error {
+	shelf, err := db.Shelf(ctx, id)
 	if err != nil {
 		logg.WrapErr(err)
 	}
 	if shelf.Items != nil || shelf.Boxes != nil {
 		return logg.WrapErr(db.ErrorNotEmpty())
 	}
-	err = db.deleteFrom("shelf", id)
+	err = db.deleteFrom(ctx, "shelf", id)
 	if err != nil {
 		logg.WrapErr(err)
 	}
@@ -318,8 +430,8 @@ func (db *DB) DeleteShelf2(id uuid.UUID) error {
 
 // MoveShelfToArea moves shelf to an area.
 // To move out of an area set "toAreaID = uuid.Nil".
-func (db *DB) MoveShelfToArea(shelfID uuid.UUID, toAreaID uuid.UUID) error {
-	err := db.moveTo("shelf", shelfID, "area", toAreaID)
+func (db *DB) MoveShelfToArea(ctx context.Context, shelfID uuid.UUID, toAreaID uuid.UUID) error {
+	err := db.moveTo(ctx, "shelf", shelfID, "area", toAreaID)
 	if err != nil {
 		return logg.WrapErr(err)
 	}
@@ -328,8 +440,8 @@ func (db *DB) MoveShelfToArea(shelfID uuid.UUID, toAreaID uuid.UUID) error {
 
 // ShelfListRows retrieves shelves by label.
 // If the query is empty or contains only spaces, it returns default results.
-func (db *DB) ShelfListRows(searchString string, limit int, pageNr int) (shelfRows []common.ListRow, err error) {
-	shelfRows, err = db.listRowsPaginatedFrom("shelf_fts", searchString, limit, pageNr)
+func (db *DB) ShelfListRows(ctx context.Context, searchString string, limit int, pageNr int) (shelfRows []common.ListRow, err error) {
+	shelfRows, err = db.listRowsPaginatedFrom(ctx, "shelf_fts", searchString, limit, pageNr)
 	if err != nil {
 		return shelfRows, logg.WrapErr(err)
 	}
@@ -339,17 +451,16 @@ func (db *DB) ShelfListRows(searchString string, limit int, pageNr int) (shelfRo
 // ShelfCounter returns the count of rows in the shelf_fts table that match
 // the specified queryString.
 // If queryString is empty, it returns the count of all rows in the table.
-func (db *DB) ShelfListCounter(queryString string) (count int, err error) {
-	countQuery := `SELECT COUNT(*) FROM shelf_fts;`
-
-	if queryString != "" {
-		countQuery = fmt.Sprintf(`
-			SELECT COUNT(*)
-			FROM shelf_fts
-      WHERE label MATCH '%s*' `, queryString)
+func (db *DB) ShelfListCounter(ctx context.Context, queryString string) (count int, err error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return 0, logg.WrapErr(err)
 	}
+	ownedBy, _ := ownerCondition("shelf_fts")
+	filter := newSearchFilter("shelf_fts", queryString, owner)
+	countQuery := `SELECT COUNT(*) FROM shelf_fts WHERE ` + filter.where(ownedBy) + `;`
 
-	err = db.Sql.QueryRow(countQuery).Scan(&count)
+	err = db.Sql.QueryRow(countQuery, filter.argsWith(owner)...).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("error while fetching the number of shelves from the database: %v", err)
 	}
@@ -358,14 +469,16 @@ func (db *DB) ShelfListCounter(queryString string) (count int, err error) {
 
 // returns the count of rows in the box_fts table that match the specified searchString.
 // If queryString is empty, it returns the count of all rows in the table.
-func (db *DB) InnerShelfInTableListCounter(searchString string, inTable string, inTableID uuid.UUID) (count int, err error) {
-	countQuery := `SELECT COUNT(*) FROM shelf_fts WHERE ` + inTable + `_id = ?;`
-
-	if searchString != "" {
-		countQuery = ` SELECT COUNT(*) FROM shelf_fts WHERE label MATCH '` + searchString + `*' AND ` + inTable + `_id = ?`
+func (db *DB) InnerShelfInTableListCounter(ctx context.Context, searchString string, inTable string, inTableID uuid.UUID) (count int, err error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return 0, logg.WrapErr(err)
 	}
+	ownedBy, _ := ownerCondition("shelf_fts")
+	filter := newSearchFilter("shelf_fts", searchString, owner)
+	countQuery := `SELECT COUNT(*) FROM shelf_fts WHERE ` + filter.where(inTable+"_id = ?", ownedBy) + `;`
 
-	err = db.Sql.QueryRow(countQuery, inTableID.String()).Scan(&count)
+	err = db.Sql.QueryRow(countQuery, filter.argsWith(inTableID.String(), owner)...).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("error while fetching the number of shelf from the database: %v", err)
 	}
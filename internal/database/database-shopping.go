@@ -0,0 +1,252 @@
+package database
+
+import (
+	"basement/main/internal/logg"
+	"basement/main/internal/shopping"
+	"basement/main/internal/stock"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// selectShoppingEntriesStmt selects the entries of an owner with the label of their item and their place.
+// Places in the trash are left out.
+const selectShoppingEntriesStmt = `
+	SELECT e.id, COALESCE(e.item_id, ''), COALESCE(i.label, e.label), e.quantity, e.note, e.generated, e.created_at,
+		COALESCE(b.id, ''), COALESCE(b.label, ''),
+		COALESCE(s.id, ''), COALESCE(s.label, ''),
+		COALESCE(a.id, ''), COALESCE(a.label, '')
+	FROM shopping_entry AS e
+	LEFT JOIN item AS i ON i.id = e.item_id
+	LEFT JOIN box AS b ON b.id = e.box_id AND b.` + NOT_DELETED + `
+	LEFT JOIN shelf AS s ON s.id = e.shelf_id AND s.` + NOT_DELETED + `
+	LEFT JOIN area AS a ON a.id = e.area_id AND a.` + NOT_DELETED + `
+	WHERE e.` + OWNER_ID + ` = ?`
+
+// ShoppingEntries returns the shopping list of the user in ctx, the oldest entry first.
+func (db *DB) ShoppingEntries(ctx context.Context) ([]shopping.Entry, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	query := selectShoppingEntriesStmt + ` ORDER BY e.id;`
+	rows, err := db.Sql.QueryContext(ctx, query, owner)
+	if err != nil {
+		return nil, logg.Errorf("%s %w", query, err)
+	}
+	defer rows.Close()
+
+	var entries []shopping.Entry
+	for rows.Next() {
+		entry, err := scanShoppingEntry(rows)
+		if err != nil {
+			return nil, logg.WrapErr(err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	return entries, nil
+}
+
+// shoppingEntry returns the entry with the id from the shopping list of the user in ctx.
+func shoppingEntry(ctx context.Context, q querier, id int64) (shopping.Entry, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return shopping.Entry{}, logg.WrapErr(err)
+	}
+	query := selectShoppingEntriesStmt + ` AND e.id = ?;`
+	entry, err := scanShoppingEntry(q.QueryRowContext(ctx, query, owner, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return shopping.Entry{}, logg.Errorf(`shopping list entry "%d" %w`, id, ErrNotExist)
+	}
+	if err != nil {
+		return shopping.Entry{}, logg.Errorf("%s %w", query, err)
+	}
+	return entry, nil
+}
+
+func scanShoppingEntry(row interface{ Scan(dest ...any) error }) (shopping.Entry, error) {
+	var entry shopping.Entry
+	var itemID, createdAt string
+	var places [3]struct{ id, label string }
+	err := row.Scan(&entry.ID, &itemID, &entry.Label, &entry.Quantity, &entry.Note, &entry.Generated, &createdAt,
+		&places[0].id, &places[0].label,
+		&places[1].id, &places[1].label,
+		&places[2].id, &places[2].label,
+	)
+	if err != nil {
+		return shopping.Entry{}, err
+	}
+	entry.ItemID = uuid.FromStringOrNil(itemID)
+	entry.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+
+	// The innermost place is where the item is put away.
+	for i, thing := range []string{"box", "shelf", "area"} {
+		if places[i].id != "" {
+			entry.Place = &shopping.Place{Thing: thing, ID: uuid.FromStringOrNil(places[i].id), Label: places[i].label}
+			break
+		}
+	}
+	return entry, nil
+}
+
+// AddShoppingEntry adds the entry to the shopping list of the user in ctx.
+// Entries of an item get the label of the item if they have none and are put away into the current place of the item.
+// If the item is already on the list, the quantity is added to its entry instead.
+func (db *DB) AddShoppingEntry(ctx context.Context, entry shopping.Entry) (shopping.Entry, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return shopping.Entry{}, logg.WrapErr(err)
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	var id int64
+	if entry.ItemID == uuid.Nil {
+		stmt := `INSERT INTO shopping_entry (owner_id, label, quantity, note, created_at) VALUES (?, ?, ?, ?, ?) RETURNING id;`
+		err = db.Sql.QueryRowContext(ctx, stmt, owner, entry.Label, entry.Quantity, entry.Note, now).Scan(&id)
+		if err != nil {
+			return shopping.Entry{}, logg.Errorf("can't add %s to the shopping list %w", entry.Label, err)
+		}
+		return shoppingEntry(ctx, db.Sql, id)
+	}
+
+	stmt := `UPDATE shopping_entry SET quantity = quantity + ?, note = COALESCE(NULLIF(?, ''), note)
+		WHERE item_id = ? AND ` + OWNER_ID + ` = ? RETURNING id;`
+	err = db.Sql.QueryRowContext(ctx, stmt, entry.Quantity, entry.Note, entry.ItemID.String(), owner).Scan(&id)
+	if err == nil {
+		return shoppingEntry(ctx, db.Sql, id)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return shopping.Entry{}, logg.Errorf("can't add item %s to the shopping list %w", entry.ItemID, err)
+	}
+
+	stmt = `INSERT INTO shopping_entry (owner_id, item_id, label, quantity, note, box_id, shelf_id, area_id, created_at)
+		SELECT ` + OWNER_ID + `, id, COALESCE(NULLIF(?, ''), label), ?, ?, box_id, shelf_id, area_id, ?
+		FROM item WHERE id = ? AND ` + OWNER_ID + ` = ? AND ` + NOT_DELETED + `
+		RETURNING id;`
+	err = db.Sql.QueryRowContext(ctx, stmt, entry.Label, entry.Quantity, entry.Note, now, entry.ItemID.String(), owner).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return shopping.Entry{}, logg.Errorf(`item "%s" %w`, entry.ItemID, ErrNotExist)
+	}
+	if err != nil {
+		return shopping.Entry{}, logg.Errorf("can't add item %s to the shopping list %w", entry.ItemID, err)
+	}
+	return shoppingEntry(ctx, db.Sql, id)
+}
+
+// GenerateShoppingEntries puts every item of the user in ctx that is below its minimum stock on the shopping list,
+// unless it is already on it. Returns the amount of added entries.
+func (db *DB) GenerateShoppingEntries(ctx context.Context) (added int, err error) {
+	return db.addLowStockEntries(ctx, uuid.Nil)
+}
+
+// addLowStockEntries puts the item on the shopping list if it is below its minimum stock and not on the list yet.
+// The entry is for the quantity missing to the minimum stock. All items are checked if itemID is uuid.Nil.
+func (db *DB) addLowStockEntries(ctx context.Context, itemID uuid.UUID) (added int, err error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return 0, logg.WrapErr(err)
+	}
+	stmt := `INSERT INTO shopping_entry (owner_id, item_id, label, quantity, generated, box_id, shelf_id, area_id, created_at)
+		SELECT ` + OWNER_ID + `, id, label, ` + ITEM_MIN_STOCK + ` - COALESCE(` + ITEM_QUANTITY + `, 0), 1, box_id, shelf_id, area_id, ?
+		FROM item
+		WHERE ` + OWNER_ID + ` = ? AND ` + NOT_DELETED + ` AND ` + lowStockCondition + `
+			AND id NOT IN (SELECT item_id FROM shopping_entry WHERE item_id IS NOT NULL AND ` + OWNER_ID + ` = ?)`
+	args := []any{time.Now().UTC().Format(time.RFC3339), owner, owner}
+	if itemID != uuid.Nil {
+		stmt += ` AND id = ?`
+		args = append(args, itemID.String())
+	}
+	result, err := db.Sql.ExecContext(ctx, stmt+";", args...)
+	if err != nil {
+		return 0, logg.Errorf("can't put low stock items on the shopping list %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, logg.WrapErr(err)
+	}
+	return int(rows), nil
+}
+
+// CheckShoppingEntry removes the bought entry with the id from the shopping list of the user in ctx.
+// The quantity of the entry is added to the stock of its item, the recorded change is nil for entries without an item.
+// The entry is deleted first in the same transaction, so an entry that is checked twice only adds its quantity once.
+// If the item is still below its minimum stock afterwards, it is put on the list again with the quantity still missing.
+func (db *DB) CheckShoppingEntry(ctx context.Context, id int64) (shopping.Entry, *stock.Change, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return shopping.Entry{}, nil, logg.WrapErr(err)
+	}
+	tx, err := db.Sql.BeginTx(ctx, nil)
+	if err != nil {
+		return shopping.Entry{}, nil, logg.WrapErr(err)
+	}
+	defer tx.Rollback()
+
+	entry, err := shoppingEntry(ctx, tx, id)
+	if err != nil {
+		return shopping.Entry{}, nil, logg.WrapErr(err)
+	}
+	err = deleteShoppingEntry(ctx, tx, owner, id)
+	if err != nil {
+		return shopping.Entry{}, nil, logg.WrapErr(err)
+	}
+	var change *stock.Change
+	if entry.ItemID != uuid.Nil {
+		c, err := changeStock(ctx, tx, entry.ItemID, entry.Quantity, stock.REASON_BOUGHT)
+		if err != nil {
+			return shopping.Entry{}, nil, logg.WrapErr(err)
+		}
+		change = &c
+	}
+	err = tx.Commit()
+	if err != nil {
+		return shopping.Entry{}, nil, logg.WrapErr(err)
+	}
+	if entry.ItemID != uuid.Nil {
+		_, err = db.addLowStockEntries(ctx, entry.ItemID)
+		if err != nil {
+			return shopping.Entry{}, nil, logg.WrapErr(err)
+		}
+	}
+	return entry, change, nil
+}
+
+// DeleteShoppingEntry removes the entry with the id from the shopping list of the user in ctx without buying it.
+func (db *DB) DeleteShoppingEntry(ctx context.Context, id int64) error {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	return deleteShoppingEntry(ctx, db.Sql, owner, id)
+}
+
+// deleteShoppingEntry returns ErrNotExist if the entry was already removed.
+func deleteShoppingEntry(ctx context.Context, q querier, owner string, id int64) error {
+	result, err := q.ExecContext(ctx, `DELETE FROM shopping_entry WHERE id = ? AND `+OWNER_ID+` = ?;`, id, owner)
+	if err != nil {
+		return logg.Errorf("can't delete shopping list entry %d %w", id, err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	if rows == 0 {
+		return logg.Errorf(`shopping list entry "%d" %w`, id, ErrNotExist)
+	}
+	return nil
+}
+
+// deleteOrphanedShoppingEntries removes the shopping list entries of purged items.
+func (db *DB) deleteOrphanedShoppingEntries(ctx context.Context) error {
+	_, err := db.Sql.ExecContext(ctx, `DELETE FROM shopping_entry WHERE item_id IS NOT NULL AND item_id NOT IN (SELECT id FROM item);`)
+	if err != nil {
+		return logg.Errorf("can't delete shopping list entries of purged items %w", err)
+	}
+	return nil
+}
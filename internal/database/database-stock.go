@@ -0,0 +1,194 @@
+package database
+
+import (
+	"basement/main/internal/common"
+	"basement/main/internal/history"
+	"basement/main/internal/households"
+	"basement/main/internal/logg"
+	"basement/main/internal/stock"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"html/template"
+	"strconv"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+const insertStockChangeStmt = `INSERT INTO stock_change (
+		owner_id, item_id, actor_id, created_at, delta, quantity, min_stock, reason
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING id;`
+
+// lowStockCondition matches items with a threshold and a quantity below it.
+const lowStockCondition = ITEM_MIN_STOCK + " > 0 AND COALESCE(" + ITEM_QUANTITY + ", 0) < " + ITEM_MIN_STOCK
+
+// ChangeStock adds delta to the quantity of the item and records the change with the reason.
+// Returns stock.ErrNotEnoughStock if the quantity would drop below 0.
+func (db *DB) ChangeStock(ctx context.Context, itemID uuid.UUID, delta int64, reason string) (stock.Change, error) {
+	tx, err := db.Sql.BeginTx(ctx, nil)
+	if err != nil {
+		return stock.Change{}, logg.WrapErr(err)
+	}
+	defer tx.Rollback()
+
+	change, err := changeStock(ctx, tx, itemID, delta, reason)
+	if err != nil {
+		return stock.Change{}, logg.WrapErr(err)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return stock.Change{}, logg.WrapErr(err)
+	}
+	_, err = db.addLowStockEntries(ctx, itemID)
+	if err != nil {
+		return stock.Change{}, logg.WrapErr(err)
+	}
+	return change, nil
+}
+
+// changeStock is ChangeStock inside of tx.
+// The caller puts the item on the shopping list with addLowStockEntries after the commit.
+func changeStock(ctx context.Context, tx *sql.Tx, itemID uuid.UUID, delta int64, reason string) (stock.Change, error) {
+	m, ok := households.FromContext(ctx)
+	if !ok {
+		return stock.Change{}, logg.WrapErr(ErrNoOwner)
+	}
+	before, err := thingStateOf(ctx, tx, "item", itemID)
+	if err != nil {
+		return stock.Change{}, logg.WrapErr(err)
+	}
+	if before == nil {
+		return stock.Change{}, logg.Errorf(`item "%s" %w`, itemID, ErrNotExist)
+	}
+
+	change := stock.Change{ItemID: itemID, Delta: delta, Reason: reason, CreatedAt: time.Now().UTC().Truncate(time.Second), ActorID: m.UserID}
+	stmt := `UPDATE item SET ` + ITEM_QUANTITY + ` = COALESCE(` + ITEM_QUANTITY + `, 0) + ?
+		WHERE id = ? AND ` + OWNER_ID + ` = ? AND ` + NOT_DELETED + `
+		RETURNING ` + ITEM_QUANTITY + `, ` + ITEM_MIN_STOCK + `;`
+	err = tx.QueryRowContext(ctx, stmt, delta, itemID.String(), m.HouseholdID.String()).Scan(&change.Quantity, &change.MinStock)
+	if errors.Is(err, sql.ErrNoRows) {
+		return stock.Change{}, logg.Errorf(`item "%s" %w`, itemID, ErrNotExist)
+	}
+	if err != nil {
+		return stock.Change{}, logg.Errorf("can't change the stock of item %s %w", itemID, err)
+	}
+	if change.Quantity < 0 {
+		return stock.Change{}, logg.Errorf("can't take %d of item %s, only %d left %w", -delta, itemID, change.Quantity-delta, stock.ErrNotEnoughStock)
+	}
+	err = tx.QueryRowContext(ctx, insertStockChangeStmt,
+		m.HouseholdID.String(), itemID.String(), m.UserID.String(), change.CreatedAt.Format(time.RFC3339),
+		change.Delta, change.Quantity, change.MinStock, change.Reason,
+	).Scan(&change.ID)
+	if err != nil {
+		return stock.Change{}, logg.Errorf("can't record the stock change of item %s %w", itemID, err)
+	}
+	err = recordHistory(ctx, tx, history.ACTION_UPDATE, "item", itemID, before)
+	if err != nil {
+		return stock.Change{}, logg.WrapErr(err)
+	}
+	return change, nil
+}
+
+// recordStockChange records the quantity an item got from the item form in the transaction of the form.
+// No change is recorded if the quantity stayed the same.
+// The caller puts the item on the shopping list with addLowStockEntries after the commit.
+func recordStockChange(ctx context.Context, tx *sql.Tx, itemID uuid.UUID, delta int64, quantity int64, minStock int64, reason string) error {
+	m, ok := households.FromContext(ctx)
+	if !ok {
+		return logg.WrapErr(ErrNoOwner)
+	}
+	if delta == 0 {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, insertStockChangeStmt,
+		m.HouseholdID.String(), itemID.String(), m.UserID.String(), time.Now().UTC().Format(time.RFC3339),
+		delta, quantity, minStock, reason,
+	)
+	if err != nil {
+		return logg.Errorf("can't record the stock change of item %s %w", itemID, err)
+	}
+	return nil
+}
+
+// deleteOrphanedStockChanges removes the stock changes of purged items.
+func (db *DB) deleteOrphanedStockChanges(ctx context.Context) error {
+	_, err := db.Sql.ExecContext(ctx, `DELETE FROM stock_change WHERE item_id NOT IN (SELECT id FROM item);`)
+	if err != nil {
+		return logg.Errorf("can't delete stock changes of purged items %w", err)
+	}
+	return nil
+}
+
+// snapshotQuantity returns the quantity of a thing state, 0 if there is none.
+func snapshotQuantity(state *thingState) int64 {
+	if state == nil {
+		return 0
+	}
+	quantity, _ := strconv.ParseInt(state.values[ITEM_QUANTITY], 10, 64)
+	return quantity
+}
+
+// StockChanges returns all recorded changes of the quantity of the item, the oldest first.
+func (db *DB) StockChanges(ctx context.Context, itemID uuid.UUID) ([]stock.Change, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	query := `
+		SELECT id, COALESCE(actor_id, ''), created_at, delta, quantity, min_stock, reason
+		FROM stock_change
+		WHERE item_id = ? AND ` + OWNER_ID + ` = ?
+		ORDER BY id;`
+	rows, err := db.Sql.QueryContext(ctx, query, itemID.String(), owner)
+	if err != nil {
+		return nil, logg.Errorf("%s %w", query, err)
+	}
+	defer rows.Close()
+
+	var changes []stock.Change
+	for rows.Next() {
+		c := stock.Change{ItemID: itemID}
+		var actorID, createdAt string
+		err := rows.Scan(&c.ID, &actorID, &createdAt, &c.Delta, &c.Quantity, &c.MinStock, &c.Reason)
+		if err != nil {
+			return nil, logg.WrapErr(err)
+		}
+		c.ActorID = uuid.FromStringOrNil(actorID)
+		c.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		changes = append(changes, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	return changes, nil
+}
+
+// LowStockListCounter returns the amount of items of the user in ctx below their minimum stock that match searchQuery.
+func (db *DB) LowStockListCounter(ctx context.Context, searchQuery string) (count int, err error) {
+	filter, err := itemListFilter(ctx, searchQuery, lowStockCondition)
+	if err != nil {
+		return 0, logg.WrapErr(err)
+	}
+	return db.itemListCount(ctx, filter)
+}
+
+// LowStockListRows returns a page of the items of the user in ctx below their minimum stock that match searchQuery.
+// The items that are missing the most of their minimum stock come first, after the sort terms of the query.
+// The snippet of every row shows the quantity and the minimum stock.
+func (db *DB) LowStockListRows(ctx context.Context, searchQuery string, limit int, page int) ([]common.ListRow, error) {
+	filter, err := itemListFilter(ctx, searchQuery, lowStockCondition)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	filter.order = append(filter.order, itemColumn("CAST(COALESCE("+ITEM_QUANTITY+", 0) AS REAL) / "+ITEM_MIN_STOCK))
+	columns := []string{ITEM_QUANTITY, ITEM_MIN_STOCK}
+	return db.itemListRows(ctx, filter, columns, limit, page, func(values []string) template.HTML {
+		quantity := values[0]
+		if quantity == "" {
+			quantity = "0"
+		}
+		return template.HTML(template.HTMLEscapeString(fmt.Sprintf("%s left, minimum %s", quantity, values[1])))
+	})
+}
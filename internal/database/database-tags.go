@@ -0,0 +1,321 @@
+package database
+
+import (
+	"basement/main/internal/common"
+	"basement/main/internal/logg"
+	"basement/main/internal/tags"
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// setTags replaces the tags of a thing with names in the transaction of the change of the thing.
+// Tags that don't exist yet are created. If names is nil the tags are not changed.
+func setTags(ctx context.Context, tx *sql.Tx, thing string, id uuid.UUID, names []string) error {
+	if names == nil {
+		return nil
+	}
+	err := ValidTable(thing)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM thing_tag WHERE thing = ? AND thing_id = ?;`, thing, id.String())
+	if err != nil {
+		return logg.Errorf(`can't remove tags of %s "%s" %w`, thing, id, err)
+	}
+	createdAt := time.Now().UTC().Format(time.RFC3339)
+	for _, name := range names {
+		newID, err := uuid.NewV4()
+		if err != nil {
+			return logg.WrapErr(err)
+		}
+		_, err = tx.ExecContext(ctx, `INSERT OR IGNORE INTO tag (id, owner_id, name, created_at) VALUES (?, ?, ?, ?);`, newID.String(), owner, name, createdAt)
+		if err != nil {
+			return logg.Errorf(`can't create tag "%s" %w`, name, err)
+		}
+		stmt := `INSERT OR IGNORE INTO thing_tag (thing, thing_id, tag_id)
+			SELECT ?, ?, id FROM tag WHERE ` + OWNER_ID + ` = ? AND name = ? COLLATE NOCASE;`
+		_, err = tx.ExecContext(ctx, stmt, thing, id.String(), owner, name)
+		if err != nil {
+			return logg.Errorf(`can't add tag "%s" to %s "%s" %w`, name, thing, id, err)
+		}
+	}
+	return nil
+}
+
+// tagsOf returns the tag names of a thing sorted by name.
+func (db *DB) tagsOf(ctx context.Context, thing string, id uuid.UUID) ([]string, error) {
+	tagsByID, err := db.tagsByThingID(ctx, thing, []string{id.String()})
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	return tagsByID[id.String()], nil
+}
+
+// attachTags sets the tags of all rows, which are all of the same type of thing.
+func (db *DB) attachTags(ctx context.Context, thing string, rows []common.ListRow) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	ids := make([]string, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID.String()
+	}
+	tagsByID, err := db.tagsByThingID(ctx, thing, ids)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	for i := range rows {
+		rows[i].Tags = tagsByID[rows[i].ID.String()]
+	}
+	return nil
+}
+
+func (db *DB) tagsByThingID(ctx context.Context, thing string, ids []string) (map[string][]string, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	args := []any{thing, owner}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	stmt := `SELECT thing_tag.thing_id, tag.name
+		FROM thing_tag JOIN tag ON tag.id = thing_tag.tag_id
+		WHERE thing_tag.thing = ? AND tag.` + OWNER_ID + ` = ? AND thing_tag.thing_id IN (?` + strings.Repeat(", ?", len(ids)-1) + `)
+		ORDER BY tag.name COLLATE NOCASE;`
+	rows, err := db.Sql.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, logg.Errorf(`can't query tags of %s %w`, thing, err)
+	}
+	defer rows.Close()
+
+	tagsByID := map[string][]string{}
+	for rows.Next() {
+		var id, name string
+		err := rows.Scan(&id, &name)
+		if err != nil {
+			return nil, logg.WrapErr(err)
+		}
+		tagsByID[id] = append(tagsByID[id], name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	return tagsByID, nil
+}
+
+// deleteOrphanedTags removes the tags of purged things.
+func (db *DB) deleteOrphanedTags(ctx context.Context) error {
+	for _, thing := range []string{"item", "box", "shelf", "area"} {
+		_, err := db.Sql.ExecContext(ctx, `DELETE FROM thing_tag WHERE thing = ? AND thing_id NOT IN (SELECT id FROM `+thing+`);`, thing)
+		if err != nil {
+			return logg.Errorf(`can't delete tags of purged %s %w`, thing, err)
+		}
+	}
+	return nil
+}
+
+// Tags returns all tags of the household sorted by name together with the amount of things
+// that have the tag. Things in the trash are not counted.
+func (db *DB) Tags(ctx context.Context) ([]tags.Tag, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	stmt := `SELECT tag.id, tag.name, COUNT(thing.id)
+		FROM tag
+		LEFT JOIN thing_tag ON thing_tag.tag_id = tag.id
+		LEFT JOIN (` + notDeletedThings + `) AS thing ON thing.thing = thing_tag.thing AND thing.id = thing_tag.thing_id
+		WHERE tag.` + OWNER_ID + ` = ?
+		GROUP BY tag.id
+		ORDER BY tag.name COLLATE NOCASE;`
+	rows, err := db.Sql.QueryContext(ctx, stmt, owner)
+	if err != nil {
+		return nil, logg.Errorf("can't query tags %w", err)
+	}
+	defer rows.Close()
+
+	list := []tags.Tag{}
+	for rows.Next() {
+		var id string
+		var tag tags.Tag
+		err := rows.Scan(&id, &tag.Name, &tag.Count)
+		if err != nil {
+			return nil, logg.WrapErr(err)
+		}
+		tag.ID = uuid.FromStringOrNil(id)
+		list = append(list, tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	return list, nil
+}
+
+// notDeletedThings selects the type, id and label of all things that are not in the trash.
+const notDeletedThings = `
+	SELECT 'item' AS thing, id, label FROM item WHERE ` + NOT_DELETED + `
+	UNION ALL SELECT 'box', id, label FROM box WHERE ` + NOT_DELETED + `
+	UNION ALL SELECT 'shelf', id, label FROM shelf WHERE ` + NOT_DELETED + `
+	UNION ALL SELECT 'area', id, label FROM area WHERE ` + NOT_DELETED
+
+// Tag returns the tag with id and the amount of things that have it.
+func (db *DB) Tag(ctx context.Context, id uuid.UUID) (tags.Tag, error) {
+	list, err := db.Tags(ctx)
+	if err != nil {
+		return tags.Tag{}, logg.WrapErr(err)
+	}
+	for _, tag := range list {
+		if tag.ID == id {
+			return tag, nil
+		}
+	}
+	return tags.Tag{}, logg.Errorf(`tag "%s" %w`, id, ErrNotExist)
+}
+
+// CreateTag creates a tag that isn't assigned to anything yet.
+// Returns tags.ErrTagExists if the household already has a tag with this name, ignoring case.
+func (db *DB) CreateTag(ctx context.Context, name string) (uuid.UUID, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return uuid.Nil, logg.WrapErr(err)
+	}
+	id, err := uuid.NewV4()
+	if err != nil {
+		return uuid.Nil, logg.WrapErr(err)
+	}
+	result, err := db.Sql.ExecContext(ctx, `INSERT OR IGNORE INTO tag (id, owner_id, name, created_at) VALUES (?, ?, ?, ?);`,
+		id.String(), owner, name, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return uuid.Nil, logg.Errorf(`can't create tag "%s" %w`, name, err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return uuid.Nil, logg.WrapErr(err)
+	}
+	if n == 0 {
+		return uuid.Nil, logg.Errorf(`"%s" %w`, name, tags.ErrTagExists)
+	}
+	return id, nil
+}
+
+// RenameTag renames a tag on all things that have it.
+// Returns tags.ErrTagExists if another tag of the household already has this name, ignoring case.
+func (db *DB) RenameTag(ctx context.Context, id uuid.UUID, name string) error {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	var taken int
+	err = db.Sql.QueryRowContext(ctx, `SELECT COUNT(*) FROM tag WHERE `+OWNER_ID+` = ? AND name = ? COLLATE NOCASE AND id != ?;`, owner, name, id.String()).Scan(&taken)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	if taken > 0 {
+		return logg.Errorf(`"%s" %w`, name, tags.ErrTagExists)
+	}
+
+	tx, err := db.Sql.BeginTx(ctx, nil)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	defer tx.Rollback()
+
+	err = recordUpdates(ctx, tx, func() error {
+		result, err := tx.ExecContext(ctx, `UPDATE tag SET name = ? WHERE id = ? AND `+OWNER_ID+` = ?;`, name, id.String(), owner)
+		if err != nil {
+			return logg.Errorf(`can't rename tag "%s" %w`, id, err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return logg.WrapErr(err)
+		}
+		if n == 0 {
+			return logg.Errorf(`tag "%s" %w`, id, ErrNotExist)
+		}
+		return nil
+	}, `SELECT thing, thing_id FROM thing_tag WHERE tag_id = ?;`, id.String())
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	return tx.Commit()
+}
+
+// DeleteTag deletes a tag and removes it from all things.
+func (db *DB) DeleteTag(ctx context.Context, id uuid.UUID) error {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	tx, err := db.Sql.BeginTx(ctx, nil)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	defer tx.Rollback()
+
+	err = recordUpdates(ctx, tx, func() error {
+		result, err := tx.ExecContext(ctx, `DELETE FROM tag WHERE id = ? AND `+OWNER_ID+` = ?;`, id.String(), owner)
+		if err != nil {
+			return logg.Errorf(`can't delete tag "%s" %w`, id, err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return logg.WrapErr(err)
+		}
+		if n == 0 {
+			return logg.Errorf(`tag "%s" %w`, id, ErrNotExist)
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM thing_tag WHERE tag_id = ?;`, id.String())
+		if err != nil {
+			return logg.Errorf(`can't remove tag "%s" from things %w`, id, err)
+		}
+		return nil
+	}, `SELECT thing, thing_id FROM thing_tag WHERE tag_id = ?;`, id.String())
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	return tx.Commit()
+}
+
+// TaggedThings returns all things that have the tag and are not in the trash, sorted by type and label.
+func (db *DB) TaggedThings(ctx context.Context, id uuid.UUID) ([]tags.TaggedThing, error) {
+	_, err := db.Tag(ctx, id)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	stmt := `SELECT thing.thing, thing.id, thing.label
+		FROM thing_tag
+		JOIN (` + notDeletedThings + `) AS thing ON thing.thing = thing_tag.thing AND thing.id = thing_tag.thing_id
+		WHERE thing_tag.tag_id = ?
+		ORDER BY thing.thing, thing.label COLLATE NOCASE;`
+	rows, err := db.Sql.QueryContext(ctx, stmt, id.String())
+	if err != nil {
+		return nil, logg.Errorf(`can't query things with tag "%s" %w`, id, err)
+	}
+	defer rows.Close()
+
+	things := []tags.TaggedThing{}
+	for rows.Next() {
+		var thingID string
+		var thing tags.TaggedThing
+		err := rows.Scan(&thing.Thing, &thingID, &thing.Label)
+		if err != nil {
+			return nil, logg.WrapErr(err)
+		}
+		thing.ID = uuid.FromStringOrNil(thingID)
+		things = append(things, thing)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, logg.WrapErr(err)
+	}
+	return things, nil
+}
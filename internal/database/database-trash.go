@@ -0,0 +1,326 @@
+package database
+
+import (
+	"basement/main/internal/env"
+	"basement/main/internal/history"
+	"basement/main/internal/logg"
+	"basement/main/internal/trash"
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// containerColumns are the columns of a table that point to the box, shelf or area it is in.
+var containerColumns = map[string][]string{
+	"item":  {FTS_BOX_ID, FTS_SHELF_ID, FTS_AREA_ID},
+	"box":   {FTS_BOX_ID, FTS_SHELF_ID, FTS_AREA_ID},
+	"shelf": {FTS_AREA_ID},
+	"area":  {},
+}
+
+// TrashedThings returns all items, boxes, shelves and areas of the household
+// that are in the trash, the latest deleted first.
+func (db *DB) TrashedThings(ctx context.Context) ([]trash.Thing, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return nil, logg.WrapErr(err)
+	}
+
+	var things []trash.Thing
+	for _, table := range []string{"item", "box", "shelf", "area"} {
+		columns := ""
+		for _, column := range containerColumns[table] {
+			columns += ", COALESCE(" + column + ", '')"
+		}
+		query := fmt.Sprintf(`SELECT id, label, %s%s FROM %s WHERE %s = ? AND %s IS NOT NULL;`, DELETED_AT, columns, table, OWNER_ID, DELETED_AT)
+		rows, err := db.Sql.QueryContext(ctx, query, owner)
+		if err != nil {
+			return nil, logg.WrapErr(err)
+		}
+
+		var found []trash.Thing
+		var locations []map[string]string
+		for rows.Next() {
+			var id, deletedAt string
+			thing := trash.Thing{Thing: table}
+			containerIDs := make([]string, len(containerColumns[table]))
+			dest := []any{&id, &thing.Label, &deletedAt}
+			for i := range containerIDs {
+				dest = append(dest, &containerIDs[i])
+			}
+			err := rows.Scan(dest...)
+			if err != nil {
+				rows.Close()
+				return nil, logg.WrapErr(err)
+			}
+			thing.ID = uuid.FromStringOrNil(id)
+			thing.DeletedAt, _ = time.Parse(time.RFC3339, deletedAt)
+
+			containers := map[string]string{}
+			for i, column := range containerColumns[table] {
+				containers[column] = containerIDs[i]
+			}
+			found = append(found, thing)
+			locations = append(locations, containers)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, logg.WrapErr(err)
+		}
+
+		// The locations are queried after the rows are closed, the in-memory database only has a single connection.
+		for i := range found {
+			location, err := innermostLocation(ctx, db.Sql, locations[i])
+			if err != nil {
+				return nil, logg.WrapErr(err)
+			}
+			found[i].Location = location.String()
+		}
+		things = append(things, found...)
+	}
+
+	sort.SliceStable(things, func(i, j int) bool {
+		return things[i].DeletedAt.After(things[j].DeletedAt)
+	})
+	return things, nil
+}
+
+// Restore moves the thing with id out of the trash to its previous location.
+// If the box, shelf or area it was in is in the trash itself or was purged, the thing is restored without that location.
+func (db *DB) Restore(ctx context.Context, thing string, id uuid.UUID) error {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	tx, err := db.Sql.BeginTx(ctx, nil)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	defer tx.Rollback()
+
+	before, err := trashedThingState(ctx, tx, thing, id)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	if before == nil {
+		return logg.Errorf(`%s "%s" in trash %w`, thing, id, ErrNotExist)
+	}
+
+	for _, column := range containerColumns[thing] {
+		container := column[:len(column)-len("_id")]
+		stmt := fmt.Sprintf(`UPDATE %s SET %s = ? WHERE id = ? AND %s IS NOT NULL AND %s != ?
+			AND NOT EXISTS (SELECT 1 FROM %s AS c WHERE c.id = %s.%s AND c.%s = ? AND c.%s);`,
+			thing, column, column, column, container, thing, column, OWNER_ID, NOT_DELETED)
+		_, err = tx.ExecContext(ctx, stmt, uuid.Nil.String(), id.String(), uuid.Nil.String(), owner)
+		if err != nil {
+			return logg.Errorf(`can't clear %s of %s "%s" %w`, column, thing, id, err)
+		}
+	}
+
+	stmt := fmt.Sprintf(`UPDATE %s SET %s = NULL WHERE id = ? AND %s = ? AND %s IS NOT NULL;`, thing, DELETED_AT, OWNER_ID, DELETED_AT)
+	_, err = tx.ExecContext(ctx, stmt, id.String(), owner)
+	if err != nil {
+		return logg.Errorf(`can't restore %s "%s" %w`, thing, id, err)
+	}
+	err = recordHistory(ctx, tx, history.ACTION_RESTORE, thing, id, before)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	return tx.Commit()
+}
+
+// Purge permanently removes the thing with id from the trash.
+func (db *DB) Purge(ctx context.Context, thing string, id uuid.UUID) error {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	tx, err := db.Sql.BeginTx(ctx, nil)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	defer tx.Rollback()
+
+	before, err := trashedThingState(ctx, tx, thing, id)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	if before == nil {
+		return logg.Errorf(`%s "%s" in trash %w`, thing, id, ErrNotExist)
+	}
+	err = clearContainer(ctx, tx, thing, id)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+
+	stmt := fmt.Sprintf(`DELETE FROM %s WHERE id = ? AND %s = ? AND %s IS NOT NULL;`, thing, OWNER_ID, DELETED_AT)
+	_, err = tx.ExecContext(ctx, stmt, id.String(), owner)
+	if err != nil {
+		return logg.Errorf(`can't purge %s "%s" %w`, thing, id, err)
+	}
+	err = recordHistory(ctx, tx, history.ACTION_PURGE, thing, id, before)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+
+	err = db.deleteOrphanedTags(ctx)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	err = db.deleteOrphanedCustomFieldValues(ctx)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	err = db.deleteOrphanedStockChanges(ctx)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	err = db.deleteOrphanedShoppingEntries(ctx)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	err = db.deleteOrphanedLoans(ctx)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	return db.deleteOrphanedPictures(ctx)
+}
+
+// clearContainer removes the box, shelf or area with the id as location of everything that is in it, before it is purged.
+// The update triggers of the contents refresh the container labels of their search rows.
+func clearContainer(ctx context.Context, tx *sql.Tx, container string, id uuid.UUID) error {
+	column := container + "_id"
+	var selects []string
+	var args []any
+	for _, table := range []string{"item", "box", "shelf"} {
+		if slices.Contains(containerColumns[table], column) {
+			selects = append(selects, fmt.Sprintf(`SELECT '%s', id FROM %s WHERE %s = ?`, table, table, column))
+			args = append(args, id.String())
+		}
+	}
+	if len(selects) == 0 {
+		return nil
+	}
+	return recordUpdates(ctx, tx, func() error {
+		for _, table := range []string{"item", "box", "shelf"} {
+			if !slices.Contains(containerColumns[table], column) {
+				continue
+			}
+			_, err := tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET %s = ? WHERE %s = ?;`, table, column, column), uuid.Nil.String(), id.String())
+			if err != nil {
+				return logg.Errorf(`can't take the contents out of %s "%s" %w`, container, id, err)
+			}
+		}
+		return nil
+	}, strings.Join(selects, " UNION ALL ")+";", args...)
+}
+
+// clearPurgedContainers removes boxes, shelves and areas that don't exist anymore as location of all things.
+func clearPurgedContainers(tx *sql.Tx) error {
+	for _, table := range []string{"item", "box", "shelf"} {
+		for _, column := range containerColumns[table] {
+			container := column[:len(column)-len("_id")]
+			stmt := fmt.Sprintf(`UPDATE %s SET %s = ? WHERE %s IS NOT NULL AND %s != ? AND %s NOT IN (SELECT id FROM %s);`,
+				table, column, column, column, column, container)
+			_, err := tx.Exec(stmt, uuid.Nil.String(), uuid.Nil.String())
+			if err != nil {
+				return logg.Errorf(`can't clear %s of %s %w`, column, table, err)
+			}
+		}
+	}
+	return nil
+}
+
+// PurgeExpiredTrash permanently removes things of all households that were moved to the trash before deletedBefore.
+// Returns the amount of purged things.
+func (db *DB) PurgeExpiredTrash(deletedBefore time.Time) (int64, error) {
+	tx, err := db.Sql.Begin()
+	if err != nil {
+		return 0, logg.WrapErr(err)
+	}
+	defer tx.Rollback()
+
+	var purged int64
+	for _, table := range []string{"item", "box", "shelf", "area"} {
+		stmt := fmt.Sprintf(`DELETE FROM %s WHERE %s IS NOT NULL AND %s < ?;`, table, DELETED_AT, DELETED_AT)
+		result, err := tx.Exec(stmt, deletedBefore.UTC().Format(time.RFC3339))
+		if err != nil {
+			return purged, logg.Errorf(`can't purge expired trash from "%s" %w`, table, err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return purged, logg.WrapErr(err)
+		}
+		purged += n
+	}
+	err = clearPurgedContainers(tx)
+	if err != nil {
+		return 0, logg.WrapErr(err)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return 0, logg.WrapErr(err)
+	}
+	if purged > 0 {
+		err := db.deleteOrphanedTags(context.Background())
+		if err != nil {
+			return purged, logg.WrapErr(err)
+		}
+		err = db.deleteOrphanedCustomFieldValues(context.Background())
+		if err != nil {
+			return purged, logg.WrapErr(err)
+		}
+		err = db.deleteOrphanedStockChanges(context.Background())
+		if err != nil {
+			return purged, logg.WrapErr(err)
+		}
+		err = db.deleteOrphanedShoppingEntries(context.Background())
+		if err != nil {
+			return purged, logg.WrapErr(err)
+		}
+		err = db.deleteOrphanedLoans(context.Background())
+		if err != nil {
+			return purged, logg.WrapErr(err)
+		}
+		err = db.deleteOrphanedPictures(context.Background())
+		if err != nil {
+			return purged, logg.WrapErr(err)
+		}
+	}
+	return purged, nil
+}
+
+// PurgeExpiredTrashEvery purges the expired trash right away and then every interval.
+// The retention period is read from the config on every run.
+// Blobs that nothing refers to anymore are removed afterwards. It never returns.
+func (db *DB) PurgeExpiredTrashEvery(interval time.Duration) {
+	for {
+		days := env.CurrentConfig().TrashRetentionDays()
+		if days > 0 {
+			purged, err := db.PurgeExpiredTrash(time.Now().AddDate(0, 0, -days))
+			if err != nil {
+				logg.Err(err)
+			} else if purged > 0 {
+				logg.Infof("purged %d things from the trash", purged)
+			}
+		}
+		removed, err := db.CollectBlobGarbage()
+		if err != nil {
+			logg.Err(err)
+		} else if removed > 0 {
+			logg.Infof("removed %d unreferenced blobs", removed)
+		}
+		time.Sleep(interval)
+	}
+}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"basement/main/internal/env"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestBackup(t *testing.T) {
+	config := env.CurrentConfig()
+	oldPath, oldRetention := config.BackupPath(), config.BackupRetention()
+	defer config.SetBackupPath(oldPath).SetBackupRetention(oldRetention)
+	dir := t.TempDir()
+	config.SetBackupPath(dir).SetBackupRetention(2)
+
+	EmptyTestDatabase()
+	resetTestItems()
+	err := dbTest.CreateNewItem(testCtx, *ITEM_1)
+	assert.Equal(t, err, nil)
+
+	var names []string
+	for i := 0; i < 3; i++ {
+		snapshot, err := dbTest.Backup()
+		assert.Equal(t, err, nil)
+		names = append(names, snapshot.Name)
+		assert.Equal(t, ValidateBackup(filepath.Join(dir, snapshot.Name)), nil)
+	}
+
+	// Only the newest 2 snapshots are kept.
+	snapshots, err := dbTest.Backups()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(snapshots), 2)
+	assert.Equal(t, snapshots[0].Name, names[2])
+	assert.Equal(t, snapshots[1].Name, names[1])
+}
+
+func TestValidateBackup(t *testing.T) {
+	dir := t.TempDir()
+
+	assert.NotEqual(t, ValidateBackup(filepath.Join(dir, "missing.db")), nil)
+
+	garbage := filepath.Join(dir, "garbage.db")
+	err := os.WriteFile(garbage, []byte("not a database"), 0644)
+	assert.Equal(t, err, nil)
+	assert.NotEqual(t, ValidateBackup(garbage), nil)
+}
+
+func TestRestoreBackupRejectsOtherFiles(t *testing.T) {
+	err := dbTest.RestoreBackup("../sqlite-database.db")
+	assert.NotEqual(t, err, nil)
+	err = dbTest.RestoreBackup("basement-20240101-000000.000.db")
+	assert.NotEqual(t, err, nil)
+}
+
+func TestRestoreOldestBackup(t *testing.T) {
+	config := env.CurrentConfig()
+	oldPath, oldRetention := config.BackupPath(), config.BackupRetention()
+	defer config.SetBackupPath(oldPath).SetBackupRetention(oldRetention)
+	config.SetBackupPath(t.TempDir()).SetBackupRetention(1)
+
+	EmptyTestDatabase()
+	resetTestItems()
+	err := dbTest.CreateNewItem(testCtx, *ITEM_1)
+	assert.Equal(t, err, nil)
+	snapshot, err := dbTest.Backup()
+	assert.Equal(t, err, nil)
+
+	err = dbTest.DeleteItem(testCtx, ITEM_1.ID)
+	assert.Equal(t, err, nil)
+	exists, err := dbTest.Exists(testCtx, "item", ITEM_1.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, exists, false)
+
+	// The retention is full, the restored snapshot must not be pruned before it is copied.
+	err = dbTest.RestoreBackup(snapshot.Name)
+	assert.Equal(t, err, nil)
+	exists, err = dbTest.Exists(testCtx, "item", ITEM_1.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, exists, true)
+
+	// Only the snapshot taken before the restore is kept.
+	snapshots, err := dbTest.Backups()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(snapshots), 1)
+	assert.NotEqual(t, snapshots[0].Name, snapshot.Name)
+}
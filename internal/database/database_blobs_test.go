@@ -0,0 +1,115 @@
+package database
+
+import (
+	"basement/main/internal/env"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestStoreBlob(t *testing.T) {
+	key, err := storeBlob(VALID_BASE64_PNG)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, isBlobKey(key), true)
+	assert.Equal(t, blobBase64(key), VALID_BASE64_PNG)
+
+	// the same picture is stored once
+	again, err := storeBlob(VALID_BASE64_PNG)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, again, key)
+
+	key, err = storeBlob("")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, key, "")
+
+	_, err = storeBlob("not base64!")
+	assert.NotEqual(t, err, nil)
+}
+
+func TestPictureIsStoredAsBlob(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, *ITEM_1), nil)
+
+	var picture, preview string
+	err := dbTest.Sql.QueryRow(`SELECT picture, preview_picture FROM item WHERE id = ?;`, ITEM_1.ID.String()).Scan(&picture, &preview)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, isBlobKey(picture), true)
+	assert.Equal(t, isBlobKey(preview), true)
+
+	saved, err := dbTest.ItemById(testCtx, ITEM_1.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, saved.Picture, VALID_BASE64_PNG)
+}
+
+func TestMoveBlobs(t *testing.T) {
+	config := env.CurrentConfig()
+	oldBlobPath := config.BlobPath()
+	defer config.SetBlobPath(oldBlobPath)
+	config.SetBlobPath(t.TempDir())
+
+	EmptyTestDatabase()
+	resetTestItems()
+	item := *ITEM_1
+	item.Picture = ""
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item), nil)
+	// a row from before the blob store
+	_, err := dbTest.Sql.Exec(`UPDATE item SET picture = ? WHERE id = ?;`, VALID_BASE64_PNG, item.ID.String())
+	assert.Equal(t, err, nil)
+
+	// a dry run doesn't write files
+	tx, err := dbTest.Sql.Begin()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, moveBlobs(tx, true), nil)
+	assert.Equal(t, tx.Rollback(), nil)
+	files, err := os.ReadDir(config.BlobPath())
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(files), 0)
+
+	tx, err = dbTest.Sql.Begin()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, moveBlobs(tx, false), nil)
+	assert.Equal(t, tx.Commit(), nil)
+
+	var picture string
+	err = dbTest.Sql.QueryRow(`SELECT picture FROM item WHERE id = ?;`, item.ID.String()).Scan(&picture)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, isBlobKey(picture), true)
+	assert.Equal(t, blobBase64(picture), VALID_BASE64_PNG)
+}
+
+func TestCollectBlobGarbage(t *testing.T) {
+	config := env.CurrentConfig()
+	oldBackupPath, oldBlobPath := config.BackupPath(), config.BlobPath()
+	defer config.SetBackupPath(oldBackupPath).SetBlobPath(oldBlobPath)
+	config.SetBackupPath(t.TempDir()).SetBlobPath(t.TempDir())
+
+	EmptyTestDatabase()
+	resetTestItems()
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, *ITEM_1), nil)
+	unused, err := storeBlob(VALID_BASE64_PNG_2)
+	assert.Equal(t, err, nil)
+
+	// new blobs are kept, their row might not be committed yet
+	removed, err := dbTest.CollectBlobGarbage()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, removed, 0)
+
+	old := time.Now().Add(-2 * blobGracePeriod)
+	err = os.Chtimes(blobFile(unused), old, old)
+	assert.Equal(t, err, nil)
+	saved, err := dbTest.ItemById(testCtx, ITEM_1.ID)
+	assert.Equal(t, err, nil)
+	used, err := storeBlob(saved.Picture)
+	assert.Equal(t, err, nil)
+	err = os.Chtimes(blobFile(used), old, old)
+	assert.Equal(t, err, nil)
+
+	removed, err = dbTest.CollectBlobGarbage()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, removed, 1)
+	assert.Equal(t, fileExists(blobFile(unused)), false)
+	assert.Equal(t, fileExists(blobFile(used)), true)
+}
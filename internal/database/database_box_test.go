@@ -18,7 +18,7 @@ func TestInsertNewBox(t *testing.T) {
 
 	// Step 2: Insert boxes
 	for _, box := range testBoxes() {
-		_, err := dbTest.insertNewBox(box)
+		_, err := dbTest.insertNewBox(testCtx, box)
 		if err != nil {
 			t.Fatalf("insertNewBox failed: %v", err)
 		}
@@ -27,7 +27,7 @@ func TestInsertNewBox(t *testing.T) {
 	// Step 3: Insert items
 	for _, item := range testItems() {
 		// fmt.Println(item)
-		err := dbTest.insertNewItem(item)
+		err := dbTest.insertNewItem(testCtx, item)
 		if err != nil {
 			t.Fatalf("insertNewItem failed: %v", err)
 		}
@@ -35,13 +35,13 @@ func TestInsertNewBox(t *testing.T) {
 
 	//	Step 4: Verify that the insertion of items was successful
 	for _, item := range testItems() {
-		_, err := dbTest.ItemByField("id", item.ID.String())
+		_, err := dbTest.ItemByField(testCtx, "id", item.ID.String())
 		if err != nil {
 			t.Fatalf("get item error: %v", err)
 		}
 	}
 
-	fetchedBox, err := dbTest.BoxById(testBox.ID)
+	fetchedBox, err := dbTest.BoxById(testCtx, testBox.ID)
 	if err != nil {
 		t.Fatalf(" the function BoxByfield not working properly : %v %v", err.Error(), testBox)
 	}
@@ -58,7 +58,7 @@ func TestInsertNewBox(t *testing.T) {
 
 	duplicateBox := *BOX_1
 
-	_, err = dbTest.insertNewBox(&duplicateBox)
+	_, err = dbTest.insertNewBox(testCtx, &duplicateBox)
 	if err == nil {
 		t.Errorf("Expected an error when inserting a box with an existing ID, got none")
 	}
@@ -71,15 +71,15 @@ func TestInsertNewBoxWithOuterBox(t *testing.T) {
 	outerBox := BOX_1
 	innerBox := BOX_2
 	innerBox.OuterBoxID = outerBox.ID
-	_, err = dbTest.insertNewBox(innerBox)
+	_, err = dbTest.insertNewBox(testCtx, innerBox)
 	assert.Equal(t, err, nil)
-	_, err = dbTest.insertNewBox(outerBox)
+	_, err = dbTest.insertNewBox(testCtx, outerBox)
 	assert.Equal(t, err, nil)
 
-	fetchedOuterBox, err := dbTest.BoxById(outerBox.ID)
+	fetchedOuterBox, err := dbTest.BoxById(testCtx, outerBox.ID)
 	assert.Equal(t, err, nil)
 	assert.Equal(t, len(fetchedOuterBox.InnerBoxes), 1)
-	fetchedInnerBox, err := dbTest.BoxById(innerBox.ID)
+	fetchedInnerBox, err := dbTest.BoxById(testCtx, innerBox.ID)
 	assert.Equal(t, err, nil)
 	assert.NotEqual(t, fetchedInnerBox.OuterBox, nil)
 }
@@ -89,10 +89,10 @@ func TestBoxByField(t *testing.T) {
 	resetTestBoxes()
 
 	testBox := BOX_1
-	dbTest.insertNewBox(testBox)
+	dbTest.insertNewBox(testCtx, testBox)
 
 	// Testing retrieval by a field that should exist
-	fetchedBox, err := dbTest.BoxById(testBox.ID)
+	fetchedBox, err := dbTest.BoxById(testCtx, testBox.ID)
 	assert.Equal(t, err, nil)
 	if err != nil {
 		t.Fatalf("Failed to retrieve box by id: %v", err)
@@ -100,7 +100,7 @@ func TestBoxByField(t *testing.T) {
 	assert.Equal(t, fetchedBox.ID.String(), testBox.ID.String())
 
 	// Testing retrieval by a non-existent field
-	_, err = dbTest.BoxByField("non_existent_field", "some_value")
+	_, err = dbTest.BoxByField(testCtx, "non_existent_field", "some_value")
 	assert.NotEqual(t, err, nil)
 
 }
@@ -112,18 +112,18 @@ func TestCreateNewBox(t *testing.T) {
 	testBox := BOX_1
 
 	// Testing creation of a new box that does not already exist
-	_, err := dbTest.CreateBox(testBox)
+	_, err := dbTest.CreateBox(testCtx, testBox)
 	assert.Equal(t, nil, err)
 	if err != nil {
 		t.Fatalf("Failed to create new box: %v", err)
 	}
 
 	// Verify box was created
-	exists := dbTest.BoxExistById(testBox.ID)
+	exists := dbTest.BoxExistById(testCtx, testBox.ID)
 	assert.Equal(t, true, exists)
 
 	// Test creating the same box again to trigger an error
-	_, err = dbTest.CreateBox(testBox)
+	_, err = dbTest.CreateBox(testCtx, testBox)
 	assert.NotEqual(t, nil, err)
 
 }
@@ -136,14 +136,14 @@ func TestBoxIDs(t *testing.T) {
 
 	// Insert test boxes into the database
 	for _, testBox := range testBoxes() {
-		_, err := dbTest.insertNewBox(testBox)
+		_, err := dbTest.insertNewBox(testCtx, testBox)
 		if err != nil {
 			t.Fatalf("Failed to insert test box: %v", err)
 		}
 	}
 
 	// Call the BoxIDs function
-	actualIDs, err := dbTest.BoxIDs()
+	actualIDs, err := dbTest.BoxIDs(testCtx)
 	if err != nil {
 		t.Fatalf("BoxIDs function returned an error: %v", err)
 	}
@@ -162,7 +162,7 @@ func TestBoxUpdateO(t *testing.T) {
 	assert.NotEqual(t, testBox.Picture, "")
 	assert.Equal(t, testBox.Picture, VALID_BASE64_PNG)
 	assert.Equal(t, testBox.PreviewPicture, "")
-	_, err := dbTest.insertNewBox(testBox)
+	_, err := dbTest.insertNewBox(testCtx, testBox)
 	if err != nil {
 		t.Fatalf("error while inserting the box: %v", err)
 	}
@@ -178,13 +178,13 @@ func TestBoxUpdateO(t *testing.T) {
 	assert.NotEqual(t, oldLabel, testBox.Label)
 	assert.NotEqual(t, oldPre, "")
 
-	err = dbTest.UpdateBox(*testBox, false, "image/png")
+	err = dbTest.UpdateBox(testCtx, *testBox, false, "image/png")
 	if err != nil {
 		t.Fatalf("error while updating the box: %v", err)
 	}
 
 	// Retrieve the updated box from the database
-	updatedBox, err := dbTest.BoxById(testBox.ID)
+	updatedBox, err := dbTest.BoxById(testCtx, testBox.ID)
 	if err != nil {
 		t.Fatalf("error while retrieving the updated box: %v", err)
 	}
@@ -201,13 +201,13 @@ func TestBoxUpdateO(t *testing.T) {
 	assert.NotEqual(t, oldLabel, updatedBox.Label)
 	assert.NotEqual(t, oldDescr, updatedBox.Description)
 
-	row, err := dbTest.listRowByID("box_fts", testBox.ID)
+	row, err := dbTest.listRowByID(testCtx, "box_fts", testBox.ID)
 	assert.NotEqual(t, row.PreviewPicture, "")
 	assert.Equal(t, row.PreviewPicture, updatedBox.PreviewPicture)
-	rows, err := dbTest.BoxListRows("", 1, 1)
+	rows, err := dbTest.BoxListRows(testCtx, "", 1, 1)
 	assert.NotEqual(t, rows[0].PreviewPicture, "")
 	fmt.Println(rows[0].PreviewPicture)
-	frows, err := common.FilledRows(dbTest.BoxListRows, "", 1, 1, 1, common.ListRowTemplateOptions{})
+	frows, err := common.FilledRows(testCtx, dbTest.BoxListRows, "", 1, 1, 1, common.ListRowTemplateOptions{})
 	assert.NotEqual(t, frows[0].PreviewPicture, "")
 	assert.NotEqual(t, frows[0].PreviewPicture, oldPre)
 	assert.Equal(t, frows[0].PreviewPicture, updatedBox.PreviewPicture)
@@ -220,14 +220,14 @@ func TestBoxUpdateBoxInBoxError(t *testing.T) {
 	resetTestBoxes()
 	var err error
 
-	_, err = dbTest.insertNewBox(BOX_1)
+	_, err = dbTest.insertNewBox(testCtx, BOX_1)
 	if err != nil {
 		t.Fatalf("error while inserting the box: %v", err)
 	}
 
 	BOX_1.OuterBoxID = BOX_1.ID
 
-	err = dbTest.UpdateBox(*BOX_1, false, "image/png")
+	err = dbTest.UpdateBox(testCtx, *BOX_1, false, "image/png")
 	assert.NotEqual(t, err, nil)
 
 	EmptyTestDatabase()
@@ -239,7 +239,7 @@ func TestBoxUpdateIgnorePicture(t *testing.T) {
 
 	testBox := BOX_1
 	testBox.PreviewPicture = VALID_BASE64_PREVIEW_PNG
-	_, err := dbTest.insertNewBox(testBox)
+	_, err := dbTest.insertNewBox(testCtx, testBox)
 	if err != nil {
 		t.Fatalf("error while inserting the box: %v", err)
 	}
@@ -258,13 +258,13 @@ func TestBoxUpdateIgnorePicture(t *testing.T) {
 	assert.NotEqual(t, oldPicture, testBox.Picture)
 	assert.NotEqual(t, oldPreviewPicture, testBox.PreviewPicture)
 
-	err = dbTest.UpdateBox(*testBox, true, "image/png")
+	err = dbTest.UpdateBox(testCtx, *testBox, true, "image/png")
 	if err != nil {
 		t.Fatalf("error while updating the box: %v", err)
 	}
 
 	// Retrieve the updated box from the database
-	updatedBox, err := dbTest.BoxById(testBox.ID)
+	updatedBox, err := dbTest.BoxById(testCtx, testBox.ID)
 	if err != nil {
 		t.Fatalf("error while retrieving the updated box: %v", err)
 	}
@@ -288,7 +288,7 @@ func TestBoxUpdateRemovePicture(t *testing.T) {
 	resetTestBoxes()
 
 	testBox := BOX_1
-	_, err := dbTest.insertNewBox(testBox)
+	_, err := dbTest.insertNewBox(testCtx, testBox)
 	if err != nil {
 		t.Fatalf("error while inserting the box: %v", err)
 	}
@@ -301,13 +301,13 @@ func TestBoxUpdateRemovePicture(t *testing.T) {
 	assert.NotEqual(t, oldPicture, testBox.Picture)
 	assert.NotEqual(t, oldPreviewPicture, testBox.PreviewPicture)
 
-	err = dbTest.UpdateBox(*testBox, false, "")
+	err = dbTest.UpdateBox(testCtx, *testBox, false, "")
 	if err != nil {
 		t.Fatalf("error while updating the box: %v", err)
 	}
 
 	// Retrieve the updated box from the database
-	updatedBox, err := dbTest.BoxById(testBox.ID)
+	updatedBox, err := dbTest.BoxById(testCtx, testBox.ID)
 	if err != nil {
 		t.Fatalf("error while retrieving the updated box: %v", err)
 	}
@@ -327,20 +327,20 @@ func TestBoxUpdateShelf(t *testing.T) {
 	box := BOX_1
 	box.ShelfID = shelf.ID
 
-	err := dbTest.CreateShelf(shelf)
+	err := dbTest.CreateShelf(testCtx, shelf)
 	assert.Equal(t, err, nil)
-	_, err = dbTest.CreateBox(box)
+	_, err = dbTest.CreateBox(testCtx, box)
 	assert.Equal(t, err, nil)
-	boxrow, _ := dbTest.BoxListRowByID(box.ID)
+	boxrow, _ := dbTest.BoxListRowByID(testCtx, box.ID)
 	assert.Equal(t, boxrow.ShelfID, shelf.ID)
 	assert.Equal(t, boxrow.ShelfLabel, shelf.Label)
 
-	err = dbTest.CreateShelf(SHELF_2)
+	err = dbTest.CreateShelf(testCtx, SHELF_2)
 	assert.Equal(t, err, nil)
 	box.ShelfID = SHELF_2.ID
 
-	dbTest.UpdateBox(*box, true, "image/png")
-	boxrow, err = dbTest.BoxListRowByID(box.ID)
+	dbTest.UpdateBox(testCtx, *box, true, "image/png")
+	boxrow, err = dbTest.BoxListRowByID(testCtx, box.ID)
 	assert.Equal(t, err, nil)
 	assert.Equal(t, boxrow.ShelfID, SHELF_2.ID)
 	assert.Equal(t, boxrow.ShelfLabel, SHELF_2.Label)
@@ -355,30 +355,30 @@ func TestDeleteBox(t *testing.T) {
 	BOX_2.OuterBoxID = BOX_1.ID
 
 	for _, box := range testBoxes() {
-		_, err := dbTest.insertNewBox(box)
+		_, err := dbTest.insertNewBox(testCtx, box)
 		if err != nil {
 			t.Fatalf("insertNewBox failed: %v", err)
 		}
 	}
 
-	err := dbTest.insertNewItem(*ITEM_1)
+	err := dbTest.insertNewItem(testCtx, *ITEM_1)
 	if err != nil {
 		t.Fatalf("insertNewItem failed: %v", err)
 	}
 
-	err = dbTest.DeleteBox(BOX_1.ID)
+	err = dbTest.DeleteBox(testCtx, BOX_1.ID)
 	assert.NotEqual(t, err, nil) // err: can't delete, box not empty
 
-	err = dbTest.DeleteItem(ITEM_1.ID)
+	err = dbTest.DeleteItem(testCtx, ITEM_1.ID)
 	if err != nil {
 		t.Fatalf("the item was not deleted: %v", err)
 	}
-	err = dbTest.DeleteBox(BOX_2.ID)
+	err = dbTest.DeleteBox(testCtx, BOX_2.ID)
 	if err != nil {
 		t.Fatalf("deleting the innerbox was not succeed: %v", err)
 	}
 
-	err = dbTest.DeleteBox(BOX_1.ID)
+	err = dbTest.DeleteBox(testCtx, BOX_1.ID)
 	if err != nil {
 		t.Fatalf("delete the box after deleting the data inside of it was not succeed")
 	}
@@ -395,36 +395,36 @@ func TestMoveBoxToBox(t *testing.T) {
 
 	// Insert test boxes into the database using range
 	for _, testBox := range testBoxes() {
-		_, err := dbTest.insertNewBox(testBox)
+		_, err := dbTest.insertNewBox(testCtx, testBox)
 		if err != nil {
 			t.Fatalf("Failed to insert test box: %v", err)
 		}
 	}
 
 	// 1. Test successful move
-	err := dbTest.MoveBoxToBox(innerBox.ID, outerBox.ID)
+	err := dbTest.MoveBoxToBox(testCtx, innerBox.ID, outerBox.ID)
 	if err != nil {
 		t.Fatalf("MoveBox function returned an error: %v", err)
 	}
-	err = dbTest.MoveBoxToBox(innerBox2.ID, outerBox.ID)
+	err = dbTest.MoveBoxToBox(testCtx, innerBox2.ID, outerBox.ID)
 	assert.Equal(t, err, nil)
 
 	// inner box
-	updatedInnerBox, err := dbTest.BoxById(innerBox.ID)
+	updatedInnerBox, err := dbTest.BoxById(testCtx, innerBox.ID)
 	if err != nil {
 		t.Fatalf("Failed to retrieve updated inner box: %v", err)
 	}
 	assert.Equal(t, outerBox.ID, updatedInnerBox.OuterBoxID)
 	assert.Equal(t, outerBox.ID, updatedInnerBox.OuterBox.ID)
 	assert.Equal(t, outerBox.Label, updatedInnerBox.OuterBox.Label)
-	updatedInnerBox2, err := dbTest.BoxById(innerBox.ID)
+	updatedInnerBox2, err := dbTest.BoxById(testCtx, innerBox.ID)
 	assert.Equal(t, err, nil)
 	assert.Equal(t, outerBox.ID, updatedInnerBox2.OuterBoxID)
 	assert.Equal(t, outerBox.ID, updatedInnerBox2.OuterBox.ID)
 	assert.Equal(t, outerBox.Label, updatedInnerBox2.OuterBox.Label)
 
 	// outer box
-	updatedOuterBox, err := dbTest.BoxById(outerBox.ID)
+	updatedOuterBox, err := dbTest.BoxById(testCtx, outerBox.ID)
 	assert.Equal(t, err, nil)
 	assert.Equal(t, len(updatedOuterBox.InnerBoxes), 2)
 	assert.Equal(t, updatedOuterBox.InnerBoxes[0].ID, innerBox.ID)
@@ -434,18 +434,18 @@ func TestMoveBoxToBox(t *testing.T) {
 
 	// 2. Test move to non-existent box (should return an error)
 	nonExistentBoxId := uuid.Must(uuid.FromString("123e4567-e89b-12d3-a456-426614174003"))
-	err = dbTest.MoveBoxToBox(innerBox.ID, nonExistentBoxId)
+	err = dbTest.MoveBoxToBox(testCtx, innerBox.ID, nonExistentBoxId)
 	assert.Equal(t, err, err)
 
 	// Move innerbox out of outerbox
-	err = dbTest.MoveBoxToBox(innerBox.ID, uuid.Nil)
+	err = dbTest.MoveBoxToBox(testCtx, innerBox.ID, uuid.Nil)
 	assert.Equal(t, err, nil)
-	updatedInnerBox, err = dbTest.BoxById(innerBox.ID)
+	updatedInnerBox, err = dbTest.BoxById(testCtx, innerBox.ID)
 	assert.Equal(t, updatedInnerBox.OuterBoxID, uuid.Nil)
 	assert.Equal(t, updatedInnerBox.OuterBox, nil)
 
 	// Move to itself
-	err = dbTest.MoveBoxToBox(innerBox.ID, innerBox.ID)
+	err = dbTest.MoveBoxToBox(testCtx, innerBox.ID, innerBox.ID)
 	assert.NotEqual(t, err, nil)
 
 	// Inner and outerbox can't be inside eachother at the same time
@@ -455,13 +455,13 @@ func TestMoveBoxToBox(t *testing.T) {
 	innerBox = BOX_1
 	outerBox = BOX_2
 	for _, testBox := range testBoxes() {
-		dbTest.insertNewBox(testBox)
+		dbTest.insertNewBox(testCtx, testBox)
 	}
-	err = dbTest.MoveBoxToBox(innerBox.ID, outerBox.ID)
-	err = dbTest.MoveBoxToBox(outerBox.ID, innerBox.ID)
+	err = dbTest.MoveBoxToBox(testCtx, innerBox.ID, outerBox.ID)
+	err = dbTest.MoveBoxToBox(testCtx, outerBox.ID, innerBox.ID)
 	assert.NotEqual(t, err, nil)
-	updatedInnerBox, err = dbTest.BoxById(innerBox.ID)
-	updatedOuterBox, err = dbTest.BoxById(outerBox.ID)
+	updatedInnerBox, err = dbTest.BoxById(testCtx, innerBox.ID)
+	updatedOuterBox, err = dbTest.BoxById(testCtx, outerBox.ID)
 	assert.NotEqual(t, updatedOuterBox.OuterBoxID, updatedInnerBox.ID)
 }
 
@@ -469,32 +469,32 @@ func TestMoveBoxToShelf(t *testing.T) {
 	EmptyTestDatabase()
 	resetTestBoxes()
 	resetShelves()
-	dbTest.CreateBox(BOX_1)
-	dbTest.CreateShelf(SHELF_1)
-	fetchedBox, _ := dbTest.BoxById(BOX_1.ID)
-	fetchedShelf, _ := dbTest.Shelf(SHELF_1.ID)
+	dbTest.CreateBox(testCtx, BOX_1)
+	dbTest.CreateShelf(testCtx, SHELF_1)
+	fetchedBox, _ := dbTest.BoxById(testCtx, BOX_1.ID)
+	fetchedShelf, _ := dbTest.Shelf(testCtx, SHELF_1.ID)
 	assert.Equal(t, fetchedBox.ShelfID, uuid.Nil)
 	assert.Equal(t, fetchedShelf.Boxes, nil)
 
 	// Move in
-	err := dbTest.MoveBoxToShelf(BOX_1.ID, SHELF_1.ID)
+	err := dbTest.MoveBoxToShelf(testCtx, BOX_1.ID, SHELF_1.ID)
 	assert.Equal(t, err, nil)
-	fetchedBox, _ = dbTest.BoxById(BOX_1.ID)
-	fetchedShelf, _ = dbTest.Shelf(SHELF_1.ID)
+	fetchedBox, _ = dbTest.BoxById(testCtx, BOX_1.ID)
+	fetchedShelf, _ = dbTest.Shelf(testCtx, SHELF_1.ID)
 	assert.Equal(t, fetchedBox.ShelfID, SHELF_1.ID)
 	assert.NotEqual(t, fetchedShelf.Boxes, nil)
 	assert.Equal(t, fetchedShelf.Boxes[0].ID, BOX_1.ID)
 
 	// Move out
-	err = dbTest.MoveBoxToShelf(BOX_1.ID, uuid.Nil)
+	err = dbTest.MoveBoxToShelf(testCtx, BOX_1.ID, uuid.Nil)
 	assert.Equal(t, err, nil)
-	fetchedBox, _ = dbTest.BoxById(BOX_1.ID)
-	fetchedShelf, _ = dbTest.Shelf(SHELF_1.ID)
+	fetchedBox, _ = dbTest.BoxById(testCtx, BOX_1.ID)
+	fetchedShelf, _ = dbTest.Shelf(testCtx, SHELF_1.ID)
 	assert.Equal(t, fetchedBox.ShelfID, uuid.Nil)
 	assert.Equal(t, fetchedShelf.Boxes, nil)
 
 	// Move non existent ID
-	err = dbTest.MoveBoxToShelf(BOX_1.ID, VALID_UUID_NOT_EXISTING)
+	err = dbTest.MoveBoxToShelf(testCtx, BOX_1.ID, VALID_UUID_NOT_EXISTING)
 	assert.NotEqual(t, err, nil)
 }
 
@@ -502,31 +502,31 @@ func TestMoveBoxToArea(t *testing.T) {
 	EmptyTestDatabase()
 	resetTestBoxes()
 	resetShelves()
-	dbTest.CreateBox(BOX_1)
-	dbTest.CreateArea(*AREA_1)
-	fetchedBox, _ := dbTest.BoxById(BOX_1.ID)
+	dbTest.CreateBox(testCtx, BOX_1)
+	dbTest.CreateArea(testCtx, *AREA_1)
+	fetchedBox, _ := dbTest.BoxById(testCtx, BOX_1.ID)
 	// fetchedArea, _ := dbTest.AreaById(AREA_1.ID)
 	assert.Equal(t, fetchedBox.AreaID, uuid.Nil)
 	// assert.Equal(t, fetchedArea.Boxes, nil)
 
 	// Move in
-	err := dbTest.MoveBoxToArea(BOX_1.ID, AREA_1.ID)
+	err := dbTest.MoveBoxToArea(testCtx, BOX_1.ID, AREA_1.ID)
 	assert.Equal(t, err, nil)
-	fetchedBox, _ = dbTest.BoxById(BOX_1.ID)
+	fetchedBox, _ = dbTest.BoxById(testCtx, BOX_1.ID)
 	// fetchedArea, _ = dbTest.Area(*AREA_1.ID)
 	assert.Equal(t, fetchedBox.AreaID, AREA_1.ID)
 	// assert.NotEqual(t, fetchedArea.Boxes, nil)
 	// assert.Equal(t, fetchedArea.Boxes[0].ID, BOX_1.ID)
 
 	// Move out
-	err = dbTest.MoveBoxToArea(BOX_1.ID, uuid.Nil)
+	err = dbTest.MoveBoxToArea(testCtx, BOX_1.ID, uuid.Nil)
 	assert.Equal(t, err, nil)
-	fetchedBox, _ = dbTest.BoxById(BOX_1.ID)
+	fetchedBox, _ = dbTest.BoxById(testCtx, BOX_1.ID)
 	// fetchedArea, _ = dbTest.Area(*AREA_1.ID)
 	assert.Equal(t, fetchedBox.AreaID, uuid.Nil)
 	// assert.Equal(t, fetchedArea.Boxes, nil)
 
 	// Move non existent ID
-	err = dbTest.MoveBoxToArea(BOX_1.ID, VALID_UUID_NOT_EXISTING)
+	err = dbTest.MoveBoxToArea(testCtx, BOX_1.ID, VALID_UUID_NOT_EXISTING)
 	assert.NotEqual(t, err, nil)
 }
@@ -0,0 +1,261 @@
+package database
+
+import (
+	"basement/main/internal/categories"
+	"basement/main/internal/common"
+	"basement/main/internal/households"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/gofrs/uuid/v5"
+)
+
+// createTestCategory creates a category below parent, uuid.Nil for a root category, and returns its id.
+func createTestCategory(t *testing.T, ctx context.Context, parent uuid.UUID, name string, unit string) uuid.UUID {
+	id, err := dbTest.CreateCategory(ctx, categories.Category{ParentID: parent, Name: name, Unit: unit})
+	assert.Equal(t, err, nil)
+	return id
+}
+
+func TestCategoryTree(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+
+	tools := createTestCategory(t, testCtx, uuid.Nil, "Tools", "pcs")
+	power := createTestCategory(t, testCtx, tools, "Power Tools", "")
+	drills := createTestCategory(t, testCtx, power, "Drills", "")
+	cables := createTestCategory(t, testCtx, uuid.Nil, "Cables", "m")
+
+	item1 := *ITEM_1
+	item1.CategoryID = drills
+	item2 := *ITEM_2
+	item2.CategoryID = tools
+	item3 := *ITEM_3
+	item3.CategoryID = cables
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item1), nil)
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item2), nil)
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item3), nil)
+
+	list, err := dbTest.Categories(testCtx)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(list), 4)
+	assert.Equal(t, list[0].ID, cables)
+	assert.Equal(t, list[1].ID, tools)
+	assert.Equal(t, list[2].ID, power)
+	assert.Equal(t, list[3].PathLabel(), "Tools > Power Tools > Drills")
+	assert.Equal(t, list[3].Depth(), 2)
+	assert.Equal(t, list[3].InheritedUnit, "pcs")
+
+	// counts include the items of subcategories
+	assert.Equal(t, list[1].ItemCount, 2)
+	assert.Equal(t, list[1].TotalQuantity, item1.Quantity+item2.Quantity)
+	assert.Equal(t, list[2].ItemCount, 1)
+	assert.Equal(t, list[0].TotalQuantity, item3.Quantity)
+
+	saved, err := dbTest.ItemById(testCtx, item1.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, saved.CategoryID, drills)
+	assert.Equal(t, saved.CategoryPath, "Tools > Power Tools > Drills")
+	assert.Equal(t, saved.Unit, "pcs")
+
+	// deleted items are not counted
+	assert.Equal(t, dbTest.DeleteItem(testCtx, item2.ID), nil)
+	tool, err := dbTest.Category(testCtx, tools)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, tool.ItemCount, 1)
+}
+
+func TestCategorySearchFilter(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+
+	tools := createTestCategory(t, testCtx, uuid.Nil, "Tools", "")
+	power := createTestCategory(t, testCtx, tools, "Power Tools", "")
+	drills := createTestCategory(t, testCtx, power, "Drills", "")
+
+	item1 := *ITEM_1
+	item1.CategoryID = drills
+	item2 := *ITEM_2
+	item2.CategoryID = tools
+	item3 := *ITEM_3
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item1), nil)
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item2), nil)
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item3), nil)
+
+	testCases := []struct {
+		search   string
+		expected int
+	}{
+		{"category:tools", 2},
+		{`category:"power tools"`, 1},
+		{"cat:DRILLS", 1},
+		{"category:power", 0},
+		{"-category:tools", 1},
+		{"category:garden", 0},
+	}
+	for _, tc := range testCases {
+		count, err := dbTest.ItemListCounter(testCtx, tc.search)
+		assert.Equal(t, err, nil)
+		if count != tc.expected {
+			t.Errorf("%q matched %d items, expected %d", tc.search, count, tc.expected)
+		}
+	}
+
+	// only items have a category
+	count, err := dbTest.BoxListCounter(testCtx, "category:tools")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, 0)
+}
+
+func TestCategoryDefaults(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+
+	voltage := createTestField(t, testCtx, "item", "Voltage", "number")
+	brand := createTestField(t, testCtx, "item", "Brand", "text")
+	tools := createTestCategory(t, testCtx, uuid.Nil, "Tools", "")
+	drills := createTestCategory(t, testCtx, tools, "Drills", "")
+
+	err := dbTest.SetCategoryDefaults(testCtx, tools, []common.CustomFieldValue{fieldValue(voltage, "230"), fieldValue(brand, "Acme")})
+	assert.Equal(t, err, nil)
+	err = dbTest.SetCategoryDefaults(testCtx, drills, []common.CustomFieldValue{fieldValue(voltage, "18")})
+	assert.Equal(t, err, nil)
+
+	defaults, err := dbTest.CategoryDefaults(testCtx, drills)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(defaults), 2)
+	assert.Equal(t, defaults[0].Value, "18")
+	assert.Equal(t, defaults[1].Value, "")
+
+	// the closest category wins, submitted values are kept
+	item1 := *ITEM_1
+	item1.CategoryID = drills
+	item2 := *ITEM_2
+	item2.CategoryID = drills
+	item2.CustomFields = []common.CustomFieldValue{fieldValue(voltage, ""), fieldValue(brand, "Other")}
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item1), nil)
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item2), nil)
+
+	saved, err := dbTest.ItemById(testCtx, item1.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, saved.CustomFields[0].Value, "18")
+	assert.Equal(t, saved.CustomFields[1].Value, "Acme")
+	saved, err = dbTest.ItemById(testCtx, item2.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, saved.CustomFields[0].Value, "18")
+	assert.Equal(t, saved.CustomFields[1].Value, "Other")
+
+	// updates don't apply defaults
+	item1.CustomFields = []common.CustomFieldValue{fieldValue(voltage, ""), fieldValue(brand, "")}
+	assert.Equal(t, dbTest.UpdateItem(testCtx, item1, true, ""), nil)
+	saved, err = dbTest.ItemById(testCtx, item1.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, saved.CustomFields[0].Value, "")
+
+	// deleting a field removes its defaults
+	assert.Equal(t, dbTest.DeleteCustomField(testCtx, voltage.ID), nil)
+	var count int
+	err = dbTest.Sql.QueryRow(`SELECT COUNT(*) FROM category_default;`).Scan(&count)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, 1)
+}
+
+func TestUpdateCategory(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+
+	tools := createTestCategory(t, testCtx, uuid.Nil, "Tools", "")
+	power := createTestCategory(t, testCtx, tools, "Power Tools", "")
+	drills := createTestCategory(t, testCtx, power, "Drills", "")
+	createTestCategory(t, testCtx, tools, "Hand Tools", "")
+
+	_, err := dbTest.CreateCategory(testCtx, categories.Category{ParentID: tools, Name: "power tools"})
+	assert.Equal(t, errors.Is(err, categories.ErrCategoryExists), true)
+	// other parents can have a category with the same name
+	createTestCategory(t, testCtx, uuid.Nil, "Power Tools", "")
+
+	err = dbTest.UpdateCategory(testCtx, categories.Category{ID: tools, ParentID: drills, Name: "Tools"})
+	assert.Equal(t, errors.Is(err, categories.ErrCategoryCycle), true)
+	err = dbTest.UpdateCategory(testCtx, categories.Category{ID: tools, ParentID: tools, Name: "Tools"})
+	assert.Equal(t, errors.Is(err, categories.ErrCategoryCycle), true)
+	err = dbTest.UpdateCategory(testCtx, categories.Category{ID: power, ParentID: tools, Name: "Hand tools"})
+	assert.Equal(t, errors.Is(err, categories.ErrCategoryExists), true)
+
+	err = dbTest.UpdateCategory(testCtx, categories.Category{ID: drills, ParentID: tools, Name: "Drills", Unit: "pcs"})
+	assert.Equal(t, err, nil)
+	drill, err := dbTest.Category(testCtx, drills)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, drill.PathLabel(), "Tools > Drills")
+	assert.Equal(t, drill.Unit, "pcs")
+}
+
+func TestDeleteCategory(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+
+	voltage := createTestField(t, testCtx, "item", "Voltage", "number")
+	tools := createTestCategory(t, testCtx, uuid.Nil, "Tools", "")
+	power := createTestCategory(t, testCtx, tools, "Power Tools", "")
+	drills := createTestCategory(t, testCtx, power, "Drills", "")
+	assert.Equal(t, dbTest.SetCategoryDefaults(testCtx, power, []common.CustomFieldValue{fieldValue(voltage, "230")}), nil)
+
+	item1 := *ITEM_1
+	item1.CategoryID = power
+	item2 := *ITEM_2
+	item2.CategoryID = tools
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item1), nil)
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item2), nil)
+
+	// items and subcategories move to the parent
+	assert.Equal(t, dbTest.DeleteCategory(testCtx, power), nil)
+	saved, err := dbTest.ItemById(testCtx, item1.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, saved.CategoryID, tools)
+	drill, err := dbTest.Category(testCtx, drills)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, drill.PathLabel(), "Tools > Drills")
+
+	// items of root categories have no category anymore
+	assert.Equal(t, dbTest.DeleteCategory(testCtx, tools), nil)
+	saved, err = dbTest.ItemById(testCtx, item2.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, saved.CategoryID, uuid.Nil)
+	assert.Equal(t, saved.CategoryPath, "")
+
+	err = dbTest.DeleteCategory(testCtx, tools)
+	assert.Equal(t, errors.Is(err, ErrNotExist), true)
+
+	var count int
+	err = dbTest.Sql.QueryRow(`SELECT COUNT(*) FROM category_default;`).Scan(&count)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, 0)
+}
+
+func TestCategoriesOfOtherHousehold(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+	otherOwner := uuid.Must(uuid.FromString("923e4567-e89b-12d3-a456-426614174002"))
+	otherCtx := households.WithMembership(context.Background(), households.Membership{HouseholdID: otherOwner, UserID: otherOwner, Role: households.ROLE_OWNER})
+
+	mine := createTestCategory(t, testCtx, uuid.Nil, "Tools", "")
+	createTestCategory(t, otherCtx, uuid.Nil, "Tools", "")
+
+	list, err := dbTest.Categories(otherCtx)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(list), 1)
+
+	_, err = dbTest.Category(otherCtx, mine)
+	assert.Equal(t, errors.Is(err, ErrNotExist), true)
+	_, err = dbTest.CreateCategory(otherCtx, categories.Category{ParentID: mine, Name: "Drills"})
+	assert.Equal(t, errors.Is(err, ErrNotExist), true)
+	err = dbTest.DeleteCategory(otherCtx, mine)
+	assert.Equal(t, errors.Is(err, ErrNotExist), true)
+
+	// items can't be put into categories of another household
+	item := *ITEM_1
+	item.CategoryID = mine
+	err = dbTest.CreateNewItem(otherCtx, item)
+	assert.Equal(t, errors.Is(err, ErrNotExist), true)
+}
@@ -2,12 +2,14 @@ package database
 
 import (
 	"basement/main/internal/env"
+	"basement/main/internal/households"
 	"basement/main/internal/logg"
+	"context"
 	"fmt"
 	"os"
 	"testing"
 
-	_ "github.com/gofrs/uuid/v5"
+	"github.com/gofrs/uuid/v5"
 	_ "modernc.org/sqlite"
 )
 
@@ -15,13 +17,25 @@ const DATABASE_TEST_V1_FILE_PATH = "./internal/database/sqlite-database-test-v1.
 
 var dbTest = &DB{}
 
+// TEST_OWNER_ID owns all things created with testCtx.
+var TEST_OWNER_ID uuid.UUID = uuid.Must(uuid.FromString("923e4567-e89b-12d3-a456-426614174001"))
+
+// testCtx is passed to every owner scoped DB method in tests.
+var testCtx = households.WithMembership(context.Background(), households.Membership{HouseholdID: TEST_OWNER_ID, UserID: TEST_OWNER_ID, Role: households.ROLE_OWNER})
+
 func TestMain(m *testing.M) {
 	env.CurrentConfig().SetTest()
+	blobs, err := os.MkdirTemp("", "basement-blobs")
+	if err != nil {
+		logg.Fatal(err)
+	}
+	env.CurrentConfig().SetBlobPath(blobs)
 	setup()
 	defer teardown()
 
 	code := m.Run()
 
+	os.RemoveAll(blobs)
 	os.Exit(code)
 }
 
@@ -35,7 +49,7 @@ func setup() {
 	dbTest.createTable(*mainTables)
 	dbTest.createTable(*virtualTables)
 	dbTest.createTable(*triggers)
-
+	dbTest.mustMigrate()
 }
 
 func teardown() {
@@ -54,6 +68,13 @@ func EmptyTestDatabase() {
 			return
 		}
 	}
+	for _, tableName := range []string{"thing_tag", "tag", "custom_field_value", "category_default", "category", "custom_field", "stock_change", "shopping_entry", "loan", "picture"} {
+		_, err := dbTest.Sql.Exec("DELETE FROM " + tableName + ";")
+		if err != nil {
+			logg.Fatalf("Failed to delete from table %s: %s", tableName, err)
+			return
+		}
+	}
 	for tableName := range *virtualTables {
 		sqlStatement := fmt.Sprintf("DELETE FROM %s;", tableName)
 		logg.Debug(sqlStatement)
@@ -0,0 +1,240 @@
+package database
+
+import (
+	"basement/main/internal/common"
+	"basement/main/internal/fields"
+	"basement/main/internal/households"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/gofrs/uuid/v5"
+)
+
+// createTestField creates a custom field and returns it with its id.
+func createTestField(t *testing.T, ctx context.Context, thing string, name string, fieldType string, options ...string) common.CustomField {
+	field := common.CustomField{Thing: thing, Name: name, Type: fieldType, Options: options}
+	id, err := dbTest.CreateCustomField(ctx, field)
+	assert.Equal(t, err, nil)
+	field.ID = id
+	return field
+}
+
+func fieldValue(field common.CustomField, v string) common.CustomFieldValue {
+	return common.CustomFieldValue{CustomField: field, Value: v}
+}
+
+func TestSetCustomFields(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+
+	serial := createTestField(t, testCtx, "item", "Serial number", "text")
+	voltage := createTestField(t, testCtx, "item", "Voltage", "number")
+	createTestField(t, testCtx, "box", "Color", "enum", "red", "blue")
+
+	item := *ITEM_1
+	item.CustomFields = []common.CustomFieldValue{fieldValue(serial, "AB12"), fieldValue(voltage, "230")}
+	err := dbTest.CreateNewItem(testCtx, item)
+	assert.Equal(t, err, nil)
+
+	saved, err := dbTest.ItemById(testCtx, item.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(saved.CustomFields), 2)
+	assert.Equal(t, saved.CustomFields[0].Name, "Serial number")
+	assert.Equal(t, saved.CustomFields[0].Value, "AB12")
+	assert.Equal(t, saved.CustomFields[1].Value, "230")
+
+	// nil keeps the current values
+	item.CustomFields = nil
+	err = dbTest.UpdateItem(testCtx, item, true, "")
+	assert.Equal(t, err, nil)
+	saved, err = dbTest.ItemById(testCtx, item.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, saved.CustomFields[0].Value, "AB12")
+
+	// empty values are removed
+	item.CustomFields = []common.CustomFieldValue{fieldValue(serial, ""), fieldValue(voltage, "12")}
+	err = dbTest.UpdateItem(testCtx, item, true, "")
+	assert.Equal(t, err, nil)
+	saved, err = dbTest.ItemById(testCtx, item.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, saved.CustomFields[0].Value, "")
+	assert.Equal(t, saved.CustomFields[1].Value, "12")
+
+	var count int
+	err = dbTest.Sql.QueryRow(`SELECT COUNT(*) FROM custom_field_value;`).Scan(&count)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, 1)
+}
+
+func TestCustomFieldSearchFilter(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+
+	serial := createTestField(t, testCtx, "item", "Serial number", "text")
+	voltage := createTestField(t, testCtx, "item", "Voltage", "number")
+	bought := createTestField(t, testCtx, "item", "Bought", "date")
+	cordless := createTestField(t, testCtx, "item", "Cordless", "boolean")
+
+	item1 := *ITEM_1
+	item1.CustomFields = []common.CustomFieldValue{fieldValue(serial, "AB12"), fieldValue(voltage, "230"), fieldValue(bought, "2023-05-01"), fieldValue(cordless, "true")}
+	item2 := *ITEM_2
+	item2.CustomFields = []common.CustomFieldValue{fieldValue(serial, "CD34"), fieldValue(voltage, "12"), fieldValue(bought, "2024-02-01")}
+	item3 := *ITEM_3
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item1), nil)
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item2), nil)
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item3), nil)
+
+	testCases := []struct {
+		search   string
+		expected int
+	}{
+		{"serial_number:ab", 1},
+		{"SERIAL_NUMBER:34", 1},
+		{"serial_number:%", 0},
+		{"-serial_number:ab", 2},
+		{"voltage>=100", 1},
+		{"voltage<100", 1},
+		{"voltage=12", 1},
+		{"bought<2024-01-01", 1},
+		{"bought>=2023-01-01", 2},
+		{"cordless:yes", 1},
+		{"-cordless:yes", 2},
+		{"color:red", 0},
+	}
+	for _, tc := range testCases {
+		count, err := dbTest.ItemListCounter(testCtx, tc.search)
+		assert.Equal(t, err, nil)
+		if count != tc.expected {
+			t.Errorf("%q matched %d items, expected %d", tc.search, count, tc.expected)
+		}
+	}
+
+	// a field of items doesn't match boxes
+	count, err := dbTest.BoxListCounter(testCtx, "voltage>0")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, 0)
+}
+
+func TestCustomFieldSort(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+
+	voltage := createTestField(t, testCtx, "item", "Voltage", "number")
+	item1 := *ITEM_1
+	item1.CustomFields = []common.CustomFieldValue{fieldValue(voltage, "230")}
+	item2 := *ITEM_2
+	item2.CustomFields = []common.CustomFieldValue{fieldValue(voltage, "12")}
+	item3 := *ITEM_3
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item1), nil)
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item2), nil)
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item3), nil)
+
+	// numbers sort numerically, things without a value come last
+	rows, err := dbTest.ItemListRows(testCtx, "sort:voltage", 10, 1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(rows), 3)
+	assert.Equal(t, rows[0].ID, item2.ID)
+	assert.Equal(t, rows[1].ID, item1.ID)
+	assert.Equal(t, rows[2].ID, item3.ID)
+
+	rows, err = dbTest.ItemListRows(testCtx, "-sort:voltage", 10, 1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, rows[0].ID, item1.ID)
+	assert.Equal(t, rows[2].ID, item3.ID)
+
+	rows, err = dbTest.ItemListRows(testCtx, "-sort:qty", 10, 1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, rows[0].ID, item2.ID)
+
+	rows, err = dbTest.ItemListRows(testCtx, "item sort:label", 10, 1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, rows[0].Label, "Item 1")
+}
+
+func TestUpdateAndDeleteCustomField(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+
+	color := createTestField(t, testCtx, "item", "Color", "enum", "red", "blue")
+	_, err := dbTest.CreateCustomField(testCtx, common.CustomField{Thing: "item", Name: "color", Type: "text"})
+	assert.Equal(t, errors.Is(err, fields.ErrFieldExists), true)
+	// other things can have a field with the same name
+	createTestField(t, testCtx, "box", "Color", "text")
+
+	item := *ITEM_1
+	item.CustomFields = []common.CustomFieldValue{fieldValue(color, "red")}
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item), nil)
+
+	color.Name = "Paint"
+	color.Options = []string{"red", "green"}
+	color.Type = "number"
+	assert.Equal(t, dbTest.UpdateCustomField(testCtx, color), nil)
+	saved, err := dbTest.CustomField(testCtx, color.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, saved.Name, "Paint")
+	assert.Equal(t, saved.Type, "enum")
+	assert.Equal(t, saved.Options, []string{"red", "green"})
+
+	count, err := dbTest.ItemListCounter(testCtx, "paint:red")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, 1)
+
+	assert.Equal(t, dbTest.DeleteCustomField(testCtx, color.ID), nil)
+	savedItem, err := dbTest.ItemById(testCtx, item.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(savedItem.CustomFields), 0)
+	err = dbTest.DeleteCustomField(testCtx, color.ID)
+	assert.Equal(t, errors.Is(err, ErrNotExist), true)
+
+	var values int
+	err = dbTest.Sql.QueryRow(`SELECT COUNT(*) FROM custom_field_value;`).Scan(&values)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, values, 0)
+}
+
+func TestCustomFieldsOfOtherHousehold(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+	otherOwner := uuid.Must(uuid.FromString("923e4567-e89b-12d3-a456-426614174002"))
+	otherCtx := households.WithMembership(context.Background(), households.Membership{HouseholdID: otherOwner, UserID: otherOwner, Role: households.ROLE_OWNER})
+
+	mine := createTestField(t, testCtx, "item", "Voltage", "number")
+	other := createTestField(t, otherCtx, "item", "Voltage", "number")
+
+	list, err := dbTest.CustomFields(otherCtx, "item")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(list), 1)
+	assert.Equal(t, list[0].ID, other.ID)
+
+	_, err = dbTest.CustomField(otherCtx, mine.ID)
+	assert.Equal(t, errors.Is(err, ErrNotExist), true)
+	err = dbTest.DeleteCustomField(otherCtx, mine.ID)
+	assert.Equal(t, errors.Is(err, ErrNotExist), true)
+
+	// values of fields of another household are ignored
+	item := *ITEM_1
+	item.CustomFields = []common.CustomFieldValue{fieldValue(other, "230")}
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item), nil)
+	saved, err := dbTest.ItemById(testCtx, item.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, saved.CustomFields[0].Value, "")
+}
+
+func TestPurgeRemovesCustomFieldValues(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+
+	voltage := createTestField(t, testCtx, "item", "Voltage", "number")
+	item := *ITEM_1
+	item.CustomFields = []common.CustomFieldValue{fieldValue(voltage, "230")}
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item), nil)
+	assert.Equal(t, dbTest.DeleteItem(testCtx, item.ID), nil)
+	assert.Equal(t, dbTest.Purge(testCtx, "item", item.ID), nil)
+
+	var count int
+	err := dbTest.Sql.QueryRow(`SELECT COUNT(*) FROM custom_field_value;`).Scan(&count)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, 0)
+}
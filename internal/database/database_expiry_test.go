@@ -0,0 +1,99 @@
+package database
+
+import (
+	"basement/main/internal/expiry"
+	"basement/main/internal/households"
+	"basement/main/internal/validate"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/gofrs/uuid/v5"
+)
+
+// daysFromNow returns the date days from today as it is stored.
+func daysFromNow(days int) string {
+	return time.Now().AddDate(0, 0, days).Format(validate.DATE_LAYOUT)
+}
+
+func TestExpiryLists(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+
+	expired := *ITEM_1
+	expired.ExpiresAt = daysFromNow(-2)
+	expiringBestBefore := *ITEM_2
+	expiringBestBefore.BestBefore = daysFromNow(5)
+	expiringBestBefore.ExpiresAt = daysFromNow(30)
+	expiringToday := *ITEM_3
+	expiringToday.ExpiresAt = daysFromNow(0)
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, expired), nil)
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, expiringBestBefore), nil)
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, expiringToday), nil)
+
+	saved, err := dbTest.ItemById(testCtx, expiringBestBefore.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, saved.BestBefore, expiringBestBefore.BestBefore)
+	assert.Equal(t, saved.ExpiresAt, expiringBestBefore.ExpiresAt)
+
+	count, err := dbTest.ExpiryListCounter(testCtx, expiry.STATUS_EXPIRED, "", 7)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, 1)
+
+	// the earlier best before date counts
+	count, err = dbTest.ExpiryListCounter(testCtx, expiry.STATUS_EXPIRING, "", 7)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, 2)
+	count, err = dbTest.ExpiryListCounter(testCtx, expiry.STATUS_EXPIRING, "", 0)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, 1)
+	count, err = dbTest.ExpiryListCounter(testCtx, expiry.STATUS_EXPIRING, `label:"Item 2"`, 7)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, 1)
+
+	rows, err := dbTest.ExpiryListRows(testCtx, expiry.STATUS_EXPIRING, "", 7, 10, 1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(rows), 2)
+	assert.Equal(t, rows[0].ID, expiringToday.ID)
+	assert.Equal(t, string(rows[0].Snippet), "Expires on "+expiringToday.ExpiresAt)
+	assert.Equal(t, string(rows[1].Snippet), "Best before "+expiringBestBefore.BestBefore+", Expires on "+expiringBestBefore.ExpiresAt)
+
+	// items without dates or in the trash never expire
+	assert.Equal(t, dbTest.DeleteItem(testCtx, expired.ID), nil)
+	count, err = dbTest.ExpiryListCounter(testCtx, expiry.STATUS_EXPIRED, "", 7)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, 0)
+
+	_, err = dbTest.ExpiryListCounter(testCtx, "rotten", "", 7)
+	assert.NotEqual(t, err, nil)
+}
+
+func TestExpiryCounts(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+	otherOwner := uuid.Must(uuid.FromString("923e4567-e89b-12d3-a456-426614174002"))
+	otherCtx := households.WithMembership(context.Background(), households.Membership{HouseholdID: otherOwner, UserID: otherOwner, Role: households.ROLE_OWNER})
+
+	expired := *ITEM_1
+	expired.BestBefore = daysFromNow(-1)
+	expiring := *ITEM_2
+	expiring.ExpiresAt = daysFromNow(3)
+	later := *ITEM_3
+	later.ExpiresAt = daysFromNow(60)
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, expired), nil)
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, later), nil)
+	assert.Equal(t, dbTest.CreateNewItem(otherCtx, expiring), nil)
+
+	counts, err := dbTest.ExpiryCounts(time.Now(), 7)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(counts), 2)
+	assert.Equal(t, counts[TEST_OWNER_ID], expiry.Counts{Expired: 1})
+	assert.Equal(t, counts[otherOwner], expiry.Counts{Expiring: 1})
+
+	// a week later everything but the later item has expired
+	counts, err = dbTest.ExpiryCounts(time.Now().AddDate(0, 0, 7), 7)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, counts[TEST_OWNER_ID], expiry.Counts{Expired: 1})
+	assert.Equal(t, counts[otherOwner], expiry.Counts{Expired: 1})
+}
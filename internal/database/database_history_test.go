@@ -0,0 +1,143 @@
+package database
+
+import (
+	"basement/main/internal/history"
+	"basement/main/internal/households"
+	"context"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/gofrs/uuid/v5"
+)
+
+// historyTestCtx uses its own household so the history of other tests doesn't show up.
+func historyTestCtx() context.Context {
+	household := uuid.Must(uuid.NewV4())
+	return households.WithMembership(context.Background(), households.Membership{HouseholdID: household, UserID: TEST_OWNER_ID, Role: households.ROLE_OWNER})
+}
+
+func TestHistory(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+	resetTestBoxes()
+	ctx := historyTestCtx()
+
+	err := dbTest.CreateNewItem(ctx, *ITEM_1)
+	assert.Equal(t, err, nil)
+	_, err = dbTest.CreateBox(ctx, BOX_1)
+	assert.Equal(t, err, nil)
+
+	item := *ITEM_1
+	item.Label = "renamed item"
+	err = dbTest.UpdateItem(ctx, item, true, "")
+	assert.Equal(t, err, nil)
+
+	// Updating without changes writes no entry.
+	err = dbTest.UpdateItem(ctx, item, true, "")
+	assert.Equal(t, err, nil)
+
+	err = dbTest.MoveItemToBox(ctx, ITEM_1.ID, BOX_1.ID)
+	assert.Equal(t, err, nil)
+	err = dbTest.DeleteItem(ctx, ITEM_1.ID)
+	assert.Equal(t, err, nil)
+
+	entries, err := dbTest.History(ctx, "item", ITEM_1.ID, 10, 1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(entries), 4)
+
+	// latest change first
+	assert.Equal(t, entries[0].Action, history.ACTION_DELETE)
+	assert.Equal(t, entries[0].After == nil, true)
+	assert.Equal(t, entries[0].From.ID, BOX_1.ID)
+
+	assert.Equal(t, entries[1].Action, history.ACTION_MOVE)
+	assert.Equal(t, entries[1].From.Empty(), true)
+	assert.Equal(t, entries[1].To.ID, BOX_1.ID)
+	assert.Equal(t, entries[1].To.Label, BOX_1.Label)
+	assert.Equal(t, entries[1].Moved(), true)
+
+	assert.Equal(t, entries[2].Action, history.ACTION_UPDATE)
+	assert.Equal(t, entries[2].Label, "renamed item")
+	assert.Equal(t, entries[2].Changes(), []history.Change{{Field: "label", Before: ITEM_1.Label, After: "renamed item"}})
+
+	assert.Equal(t, entries[3].Action, history.ACTION_CREATE)
+	assert.Equal(t, entries[3].Before == nil, true)
+	assert.Equal(t, entries[3].ActorID, TEST_OWNER_ID)
+
+	activity, err := dbTest.Activity(ctx, 10, 1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(activity), 5)
+
+	// Other households don't see the history.
+	entries, err = dbTest.History(historyTestCtx(), "item", ITEM_1.ID, 10, 1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(entries), 0)
+}
+
+func TestHistoryIsAppendOnly(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+	ctx := historyTestCtx()
+
+	err := dbTest.CreateNewItem(ctx, *ITEM_1)
+	assert.Equal(t, err, nil)
+
+	_, err = dbTest.Sql.Exec("UPDATE history SET label = 'changed' WHERE thing_id = ?;", ITEM_1.ID.String())
+	assert.NotEqual(t, err, nil)
+	_, err = dbTest.Sql.Exec("DELETE FROM history WHERE thing_id = ?;", ITEM_1.ID.String())
+	assert.NotEqual(t, err, nil)
+}
+
+func TestHistoryOfTagsLoansAndPictures(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+	ctx := historyTestCtx()
+
+	item := *ITEM_1
+	item.Tags = []string{"garden"}
+	err := dbTest.CreateNewItem(ctx, item)
+	assert.Equal(t, err, nil)
+	entries, err := dbTest.History(ctx, "item", item.ID, 10, 1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, entries[0].After["tags"], "garden")
+
+	item.Tags = []string{"tools", "garden"}
+	err = dbTest.UpdateItem(ctx, item, true, "")
+	assert.Equal(t, err, nil)
+	loan, err := dbTest.Lend(ctx, "item", item.ID, "Alex", daysFromNow(7))
+	assert.Equal(t, err, nil)
+	_, err = dbTest.ReturnLoan(ctx, loan.ID)
+	assert.Equal(t, err, nil)
+	_, err = dbTest.AddPicture(ctx, "item", item.ID, VALID_BASE64_PNG, "image/png", "front")
+	assert.Equal(t, err, nil)
+
+	entries, err = dbTest.History(ctx, "item", item.ID, 10, 1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(entries), 5)
+	assert.Equal(t, entries[0].After["pictures"] != "", true)
+	assert.Equal(t, entries[1].Changes(), []history.Change{{Field: "lent_to", Before: "Alex until " + daysFromNow(7)}})
+	assert.Equal(t, entries[2].Changes(), []history.Change{{Field: "lent_to", After: "Alex until " + daysFromNow(7)}})
+	assert.Equal(t, entries[3].Changes(), []history.Change{{Field: "tags", Before: "garden", After: "garden, tools"}})
+}
+
+func TestHistoryIsWrittenWithTheChange(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+	ctx := historyTestCtx()
+
+	err := dbTest.CreateNewItem(ctx, *ITEM_1)
+	assert.Equal(t, err, nil)
+	_, err = dbTest.Sql.Exec(`CREATE TEMP TRIGGER history_fails BEFORE INSERT ON history WHEN NEW.label = 'no history'
+		BEGIN SELECT RAISE(ABORT, 'history fails'); END;`)
+	assert.Equal(t, err, nil)
+	defer dbTest.Sql.Exec(`DROP TRIGGER history_fails;`)
+
+	// A change that can't be recorded isn't made.
+	item := *ITEM_1
+	item.Label = "no history"
+	err = dbTest.UpdateItem(ctx, item, true, "")
+	assert.NotEqual(t, err, nil)
+	saved, err := dbTest.ItemById(ctx, ITEM_1.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, saved.Label, ITEM_1.Label)
+}
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"basement/main/internal/households"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/gofrs/uuid/v5"
+)
+
+func resetHouseholds(t *testing.T) {
+	_, err := dbTest.Sql.Exec("DELETE FROM household_member;")
+	assert.Equal(t, err, nil)
+	_, err = dbTest.Sql.Exec("DELETE FROM household;")
+	assert.Equal(t, err, nil)
+}
+
+func insertHouseholdTestUser(t *testing.T, id string, username string) uuid.UUID {
+	_, err := dbTest.Sql.Exec("INSERT OR IGNORE INTO user (id, username, passwordhash) VALUES (?, ?, ?)", id, username, "hash")
+	assert.Equal(t, err, nil)
+	return uuid.Must(uuid.FromString(id))
+}
+
+func TestPersonalHousehold(t *testing.T) {
+	resetHouseholds(t)
+	ctx := context.Background()
+	userID := insertHouseholdTestUser(t, "323e4567-e89b-12d3-a456-426614174001", "household-alice")
+
+	m, err := dbTest.Membership(ctx, userID, uuid.Nil)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, m.HouseholdID, userID)
+	assert.Equal(t, m.HouseholdName, PERSONAL_HOUSEHOLD_NAME)
+	assert.Equal(t, m.Role, households.ROLE_OWNER)
+
+	// Creating the personal household a second time doesn't fail.
+	m, err = dbTest.Membership(ctx, userID, userID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, m.HouseholdID, userID)
+
+	_, err = dbTest.Membership(ctx, userID, uuid.Must(uuid.NewV4()))
+	assert.Equal(t, errors.Is(err, households.ErrNotMember), true)
+}
+
+func TestHouseholdMembers(t *testing.T) {
+	resetHouseholds(t)
+	ctx := context.Background()
+	alice := insertHouseholdTestUser(t, "323e4567-e89b-12d3-a456-426614174001", "household-alice")
+	bob := insertHouseholdTestUser(t, "323e4567-e89b-12d3-a456-426614174002", "household-bob")
+
+	id, err := dbTest.CreateHousehold(ctx, alice, "Basement")
+	assert.Equal(t, err, nil)
+
+	err = dbTest.AddMember(ctx, id, "household-bob", households.ROLE_VIEWER)
+	assert.Equal(t, err, nil)
+	err = dbTest.AddMember(ctx, id, "household-bob", households.ROLE_EDITOR)
+	assert.Equal(t, errors.Is(err, ErrExist), true)
+	err = dbTest.AddMember(ctx, id, "household-nobody", households.ROLE_EDITOR)
+	assert.Equal(t, errors.Is(err, ErrNotExist), true)
+
+	m, err := dbTest.Membership(ctx, bob, id)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, m.HouseholdName, "Basement")
+	assert.Equal(t, m.Role, households.ROLE_VIEWER)
+
+	members, err := dbTest.Members(ctx, id)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(members), 2)
+	assert.Equal(t, members[0].Username, "household-alice")
+
+	memberships, err := dbTest.Households(ctx, bob)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(memberships), 1)
+
+	// The only owner can't leave.
+	err = dbTest.RemoveMember(ctx, id, alice)
+	assert.Equal(t, errors.Is(err, households.ErrLastOwner), true)
+
+	err = dbTest.RemoveMember(ctx, id, bob)
+	assert.Equal(t, err, nil)
+	_, err = dbTest.Membership(ctx, bob, id)
+	assert.Equal(t, errors.Is(err, households.ErrNotMember), true)
+}
+
+func TestHouseholdSharesThings(t *testing.T) {
+	EmptyTestDatabase()
+	resetHouseholds(t)
+	ctx := context.Background()
+	alice := insertHouseholdTestUser(t, "323e4567-e89b-12d3-a456-426614174001", "household-alice")
+	bob := insertHouseholdTestUser(t, "323e4567-e89b-12d3-a456-426614174002", "household-bob")
+
+	id, err := dbTest.CreateHousehold(ctx, alice, "Basement")
+	assert.Equal(t, err, nil)
+	err = dbTest.AddMember(ctx, id, "household-bob", households.ROLE_EDITOR)
+	assert.Equal(t, err, nil)
+
+	aliceMembership, err := dbTest.Membership(ctx, alice, id)
+	assert.Equal(t, err, nil)
+	bobMembership, err := dbTest.Membership(ctx, bob, id)
+	assert.Equal(t, err, nil)
+	bobPersonal, err := dbTest.Membership(ctx, bob, uuid.Nil)
+	assert.Equal(t, err, nil)
+
+	_, err = dbTest.CreateBox(households.WithMembership(ctx, aliceMembership), BOX_1)
+	assert.Equal(t, err, nil)
+
+	exists, err := dbTest.Exists(households.WithMembership(ctx, bobMembership), "box", BOX_1.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, exists, true)
+
+	exists, err = dbTest.Exists(households.WithMembership(ctx, bobPersonal), "box", BOX_1.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, exists, false)
+}
@@ -0,0 +1,135 @@
+package database
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+// A lossless WebP picture of 48 x 64 pixels.
+const VALID_BASE64_WEBP = "UklGRrIBAABXRUJQVlA4TKUBAAAvSsAYAA8w//M///MfeJAkbXvaSG7m8Q3GfYSBJekwQztm/IcZlgwnmWImn2BK7aFmBtnVir6q//8VOkFE/xm4baTIu8c48ArEo6+B3zFKYln3pqClSCKX0begFTAXFOLXHSyF8cCNcZEG4OywuA4KVVfJCiArU7GAgJI8+lJP/OKMT/fBAjevg1cYB7YVkFuWga2lyPi5I0HFy5YTpWIHg0RZpkniRVW9odHAKOwosWuOGdxIyn2OvaCDvhg/we6TwadPBPbqBV58MsLmMJ8yZnOWk8SRz4N+QoyPL+MnamzMvcE1rHNEr91F9GKZPVUcS9w7PhhH36suB9qPeYb/oLk6cuTiJ0wOK3m5h1cKjW6EVZCYMK7dxcKCBdgP9HkKr9gkAO2P8GKZGWVdIAatQa+1IDpt6qyorVwdy01xdW8Jkfk6xjEXmVQQ+HQdFr6OKhIN34dXWq0+0qr6EJSCeeVLH9+gvGTLyqM65PQ44ihzlTXxQKjKbAvshXgir7Lil9w4L2bvMycmjQcqXaMCO6BlY28i+FOLzbfI1vEqxAhotocAAA=="
+
+// halves returns a picture with a red left half and a blue right half.
+func halves(width int, height int) *image.RGBA {
+	pic := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.RGBA{B: 255, A: 255}
+			if x < width/2 {
+				c = color.RGBA{R: 255, A: 255}
+			}
+			pic.Set(x, y, c)
+		}
+	}
+	return pic
+}
+
+// withOrientation returns the JPEG with an EXIF segment holding the orientation.
+func withOrientation(t *testing.T, jpg []byte, orientation int) []byte {
+	segment := append(bytes.Clone(exifHeader), orientationTIFF(orientation)...)
+
+	out := []byte{0xFF, 0xD8, 0xFF, 0xE1}
+	out = binary.BigEndian.AppendUint16(out, uint16(len(segment)+2))
+	out = append(out, segment...)
+	assert.Equal(t, bytes.HasPrefix(jpg, []byte{0xFF, 0xD8}), true)
+	return append(out, jpg[2:]...)
+}
+
+func resized(t *testing.T, pic []byte, side int) (image.Image, string) {
+	var out bytes.Buffer
+	err := ResizePicture(bytes.NewReader(pic), &out, side)
+	assert.Equal(t, err, nil)
+	img, format, err := image.Decode(&out)
+	assert.Equal(t, err, nil)
+	return img, format
+}
+
+func TestResizePicture(t *testing.T) {
+	var pngPic, jpegPic, gifPic bytes.Buffer
+	assert.Equal(t, png.Encode(&pngPic, halves(200, 100)), nil)
+	assert.Equal(t, jpeg.Encode(&jpegPic, halves(200, 100), nil), nil)
+	assert.Equal(t, gif.Encode(&gifPic, halves(200, 100), nil), nil)
+	webpPic, err := Base64StringToByte(VALID_BASE64_WEBP)
+	assert.Equal(t, err, nil)
+
+	img, format := resized(t, pngPic.Bytes(), 50)
+	assert.Equal(t, img.Bounds().Size(), image.Pt(50, 25))
+	assert.Equal(t, format, "png")
+
+	img, format = resized(t, jpegPic.Bytes(), 50)
+	assert.Equal(t, img.Bounds().Size(), image.Pt(50, 25))
+	assert.Equal(t, format, "jpeg")
+
+	img, format = resized(t, gifPic.Bytes(), 50)
+	assert.Equal(t, img.Bounds().Size(), image.Pt(50, 25))
+	assert.Equal(t, format, "jpeg")
+
+	img, _ = resized(t, webpPic, 32)
+	assert.Equal(t, img.Bounds().Size(), image.Pt(24, 32))
+
+	// smaller pictures aren't scaled up
+	img, _ = resized(t, pngPic.Bytes(), 400)
+	assert.Equal(t, img.Bounds().Size(), image.Pt(200, 100))
+
+	err = ResizePicture(bytes.NewReader([]byte("not a picture")), &bytes.Buffer{}, 50)
+	assert.Equal(t, errors.Is(err, UnsupportedImageFormat), true)
+}
+
+// withSize returns the PNG with the width and height in its header changed, without changing its pixels.
+func withSize(pic []byte, width int, height int) []byte {
+	// The IHDR chunk follows the signature, its data starts with width and height and ends 13 bytes later with a checksum.
+	out := bytes.Clone(pic)
+	binary.BigEndian.PutUint32(out[16:], uint32(width))
+	binary.BigEndian.PutUint32(out[20:], uint32(height))
+	binary.BigEndian.PutUint32(out[29:], crc32.ChecksumIEEE(out[12:29]))
+	return out
+}
+
+func TestResizePictureTooManyPixels(t *testing.T) {
+	var pngPic bytes.Buffer
+	assert.Equal(t, png.Encode(&pngPic, halves(2, 2)), nil)
+	huge := withSize(pngPic.Bytes(), 60000, 60000)
+	config, _, err := image.DecodeConfig(bytes.NewReader(huge))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, config.Width, 60000)
+
+	err = ResizePicture(bytes.NewReader(huge), &bytes.Buffer{}, 50)
+	assert.Equal(t, errors.Is(err, ErrPictureTooLarge), true)
+	_, err = ResizeImage(ByteToBase64String(huge), 50, "image/png")
+	assert.Equal(t, errors.Is(err, ErrPictureTooLarge), true)
+}
+
+func TestResizePictureAppliesOrientation(t *testing.T) {
+	var jpegPic bytes.Buffer
+	assert.Equal(t, jpeg.Encode(&jpegPic, halves(80, 40), nil), nil)
+	assert.Equal(t, exifOrientation(jpegPic.Bytes()), 0)
+	rotated := withOrientation(t, jpegPic.Bytes(), 6)
+	assert.Equal(t, exifOrientation(rotated), 6)
+
+	// turned clockwise the left half is on top
+	img, _ := resized(t, rotated, 80)
+	assert.Equal(t, img.Bounds().Size(), image.Pt(40, 80))
+	r, _, b, _ := img.At(20, 10).RGBA()
+	assert.Equal(t, r > b, true)
+	r, _, b, _ = img.At(20, 70).RGBA()
+	assert.Equal(t, b > r, true)
+}
+
+func TestResizeImageFormats(t *testing.T) {
+	_, err := ResizeImage(VALID_BASE64_WEBP, 50, "image/webp")
+	assert.Equal(t, err, nil)
+	preview, err := ResizeImage(VALID_BASE64_PNG, 50, "")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, preview, "")
+	_, err = ResizeImage(VALID_BASE64_PNG, 50, "image/tiff")
+	assert.Equal(t, errors.Is(err, UnsupportedImageFormat), true)
+}
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"basement/main/internal/insurance"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestInsuranceReport(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+	resetTestBoxes()
+
+	_, err := dbTest.CreateBox(testCtx, BOX_1)
+	assert.Equal(t, err, nil)
+	_, err = dbTest.CreateBox(testCtx, BOX_2)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, dbTest.MoveBoxToBox(testCtx, BOX_2.ID, BOX_1.ID), nil)
+
+	drill := *ITEM_1
+	drill.Quantity = 2
+	drill.Price = 79.99
+	drill.Currency = "EUR"
+	drill.PurchaseDate = "2024-03-01"
+	drill.Vendor = "Hardware Store"
+	drill.SerialNumber = "SN-42"
+	drill.WarrantyUntil = "2026-03-01"
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, drill), nil)
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, *ITEM_2), nil)
+	assert.Equal(t, dbTest.MoveItemToBox(testCtx, drill.ID, BOX_2.ID), nil)
+
+	saved, err := dbTest.ItemById(testCtx, drill.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, saved.Price, drill.Price)
+	assert.Equal(t, saved.Currency, drill.Currency)
+	assert.Equal(t, saved.PurchaseDate, drill.PurchaseDate)
+	assert.Equal(t, saved.Vendor, drill.Vendor)
+	assert.Equal(t, saved.SerialNumber, drill.SerialNumber)
+	assert.Equal(t, saved.WarrantyUntil, drill.WarrantyUntil)
+
+	// items without a price are left out
+	items, err := dbTest.InsuranceItems(testCtx)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(items), 1)
+	assert.Equal(t, items[0].BoxID, BOX_2.ID)
+	places, err := dbTest.InsurancePlaces(testCtx)
+	assert.Equal(t, err, nil)
+
+	report := insurance.NewReport(items, places)
+	assert.Equal(t, report.Totals, []insurance.Total{{Currency: "EUR", Value: 159.98}})
+	assert.Equal(t, len(report.Places), 2)
+	assert.Equal(t, report.Places[0].ID, BOX_1.ID)
+	assert.Equal(t, report.Places[1].Path, BOX_1.Label+" > "+BOX_2.Label)
+
+	drill.Price = 0
+	assert.Equal(t, dbTest.UpdateItem(testCtx, drill, true, ""), nil)
+	items, err = dbTest.InsuranceItems(testCtx)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(items), 0)
+}
@@ -15,10 +15,10 @@ func TestInsertNewItem(t *testing.T) {
 
 	item := ITEM_1
 
-	err := dbTest.insertNewItem(*item)
+	err := dbTest.insertNewItem(testCtx, *item)
 	assert.Equal(t, err, nil)
 
-	retrievedItem, err := dbTest.ItemById(item.ID)
+	retrievedItem, err := dbTest.ItemById(testCtx, item.ID)
 	assert.Equal(t, err, nil)
 
 	assert.Equal(t, item.ID, retrievedItem.ID)
@@ -30,11 +30,20 @@ func TestInsertNewItem(t *testing.T) {
 
 	// ListRow
 	var retrievedItemRow *common.ListRow
-	retrievedItemRow, err = dbTest.ItemListRowByID(item.ID)
+	retrievedItemRow, err = dbTest.ItemListRowByID(testCtx, item.ID)
 	assert.Equal(t, err, nil)
 
 	assert.Equal(t, item.ID, retrievedItemRow.ID)
 	assert.Equal(t, item.Label, retrievedItemRow.Label)
+
+	// a picture that can't be stored fails the insert
+	broken := *ITEM_2
+	broken.Picture = INVALID_BASE64_PNG
+	err = dbTest.insertNewItem(testCtx, broken)
+	assert.NotEqual(t, err, nil)
+	exists, err := dbTest.Exists(testCtx, "item", broken.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, exists, false)
 }
 
 func TestUpdateItem(t *testing.T) {
@@ -44,15 +53,15 @@ func TestUpdateItem(t *testing.T) {
 
 	item := ITEM_1
 
-	err := dbTest.insertNewItem(*item)
+	err := dbTest.insertNewItem(testCtx, *item)
 	assert.Equal(t, err, nil)
 
 	item.Label = "Updated Item Label"
 	item.Description = "Updated Description"
 
-	err = dbTest.UpdateItem(*item, true, "image/png")
+	err = dbTest.UpdateItem(testCtx, *item, true, "image/png")
 	assert.Equal(t, err, nil)
-	retrievedItem, err := dbTest.ItemById(item.ID)
+	retrievedItem, err := dbTest.ItemById(testCtx, item.ID)
 	assert.Equal(t, err, nil)
 
 	assert.Equal(t, item.Label, retrievedItem.Label)
@@ -60,7 +69,7 @@ func TestUpdateItem(t *testing.T) {
 
 	// ListRow
 	var retrievedItemRow *common.ListRow
-	retrievedItemRow, err = dbTest.ItemListRowByID(item.ID)
+	retrievedItemRow, err = dbTest.ItemListRowByID(testCtx, item.ID)
 	assert.Equal(t, err, nil)
 
 	assert.Equal(t, item.ID, retrievedItemRow.ID)
@@ -74,13 +83,13 @@ func TestDeleteItem(t *testing.T) {
 
 	item := ITEM_1
 
-	err := dbTest.insertNewItem(*item)
+	err := dbTest.insertNewItem(testCtx, *item)
 	assert.Equal(t, err, nil)
 
-	err = dbTest.DeleteItem(item.ID)
+	err = dbTest.DeleteItem(testCtx, item.ID)
 	assert.Equal(t, err, nil)
 
-	_, err = dbTest.ItemById(item.ID)
+	_, err = dbTest.ItemById(testCtx, item.ID)
 	assert.NotEqual(t, nil, err)
 }
 
@@ -91,13 +100,13 @@ func TestItemExist(t *testing.T) {
 
 	item := ITEM_1
 
-	exists := dbTest.ItemExist("id", item.ID.String())
+	exists := dbTest.ItemExist(testCtx, "id", item.ID.String())
 	assert.Equal(t, false, exists)
 
-	err := dbTest.insertNewItem(*item)
+	err := dbTest.insertNewItem(testCtx, *item)
 	assert.Equal(t, err, nil)
 
-	exists = dbTest.ItemExist("id", item.ID.String())
+	exists = dbTest.ItemExist(testCtx, "id", item.ID.String())
 	assert.Equal(t, true, exists)
 }
 
@@ -109,11 +118,11 @@ func TestItemIDs(t *testing.T) {
 
 	items := testItems()
 	for _, item := range items {
-		err := dbTest.insertNewItem(item)
+		err := dbTest.insertNewItem(testCtx, item)
 		assert.Equal(t, err, nil)
 	}
 
-	ids, err := dbTest.ItemIDs()
+	ids, err := dbTest.ItemIDs(testCtx)
 	assert.Equal(t, err, nil)
 	assert.Equal(t, len(items), len(ids))
 
@@ -134,16 +143,16 @@ func TestDeleteItems(t *testing.T) {
 	items := testItems()
 	var itemIDs []uuid.UUID
 	for _, item := range items {
-		err := dbTest.insertNewItem(item)
+		err := dbTest.insertNewItem(testCtx, item)
 		assert.Equal(t, err, nil)
 		itemIDs = append(itemIDs, item.ID)
 	}
 
-	err := dbTest.DeleteItems(itemIDs)
+	err := dbTest.DeleteItems(testCtx, itemIDs)
 	assert.Equal(t, err, nil)
 
 	for _, id := range itemIDs {
-		_, err := dbTest.ItemById(id)
+		_, err := dbTest.ItemById(testCtx, id)
 		assert.NotEqual(t, nil, err)
 	}
 }
@@ -157,19 +166,19 @@ func TestMoveItemToBox(t *testing.T) {
 	box2 := BOX_2
 	item := ITEM_1
 
-	_, err := dbTest.insertNewBox(box1)
+	_, err := dbTest.insertNewBox(testCtx, box1)
 	assert.Equal(t, err, nil)
-	_, err = dbTest.insertNewBox(box2)
+	_, err = dbTest.insertNewBox(testCtx, box2)
 	assert.Equal(t, err, nil)
-	err = dbTest.insertNewItem(*item)
+	err = dbTest.insertNewItem(testCtx, *item)
 	assert.Equal(t, err, nil)
 
 	// Move item in box2
-	err = dbTest.MoveItemToBox(item.ID, box2.ID)
+	err = dbTest.MoveItemToBox(testCtx, item.ID, box2.ID)
 	assert.Equal(t, err, nil)
-	retrievedItem, err := dbTest.ItemById(item.ID)
+	retrievedItem, err := dbTest.ItemById(testCtx, item.ID)
 	assert.Equal(t, err, nil)
-	retrievedBox, err := dbTest.BoxById(box2.ID)
+	retrievedBox, err := dbTest.BoxById(testCtx, box2.ID)
 	assert.Equal(t, err, nil)
 
 	assert.Equal(t, box2.ID, retrievedItem.BoxID)
@@ -177,11 +186,11 @@ func TestMoveItemToBox(t *testing.T) {
 	assert.Equal(t, retrievedBox.Items[0].BoxID, box2.ID)
 
 	// Move item out of box2
-	err = dbTest.MoveItemToBox(item.ID, uuid.Nil)
+	err = dbTest.MoveItemToBox(testCtx, item.ID, uuid.Nil)
 	assert.Equal(t, err, nil)
-	retrievedItem, err = dbTest.ItemById(item.ID)
+	retrievedItem, err = dbTest.ItemById(testCtx, item.ID)
 	assert.Equal(t, err, nil)
-	retrievedBox, err = dbTest.BoxById(box2.ID)
+	retrievedBox, err = dbTest.BoxById(testCtx, box2.ID)
 	assert.Equal(t, err, nil)
 
 	assert.Equal(t, retrievedItem.BoxID, uuid.Nil)
@@ -202,43 +211,43 @@ func TestMoveItemToObject(t *testing.T) {
 	item := ITEM_1
 
 	// Insert objects into the database
-	_, err := dbTest.insertNewArea(*area)
+	_, err := dbTest.insertNewArea(testCtx, *area)
 	assert.Equal(t, nil, err)
-	err = dbTest.CreateShelf(shelf)
+	err = dbTest.CreateShelf(testCtx, shelf)
 	assert.Equal(t, nil, err)
-	_, err = dbTest.insertNewBox(box)
+	_, err = dbTest.insertNewBox(testCtx, box)
 	assert.Equal(t, nil, err)
-	err = dbTest.insertNewItem(*item)
+	err = dbTest.insertNewItem(testCtx, *item)
 	assert.Equal(t, nil, err)
 
 	// Move item to area
-	err = dbTest.MoveItemToObject(item.ID, area.ID, "area")
+	err = dbTest.MoveItemToObject(testCtx, item.ID, area.ID, "area")
 	assert.Equal(t, nil, err)
-	retrievedItem, err := dbTest.ItemById(item.ID)
+	retrievedItem, err := dbTest.ItemById(testCtx, item.ID)
 	assert.Equal(t, nil, err)
 
 	assert.Equal(t, area.ID, retrievedItem.AreaID)
 	assert.Equal(t, area.Label, retrievedItem.AreaLabel)
 
 	// Move item to shelf
-	err = dbTest.MoveItemToObject(item.ID, shelf.ID, "shelf")
+	err = dbTest.MoveItemToObject(testCtx, item.ID, shelf.ID, "shelf")
 	assert.Equal(t, nil, err)
-	retrievedItem, err = dbTest.ItemById(item.ID)
+	retrievedItem, err = dbTest.ItemById(testCtx, item.ID)
 	assert.Equal(t, nil, err)
 
 	assert.Equal(t, shelf.ID, retrievedItem.ShelfID)
 	assert.Equal(t, shelf.Label, retrievedItem.ShelfLabel)
 
 	// Move item to box
-	err = dbTest.MoveItemToObject(item.ID, box.ID, "box")
+	err = dbTest.MoveItemToObject(testCtx, item.ID, box.ID, "box")
 	assert.Equal(t, nil, err)
-	retrievedItem, err = dbTest.ItemById(item.ID)
+	retrievedItem, err = dbTest.ItemById(testCtx, item.ID)
 	assert.Equal(t, nil, err)
 
 	assert.Equal(t, box.ID, retrievedItem.BoxID)
 	assert.Equal(t, box.Label, retrievedItem.BoxLabel)
 
 	// Test invalid object type
-	err = dbTest.MoveItemToObject(item.ID, box.ID, "invalid")
+	err = dbTest.MoveItemToObject(testCtx, item.ID, box.ID, "invalid")
 	assert.NotEqual(t, nil, err)
 }
@@ -0,0 +1,119 @@
+package database
+
+import (
+	"basement/main/internal/lending"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/gofrs/uuid/v5"
+)
+
+func TestLendAndReturn(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+
+	item := *ITEM_1
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item), nil)
+
+	loan, err := dbTest.Lend(testCtx, "item", item.ID, "Alex", daysFromNow(7))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, loan.Label, item.Label)
+	assert.Equal(t, loan.Borrower, "Alex")
+	assert.Equal(t, loan.ActorID, TEST_OWNER_ID)
+	assert.Equal(t, loan.Returned(), false)
+
+	_, err = dbTest.Lend(testCtx, "item", item.ID, "Sam", daysFromNow(7))
+	assert.Equal(t, errors.Is(err, lending.ErrAlreadyLent), true)
+	// The database refuses a second loan that isn't returned, even without the check of Lend.
+	_, err = dbTest.Sql.Exec(`INSERT INTO loan (owner_id, thing, thing_id, borrower, lent_at, due_date) VALUES (?, 'item', ?, 'Sam', ?, ?);`,
+		TEST_OWNER_ID.String(), item.ID.String(), time.Now().UTC().Format(time.RFC3339), daysFromNow(7))
+	assert.Equal(t, uniqueViolation(err), true)
+	_, err = dbTest.Lend(testCtx, "item", ITEM_2.ID, "Sam", daysFromNow(7))
+	assert.Equal(t, errors.Is(err, ErrNotExist), true)
+	_, err = dbTest.Lend(testCtx, "shelf", item.ID, "Sam", daysFromNow(7))
+	assert.NotEqual(t, err, nil)
+
+	current, err := dbTest.ThingLoan(testCtx, "item", item.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, current.ID, loan.ID)
+
+	returned, err := dbTest.ReturnLoan(testCtx, loan.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, returned.Returned(), true)
+	_, err = dbTest.ReturnLoan(testCtx, loan.ID)
+	assert.Equal(t, errors.Is(err, ErrNotExist), true)
+
+	current, err = dbTest.ThingLoan(testCtx, "item", item.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, current, (*lending.Loan)(nil))
+	loans, err := dbTest.Loans(testCtx)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(loans), 0)
+}
+
+func TestLentBoxLendsItsContents(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+	resetTestBoxes()
+
+	_, err := dbTest.CreateBox(testCtx, BOX_1)
+	assert.Equal(t, err, nil)
+	_, err = dbTest.CreateBox(testCtx, BOX_2)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, dbTest.MoveBoxToBox(testCtx, BOX_2.ID, BOX_1.ID), nil)
+	for _, item := range testItems() {
+		assert.Equal(t, dbTest.CreateNewItem(testCtx, item), nil)
+	}
+	assert.Equal(t, dbTest.MoveItemToBox(testCtx, ITEM_1.ID, BOX_1.ID), nil)
+	assert.Equal(t, dbTest.MoveItemToBox(testCtx, ITEM_2.ID, BOX_2.ID), nil)
+
+	loan, err := dbTest.Lend(testCtx, "box", BOX_1.ID, "Neighbour", daysFromNow(-1))
+	assert.Equal(t, err, nil)
+
+	// the contents of the box and its inner boxes are lent out with it
+	for _, id := range []uuid.UUID{ITEM_1.ID, ITEM_2.ID} {
+		current, err := dbTest.ThingLoan(testCtx, "item", id)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, current.ID, loan.ID)
+	}
+	current, err := dbTest.ThingLoan(testCtx, "box", BOX_2.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, current.ID, loan.ID)
+	_, err = dbTest.Lend(testCtx, "item", ITEM_2.ID, "Sam", daysFromNow(7))
+	assert.Equal(t, errors.Is(err, lending.ErrAlreadyLent), true)
+
+	rows, err := dbTest.ItemListRows(testCtx, "", 10, 1)
+	assert.Equal(t, err, nil)
+	lentTo := map[uuid.UUID]string{}
+	for _, row := range rows {
+		lentTo[row.ID] = row.LentTo
+	}
+	assert.Equal(t, lentTo[ITEM_1.ID], "Neighbour")
+	assert.Equal(t, lentTo[ITEM_2.ID], "Neighbour")
+	assert.Equal(t, lentTo[ITEM_3.ID], "")
+
+	loans, err := dbTest.Loans(testCtx)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(loans), 1)
+	assert.Equal(t, loans[0].Label, BOX_1.Label)
+	assert.Equal(t, len(lending.Filter(loans, lending.STATUS_OVERDUE, time.Now())), 1)
+}
+
+func TestPurgeRemovesLoans(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+
+	item := *ITEM_1
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item), nil)
+	_, err := dbTest.Lend(testCtx, "item", item.ID, "Alex", daysFromNow(7))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, dbTest.DeleteItem(testCtx, item.ID), nil)
+	assert.Equal(t, dbTest.Purge(testCtx, "item", item.ID), nil)
+
+	var count int
+	err = dbTest.Sql.QueryRow(`SELECT COUNT(*) FROM loan;`).Scan(&count)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, 0)
+}
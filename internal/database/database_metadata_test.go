@@ -0,0 +1,167 @@
+package database
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+// withJPEGSegment returns the JPEG with a segment inserted after its start.
+func withJPEGSegment(jpg []byte, marker byte, data []byte) []byte {
+	out := []byte{0xFF, 0xD8, 0xFF, marker}
+	out = binary.BigEndian.AppendUint16(out, uint16(len(data)+2))
+	out = append(out, data...)
+	return append(out, jpg[2:]...)
+}
+
+// withPNGChunk returns the PNG with a chunk after its header.
+func withPNGChunk(pic []byte, name string, data []byte) []byte {
+	ihdrEnd := len(pngSignature) + 12 + int(binary.BigEndian.Uint32(pic[len(pngSignature):]))
+	out := bytes.Clone(pic[:ihdrEnd])
+	out = binary.BigEndian.AppendUint32(out, uint32(len(data)))
+	start := len(out)
+	out = append(out, name...)
+	out = append(out, data...)
+	out = binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE(out[start:]))
+	return append(out, pic[ihdrEnd:]...)
+}
+
+// withPNGText returns the PNG with a text chunk after its header.
+func withPNGText(pic []byte, text string) []byte {
+	return withPNGChunk(pic, "tEXt", []byte(text))
+}
+
+func TestStripJPEGMetadata(t *testing.T) {
+	var jpg bytes.Buffer
+	assert.Equal(t, jpeg.Encode(&jpg, halves(80, 40), nil), nil)
+	pic := withOrientation(t, jpg.Bytes(), 6)
+	pic = withJPEGSegment(pic, 0xE1, []byte("http://ns.adobe.com/xap/1.0/\x00<GPSLatitude>52.5</GPSLatitude>"))
+	pic = withJPEGSegment(pic, 0xFE, []byte("Phone of Alex"))
+	pic = withJPEGSegment(pic, 0xE2, []byte("MPF\x00<GPSLongitude>13.4</GPSLongitude>"))
+	pic = withJPEGSegment(pic, 0xE2, append(bytes.Clone(iccHeader), "sRGB profile"...))
+
+	stripped, err := stripMetadata(pic)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, bytes.Contains(stripped, []byte("GPSLatitude")), false)
+	assert.Equal(t, bytes.Contains(stripped, []byte("Phone of Alex")), false)
+	assert.Equal(t, bytes.Contains(stripped, []byte("GPSLongitude")), false)
+	assert.Equal(t, bytes.Contains(stripped, []byte("sRGB profile")), true)
+	assert.Equal(t, exifOrientation(stripped), 6)
+	img, err := jpeg.Decode(bytes.NewReader(stripped))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, img.Bounds().Size(), image.Pt(80, 40))
+
+	// stripping again changes nothing
+	again, err := stripMetadata(stripped)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, again, stripped)
+
+	_, err = stripMetadata(pic[:30])
+	assert.NotEqual(t, err, nil)
+}
+
+func TestStripBrokenJPEG(t *testing.T) {
+	testCases := []struct {
+		name string
+		pic  []byte
+	}{
+		{"Segment Length 0", []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x00, 0xFF, 0xDA, 0x00, 0x00}},
+		{"Segment Length 1", []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x01, 0xFF, 0xDA, 0x00, 0x00}},
+		{"Segment Longer Than The Picture", []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x20, 'E', 'x', 'i', 'f', 0x00, 0x00}},
+		{"Only The Start", []byte{0xFF, 0xD8}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, exifOrientation(tc.pic), 0)
+			_, err := stripMetadata(tc.pic)
+			assert.NotEqual(t, err, nil)
+		})
+	}
+}
+
+func TestStripPNGMetadata(t *testing.T) {
+	var pic bytes.Buffer
+	assert.Equal(t, png.Encode(&pic, halves(20, 10)), nil)
+	withText := withPNGText(pic.Bytes(), "Author\x00Alex")
+
+	stripped, err := stripMetadata(withText)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, stripped, pic.Bytes())
+
+	// the orientation of the eXIf chunk is kept
+	withExif := withPNGChunk(withText, "eXIf", orientationTIFF(6))
+	assert.Equal(t, exifOrientation(withExif), 6)
+	stripped, err = stripMetadata(withExif)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, exifOrientation(stripped), 6)
+	assert.Equal(t, bytes.Contains(stripped, []byte("Alex")), false)
+	_, err = png.Decode(bytes.NewReader(stripped))
+	assert.Equal(t, err, nil)
+}
+
+func TestStripWebPMetadata(t *testing.T) {
+	pic, err := Base64StringToByte(VALID_BASE64_WEBP)
+	assert.Equal(t, err, nil)
+	// pictures without metadata stay the same
+	stripped, err := stripMetadata(pic)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, stripped, pic)
+}
+
+func TestStripPictureMetadata(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+
+	var jpg bytes.Buffer
+	assert.Equal(t, jpeg.Encode(&jpg, halves(80, 40), nil), nil)
+	clean := withOrientation(t, jpg.Bytes(), 6)
+	withGPS := withJPEGSegment(clean, 0xED, []byte("Photoshop 3.0\x00GPS"))
+
+	item := *ITEM_1
+	item.Picture = ""
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item), nil)
+	// a picture from before the metadata was stripped
+	key, err := storeBlob(ByteToBase64String(withGPS))
+	assert.Equal(t, err, nil)
+	_, err = dbTest.Sql.Exec(`UPDATE item SET picture = ? WHERE id = ?;`, key, item.ID.String())
+	assert.Equal(t, err, nil)
+
+	stripped, err := dbTest.StripPictureMetadata()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, stripped, 1)
+	saved, err := dbTest.ItemById(testCtx, item.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, saved.Picture, ByteToBase64String(clean))
+
+	stripped, err = dbTest.StripPictureMetadata()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, stripped, 0)
+}
+
+func TestUploadedPictureIsStripped(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+
+	var jpg bytes.Buffer
+	assert.Equal(t, jpeg.Encode(&jpg, halves(80, 40), nil), nil)
+	pic := withJPEGSegment(jpg.Bytes(), 0xFE, []byte("Phone of Alex"))
+
+	item := *ITEM_1
+	item.Picture = ByteToBase64String(pic)
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item), nil)
+	saved, err := dbTest.ItemById(testCtx, item.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, saved.Picture, ByteToBase64String(jpg.Bytes()))
+
+	assert.Equal(t, dbTest.UpdateItem(testCtx, item, false, "image/jpeg"), nil)
+	saved, err = dbTest.ItemById(testCtx, item.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, saved.Picture, ByteToBase64String(jpg.Bytes()))
+}
@@ -0,0 +1,119 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+var testMigrations = []migration{
+	{version: 1, name: "create note", statements: []string{
+		`CREATE TABLE note (id TEXT PRIMARY KEY, label TEXT);`,
+	}},
+	{version: 2, name: "add note text", statements: []string{
+		`ALTER TABLE note ADD COLUMN text TEXT;`,
+	}},
+}
+
+func newMigrationTestDB() *DB {
+	db := &DB{}
+	db.open(":memory:")
+	return db
+}
+
+func TestMigrate(t *testing.T) {
+	db := newMigrationTestDB()
+	defer db.Sql.Close()
+
+	version, err := db.SchemaVersion()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, version, 0)
+
+	applied, err := db.migrate(testMigrations, false)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(applied), 2)
+
+	version, err = db.SchemaVersion()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, version, 2)
+
+	_, err = db.Sql.Exec(`INSERT INTO note (id, label, text) VALUES ('1', 'a', 'b');`)
+	assert.Equal(t, err, nil)
+
+	// Running again applies nothing.
+	applied, err = db.migrate(testMigrations, false)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(applied), 0)
+}
+
+func TestMigrateDryRun(t *testing.T) {
+	db := newMigrationTestDB()
+	defer db.Sql.Close()
+
+	applied, err := db.migrate(testMigrations, true)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(applied), 2)
+
+	version, err := db.SchemaVersion()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, version, 0)
+
+	var count int
+	err = db.Sql.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'note';`).Scan(&count)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, 0)
+}
+
+func TestMigrateFailureRollsBack(t *testing.T) {
+	db := newMigrationTestDB()
+	defer db.Sql.Close()
+
+	broken := append(testMigrations[:1:1], migration{version: 2, name: "broken", statements: []string{
+		`ALTER TABLE note ADD COLUMN text TEXT;`,
+		`ALTER TABLE missing ADD COLUMN text TEXT;`,
+	}})
+
+	applied, err := db.migrate(broken, false)
+	assert.NotEqual(t, err, nil)
+	assert.Equal(t, len(applied), 1)
+
+	status, err := db.migrationStatus(broken)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, status.CurrentVersion, 1)
+	assert.Equal(t, status.LatestVersion, 2)
+	assert.Equal(t, len(status.Pending), 1)
+
+	// First statement of the failed migration must be rolled back too.
+	_, err = db.Sql.Exec(`INSERT INTO note (id, label, text) VALUES ('1', 'a', 'b');`)
+	assert.NotEqual(t, err, nil)
+}
+
+func TestValidMigrations(t *testing.T) {
+	err := validMigrations(testMigrations)
+	assert.Equal(t, err, nil)
+
+	err = validMigrations([]migration{{version: 2, name: "gap", statements: []string{"SELECT 1;"}}})
+	assert.NotEqual(t, err, nil)
+
+	err = validMigrations([]migration{{version: 1, name: "empty"}})
+	assert.NotEqual(t, err, nil)
+}
+
+func TestOwnerDoesNotReferenceUser(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestBoxes()
+
+	for _, table := range []string{"item", "box", "shelf", "area"} {
+		var references int
+		err := dbTest.Sql.QueryRow(`SELECT COUNT(*) FROM pragma_foreign_key_list(?) WHERE "from" = ?;`, table, OWNER_ID).Scan(&references)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, references, 0)
+	}
+	// the edited schema is still used
+	box := *BOX_1
+	_, err := dbTest.CreateBox(testCtx, &box)
+	assert.Equal(t, err, nil)
+	exists, err := dbTest.Exists(testCtx, "box", box.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, exists, true)
+}
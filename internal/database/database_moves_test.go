@@ -0,0 +1,60 @@
+package database
+
+import (
+	"basement/main/internal/moves"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/gofrs/uuid/v5"
+)
+
+func TestUndoMoves(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+	resetTestBoxes()
+	ctx := historyTestCtx()
+
+	err := dbTest.CreateNewItem(ctx, *ITEM_1)
+	assert.Equal(t, err, nil)
+	err = dbTest.CreateNewItem(ctx, *ITEM_2)
+	assert.Equal(t, err, nil)
+	_, err = dbTest.CreateBox(ctx, BOX_1)
+	assert.Equal(t, err, nil)
+	_, err = dbTest.CreateBox(ctx, BOX_2)
+	assert.Equal(t, err, nil)
+
+	// Moves without an operation can't be undone.
+	err = dbTest.MoveItemToBox(ctx, ITEM_1.ID, BOX_1.ID)
+	assert.Equal(t, err, nil)
+
+	operationID := uuid.Must(uuid.NewV4())
+	operationCtx := moves.WithOperation(ctx, operationID)
+	err = dbTest.MoveItemToBox(operationCtx, ITEM_1.ID, BOX_2.ID)
+	assert.Equal(t, err, nil)
+	err = dbTest.MoveItemToBox(operationCtx, ITEM_2.ID, BOX_2.ID)
+	assert.Equal(t, err, nil)
+
+	// Other households can't undo the operation.
+	_, err = dbTest.UndoMoves(historyTestCtx(), operationID)
+	assert.NotEqual(t, err, nil)
+
+	count, err := dbTest.UndoMoves(ctx, operationID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, 2)
+
+	item, err := dbTest.ItemById(ctx, ITEM_1.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, item.BoxID, BOX_1.ID)
+	item, err = dbTest.ItemById(ctx, ITEM_2.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, item.BoxID.IsNil(), true)
+
+	// An operation can only be undone once.
+	_, err = dbTest.UndoMoves(ctx, operationID)
+	assert.NotEqual(t, err, nil)
+
+	entries, err := dbTest.History(ctx, "item", ITEM_1.ID, 1, 1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, entries[0].From.ID, BOX_2.ID)
+	assert.Equal(t, entries[0].To.ID, BOX_1.ID)
+}
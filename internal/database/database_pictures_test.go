@@ -0,0 +1,142 @@
+package database
+
+import (
+	"basement/main/internal/env"
+	"basement/main/internal/pictures"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestPictureGallery(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+
+	item := *ITEM_1
+	item.Picture = ""
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item), nil)
+
+	front, err := dbTest.AddPicture(testCtx, "item", item.ID, VALID_BASE64_PNG, "image/png", "Front")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, front.Cover, true)
+	plate, err := dbTest.AddPicture(testCtx, "item", item.ID, VALID_BASE64_PNG_2, "image/png", "Serial plate")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, plate.Cover, false)
+	assert.Equal(t, plate.Position, front.Position+1)
+
+	// the first picture is the picture of the item
+	saved, err := dbTest.ItemById(testCtx, item.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, saved.Picture, VALID_BASE64_PNG)
+
+	_, err = dbTest.MovePicture(testCtx, plate.ID, pictures.DIRECTION_UP)
+	assert.Equal(t, err, nil)
+	_, err = dbTest.MovePicture(testCtx, plate.ID, pictures.DIRECTION_UP)
+	assert.Equal(t, err, nil)
+	_, err = dbTest.UpdatePictureCaption(testCtx, front.ID, "Front side")
+	assert.Equal(t, err, nil)
+	list, err := dbTest.Pictures(testCtx, "item", item.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(list), 2)
+	assert.Equal(t, list[0].ID, plate.ID)
+	assert.Equal(t, list[1].Caption, "Front side")
+
+	_, err = dbTest.SetCoverPicture(testCtx, plate.ID)
+	assert.Equal(t, err, nil)
+	saved, err = dbTest.ItemById(testCtx, item.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, saved.Picture, VALID_BASE64_PNG_2)
+
+	// the next picture takes the place of a removed cover
+	_, err = dbTest.DeletePicture(testCtx, plate.ID)
+	assert.Equal(t, err, nil)
+	list, err = dbTest.Pictures(testCtx, "item", item.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(list), 1)
+	assert.Equal(t, list[0].Cover, true)
+	saved, err = dbTest.ItemById(testCtx, item.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, saved.Picture, VALID_BASE64_PNG)
+
+	_, err = dbTest.DeletePicture(testCtx, front.ID)
+	assert.Equal(t, err, nil)
+	saved, err = dbTest.ItemById(testCtx, item.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, saved.Picture, "")
+	_, err = dbTest.DeletePicture(testCtx, front.ID)
+	assert.Equal(t, errors.Is(err, ErrNotExist), true)
+	_, err = dbTest.AddPicture(testCtx, "item", ITEM_2.ID, VALID_BASE64_PNG, "image/png", "")
+	assert.Equal(t, errors.Is(err, ErrNotExist), true)
+}
+
+func TestFormPictureIsTheCover(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+
+	item := *ITEM_1
+	item.Picture = VALID_BASE64_PNG
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item), nil)
+	list, err := dbTest.Pictures(testCtx, "item", item.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(list), 1)
+	assert.Equal(t, list[0].Cover, true)
+
+	item.Picture = VALID_BASE64_PNG_2
+	assert.Equal(t, dbTest.UpdateItem(testCtx, item, false, "image/png"), nil)
+	list, err = dbTest.Pictures(testCtx, "item", item.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(list), 1)
+	assert.Equal(t, list[0].Picture, VALID_BASE64_PNG_2)
+
+	assert.Equal(t, dbTest.DeleteItem(testCtx, item.ID), nil)
+	assert.Equal(t, dbTest.Purge(testCtx, "item", item.ID), nil)
+	var count int
+	err = dbTest.Sql.QueryRow(`SELECT COUNT(*) FROM picture;`).Scan(&count)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, 0)
+}
+
+func TestPictureRendition(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+
+	side := env.CurrentConfig().MediumPictureSide()
+	var large bytes.Buffer
+	assert.Equal(t, png.Encode(&large, image.NewRGBA(image.Rect(0, 0, 2*side, side))), nil)
+	item := *ITEM_1
+	item.Picture = ByteToBase64String(large.Bytes())
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item), nil)
+
+	original, err := dbTest.PictureRendition(testCtx, "item", item.ID.String(), pictures.SIZE_ORIGINAL)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, original.ContentType, "image/png")
+	assert.Equal(t, original.Content, large.Bytes())
+
+	medium, err := dbTest.PictureRendition(testCtx, "item", item.ID.String(), pictures.SIZE_MEDIUM)
+	assert.Equal(t, err, nil)
+	config, _, err := image.DecodeConfig(bytes.NewReader(medium.Content))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, config.Width, side)
+	assert.NotEqual(t, medium.ETag, original.ETag)
+
+	thumbnail, err := dbTest.PictureRendition(testCtx, "item", item.ID.String(), pictures.SIZE_THUMBNAIL)
+	assert.Equal(t, err, nil)
+	assert.NotEqual(t, thumbnail.ETag, original.ETag)
+
+	// the cover is also a picture of the gallery
+	list, err := dbTest.Pictures(testCtx, "item", item.ID)
+	assert.Equal(t, err, nil)
+	gallery, err := dbTest.PictureRendition(testCtx, pictures.THING_GALLERY, fmt.Sprint(list[0].ID), pictures.SIZE_ORIGINAL)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, gallery.ETag, original.ETag)
+
+	_, err = dbTest.PictureRendition(testCtx, "item", ITEM_2.ID.String(), pictures.SIZE_ORIGINAL)
+	assert.Equal(t, errors.Is(err, ErrNotExist), true)
+	_, err = dbTest.PictureRendition(testCtx, "item", item.ID.String(), "huge")
+	assert.NotEqual(t, err, nil)
+}
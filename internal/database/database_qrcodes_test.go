@@ -0,0 +1,197 @@
+package database
+
+import (
+	"basement/main/internal/env"
+	"basement/main/internal/households"
+	"basement/main/internal/qrcodes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/gofrs/uuid/v5"
+)
+
+// isPayload reports whether the QR code is a generated short URL.
+func isPayload(qrcode string) bool {
+	return strings.HasPrefix(qrcode, env.CurrentConfig().PublicURL()+qrcodes.PATH_PREFIX)
+}
+
+func TestNewThingsGetQRCodes(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+	resetTestBoxes()
+
+	item := *ITEM_1
+	item.QRCode = ""
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item), nil)
+	saved, err := dbTest.ItemById(testCtx, item.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, isPayload(saved.QRCode), true)
+	payload := saved.QRCode
+
+	// relabelling with an empty QR code field keeps the code
+	item.Label = "relabelled"
+	assert.Equal(t, dbTest.UpdateItem(testCtx, item, true, ""), nil)
+	saved, err = dbTest.ItemById(testCtx, item.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, saved.QRCode, payload)
+
+	got, err := dbTest.QRCodePayload(testCtx, "item", item.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, got, payload)
+
+	// codes that are typed in are kept
+	box := *BOX_1
+	_, err = dbTest.CreateBox(testCtx, &box)
+	assert.Equal(t, err, nil)
+	got, err = dbTest.QRCodePayload(testCtx, "box", box.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, got, BOX_1.QRCode)
+}
+
+func TestQRCodePayloadErrors(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+
+	_, err := dbTest.QRCodePayload(testCtx, "item", ITEM_1.ID)
+	assert.Equal(t, errors.Is(err, ErrNotExist), true)
+
+	_, err = dbTest.QRCodePayload(testCtx, "user", ITEM_1.ID)
+	assert.NotEqual(t, err, nil)
+
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, *ITEM_1), nil)
+	_, err = dbTest.Sql.Exec(`UPDATE item SET qrcode = NULL WHERE id = ?;`, ITEM_1.ID.String())
+	assert.Equal(t, err, nil)
+	_, err = dbTest.QRCodePayload(testCtx, "item", ITEM_1.ID)
+	assert.Equal(t, errors.Is(err, ErrNotExist), true)
+}
+
+func TestAssignQRCodes(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, *ITEM_1), nil)
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, *ITEM_2), nil)
+	// a row from before QR codes were generated
+	_, err := dbTest.Sql.Exec(`UPDATE item SET qrcode = '' WHERE id = ?;`, ITEM_1.ID.String())
+	assert.Equal(t, err, nil)
+
+	tx, err := dbTest.Sql.Begin()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, assignQRCodes(tx, false), nil)
+	assert.Equal(t, tx.Commit(), nil)
+
+	first, err := dbTest.QRCodePayload(testCtx, "item", ITEM_1.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, isPayload(first), true)
+	second, err := dbTest.QRCodePayload(testCtx, "item", ITEM_2.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, second, ITEM_2.QRCode)
+}
+
+func TestThingByQRCode(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+	resetTestBoxes()
+
+	item := *ITEM_1
+	item.QRCode = ""
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item), nil)
+	payload, err := dbTest.QRCodePayload(testCtx, "item", item.ID)
+	assert.Equal(t, err, nil)
+	box := *BOX_1
+	_, err = dbTest.CreateBox(testCtx, &box)
+	assert.Equal(t, err, nil)
+
+	thing, id, err := dbTest.ThingByQRCode(testCtx, strings.TrimPrefix(payload, env.CurrentConfig().PublicURL()+qrcodes.PATH_PREFIX))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, thing, "item")
+	assert.Equal(t, id, item.ID)
+
+	// QR codes that were typed in match as a whole
+	thing, id, err = dbTest.ThingByQRCode(testCtx, BOX_1.QRCode)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, thing, "box")
+	assert.Equal(t, id, box.ID)
+	_, _, err = dbTest.ThingByQRCode(testCtx, BOX_1.QRCode[1:])
+	assert.Equal(t, errors.Is(err, qrcodes.ErrNotAssigned), true)
+
+	assert.Equal(t, dbTest.DeleteItem(testCtx, item.ID), nil)
+	_, _, err = dbTest.ThingByQRCode(testCtx, payload[len(payload)-10:])
+	assert.Equal(t, errors.Is(err, qrcodes.ErrNotAssigned), true)
+}
+
+func TestThingByQRCodeInOtherHousehold(t *testing.T) {
+	EmptyTestDatabase()
+	resetHouseholds(t)
+	resetTestBoxes()
+	ctx := context.Background()
+	alice := insertHouseholdTestUser(t, "423e4567-e89b-12d3-a456-426614174001", "qrcode-alice")
+	bob := insertHouseholdTestUser(t, "423e4567-e89b-12d3-a456-426614174002", "qrcode-bob")
+
+	id, err := dbTest.CreateHousehold(ctx, alice, "Garage")
+	assert.Equal(t, err, nil)
+	garage, err := dbTest.Membership(ctx, alice, id)
+	assert.Equal(t, err, nil)
+	personal, err := dbTest.Membership(ctx, alice, uuid.Nil)
+	assert.Equal(t, err, nil)
+	bobPersonal, err := dbTest.Membership(ctx, bob, uuid.Nil)
+	assert.Equal(t, err, nil)
+	_, err = dbTest.CreateBox(households.WithMembership(ctx, garage), BOX_1)
+	assert.Equal(t, err, nil)
+
+	// members learn which household the code is in, so it isn't claimed a second time
+	_, _, err = dbTest.ThingByQRCode(households.WithMembership(ctx, personal), BOX_1.QRCode)
+	var other *qrcodes.OtherHouseholdError
+	assert.Equal(t, errors.As(err, &other), true)
+	assert.Equal(t, other.HouseholdID, id)
+	assert.Equal(t, other.HouseholdName, "Garage")
+
+	_, _, err = dbTest.ThingByQRCode(households.WithMembership(ctx, bobPersonal), BOX_1.QRCode)
+	assert.Equal(t, errors.As(err, &other), true)
+	assert.Equal(t, other.HouseholdID, uuid.Nil)
+	assert.Equal(t, other.HouseholdName, "")
+
+	thing, boxID, err := dbTest.ThingByQRCode(households.WithMembership(ctx, garage), BOX_1.QRCode)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, thing, "box")
+	assert.Equal(t, boxID, BOX_1.ID)
+}
+
+func TestClaimQRCode(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+
+	item := *ITEM_1
+	item.QRCode = ""
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item), nil)
+	_, err := dbTest.Sql.Exec(`UPDATE item SET qrcode = NULL WHERE id = ?;`, item.ID.String())
+	assert.Equal(t, err, nil)
+	before, err := dbTest.History(testCtx, "item", item.ID, 100, 1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, dbTest.ClaimQRCode(testCtx, "item", item.ID, qrcodes.Payload("STICKER-7"), false), nil)
+
+	thing, id, err := dbTest.ThingByQRCode(testCtx, "STICKER-7")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, thing, "item")
+	assert.Equal(t, id, item.ID)
+	after, err := dbTest.History(testCtx, "item", item.ID, 100, 1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(after), len(before)+1)
+
+	// printed codes keep working unless replacing them is confirmed
+	err = dbTest.ClaimQRCode(testCtx, "item", item.ID, qrcodes.Payload("STICKER-8"), false)
+	assert.Equal(t, errors.Is(err, qrcodes.ErrHasQRCode), true)
+	_, _, err = dbTest.ThingByQRCode(testCtx, "STICKER-7")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, dbTest.ClaimQRCode(testCtx, "item", item.ID, qrcodes.Payload("STICKER-8"), true), nil)
+	_, _, err = dbTest.ThingByQRCode(testCtx, "STICKER-7")
+	assert.Equal(t, errors.Is(err, qrcodes.ErrNotAssigned), true)
+
+	err = dbTest.ClaimQRCode(testCtx, "item", ITEM_2.ID, qrcodes.Payload("STICKER-9"), true)
+	assert.Equal(t, errors.Is(err, ErrNotExist), true)
+	err = dbTest.ClaimQRCode(testCtx, "user", item.ID, qrcodes.Payload("STICKER-9"), true)
+	assert.NotEqual(t, err, nil)
+}
@@ -1,8 +1,10 @@
 package database
 
 import (
-	"github.com/go-playground/assert/v2"
+	"html/template"
 	"testing"
+
+	"github.com/go-playground/assert/v2"
 )
 
 func TestVirtualBoxInsert(t *testing.T) {
@@ -12,17 +14,17 @@ func TestVirtualBoxInsert(t *testing.T) {
 	testbox := BOX_1
 
 	// Create the outerbox
-	_, err := dbTest.CreateBox(testbox)
+	_, err := dbTest.CreateBox(testCtx, testbox)
 	if err != nil {
 		t.Fatalf("Failed to create outer box: %v", err)
 	}
 
 	// Check if the outerbox exists in box_fts
-	exist, err := dbTest.VirtualBoxExist(testbox.ID)
+	exist, err := dbTest.VirtualBoxExist(testCtx, testbox.ID)
 	assert.Equal(t, err, nil)
 	assert.Equal(t, exist, true)
 
-	boxListRow, err := dbTest.BoxListRowByID(testbox.ID)
+	boxListRow, err := dbTest.BoxListRowByID(testCtx, testbox.ID)
 	if err != nil {
 		t.Fatalf("Failed to create outer box: %v", err)
 	}
@@ -40,25 +42,25 @@ func TestVirtualBoxUpdate(t *testing.T) {
 	testbox.OuterBoxID = BOX_2.ID
 
 	// Create the outerbox
-	_, err := dbTest.CreateBox(outerBox)
+	_, err := dbTest.CreateBox(testCtx, outerBox)
 	if err != nil {
 		t.Fatalf("Failed to create outer box: %v", err)
 	}
 
 	// Create the testBox
-	_, err = dbTest.CreateBox(testbox)
+	_, err = dbTest.CreateBox(testCtx, testbox)
 	if err != nil {
 		t.Fatalf("Failed to create outer box while checking the BoxTriger: %v", err)
 	}
 
 	testbox.Label = "new testbox label"
-	dbTest.UpdateBox(*testbox, true, "image/png")
+	dbTest.UpdateBox(testCtx, *testbox, true, "image/png")
 
 	outerBox.Label = "new outerbox label"
-	dbTest.UpdateBox(*outerBox, true, "image/png")
+	dbTest.UpdateBox(testCtx, *outerBox, true, "image/png")
 
 	// Get the box_fts to check if the outerbox_label  was updated
-	afterUpdate, err := dbTest.BoxListRowByID(testbox.ID)
+	afterUpdate, err := dbTest.BoxListRowByID(testCtx, testbox.ID)
 	if err != nil {
 		t.Fatalf("Failed to fetch the testbox while checking the BoxTriger: %v", err)
 	}
@@ -76,30 +78,30 @@ func TestVirtualBoxUpdateIgnorePicture(t *testing.T) {
 	testbox.OuterBoxID = BOX_2.ID
 
 	// Create the outerbox
-	_, err := dbTest.CreateBox(outerBox)
+	_, err := dbTest.CreateBox(testCtx, outerBox)
 	if err != nil {
 		t.Fatalf("Failed to create outer box: %v", err)
 	}
 
 	// Create the testBox
-	_, err = dbTest.CreateBox(testbox)
+	_, err = dbTest.CreateBox(testCtx, testbox)
 	if err != nil {
 		t.Fatalf("Failed to create outer box while checking the BoxTriger: %v", err)
 	}
 
-	beforeUpdate, err := dbTest.BoxListRowByID(testbox.ID)
+	beforeUpdate, err := dbTest.BoxListRowByID(testCtx, testbox.ID)
 	assert.NotEqual(t, beforeUpdate.PreviewPicture, "")
 
 	testbox.Label = "new testbox label"
 	testbox.Picture = ""
-	dbTest.UpdateBox(*testbox, true, "image/png")
+	dbTest.UpdateBox(testCtx, *testbox, true, "image/png")
 
 	outerBox.Label = "new outerbox label"
 	outerBox.Picture = ""
-	dbTest.UpdateBox(*outerBox, true, "image/png")
+	dbTest.UpdateBox(testCtx, *outerBox, true, "image/png")
 
 	// Get the box_fts to check if the outerbox_label  was updated
-	afterUpdate, err := dbTest.BoxListRowByID(testbox.ID)
+	afterUpdate, err := dbTest.BoxListRowByID(testCtx, testbox.ID)
 	if err != nil {
 		t.Fatalf("Failed to fetch the testbox while checking the BoxTriger: %v", err)
 	}
@@ -116,18 +118,18 @@ func TestVirtualBoxDelete(t *testing.T) {
 	testbox := BOX_1
 
 	// Create the testBox
-	_, err := dbTest.CreateBox(testbox)
+	_, err := dbTest.CreateBox(testCtx, testbox)
 	if err != nil {
 		t.Fatalf("Failed to create outer box while checking the BoxTriger: %v", err)
 	}
 
-	dbTest.DeleteBox(testbox.ID)
+	dbTest.DeleteBox(testCtx, testbox.ID)
 	if err != nil {
 		t.Fatalf("error while Deleting the the box: %v", err)
 	}
 
 	// Check if the outerbox exists in box_fts
-	exist, err := dbTest.VirtualBoxExist(testbox.ID)
+	exist, err := dbTest.VirtualBoxExist(testCtx, testbox.ID)
 	assert.Equal(t, err, nil)
 	assert.NotEqual(t, exist, true)
 }
@@ -137,7 +139,7 @@ func TestBoxListRows(t *testing.T) {
 	resetTestBoxes()
 	// Insert the new boxes
 	for _, box := range testBoxes() {
-		_, err := dbTest.insertNewBox(box)
+		_, err := dbTest.insertNewBox(testCtx, box)
 		if err != nil {
 			t.Fatalf("insertNewBox failed while testing the boxFuzzyFinder: %v", err)
 		}
@@ -158,7 +160,7 @@ func TestBoxListRows(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			virtualBoxes, err := dbTest.BoxListRows(tc.query, 10, 1)
+			virtualBoxes, err := dbTest.BoxListRows(testCtx, tc.query, 10, 1)
 			if err != nil {
 				t.Fatalf("error occurred while testing boxFuzzyFinder(): %v", err)
 			}
@@ -168,3 +170,205 @@ func TestBoxListRows(t *testing.T) {
 		})
 	}
 }
+
+func TestSearchFilter(t *testing.T) {
+	testCases := []struct {
+		name       string
+		table      string
+		search     string
+		conditions []string
+		args       []any
+	}{
+		{"Empty", "item_fts", "   ", nil, nil},
+		{"Words", "item_fts", " red  box ", []string{"item_fts MATCH ?"},
+			[]any{FTS_SEARCH_COLUMNS + ` : "red"* AND ` + FTS_SEARCH_COLUMNS + ` : "box"*`}},
+		{"Field And Phrase", "box_fts", `area:"Basement 1"`, []string{"box_fts MATCH ?"},
+			[]any{`{area_label} : "Basement 1"`}},
+		{"Negation", "item_fts", "-broken", []string{"id NOT IN (SELECT id FROM item_fts WHERE item_fts MATCH ?)"},
+			[]any{FTS_SEARCH_COLUMNS + ` : "broken"*`}},
+		{"Quantity", "item_fts", "qty>2", []string{"id IN (SELECT id FROM item WHERE quantity > ?)"}, []any{float64(2)}},
+		{"Quantity Of Boxes", "box_fts", "qty>2", []string{"0"}, nil},
+		{"Custom Field", "box_fts", "color:red", []string{"id IN (SELECT v.thing_id FROM custom_field_value AS v JOIN custom_field AS f ON f.id = v.field_id " +
+			"WHERE f.owner_id = ? AND f.thing = ? AND REPLACE(f.name, ' ', '_') = ? COLLATE NOCASE AND v.value LIKE ? ESCAPE '\\')"}, []any{"owner", "box", "color", "%red%"}},
+		{"Tag", "shelf_fts", "tag:winter", []string{"id IN (SELECT thing_tag.thing_id FROM thing_tag JOIN tag ON tag.id = thing_tag.tag_id " +
+			"WHERE tag.owner_id = ? AND thing_tag.thing = ? AND tag.name = ? COLLATE NOCASE)"}, []any{"owner", "shelf", "winter"}},
+		{"Category", "item_fts", "-category:tools", []string{"id NOT IN (WITH RECURSIVE matched(id) AS (" +
+			"SELECT id FROM category WHERE owner_id = ? AND name = ? COLLATE NOCASE " +
+			"UNION SELECT category.id FROM category JOIN matched ON category.parent_id = matched.id WHERE category.owner_id = ?) " +
+			"SELECT id FROM item WHERE owner_id = ? AND category_id IN matched)"}, []any{"owner", "tools", "owner", "owner"}},
+		{"Sort Is No Condition", "item_fts", "sort:label", nil, nil},
+		{"Invalid Syntax Searches Words", "item_fts", `label:`, []string{"item_fts MATCH ?"},
+			[]any{FTS_SEARCH_COLUMNS + ` : "label:"*`}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			filter := newSearchFilter(tc.table, tc.search, "owner")
+			assert.Equal(t, filter.conditions, tc.conditions)
+			assert.Equal(t, filter.args, tc.args)
+		})
+	}
+}
+
+func TestItemListRowsSearch(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+	resetTestBoxes()
+
+	ITEM_1.Label = "Hammer"
+	ITEM_1.Description = "heavy claw hammer with a wooden handle"
+	ITEM_2.Label = "Nails"
+	ITEM_2.Description = "for the hammer <b>"
+	ITEM_3.Label = "Screwdriver"
+	ITEM_3.Description = "flat head"
+	BOX_1.Label = "Toolbox"
+	for _, item := range testItems() {
+		err := dbTest.CreateNewItem(testCtx, item)
+		assert.Equal(t, err, nil)
+	}
+	_, err := dbTest.CreateBox(testCtx, BOX_1)
+	assert.Equal(t, err, nil)
+	err = dbTest.MoveItemToBox(testCtx, ITEM_3.ID, BOX_1.ID)
+	assert.Equal(t, err, nil)
+
+	// A match in the label ranks above a match in the description.
+	rows, err := dbTest.ItemListRows(testCtx, "hamm", 10, 1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(rows), 2)
+	assert.Equal(t, rows[0].ID, ITEM_1.ID)
+	assert.Equal(t, rows[0].LabelHighlight, template.HTML("<mark>Hammer</mark>"))
+	assert.Equal(t, rows[1].ID, ITEM_2.ID)
+	assert.Equal(t, rows[1].LabelHighlight, template.HTML(""))
+	assert.Equal(t, rows[1].Snippet, template.HTML("for the <mark>hammer</mark> &lt;b&gt;"))
+
+	count, err := dbTest.ItemListCounter(testCtx, "hamm")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, 2)
+
+	// Location labels are searched as well.
+	rows, err = dbTest.ItemListRows(testCtx, "toolbox", 10, 1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(rows), 1)
+	assert.Equal(t, rows[0].ID, ITEM_3.ID)
+
+	// Every word has to match.
+	count, err = dbTest.ItemListCounter(testCtx, "hammer wooden")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, 1)
+
+	// FTS5 syntax is not interpreted.
+	for _, search := range []string{`"`, "label:{Nails}", "hammer OR nails", "' OR 1=1 --", "NEAR(hammer nails)"} {
+		_, err = dbTest.ItemListRows(testCtx, search, 10, 1)
+		assert.Equal(t, err, nil)
+		_, err = dbTest.ItemListCounter(testCtx, search)
+		assert.Equal(t, err, nil)
+	}
+	count, err = dbTest.ItemListCounter(testCtx, "hammer OR nails")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, 0)
+}
+
+func TestSearch(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+	resetTestBoxes()
+	resetShelves()
+
+	ITEM_1.Label = "Drill"
+	ITEM_2.Label = "Drill bits"
+	BOX_1.Label = "Drill case"
+	SHELF_1.Label = "Tools"
+	SHELF_1.Description = "drill and saw"
+	for _, item := range testItems() {
+		err := dbTest.CreateNewItem(testCtx, item)
+		assert.Equal(t, err, nil)
+	}
+	_, err := dbTest.CreateBox(testCtx, BOX_1)
+	assert.Equal(t, err, nil)
+	err = dbTest.CreateShelf(testCtx, SHELF_1)
+	assert.Equal(t, err, nil)
+
+	results, err := dbTest.Search(testCtx, "drill", 1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, results.Total, 4)
+	assert.Equal(t, len(results.Groups), 4)
+
+	items := results.Groups[0]
+	assert.Equal(t, items.Thing, "item")
+	assert.Equal(t, items.Count, 2)
+	assert.Equal(t, len(items.Results), 1)
+	assert.Equal(t, items.More(), true)
+	assert.Equal(t, items.Results[0].URL, "/item/"+items.Results[0].ID.String())
+
+	assert.Equal(t, results.Groups[1].Count, 1)
+	assert.Equal(t, results.Groups[1].Results[0].ID, BOX_1.ID)
+	assert.Equal(t, results.Groups[2].Count, 1)
+	assert.Equal(t, results.Groups[2].Results[0].URL, "/shelf/"+SHELF_1.ID.String())
+	assert.Equal(t, results.Groups[3].Count, 0)
+	assert.Equal(t, len(results.Groups[3].Results), 0)
+
+	// Other households find nothing.
+	results, err = dbTest.Search(historyTestCtx(), "drill", 10)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, results.Total, 0)
+
+	results, err = dbTest.Search(testCtx, " ", 10)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, results.Total, 0)
+}
+
+func TestItemListRowsSearchQuery(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+	resetTestBoxes()
+
+	ITEM_1.Label = "Drill"
+	ITEM_1.Description = "cordless"
+	ITEM_1.Quantity = 1
+	ITEM_1.Weight = 2.5
+	ITEM_2.Label = "Drill"
+	ITEM_2.Description = "broken"
+	ITEM_2.Quantity = 3
+	ITEM_3.Label = "Hammer"
+	ITEM_3.Description = "for the drill"
+	ITEM_3.Quantity = 5
+	BOX_1.Label = "Garage shelf"
+	for _, item := range testItems() {
+		err := dbTest.CreateNewItem(testCtx, item)
+		assert.Equal(t, err, nil)
+	}
+	_, err := dbTest.CreateBox(testCtx, BOX_1)
+	assert.Equal(t, err, nil)
+	err = dbTest.MoveItemToBox(testCtx, ITEM_1.ID, BOX_1.ID)
+	assert.Equal(t, err, nil)
+
+	testCases := []struct {
+		search   string
+		expected int
+	}{
+		{"drill", 3},
+		{"label:drill", 2},
+		{"description:drill", 1},
+		{"drill -broken", 2},
+		{"drill box:garage", 1},
+		{`box:"garage shelf"`, 1},
+		{`box:"shelf garage"`, 0},
+		{"qty>2", 2},
+		{"qty>=3 -label:hammer", 1},
+		{"drill qty<2", 1},
+		{"weight=2.5", 1},
+		{"-qty>1", 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.search, func(t *testing.T) {
+			count, err := dbTest.ItemListCounter(testCtx, tc.search)
+			assert.Equal(t, err, nil)
+			assert.Equal(t, count, tc.expected)
+
+			rows, err := dbTest.ItemListRows(testCtx, tc.search, 10, 1)
+			assert.Equal(t, err, nil)
+			assert.Equal(t, len(rows), tc.expected)
+		})
+	}
+}
@@ -16,19 +16,19 @@ func init() {
 
 func TestExistShelf(t *testing.T) {
 	EmptyTestDatabase()
-	exists, err := dbTest.Exists("shelf", SHELF_VALID_UUID_1)
+	exists, err := dbTest.Exists(testCtx, "shelf", SHELF_VALID_UUID_1)
 	assert.Equal(t, err, nil)
 	assert.Equal(t, exists, false)
 
-	dbTest.createNewShelf(SHELF_VALID_UUID_1)
-	exists, err = dbTest.Exists("shelf", SHELF_VALID_UUID_1)
+	dbTest.createNewShelf(testCtx, SHELF_VALID_UUID_1)
+	exists, err = dbTest.Exists(testCtx, "shelf", SHELF_VALID_UUID_1)
 	assert.Equal(t, err, nil)
 	assert.Equal(t, exists, true)
 }
 
 func TestCreateNewShelf(t *testing.T) {
 	EmptyTestDatabase()
-	id, err := dbTest.CreateNewShelf()
+	id, err := dbTest.CreateNewShelf(testCtx)
 	assert.Equal(t, err, nil)
 	assert.NotEqual(t, id, uuid.Nil)
 }
@@ -43,45 +43,45 @@ func TestCreateShelf(t *testing.T) {
 	EmptyTestDatabase()
 	resetShelves()
 	shelf := SHELF_1
-	err = dbTest.CreateShelf(shelf)
-	createdShelf, err := dbTest.Shelf(shelf.ID)
+	err = dbTest.CreateShelf(testCtx, shelf)
+	createdShelf, err := dbTest.Shelf(testCtx, shelf.ID)
 	assert.Equal(t, err, nil)
 	assert.Equal(t, shelf.ID, createdShelf.ID)
 
 	// item does not exist and should not be created
 	shelf.Items = append(shelf.Items, &common.ListRow{ID: ITEM_1.ID})
 	assert.Equal(t, len(shelf.Items), 1)
-	err = dbTest.CreateShelf(shelf)
+	err = dbTest.CreateShelf(testCtx, shelf)
 	assert.NotEqual(t, err, nil)
 	shelf.Items = nil
 
 	// box does not exist and should not be created
 	shelf.Boxes = append(shelf.Boxes, &common.ListRow{ID: BOX_1.ID})
 	assert.Equal(t, len(shelf.Boxes), 1)
-	err = dbTest.CreateShelf(shelf)
+	err = dbTest.CreateShelf(testCtx, shelf)
 	assert.NotEqual(t, err, nil)
 	shelf.Items = nil
 
-	err = dbTest.CreateNewItem(*ITEM_1)
+	err = dbTest.CreateNewItem(testCtx, *ITEM_1)
 	assert.Equal(t, err, nil)
-	_, err = dbTest.CreateBox(BOX_1)
+	_, err = dbTest.CreateBox(testCtx, BOX_1)
 	assert.Equal(t, err, nil)
 	shelf.Items = append(shelf.Items, &common.ListRow{ID: ITEM_1.ID})
 	shelf.Boxes = append(shelf.Boxes, &common.ListRow{ID: BOX_1.ID})
-	err = dbTest.CreateShelf(shelf)
+	err = dbTest.CreateShelf(testCtx, shelf)
 	assert.NotEqual(t, err, nil)
 
 	shelf.Items = nil
 	shelf.Boxes = nil
-	err = dbTest.CreateShelf(shelf)
-	createdShelf, err = dbTest.Shelf(shelf.ID)
+	err = dbTest.CreateShelf(testCtx, shelf)
+	createdShelf, err = dbTest.Shelf(testCtx, shelf.ID)
 	assert.Equal(t, err, nil)
 
 	assert.Equal(t, shelf.Label, createdShelf.Label)
 	assert.Equal(t, shelf.Description, createdShelf.Description)
 	assert.Equal(t, VALID_BASE64_PNG, createdShelf.Picture)
 	assert.NotEqual(t, "", createdShelf.PreviewPicture)
-	assert.Equal(t, shelf.QRCode, createdShelf.QRCode)
+	assert.Equal(t, isPayload(createdShelf.QRCode), true)
 	assert.Equal(t, shelf.Height, createdShelf.Height)
 	assert.Equal(t, shelf.Width, createdShelf.Width)
 	assert.Equal(t, shelf.Depth, createdShelf.Depth)
@@ -92,14 +92,13 @@ func TestCreateShelf(t *testing.T) {
 	resetShelves()
 	shelf.Picture = INVALID_BASE64_PNG
 
-	// Expected error log converting picture
-	// but NO error returned!
-	err = dbTest.CreateShelf(shelf)
-	assert.Equal(t, err, nil)
-	assert.NotEqual(t, uuid.Nil, shelf.ID)
+	// A picture that can't be stored fails the request instead of being dropped.
+	err = dbTest.CreateShelf(testCtx, shelf)
+	assert.NotEqual(t, err, nil)
 
-	createdShelf, err = dbTest.Shelf(shelf.ID)
-	assert.Equal(t, "", createdShelf.Picture)
+	exists, err := dbTest.Exists(testCtx, "shelf", shelf.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, exists, false)
 }
 
 func TestDeleteShelf(t *testing.T) {
@@ -107,17 +106,17 @@ func TestDeleteShelf(t *testing.T) {
 	resetShelves()
 	resetTestItems()
 	var err error
-	err = dbTest.CreateShelf(SHELF_1)
+	err = dbTest.CreateShelf(testCtx, SHELF_1)
 	assert.Equal(t, err, nil)
-	_, err = dbTest.DeleteShelf(SHELF_1.ID)
+	_, err = dbTest.DeleteShelf(testCtx, SHELF_1.ID)
 	assert.Equal(t, err, nil)
 
 	// should not delete shelf with an item
-	dbTest.CreateShelf(SHELF_1)
-	dbTest.CreateNewItem(*ITEM_1)
-	dbTest.MoveItemToShelf(ITEM_1.ID, SHELF_1.ID)
+	dbTest.CreateShelf(testCtx, SHELF_1)
+	dbTest.CreateNewItem(testCtx, *ITEM_1)
+	dbTest.MoveItemToShelf(testCtx, ITEM_1.ID, SHELF_1.ID)
 
-	_, err = dbTest.DeleteShelf(SHELF_1.ID)
+	_, err = dbTest.DeleteShelf(testCtx, SHELF_1.ID)
 	assert.NotEqual(t, err, nil)
 }
 
@@ -127,7 +126,7 @@ func TestUpdateShelf(t *testing.T) {
 
 	shelf := SHELF_1
 
-	err := dbTest.createNewShelf(shelf.ID)
+	err := dbTest.createNewShelf(testCtx, shelf.ID)
 	assert.Equal(t, err, nil)
 
 	shelf.Label = "Updated Label"
@@ -139,10 +138,10 @@ func TestUpdateShelf(t *testing.T) {
 	shelf.Cols = 5
 	shelf.Picture = VALID_BASE64_PNG
 
-	err = dbTest.UpdateShelf(shelf, false, "image/png")
+	err = dbTest.UpdateShelf(testCtx, shelf, false, "image/png")
 	assert.Equal(t, err, nil)
 
-	updatedShelf, err := dbTest.Shelf(shelf.ID)
+	updatedShelf, err := dbTest.Shelf(testCtx, shelf.ID)
 	assert.Equal(t, err, nil)
 
 	assert.Equal(t, "Updated Label", updatedShelf.Label)
@@ -163,34 +162,36 @@ func TestShelfListRows(t *testing.T) {
 	resetShelves()
 
 	for _, shelf := range testShelves() {
-		err := dbTest.CreateShelf(&shelf)
+		err := dbTest.CreateShelf(testCtx, &shelf)
 		if err != nil {
 			t.Fatalf("create shelf setup failed: %v", err)
 		}
 	}
 
-	shelves, err := dbTest.ShelfListRows("Shelf", 10, 1)
+	shelves, err := dbTest.ShelfListRows(testCtx, "Shelf", 10, 1)
 	assert.Equal(t, err, nil)
 	assert.Equal(t, len(shelves), 4)
 
-	shelves, err = dbTest.ShelfListRows("A", 10, 1)
+	// Two labels and three descriptions start with "A".
+	shelves, err = dbTest.ShelfListRows(testCtx, "A", 10, 1)
 	assert.Equal(t, err, nil)
-	assert.Equal(t, len(shelves), 2)
+	assert.Equal(t, len(shelves), 5)
 
-	shelves, err = dbTest.ShelfListRows("B", 10, 1)
+	shelves, err = dbTest.ShelfListRows(testCtx, "B", 10, 1)
 	assert.Equal(t, err, nil)
 	assert.Equal(t, len(shelves), 1)
 
-	shelves, err = dbTest.ShelfListRows("Test", 10, 1)
+	shelves, err = dbTest.ShelfListRows(testCtx, "Test", 10, 1)
 	assert.Equal(t, err, nil)
 	assert.Equal(t, len(shelves), 3)
 
-	shelves, err = dbTest.ShelfListRows("Shelf A", 2, 1)
+	// Both words are in the label of SHELF_5, it ranks above the matches in descriptions.
+	shelves, err = dbTest.ShelfListRows(testCtx, "Shelf A", 2, 1)
 	assert.Equal(t, err, nil)
-	assert.Equal(t, len(shelves), 1)
+	assert.Equal(t, len(shelves), 2)
 	assert.Equal(t, shelves[0].ID, SHELF_5.ID)
 
-	shelves, err = dbTest.ShelfListRows("", 10, 1)
+	shelves, err = dbTest.ShelfListRows(testCtx, "", 10, 1)
 	assert.Equal(t, err, nil)
 	assert.Equal(t, len(shelves), 6)
 }
@@ -202,43 +203,43 @@ func TestMoveItemToShelf(t *testing.T) {
 
 	item := ITEM_1
 
-	err := dbTest.CreateNewItem(*item)
+	err := dbTest.CreateNewItem(testCtx, *item)
 	assert.Equal(t, err, nil)
 
 	// Create a test shelf
 	shelf := SHELF_1
-	err = dbTest.CreateShelf(shelf)
+	err = dbTest.CreateShelf(testCtx, shelf)
 	assert.Equal(t, err, nil)
 
 	// Move the item to the shelf
-	err = dbTest.MoveItemToShelf(item.ID, shelf.ID)
+	err = dbTest.MoveItemToShelf(testCtx, item.ID, shelf.ID)
 	assert.Equal(t, err, nil)
 
 	// Verify item is associated with the shelf
-	updatedItem, err := dbTest.ItemListRowByID(item.ID)
+	updatedItem, err := dbTest.ItemListRowByID(testCtx, item.ID)
 	assert.Equal(t, err, nil)
 	assert.Equal(t, shelf.ID, updatedItem.ShelfID)
 
 	// Move the item out of the shelf
-	err = dbTest.MoveItemToShelf(item.ID, uuid.Nil)
+	err = dbTest.MoveItemToShelf(testCtx, item.ID, uuid.Nil)
 	assert.Equal(t, err, nil)
-	updatedItem, err = dbTest.ItemListRowByID(item.ID)
+	updatedItem, err = dbTest.ItemListRowByID(testCtx, item.ID)
 	assert.Equal(t, err, nil)
 	assert.Equal(t, uuid.Nil, updatedItem.ShelfID)
 
 	// Attempt to move a non-existent item
-	err = dbTest.MoveItemToShelf(VALID_UUID_NOT_EXISTING, shelf.ID)
+	err = dbTest.MoveItemToShelf(testCtx, VALID_UUID_NOT_EXISTING, shelf.ID)
 	// logg.Err(err)
 	assert.NotEqual(t, err, nil)
 
 	// Attempt to move the item to a non-existent shelf
-	err = dbTest.MoveItemToShelf(item.ID, VALID_UUID_NOT_EXISTING)
+	err = dbTest.MoveItemToShelf(testCtx, item.ID, VALID_UUID_NOT_EXISTING)
 	assert.NotEqual(t, err, nil)
 
 	// Move item to itself (makes no sense do to so)
-	err = dbTest.MoveItemToBox(item.ID, item.ID)
+	err = dbTest.MoveItemToBox(testCtx, item.ID, item.ID)
 	assert.NotEqual(t, err, nil)
-	err = dbTest.MoveItemToShelf(item.ID, item.ID)
+	err = dbTest.MoveItemToShelf(testCtx, item.ID, item.ID)
 	assert.NotEqual(t, err, nil)
 }
 
@@ -0,0 +1,123 @@
+package database
+
+import (
+	"basement/main/internal/shopping"
+	"basement/main/internal/stock"
+	"errors"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/gofrs/uuid/v5"
+)
+
+func TestShoppingEntries(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+	resetTestBoxes()
+
+	item := *ITEM_1
+	item.Quantity = 4
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item), nil)
+	_, err := dbTest.CreateBox(testCtx, BOX_1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, dbTest.MoveItemToBox(testCtx, item.ID, BOX_1.ID), nil)
+
+	milk, err := dbTest.AddShoppingEntry(testCtx, shopping.Entry{Label: "Milk", Quantity: 2, Note: "lactose free"})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, milk.ItemID, uuid.Nil)
+	assert.Equal(t, milk.Place, (*shopping.Place)(nil))
+
+	// entries of items get the label and the place of the item
+	entry, err := dbTest.AddShoppingEntry(testCtx, shopping.Entry{ItemID: item.ID, Quantity: 1})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, entry.Label, item.Label)
+	assert.Equal(t, *entry.Place, shopping.Place{Thing: "box", ID: BOX_1.ID, Label: BOX_1.Label})
+
+	// adding the same item again adds to its entry
+	again, err := dbTest.AddShoppingEntry(testCtx, shopping.Entry{ItemID: item.ID, Quantity: 3})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, again.ID, entry.ID)
+	assert.Equal(t, again.Quantity, int64(4))
+
+	_, err = dbTest.AddShoppingEntry(testCtx, shopping.Entry{ItemID: ITEM_2.ID, Quantity: 1})
+	assert.NotEqual(t, err, nil)
+
+	entries, err := dbTest.ShoppingEntries(testCtx)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(entries), 2)
+	assert.Equal(t, entries[0].Label, "Milk")
+	assert.Equal(t, entries[0].Note, "lactose free")
+
+	assert.Equal(t, dbTest.DeleteShoppingEntry(testCtx, milk.ID), nil)
+	assert.NotEqual(t, dbTest.DeleteShoppingEntry(testCtx, milk.ID), nil)
+}
+
+func TestCheckShoppingEntry(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+
+	item := *ITEM_1
+	item.Quantity = 5
+	item.MinStock = 4
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item), nil)
+
+	// consuming below the minimum stock puts the missing quantity on the list
+	_, err := dbTest.ChangeStock(testCtx, item.ID, -4, "")
+	assert.Equal(t, err, nil)
+	entries, err := dbTest.ShoppingEntries(testCtx)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(entries), 1)
+	assert.Equal(t, entries[0].ItemID, item.ID)
+	assert.Equal(t, entries[0].Quantity, int64(3))
+	assert.Equal(t, entries[0].Generated, true)
+
+	// generating again doesn't add the item twice
+	added, err := dbTest.GenerateShoppingEntries(testCtx)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, added, 0)
+
+	checked, change, err := dbTest.CheckShoppingEntry(testCtx, entries[0].ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, checked.ID, entries[0].ID)
+	assert.Equal(t, change.Quantity, int64(4))
+	assert.Equal(t, change.Reason, stock.REASON_BOUGHT)
+
+	// a double submit doesn't add the stock twice
+	_, _, err = dbTest.CheckShoppingEntry(testCtx, entries[0].ID)
+	assert.Equal(t, errors.Is(err, ErrNotExist), true)
+
+	saved, err := dbTest.ItemById(testCtx, item.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, saved.Quantity, int64(4))
+	entries, err = dbTest.ShoppingEntries(testCtx)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(entries), 0)
+
+	// entries without an item only leave the list
+	bread, err := dbTest.AddShoppingEntry(testCtx, shopping.Entry{Label: "Bread", Quantity: 1})
+	assert.Equal(t, err, nil)
+	_, change, err = dbTest.CheckShoppingEntry(testCtx, bread.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, change, (*stock.Change)(nil))
+	_, _, err = dbTest.CheckShoppingEntry(testCtx, bread.ID)
+	assert.NotEqual(t, err, nil)
+}
+
+func TestPurgeRemovesShoppingEntries(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+
+	item := *ITEM_1
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item), nil)
+	_, err := dbTest.AddShoppingEntry(testCtx, shopping.Entry{ItemID: item.ID, Quantity: 1})
+	assert.Equal(t, err, nil)
+	_, err = dbTest.AddShoppingEntry(testCtx, shopping.Entry{Label: "Milk", Quantity: 1})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, dbTest.DeleteItem(testCtx, item.ID), nil)
+	assert.Equal(t, dbTest.Purge(testCtx, "item", item.ID), nil)
+
+	entries, err := dbTest.ShoppingEntries(testCtx)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(entries), 1)
+	assert.Equal(t, entries[0].Label, "Milk")
+}
@@ -0,0 +1,103 @@
+package database
+
+import (
+	"basement/main/internal/stock"
+	"errors"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestChangeStock(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+
+	item := *ITEM_1
+	item.Quantity = 5
+	item.MinStock = 3
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item), nil)
+
+	change, err := dbTest.ChangeStock(testCtx, item.ID, -3, "Dinner")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, change.Quantity, int64(2))
+	assert.Equal(t, change.MinStock, int64(3))
+	assert.Equal(t, change.Below(), true)
+
+	change, err = dbTest.ChangeStock(testCtx, item.ID, 4, "Groceries")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, change.Quantity, int64(6))
+	assert.Equal(t, change.Below(), false)
+
+	// the quantity never drops below 0
+	_, err = dbTest.ChangeStock(testCtx, item.ID, -7, "Party")
+	assert.Equal(t, errors.Is(err, stock.ErrNotEnoughStock), true)
+
+	item.Quantity = 4
+	assert.Equal(t, dbTest.UpdateItem(testCtx, item, true, ""), nil)
+
+	saved, err := dbTest.ItemById(testCtx, item.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, saved.Quantity, int64(4))
+
+	changes, err := dbTest.StockChanges(testCtx, item.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(changes), 4)
+	reasons := []string{stock.REASON_CREATED, "Dinner", "Groceries", stock.REASON_EDITED}
+	deltas := []int64{5, -3, 4, -2}
+	for i, c := range changes {
+		assert.Equal(t, c.Reason, reasons[i])
+		assert.Equal(t, c.Delta, deltas[i])
+		assert.Equal(t, c.ActorID, TEST_OWNER_ID)
+	}
+}
+
+func TestLowStockList(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+
+	empty := *ITEM_1
+	empty.Quantity = 0
+	empty.MinStock = 2
+	low := *ITEM_2
+	low.Quantity = 3
+	low.MinStock = 4
+	enough := *ITEM_3
+	enough.Quantity = 5
+	enough.MinStock = 5
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, empty), nil)
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, low), nil)
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, enough), nil)
+
+	count, err := dbTest.LowStockListCounter(testCtx, "")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, 2)
+
+	rows, err := dbTest.LowStockListRows(testCtx, "", 10, 1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(rows), 2)
+	assert.Equal(t, rows[0].ID, empty.ID)
+	assert.Equal(t, string(rows[0].Snippet), "0 left, minimum 2")
+	assert.Equal(t, string(rows[1].Snippet), "3 left, minimum 4")
+
+	_, err = dbTest.ChangeStock(testCtx, low.ID, 1, "")
+	assert.Equal(t, err, nil)
+	count, err = dbTest.LowStockListCounter(testCtx, "")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, 1)
+}
+
+func TestPurgeRemovesStockChanges(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+
+	item := *ITEM_1
+	item.Quantity = 2
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item), nil)
+	assert.Equal(t, dbTest.DeleteItem(testCtx, item.ID), nil)
+	assert.Equal(t, dbTest.Purge(testCtx, "item", item.ID), nil)
+
+	var count int
+	err := dbTest.Sql.QueryRow(`SELECT COUNT(*) FROM stock_change;`).Scan(&count)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, 0)
+}
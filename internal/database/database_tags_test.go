@@ -0,0 +1,191 @@
+package database
+
+import (
+	"basement/main/internal/households"
+	"basement/main/internal/tags"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/gofrs/uuid/v5"
+)
+
+func TestSetTags(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+
+	item := *ITEM_1
+	item.Tags = []string{"Camping", "winter"}
+	err := dbTest.CreateNewItem(testCtx, item)
+	assert.Equal(t, err, nil)
+
+	saved, err := dbTest.ItemById(testCtx, item.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, saved.Tags, []string{"Camping", "winter"})
+
+	// nil keeps the current tags
+	item.Tags = nil
+	err = dbTest.UpdateItem(testCtx, item, true, "")
+	assert.Equal(t, err, nil)
+	saved, err = dbTest.ItemById(testCtx, item.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, saved.Tags, []string{"Camping", "winter"})
+
+	// existing tags are reused ignoring case
+	item.Tags = []string{"camping", "Tools"}
+	err = dbTest.UpdateItem(testCtx, item, true, "")
+	assert.Equal(t, err, nil)
+	saved, err = dbTest.ItemById(testCtx, item.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, saved.Tags, []string{"Camping", "Tools"})
+
+	list, err := dbTest.Tags(testCtx)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(list), 3)
+	assert.Equal(t, list[0].Name, "Camping")
+	assert.Equal(t, list[0].Count, 1)
+	assert.Equal(t, list[2].Name, "winter")
+	assert.Equal(t, list[2].Count, 0)
+
+	item.Tags = []string{}
+	err = dbTest.UpdateItem(testCtx, item, true, "")
+	assert.Equal(t, err, nil)
+	saved, err = dbTest.ItemById(testCtx, item.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(saved.Tags), 0)
+}
+
+func TestTagSearchFilter(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+	resetTestBoxes()
+
+	item1 := *ITEM_1
+	item1.Tags = []string{"Winter Tires"}
+	item2 := *ITEM_2
+	item2.Tags = []string{"camping"}
+	box := *BOX_1
+	box.Tags = []string{"camping"}
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item1), nil)
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item2), nil)
+	_, err := dbTest.CreateBox(testCtx, &box)
+	assert.Equal(t, err, nil)
+
+	rows, err := dbTest.ItemListRows(testCtx, `tag:"winter tires"`, 10, 1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(rows), 1)
+	assert.Equal(t, rows[0].ID, item1.ID)
+	assert.Equal(t, rows[0].Tags, []string{"Winter Tires"})
+
+	// only whole names match
+	rows, err = dbTest.ItemListRows(testCtx, "tag:winter", 10, 1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(rows), 0)
+
+	rows, err = dbTest.ItemListRows(testCtx, "-tag:camping", 10, 1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(rows), 1)
+	assert.Equal(t, rows[0].ID, item1.ID)
+
+	count, err := dbTest.ItemListCounter(testCtx, "tag:camping")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, 1)
+
+	boxRows, err := dbTest.BoxListRows(testCtx, "tag:CAMPING", 10, 1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(boxRows), 1)
+	assert.Equal(t, boxRows[0].Tags, []string{"camping"})
+
+	results, err := dbTest.Search(testCtx, "tag:camping", 10)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, results.Total, 2)
+}
+
+func TestRenameAndDeleteTag(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+
+	item := *ITEM_1
+	item.Tags = []string{"camping"}
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item), nil)
+
+	id, err := dbTest.CreateTag(testCtx, "Tools")
+	assert.Equal(t, err, nil)
+	_, err = dbTest.CreateTag(testCtx, "tools")
+	assert.Equal(t, errors.Is(err, tags.ErrTagExists), true)
+
+	list, err := dbTest.Tags(testCtx)
+	assert.Equal(t, err, nil)
+	campingID := list[0].ID
+
+	err = dbTest.RenameTag(testCtx, campingID, "TOOLS")
+	assert.Equal(t, errors.Is(err, tags.ErrTagExists), true)
+	err = dbTest.RenameTag(testCtx, campingID, "Outdoor")
+	assert.Equal(t, err, nil)
+
+	saved, err := dbTest.ItemById(testCtx, item.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, saved.Tags, []string{"Outdoor"})
+
+	things, err := dbTest.TaggedThings(testCtx, campingID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, things, []tags.TaggedThing{{Thing: "item", ID: item.ID, Label: item.Label}})
+
+	err = dbTest.DeleteTag(testCtx, campingID)
+	assert.Equal(t, err, nil)
+	saved, err = dbTest.ItemById(testCtx, item.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(saved.Tags), 0)
+
+	err = dbTest.DeleteTag(testCtx, campingID)
+	assert.Equal(t, errors.Is(err, ErrNotExist), true)
+	err = dbTest.DeleteTag(testCtx, id)
+	assert.Equal(t, err, nil)
+}
+
+func TestTagsOfOtherHousehold(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+	otherOwner := uuid.Must(uuid.FromString("923e4567-e89b-12d3-a456-426614174002"))
+	otherCtx := households.WithMembership(context.Background(), households.Membership{HouseholdID: otherOwner, UserID: otherOwner, Role: households.ROLE_OWNER})
+
+	item := *ITEM_1
+	item.Tags = []string{"camping"}
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item), nil)
+
+	list, err := dbTest.Tags(otherCtx)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(list), 0)
+
+	// the same name is a different tag in another household
+	_, err = dbTest.CreateTag(otherCtx, "camping")
+	assert.Equal(t, err, nil)
+
+	mine, err := dbTest.Tags(testCtx)
+	assert.Equal(t, err, nil)
+	err = dbTest.RenameTag(otherCtx, mine[0].ID, "mine")
+	assert.Equal(t, errors.Is(err, ErrNotExist), true)
+	err = dbTest.DeleteTag(otherCtx, mine[0].ID)
+	assert.Equal(t, errors.Is(err, ErrNotExist), true)
+}
+
+func TestPurgeRemovesTags(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+
+	item := *ITEM_1
+	item.Tags = []string{"camping"}
+	assert.Equal(t, dbTest.CreateNewItem(testCtx, item), nil)
+	assert.Equal(t, dbTest.DeleteItem(testCtx, item.ID), nil)
+
+	list, err := dbTest.Tags(testCtx)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, list[0].Count, 0)
+
+	assert.Equal(t, dbTest.Purge(testCtx, "item", item.ID), nil)
+	var count int
+	err = dbTest.Sql.QueryRow(`SELECT COUNT(*) FROM thing_tag;`).Scan(&count)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, 0)
+}
@@ -0,0 +1,174 @@
+package database
+
+import (
+	"basement/main/internal/history"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestTrashRestore(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+	resetTestBoxes()
+	ctx := historyTestCtx()
+
+	err := dbTest.CreateNewItem(ctx, *ITEM_1)
+	assert.Equal(t, err, nil)
+	_, err = dbTest.CreateBox(ctx, BOX_1)
+	assert.Equal(t, err, nil)
+	err = dbTest.MoveItemToBox(ctx, ITEM_1.ID, BOX_1.ID)
+	assert.Equal(t, err, nil)
+
+	err = dbTest.DeleteItem(ctx, ITEM_1.ID)
+	assert.Equal(t, err, nil)
+
+	// Trashed things are hidden everywhere except in the trash.
+	assert.Equal(t, dbTest.ItemExist(ctx, "id", ITEM_1.ID.String()), false)
+	count, err := dbTest.ItemListCounter(ctx, "")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, 0)
+	_, err = dbTest.ItemById(ctx, ITEM_1.ID)
+	assert.NotEqual(t, err, nil)
+
+	things, err := dbTest.TrashedThings(ctx)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(things), 1)
+	assert.Equal(t, things[0].ID, ITEM_1.ID)
+	assert.Equal(t, things[0].Thing, "item")
+	assert.Equal(t, things[0].Location, `box "`+BOX_1.Label+`"`)
+
+	// Other households can't restore it.
+	err = dbTest.Restore(historyTestCtx(), "item", ITEM_1.ID)
+	assert.NotEqual(t, err, nil)
+
+	err = dbTest.Restore(ctx, "item", ITEM_1.ID)
+	assert.Equal(t, err, nil)
+	item, err := dbTest.ItemById(ctx, ITEM_1.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, item.BoxID, BOX_1.ID)
+	count, err = dbTest.ItemListCounter(ctx, "")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, 1)
+
+	things, err = dbTest.TrashedThings(ctx)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(things), 0)
+
+	entries, err := dbTest.History(ctx, "item", ITEM_1.ID, 1, 1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, entries[0].Action, history.ACTION_RESTORE)
+	assert.Equal(t, entries[0].To.ID, BOX_1.ID)
+}
+
+func TestTrashRestoreWithoutLocation(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+	resetTestBoxes()
+	ctx := historyTestCtx()
+
+	err := dbTest.CreateNewItem(ctx, *ITEM_1)
+	assert.Equal(t, err, nil)
+	_, err = dbTest.CreateBox(ctx, BOX_1)
+	assert.Equal(t, err, nil)
+	err = dbTest.MoveItemToBox(ctx, ITEM_1.ID, BOX_1.ID)
+	assert.Equal(t, err, nil)
+
+	err = dbTest.DeleteItem(ctx, ITEM_1.ID)
+	assert.Equal(t, err, nil)
+	err = dbTest.DeleteBox(ctx, BOX_1.ID)
+	assert.Equal(t, err, nil)
+	err = dbTest.Purge(ctx, "box", BOX_1.ID)
+	assert.Equal(t, err, nil)
+
+	// The box is gone, the item is restored without a location.
+	err = dbTest.Restore(ctx, "item", ITEM_1.ID)
+	assert.Equal(t, err, nil)
+	item, err := dbTest.ItemById(ctx, ITEM_1.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, item.BoxID.IsNil(), true)
+}
+
+func TestTrashPurge(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+	ctx := historyTestCtx()
+
+	err := dbTest.CreateNewItem(ctx, *ITEM_1)
+	assert.Equal(t, err, nil)
+
+	// Only things in the trash can be purged.
+	err = dbTest.Purge(ctx, "item", ITEM_1.ID)
+	assert.NotEqual(t, err, nil)
+
+	err = dbTest.DeleteItem(ctx, ITEM_1.ID)
+	assert.Equal(t, err, nil)
+	err = dbTest.Purge(ctx, "item", ITEM_1.ID)
+	assert.Equal(t, err, nil)
+
+	things, err := dbTest.TrashedThings(ctx)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(things), 0)
+	err = dbTest.Restore(ctx, "item", ITEM_1.ID)
+	assert.NotEqual(t, err, nil)
+
+	entries, err := dbTest.History(ctx, "item", ITEM_1.ID, 1, 1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, entries[0].Action, history.ACTION_PURGE)
+}
+
+func TestPurgeExpiredTrash(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+	ctx := historyTestCtx()
+
+	err := dbTest.CreateNewItem(ctx, *ITEM_1)
+	assert.Equal(t, err, nil)
+	err = dbTest.DeleteItem(ctx, ITEM_1.ID)
+	assert.Equal(t, err, nil)
+
+	purged, err := dbTest.PurgeExpiredTrash(time.Now().Add(-time.Hour))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, purged, int64(0))
+
+	purged, err = dbTest.PurgeExpiredTrash(time.Now().Add(time.Hour))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, purged, int64(1))
+
+	things, err := dbTest.TrashedThings(ctx)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(things), 0)
+}
+
+func TestPurgeClearsContents(t *testing.T) {
+	EmptyTestDatabase()
+	resetTestItems()
+	resetTestBoxes()
+	ctx := historyTestCtx()
+
+	err := dbTest.CreateNewItem(ctx, *ITEM_1)
+	assert.Equal(t, err, nil)
+	_, err = dbTest.CreateBox(ctx, BOX_1)
+	assert.Equal(t, err, nil)
+	err = dbTest.MoveItemToBox(ctx, ITEM_1.ID, BOX_1.ID)
+	assert.Equal(t, err, nil)
+
+	// The box goes to the trash with the item still in it.
+	_, err = dbTest.Sql.Exec(`UPDATE box SET `+DELETED_AT+` = ? WHERE id = ?;`, time.Now().UTC().Format(time.RFC3339), BOX_1.ID.String())
+	assert.Equal(t, err, nil)
+	err = dbTest.Purge(ctx, "box", BOX_1.ID)
+	assert.Equal(t, err, nil)
+
+	item, err := dbTest.ItemById(ctx, ITEM_1.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, item.BoxID.IsNil(), true)
+	row, err := dbTest.listRowByID(ctx, "item_fts", ITEM_1.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, row.BoxLabel, "")
+
+	entries, err := dbTest.History(ctx, "item", ITEM_1.ID, 1, 1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, entries[0].From.ID, BOX_1.ID)
+	assert.Equal(t, entries[0].To.Empty(), true)
+}
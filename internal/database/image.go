@@ -1,24 +1,34 @@
 package database
 
 import (
+	"basement/main/internal/env"
 	"basement/main/internal/logg"
 	"bytes"
 	"encoding/base64"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"image"
+	_ "image/gif"
 	"image/jpeg"
 	"image/png"
 	"io"
 	"math"
+	"net/http"
 
 	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
 )
 
 var UnsupportedImageFormat error = errors.New("Unsupported picture format")
+var ErrPictureTooLarge = errors.New("picture has too many pixels")
 
-// updatePicture checks for valid base64 encoding and creates a resized preview image
-// in `previewPicture` with max side length of 50 pixel.
+// MAX_PICTURE_PIXELS limits the width times height of pictures that are decoded.
+// Decoding needs 4 bytes per pixel, a small file can declare a huge picture.
+const MAX_PICTURE_PIXELS = 50_000_000
+
+// updatePicture checks for valid base64 encoding, removes the metadata of the picture
+// and creates a resized preview image in `previewPicture` with the configured preview side length.
 // In case of error the strings will be set to empty string.
 func updatePicture(picture *string, previewPicture *string) error {
 	if *picture != "" {
@@ -26,10 +36,17 @@ func updatePicture(picture *string, previewPicture *string) error {
 		if err != nil {
 			*picture = ""
 			*previewPicture = ""
-			return logg.Errorf("CreateShelf: invalid base64 in Picture %w", err)
+			return logg.Errorf("invalid base64 in picture %w", err)
 		}
 
-		*previewPicture, err = ResizePNG(*picture, 50)
+		*picture, err = stripMetadataBase64(*picture)
+		if err != nil {
+			*picture = ""
+			*previewPicture = ""
+			return logg.WrapErr(err)
+		}
+
+		*previewPicture, err = resizeBase64(*picture, env.CurrentConfig().PreviewPictureSide())
 		if err != nil {
 			*previewPicture = ""
 			return logg.WrapErr(err)
@@ -38,125 +55,208 @@ func updatePicture(picture *string, previewPicture *string) error {
 	return nil
 }
 
-// ResizePNG2 resizes image while keeping aspect ratio.
-// Longest side will fit the pixel value of `fitLongestSideToPixel`.
-func ResizePNG(input64 string, fitLongestSideToPixel int) (string, error) {
+// ResizeImage resizes the base64 encoded picture in the uploaded format, like "image/png",
+// while keeping its aspect ratio. The longest side will fit the pixel value of `fitLongestSideToPixel`.
+// An empty format removes the picture.
+func ResizeImage(input64 string, fitLongestSideToPixel int, format string) (string, error) {
+	switch format {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+		return resizeBase64(input64, fitLongestSideToPixel)
+	case "":
+		logg.Debug("remove image")
+		return "", nil
+	default:
+		return "", logg.WrapErr(fmt.Errorf("%w %s", UnsupportedImageFormat, format))
+	}
+}
+
+// resizeBase64 is ResizePicture for base64 encoded pictures.
+func resizeBase64(input64 string, fitLongestSideToPixel int) (string, error) {
 	pic, err := Base64StringToByte(input64)
 	if err != nil {
 		return "", logg.WrapErr(err)
 	}
 
-	picreader := bytes.NewReader(pic)
-	var output []byte
-	buf := bytes.NewBuffer(output)
-
-	err = ResizePNG2(picreader, buf, fitLongestSideToPixel)
+	var buf bytes.Buffer
+	err = ResizePicture(bytes.NewReader(pic), &buf, fitLongestSideToPixel)
 	if err != nil {
 		return "", logg.WrapErr(err)
 	}
 	return ByteToBase64String(buf.Bytes()), nil
 }
 
-// ResizePNG2 resizes image while keeping aspect ratio.
-// Longest side will fit the pixel value of `fitLongestSideToPixel`.
-func ResizePNG2(input io.Reader, output io.Writer, fitLongestSideToPixel int) error {
-	// Decode the image (from PNG to image.Image):
-	src, err := png.Decode(input)
+// ResizePicture reads a PNG, JPEG, GIF or WebP picture from `input`, turns it upright
+// according to its EXIF orientation and scales it down while keeping its aspect ratio.
+// The longest side will fit the pixel value of `fitLongestSideToPixel`, smaller pictures keep their size.
+// PNG pictures and pictures with transparency are written as PNG, all others as JPEG
+// with the configured quality.
+func ResizePicture(input io.Reader, output io.Writer, fitLongestSideToPixel int) error {
+	pic, err := io.ReadAll(input)
 	if err != nil {
 		return logg.WrapErr(err)
 	}
-
-	// Set the expected size that you want:
-	var scale float64
-	var newX, newY int
-	if src.Bounds().Max.X > src.Bounds().Max.Y {
-		scale = float64(src.Bounds().Max.X) / float64(fitLongestSideToPixel)
-	} else {
-		scale = float64(src.Bounds().Max.Y) / float64(fitLongestSideToPixel)
+	config, _, err := image.DecodeConfig(bytes.NewReader(pic))
+	if errors.Is(err, image.ErrFormat) {
+		return logg.WrapErr(fmt.Errorf("%w %s", UnsupportedImageFormat, http.DetectContentType(pic)))
+	}
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	if tooManyPixels(config) {
+		return logg.Errorf("%d x %d %w, at most %d are allowed", config.Width, config.Height, ErrPictureTooLarge, MAX_PICTURE_PIXELS)
+	}
+	src, format, err := image.Decode(bytes.NewReader(pic))
+	if err != nil {
+		return logg.WrapErr(err)
 	}
-	newX = int(math.Round(float64(src.Bounds().Max.X) / scale))
-	newY = int(math.Round(float64(src.Bounds().Max.Y) / scale))
-
-	dst := image.NewRGBA(image.Rect(0, 0, newX, newY))
 
-	// Resize:
-	draw.NearestNeighbor.Scale(dst, dst.Rect, src, src.Bounds(), draw.Over, nil)
+	dst := orient(scaleDown(src, fitLongestSideToPixel), exifOrientation(pic))
 
-	// Encode to `output`:
-	err = png.Encode(output, dst)
+	if format == "png" || !dst.Opaque() {
+		err = png.Encode(output, dst)
+	} else {
+		err = jpeg.Encode(output, dst, &jpeg.Options{Quality: env.CurrentConfig().PictureQuality()})
+	}
 	if err != nil {
 		return logg.WrapErr(err)
 	}
-
 	return nil
 }
 
-func ResizeImage(input64 string, fitLongestSideToPixel int, format string) (string, error) {
-	switch format {
-	case "image/jpeg":
-		return ResizeJPEG(input64, 50)
-	case "image/png":
-		return ResizePNG(input64, fitLongestSideToPixel)
-	case "":
-		logg.Debug("remove image")
-		return "", nil
-	default:
-		// logg.Warningf("%s", UnsupportedImageFormat.Error())
-		return "", logg.WrapErr(fmt.Errorf("%w %s", UnsupportedImageFormat, format))
-	}
+// tooManyPixels returns true if the picture of config has more than MAX_PICTURE_PIXELS.
+func tooManyPixels(config image.Config) bool {
+	return int64(config.Width)*int64(config.Height) > MAX_PICTURE_PIXELS
 }
 
-// ResizeJPEG resizes a JPEG image while keeping its aspect ratio.
-// The longest side will fit the pixel value of `fitLongestSideToPixel`.
-func ResizeJPEG(input64 string, fitLongestSideToPixel int) (string, error) {
-	pic, err := Base64StringToByte(input64)
-	if err != nil {
-		return "", logg.WrapErr(err)
+// scaleDown returns src scaled with a Catmull-Rom kernel so its longest side fits `fitLongestSideToPixel`.
+// Smaller pictures keep their size.
+func scaleDown(src image.Image, fitLongestSideToPixel int) *image.RGBA {
+	width, height := src.Bounds().Dx(), src.Bounds().Dy()
+	scale := float64(max(width, height)) / float64(fitLongestSideToPixel)
+	if scale > 1 {
+		width = max(1, int(math.Round(float64(width)/scale)))
+		height = max(1, int(math.Round(float64(height)/scale)))
 	}
 
-	picreader := bytes.NewReader(pic)
-	var output []byte
-	buf := bytes.NewBuffer(output)
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Rect, src, src.Bounds(), draw.Src, nil)
+	return dst
+}
 
-	err = ResizeJPEG2(picreader, buf, fitLongestSideToPixel)
-	if err != nil {
-		return "", logg.WrapErr(err)
+// orient returns src turned and mirrored so it is upright for the EXIF orientation from 1 to 8.
+// Other values return src unchanged.
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return src
 	}
-	return ByteToBase64String(buf.Bytes()), nil
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	if orientation >= 5 {
+		// Orientations 5 to 8 swap width and height.
+		dst = image.NewRGBA(image.Rect(0, 0, h, w))
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirror horizontally
+				dx, dy = w-1-x, y
+			case 3: // rotate by 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirror vertically
+				dx, dy = x, h-1-y
+			case 5: // mirror along the top left to bottom right diagonal
+				dx, dy = y, x
+			case 6: // rotate clockwise
+				dx, dy = h-1-y, x
+			case 7: // mirror along the top right to bottom left diagonal
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotate counterclockwise
+				dx, dy = y, w-1-x
+			}
+			dst.SetRGBA(dx, dy, src.RGBAAt(src.Rect.Min.X+x, src.Rect.Min.Y+y))
+		}
+	}
+	return dst
 }
 
-// ResizeJPEG2 resizes a JPEG image (read from `input`) while keeping its aspect ratio.
-// The longest side will fit the pixel value of `fitLongestSideToPixel`.
-func ResizeJPEG2(input io.Reader, output io.Writer, fitLongestSideToPixel int) error {
-	// Decode the image (from JPEG to image.Image):
-	src, err := jpeg.Decode(input)
-	if err != nil {
-		return logg.WrapErr(err)
+// exifOrientation returns the EXIF orientation from 1 to 8 of a JPEG, PNG or WebP picture
+// or 0 if it has none.
+func exifOrientation(pic []byte) int {
+	tiff := exifData(pic)
+	if len(tiff) < 8 {
+		return 0
 	}
-
-	// Calculate scale and new dimensions:
-	var scale float64
-	var newX, newY int
-	if src.Bounds().Max.X > src.Bounds().Max.Y {
-		scale = float64(src.Bounds().Max.X) / float64(fitLongestSideToPixel)
-	} else {
-		scale = float64(src.Bounds().Max.Y) / float64(fitLongestSideToPixel)
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
 	}
-	newX = int(math.Round(float64(src.Bounds().Max.X) / scale))
-	newY = int(math.Round(float64(src.Bounds().Max.Y) / scale))
-
-	dst := image.NewRGBA(image.Rect(0, 0, newX, newY))
-
-	// Resize:
-	draw.NearestNeighbor.Scale(dst, dst.Rect, src, src.Bounds(), draw.Over, nil)
-
-	// Encode to `output` in JPEG format:
-	// You can pass &jpeg.Options{Quality: 80} (or another value) for quality customization.
-	err = jpeg.Encode(output, dst, nil)
-	if err != nil {
-		return logg.WrapErr(err)
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 0
 	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		const orientationTag = 0x0112
+		if order.Uint16(tiff[entry:]) == orientationTag {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+	return 0
+}
 
+// exifData returns the TIFF structure holding the EXIF data of a JPEG, PNG or WebP picture or nil if it has none.
+func exifData(pic []byte) []byte {
+	switch {
+	case bytes.HasPrefix(pic, []byte{0xFF, 0xD8}):
+		// JPEG segments start with a marker and their length, EXIF is in the APP1 segment.
+		for i := 2; i+4 <= len(pic) && pic[i] == 0xFF; {
+			marker := pic[i+1]
+			length := int(binary.BigEndian.Uint16(pic[i+2:]))
+			if marker == 0xDA || length < 2 || i+2+length > len(pic) {
+				// The image data starts or the segment is broken, there are no more segments.
+				return nil
+			}
+			segment := pic[i+4 : i+2+length]
+			if marker == 0xE1 && bytes.HasPrefix(segment, exifHeader) {
+				return segment[len(exifHeader):]
+			}
+			i += 2 + length
+		}
+	case bytes.HasPrefix(pic, pngSignature):
+		// PNG chunks start with their length and name and end with a checksum.
+		for i := len(pngSignature); i+12 <= len(pic); {
+			length := int(binary.BigEndian.Uint32(pic[i:]))
+			if i+12+length > len(pic) {
+				return nil
+			}
+			if string(pic[i+4:i+8]) == "eXIf" {
+				return bytes.TrimPrefix(pic[i+8:i+8+length], exifHeader)
+			}
+			i += 12 + length
+		}
+	case len(pic) >= 12 && string(pic[:4]) == "RIFF" && string(pic[8:12]) == "WEBP":
+		// WebP chunks start with their name and length and are padded to an even length.
+		for i := 12; i+8 <= len(pic); {
+			length := int(binary.LittleEndian.Uint32(pic[i+4:]))
+			if length < 0 || i+8+length > len(pic) {
+				return nil
+			}
+			if string(pic[i:i+4]) == "EXIF" {
+				return bytes.TrimPrefix(pic[i+8:i+8+length], exifHeader)
+			}
+			i += 8 + length + length%2
+		}
+	}
 	return nil
 }
 
@@ -0,0 +1,266 @@
+package database
+
+import (
+	"basement/main/internal/logg"
+	"bytes"
+	"database/sql"
+	"encoding/binary"
+	"hash/crc32"
+	"os"
+)
+
+// exifHeader starts the EXIF data in JPEG segments and sometimes in WebP chunks.
+var exifHeader = []byte("Exif\x00\x00")
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// iccHeader starts the APP2 segments of JPEG pictures that hold a color profile.
+// Other APP2 segments, like MPF, can hold more pictures with their own metadata.
+var iccHeader = []byte("ICC_PROFILE\x00")
+
+// stripMetadata returns the picture without its EXIF, XMP, IPTC and text metadata,
+// which can hold GPS coordinates, the camera and the owner.
+// Only the EXIF orientation is kept, so the picture is still shown upright, and the pixels aren't touched.
+// JPEG, PNG and WebP pictures are stripped, others are returned unchanged.
+func stripMetadata(pic []byte) ([]byte, error) {
+	orientation := exifOrientation(pic)
+	switch {
+	case bytes.HasPrefix(pic, []byte{0xFF, 0xD8}):
+		return stripJPEG(pic, orientation)
+	case bytes.HasPrefix(pic, pngSignature):
+		return stripPNG(pic, orientation)
+	case len(pic) >= 12 && string(pic[:4]) == "RIFF" && string(pic[8:12]) == "WEBP":
+		return stripWebP(pic, orientation)
+	}
+	return pic, nil
+}
+
+// stripMetadataBase64 is stripMetadata for base64 encoded pictures.
+func stripMetadataBase64(picture string) (string, error) {
+	if picture == "" {
+		return "", nil
+	}
+	pic, err := Base64StringToByte(picture)
+	if err != nil {
+		return "", logg.Errorf("invalid base64 in picture %w", err)
+	}
+	stripped, err := stripMetadata(pic)
+	if err != nil {
+		return "", logg.WrapErr(err)
+	}
+	return ByteToBase64String(stripped), nil
+}
+
+// stripJPEG keeps the segments that are needed to show the picture: JFIF (APP0), the color profile (APP2 with iccHeader),
+// Adobe color information (APP14) and all that aren't application data or comments.
+func stripJPEG(pic []byte, orientation int) ([]byte, error) {
+	out := []byte{0xFF, 0xD8}
+	if orientation > 1 {
+		out = append(out, 0xFF, 0xE1)
+		exif := append(bytes.Clone(exifHeader), orientationTIFF(orientation)...)
+		out = binary.BigEndian.AppendUint16(out, uint16(len(exif)+2))
+		out = append(out, exif...)
+	}
+	for i := 2; ; {
+		if i+4 > len(pic) || pic[i] != 0xFF {
+			return nil, logg.NewError("broken JPEG segment")
+		}
+		marker := pic[i+1]
+		if marker == 0xFF {
+			// fill byte before a marker
+			i++
+			continue
+		}
+		if marker == 0xDA {
+			// The image data starts, there are no more segments.
+			return append(out, pic[i:]...), nil
+		}
+		length := int(binary.BigEndian.Uint16(pic[i+2:]))
+		end := i + 2 + length
+		if length < 2 || end > len(pic) {
+			return nil, logg.NewError("broken JPEG segment")
+		}
+		colorProfile := marker == 0xE2 && bytes.HasPrefix(pic[i+4:end], iccHeader)
+		application := marker >= 0xE1 && marker <= 0xEF && !colorProfile && marker != 0xEE
+		if !application && marker != 0xFE {
+			out = append(out, pic[i:end]...)
+		}
+		i = end
+	}
+}
+
+// stripPNG removes the EXIF and text chunks and the modification time.
+func stripPNG(pic []byte, orientation int) ([]byte, error) {
+	out := bytes.Clone(pngSignature)
+	for i := len(pngSignature); i < len(pic); {
+		if i+12 > len(pic) {
+			return nil, logg.NewError("broken PNG chunk")
+		}
+		end := i + 12 + int(binary.BigEndian.Uint32(pic[i:]))
+		if end > len(pic) || end < i {
+			return nil, logg.NewError("broken PNG chunk")
+		}
+		chunk := string(pic[i+4 : i+8])
+		switch chunk {
+		case "eXIf", "tEXt", "zTXt", "iTXt", "tIME":
+		default:
+			out = append(out, pic[i:end]...)
+		}
+		if chunk == "IHDR" && orientation > 1 {
+			// The EXIF chunk must come before the image data.
+			tiff := orientationTIFF(orientation)
+			out = binary.BigEndian.AppendUint32(out, uint32(len(tiff)))
+			start := len(out)
+			out = append(out, "eXIf"...)
+			out = append(out, tiff...)
+			out = binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE(out[start:]))
+		}
+		i = end
+	}
+	return out, nil
+}
+
+// stripWebP removes the EXIF and XMP chunks and their flags in the extended header.
+func stripWebP(pic []byte, orientation int) ([]byte, error) {
+	const exifFlag, xmpFlag = 0x08, 0x04
+	out := bytes.Clone(pic[:12])
+	extended := -1
+	for i := 12; i < len(pic); {
+		if i+8 > len(pic) {
+			return nil, logg.NewError("broken WebP chunk")
+		}
+		length := int(binary.LittleEndian.Uint32(pic[i+4:]))
+		end := i + 8 + length + length%2
+		if end > len(pic) || end < i {
+			return nil, logg.NewError("broken WebP chunk")
+		}
+		chunk := string(pic[i : i+4])
+		if chunk == "VP8X" && length > 0 {
+			extended = len(out) + 8
+		}
+		if chunk != "EXIF" && chunk != "XMP " {
+			out = append(out, pic[i:end]...)
+		}
+		i = end
+	}
+
+	// Only the extended format can hold EXIF data.
+	if extended >= 0 {
+		out[extended] &^= exifFlag | xmpFlag
+		if orientation > 1 {
+			out[extended] |= exifFlag
+			tiff := orientationTIFF(orientation)
+			out = append(out, "EXIF"...)
+			out = binary.LittleEndian.AppendUint32(out, uint32(len(tiff)))
+			out = append(out, tiff...)
+		}
+	}
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out, nil
+}
+
+// orientationTIFF returns EXIF data that only holds the orientation.
+func orientationTIFF(orientation int) []byte {
+	tiff := []byte("II*\x00")
+	tiff = binary.LittleEndian.AppendUint32(tiff, 8) // offset of the first directory
+	tiff = binary.LittleEndian.AppendUint16(tiff, 1) // amount of entries
+	tiff = binary.LittleEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 3) // SHORT
+	tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, uint16(orientation))
+	tiff = append(tiff, 0, 0)       // padding of the value
+	return append(tiff, 0, 0, 0, 0) // no next directory
+}
+
+// StripPictureMetadata removes the metadata from all stored pictures, including those of things in the trash.
+// A stripped picture is stored as a new blob, the old one is removed by the garbage collection
+// once no snapshot refers to it anymore. Returns the amount of stripped pictures.
+func (db *DB) StripPictureMetadata() (int, error) {
+	keys, err := pictureKeys(db.Sql)
+	if err != nil {
+		return 0, logg.WrapErr(err)
+	}
+
+	stripped := 0
+	for _, key := range keys {
+		pic, err := os.ReadFile(blobFile(key))
+		if err != nil {
+			logg.Errorf(`can't read blob "%s" %w`, key, err)
+			continue
+		}
+		clean, err := stripMetadata(pic)
+		if err != nil {
+			logg.Errorf(`can't strip the metadata of blob "%s" %w`, key, err)
+			continue
+		}
+		if bytes.Equal(clean, pic) {
+			continue
+		}
+		newKey, err := storeBlob(ByteToBase64String(clean))
+		if err != nil {
+			return stripped, logg.WrapErr(err)
+		}
+		err = db.replaceBlobKey(key, newKey)
+		if err != nil {
+			return stripped, logg.WrapErr(err)
+		}
+		stripped++
+	}
+	return stripped, nil
+}
+
+// pictureKeys returns the keys of all pictures in their original size.
+// Previews are made by the image pipeline, which doesn't write metadata.
+// Tables that don't exist yet are skipped.
+func pictureKeys(conn *sql.DB) ([]string, error) {
+	var keys []string
+	seen := map[string]bool{}
+	for table := range blobColumns {
+		exists, err := tableExists(conn, table)
+		if err != nil {
+			return nil, logg.WrapErr(err)
+		}
+		if !exists {
+			continue
+		}
+		rows, err := conn.Query(`SELECT DISTINCT ` + BASIC_INFO_PICTURE + ` FROM ` + table + ` WHERE ` + BASIC_INFO_PICTURE + ` IS NOT NULL;`)
+		if err != nil {
+			return nil, logg.Errorf(`can't read pictures of "%s" %w`, table, err)
+		}
+		for rows.Next() {
+			var key string
+			err = rows.Scan(&key)
+			if err != nil {
+				rows.Close()
+				return nil, logg.WrapErr(err)
+			}
+			if isBlobKey(key) && !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, logg.WrapErr(err)
+		}
+	}
+	return keys, nil
+}
+
+// replaceBlobKey makes all rows that refer to the blob with the old key refer to the new one.
+func (db *DB) replaceBlobKey(oldKey string, newKey string) error {
+	tx, err := db.Sql.Begin()
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	defer tx.Rollback()
+	for table, columns := range blobColumns {
+		for _, column := range columns {
+			_, err = tx.Exec(`UPDATE `+table+` SET `+column+` = ? WHERE `+column+` = ?;`, newKey, oldKey)
+			if err != nil {
+				return logg.Errorf(`can't replace blob "%s" in "%s" %w`, oldKey, table, err)
+			}
+		}
+	}
+	return tx.Commit()
+}
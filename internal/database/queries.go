@@ -10,6 +10,15 @@ const (
 	BASIC_INFO_PREVIEW_PICTURE = "preview_picture"
 	BASIC_INFO_QRCODE          = "qrcode"
 
+	// owner of an item, box, shelf or area, added in migration 1.
+	// Since migration 2 the owner is a household, every user has a personal household with the user id.
+	OWNER_ID = "owner_id"
+
+	// time an item, box, shelf or area was moved to the trash, added in migration 4.
+	// NULL for things that are not in the trash.
+	DELETED_AT  = "deleted_at"
+	NOT_DELETED = DELETED_AT + " IS NULL"
+
 	// single string with all columns of basic info which is present in every table
 	ALL_BASIC_INFO_COLS string = "" +
 		BASIC_INFO_ID + "," +
@@ -105,9 +114,186 @@ const (
     username TEXT UNIQUE,
     passwordhash TEXT);`
 
+	CREATE_HOUSEHOLD_TABLE_STMT = `CREATE TABLE household (
+    id TEXT NOT NULL PRIMARY KEY,
+    name TEXT NOT NULL);`
+
+	CREATE_HOUSEHOLD_MEMBER_TABLE_STMT = `CREATE TABLE household_member (
+    household_id TEXT NOT NULL REFERENCES household(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL,
+    role TEXT NOT NULL,
+    PRIMARY KEY (household_id, user_id));`
+
+	// history is append-only, the triggers below reject every UPDATE and DELETE.
+	CREATE_HISTORY_TABLE_STMT = `CREATE TABLE history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    owner_id TEXT NOT NULL,
+    actor_id TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    action TEXT NOT NULL,
+    thing TEXT NOT NULL,
+    thing_id TEXT NOT NULL,
+    label TEXT NOT NULL,
+    before_values TEXT,
+    after_values TEXT,
+    from_thing TEXT,
+    from_id TEXT,
+    from_label TEXT,
+    to_thing TEXT,
+    to_id TEXT,
+    to_label TEXT);`
+
+	CREATE_HISTORY_UPDATE_TRIGGER = `CREATE TRIGGER history_no_update BEFORE UPDATE ON history
+	BEGIN
+		SELECT RAISE(ABORT, 'history is append-only');
+	END;`
+
+	CREATE_HISTORY_DELETE_TRIGGER = `CREATE TRIGGER history_no_delete BEFORE DELETE ON history
+	BEGIN
+		SELECT RAISE(ABORT, 'history is append-only');
+	END;`
+
+	CREATE_MOVE_OPERATION_TABLE_STMT = `CREATE TABLE move_operation (
+    id TEXT PRIMARY KEY,
+    owner_id TEXT NOT NULL,
+    actor_id TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    undone_at TEXT);`
+
+	// The location of a thing before it was moved by a move operation.
+	CREATE_MOVE_OPERATION_THING_TABLE_STMT = `CREATE TABLE move_operation_thing (
+    operation_id TEXT NOT NULL,
+    thing TEXT NOT NULL,
+    thing_id TEXT NOT NULL,
+    box_id TEXT,
+    shelf_id TEXT,
+    area_id TEXT);`
+
+	// Tag names are unique per household, ignoring case.
+	CREATE_TAG_TABLE_STMT = `CREATE TABLE tag (
+    id TEXT NOT NULL PRIMARY KEY,
+    owner_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    UNIQUE (owner_id, name COLLATE NOCASE));`
+
+	// Assigns tags to items, boxes, shelves and areas.
+	CREATE_THING_TAG_TABLE_STMT = `CREATE TABLE thing_tag (
+    thing TEXT NOT NULL,
+    thing_id TEXT NOT NULL,
+    tag_id TEXT NOT NULL REFERENCES tag(id) ON DELETE CASCADE,
+    PRIMARY KEY (thing, thing_id, tag_id));`
+
+	// User defined fields of items, boxes, shelves or areas.
+	// options holds the choices of enum fields, one per line.
+	CREATE_CUSTOM_FIELD_TABLE_STMT = `CREATE TABLE custom_field (
+    id TEXT NOT NULL PRIMARY KEY,
+    owner_id TEXT NOT NULL,
+    thing TEXT NOT NULL,
+    name TEXT NOT NULL,
+    type TEXT NOT NULL,
+    options TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    UNIQUE (owner_id, thing, name COLLATE NOCASE));`
+
+	// Values of custom fields, things without a value for a field have no row.
+	CREATE_CUSTOM_FIELD_VALUE_TABLE_STMT = `CREATE TABLE custom_field_value (
+    field_id TEXT NOT NULL REFERENCES custom_field(id) ON DELETE CASCADE,
+    thing_id TEXT NOT NULL,
+    value TEXT NOT NULL,
+    PRIMARY KEY (field_id, thing_id));`
+
+	// Category tree of items. Root categories have no parent_id.
+	// unit is a hint for the quantity of items, like "m" or "pcs", inherited by subcategories without a unit.
+	CREATE_CATEGORY_TABLE_STMT = `CREATE TABLE category (
+    id TEXT NOT NULL PRIMARY KEY,
+    owner_id TEXT NOT NULL,
+    parent_id TEXT REFERENCES category(id),
+    name TEXT NOT NULL,
+    unit TEXT NOT NULL,
+    created_at TEXT NOT NULL);`
+
+	// Custom field values new items of a category and its subcategories get by default.
+	CREATE_CATEGORY_DEFAULT_TABLE_STMT = `CREATE TABLE category_default (
+    category_id TEXT NOT NULL REFERENCES category(id) ON DELETE CASCADE,
+    field_id TEXT NOT NULL REFERENCES custom_field(id) ON DELETE CASCADE,
+    value TEXT NOT NULL,
+    PRIMARY KEY (category_id, field_id));`
+
+	// Every change of the quantity of an item with the quantity after the change.
+	// actor_id is NULL for the stock that items had when the table was added.
+	CREATE_STOCK_CHANGE_TABLE_STMT = `CREATE TABLE stock_change (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    owner_id TEXT NOT NULL,
+    item_id TEXT NOT NULL REFERENCES item(id),
+    actor_id TEXT,
+    created_at TEXT NOT NULL,
+    delta INTEGER NOT NULL,
+    quantity INTEGER NOT NULL,
+    min_stock INTEGER NOT NULL,
+    reason TEXT NOT NULL);`
+
+	// Things to buy. item_id is NULL for entries added by hand without an item.
+	// box_id, shelf_id and area_id are the place of the item when the entry was added,
+	// where the item is put away after it was bought.
+	CREATE_SHOPPING_ENTRY_TABLE_STMT = `CREATE TABLE shopping_entry (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    owner_id TEXT NOT NULL,
+    item_id TEXT REFERENCES item(id),
+    label TEXT NOT NULL,
+    quantity INTEGER NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    generated INTEGER NOT NULL DEFAULT 0,
+    box_id TEXT,
+    shelf_id TEXT,
+    area_id TEXT,
+    created_at TEXT NOT NULL);`
+
+	// Items and boxes lent to a borrower. returned_at is NULL until the thing is given back.
+	CREATE_LOAN_TABLE_STMT = `CREATE TABLE loan (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    owner_id TEXT NOT NULL,
+    thing TEXT NOT NULL,
+    thing_id TEXT NOT NULL,
+    borrower TEXT NOT NULL,
+    lent_at TEXT NOT NULL,
+    due_date TEXT NOT NULL,
+    returned_at TEXT,
+    actor_id TEXT);`
+
+	CREATE_PICTURE_TABLE_STMT = `CREATE TABLE picture (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    owner_id TEXT NOT NULL,
+    thing TEXT NOT NULL,
+    thing_id TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    caption TEXT NOT NULL DEFAULT '',
+    cover INTEGER NOT NULL DEFAULT 0,
+    picture TEXT NOT NULL,
+    preview_picture TEXT,
+    created_at TEXT NOT NULL);`
+
+	CREATE_SCHEMA_VERSION_TABLE_STMT = `CREATE TABLE IF NOT EXISTS schema_version (
+    version INTEGER NOT NULL PRIMARY KEY,
+    name TEXT NOT NULL,
+    applied_at TEXT NOT NULL);`
+
 	// Item
 	ITEM_QUANTITY    = "quantity"
 	ITEM_WEIGHT      = "weight"
+	ITEM_CATEGORY_ID = "category_id"
+	ITEM_BEST_BEFORE = "best_before"
+	ITEM_EXPIRES_AT  = "expires_at"
+	ITEM_MIN_STOCK   = "min_stock"
+	// purchase details for insurance
+	ITEM_PURCHASE_DATE  = "purchase_date"
+	ITEM_PRICE          = "price"
+	ITEM_CURRENCY       = "currency"
+	ITEM_VENDOR         = "vendor"
+	ITEM_SERIAL_NUMBER  = "serial_number"
+	ITEM_WARRANTY_UNTIL = "warranty_until"
+	// the earlier of the best before and the expiry date, NULL if the item has neither
+	ITEM_EXPIRY_DATE = "COALESCE(MIN(" + ITEM_BEST_BEFORE + ", " + ITEM_EXPIRES_AT + "), " + ITEM_BEST_BEFORE + ", " + ITEM_EXPIRES_AT + ")"
 	ITEM_BOX_ID      = FTS_BOX_ID
 	ITEM_BOX_LABEL   = FTS_BOX_LABEL
 	ITEM_SHELF_ID    = FTS_SHELF_ID
@@ -305,9 +491,55 @@ const (
 		"BEGIN " +
 		"	DELETE FROM area_fts WHERE " + FTS_ID + " = old." + FTS_ID + ";" +
 		"END;"
+
+	// Trashed things are removed from the fts tables and added again when they are restored.
+	// Used in migration 4.
+	CREATE_ITEM_RESTORE_TRIGGER = "" +
+		"CREATE TRIGGER item_restore AFTER UPDATE OF " + DELETED_AT + " ON item " +
+		"WHEN old." + DELETED_AT + " IS NOT NULL AND new." + NOT_DELETED + " " +
+		"BEGIN " +
+		"    INSERT INTO item_fts(" + ALL_FTS_COLS + ")" +
+		"    VALUES (" + CREATE_ITEM_BOX_INSERT_TRIGGER_VALUES_BLOCK + ");" +
+		"END;"
+
+	CREATE_BOX_RESTORE_TRIGGER = "" +
+		"CREATE TRIGGER box_restore AFTER UPDATE OF " + DELETED_AT + " ON box " +
+		"WHEN old." + DELETED_AT + " IS NOT NULL AND new." + NOT_DELETED + " " +
+		"BEGIN " +
+		"    INSERT INTO box_fts(" + ALL_FTS_COLS + ")" +
+		"    VALUES (" + CREATE_ITEM_BOX_INSERT_TRIGGER_VALUES_BLOCK + ");" +
+		"END;"
+
+	CREATE_SHELF_RESTORE_TRIGGER = "" +
+		"CREATE TRIGGER shelf_restore AFTER UPDATE OF " + DELETED_AT + " ON shelf " +
+		"WHEN old." + DELETED_AT + " IS NOT NULL AND new." + NOT_DELETED + " " +
+		"BEGIN " +
+		"    INSERT INTO shelf_fts(" + FTS_ID + "," + FTS_LABEL + "," + FTS_DESCRIPTION + "," + FTS_PREVIEW_PICTURE + "," + FTS_AREA_ID + "," + FTS_AREA_LABEL + ")" +
+		"    VALUES (new." + FTS_ID + ", new." + FTS_LABEL + ", new." + FTS_DESCRIPTION + ", new." + FTS_PREVIEW_PICTURE + ", new." + FTS_AREA_ID + "," +
+		"    (SELECT " + BASIC_INFO_LABEL + " FROM area WHERE area." + BASIC_INFO_ID + " = new." + FTS_AREA_ID + "));" +
+		"END;"
+
+	CREATE_AREA_RESTORE_TRIGGER = "" +
+		"CREATE TRIGGER area_restore AFTER UPDATE OF " + DELETED_AT + " ON area " +
+		"WHEN old." + DELETED_AT + " IS NOT NULL AND new." + NOT_DELETED + " " +
+		"BEGIN " +
+		"    INSERT INTO area_fts(" + FTS_ID + "," + FTS_LABEL + "," + FTS_DESCRIPTION + "," + FTS_PREVIEW_PICTURE + ")" +
+		"    VALUES (new." + FTS_ID + ", new." + FTS_LABEL + ", new." + FTS_DESCRIPTION + ", new." + FTS_PREVIEW_PICTURE + ");" +
+		"END;"
 )
 
-// fetchBoxQuery returns a formatted SQL query to fetch box details
+// trashTrigger returns a statement that creates a trigger which removes things from the fts table of table
+// when they are moved to the trash.
+func trashTrigger(table string) string {
+	return "CREATE TRIGGER " + table + "_trash AFTER UPDATE OF " + DELETED_AT + " ON " + table + " " +
+		"WHEN old." + NOT_DELETED + " AND new." + DELETED_AT + " IS NOT NULL " +
+		"BEGIN " +
+		"    DELETE FROM " + table + "_fts WHERE " + FTS_ID + " = old." + FTS_ID + ";" +
+		"END;"
+}
+
+// fetchBoxQuery returns a formatted SQL query to fetch box details.
+// With useBoxID the query expects the field value and the owner id as parameters.
 func fetchBoxQuery(useBoxID bool, field string) string {
 	query := `
         SELECT 
@@ -319,7 +551,7 @@ func fetchBoxQuery(useBoxID bool, field string) string {
         LEFT JOIN area AS a ON b.area_id = a.id`
 
 	if useBoxID {
-		query += fmt.Sprintf(" WHERE b.%s = ?;", field)
+		query += fmt.Sprintf(" WHERE b.%s = ? AND b.%s = ? AND b.%s;", field, OWNER_ID, NOT_DELETED)
 	}
 
 	return query
@@ -5,16 +5,27 @@ package env
 const configFile string = "config-dev.conf"
 
 var defaultDevConfigPreset Configuration = Configuration{
-	env:              env_dev,
-	alwaysAuthorized: true,
-	defaultTableSize: 10,
-	infoLogsEnabled:  true,
-	debugLogsEnabled: true,
-	errorLogsEnabled: true,
-	useMemoryDB:      false,
-	dbPath:           "./internal/database/sqlite-database.db",
-	staticPath:       "./internal/static",
-	templatePath:     "./internal",
+	env:                 env_dev,
+	alwaysAuthorized:    true,
+	defaultTableSize:    10,
+	infoLogsEnabled:     true,
+	debugLogsEnabled:    true,
+	errorLogsEnabled:    true,
+	useMemoryDB:         false,
+	dbPath:              "./internal/database/sqlite-database.db",
+	staticPath:          "./internal/static",
+	templatePath:        "./internal",
+	trashRetentionDays:  30,
+	expiryWarningDays:   7,
+	backupPath:          "./internal/database/backups",
+	backupIntervalHours: 24,
+	backupRetention:     7,
+	blobPath:            "./internal/database/blobs",
+	previewPictureSide:  50,
+	mediumPictureSide:   400,
+	pictureQuality:      85,
+	publicURL:           "http://localhost:8101",
+	serverAdmins:        "Development User",
 }
 
 // Copy of preset development config.
@@ -11,16 +11,27 @@ const configFile string = "config.conf"
 var homeDir string = os.Getenv("HOME")
 
 var defaultProdConfigPreset Configuration = Configuration{
-	env:              env_prod,
-	alwaysAuthorized: false,
-	defaultTableSize: 15,
-	infoLogsEnabled:  true,
-	debugLogsEnabled: false,
-	errorLogsEnabled: true,
-	useMemoryDB:      false,
-	dbPath:           homeDir + "/.local/share/basement-organizer/internal/database/sqlite-database.db",
-	staticPath:       homeDir + "/.local/share/basement-organizer/internal/static",
-	templatePath:     homeDir + "/.local/share/basement-organizer/internal",
+	env:                 env_prod,
+	alwaysAuthorized:    false,
+	defaultTableSize:    15,
+	infoLogsEnabled:     true,
+	debugLogsEnabled:    false,
+	errorLogsEnabled:    true,
+	useMemoryDB:         false,
+	dbPath:              homeDir + "/.local/share/basement-organizer/internal/database/sqlite-database.db",
+	staticPath:          homeDir + "/.local/share/basement-organizer/internal/static",
+	templatePath:        homeDir + "/.local/share/basement-organizer/internal",
+	trashRetentionDays:  30,
+	expiryWarningDays:   7,
+	backupPath:          homeDir + "/.local/share/basement-organizer/backups",
+	backupIntervalHours: 24,
+	backupRetention:     7,
+	blobPath:            homeDir + "/.local/share/basement-organizer/blobs",
+	previewPictureSide:  50,
+	mediumPictureSide:   400,
+	pictureQuality:      85,
+	publicURL:           "http://localhost:8101",
+	serverAdmins:        "",
 }
 
 // Copy of preset production config.
@@ -5,16 +5,27 @@ package env
 const configFile string = "config-test.conf"
 
 var defaultTestConfigPreset Configuration = Configuration{
-	env:              env_test,
-	alwaysAuthorized: false,
-	defaultTableSize: 15,
-	infoLogsEnabled:  false,
-	debugLogsEnabled: false,
-	errorLogsEnabled: false,
-	useMemoryDB:      true,
-	dbPath:           ":memory:",
-	staticPath:       "./internal/static",
-	templatePath:     "./internal",
+	env:                 env_test,
+	alwaysAuthorized:    false,
+	defaultTableSize:    15,
+	infoLogsEnabled:     false,
+	debugLogsEnabled:    false,
+	errorLogsEnabled:    false,
+	useMemoryDB:         true,
+	dbPath:              ":memory:",
+	staticPath:          "./internal/static",
+	templatePath:        "./internal",
+	trashRetentionDays:  30,
+	expiryWarningDays:   7,
+	backupPath:          "./internal/database/backups",
+	backupIntervalHours: 24,
+	backupRetention:     7,
+	blobPath:            "./internal/database/blobs",
+	previewPictureSide:  50,
+	mediumPictureSide:   400,
+	pictureQuality:      85,
+	publicURL:           "http://localhost:8101",
+	serverAdmins:        "",
 }
 
 // Copy of preset test config.
@@ -17,20 +17,31 @@ const ignore_config_method_checks string = "Fields,Methods,Init,FieldValues"
 // Every field needs to have a setter and getter method except ignored fields.
 // Example field: defaultTableSize needs to implement SetDefaultTablesize() and DefaultTableSize().
 type Configuration struct {
-	fields           []string                 // not part of user configuration
-	methods          []string                 // not part of user configuration
-	fieldValues      map[string]fieldMetaData // not part of user configuration
-	env              environment              // not part of user configuration
-	alwaysAuthorized bool
-	defaultTableSize int
-	showTableSize    bool
-	infoLogsEnabled  bool
-	debugLogsEnabled bool
-	errorLogsEnabled bool
-	useMemoryDB      bool
-	dbPath           string
-	staticPath       string
-	templatePath     string
+	fields              []string                 // not part of user configuration
+	methods             []string                 // not part of user configuration
+	fieldValues         map[string]fieldMetaData // not part of user configuration
+	env                 environment              // not part of user configuration
+	alwaysAuthorized    bool
+	defaultTableSize    int
+	showTableSize       bool
+	infoLogsEnabled     bool
+	debugLogsEnabled    bool
+	errorLogsEnabled    bool
+	useMemoryDB         bool
+	dbPath              string
+	staticPath          string
+	templatePath        string
+	trashRetentionDays  int
+	expiryWarningDays   int
+	backupPath          string
+	backupIntervalHours int
+	backupRetention     int
+	blobPath            string
+	previewPictureSide  int
+	mediumPictureSide   int
+	pictureQuality      int
+	publicURL           string
+	serverAdmins        string
 }
 
 // Init returns false if some Get or Set methods are missing from struct.
@@ -202,6 +213,189 @@ func (c *Configuration) DefaultTableSize() int {
 	return configInstance.defaultTableSize
 }
 
+// SetTrashRetentionDays sets the amount of days things stay in the trash before they are purged.
+// 0 keeps things in the trash forever.
+func (c *Configuration) SetTrashRetentionDays(days int) *Configuration {
+	if days < 0 {
+		logg.Fatalf("[SetTrashRetentionDays] days can't be negative but is %d", days)
+	}
+	c.trashRetentionDays = days
+	loadLog(fmt.Sprintf("set trash retention to %d days", days), 2)
+	return c
+}
+
+// TrashRetentionDays returns the amount of days things stay in the trash before they are purged.
+func (c *Configuration) TrashRetentionDays() int {
+	return configInstance.trashRetentionDays
+}
+
+// SetExpiryWarningDays sets how many days before their expiry date items are shown as expiring soon.
+// 0 only warns about items that are already expired.
+func (c *Configuration) SetExpiryWarningDays(days int) *Configuration {
+	if days < 0 {
+		logg.Fatalf("[SetExpiryWarningDays] days can't be negative but is %d", days)
+	}
+	c.expiryWarningDays = days
+	loadLog(fmt.Sprintf("set expiry warning to %d days", days), 2)
+	return c
+}
+
+// ExpiryWarningDays returns how many days before their expiry date items are shown as expiring soon.
+func (c *Configuration) ExpiryWarningDays() int {
+	return configInstance.expiryWarningDays
+}
+
+// SetBackupPath sets the directory where snapshots of the database are stored.
+func (c *Configuration) SetBackupPath(path string) *Configuration {
+	if path == "" {
+		logg.Fatal("Can't set BackupPath to \"\".")
+	}
+	c.backupPath = path
+	loadLog("set BackupPath to "+path, 1)
+	return c
+}
+
+// BackupPath returns the directory where snapshots of the database are stored.
+func (c *Configuration) BackupPath() string {
+	return c.backupPath
+}
+
+// SetBackupIntervalHours sets how many hours pass between scheduled snapshots of the database.
+// 0 disables scheduled snapshots.
+func (c *Configuration) SetBackupIntervalHours(hours int) *Configuration {
+	if hours < 0 {
+		logg.Fatalf("[SetBackupIntervalHours] hours can't be negative but is %d", hours)
+	}
+	c.backupIntervalHours = hours
+	loadLog(fmt.Sprintf("set backup interval to %d hours", hours), 2)
+	return c
+}
+
+// BackupIntervalHours returns how many hours pass between scheduled snapshots of the database.
+func (c *Configuration) BackupIntervalHours() int {
+	return configInstance.backupIntervalHours
+}
+
+// SetBackupRetention sets how many of the newest snapshots are kept.
+// 0 keeps all snapshots.
+func (c *Configuration) SetBackupRetention(count int) *Configuration {
+	if count < 0 {
+		logg.Fatalf("[SetBackupRetention] count can't be negative but is %d", count)
+	}
+	c.backupRetention = count
+	loadLog(fmt.Sprintf("set backup retention to %d snapshots", count), 2)
+	return c
+}
+
+// BackupRetention returns how many of the newest snapshots are kept.
+func (c *Configuration) BackupRetention() int {
+	return configInstance.backupRetention
+}
+
+// SetBlobPath sets the directory where the files of pictures are stored.
+func (c *Configuration) SetBlobPath(path string) *Configuration {
+	if path == "" {
+		logg.Fatal("Can't set BlobPath to \"\".")
+	}
+	c.blobPath = path
+	loadLog("set BlobPath to "+path, 1)
+	return c
+}
+
+// BlobPath returns the directory where the files of pictures are stored.
+// Snapshots of the database refer to these files, so they must be backed up together.
+func (c *Configuration) BlobPath() string {
+	return c.blobPath
+}
+
+// SetPreviewPictureSide sets the longest side in pixels of the previews shown in lists.
+// Only pictures that are uploaded afterwards get previews of the new size.
+func (c *Configuration) SetPreviewPictureSide(pixels int) *Configuration {
+	if pixels < 1 {
+		logg.Fatalf("[SetPreviewPictureSide] pixels must be above 0 but is %d", pixels)
+	}
+	c.previewPictureSide = pixels
+	loadLog(fmt.Sprintf("set preview picture side to %d pixels", pixels), 2)
+	return c
+}
+
+// PreviewPictureSide returns the longest side in pixels of the previews shown in lists.
+func (c *Configuration) PreviewPictureSide() int {
+	return configInstance.previewPictureSide
+}
+
+// SetMediumPictureSide sets the longest side in pixels of the pictures shown on details pages.
+func (c *Configuration) SetMediumPictureSide(pixels int) *Configuration {
+	if pixels < 1 {
+		logg.Fatalf("[SetMediumPictureSide] pixels must be above 0 but is %d", pixels)
+	}
+	c.mediumPictureSide = pixels
+	loadLog(fmt.Sprintf("set medium picture side to %d pixels", pixels), 2)
+	return c
+}
+
+// MediumPictureSide returns the longest side in pixels of the pictures shown on details pages.
+func (c *Configuration) MediumPictureSide() int {
+	return configInstance.mediumPictureSide
+}
+
+// SetPictureQuality sets the JPEG quality from 1 to 100 of scaled down pictures.
+func (c *Configuration) SetPictureQuality(quality int) *Configuration {
+	if quality < 1 || quality > 100 {
+		logg.Fatalf("[SetPictureQuality] quality must be between 1 and 100 but is %d", quality)
+	}
+	c.pictureQuality = quality
+	loadLog(fmt.Sprintf("set picture quality to %d", quality), 2)
+	return c
+}
+
+// PictureQuality returns the JPEG quality from 1 to 100 of scaled down pictures.
+func (c *Configuration) PictureQuality() int {
+	return configInstance.pictureQuality
+}
+
+// SetPublicURL sets the scheme and host phones reach the server at, like "http://192.168.1.20:8101".
+// New QR codes link to it, codes that were already printed keep their address.
+func (c *Configuration) SetPublicURL(url string) *Configuration {
+	if url == "" {
+		logg.Fatal("Can't set PublicURL to \"\".")
+	}
+	c.publicURL = strings.TrimSuffix(url, "/")
+	loadLog("set PublicURL to "+c.publicURL, 1)
+	return c
+}
+
+// PublicURL returns the scheme and host phones reach the server at, without a trailing slash.
+func (c *Configuration) PublicURL() string {
+	return c.publicURL
+}
+
+// SetServerAdmins sets the comma separated usernames of the users who manage the whole server, like backups.
+// Nobody manages the server over the web if it is empty.
+func (c *Configuration) SetServerAdmins(usernames string) *Configuration {
+	c.serverAdmins = usernames
+	loadLog("set ServerAdmins to "+usernames, 1)
+	return c
+}
+
+// ServerAdmins returns the comma separated usernames of the users who manage the whole server.
+func (c *Configuration) ServerAdmins() string {
+	return c.serverAdmins
+}
+
+// IsServerAdmin returns true if username is one of the configured server admins.
+func (c *Configuration) IsServerAdmin(username string) bool {
+	if username == "" {
+		return false
+	}
+	for _, admin := range strings.Split(c.serverAdmins, ",") {
+		if strings.TrimSpace(admin) == username {
+			return true
+		}
+	}
+	return false
+}
+
 // SetUseMemoryDB sets if DB should use memory instead of files.
 func (c *Configuration) SetUseMemoryDB(useMemory bool) *Configuration {
 	c.useMemoryDB = useMemory
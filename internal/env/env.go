@@ -115,6 +115,8 @@ func applyConfig(c Configuration) {
 	configInstance.SetErrorLogsEnabled(c.errorLogsEnabled)
 	configInstance.SetShowTableSize(c.showTableSize)
 	configInstance.SetDefaultTableSize(c.defaultTableSize)
+	configInstance.SetTrashRetentionDays(c.trashRetentionDays)
+	configInstance.SetExpiryWarningDays(c.expiryWarningDays)
 	// configInstance.SetConfigFile(c.configFile)
 
 	if c.useMemoryDB {
@@ -124,6 +126,15 @@ func applyConfig(c Configuration) {
 		configInstance.SetDbPath(c.dbPath)
 	}
 	configInstance.SetTemplatePath(c.templatePath)
+	configInstance.SetBackupPath(c.backupPath)
+	configInstance.SetBackupIntervalHours(c.backupIntervalHours)
+	configInstance.SetBackupRetention(c.backupRetention)
+	configInstance.SetBlobPath(c.blobPath)
+	configInstance.SetPreviewPictureSide(c.previewPictureSide)
+	configInstance.SetMediumPictureSide(c.mediumPictureSide)
+	configInstance.SetPictureQuality(c.pictureQuality)
+	configInstance.SetPublicURL(c.publicURL)
+	configInstance.SetServerAdmins(c.serverAdmins)
 	configInstance.SetStaticPath(c.staticPath)
 
 	switch c.env {
@@ -156,6 +167,10 @@ func createFileFromConfiguration(path string, config *Configuration) error {
 	data := config.FieldValues()
 	for k, v := range data {
 		lines[i] = k + "=" + string(v.Value)
+		if v.Value == "" {
+			// Options without a value can't be parsed, the default is used instead.
+			lines[i] = "# " + lines[i]
+		}
 		i++
 	}
 	sort.Strings(lines)
@@ -134,6 +134,177 @@ func TestCheckDefaultTableSizeConstraints(t *testing.T) {
 	}
 }
 
+func TestCheckTrashRetentionDaysConstraints(t *testing.T) {
+	tests := map[string]struct {
+		input       Configuration
+		expectedErr bool
+	}{
+		"invalid trashRetentionDays -1": {
+			input:       Configuration{trashRetentionDays: -1},
+			expectedErr: true,
+		},
+		"valid trashRetentionDays 0 keeps things forever": {
+			input: Configuration{trashRetentionDays: 0},
+		},
+		"valid trashRetentionDays": {
+			input: Configuration{trashRetentionDays: 30},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := validateTrashRetentionDays(&tt.input)
+			if tt.expectedErr && (err == nil) {
+				t.Errorf("got error: nil expected error with input \"%d\"", tt.input.trashRetentionDays)
+			}
+			if !tt.expectedErr && (err != nil) {
+				t.Errorf("got error: \"%s\" expected no error with input \"%d\"", logg.CleanLastError(err), tt.input.trashRetentionDays)
+			}
+		})
+	}
+}
+
+func TestCheckExpiryWarningDaysConstraints(t *testing.T) {
+	tests := map[string]struct {
+		input       Configuration
+		expectedErr bool
+	}{
+		"invalid expiryWarningDays -1": {
+			input:       Configuration{expiryWarningDays: -1},
+			expectedErr: true,
+		},
+		"valid expiryWarningDays 0 only warns about expired items": {
+			input: Configuration{expiryWarningDays: 0},
+		},
+		"valid expiryWarningDays": {
+			input: Configuration{expiryWarningDays: 7},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := validateExpiryWarningDays(&tt.input)
+			if tt.expectedErr && (err == nil) {
+				t.Errorf("got error: nil expected error with input \"%d\"", tt.input.expiryWarningDays)
+			}
+			if !tt.expectedErr && (err != nil) {
+				t.Errorf("got error: \"%s\" expected no error with input \"%d\"", logg.CleanLastError(err), tt.input.expiryWarningDays)
+			}
+		})
+	}
+}
+
+func TestCheckPictureConstraints(t *testing.T) {
+	valid := Configuration{previewPictureSide: 50, mediumPictureSide: 400, pictureQuality: 85}
+	tests := map[string]struct {
+		input       func(c Configuration) Configuration
+		expectedErr bool
+	}{
+		"valid picture options": {
+			input: func(c Configuration) Configuration { return c },
+		},
+		"invalid previewPictureSide 0": {
+			input:       func(c Configuration) Configuration { c.previewPictureSide = 0; return c },
+			expectedErr: true,
+		},
+		"invalid mediumPictureSide -1": {
+			input:       func(c Configuration) Configuration { c.mediumPictureSide = -1; return c },
+			expectedErr: true,
+		},
+		"invalid pictureQuality 101": {
+			input:       func(c Configuration) Configuration { c.pictureQuality = 101; return c },
+			expectedErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			input := tt.input(valid)
+			err := validatePictureOptions(&input)
+			if tt.expectedErr && (err == nil) {
+				t.Errorf("got error: nil expected error with input \"%v\"", input)
+			}
+			if !tt.expectedErr && (err != nil) {
+				t.Errorf("got error: \"%s\" expected no error with input \"%v\"", logg.CleanLastError(err), input)
+			}
+		})
+	}
+}
+
+func TestCheckPublicURLConstraints(t *testing.T) {
+	tests := map[string]struct {
+		input       Configuration
+		expectedErr bool
+	}{
+		"valid publicURL": {
+			input: Configuration{publicURL: "http://192.168.1.20:8101"},
+		},
+		"valid publicURL with https": {
+			input: Configuration{publicURL: "https://basement.example.org"},
+		},
+		"invalid empty publicURL": {
+			input:       Configuration{publicURL: ""},
+			expectedErr: true,
+		},
+		"invalid publicURL without scheme": {
+			input:       Configuration{publicURL: "192.168.1.20:8101"},
+			expectedErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := validatePublicURL(&tt.input)
+			if tt.expectedErr && (err == nil) {
+				t.Errorf("got error: nil expected error with input \"%s\"", tt.input.publicURL)
+			}
+			if !tt.expectedErr && (err != nil) {
+				t.Errorf("got error: \"%s\" expected no error with input \"%s\"", logg.CleanLastError(err), tt.input.publicURL)
+			}
+		})
+	}
+}
+
+func TestIsServerAdmin(t *testing.T) {
+	tests := map[string]struct {
+		input    Configuration
+		username string
+		expected bool
+	}{
+		"no server admins": {
+			input:    Configuration{serverAdmins: ""},
+			username: "alice",
+		},
+		"empty username": {
+			input:    Configuration{serverAdmins: "alice,"},
+			username: "",
+		},
+		"only server admin": {
+			input:    Configuration{serverAdmins: "alice"},
+			username: "alice",
+			expected: true,
+		},
+		"one of several server admins": {
+			input:    Configuration{serverAdmins: "alice, bob"},
+			username: "bob",
+			expected: true,
+		},
+		"username is part of a server admin": {
+			input:    Configuration{serverAdmins: "alice2"},
+			username: "alice",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := tt.input.IsServerAdmin(tt.username)
+			if got != tt.expected {
+				t.Errorf("got %t expected %t with serverAdmins \"%s\" and username \"%s\"", got, tt.expected, tt.input.serverAdmins, tt.username)
+			}
+		})
+	}
+}
+
 func TestCheckDBConstraints(t *testing.T) {
 	dbConstraintsTests := map[string]struct {
 		input       Configuration
@@ -6,6 +6,7 @@ import (
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"net/url"
 	"os"
 	"reflect"
 	"slices"
@@ -108,6 +109,30 @@ func ValidateOptions(config *Configuration) (errors []error) {
 	if err != nil {
 		errors = append(errors, err)
 	}
+	err = validateTrashRetentionDays(config)
+	if err != nil {
+		errors = append(errors, err)
+	}
+	err = validateExpiryWarningDays(config)
+	if err != nil {
+		errors = append(errors, err)
+	}
+	err = validateBackupOptions(config)
+	if err != nil {
+		errors = append(errors, err)
+	}
+	err = validateBlobPath(config)
+	if err != nil {
+		errors = append(errors, err)
+	}
+	err = validatePictureOptions(config)
+	if err != nil {
+		errors = append(errors, err)
+	}
+	err = validatePublicURL(config)
+	if err != nil {
+		errors = append(errors, err)
+	}
 	err = validateDBOptions(config)
 	if err != nil {
 		errors = append(errors, err)
@@ -125,6 +150,61 @@ func validateDefaultTableSize(config *Configuration) (err error) {
 	return err
 }
 
+func validateTrashRetentionDays(config *Configuration) (err error) {
+	if config.trashRetentionDays < 0 {
+		err = logg.NewError(fmt.Sprintf("trashRetentionDays can't be negative. trashRetentionDays=%d", config.trashRetentionDays))
+	}
+	return err
+}
+
+func validateExpiryWarningDays(config *Configuration) (err error) {
+	if config.expiryWarningDays < 0 {
+		err = logg.NewError(fmt.Sprintf("expiryWarningDays can't be negative. expiryWarningDays=%d", config.expiryWarningDays))
+	}
+	return err
+}
+
+func validateBackupOptions(config *Configuration) (err error) {
+	if config.backupPath == "" {
+		return logg.NewError("backupPath can't be empty")
+	}
+	if config.backupIntervalHours < 0 {
+		return logg.NewError(fmt.Sprintf("backupIntervalHours can't be negative. backupIntervalHours=%d", config.backupIntervalHours))
+	}
+	if config.backupRetention < 0 {
+		return logg.NewError(fmt.Sprintf("backupRetention can't be negative. backupRetention=%d", config.backupRetention))
+	}
+	return nil
+}
+
+func validateBlobPath(config *Configuration) (err error) {
+	if config.blobPath == "" {
+		err = logg.NewError("blobPath can't be empty")
+	}
+	return err
+}
+
+func validatePictureOptions(config *Configuration) (err error) {
+	if config.previewPictureSide < 1 {
+		return logg.NewError(fmt.Sprintf("previewPictureSide must be above 0. previewPictureSide=%d", config.previewPictureSide))
+	}
+	if config.mediumPictureSide < 1 {
+		return logg.NewError(fmt.Sprintf("mediumPictureSide must be above 0. mediumPictureSide=%d", config.mediumPictureSide))
+	}
+	if config.pictureQuality < 1 || config.pictureQuality > 100 {
+		return logg.NewError(fmt.Sprintf("pictureQuality must be between 1 and 100. pictureQuality=%d", config.pictureQuality))
+	}
+	return nil
+}
+
+func validatePublicURL(config *Configuration) (err error) {
+	u, parseErr := url.Parse(config.publicURL)
+	if parseErr != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return logg.NewError(fmt.Sprintf(`publicURL must be like "http://192.168.1.20:8101". publicURL=%s`, config.publicURL))
+	}
+	return nil
+}
+
 // validateDBOptions checks for consistency between different options regarding DB.
 func validateDBOptions(config *Configuration) (err error) {
 	invalidMemoryDB := (config.dbPath == ":memory:") && (config.useMemoryDB == false)
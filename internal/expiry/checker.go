@@ -0,0 +1,76 @@
+package expiry
+
+import (
+	"basement/main/internal/env"
+	"basement/main/internal/logg"
+	"basement/main/internal/server"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// Checker counts the expired items and items expiring soon of all households in the background,
+// so the counts are ready when a user logs in.
+type Checker struct {
+	db     ExpiryDatabase
+	mu     sync.RWMutex
+	counts map[uuid.UUID]Counts // by household id
+}
+
+func NewChecker(db ExpiryDatabase) *Checker {
+	return &Checker{db: db}
+}
+
+// Check counts the items of all households again.
+// The warning period is read from the config on every check.
+func (c *Checker) Check() error {
+	counts, err := c.db.ExpiryCounts(time.Now(), env.CurrentConfig().ExpiryWarningDays())
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	c.mu.Lock()
+	c.counts = counts
+	c.mu.Unlock()
+	return nil
+}
+
+// CheckEvery checks the items right away and then every interval. It never returns.
+func (c *Checker) CheckEvery(interval time.Duration) {
+	for {
+		err := c.Check()
+		if err != nil {
+			logg.Err(err)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// Counts returns the counts of the household from the last check.
+func (c *Checker) Counts(householdID uuid.UUID) Counts {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.counts[householdID]
+}
+
+// LoginNotifications adds a warning for every household of the user with expired items or items expiring soon.
+// Meant to be registered with auth.RegisterLoginNotifier.
+func (c *Checker) LoginNotifications(ctx context.Context, userID uuid.UUID, notifications *server.Notifications) {
+	memberships, err := c.db.Households(ctx, userID)
+	if err != nil {
+		logg.Err(err)
+		return
+	}
+	for _, m := range memberships {
+		message := c.Counts(m.HouseholdID).Message()
+		if message == "" {
+			continue
+		}
+		if len(memberships) > 1 {
+			message = fmt.Sprintf("%s: %s", m.HouseholdName, message)
+		}
+		notifications.AddWarning(message)
+	}
+}
@@ -0,0 +1,48 @@
+package expiry
+
+import (
+	"basement/main/internal/common"
+	"basement/main/internal/households"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// Expiry states of items. The expiry date of an item is the earlier of its best before and expiry date.
+const (
+	STATUS_EXPIRED  = "expired"  // the expiry date is before today
+	STATUS_EXPIRING = "expiring" // the expiry date is today or within the warning days
+)
+
+type ExpiryDatabase interface {
+	ExpiryListCounter(ctx context.Context, status string, searchQuery string, warnDays int) (count int, err error)
+	ExpiryListRows(ctx context.Context, status string, searchQuery string, warnDays int, limit int, page int) ([]common.ListRow, error)
+	ExpiryCounts(today time.Time, warnDays int) (map[uuid.UUID]Counts, error)
+	Households(ctx context.Context, userID uuid.UUID) ([]households.Membership, error)
+}
+
+// Counts is the amount of expired items and items expiring soon of a household.
+type Counts struct {
+	Expired  int
+	Expiring int
+}
+
+// Message describes the counts for a notification, like "2 items have expired and 1 item expires soon".
+// Returns "" if there are no expired or expiring items.
+func (c Counts) Message() string {
+	var parts []string
+	if c.Expired == 1 {
+		parts = append(parts, "1 item has expired")
+	} else if c.Expired > 1 {
+		parts = append(parts, fmt.Sprintf("%d items have expired", c.Expired))
+	}
+	if c.Expiring == 1 {
+		parts = append(parts, "1 item expires soon")
+	} else if c.Expiring > 1 {
+		parts = append(parts, fmt.Sprintf("%d items expire soon", c.Expiring))
+	}
+	return strings.Join(parts, " and ")
+}
@@ -0,0 +1,22 @@
+package expiry
+
+import "testing"
+
+func TestCountsMessage(t *testing.T) {
+	tests := map[string]struct {
+		counts   Counts
+		expected string
+	}{
+		"nothing":          {Counts{}, ""},
+		"one expired":      {Counts{Expired: 1}, "1 item has expired"},
+		"several expiring": {Counts{Expiring: 3}, "3 items expire soon"},
+		"expired and soon": {Counts{Expired: 2, Expiring: 1}, "2 items have expired and 1 item expires soon"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := tt.counts.Message(); got != tt.expected {
+				t.Errorf("got %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
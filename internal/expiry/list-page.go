@@ -0,0 +1,94 @@
+package expiry
+
+import (
+	"basement/main/internal/auth"
+	"basement/main/internal/common"
+	"basement/main/internal/env"
+	"basement/main/internal/server"
+	"basement/main/internal/templates"
+	"context"
+	"maps"
+	"net/http"
+)
+
+// ListPage shows the expired items or the items expiring soon of the household, the earliest first.
+//
+//	GET /items/expired
+//	GET /items/expiring
+func ListPage(db ExpiryDatabase, status string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Add("Allow", http.MethodGet)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+			return
+		}
+
+		authenticated, _ := auth.Authenticated(r)
+		user, _ := auth.UserSessionData(r)
+		warnDays := env.CurrentConfig().ExpiryWarningDays()
+
+		// page template
+		page := templates.NewPageTemplate()
+		page.Title = "Expired items"
+		if status == STATUS_EXPIRING {
+			page.Title = "Items expiring soon"
+		}
+		page.RequestOrigin = "Items"
+		page.Authenticated = authenticated
+		page.User = user
+		data := page.Map()
+		data["Status"] = status
+		data["WarningDays"] = warnDays
+
+		// list template
+		listTmpl := common.ListTemplate{
+			FormHXGet:     "/items/" + status,
+			PlaceHolder:   true,
+			ShowLimit:     env.CurrentConfig().ShowTableSize(),
+			HideMoveCol:   true,
+			RequestOrigin: common.ParseOrigin(r),
+		}
+
+		// search-input template
+		searchString := common.SearchString(r)
+		listTmpl.SearchInput = true
+		listTmpl.SearchInputLabel = "Search items"
+		listTmpl.SearchInputValue = searchString
+
+		count, err := db.ExpiryListCounter(r.Context(), status, searchString, warnDays)
+		if err != nil {
+			server.WriteInternalServerError("cant query "+status+" items", err, w, r)
+			return
+		}
+
+		// pagination
+		pageNr := common.ParsePageNumber(r)
+		limit := common.ParseLimit(r)
+		data = common.Pagination(data, count, limit, pageNr)
+		listTmpl.Pagination = true
+		listTmpl.CurrentPageNumber = data["PageNumber"].(int)
+		listTmpl.Limit = limit
+		listTmpl.PaginationButtons = data["Pages"].([]common.PaginationButton)
+
+		var rows []common.ListRow
+		if count > 0 {
+			rowTemplateOptions := common.ListRowTemplateOptions{
+				HideMoveCol: true,
+				RowHXGet:    "/item",
+			}
+			listRows := func(ctx context.Context, query string, limit int, page int) ([]common.ListRow, error) {
+				return db.ExpiryListRows(ctx, status, query, warnDays, limit, page)
+			}
+			rows, err = common.FilledRows(r.Context(), listRows, searchString, limit, pageNr, count, rowTemplateOptions)
+			if err != nil {
+				server.WriteInternalServerError("cant query "+status+" items", err, w, r)
+				return
+			}
+		}
+		listTmpl.Rows = rows
+
+		maps.Copy(data, listTmpl.Map())
+		server.MustRender(w, r, "expiry-list-page", data)
+	}
+}
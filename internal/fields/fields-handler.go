@@ -0,0 +1,257 @@
+package fields
+
+import (
+	"basement/main/internal/auth"
+	"basement/main/internal/common"
+	"basement/main/internal/logg"
+	"basement/main/internal/server"
+	"basement/main/internal/templates"
+	"basement/main/internal/validate"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// ThingFields are the custom fields of one type of thing, shown on the fields page.
+type ThingFields struct {
+	Thing  string
+	Fields []common.CustomField
+}
+
+// FieldsHandler lists and creates the custom fields of the active household.
+//
+//	GET  /fields                   = custom field management page
+//	GET  /api/v1/fields?thing=item = custom fields of a type of thing as JSON, all fields without "thing"
+//	POST                           = create field from form values "thing", "name", "type" and "options"
+func FieldsHandler(db FieldDatabase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			things := Things
+			if thing := r.FormValue("thing"); thing != "" {
+				if !slices.Contains(Things, thing) {
+					server.WriteBadRequestError(`"thing" must be one of `+strings.Join(Things, ", "), nil, w, r)
+					return
+				}
+				things = []string{thing}
+			}
+			all := []ThingFields{}
+			for _, thing := range things {
+				fields, err := db.CustomFields(r.Context(), thing)
+				if err != nil {
+					server.WriteInternalServerError("can't query custom fields", err, w, r)
+					return
+				}
+				all = append(all, ThingFields{Thing: thing, Fields: fields})
+			}
+			if !server.WantsTemplateData(r) {
+				list := []common.CustomField{}
+				for _, t := range all {
+					list = append(list, t.Fields...)
+				}
+				server.WriteJSON(w, list)
+				return
+			}
+			pageHandler(w, r, all)
+			break
+
+		case http.MethodPost:
+			thing := r.FormValue("thing")
+			if !slices.Contains(Things, thing) {
+				writeInvalid(w, r, "Custom fields can only be added to "+strings.Join(Things, ", ")+".")
+				return
+			}
+			field, ok := validField(w, r, r.FormValue("type"))
+			if !ok {
+				return
+			}
+			field.Thing = thing
+			id, err := db.CreateCustomField(r.Context(), field)
+			if errors.Is(err, ErrFieldExists) {
+				writeFieldExists(w, r, field, err)
+				return
+			}
+			if err != nil {
+				server.WriteInternalServerError("can't create custom field", err, w, r)
+				return
+			}
+			field.ID = id
+			if !server.WantsTemplateData(r) {
+				w.WriteHeader(http.StatusCreated)
+				server.WriteJSON(w, field)
+				return
+			}
+			server.RedirectWithSuccessNotification(w, "/fields", `Created field "`+field.Name+`"`)
+			break
+
+		default:
+			w.Header().Add("Allow", http.MethodGet)
+			w.Header().Add("Allow", http.MethodPost)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+		}
+	}
+}
+
+// FieldHandler shows, changes and deletes the custom field with the path value "id".
+//
+//	GET    /api/v1/fields/{id} = custom field as JSON
+//	PUT    = change name and options to the form values "name" and "options", the type can't be changed
+//	DELETE = delete field together with its values
+func FieldHandler(db FieldDatabase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			id := server.ValidID(w, r, "can't find custom field, invalid id")
+			if id == uuid.Nil {
+				return
+			}
+			field, err := db.CustomField(r.Context(), id)
+			if err != nil {
+				server.WriteNotFoundError("can't find custom field", err, w, r)
+				return
+			}
+			if server.WantsTemplateData(r) {
+				http.Redirect(w, r, "/fields", http.StatusSeeOther)
+				return
+			}
+			server.WriteJSON(w, field)
+			break
+
+		case http.MethodPut:
+			id := server.ValidID(w, r, "can't change custom field, invalid id")
+			if id == uuid.Nil {
+				return
+			}
+			current, err := db.CustomField(r.Context(), id)
+			if err != nil {
+				server.WriteNotFoundError("can't find custom field", err, w, r)
+				return
+			}
+			field, ok := validField(w, r, current.Type)
+			if !ok {
+				return
+			}
+			field.ID = id
+			field.Thing = current.Thing
+			err = db.UpdateCustomField(r.Context(), field)
+			if errors.Is(err, ErrFieldExists) {
+				writeFieldExists(w, r, field, err)
+				return
+			}
+			if err != nil {
+				server.WriteNotFoundError("can't change custom field", err, w, r)
+				return
+			}
+			if !server.WantsTemplateData(r) {
+				server.WriteJSON(w, field)
+				return
+			}
+			server.RedirectWithSuccessNotification(w, "/fields", `Saved field "`+field.Name+`"`)
+			break
+
+		case http.MethodDelete:
+			id := server.ValidID(w, r, "can't delete custom field, invalid id")
+			if id == uuid.Nil {
+				return
+			}
+			err := db.DeleteCustomField(r.Context(), id)
+			if err != nil {
+				server.WriteNotFoundError("can't delete custom field", err, w, r)
+				return
+			}
+			if !server.WantsTemplateData(r) {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			server.RedirectWithSuccessNotification(w, "/fields", "Deleted field")
+			break
+
+		default:
+			w.Header().Add("Allow", http.MethodGet)
+			w.Header().Add("Allow", http.MethodPut)
+			w.Header().Add("Allow", http.MethodDelete)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+		}
+	}
+}
+
+func pageHandler(w http.ResponseWriter, r *http.Request, fields []ThingFields) {
+	authenticated, _ := auth.Authenticated(r)
+	username, _ := auth.UserSessionData(r)
+	page := templates.NewPageTemplate()
+	page.Title = "Custom fields"
+	page.RequestOrigin = "Fields"
+	page.Authenticated = authenticated
+	page.User = username
+
+	data := page.Map()
+	data["Things"] = fields
+	data["Types"] = validate.FieldTypes
+	server.MustRender(w, r, "fields-page", data)
+}
+
+// validField returns the field described by the form values "name" and "options".
+// Options are separated by commas. Writes an error and returns false if the field is invalid.
+func validField(w http.ResponseWriter, r *http.Request, fieldType string) (common.CustomField, bool) {
+	input := validate.CustomFieldDefinitionValidate{
+		Name:    validate.NewStringField(r.FormValue("name")),
+		Type:    validate.NewStringField(fieldType),
+		Options: splitOptions(r.FormValue("options")),
+	}
+	if fieldType != validate.FIELD_TYPE_ENUM {
+		input.Options = nil
+	}
+	validator := validate.Validate{}
+	validator.ValidateCustomFieldDefinition(input)
+	if validator.Messages.FieldNameError == "" && common.IsSearchField(input.Name.String()) {
+		validator.Messages.FieldNameError = `"` + input.Name.String() + `" is used by the search and can't be the name of a field`
+	}
+
+	var messages []string
+	for _, message := range []string{validator.Messages.FieldNameError, validator.Messages.FieldTypeError, validator.Messages.FieldOptionsError} {
+		if message != "" {
+			messages = append(messages, message)
+		}
+	}
+	if len(messages) == 0 {
+		return common.CustomField{Name: input.Name.String(), Type: input.Type.String(), Options: input.Options}, true
+	}
+	writeInvalid(w, r, strings.Join(messages, ". ")+".")
+	return common.CustomField{}, false
+}
+
+// splitOptions splits comma separated options, collapses the spaces in them and drops empty and repeated options.
+func splitOptions(value string) []string {
+	options := []string{}
+	for _, option := range strings.Split(value, ",") {
+		option = strings.Join(strings.Fields(option), " ")
+		if option != "" && !slices.Contains(options, option) {
+			options = append(options, option)
+		}
+	}
+	return options
+}
+
+func writeInvalid(w http.ResponseWriter, r *http.Request, message string) {
+	if server.WantsTemplateData(r) {
+		server.TriggerSingleErrorNotification(w, message)
+	} else {
+		server.WriteBadRequestError(message, nil, w, r)
+	}
+}
+
+func writeFieldExists(w http.ResponseWriter, r *http.Request, field common.CustomField, err error) {
+	message := `A field named "` + field.Name + `" already exists.`
+	if server.WantsTemplateData(r) {
+		logg.Err(err)
+		server.TriggerSingleErrorNotification(w, message)
+		return
+	}
+	w.WriteHeader(http.StatusConflict)
+	server.WriteFprint(w, message)
+}
@@ -0,0 +1,22 @@
+package fields
+
+import (
+	"basement/main/internal/common"
+	"context"
+	"errors"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+var ErrFieldExists = errors.New("field already exists")
+
+type FieldDatabase interface {
+	CustomFields(ctx context.Context, thing string) ([]common.CustomField, error)
+	CustomField(ctx context.Context, id uuid.UUID) (common.CustomField, error)
+	CreateCustomField(ctx context.Context, field common.CustomField) (uuid.UUID, error)
+	UpdateCustomField(ctx context.Context, field common.CustomField) error
+	DeleteCustomField(ctx context.Context, id uuid.UUID) error
+}
+
+// Things are the types of things that can have custom fields.
+var Things = []string{"item", "box", "shelf", "area"}
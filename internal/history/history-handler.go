@@ -0,0 +1,84 @@
+package history
+
+import (
+	"basement/main/internal/auth"
+	"basement/main/internal/common"
+	"basement/main/internal/server"
+	"basement/main/internal/templates"
+	"net/http"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// HistoryHandler renders the history of a single thing.
+// thing is the type of the thing like "item" or "box", the id is read from the path value "id".
+//
+//	GET /item/{id}/history
+func HistoryHandler(db HistoryDatabase, thing string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Add("Allow", http.MethodGet)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+			return
+		}
+
+		id := server.ValidID(w, r, "can't show history, invalid id")
+		if id == uuid.Nil {
+			return
+		}
+
+		page := common.ParsePageNumber(r)
+		entries, err := db.History(r.Context(), thing, id, common.ParseLimit(r), page)
+		if err != nil {
+			server.WriteInternalServerError("can't query history of "+thing, err, w, r)
+			return
+		}
+
+		title := "History of " + thing
+		if len(entries) > 0 {
+			title += ` "` + entries[0].Label + `"`
+		}
+		renderHistoryPage(w, r, title, "", entries, page)
+	}
+}
+
+// ActivityHandler renders the latest changes of all things in the household.
+//
+//	GET /activity
+func ActivityHandler(db HistoryDatabase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Add("Allow", http.MethodGet)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+			return
+		}
+
+		page := common.ParsePageNumber(r)
+		entries, err := db.Activity(r.Context(), common.ParseLimit(r), page)
+		if err != nil {
+			server.WriteInternalServerError("can't query activity", err, w, r)
+			return
+		}
+		renderHistoryPage(w, r, "Activity", "Activity", entries, page)
+	}
+}
+
+func renderHistoryPage(w http.ResponseWriter, r *http.Request, title string, requestOrigin string, entries []Entry, pageNr int) {
+	authenticated, _ := auth.Authenticated(r)
+	username, _ := auth.UserSessionData(r)
+	page := templates.NewPageTemplate()
+	page.Title = title
+	page.RequestOrigin = requestOrigin
+	page.Authenticated = authenticated
+	page.User = username
+
+	data := page.Map()
+	data["Entries"] = entries
+	data["PageNumber"] = pageNr
+	data["PreviousPage"] = pageNr - 1
+	data["NextPage"] = pageNr + 1
+	data["HasNextPage"] = len(entries) == common.ParseLimit(r)
+	server.MustRender(w, r, "history-page", data)
+}
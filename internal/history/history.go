@@ -0,0 +1,95 @@
+package history
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// Action that changed a thing.
+type Action string
+
+const (
+	ACTION_CREATE  Action = "create"
+	ACTION_UPDATE  Action = "update"
+	ACTION_MOVE    Action = "move"
+	ACTION_DELETE  Action = "delete"  // moved to the trash
+	ACTION_RESTORE Action = "restore" // restored from the trash
+	ACTION_PURGE   Action = "purge"   // permanently removed from the trash
+)
+
+type HistoryDatabase interface {
+	History(ctx context.Context, thing string, id uuid.UUID, limit int, page int) ([]Entry, error)
+	Activity(ctx context.Context, limit int, page int) ([]Entry, error)
+}
+
+// Entry is a single change of an item, box, shelf or area.
+// Entries are never changed or deleted after they are written.
+type Entry struct {
+	ID        int64
+	Time      time.Time
+	ActorID   uuid.UUID
+	ActorName string
+	Action    Action
+	Thing     string // "item", "box", "shelf" or "area"
+	ThingID   uuid.UUID
+	Label     string
+	Before    map[string]string // column values before the change, nil for ACTION_CREATE
+	After     map[string]string // column values after the change, nil for ACTION_DELETE
+	From      Location
+	To        Location
+}
+
+// Location is the innermost box, shelf or area a thing was in.
+// The label is stored as it was at the time of the change.
+type Location struct {
+	Thing string
+	ID    uuid.UUID
+	Label string
+}
+
+// Change of a single value.
+type Change struct {
+	Field  string
+	Before string
+	After  string
+}
+
+// Empty returns true if the thing wasn't inside of a box, shelf or area.
+func (l Location) Empty() bool {
+	return l.ID == uuid.Nil
+}
+
+func (l Location) String() string {
+	if l.Empty() {
+		return "-"
+	}
+	return l.Thing + ` "` + l.Label + `"`
+}
+
+// Moved returns true if the location of the thing changed.
+func (e Entry) Moved() bool {
+	return e.From.ID != e.To.ID
+}
+
+// Changes returns all values that differ between Before and After sorted by field.
+func (e Entry) Changes() []Change {
+	var changes []Change
+	for field, before := range e.Before {
+		after := e.After[field]
+		if before != after {
+			changes = append(changes, Change{Field: field, Before: before, After: after})
+		}
+	}
+	for field, after := range e.After {
+		if _, ok := e.Before[field]; !ok && after != "" {
+			changes = append(changes, Change{Field: field, After: after})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+	return changes
+}
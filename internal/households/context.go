@@ -0,0 +1,22 @@
+package households
+
+import (
+	"context"
+)
+
+type contextKey int
+
+const membershipKey contextKey = iota
+
+// WithMembership returns a copy of ctx that carries the membership of the logged in user.
+// Database functions only access things of this household.
+func WithMembership(ctx context.Context, m Membership) context.Context {
+	return context.WithValue(ctx, membershipKey, m)
+}
+
+// FromContext returns the membership stored with WithMembership.
+// ok is false if ctx has no membership.
+func FromContext(ctx context.Context) (m Membership, ok bool) {
+	m, ok = ctx.Value(membershipKey).(Membership)
+	return m, ok
+}
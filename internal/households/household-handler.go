@@ -0,0 +1,206 @@
+package households
+
+import (
+	"basement/main/internal/auth"
+	"basement/main/internal/logg"
+	"basement/main/internal/server"
+	"basement/main/internal/templates"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+var householdDB HouseholdDatabase
+
+// RegisterDBInstance sets db instance for internal package usage.
+// Is used for public functions that depend on the DB without the need to pass the instance as a parameter.
+func RegisterDBInstance(db HouseholdDatabase) {
+	householdDB = db
+	logg.Debug("householdDB in households package registered")
+}
+
+// WithRequestMembership returns a shallow copy of r whose context carries the membership
+// of the session user in the active household, see auth.WithSessionUser.
+// Falls back to the personal household if the user is not a member of the active household anymore.
+func WithRequestMembership(r *http.Request) (*http.Request, error) {
+	userID, ok := auth.UserID(r.Context())
+	if !ok {
+		return r, logg.NewError("no user in request context")
+	}
+
+	m, err := householdDB.Membership(r.Context(), userID, auth.ActiveHouseholdID(r))
+	if errors.Is(err, ErrNotMember) {
+		m, err = householdDB.Membership(r.Context(), userID, uuid.Nil)
+	}
+	if err != nil {
+		return r, logg.WrapErr(err)
+	}
+	return r.WithContext(WithMembership(r.Context(), m)), nil
+}
+
+// RequireRole returns true if the user of r has at least role in the active household.
+// Otherwise it writes a forbidden error and returns false.
+func RequireRole(w http.ResponseWriter, r *http.Request, role Role) bool {
+	m, ok := FromContext(r.Context())
+	if ok && m.Role.Allows(role) {
+		return true
+	}
+	server.WriteForbiddenError("You need to be "+string(role)+" of this household to do this.", w, r)
+	return false
+}
+
+// PageHandler renders the household page with the members of the active household.
+func PageHandler(db HouseholdDatabase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Add("Allow", http.MethodGet)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+			return
+		}
+
+		m, _ := FromContext(r.Context())
+		households, err := db.Households(r.Context(), m.UserID)
+		if err != nil {
+			server.WriteInternalServerError("can't query households", err, w, r)
+			return
+		}
+		members, err := db.Members(r.Context(), m.HouseholdID)
+		if err != nil {
+			server.WriteInternalServerError("can't query household members", err, w, r)
+			return
+		}
+
+		authenticated, _ := auth.Authenticated(r)
+		username, _ := auth.UserSessionData(r)
+		page := templates.NewPageTemplate()
+		page.Title = "Household"
+		page.RequestOrigin = "Household"
+		page.Authenticated = authenticated
+		page.User = username
+
+		data := page.Map()
+		data["Membership"] = m
+		data["CanManage"] = m.CanManage()
+		data["Households"] = households
+		data["Members"] = members
+		data["Roles"] = []Role{ROLE_VIEWER, ROLE_EDITOR, ROLE_OWNER}
+		server.MustRender(w, r, "household-page", data)
+	}
+}
+
+// HouseholdsHandler creates a new household with the user as owner and switches to it.
+//
+//	POST = create household from form value "name"
+func HouseholdsHandler(db HouseholdDatabase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Add("Allow", http.MethodPost)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+			return
+		}
+
+		name := strings.TrimSpace(r.PostFormValue("name"))
+		if name == "" {
+			server.TriggerSingleErrorNotification(w, "The household needs a name.")
+			return
+		}
+
+		m, _ := FromContext(r.Context())
+		id, err := db.CreateHousehold(r.Context(), m.UserID, name)
+		if err != nil {
+			server.WriteInternalServerError("can't create household", err, w, r)
+			return
+		}
+		err = auth.SetActiveHouseholdID(w, r, id)
+		if err != nil {
+			server.WriteInternalServerError("can't switch household", err, w, r)
+			return
+		}
+		server.RedirectWithSuccessNotification(w, "/household", "Created household "+name)
+	}
+}
+
+// SwitchHandler makes the household with the path value "id" the active household of the user.
+func SwitchHandler(db HouseholdDatabase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Add("Allow", http.MethodPost)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+			return
+		}
+
+		id := server.ValidID(w, r, "can't switch household, invalid id")
+		if id == uuid.Nil {
+			return
+		}
+
+		m, _ := FromContext(r.Context())
+		to, err := db.Membership(r.Context(), m.UserID, id)
+		if err != nil {
+			server.WriteNotFoundError("can't find household", err, w, r)
+			return
+		}
+		err = auth.SetActiveHouseholdID(w, r, to.HouseholdID)
+		if err != nil {
+			server.WriteInternalServerError("can't switch household", err, w, r)
+			return
+		}
+		server.RedirectWithSuccessNotification(w, "/household", "Switched to household "+to.HouseholdName)
+	}
+}
+
+// MembersHandler invites and removes members of the active household.
+//
+//	POST   = add user with form values "username" and "role"
+//	DELETE = remove user with path value "id"
+func MembersHandler(db HouseholdDatabase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		m, _ := FromContext(r.Context())
+
+		switch r.Method {
+		case http.MethodPost:
+			username := strings.TrimSpace(r.PostFormValue("username"))
+			role, err := ParseRole(r.PostFormValue("role"))
+			if err != nil {
+				server.WriteBadRequestError("invalid role", err, w, r)
+				return
+			}
+			err = db.AddMember(r.Context(), m.HouseholdID, username, role)
+			if err != nil {
+				logg.Err(err)
+				server.TriggerSingleErrorNotification(w, `Can't invite "`+username+`".`)
+				return
+			}
+			server.RedirectWithSuccessNotification(w, "/household", `Invited "`+username+`" as `+string(role))
+			break
+
+		case http.MethodDelete:
+			id := server.ValidID(w, r, "can't remove member, invalid id")
+			if id == uuid.Nil {
+				return
+			}
+			err := db.RemoveMember(r.Context(), m.HouseholdID, id)
+			if errors.Is(err, ErrLastOwner) {
+				server.TriggerSingleErrorNotification(w, "The last owner can't be removed.")
+				return
+			}
+			if err != nil {
+				server.WriteNotFoundError("can't remove member", err, w, r)
+				return
+			}
+			server.RedirectWithSuccessNotification(w, "/household", "Removed member")
+			break
+
+		default:
+			w.Header().Add("Allow", http.MethodPost)
+			w.Header().Add("Allow", http.MethodDelete)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package households
+
+import (
+	"basement/main/internal/logg"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// Role of a user inside of a household.
+type Role string
+
+const (
+	ROLE_VIEWER Role = "viewer" // can only read things
+	ROLE_EDITOR Role = "editor" // can create, update, move and delete things
+	ROLE_OWNER  Role = "owner"  // can additionally invite and remove members
+)
+
+var ErrNotMember = errors.New("not a member of the household")
+var ErrLastOwner = errors.New("household needs at least one owner")
+
+type HouseholdDatabase interface {
+	Membership(ctx context.Context, userID uuid.UUID, householdID uuid.UUID) (Membership, error)
+	Households(ctx context.Context, userID uuid.UUID) ([]Membership, error)
+	Members(ctx context.Context, householdID uuid.UUID) ([]Member, error)
+	CreateHousehold(ctx context.Context, userID uuid.UUID, name string) (uuid.UUID, error)
+	AddMember(ctx context.Context, householdID uuid.UUID, username string, role Role) error
+	RemoveMember(ctx context.Context, householdID uuid.UUID, userID uuid.UUID) error
+}
+
+// Membership is the role of a user in a household.
+// Things like items and boxes belong to the household, not to a single user.
+type Membership struct {
+	HouseholdID   uuid.UUID
+	HouseholdName string
+	UserID        uuid.UUID
+	Role          Role
+}
+
+// Member of a household as shown in the member list.
+type Member struct {
+	UserID   uuid.UUID
+	Username string
+	Role     Role
+}
+
+// ParseRole returns the role with the name s.
+func ParseRole(s string) (Role, error) {
+	role := Role(s)
+	if role.level() == 0 {
+		return "", logg.NewError(fmt.Sprintf(`unknown role "%s"`, s))
+	}
+	return role, nil
+}
+
+func (role Role) level() int {
+	switch role {
+	case ROLE_VIEWER:
+		return 1
+	case ROLE_EDITOR:
+		return 2
+	case ROLE_OWNER:
+		return 3
+	}
+	return 0
+}
+
+// Allows returns true if role has at least the permissions of required.
+//
+//	ROLE_OWNER.Allows(ROLE_EDITOR)  // true
+//	ROLE_VIEWER.Allows(ROLE_EDITOR) // false
+func (role Role) Allows(required Role) bool {
+	return role.level() >= required.level() && role.level() > 0
+}
+
+// CanEdit returns true if things of the household can be created, updated, moved or deleted.
+func (m Membership) CanEdit() bool {
+	return m.Role.Allows(ROLE_EDITOR)
+}
+
+// CanManage returns true if members can be invited and removed.
+func (m Membership) CanManage() bool {
+	return m.Role.Allows(ROLE_OWNER)
+}
@@ -0,0 +1,89 @@
+package insurance
+
+import (
+	"basement/main/internal/auth"
+	"basement/main/internal/env"
+	"basement/main/internal/server"
+	"basement/main/internal/templates"
+	"bytes"
+	"net/http"
+	"time"
+)
+
+// ReportHandler serves the insurance report of the items with a price.
+//
+//	GET /reports/insurance            = printable HTML with the pictures of the items
+//	GET /reports/insurance?format=csv = the items as CSV attachment
+//	GET /api/v1/reports/insurance     = the report as JSON
+func ReportHandler(db InsuranceDatabase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Add("Allow", http.MethodGet)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+			return
+		}
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = FORMAT_HTML
+		}
+		if format != FORMAT_HTML && format != FORMAT_CSV {
+			server.WriteBadRequestError(`The format must be "html" or "csv".`, nil, w, r)
+			return
+		}
+		items, err := db.InsuranceItems(r.Context())
+		if err != nil {
+			server.WriteInternalServerError("can't query the items with a price", err, w, r)
+			return
+		}
+		places, err := db.InsurancePlaces(r.Context())
+		if err != nil {
+			server.WriteInternalServerError("can't query the places of the items", err, w, r)
+			return
+		}
+		report := NewReport(items, places)
+
+		if format == FORMAT_CSV {
+			var b bytes.Buffer
+			err := WriteCSV(&b, report, baseURL(r))
+			if err != nil {
+				server.WriteInternalServerError("can't write the insurance report", err, w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			w.Header().Set("Content-Disposition", `attachment; filename="insurance-report.csv"`)
+			w.Write(b.Bytes())
+			return
+		}
+		if !server.WantsTemplateData(r) {
+			server.WriteJSON(w, report)
+			return
+		}
+
+		authenticated, _ := auth.Authenticated(r)
+		user, _ := auth.UserSessionData(r)
+		page := templates.NewPageTemplate()
+		page.Title = "Insurance report"
+		page.RequestOrigin = "Insurance"
+		page.Authenticated = authenticated
+		page.User = user
+
+		data := page.Map()
+		data["Report"] = report
+		data["Date"] = time.Now().Format("2006-01-02")
+		server.MustRender(w, r, "insurance-report-page", data)
+	}
+}
+
+// baseURL returns the configured public URL, like "http://localhost:8101".
+// Falls back to the scheme and host the request was sent to if no public URL is configured.
+func baseURL(r *http.Request) string {
+	if public := env.CurrentConfig().PublicURL(); public != "" {
+		return public
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
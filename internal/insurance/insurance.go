@@ -0,0 +1,213 @@
+package insurance
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+const (
+	FORMAT_HTML = "html"
+	FORMAT_CSV  = "csv"
+)
+
+type InsuranceDatabase interface {
+	InsuranceItems(ctx context.Context) ([]Item, error)
+	InsurancePlaces(ctx context.Context) ([]Place, error)
+}
+
+// Item is an item with a price and the details an insurance asks for.
+type Item struct {
+	ID            uuid.UUID `json:"id"`
+	Label         string    `json:"label"`
+	Quantity      int64     `json:"quantity"`
+	Price         float64   `json:"price"` // price of one piece
+	Currency      string    `json:"currency"`
+	PurchaseDate  string    `json:"purchaseDate"`
+	Vendor        string    `json:"vendor"`
+	SerialNumber  string    `json:"serialNumber"`
+	WarrantyUntil string    `json:"warrantyUntil"`
+	Picture       string    `json:"-"` // refers to the picture of the item, "" if it has none
+	BoxID         uuid.UUID `json:"boxId"`
+	ShelfID       uuid.UUID `json:"shelfId"`
+	AreaID        uuid.UUID `json:"areaId"`
+	// Filled by NewReport.
+	Path string `json:"path"` // labels of the area, shelf and boxes the item is in, like "Basement > Shelf A > Box 1"
+}
+
+// Value returns the price of all pieces of the item.
+func (i Item) Value() float64 {
+	return i.Price * float64(i.Quantity)
+}
+
+// Place is an area, a shelf or a box with the places it is in.
+type Place struct {
+	Thing   string    `json:"thing"` // "area", "shelf" or "box"
+	ID      uuid.UUID `json:"id"`
+	Label   string    `json:"label"`
+	BoxID   uuid.UUID `json:"-"` // outer box of a box
+	ShelfID uuid.UUID `json:"-"` // shelf of a box
+	AreaID  uuid.UUID `json:"-"` // area of a box or a shelf
+}
+
+// Total is the value of items in one currency. Currency is "" for items without a currency.
+type Total struct {
+	Currency string  `json:"currency"`
+	Value    float64 `json:"value"`
+}
+
+func (t Total) String() string {
+	if t.Currency == "" {
+		return fmt.Sprintf("%.2f", t.Value)
+	}
+	return fmt.Sprintf("%.2f %s", t.Value, t.Currency)
+}
+
+// PlaceTotal is the value of the items in a place and in all places inside of it.
+type PlaceTotal struct {
+	Place
+	Path   string  `json:"path"`
+	Totals []Total `json:"totals"`
+}
+
+// Report lists the items with a price and totals their value per place.
+type Report struct {
+	Items       []Item       `json:"items"`  // sorted by path and label
+	Places      []PlaceTotal `json:"places"` // places with items, sorted by path
+	Totals      []Total      `json:"totals"` // value of all items per currency
+	Unallocated []Total      `json:"unallocated"`
+}
+
+// NewReport builds the report of the items with a price above 0.
+// An item in a box counts for the box, its outer boxes and the shelf and area of the innermost box that has one.
+// Items that aren't in any place only count for the totals and Unallocated.
+func NewReport(items []Item, places []Place) Report {
+	byID := make(map[uuid.UUID]Place, len(places))
+	for _, p := range places {
+		byID[p.ID] = p
+	}
+	placeTotals := map[uuid.UUID]map[string]float64{}
+	totals := map[string]float64{}
+	unallocated := map[string]float64{}
+
+	report := Report{Items: []Item{}, Places: []PlaceTotal{}}
+	for _, item := range items {
+		if item.Price <= 0 {
+			continue
+		}
+		chain := containers(byID, item.BoxID, item.ShelfID, item.AreaID)
+		item.Path = path(chain)
+		report.Items = append(report.Items, item)
+
+		totals[item.Currency] += item.Value()
+		if len(chain) == 0 {
+			unallocated[item.Currency] += item.Value()
+		}
+		for _, p := range chain {
+			if placeTotals[p.ID] == nil {
+				placeTotals[p.ID] = map[string]float64{}
+			}
+			placeTotals[p.ID][item.Currency] += item.Value()
+		}
+	}
+
+	for id, values := range placeTotals {
+		place := byID[id]
+		chain := containers(byID, place.BoxID, place.ShelfID, place.AreaID)
+		chain = append([]Place{place}, chain...)
+		report.Places = append(report.Places, PlaceTotal{Place: place, Path: path(chain), Totals: sortedTotals(values)})
+	}
+	sort.Slice(report.Places, func(i, j int) bool { return report.Places[i].Path < report.Places[j].Path })
+	sort.SliceStable(report.Items, func(i, j int) bool {
+		if report.Items[i].Path != report.Items[j].Path {
+			return report.Items[i].Path < report.Items[j].Path
+		}
+		return report.Items[i].Label < report.Items[j].Label
+	})
+	report.Totals = sortedTotals(totals)
+	report.Unallocated = sortedTotals(unallocated)
+	return report
+}
+
+// containers returns the boxes, the shelf and the area something with these ids is in, the innermost first.
+// The box wins over the shelf and area ids, because moving something into a box keeps its old ids.
+func containers(places map[uuid.UUID]Place, boxID uuid.UUID, shelfID uuid.UUID, areaID uuid.UUID) []Place {
+	var chain []Place
+	if _, ok := places[boxID]; ok {
+		shelfID, areaID = uuid.Nil, uuid.Nil
+	}
+	seen := map[uuid.UUID]bool{}
+	for boxID != uuid.Nil && !seen[boxID] {
+		box, ok := places[boxID]
+		if !ok {
+			break
+		}
+		seen[boxID] = true
+		chain = append(chain, box)
+		if shelfID == uuid.Nil && areaID == uuid.Nil {
+			shelfID, areaID = box.ShelfID, box.AreaID
+		}
+		boxID = box.BoxID
+	}
+	if shelf, ok := places[shelfID]; ok {
+		chain = append(chain, shelf)
+		if areaID == uuid.Nil {
+			areaID = shelf.AreaID
+		}
+	}
+	if area, ok := places[areaID]; ok {
+		chain = append(chain, area)
+	}
+	return chain
+}
+
+// path joins the labels of the places from the outermost to the innermost.
+func path(chain []Place) string {
+	labels := make([]string, len(chain))
+	for i, p := range chain {
+		labels[len(chain)-1-i] = p.Label
+	}
+	return strings.Join(labels, " > ")
+}
+
+func sortedTotals(values map[string]float64) []Total {
+	totals := make([]Total, 0, len(values))
+	for currency, value := range values {
+		totals = append(totals, Total{Currency: currency, Value: value})
+	}
+	sort.Slice(totals, func(i, j int) bool { return totals[i].Currency < totals[j].Currency })
+	return totals
+}
+
+// WriteCSV writes a row for every item of the report.
+// The picture column links to the original picture on the server at baseURL, because CSV can't hold pictures.
+func WriteCSV(w io.Writer, report Report, baseURL string) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"Place", "Item", "Quantity", "Price", "Currency", "Value", "Purchase date", "Vendor", "Serial number", "Warranty until", "Picture"})
+	for _, item := range report.Items {
+		picture := ""
+		if item.Picture != "" {
+			picture = baseURL + "/picture/item/" + item.ID.String() + "/original"
+		}
+		out.Write([]string{
+			csvText(item.Path), csvText(item.Label), fmt.Sprint(item.Quantity), fmt.Sprintf("%.2f", item.Price), csvText(item.Currency),
+			fmt.Sprintf("%.2f", item.Value()), csvText(item.PurchaseDate), csvText(item.Vendor), csvText(item.SerialNumber), csvText(item.WarrantyUntil), picture,
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// csvText prefixes text that starts like a formula with "'", so spreadsheets show it as text.
+// Otherwise a member of the household could put a formula into the report another member opens.
+func csvText(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
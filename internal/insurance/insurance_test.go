@@ -0,0 +1,75 @@
+package insurance
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/gofrs/uuid/v5"
+)
+
+func TestNewReport(t *testing.T) {
+	area := Place{Thing: "area", ID: uuid.Must(uuid.NewV4()), Label: "Basement"}
+	shelf := Place{Thing: "shelf", ID: uuid.Must(uuid.NewV4()), Label: "Shelf", AreaID: area.ID}
+	outer := Place{Thing: "box", ID: uuid.Must(uuid.NewV4()), Label: "Outer", ShelfID: shelf.ID}
+	inner := Place{Thing: "box", ID: uuid.Must(uuid.NewV4()), Label: "Inner", BoxID: outer.ID}
+	places := []Place{area, shelf, outer, inner}
+
+	items := []Item{
+		// the old area id of an item moved into a box doesn't count
+		{ID: uuid.Must(uuid.NewV4()), Label: "Drill", Quantity: 1, Price: 120, Currency: "EUR", BoxID: inner.ID, AreaID: uuid.Must(uuid.NewV4())},
+		{ID: uuid.Must(uuid.NewV4()), Label: "Saw", Quantity: 2, Price: 15.5, Currency: "EUR", ShelfID: shelf.ID},
+		{ID: uuid.Must(uuid.NewV4()), Label: "Camera", Quantity: 1, Price: 300, Currency: "USD", AreaID: area.ID},
+		{ID: uuid.Must(uuid.NewV4()), Label: "Lamp", Quantity: 1, Price: 40, Currency: "EUR"},
+		{ID: uuid.Must(uuid.NewV4()), Label: "Screws", Quantity: 100, BoxID: inner.ID},
+	}
+	report := NewReport(items, places)
+
+	assert.Equal(t, len(report.Items), 4)
+	assert.Equal(t, report.Items[0].Label, "Lamp")
+	assert.Equal(t, report.Items[1].Label, "Camera")
+	assert.Equal(t, report.Items[3].Path, "Basement > Shelf > Outer > Inner")
+	assert.Equal(t, report.Totals, []Total{{Currency: "EUR", Value: 191}, {Currency: "USD", Value: 300}})
+	assert.Equal(t, report.Unallocated, []Total{{Currency: "EUR", Value: 40}})
+
+	assert.Equal(t, len(report.Places), 4)
+	assert.Equal(t, report.Places[0].Path, "Basement")
+	assert.Equal(t, report.Places[0].Totals, []Total{{Currency: "EUR", Value: 151}, {Currency: "USD", Value: 300}})
+	assert.Equal(t, report.Places[1].Path, "Basement > Shelf")
+	assert.Equal(t, report.Places[1].Totals, []Total{{Currency: "EUR", Value: 151}})
+	assert.Equal(t, report.Places[2].Path, "Basement > Shelf > Outer")
+	assert.Equal(t, report.Places[2].Totals, []Total{{Currency: "EUR", Value: 120}})
+	assert.Equal(t, report.Places[3].Path, "Basement > Shelf > Outer > Inner")
+}
+
+func TestWriteCSV(t *testing.T) {
+	id := uuid.Must(uuid.NewV4())
+	report := NewReport([]Item{{ID: id, Label: "Drill, cordless", Quantity: 2, Price: 60, Currency: "EUR", Picture: "iVBOR"}}, nil)
+	var b bytes.Buffer
+	assert.Equal(t, WriteCSV(&b, report, "http://localhost:8101"), nil)
+	lines := strings.Split(b.String(), "\n")
+	assert.Equal(t, lines[1], `,"Drill, cordless",2,60.00,EUR,120.00,,,,,http://localhost:8101/picture/item/`+id.String()+"/original")
+}
+
+func TestWriteCSVFormulas(t *testing.T) {
+	area := Place{Thing: "area", ID: uuid.Must(uuid.NewV4()), Label: "@Garage"}
+	report := NewReport([]Item{
+		{ID: uuid.Must(uuid.NewV4()), Label: `=HYPERLINK("http://evil.com","Drill")`, Vendor: "+49 123", SerialNumber: "-5", Quantity: 1, Price: 2, Currency: "EUR", AreaID: area.ID},
+		{ID: uuid.Must(uuid.NewV4()), Label: "\tSaw", Vendor: "\rShop", SerialNumber: "A-5", Quantity: 1, Price: 1, Currency: "EUR", AreaID: area.ID},
+	}, []Place{area})
+	var b bytes.Buffer
+	assert.Equal(t, WriteCSV(&b, report, "http://localhost:8101"), nil)
+	records, err := csv.NewReader(&b).ReadAll()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(records), 3)
+	saw, drill := records[1], records[2]
+	assert.Equal(t, drill[0], "'@Garage")
+	assert.Equal(t, drill[1], `'=HYPERLINK("http://evil.com","Drill")`)
+	assert.Equal(t, drill[7], "'+49 123")
+	assert.Equal(t, drill[8], "'-5")
+	assert.Equal(t, saw[1], "'\tSaw")
+	assert.Equal(t, saw[7], "'\rShop")
+	assert.Equal(t, saw[8], "A-5")
+}
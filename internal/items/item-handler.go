@@ -58,7 +58,7 @@ func createItem(w http.ResponseWriter, r *http.Request, db ItemDatabase) {
 	validator, err := ValidateItem(r, w)
 	if err != nil {
 		if err == validator.Err() {
-			renderItemTemplate(r, w, validator.ItemFormData(), common.CreateMode)
+			renderItemTemplate(r, w, db, common.WithCustomFieldInputs(validator.ItemFormData()), common.CreateMode)
 		} else {
 			logg.Err(err)
 			server.TriggerSingleErrorNotification(w, "Error while generating the Item please comeback later")
@@ -67,8 +67,9 @@ func createItem(w http.ResponseWriter, r *http.Request, db ItemDatabase) {
 	}
 
 	item := ToItem(validator.Item)
+	item.Tags = common.ParseTags(r)
 
-	if err := db.CreateNewItem(item); err != nil {
+	if err := db.CreateNewItem(r.Context(), item); err != nil {
 		if err == db.ErrorExist() {
 			logg.Debugf("the Label is already token please choice another one", err)
 			templates.RenderErrorNotification(w, "the Label is already token please choice another one")
@@ -87,7 +88,7 @@ func updateItem(w http.ResponseWriter, r *http.Request, db ItemDatabase) {
 	if err != nil {
 		if err == validator.Err() {
 			logg.Debugf("validation error while updating the Item: %v", err)
-			renderItemTemplate(r, w, validator.ItemFormData(), common.EditMode)
+			renderItemTemplate(r, w, db, common.WithCustomFieldInputs(validator.ItemFormData()), common.EditMode)
 		} else {
 			logg.Debugf("error happened while updating the Item: %v", err)
 			server.TriggerSingleErrorNotification(w, "Error while generating the Item please comeback later")
@@ -96,6 +97,7 @@ func updateItem(w http.ResponseWriter, r *http.Request, db ItemDatabase) {
 	}
 
 	item := ToItem(validator.Item)
+	item.Tags = common.ParseTags(r)
 	ignorePicture := server.ParseIgnorePicture(r)
 	pictureFormat := ""
 	if !ignorePicture {
@@ -105,7 +107,7 @@ func updateItem(w http.ResponseWriter, r *http.Request, db ItemDatabase) {
 		}
 	}
 
-	err = db.UpdateItem(item, ignorePicture, pictureFormat)
+	err = db.UpdateItem(r.Context(), item, ignorePicture, pictureFormat)
 	if err != nil {
 		server.WriteNotFoundError("Can't update item. "+logg.CleanLastError(err), err, w, r)
 		return
@@ -121,7 +123,7 @@ func deleteItem(w http.ResponseWriter, r *http.Request, db ItemDatabase) {
 		return
 	}
 
-	if err := db.DeleteItem(id); err != nil {
+	if err := db.DeleteItem(r.Context(), id); err != nil {
 		server.WriteBadRequestError(logg.CleanLastError(err), err, w, r)
 		return
 	}
@@ -146,7 +148,7 @@ func MoveItem(db ItemDatabase) func(w http.ResponseWriter, r *http.Request) {
 			server.WriteInternalServerError(errMsgForUser, err, w, r)
 			return
 		}
-		err = db.MoveItemToBox(id, id2)
+		err = db.MoveItemToBox(r.Context(), id, id2)
 		if err != nil {
 			err = logg.Errorf("%s %w", errMsgForUser, err)
 			server.WriteInternalServerError(errMsgForUser, err, w, r)
@@ -5,8 +5,13 @@ import (
 	"basement/main/internal/env"
 	"basement/main/internal/logg"
 	"basement/main/internal/server"
+	"basement/main/internal/stock"
 	"basement/main/internal/templates"
+	"context"
 	"net/http"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
 )
 
 // Render Item Root page where you can search the available Items
@@ -19,15 +24,29 @@ func PageTemplate(db ItemDatabase) http.HandlerFunc {
 		data.TypeMap["HideBoxLabel"] = false
 		data.TypeMap["HideShelfLabel"] = false
 		data.TypeMap["HideAreaLabel"] = false
+		categories, err := db.Categories(r.Context())
+		if err != nil {
+			server.WriteInternalServerError("can't query categories please comeback later", err, w, r)
+			return
+		}
+		data.TypeMap["Categories"] = categories
 		server.MustRender(w, r, "item-page-template", data.TypeMap)
 	}
 }
 
-// Render create Item Template with default values
-func CreateTemplate() http.HandlerFunc {
+// Render create Item Template with default values.
+// The query value "category" preselects the category of the new item.
+func CreateTemplate(db ItemDatabase) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		item := newItem()
-		renderItemTemplate(r, w, item.Map(), common.CreateMode)
+		item.CategoryID = uuid.FromStringOrNil(r.URL.Query().Get("category"))
+		var err error
+		item.CustomFields, err = common.EmptyCustomFieldValues(r.Context(), "item")
+		if err != nil {
+			server.WriteInternalServerError("can't load the custom fields of items please comeback later", err, w, r)
+			return
+		}
+		renderItemTemplate(r, w, db, item.Map(), common.CreateMode)
 	}
 }
 
@@ -38,12 +57,12 @@ func UpdateTemplate(db ItemDatabase) http.HandlerFunc {
 		if id.IsNil() {
 			return
 		}
-		item, err := db.ItemById(id)
+		item, err := db.ItemById(r.Context(), id)
 		if err != nil {
 			server.WriteInternalServerError("can't query items please comeback later", err, w, r)
 			return
 		}
-		renderItemTemplate(r, w, item.Map(), common.EditMode)
+		renderItemTemplate(r, w, db, item.Map(), common.EditMode)
 	}
 }
 
@@ -54,12 +73,23 @@ func PreviewTemplate(db ItemDatabase) http.HandlerFunc {
 		if id.IsNil() {
 			return
 		}
-		item, err := db.ItemById(id)
+		item, err := db.ItemById(r.Context(), id)
 		if err != nil {
 			server.WriteInternalServerError("can't query items please comeback later", err, w, r)
 			return
 		}
-		renderItemTemplate(r, w, item.Map(), common.PreviewMode)
+		values := item.Map()
+		changes, err := db.StockChanges(r.Context(), id)
+		if err != nil {
+			logg.Warningf("can't load the stock changes of item %s %v", id, err)
+		}
+		values["StockChanges"] = changes
+		values["StockChart"] = stock.NewChart(changes, time.Now())
+		values["Loan"], err = db.ThingLoan(r.Context(), "item", id)
+		if err != nil {
+			logg.Warningf("can't load the loan of item %s %v", id, err)
+		}
+		renderItemTemplate(r, w, db, values, common.PreviewMode)
 	}
 }
 
@@ -67,7 +97,7 @@ func PreviewTemplate(db ItemDatabase) http.HandlerFunc {
 func getTemplateData(r *http.Request, db ItemDatabase, w http.ResponseWriter) common.Data {
 	data := common.InitData(r, true)
 
-	count, err := db.ItemListCounter(data.GetSearchInputValue())
+	count, err := db.ItemListCounter(r.Context(), data.GetSearchInputValue())
 	if err != nil {
 		server.WriteInternalServerError("error items counter", err, w, r)
 		return common.Data{}
@@ -85,7 +115,7 @@ func getTemplateData(r *http.Request, db ItemDatabase, w http.ResponseWriter) co
 	var items []common.ListRow
 	if count > 0 {
 		data.SetListRowTemplateOptions(common.ListRowTemplateOptions{RowHXGet: "item"})
-		items, err = filledItemRows(db, data)
+		items, err = filledItemRows(r.Context(), db, data)
 		if err != nil {
 			server.WriteInternalServerError("can't query items please comeback later", err, w, r)
 			return common.Data{}
@@ -98,10 +128,10 @@ func getTemplateData(r *http.Request, db ItemDatabase, w http.ResponseWriter) co
 
 // filledItemRows returns ListRows of Items with empty entries filled up to match limit.
 // count - The total number of records found from the search query.
-func filledItemRows(db ItemDatabase, data common.Data) ([]common.ListRow, error) {
+func filledItemRows(ctx context.Context, db ItemDatabase, data common.Data) ([]common.ListRow, error) {
 	limit := data.GetLimit()
 	itemsMaps := make([]common.ListRow, limit)
-	items, err := db.ItemListRows(data.GetSearchInputValue(), limit, data.GetPageNumber())
+	items, err := db.ItemListRows(ctx, data.GetSearchInputValue(), limit, data.GetPageNumber())
 	if err != nil {
 		return nil, logg.WrapErr(err)
 	}
@@ -118,14 +148,19 @@ func filledItemRows(db ItemDatabase, data common.Data) ([]common.ListRow, error)
 	return itemsMaps, nil
 }
 
-func renderItemTemplate(r *http.Request, w http.ResponseWriter, values map[string]any, typeMode common.Mode) {
+func renderItemTemplate(r *http.Request, w http.ResponseWriter, db ItemDatabase, values map[string]any, typeMode common.Mode) {
+	categories, err := db.Categories(r.Context())
+	if err != nil {
+		logg.Warningf("can't load the categories of the item form %v", err)
+	}
+	values["Categories"] = categories
 	data := common.InitData(r, false)
 	data.SetEnvDevelopment(env.Development())
 	data.SetTypeMode(typeMode)
 	data.SetDetailesData(values)
 	data.SetOrigin("Item")
 	data.SetTitle(common.ToUpper(string(typeMode)) + " Item")
-	err := templates.Render(w, "item-template", data.TypeMap)
+	err = templates.Render(w, "item-template", data.TypeMap)
 	if err != nil {
 		logg.Warningf("An Error accrue while fetching item Extra Info", err)
 	}
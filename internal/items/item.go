@@ -1,11 +1,16 @@
 package items
 
 import (
+	"basement/main/internal/categories"
 	"basement/main/internal/common"
+	"basement/main/internal/lending"
 	"basement/main/internal/logg"
+	"basement/main/internal/stock"
 	"basement/main/internal/validate"
+	"context"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/gofrs/uuid/v5"
 )
@@ -20,6 +25,20 @@ type Item struct {
 	ShelfLabel string
 	AreaID     uuid.UUID
 	AreaLabel  string
+	CategoryID uuid.UUID
+	BestBefore string // date in validate.DATE_LAYOUT, "" if the item has none
+	ExpiresAt  string // date in validate.DATE_LAYOUT, "" if the item has none
+	MinStock   int64  // the item is low on stock if Quantity is below, 0 for no threshold
+	// Purchase details for insurance, all empty or 0 if unknown.
+	PurchaseDate  string  // date in validate.DATE_LAYOUT
+	Price         float64 // price of one piece
+	Currency      string  // ISO 4217 code like "EUR"
+	Vendor        string
+	SerialNumber  string
+	WarrantyUntil string // date in validate.DATE_LAYOUT
+	// Filled when reading an item with a category.
+	CategoryPath string // like "Tools > Power Tools > Drills"
+	Unit         string // unit hint of the category for the quantity
 }
 
 func (i Item) String() string {
@@ -28,43 +47,47 @@ func (i Item) String() string {
 }
 
 type ItemDatabase interface {
-	CreateNewItem(newItem Item) error
-	ItemByField(field string, value string) (Item, error)
-	ItemListRowByID(id uuid.UUID) (*common.ListRow, error)
-	ItemById(id uuid.UUID) (*Item, error)
-	ItemIDs() ([]uuid.UUID, error)
-	ItemExist(field string, value string) bool
-	Items() ([][]string, error)
-	UpdateItem(item Item, ignorePicture bool, pictureFormat string) error
-	DeleteItem(itemId uuid.UUID) error
-	DeleteItems(itemId []uuid.UUID) error
-	InsertSampleItems()
+	CreateNewItem(ctx context.Context, newItem Item) error
+	ItemByField(ctx context.Context, field string, value string) (Item, error)
+	ItemListRowByID(ctx context.Context, id uuid.UUID) (*common.ListRow, error)
+	ItemById(ctx context.Context, id uuid.UUID) (*Item, error)
+	ItemIDs(ctx context.Context) ([]uuid.UUID, error)
+	ItemExist(ctx context.Context, field string, value string) bool
+	Items(ctx context.Context) ([][]string, error)
+	UpdateItem(ctx context.Context, item Item, ignorePicture bool, pictureFormat string) error
+	DeleteItem(ctx context.Context, itemId uuid.UUID) error
+	DeleteItems(ctx context.Context, itemId []uuid.UUID) error
+	InsertSampleItems(ctx context.Context)
 	ErrorExist() error
-	MoveItemToBox(itemID uuid.UUID, boxID uuid.UUID) error
-	MoveItemToShelf(itemID uuid.UUID, shelfID uuid.UUID) error
-	MoveItemToArea(itemID uuid.UUID, areaID uuid.UUID) error
+	MoveItemToBox(ctx context.Context, itemID uuid.UUID, boxID uuid.UUID) error
+	MoveItemToShelf(ctx context.Context, itemID uuid.UUID, shelfID uuid.UUID) error
+	MoveItemToArea(ctx context.Context, itemID uuid.UUID, areaID uuid.UUID) error
 
 	// search functions
-	ItemListCounter(queryString string) (count int, err error)
-	ItemListRows(searchString string, limit int, pageNr int) (shelfRows []common.ListRow, err error)
+	ItemListCounter(ctx context.Context, queryString string) (count int, err error)
+	ItemListRows(ctx context.Context, searchString string, limit int, pageNr int) (shelfRows []common.ListRow, err error)
 
 	// required in common.Database interface
-	InnerListRowsFrom2(belongsToTable string, belongsToTableID uuid.UUID, listRowsTable string) ([]common.ListRow, error)
-	InnerListRowsPaginatedFrom(belongsToTable string, belongsToTableID uuid.UUID, listRowsTable string, searchQuery string, limit int, page int) (listRows []common.ListRow, err error)
-	InnerBoxInBoxListCounter(searchString string, inTable string, inTableID uuid.UUID) (count int, err error)
-	InnerShelfInTableListCounter(searchString string, inTable string, inTableID uuid.UUID) (count int, err error)
-	InnerThingInTableListCounter(searchString string, thing int, inTable string, inTableID uuid.UUID) (count int, err error)
-	MoveShelfToArea(shelfID uuid.UUID, toAreaID uuid.UUID) error
-	BoxListCounter(searchQuery string) (count int, err error)
-	ShelfListCounter(searchQuery string) (count int, err error)
-	ShelfListRows(searchQuery string, limit int, page int) (shelfRows []common.ListRow, err error)
-	AreaListCounter(searchQuery string) (count int, err error)
-	BoxListRows(searchQuery string, limit int, page int) ([]common.ListRow, error)
-	AreaListRows(searchQuery string, limit int, page int) (areaRows []common.ListRow, err error)
-	DeleteBox(boxID uuid.UUID) error
-	DeleteShelf(id uuid.UUID) (label string, err error)
-	DeleteShelf2(id uuid.UUID) error
-	DeleteArea(areaID uuid.UUID) error
+	InnerListRowsFrom2(ctx context.Context, belongsToTable string, belongsToTableID uuid.UUID, listRowsTable string) ([]common.ListRow, error)
+	InnerListRowsPaginatedFrom(ctx context.Context, belongsToTable string, belongsToTableID uuid.UUID, listRowsTable string, searchQuery string, limit int, page int) (listRows []common.ListRow, err error)
+	InnerBoxInBoxListCounter(ctx context.Context, searchString string, inTable string, inTableID uuid.UUID) (count int, err error)
+	InnerShelfInTableListCounter(ctx context.Context, searchString string, inTable string, inTableID uuid.UUID) (count int, err error)
+	InnerThingInTableListCounter(ctx context.Context, searchString string, thing int, inTable string, inTableID uuid.UUID) (count int, err error)
+	MoveShelfToArea(ctx context.Context, shelfID uuid.UUID, toAreaID uuid.UUID) error
+	BoxListCounter(ctx context.Context, searchQuery string) (count int, err error)
+	ShelfListCounter(ctx context.Context, searchQuery string) (count int, err error)
+	ShelfListRows(ctx context.Context, searchQuery string, limit int, page int) (shelfRows []common.ListRow, err error)
+	AreaListCounter(ctx context.Context, searchQuery string) (count int, err error)
+	BoxListRows(ctx context.Context, searchQuery string, limit int, page int) ([]common.ListRow, error)
+	AreaListRows(ctx context.Context, searchQuery string, limit int, page int) (areaRows []common.ListRow, err error)
+	DeleteBox(ctx context.Context, boxID uuid.UUID) error
+	DeleteShelf(ctx context.Context, id uuid.UUID) (label string, err error)
+	DeleteShelf2(ctx context.Context, id uuid.UUID) error
+	DeleteArea(ctx context.Context, areaID uuid.UUID) error
+	CustomFields(ctx context.Context, thing string) ([]common.CustomField, error)
+	Categories(ctx context.Context) ([]categories.Category, error)
+	StockChanges(ctx context.Context, itemID uuid.UUID) ([]stock.Change, error)
+	ThingLoan(ctx context.Context, thing string, id uuid.UUID) (*lending.Loan, error)
 }
 
 const (
@@ -81,6 +104,17 @@ const (
 	SHELF_LABEL string = "shelf_label"
 	AREA_ID     string = "area_id"
 	AREA_LABEL  string = "area_label"
+	CATEGORY_ID string = "category_id"
+	BEST_BEFORE string = "best_before"
+	EXPIRES_AT  string = "expires_at"
+	MIN_STOCK   string = "min_stock"
+
+	PURCHASE_DATE  string = "purchase_date"
+	PRICE          string = "price"
+	CURRENCY       string = "currency"
+	VENDOR         string = "vendor"
+	SERIAL_NUMBER  string = "serial_number"
+	WARRANTY_UNTIL string = "warranty_until"
 )
 
 const (
@@ -91,19 +125,34 @@ const (
 // return the Item in type map
 func (s *Item) Map() map[string]any {
 	shelfMap := map[string]any{
-		"ID":             s.ID,
-		"Label":          s.Label,
-		"Description":    s.Description,
-		"Weight":         s.Weight,
-		"Quantity":       s.Quantity,
-		"Picture":        s.Picture,
-		"PreviewPicture": s.PreviewPicture,
-		"BoxID":          s.BoxID,
-		"BoxLabel":       s.BoxLabel,
-		"ShelfID":        s.ShelfID,
-		"ShelfLabel":     s.ShelfLabel,
-		"AreaID":         s.AreaID,
-		"AreaLabel":      s.AreaLabel,
+		"ID":                s.ID,
+		"Label":             s.Label,
+		"Description":       s.Description,
+		"Weight":            s.Weight,
+		"Quantity":          s.Quantity,
+		"Picture":           s.Picture,
+		"PreviewPicture":    s.PreviewPicture,
+		"QRCode":            s.QRCode,
+		"BoxID":             s.BoxID,
+		"BoxLabel":          s.BoxLabel,
+		"ShelfID":           s.ShelfID,
+		"ShelfLabel":        s.ShelfLabel,
+		"AreaID":            s.AreaID,
+		"AreaLabel":         s.AreaLabel,
+		"CategoryID":        s.CategoryID,
+		"CategoryPath":      s.CategoryPath,
+		"Unit":              s.Unit,
+		"BestBefore":        s.BestBefore,
+		"ExpiresAt":         s.ExpiresAt,
+		"MinStock":          s.MinStock,
+		"PurchaseDate":      s.PurchaseDate,
+		"Price":             s.Price,
+		"Currency":          s.Currency,
+		"Vendor":            s.Vendor,
+		"SerialNumber":      s.SerialNumber,
+		"WarrantyUntil":     s.WarrantyUntil,
+		"Tags":              s.Tags,
+		"CustomFieldInputs": s.CustomFieldInputs(),
 	}
 
 	return shelfMap
@@ -127,16 +176,28 @@ func newItem() *Item {
 func ToItem(validatedItem validate.ItemValidate) Item {
 	item := Item{
 		BasicInfo: common.BasicInfo{
-			ID:          validatedItem.ID.UUID(),
-			Label:       validatedItem.Label.String(),
-			Description: validatedItem.Description.String(),
-			Picture:     validatedItem.Picture.String(),
+			ID:           validatedItem.ID.UUID(),
+			Label:        validatedItem.Label.String(),
+			Description:  validatedItem.Description.String(),
+			Picture:      validatedItem.Picture.String(),
+			CustomFields: common.CustomFieldValues(validatedItem.CustomFields),
 		},
-		Quantity: validatedItem.Quantity.Int(),
-		Weight:   validatedItem.Weight.Float64(),
-		BoxID:    validatedItem.BoxID.UUID(),
-		ShelfID:  validatedItem.ShelfID.UUID(),
-		AreaID:   validatedItem.AreaID.UUID(),
+		Quantity:   validatedItem.Quantity.Int(),
+		Weight:     validatedItem.Weight.Float64(),
+		BoxID:      validatedItem.BoxID.UUID(),
+		ShelfID:    validatedItem.ShelfID.UUID(),
+		AreaID:     validatedItem.AreaID.UUID(),
+		CategoryID: validatedItem.CategoryID.UUID(),
+		BestBefore: validatedItem.BestBefore.String(),
+		ExpiresAt:  validatedItem.ExpiresAt.String(),
+		MinStock:   validatedItem.MinStock.Int(),
+
+		PurchaseDate:  validatedItem.PurchaseDate.String(),
+		Price:         validatedItem.Price.Float64(),
+		Currency:      validatedItem.Currency.String(),
+		Vendor:        validatedItem.Vendor.String(),
+		SerialNumber:  validatedItem.SerialNumber.String(),
+		WarrantyUntil: validatedItem.WarrantyUntil.String(),
 	}
 	return item
 }
@@ -151,12 +212,28 @@ func ValidateItem(r *http.Request, w http.ResponseWriter) (validate.Validate, er
 			Description: validate.NewStringField(r.PostFormValue(DESCRIPTION)),
 			Picture:     validate.NewStringField(common.ParsePicture(r)),
 		},
-		Quantity: validate.NewIntField(r.PostFormValue(QUANTITY)),
-		Weight:   validate.NewFloatField(r.PostFormValue(WEIGHT)),
-		BoxID:    validate.NewUUIDField(r.PostFormValue(BOX_ID)),
-		ShelfID:  validate.NewUUIDField(r.PostFormValue(SHELF_ID)),
-		AreaID:   validate.NewUUIDField(r.PostFormValue(AREA_ID)),
+		Quantity:   validate.NewIntField(r.PostFormValue(QUANTITY)),
+		Weight:     validate.NewFloatField(r.PostFormValue(WEIGHT)),
+		BoxID:      validate.NewUUIDField(r.PostFormValue(BOX_ID)),
+		ShelfID:    validate.NewUUIDField(r.PostFormValue(SHELF_ID)),
+		AreaID:     validate.NewUUIDField(r.PostFormValue(AREA_ID)),
+		CategoryID: validate.NewUUIDField(r.PostFormValue(CATEGORY_ID)),
+		BestBefore: validate.NewStringField(r.PostFormValue(BEST_BEFORE)),
+		ExpiresAt:  validate.NewStringField(r.PostFormValue(EXPIRES_AT)),
+		MinStock:   validate.NewIntField(r.PostFormValue(MIN_STOCK)),
+
+		PurchaseDate:  validate.NewStringField(r.PostFormValue(PURCHASE_DATE)),
+		Price:         validate.NewFloatField(r.PostFormValue(PRICE)),
+		Currency:      validate.NewStringField(strings.ToUpper(strings.TrimSpace(r.PostFormValue(CURRENCY)))),
+		Vendor:        validate.NewStringField(r.PostFormValue(VENDOR)),
+		SerialNumber:  validate.NewStringField(r.PostFormValue(SERIAL_NUMBER)),
+		WarrantyUntil: validate.NewStringField(r.PostFormValue(WARRANTY_UNTIL)),
 	}
+	customFields, err := common.ParseCustomFields(r, "item")
+	if err != nil {
+		return validate.Validate{}, logg.WrapErr(err)
+	}
+	item.CustomFields = customFields
 	logg.DebugJSONLite(item.Map(), 50)
 
 	validator := validate.Validate{Item: item}
@@ -0,0 +1,181 @@
+package lending
+
+import (
+	"basement/main/internal/auth"
+	"basement/main/internal/logg"
+	"basement/main/internal/server"
+	"basement/main/internal/templates"
+	"basement/main/internal/validate"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+const maxBorrowerLength = 100
+
+// LendHandler lends the item or box with the path value "id" out.
+//
+//	POST /item/{id}/lend        = lend to form value "borrower" until form value "due_date"
+//	POST /box/{id}/lend
+//	POST /api/v1/item/{id}/lend = same, responds with the loan as JSON
+//	POST /api/v1/box/{id}/lend
+func LendHandler(db LendingDatabase, thing string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Add("Allow", http.MethodPost)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+			return
+		}
+		id := server.ValidID(w, r, "can't lend "+thing+", invalid id")
+		if id == uuid.Nil {
+			return
+		}
+		borrower, dueDate, ok := validLoan(w, r)
+		if !ok {
+			return
+		}
+
+		loan, err := db.Lend(r.Context(), thing, id, borrower, dueDate)
+		if errors.Is(err, ErrAlreadyLent) {
+			writeAlreadyLent(w, r, thing, err)
+			return
+		}
+		if err != nil {
+			server.WriteNotFoundError("can't lend "+thing, err, w, r)
+			return
+		}
+		if !server.WantsTemplateData(r) {
+			w.WriteHeader(http.StatusCreated)
+			server.WriteJSON(w, loan)
+			return
+		}
+		server.RedirectWithSuccessNotification(w, "/"+thing+"/"+id.String(), "Lent to "+loan.Borrower+" until "+loan.DueDate)
+	}
+}
+
+// ReturnHandler marks the loan with the path value "id" as returned.
+//
+//	POST /loans/{id}/return        = redirects to the returned thing
+//	POST /api/v1/loans/{id}/return = responds with the loan as JSON
+func ReturnHandler(db LendingDatabase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Add("Allow", http.MethodPost)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+			return
+		}
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil || id <= 0 {
+			server.WriteBadRequestError("invalid loan", nil, w, r)
+			return
+		}
+		loan, err := db.ReturnLoan(r.Context(), id)
+		if err != nil {
+			server.WriteNotFoundError("can't return loan", err, w, r)
+			return
+		}
+		if !server.WantsTemplateData(r) {
+			server.WriteJSON(w, loan)
+			return
+		}
+		server.RedirectWithSuccessNotification(w, "/"+loan.Thing+"/"+loan.ThingID.String(), loan.Borrower+" returned "+loan.Label)
+	}
+}
+
+// LoansHandler lists the things of the household that are lent out, the earliest due first.
+//
+//	GET /loans                       = page with the overdue and all other loans
+//	GET /api/v1/loans                = all loans that aren't returned as JSON
+//	GET /api/v1/loans?status=overdue = only the overdue loans
+func LoansHandler(db LendingDatabase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Add("Allow", http.MethodGet)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+			return
+		}
+		status := r.URL.Query().Get("status")
+		if status == "" {
+			status = STATUS_LENT
+		}
+		if status != STATUS_LENT && status != STATUS_OVERDUE {
+			server.WriteBadRequestError(`The status must be "lent" or "overdue".`, nil, w, r)
+			return
+		}
+		loans, err := db.Loans(r.Context())
+		if err != nil {
+			server.WriteInternalServerError("can't query loans", err, w, r)
+			return
+		}
+		today := time.Now()
+		if !server.WantsTemplateData(r) {
+			server.WriteJSON(w, Filter(loans, status, today))
+			return
+		}
+
+		authenticated, _ := auth.Authenticated(r)
+		user, _ := auth.UserSessionData(r)
+		page := templates.NewPageTemplate()
+		page.Title = "Lent out"
+		page.RequestOrigin = "Loans"
+		page.Authenticated = authenticated
+		page.User = user
+
+		overdue := Filter(loans, STATUS_OVERDUE, today)
+		lent := []Loan{}
+		for _, l := range loans {
+			if !l.Overdue(today) {
+				lent = append(lent, l)
+			}
+		}
+		data := page.Map()
+		data["Overdue"] = overdue
+		data["Lent"] = lent
+		server.MustRender(w, r, "loans-page", data)
+	}
+}
+
+// validLoan returns the form values "borrower" and "due_date".
+// Spaces in the borrower are collapsed. Writes an error and returns false if they are invalid.
+func validLoan(w http.ResponseWriter, r *http.Request) (borrower string, dueDate string, ok bool) {
+	borrower = strings.Join(strings.Fields(r.FormValue("borrower")), " ")
+	dueDate = strings.TrimSpace(r.FormValue("due_date"))
+	message := ""
+	if borrower == "" {
+		message = "Who borrows it?"
+	} else if utf8.RuneCountInString(borrower) > maxBorrowerLength {
+		message = "The name of the borrower can't be longer than 100 characters."
+	} else if _, err := time.Parse(validate.DATE_LAYOUT, dueDate); err != nil {
+		message = "The due date must be a date like 2024-12-31."
+	} else if dueDate < time.Now().Format(validate.DATE_LAYOUT) {
+		message = "The due date can't be in the past."
+	}
+	if message != "" {
+		if server.WantsTemplateData(r) {
+			server.TriggerSingleErrorNotification(w, message)
+		} else {
+			server.WriteBadRequestError(message, nil, w, r)
+		}
+		return "", "", false
+	}
+	return borrower, dueDate, true
+}
+
+func writeAlreadyLent(w http.ResponseWriter, r *http.Request, thing string, err error) {
+	message := "The " + thing + " is already lent out."
+	if server.WantsTemplateData(r) {
+		logg.Err(err)
+		server.TriggerSingleErrorNotification(w, message)
+		return
+	}
+	w.WriteHeader(http.StatusConflict)
+	server.WriteFprint(w, message)
+}
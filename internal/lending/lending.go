@@ -0,0 +1,66 @@
+package lending
+
+import (
+	"basement/main/internal/validate"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+var ErrAlreadyLent = errors.New("already lent out")
+
+// Things that can be lent out. Everything inside a lent box is lent out with it.
+var Things = []string{"item", "box"}
+
+// Loan statuses to filter loans by.
+const (
+	STATUS_LENT    = "lent"    // not returned yet
+	STATUS_OVERDUE = "overdue" // not returned by the due date
+)
+
+type LendingDatabase interface {
+	Lend(ctx context.Context, thing string, id uuid.UUID, borrower string, dueDate string) (Loan, error)
+	ReturnLoan(ctx context.Context, id int64) (Loan, error)
+	Loans(ctx context.Context) ([]Loan, error)
+	ThingLoan(ctx context.Context, thing string, id uuid.UUID) (*Loan, error)
+}
+
+// Loan is an item or box lent to a borrower.
+type Loan struct {
+	ID         int64      `json:"id"`
+	Thing      string     `json:"thing"` // "item" or "box"
+	ThingID    uuid.UUID  `json:"thingId"`
+	Label      string     `json:"label"` // label of the lent thing
+	Borrower   string     `json:"borrower"`
+	LentAt     time.Time  `json:"lentAt"`
+	DueDate    string     `json:"dueDate"`    // like 2024-12-31
+	ReturnedAt *time.Time `json:"returnedAt"` // nil until the thing was returned
+	ActorID    uuid.UUID  `json:"actorId"`    // user who lent the thing out
+}
+
+// Returned returns true if the thing was given back.
+func (l Loan) Returned() bool {
+	return l.ReturnedAt != nil
+}
+
+// Overdue returns true if the thing wasn't returned and the due date is before today.
+func (l Loan) Overdue(today time.Time) bool {
+	return !l.Returned() && l.DueDate < today.Format(validate.DATE_LAYOUT)
+}
+
+// Filter returns the loans with the status.
+func Filter(loans []Loan, status string, today time.Time) []Loan {
+	filtered := []Loan{}
+	for _, l := range loans {
+		if l.Returned() {
+			continue
+		}
+		if status == STATUS_OVERDUE && !l.Overdue(today) {
+			continue
+		}
+		filtered = append(filtered, l)
+	}
+	return filtered
+}
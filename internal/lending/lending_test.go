@@ -0,0 +1,40 @@
+package lending
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestOverdue(t *testing.T) {
+	today := time.Date(2024, 6, 15, 18, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		loan     Loan
+		expected bool
+	}{
+		"due yesterday":     {Loan{DueDate: "2024-06-14"}, true},
+		"due today":         {Loan{DueDate: "2024-06-15"}, false},
+		"due tomorrow":      {Loan{DueDate: "2024-06-16"}, false},
+		"returned too late": {Loan{DueDate: "2024-06-01", ReturnedAt: &today}, false},
+		"due last year":     {Loan{DueDate: "2023-12-31"}, true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.loan.Overdue(today), tt.expected)
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	today := time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)
+	loans := []Loan{
+		{ID: 1, DueDate: "2024-06-01"},
+		{ID: 2, DueDate: "2024-07-01"},
+		{ID: 3, DueDate: "2024-06-01", ReturnedAt: &today},
+	}
+	assert.Equal(t, len(Filter(loans, STATUS_LENT, today)), 2)
+	overdue := Filter(loans, STATUS_OVERDUE, today)
+	assert.Equal(t, len(overdue), 1)
+	assert.Equal(t, overdue[0].ID, int64(1))
+}
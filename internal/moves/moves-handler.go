@@ -0,0 +1,48 @@
+package moves
+
+import (
+	"basement/main/internal/server"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// UndoHandler moves all things of a move operation back to their previous box, shelf and area.
+// Redirects back to the page the undo was requested from.
+//
+//	POST /api/v1/moves/{id}/undo
+func UndoHandler(db MoveDatabase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Add("Allow", http.MethodPost)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+			return
+		}
+
+		id := server.ValidID(w, r, "invalid move operation id")
+		if id == uuid.Nil {
+			return
+		}
+		count, err := db.UndoMoves(r.Context(), id)
+		if err != nil {
+			server.WriteNotFoundError("can't undo move", err, w, r)
+			return
+		}
+		server.RedirectWithSuccessNotification(w, currentPath(r), fmt.Sprintf("moved %d things back", count))
+	}
+}
+
+// currentPath returns the path and query of the page htmx sent the request from.
+func currentPath(r *http.Request) string {
+	u, err := url.Parse(r.Header.Get("HX-Current-URL"))
+	if err != nil || u.Path == "" {
+		return "/"
+	}
+	if u.RawQuery != "" {
+		return u.Path + "?" + u.RawQuery
+	}
+	return u.Path
+}
@@ -0,0 +1,28 @@
+package moves
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+type MoveDatabase interface {
+	UndoMoves(ctx context.Context, operationID uuid.UUID) (count int, err error)
+}
+
+type contextKey int
+
+const operationKey contextKey = iota
+
+// WithOperation returns a copy of ctx that groups all moves done with it into a single operation.
+// The previous box, shelf and area of every moved thing are recorded so the operation can be undone.
+func WithOperation(ctx context.Context, operationID uuid.UUID) context.Context {
+	return context.WithValue(ctx, operationKey, operationID)
+}
+
+// OperationFromContext returns the operation id stored with WithOperation.
+// ok is false if ctx has no operation.
+func OperationFromContext(ctx context.Context) (operationID uuid.UUID, ok bool) {
+	operationID, ok = ctx.Value(operationKey).(uuid.UUID)
+	return operationID, ok
+}
@@ -0,0 +1,237 @@
+package pictures
+
+import (
+	"basement/main/internal/common"
+	"basement/main/internal/logg"
+	"basement/main/internal/server"
+	"bytes"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// ACTION_COVER makes a picture the cover of its thing, DIRECTION_UP and DIRECTION_DOWN move it in the gallery.
+const ACTION_COVER = "cover"
+
+// GalleryHandler lists and adds the pictures of the item, box, shelf or area with the path value "id".
+//
+//	GET  /item/{id}/pictures        = the gallery, loaded by the details page
+//	POST /item/{id}/pictures        = add form file "picture" with form value "caption"
+//	GET  /api/v1/item/{id}/pictures = the pictures as JSON
+//	POST /api/v1/item/{id}/pictures = same as above, responds with the new picture as JSON
+func GalleryHandler(db PictureDatabase, thing string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := server.ValidID(w, r, "the "+thing+" doesn't exist")
+		if id == uuid.Nil {
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			list, err := db.Pictures(r.Context(), thing, id)
+			if err != nil {
+				server.WriteInternalServerError("can't query the pictures of the "+thing, err, w, r)
+				return
+			}
+			if !server.WantsTemplateData(r) {
+				if list == nil {
+					list = []Picture{}
+				}
+				server.WriteJSON(w, list)
+				return
+			}
+			server.MustRender(w, r, "picture-gallery", map[string]any{"Thing": thing, "ID": id, "Pictures": list})
+
+		case http.MethodPost:
+			picture := common.ParsePicture(r)
+			if picture == "" {
+				writeInvalid(w, r, "Choose a picture to add.")
+				return
+			}
+			format, err := common.ParsePictureFormat(r)
+			if err != nil {
+				logg.Debug("no picture format")
+			}
+			caption, ok := validCaption(w, r)
+			if !ok {
+				return
+			}
+			added, err := db.AddPicture(r.Context(), thing, id, picture, format, caption)
+			if err != nil {
+				writeInvalid(w, r, "Can't add the picture. "+logg.CleanLastError(err))
+				logg.Err(err)
+				return
+			}
+			if !server.WantsTemplateData(r) {
+				server.WriteJSON(w, added)
+				return
+			}
+			server.RedirectWithSuccessNotification(w, "/"+thing+"/"+id.String(), "Picture added")
+
+		default:
+			w.Header().Add("Allow", http.MethodGet)
+			w.Header().Add("Allow", http.MethodPost)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+		}
+	}
+}
+
+// PictureHandler changes the caption of or removes the picture with the path value "id".
+//
+//	PUT    /pictures/{id} = change the caption to form value "caption"
+//	DELETE /pictures/{id} = remove the picture, the next picture becomes the cover if it was the cover
+//	PUT    /api/v1/pictures/{id}
+//	DELETE /api/v1/pictures/{id}
+func PictureHandler(db PictureDatabase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := validPictureID(w, r)
+		if !ok {
+			return
+		}
+		var p Picture
+		var err error
+		var message string
+		switch r.Method {
+		case http.MethodPut:
+			caption, ok := validCaption(w, r)
+			if !ok {
+				return
+			}
+			p, err = db.UpdatePictureCaption(r.Context(), id, caption)
+			message = "Caption saved"
+		case http.MethodDelete:
+			p, err = db.DeletePicture(r.Context(), id)
+			message = "Picture removed"
+		default:
+			w.Header().Add("Allow", http.MethodPut)
+			w.Header().Add("Allow", http.MethodDelete)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+			return
+		}
+		if err != nil {
+			server.WriteNotFoundError("can't change picture", err, w, r)
+			return
+		}
+		writeChanged(w, r, p, message)
+	}
+}
+
+// ActionHandler applies the action ACTION_COVER, DIRECTION_UP or DIRECTION_DOWN to the picture with the path value "id".
+//
+//	POST /pictures/{id}/cover = make it the cover of its thing
+//	POST /pictures/{id}/up    = swap it with the picture before it
+//	POST /pictures/{id}/down  = swap it with the picture after it
+//	POST /api/v1/pictures/{id}/cover
+func ActionHandler(db PictureDatabase, action string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Add("Allow", http.MethodPost)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+			return
+		}
+		id, ok := validPictureID(w, r)
+		if !ok {
+			return
+		}
+		var p Picture
+		var err error
+		message := "Picture moved"
+		if action == ACTION_COVER {
+			p, err = db.SetCoverPicture(r.Context(), id)
+			message = "Cover changed"
+		} else {
+			p, err = db.MovePicture(r.Context(), id, action)
+		}
+		if err != nil {
+			server.WriteNotFoundError("can't change picture", err, w, r)
+			return
+		}
+		writeChanged(w, r, p, message)
+	}
+}
+
+// RenditionHandler serves the picture of the thing with the path values "thing" and "id" in the size "size".
+// The ETag and Last-Modified headers let browsers revalidate with 304 Not Modified instead of loading it again.
+//
+//	GET /picture/item/{id}/thumbnail = the preview of the cover of the item
+//	GET /picture/item/{id}/medium    = the cover scaled down for details pages
+//	GET /picture/item/{id}/original  = the cover as uploaded
+//	GET /picture/picture/{id}/medium = the picture with the id of a gallery
+func RenditionHandler(db PictureDatabase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Add("Allow", http.MethodGet)
+			w.Header().Add("Allow", http.MethodHead)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+			return
+		}
+		thing, id, size := r.PathValue("thing"), r.PathValue("id"), r.PathValue("size")
+		if thing == THING_GALLERY {
+			if _, ok := validPictureID(w, r); !ok {
+				return
+			}
+		} else if !slices.Contains(Things, thing) || uuid.FromStringOrNil(id) == uuid.Nil {
+			server.WriteBadRequestError("the "+thing+" doesn't exist", nil, w, r)
+			return
+		}
+		if !slices.Contains(Sizes, size) {
+			server.WriteBadRequestError(`"`+size+`" is not a size`, nil, w, r)
+			return
+		}
+
+		rendition, err := db.PictureRendition(r.Context(), thing, id, size)
+		if err != nil {
+			server.WriteNotFoundError("the "+thing+" has no picture", err, w, r)
+			return
+		}
+		w.Header().Set("Content-Type", rendition.ContentType)
+		w.Header().Set("ETag", rendition.ETag)
+		// The URL stays the same when the picture changes, so browsers must revalidate.
+		w.Header().Set("Cache-Control", "private, no-cache")
+		http.ServeContent(w, r, "", rendition.ModTime, bytes.NewReader(rendition.Content))
+	}
+}
+
+// writeChanged responds with the changed picture as JSON or reloads the page of its thing.
+func writeChanged(w http.ResponseWriter, r *http.Request, p Picture, message string) {
+	if !server.WantsTemplateData(r) {
+		server.WriteJSON(w, p)
+		return
+	}
+	server.RedirectWithSuccessNotification(w, "/"+p.Thing+"/"+p.ThingID.String(), message)
+}
+
+func validPictureID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		server.WriteBadRequestError("invalid picture", nil, w, r)
+		return 0, false
+	}
+	return id, true
+}
+
+// validCaption returns the form value "caption" with collapsed spaces.
+// Writes an error and returns false if it is too long.
+func validCaption(w http.ResponseWriter, r *http.Request) (string, bool) {
+	caption := strings.Join(strings.Fields(r.FormValue("caption")), " ")
+	if utf8.RuneCountInString(caption) > maxCaptionLength {
+		writeInvalid(w, r, "The caption can't be longer than 100 characters.")
+		return "", false
+	}
+	return caption, true
+}
+
+func writeInvalid(w http.ResponseWriter, r *http.Request, message string) {
+	if server.WantsTemplateData(r) {
+		server.TriggerSingleErrorNotification(w, message)
+	} else {
+		server.WriteBadRequestError(message, nil, w, r)
+	}
+}
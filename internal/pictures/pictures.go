@@ -0,0 +1,63 @@
+package pictures
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// Things that can have pictures.
+var Things = []string{"item", "box", "shelf", "area"}
+
+// THING_GALLERY addresses a single picture of a gallery by its id in picture URLs.
+const THING_GALLERY = "picture"
+
+// Sizes a picture is served in.
+const (
+	SIZE_THUMBNAIL = "thumbnail" // the preview, for lists
+	SIZE_MEDIUM    = "medium"    // scaled down, for details pages
+	SIZE_ORIGINAL  = "original"  // as uploaded
+)
+
+var Sizes = []string{SIZE_THUMBNAIL, SIZE_MEDIUM, SIZE_ORIGINAL}
+
+// Directions to move a picture in the gallery.
+const (
+	DIRECTION_UP   = "up"   // one position to the front
+	DIRECTION_DOWN = "down" // one position to the back
+)
+
+const maxCaptionLength = 100
+
+type PictureDatabase interface {
+	Pictures(ctx context.Context, thing string, thingID uuid.UUID) ([]Picture, error)
+	AddPicture(ctx context.Context, thing string, thingID uuid.UUID, picture string, format string, caption string) (Picture, error)
+	UpdatePictureCaption(ctx context.Context, id int64, caption string) (Picture, error)
+	MovePicture(ctx context.Context, id int64, direction string) (Picture, error)
+	SetCoverPicture(ctx context.Context, id int64) (Picture, error)
+	DeletePicture(ctx context.Context, id int64) (Picture, error)
+	PictureRendition(ctx context.Context, thing string, id string, size string) (Rendition, error)
+}
+
+// Picture is one of the pictures of an item, box, shelf or area.
+// The picture and preview of the cover picture are also the picture and preview of the thing.
+type Picture struct {
+	ID             int64     `json:"id"`
+	Thing          string    `json:"thing"`
+	ThingID        uuid.UUID `json:"thingId"`
+	Position       int64     `json:"position"` // pictures are shown by ascending position
+	Caption        string    `json:"caption"`
+	Cover          bool      `json:"cover"`
+	Picture        string    `json:"picture"`        // base64 encoded
+	PreviewPicture string    `json:"previewPicture"` // base64 encoded
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+// Rendition is a picture in one of the Sizes.
+type Rendition struct {
+	ETag        string    // quoted, changes with the picture and the size
+	ContentType string    // like "image/png"
+	ModTime     time.Time // when the file was written
+	Content     []byte
+}
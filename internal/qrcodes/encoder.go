@@ -0,0 +1,477 @@
+package qrcodes
+
+import (
+	"basement/main/internal/logg"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+)
+
+// Symbol is an encoded QR code. It uses byte mode and error correction level M,
+// which still reads with about 15% of a sticker damaged.
+type Symbol struct {
+	Size     int // modules per side, without the quiet zone
+	modules  []bool
+	function []bool // finder, timing, alignment and format modules, which aren't masked
+}
+
+// quietZone is the light border in modules that scanners need around a code.
+const quietZone = 4
+
+// blocks describes the error correction of a version at level M.
+type blocks struct {
+	ecPerBlock int
+	group1     int // blocks in the first group
+	data1      int // data codewords per block of the first group
+	group2     int
+	data2      int // data codewords per block of the second group, data1 + 1
+}
+
+// versionsM are the versions 1 to 10 at error correction level M.
+// Longer payloads than version 10 holds aren't needed for short URLs.
+var versionsM = []blocks{
+	{},
+	{10, 1, 16, 0, 0},
+	{16, 1, 28, 0, 0},
+	{26, 1, 44, 0, 0},
+	{18, 2, 32, 0, 0},
+	{24, 2, 43, 0, 0},
+	{16, 4, 27, 0, 0},
+	{18, 4, 31, 0, 0},
+	{22, 2, 38, 2, 39},
+	{22, 3, 36, 2, 37},
+	{26, 4, 43, 1, 44},
+}
+
+// alignmentPositions are the row and column centers of the alignment patterns per version.
+var alignmentPositions = [][]int{
+	{}, {}, {6, 18}, {6, 22}, {6, 26}, {6, 30}, {6, 34},
+	{6, 22, 38}, {6, 24, 42}, {6, 26, 46}, {6, 28, 50},
+}
+
+func (b blocks) dataCodewords() int {
+	return b.group1*b.data1 + b.group2*b.data2
+}
+
+// Encode returns the smallest QR code that holds the text.
+func Encode(text string) (*Symbol, error) {
+	version := 0
+	for v := 1; v < len(versionsM); v++ {
+		if headerBits(v)+8*len(text) <= 8*versionsM[v].dataCodewords() {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, logg.NewError(fmt.Sprintf("%d characters are too long for a QR code", len(text)))
+	}
+
+	s := newSymbol(version)
+	data := dataCodewords(text, version)
+	s.place(interleave(data, versionsM[version]))
+
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		s.applyMask(mask)
+		s.drawFormat(mask)
+		if p := s.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		s.applyMask(mask) // masking twice restores the data
+	}
+	s.applyMask(best)
+	s.drawFormat(best)
+	return s, nil
+}
+
+// headerBits is the length of the byte mode indicator and the character count.
+func headerBits(version int) int {
+	if version < 10 {
+		return 4 + 8
+	}
+	return 4 + 16
+}
+
+// dataCodewords returns the text in byte mode with terminator and padding.
+func dataCodewords(text string, version int) []byte {
+	capacity := 8 * versionsM[version].dataCodewords()
+	var w bitWriter
+	w.write(0b0100, 4) // byte mode
+	w.write(len(text), headerBits(version)-4)
+	for i := 0; i < len(text); i++ {
+		w.write(int(text[i]), 8)
+	}
+	w.write(0, min(4, capacity-w.len))
+	w.write(0, (8-w.len%8)%8)
+	for pad := 0xEC; w.len < capacity; pad ^= 0xEC ^ 0x11 {
+		w.write(pad, 8)
+	}
+	return w.bytes
+}
+
+type bitWriter struct {
+	bytes []byte
+	len   int // in bits
+}
+
+func (w *bitWriter) write(value int, bits int) {
+	for i := bits - 1; i >= 0; i-- {
+		if w.len%8 == 0 {
+			w.bytes = append(w.bytes, 0)
+		}
+		if value>>i&1 == 1 {
+			w.bytes[w.len/8] |= 0x80 >> (w.len % 8)
+		}
+		w.len++
+	}
+}
+
+// interleave splits the data into blocks, adds their error correction and
+// returns the codewords in the order they are placed.
+func interleave(data []byte, b blocks) []byte {
+	var dataBlocks, ecBlocks [][]byte
+	for i := 0; i < b.group1+b.group2; i++ {
+		n := b.data1
+		if i >= b.group1 {
+			n = b.data2
+		}
+		dataBlocks = append(dataBlocks, data[:n])
+		ecBlocks = append(ecBlocks, reedSolomon(data[:n], b.ecPerBlock))
+		data = data[n:]
+	}
+
+	var out []byte
+	for i := 0; i < max(b.data1, b.data2); i++ {
+		for _, block := range dataBlocks {
+			if i < len(block) {
+				out = append(out, block[i])
+			}
+		}
+	}
+	for i := 0; i < b.ecPerBlock; i++ {
+		for _, block := range ecBlocks {
+			out = append(out, block[i])
+		}
+	}
+	return out
+}
+
+// gfExp and gfLog are the powers of 2 and their logarithms in GF(256) with the polynomial 0x11D.
+var gfExp, gfLog = func() ([512]byte, [256]byte) {
+	var exp [512]byte
+	var log [256]byte
+	x := 1
+	for i := 0; i < 255; i++ {
+		exp[i] = byte(x)
+		log[x] = byte(i)
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11D
+		}
+	}
+	for i := 255; i < 512; i++ {
+		exp[i] = exp[i-255]
+	}
+	return exp, log
+}()
+
+func gfMul(a byte, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+// reedSolomon returns the error correction codewords of the data.
+func reedSolomon(data []byte, ecCodewords int) []byte {
+	// generator polynomial (x - 2^0)(x - 2^1)...(x - 2^(n-1)), highest coefficient first
+	generator := []byte{1}
+	for i := 0; i < ecCodewords; i++ {
+		next := make([]byte, len(generator)+1)
+		for j, c := range generator {
+			next[j] ^= c
+			next[j+1] ^= gfMul(c, gfExp[i])
+		}
+		generator = next
+	}
+
+	remainder := make([]byte, len(data)+ecCodewords)
+	copy(remainder, data)
+	for i := range data {
+		factor := remainder[i]
+		if factor == 0 {
+			continue
+		}
+		for j := 1; j < len(generator); j++ {
+			remainder[i+j] ^= gfMul(generator[j], factor)
+		}
+	}
+	return remainder[len(data):]
+}
+
+func newSymbol(version int) *Symbol {
+	size := 17 + 4*version
+	s := &Symbol{Size: size, modules: make([]bool, size*size), function: make([]bool, size*size)}
+
+	for i := 0; i < size; i++ {
+		s.setFunction(6, i, i%2 == 0)
+		s.setFunction(i, 6, i%2 == 0)
+	}
+	s.drawFinder(3, 3)
+	s.drawFinder(size-4, 3)
+	s.drawFinder(3, size-4)
+
+	positions := alignmentPositions[version]
+	last := len(positions) - 1
+	for i, y := range positions {
+		for j, x := range positions {
+			// The finder patterns take these corners.
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					s.setFunction(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	s.drawFormat(0) // reserves the modules, the mask is drawn later
+	if version >= 7 {
+		bits := version
+		for i := 0; i < 12; i++ {
+			bits = bits<<1 ^ (bits>>11)*0x1F25
+		}
+		bits = version<<12 | bits
+		for i := 0; i < 18; i++ {
+			dark := bits>>i&1 == 1
+			a, b := size-11+i%3, i/3
+			s.setFunction(a, b, dark)
+			s.setFunction(b, a, dark)
+		}
+	}
+	return s
+}
+
+// drawFinder draws a finder pattern with its separator around the center x, y.
+func (s *Symbol) drawFinder(x int, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx < 0 || xx >= s.Size || yy < 0 || yy >= s.Size {
+				continue
+			}
+			distance := max(abs(dx), abs(dy))
+			s.setFunction(xx, yy, distance != 2 && distance != 4)
+		}
+	}
+}
+
+// formatBits returns the error correction level M and the mask with their BCH code.
+func formatBits(mask int) int {
+	const levelM = 0b00
+	data := levelM<<3 | mask
+	bits := data
+	for i := 0; i < 10; i++ {
+		bits = bits<<1 ^ (bits>>9)*0x537
+	}
+	return (data<<10 | bits) ^ 0x5412
+}
+
+// drawFormat draws both copies of the format information and the dark module.
+func (s *Symbol) drawFormat(mask int) {
+	bits := formatBits(mask)
+	bit := func(i int) bool { return bits>>i&1 == 1 }
+
+	for i := 0; i <= 5; i++ {
+		s.setFunction(8, i, bit(i))
+	}
+	s.setFunction(8, 7, bit(6))
+	s.setFunction(8, 8, bit(7))
+	s.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		s.setFunction(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		s.setFunction(s.Size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		s.setFunction(8, s.Size-15+i, bit(i))
+	}
+	s.setFunction(8, s.Size-8, true)
+}
+
+// place fills the modules that aren't function modules with the codewords in the zigzag order,
+// two columns at a time from the bottom right.
+func (s *Symbol) place(codewords []byte) {
+	i := 0
+	for right := s.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			// the vertical timing pattern
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vertical := 0; vertical < s.Size; vertical++ {
+			y := vertical
+			if upward {
+				y = s.Size - 1 - vertical
+			}
+			for j := 0; j < 2; j++ {
+				x := right - j
+				if s.function[y*s.Size+x] || i >= len(codewords)*8 {
+					continue
+				}
+				s.modules[y*s.Size+x] = codewords[i/8]>>(7-i%8)&1 == 1
+				i++
+			}
+		}
+	}
+}
+
+// applyMask inverts the data modules where the mask pattern is true.
+func (s *Symbol) applyMask(mask int) {
+	for y := 0; y < s.Size; y++ {
+		for x := 0; x < s.Size; x++ {
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert && !s.function[y*s.Size+x] {
+				s.modules[y*s.Size+x] = !s.modules[y*s.Size+x]
+			}
+		}
+	}
+}
+
+// penalty rates how hard the symbol is to read, lower is better.
+func (s *Symbol) penalty() int {
+	penalty := 0
+	dark := 0
+	finderLike := []string{"10111010000", "00001011101"}
+	for a := 0; a < s.Size; a++ {
+		var row, column strings.Builder
+		for b := 0; b < s.Size; b++ {
+			row.WriteByte(bitChar(s.Dark(b, a)))
+			column.WriteByte(bitChar(s.Dark(a, b)))
+			if s.Dark(b, a) {
+				dark++
+			}
+		}
+		for _, line := range []string{row.String(), column.String()} {
+			// runs of five or more modules of the same color
+			run := 1
+			for i := 1; i <= len(line); i++ {
+				if i < len(line) && line[i] == line[i-1] {
+					run++
+					continue
+				}
+				if run >= 5 {
+					penalty += run - 2
+				}
+				run = 1
+			}
+			for _, pattern := range finderLike {
+				penalty += 40 * strings.Count(line, pattern)
+			}
+		}
+	}
+	// blocks of 2 x 2 modules of the same color
+	for y := 0; y < s.Size-1; y++ {
+		for x := 0; x < s.Size-1; x++ {
+			c := s.Dark(x, y)
+			if c == s.Dark(x+1, y) && c == s.Dark(x, y+1) && c == s.Dark(x+1, y+1) {
+				penalty += 3
+			}
+		}
+	}
+	// the share of dark modules deviating from 50%
+	percent := dark * 100 / (s.Size * s.Size)
+	penalty += abs(percent-50) / 5 * 10
+	return penalty
+}
+
+func bitChar(dark bool) byte {
+	if dark {
+		return '1'
+	}
+	return '0'
+}
+
+func (s *Symbol) setFunction(x int, y int, dark bool) {
+	s.modules[y*s.Size+x] = dark
+	s.function[y*s.Size+x] = true
+}
+
+// Dark reports if the module in column x and row y is dark.
+func (s *Symbol) Dark(x int, y int) bool {
+	return s.modules[y*s.Size+x]
+}
+
+// WritePNG writes the symbol with its quiet zone as PNG, each module is scale pixels wide.
+func (s *Symbol) WritePNG(w io.Writer, scale int) error {
+	side := (s.Size + 2*quietZone) * scale
+	img := image.NewPaletted(image.Rect(0, 0, side, side), color.Palette{color.White, color.Black})
+	for y := 0; y < s.Size; y++ {
+		for x := 0; x < s.Size; x++ {
+			if !s.Dark(x, y) {
+				continue
+			}
+			for py := 0; py < scale; py++ {
+				for px := 0; px < scale; px++ {
+					img.SetColorIndex((x+quietZone)*scale+px, (y+quietZone)*scale+py, 1)
+				}
+			}
+		}
+	}
+	err := png.Encode(w, img)
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	return nil
+}
+
+// WriteSVG writes the symbol with its quiet zone as SVG that scales to any size.
+func (s *Symbol) WriteSVG(w io.Writer) error {
+	side := s.Size + 2*quietZone
+	var path strings.Builder
+	for y := 0; y < s.Size; y++ {
+		for x := 0; x < s.Size; x++ {
+			if s.Dark(x, y) {
+				fmt.Fprintf(&path, "M%d,%dh1v1h-1z", x+quietZone, y+quietZone)
+			}
+		}
+	}
+	_, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+
+		`<rect width="100%%" height="100%%" fill="#fff"/><path d="%s" fill="#000"/></svg>`, side, side, path.String())
+	if err != nil {
+		return logg.WrapErr(err)
+	}
+	return nil
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
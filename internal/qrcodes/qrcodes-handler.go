@@ -0,0 +1,210 @@
+package qrcodes
+
+import (
+	"basement/main/internal/auth"
+	"basement/main/internal/common"
+	"basement/main/internal/logg"
+	"basement/main/internal/server"
+	"basement/main/internal/templates"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// Handler serves the QR code of the item, box, shelf or area with the path values "thing" and "id".
+//
+//	GET /qr/{thing}/{id}            = the QR code as PNG
+//	GET /qr/{thing}/{id}?format=svg = the QR code as SVG, for printing at any size
+func Handler(db QRCodeDatabase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Add("Allow", http.MethodGet)
+			w.Header().Add("Allow", http.MethodHead)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+			return
+		}
+		thing := r.PathValue("thing")
+		id := uuid.FromStringOrNil(r.PathValue("id"))
+		if !slices.Contains(Things, thing) || id == uuid.Nil {
+			server.WriteBadRequestError("the "+thing+" doesn't exist", nil, w, r)
+			return
+		}
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = FORMAT_PNG
+		}
+		if format != FORMAT_PNG && format != FORMAT_SVG {
+			server.WriteBadRequestError(`"`+format+`" is not a format, use "png" or "svg"`, nil, w, r)
+			return
+		}
+
+		payload, err := db.QRCodePayload(r.Context(), thing, id)
+		if err != nil {
+			server.WriteNotFoundError("the "+thing+" has no QR code", err, w, r)
+			return
+		}
+		symbol, err := Encode(payload)
+		if err != nil {
+			server.WriteBadRequestError("the QR code of the "+thing+" is too long", err, w, r)
+			return
+		}
+
+		var b bytes.Buffer
+		if format == FORMAT_SVG {
+			w.Header().Set("Content-Type", "image/svg+xml")
+			err = symbol.WriteSVG(&b)
+		} else {
+			w.Header().Set("Content-Type", "image/png")
+			err = symbol.WritePNG(&b, pngModuleSize)
+		}
+		if err != nil {
+			server.WriteInternalServerError("can't write the QR code", err, w, r)
+			return
+		}
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="qr-%s-%s.%s"`, thing, id, format))
+		w.Header().Set("ETag", fmt.Sprintf(`"%x-%s"`, sha256.Sum256([]byte(payload)), format))
+		// The payload can be changed on the details page, so browsers must revalidate.
+		w.Header().Set("Cache-Control", "private, no-cache")
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(b.Bytes()))
+	}
+}
+
+// ResolveHandler sends a scanned QR code with the path value "code" to the thing it is on.
+//
+//	GET  /q/{code}                       = redirects to /item/{id}, /box/{id}, /shelf/{id} or /area/{id}
+//	                                       or shows the claim page if the code isn't on anything on the server yet
+//	GET  /q/{code}?thing=box&query=drill = claim page with the boxes that match the query
+//	POST /q/{code}                       = puts the code on the thing with form values "thing" and "id",
+//	                                       form value "replace=true" replaces the QR code the thing already has
+func ResolveHandler(db QRCodeDatabase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		code := r.PathValue("code")
+		if !ValidCode(code) {
+			server.WriteBadRequestError(`"`+code+`" is not a QR code`, nil, w, r)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+			resolve(w, r, db, code)
+		case http.MethodPost:
+			claim(w, r, db, code)
+		default:
+			w.Header().Add("Allow", http.MethodGet)
+			w.Header().Add("Allow", http.MethodHead)
+			w.Header().Add("Allow", http.MethodPost)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+		}
+	}
+}
+
+func resolve(w http.ResponseWriter, r *http.Request, db QRCodeDatabase, code string) {
+	thing, id, err := db.ThingByQRCode(r.Context(), code)
+	if err == nil {
+		http.Redirect(w, r, "/"+thing+"/"+id.String(), http.StatusSeeOther)
+		return
+	}
+	var other *OtherHouseholdError
+	if errors.As(err, &other) {
+		server.WriteForbiddenError(otherHouseholdMessage(code, other), w, r)
+		return
+	}
+	if !errors.Is(err, ErrNotAssigned) {
+		server.WriteInternalServerError("can't look up the QR code", err, w, r)
+		return
+	}
+
+	// Pre-printed stickers are claimed by the thing they are stuck on.
+	thing = r.URL.Query().Get("thing")
+	if !slices.Contains(Things, thing) {
+		thing = "box"
+	}
+	query := r.URL.Query().Get("query")
+	rows, err := claimRows(r.Context(), db, thing, query)
+	if err != nil {
+		server.WriteInternalServerError("can't list the things to put the QR code on", err, w, r)
+		return
+	}
+
+	authenticated, _ := auth.Authenticated(r)
+	user, _ := auth.UserSessionData(r)
+	page := templates.NewPageTemplate()
+	page.Title = "Claim QR code"
+	page.Authenticated = authenticated
+	page.User = user
+	data := page.Map()
+	data["Code"] = code
+	data["Things"] = Things
+	data["Thing"] = thing
+	data["Query"] = query
+	data["Rows"] = rows
+	server.MustRender(w, r, "qr-claim-page", data)
+}
+
+// claimRows returns the things that match the query, which the QR code can be put on.
+func claimRows(ctx context.Context, db QRCodeDatabase, thing string, query string) ([]common.ListRow, error) {
+	switch thing {
+	case "item":
+		return db.ItemListRows(ctx, query, claimRowsLimit, 1)
+	case "box":
+		return db.BoxListRows(ctx, query, claimRowsLimit, 1)
+	case "shelf":
+		return db.ShelfListRows(ctx, query, claimRowsLimit, 1)
+	case "area":
+		return db.AreaListRows(ctx, query, claimRowsLimit, 1)
+	}
+	return nil, logg.NewError(`"` + thing + `" has no QR code`)
+}
+
+func claim(w http.ResponseWriter, r *http.Request, db QRCodeDatabase, code string) {
+	thing := r.FormValue("thing")
+	id := uuid.FromStringOrNil(r.FormValue("id"))
+	if !slices.Contains(Things, thing) || id == uuid.Nil {
+		server.WriteBadRequestError("pick an item, box, shelf or area for the QR code", nil, w, r)
+		return
+	}
+	// A code stays on one thing, otherwise scanning it would be ambiguous.
+	owner, ownerID, err := db.ThingByQRCode(r.Context(), code)
+	if err == nil {
+		server.RedirectWithWarningNotification(w, "/"+owner+"/"+ownerID.String(), "The QR code "+code+" is already on this "+owner)
+		return
+	}
+	var other *OtherHouseholdError
+	if errors.As(err, &other) {
+		server.WriteForbiddenError(otherHouseholdMessage(code, other), w, r)
+		return
+	}
+	if !errors.Is(err, ErrNotAssigned) {
+		server.WriteInternalServerError("can't look up the QR code", err, w, r)
+		return
+	}
+
+	err = db.ClaimQRCode(r.Context(), thing, id, Payload(code), r.FormValue("replace") == "true")
+	if errors.Is(err, ErrHasQRCode) {
+		// Replaces the form of the thing with one that asks to confirm the replacement.
+		server.MustRender(w, r, "qr-claim-replace", map[string]any{"Thing": thing, "ID": id})
+		return
+	}
+	if err != nil {
+		server.WriteNotFoundError("can't put the QR code on the "+thing, err, w, r)
+		return
+	}
+	server.RedirectWithSuccessNotification(w, "/"+thing+"/"+id.String(), "The QR code "+code+" is on this "+thing+" now")
+}
+
+// otherHouseholdMessage tells the user that the QR code is on a thing of another household.
+// Only members learn the name of the household, so they know which one to switch to.
+func otherHouseholdMessage(code string, other *OtherHouseholdError) string {
+	if other.HouseholdName == "" {
+		return "The QR code " + code + " is on a thing of another household"
+	}
+	return "The QR code " + code + ` is on a thing of your household "` + other.HouseholdName + `", switch to it to open the thing`
+}
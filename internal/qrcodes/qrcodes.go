@@ -0,0 +1,106 @@
+package qrcodes
+
+import (
+	"basement/main/internal/common"
+	"basement/main/internal/env"
+	"context"
+	"crypto/rand"
+	"errors"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+var ErrNotAssigned = errors.New("is not assigned to anything")
+var ErrHasQRCode = errors.New("already has a QR code")
+
+// OtherHouseholdError is returned when a QR code is on a thing of another household than the active one.
+// HouseholdName is empty if the user is not a member of that household.
+type OtherHouseholdError struct {
+	HouseholdID   uuid.UUID
+	HouseholdName string
+}
+
+func (e *OtherHouseholdError) Error() string {
+	if e.HouseholdName == "" {
+		return "is on a thing of another household"
+	}
+	return `is on a thing of household "` + e.HouseholdName + `"`
+}
+
+// Things that have QR codes.
+var Things = []string{"item", "box", "shelf", "area"}
+
+// Formats a QR code is served in.
+const (
+	FORMAT_PNG = "png"
+	FORMAT_SVG = "svg"
+)
+
+// PATH_PREFIX starts the path of the short URL in a payload, followed by the code.
+const PATH_PREFIX = "/q/"
+
+// codeAlphabet leaves out characters that are easy to confuse on a printed sticker, like 0, o, 1 and l.
+const codeAlphabet = "23456789abcdefghjkmnpqrstuvwxyz"
+
+// codeLength gives 31^10 codes, enough that random codes don't collide.
+const codeLength = 10
+
+// maxCodeLength limits the codes of pre-printed stickers that are claimed.
+const maxCodeLength = 64
+
+// claimRowsLimit is how many things the claim page lists to choose from.
+const claimRowsLimit = 10
+
+// pngModuleSize is the width in pixels of a module in PNG codes.
+const pngModuleSize = 8
+
+type QRCodeDatabase interface {
+	QRCodePayload(ctx context.Context, thing string, id uuid.UUID) (string, error)
+	ThingByQRCode(ctx context.Context, code string) (thing string, id uuid.UUID, err error)
+	ClaimQRCode(ctx context.Context, thing string, id uuid.UUID, payload string, replace bool) error
+	ItemListRows(ctx context.Context, searchString string, limit int, pageNr int) ([]common.ListRow, error)
+	BoxListRows(ctx context.Context, searchQuery string, limit int, page int) ([]common.ListRow, error)
+	ShelfListRows(ctx context.Context, searchString string, limit int, pageNr int) ([]common.ListRow, error)
+	AreaListRows(ctx context.Context, searchQuery string, limit int, page int) ([]common.ListRow, error)
+}
+
+// NewPayload returns the short URL of a new random code on the configured public URL, like "http://localhost:8101/q/7kx2m9qh4c".
+// It is stored in the QRCode of a thing, so the code stays the same when the thing is relabelled or moved.
+func NewPayload() string {
+	return Payload(NewCode())
+}
+
+// Payload returns the short URL of the code on the configured public URL.
+func Payload(code string) string {
+	return env.CurrentConfig().PublicURL() + PATH_PREFIX + code
+}
+
+// ValidCode returns true if code can be in a short URL without escaping.
+// Generated codes only use codeAlphabet, pre-printed stickers may also use upper case letters, "-" and "_".
+func ValidCode(code string) bool {
+	if code == "" || len(code) > maxCodeLength {
+		return false
+	}
+	for _, c := range code {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// NewCode returns a random code of codeLength characters from codeAlphabet.
+func NewCode() string {
+	code := make([]byte, 0, codeLength)
+	b := make([]byte, 1)
+	for len(code) < codeLength {
+		// Read never returns an error on the supported platforms.
+		rand.Read(b)
+		// Bytes above the last full multiple of the alphabet would make some characters more likely.
+		if int(b[0]) >= 256/len(codeAlphabet)*len(codeAlphabet) {
+			continue
+		}
+		code = append(code, codeAlphabet[int(b[0])%len(codeAlphabet)])
+	}
+	return string(code)
+}
@@ -0,0 +1,193 @@
+package qrcodes
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestReedSolomon(t *testing.T) {
+	// "HELLO WORLD" as version 1-M in alphanumeric mode
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	assert.Equal(t, reedSolomon(data, 10), []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23})
+}
+
+func TestFormatAndVersionBits(t *testing.T) {
+	assert.Equal(t, formatBits(0), 0b101010000010010)
+	assert.Equal(t, formatBits(5), 0b100000011001110)
+
+	s := newSymbol(7)
+	var bits int
+	for i := 17; i >= 0; i-- {
+		bits <<= 1
+		if s.Dark(i/3, s.Size-11+i%3) {
+			bits |= 1
+		}
+	}
+	assert.Equal(t, bits, 0b000111110010010100)
+}
+
+// decode reads the text back from the symbol, checking the format information and the error correction.
+func decode(t *testing.T, s *Symbol) string {
+	version := (s.Size - 17) / 4
+	mask := -1
+	for m := 0; m < 8; m++ {
+		matches := true
+		for i := 0; i < 8; i++ {
+			if s.Dark(s.Size-1-i, 8) != (formatBits(m)>>i&1 == 1) {
+				matches = false
+			}
+		}
+		if matches {
+			mask = m
+		}
+	}
+	assert.NotEqual(t, mask, -1)
+
+	// reading is placing in reverse: unmask and collect the bits in the zigzag order
+	read := newSymbol(version)
+	copy(read.modules, s.modules)
+	read.applyMask(mask)
+	var bits []bool
+	for right := s.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vertical := 0; vertical < s.Size; vertical++ {
+			y := vertical
+			if (right+1)&2 == 0 {
+				y = s.Size - 1 - vertical
+			}
+			for j := 0; j < 2; j++ {
+				if !read.function[y*s.Size+right-j] {
+					bits = append(bits, read.Dark(right-j, y))
+				}
+			}
+		}
+	}
+	codewords := make([]byte, len(bits)/8)
+	for i := range codewords {
+		for j := 0; j < 8; j++ {
+			if bits[i*8+j] {
+				codewords[i] |= 0x80 >> j
+			}
+		}
+	}
+
+	// undo the interleaving and check the error correction of each block
+	b := versionsM[version]
+	count := b.group1 + b.group2
+	blocks := make([][]byte, count)
+	i := 0
+	for column := 0; column < max(b.data1, b.data2); column++ {
+		for block := 0; block < count; block++ {
+			if block < b.group1 && column >= b.data1 {
+				continue
+			}
+			blocks[block] = append(blocks[block], codewords[i])
+			i++
+		}
+	}
+	var data []byte
+	for block := range blocks {
+		var ec []byte
+		for column := 0; column < b.ecPerBlock; column++ {
+			ec = append(ec, codewords[i+column*count+block])
+		}
+		assert.Equal(t, reedSolomon(blocks[block], b.ecPerBlock), ec)
+		data = append(data, blocks[block]...)
+	}
+
+	var r bitReader
+	r.bytes = data
+	assert.Equal(t, r.read(4), 0b0100)
+	length := r.read(headerBits(version) - 4)
+	var text strings.Builder
+	for i := 0; i < length; i++ {
+		text.WriteByte(byte(r.read(8)))
+	}
+	return text.String()
+}
+
+type bitReader struct {
+	bytes []byte
+	pos   int
+}
+
+func (r *bitReader) read(bits int) int {
+	value := 0
+	for i := 0; i < bits; i++ {
+		value = value<<1 | int(r.bytes[r.pos/8]>>(7-r.pos%8)&1)
+		r.pos++
+	}
+	return value
+}
+
+func TestEncode(t *testing.T) {
+	tests := map[string]struct {
+		text    string
+		version int
+	}{
+		"short URL":                   {text: "http://localhost:8101/q/7kx2m9qh4c", version: 3},
+		"empty":                       {text: "", version: 1},
+		"version with version bits":   {text: strings.Repeat("b", 110), version: 7},
+		"two groups of blocks":        {text: strings.Repeat("c", 140), version: 8},
+		"longer character count":      {text: strings.Repeat("d", 200), version: 10},
+		"not ASCII bytes are encoded": {text: "Kühlschrank", version: 1},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			s, err := Encode(tt.text)
+			assert.Equal(t, err, nil)
+			assert.Equal(t, s.Size, 17+4*tt.version)
+			assert.Equal(t, decode(t, s), tt.text)
+		})
+	}
+
+	_, err := Encode(strings.Repeat("e", 214))
+	assert.NotEqual(t, err, nil)
+}
+
+func TestWrite(t *testing.T) {
+	s, err := Encode("http://localhost:8101/q/7kx2m9qh4c")
+	assert.Equal(t, err, nil)
+
+	var b bytes.Buffer
+	assert.Equal(t, s.WritePNG(&b, 4), nil)
+	img, err := png.Decode(&b)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, img.Bounds().Dx(), (s.Size+2*quietZone)*4)
+	// the top left corner of the finder pattern is dark, the quiet zone light
+	r, _, _, _ := img.At(quietZone*4, quietZone*4).RGBA()
+	assert.Equal(t, r, uint32(0))
+	r, _, _, _ = img.At(0, 0).RGBA()
+	assert.Equal(t, r, uint32(0xffff))
+
+	b.Reset()
+	assert.Equal(t, s.WriteSVG(&b), nil)
+	assert.Equal(t, strings.HasPrefix(b.String(), "<svg"), true)
+	assert.Equal(t, strings.Contains(b.String(), "M4,4h1v1h-1z"), true)
+}
+
+func TestNewCode(t *testing.T) {
+	code := NewCode()
+	assert.Equal(t, len(code), codeLength)
+	for _, c := range code {
+		assert.Equal(t, strings.ContainsRune(codeAlphabet, c), true)
+	}
+	assert.NotEqual(t, NewCode(), code)
+}
+
+func TestValidCode(t *testing.T) {
+	assert.Equal(t, ValidCode(NewCode()), true)
+	assert.Equal(t, ValidCode("BOX-0042_a"), true)
+	assert.Equal(t, ValidCode(""), false)
+	assert.Equal(t, ValidCode("a/b"), false)
+	assert.Equal(t, ValidCode("a%20b"), false)
+	assert.Equal(t, ValidCode("Kühlschrank"), false)
+	assert.Equal(t, ValidCode(strings.Repeat("a", maxCodeLength)), true)
+	assert.Equal(t, ValidCode(strings.Repeat("a", maxCodeLength+1)), false)
+}
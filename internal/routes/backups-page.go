@@ -0,0 +1,69 @@
+package routes
+
+import (
+	"net/http"
+
+	"basement/main/internal/backup"
+	"basement/main/internal/env"
+	"basement/main/internal/server"
+	"basement/main/internal/templates"
+)
+
+// SettingsPageBackups lists all snapshots of the database on the settings page.
+func SettingsPageBackups(db backup.BackupDatabase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		snapshots, err := db.Backups()
+		if err != nil {
+			server.WriteInternalServerError("can't list backups", err, w, r)
+			return
+		}
+		data := settingsPageData(r)
+		data["ShowBackups"] = true
+		data["Backups"] = snapshots
+		data["BackupPath"] = env.CurrentConfig().BackupPath()
+		data["BackupIntervalHours"] = env.CurrentConfig().BackupIntervalHours()
+		data["BackupRetention"] = env.CurrentConfig().BackupRetention()
+		server.MustRender(w, r, templates.TEMPLATE_SETTINGS_PAGE, data)
+	}
+}
+
+// BackupCreateHandler writes a snapshot of the database right away.
+//
+//	POST /api/v1/backups
+func BackupCreateHandler(db backup.BackupDatabase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Add("Allow", http.MethodPost)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+			return
+		}
+		snapshot, err := db.Backup()
+		if err != nil {
+			server.WriteInternalServerError("can't create backup", err, w, r)
+			return
+		}
+		server.RedirectWithSuccessNotification(w, "/settings/backups", "Created backup "+snapshot.Name)
+	}
+}
+
+// BackupRestoreHandler validates a snapshot and replaces the live database with it.
+//
+//	POST /api/v1/backups/{name}/restore
+func BackupRestoreHandler(db backup.BackupDatabase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Add("Allow", http.MethodPost)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+			return
+		}
+		name := r.PathValue("name")
+		err := db.RestoreBackup(name)
+		if err != nil {
+			server.WriteBadRequestError("can't restore backup "+name, err, w, r)
+			return
+		}
+		server.RedirectWithSuccessNotification(w, "/settings/backups", "Restored backup "+name)
+	}
+}
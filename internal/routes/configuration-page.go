@@ -13,7 +13,7 @@ import (
 )
 
 func SettingsPageConfiguration(w http.ResponseWriter, r *http.Request) {
-	data := settingsPageData(r).Map()
+	data := settingsPageData(r)
 	data["Configuration"] = env.CurrentConfig()
 	server.MustRender(w, r, templates.TEMPLATE_SETTINGS_PAGE, data)
 }
@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 
@@ -34,7 +35,7 @@ func AddTo(db *database.DB) http.HandlerFunc {
 		switch thing {
 		case "box":
 			data.SetRowHXGet("/box")
-			count, err = db.BoxListCounter("")
+			count, err = db.BoxListCounter(r.Context(), "")
 			if err != nil {
 				server.WriteInternalServerError("no box list counter", err, w, r)
 				return
@@ -50,7 +51,7 @@ func AddTo(db *database.DB) http.HandlerFunc {
 					RowActionName:         actionName,
 					RowActionHXPostWithID: post,
 				}
-				rows, err = common.FilledRows(db.BoxListRows, data.GetSearchInputValue(), data.GetLimit(), data.GetPageNumber(), count, rowOptions)
+				rows, err = common.FilledRows(r.Context(), db.BoxListRows, data.GetSearchInputValue(), data.GetLimit(), data.GetPageNumber(), count, rowOptions)
 				if err != nil {
 					server.WriteInternalServerError("cant query "+thing+" please comeback later", err, w, r)
 				}
@@ -59,7 +60,7 @@ func AddTo(db *database.DB) http.HandlerFunc {
 
 		case "shelf":
 			data.SetRowHXGet("/shelves")
-			count, err = db.ShelfListCounter("")
+			count, err = db.ShelfListCounter(r.Context(), "")
 			if err != nil {
 				server.WriteInternalServerError("no shelf list counter", err, w, r)
 				return
@@ -76,7 +77,7 @@ func AddTo(db *database.DB) http.HandlerFunc {
 					RowActionName:         actionName,
 					RowActionHXPostWithID: post,
 				}
-				rows, err = common.FilledRows(db.ShelfListRows, data.GetSearchInputValue(), data.GetLimit(), data.GetPageNumber(), count, rowOptions)
+				rows, err = common.FilledRows(r.Context(), db.ShelfListRows, data.GetSearchInputValue(), data.GetLimit(), data.GetPageNumber(), count, rowOptions)
 				if err != nil {
 					server.WriteInternalServerError("cant query "+thing+" please comeback later", err, w, r)
 				}
@@ -86,7 +87,7 @@ func AddTo(db *database.DB) http.HandlerFunc {
 
 		case "area":
 			data.SetRowHXGet("/area")
-			count, err = db.AreaListCounter("")
+			count, err = db.AreaListCounter(r.Context(), "")
 			if err != nil {
 				server.WriteInternalServerError("no area list counter", err, w, r)
 				return
@@ -103,7 +104,7 @@ func AddTo(db *database.DB) http.HandlerFunc {
 					RowActionName:         actionName,
 					RowActionHXPostWithID: post,
 				}
-				rows, err = common.FilledRows(db.AreaListRows, data.GetSearchInputValue(), data.GetLimit(), data.GetPageNumber(), count, rowOptions)
+				rows, err = common.FilledRows(r.Context(), db.AreaListRows, data.GetSearchInputValue(), data.GetLimit(), data.GetPageNumber(), count, rowOptions)
 				if err != nil {
 					server.WriteInternalServerError("cant query "+thing+" please comeback later", err, w, r)
 				}
@@ -143,7 +144,7 @@ func Element(db *database.DB) http.HandlerFunc {
 			return
 		}
 
-		data, err := fetchObjects(db, thing, thingID)
+		data, err := fetchObjects(r.Context(), db, thing, thingID)
 		if err != nil {
 			server.WriteNotFoundError(fmt.Sprintf("No matching objects found for %s", thing), err, w, r)
 			return
@@ -197,18 +198,18 @@ func Element(db *database.DB) http.HandlerFunc {
 }
 
 // Fetch the requested object and any related objects
-func fetchObjects(db *database.DB, thing string, thingID uuid.UUID) ([]map[string]interface{}, error) {
+func fetchObjects(ctx context.Context, db *database.DB, thing string, thingID uuid.UUID) ([]map[string]interface{}, error) {
 	var obj interface{}
 	var err error
 	response := []map[string]interface{}{}
 
 	switch thing {
 	case "box":
-		obj, err = db.BoxById(thingID)
+		obj, err = db.BoxById(ctx, thingID)
 	case "shelf":
-		obj, err = db.Shelf(thingID)
+		obj, err = db.Shelf(ctx, thingID)
 	case "area":
-		obj, err = db.AreaById(thingID)
+		obj, err = db.AreaById(ctx, thingID)
 	default:
 		return nil, fmt.Errorf("unknown type: %s", thing)
 	}
@@ -222,11 +223,11 @@ func fetchObjects(db *database.DB, thing string, thingID uuid.UUID) ([]map[strin
 		response = append(response, map[string]interface{}{"box": box})
 
 		if box.ShelfID != uuid.Nil {
-			if shelf, err := db.Shelf(box.ShelfID); err == nil {
+			if shelf, err := db.Shelf(ctx, box.ShelfID); err == nil {
 				response = append(response, map[string]interface{}{"shelf": shelf})
 
 				if shelf.AreaID != uuid.Nil {
-					if area, err := db.AreaById(shelf.AreaID); err == nil {
+					if area, err := db.AreaById(ctx, shelf.AreaID); err == nil {
 						response = append(response, map[string]interface{}{"area": area})
 					}
 				}
@@ -237,7 +238,7 @@ func fetchObjects(db *database.DB, thing string, thingID uuid.UUID) ([]map[strin
 		response = append(response, map[string]interface{}{"shelf": shelf})
 
 		if shelf.AreaID != uuid.Nil {
-			if area, err := db.AreaById(shelf.AreaID); err == nil {
+			if area, err := db.AreaById(ctx, shelf.AreaID); err == nil {
 				response = append(response, map[string]interface{}{"area": area})
 			}
 		}
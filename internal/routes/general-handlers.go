@@ -6,6 +6,7 @@ import (
 
 	"basement/main/internal/auth"
 	"basement/main/internal/common"
+	"basement/main/internal/households"
 	"basement/main/internal/logg"
 	"basement/main/internal/server"
 	"basement/main/internal/templates"
@@ -13,11 +14,51 @@ import (
 
 // Handle registers a route that requires authentication.
 // If the user is not authenticated, they are redirected to the /auth page.
+// The request context of handler carries the user id and the membership in the active household,
+// see auth.WithSessionUser and households.WithRequestMembership.
+// Requests that mutate something need at least the editor role.
 func Handle(route string, handler http.HandlerFunc) {
+	HandleWithRole(route, households.ROLE_EDITOR, handler)
+}
+
+// HandleWithRole is like Handle but requests that mutate something need at least role.
+// GET, HEAD and OPTIONS requests are allowed for every member of the household.
+func HandleWithRole(route string, role households.Role, handler http.HandlerFunc) {
+	handleAuthenticated(route, role, handler, func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/auth", http.StatusSeeOther)
+	})
+}
+
+// HandleWithLoginRedirect is like Handle but users who aren't authenticated are sent to the login page,
+// which brings them back to the requested URL. Useful for links that are opened from outside, like scanned QR codes.
+func HandleWithLoginRedirect(route string, handler http.HandlerFunc) {
+	handleAuthenticated(route, households.ROLE_EDITOR, handler, func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, auth.LoginPath(r.URL.RequestURI()), http.StatusSeeOther)
+	})
+}
+
+// HandleWithServerAdmin is like Handle but every request needs a user configured as server admin,
+// because handler affects all households of the server.
+func HandleWithServerAdmin(route string, handler http.HandlerFunc) {
+	HandleWithRole(route, households.ROLE_VIEWER, serverAdminOnly(handler))
+}
+
+// serverAdminOnly returns handler that writes a forbidden error for users who aren't server admins.
+func serverAdminOnly(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !auth.ServerAdmin(r) {
+			server.WriteForbiddenError("only server admins can do this", w, r)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}
+}
+
+func handleAuthenticated(route string, role households.Role, handler http.HandlerFunc, unauthenticated http.HandlerFunc) {
 	http.HandleFunc(route, func(w http.ResponseWriter, r *http.Request) {
 		authenticated, _ := auth.Authenticated(r)
 		if !authenticated {
-			http.Redirect(w, r, "/auth", http.StatusSeeOther)
+			unauthenticated(w, r)
 			return
 		}
 
@@ -33,10 +74,28 @@ func Handle(route string, handler http.HandlerFunc) {
 			// logg.Debug(colorMsg)
 		}
 
+		r, err := households.WithRequestMembership(auth.WithSessionUser(r))
+		if err != nil {
+			server.WriteInternalServerError("can't find household", err, w, r)
+			return
+		}
+		if mutates(r) && !households.RequireRole(w, r, role) {
+			return
+		}
+
 		handler.ServeHTTP(w, r)
 	})
 }
 
+// mutates returns true if r may change something.
+func mutates(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
+
 // HandlePublic registers a public route that does not require authentication.
 // Useful for pages like login or registration.
 func HandlePublic(route string, handler http.HandlerFunc) {
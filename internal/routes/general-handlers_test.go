@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"basement/main/internal/env"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestServerAdminOnlyConfigurationUpdate(t *testing.T) {
+	c := env.CurrentConfig()
+	alwaysAuthorized, admins := c.AlwaysAuthorized(), c.ServerAdmins()
+	defer func() {
+		c.SetAlwaysAuthorized(alwaysAuthorized)
+		c.SetServerAdmins(admins)
+	}()
+	// Every request is authenticated, like a household editor who isn't a server admin.
+	c.SetAlwaysAuthorized(true).SetServerAdmins("alice")
+
+	r := httptest.NewRequest(http.MethodPut, "/settings/configuration/update", strings.NewReader("serverAdmins=mallory"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	serverAdminOnly(SettingsConfigurationUpdateHandler)(w, r)
+
+	assert.Equal(t, w.Code, http.StatusForbidden)
+	assert.Equal(t, c.ServerAdmins(), "alice")
+	assert.Equal(t, c.IsServerAdmin("mallory"), false)
+}
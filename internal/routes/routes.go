@@ -5,27 +5,60 @@ import (
 
 	"basement/main/internal/areas"
 	"basement/main/internal/auth"
+	"basement/main/internal/backup"
 	"basement/main/internal/boxes"
+	"basement/main/internal/categories"
 	"basement/main/internal/common"
 	"basement/main/internal/database"
+	"basement/main/internal/expiry"
+	"basement/main/internal/fields"
+	"basement/main/internal/history"
+	"basement/main/internal/households"
+	"basement/main/internal/insurance"
 	"basement/main/internal/items"
+	"basement/main/internal/lending"
 	"basement/main/internal/logg"
+	"basement/main/internal/moves"
+	"basement/main/internal/pictures"
+	"basement/main/internal/qrcodes"
+	"basement/main/internal/search"
 	"basement/main/internal/server"
 	"basement/main/internal/shelves"
+	"basement/main/internal/shopping"
+	"basement/main/internal/stock"
+	"basement/main/internal/tags"
 	"basement/main/internal/templates"
+	"basement/main/internal/trash"
 
 	"github.com/gofrs/uuid/v5"
 )
 
 func RegisterRoutes(db *database.DB) {
 	common.RegisterDBInstance(db)
+	households.RegisterDBInstance(db)
 	staticRoutes()
 	navigationRoutes()
 	authRoutes(db)
+	householdRoutes(db)
 	itemsRoutes(db)
+	expiryRoutes(db)
+	stockRoutes(db)
+	shoppingRoutes(db)
+	lendingRoutes(db)
+	insuranceRoutes(db)
+	pictureRoutes(db)
+	qrCodeRoutes(db)
 	boxesRoutes(db)
 	shelvesRoutes(db)
 	areaRoutes(db)
+	historyRoutes(db)
+	searchRoutes(db)
+	tagRoutes(db)
+	fieldRoutes(db)
+	categoryRoutes(db)
+	trashRoutes(db)
+	moveRoutes(db)
+	backupRoutes(db)
 	experimentalRoutes(db)
 
 	Handle("/addto/{thing}", AddTo(db))
@@ -40,9 +73,10 @@ func staticRoutes() {
 
 func navigationRoutes() {
 	Handle("/settings", SettingsPage)
-	Handle("/settings/configuration", SettingsPageConfiguration)
-	Handle("/settings/configuration/default/{option}", SettingsPageConfigurationDefaultValue)
-	Handle("/settings/configuration/update", SettingsConfigurationUpdateHandler)
+	// The configuration applies to the whole server, including who the server admins are.
+	HandleWithServerAdmin("/settings/configuration", SettingsPageConfiguration)
+	HandleWithServerAdmin("/settings/configuration/default/{option}", SettingsPageConfigurationDefaultValue)
+	HandleWithServerAdmin("/settings/configuration/update", SettingsConfigurationUpdateHandler)
 	Handle("/sample-page", SamplePage)
 	Handle("/personal-page", PersonalPage)
 }
@@ -50,8 +84,8 @@ func navigationRoutes() {
 func authRoutes(db auth.AuthDatabase) {
 	HandlePublic("/login", auth.LoginHandler(db))
 	HandlePublic("/register", auth.RegisterHandler(db))
-	Handle("/logout", auth.LogoutHandler)
-	Handle("/update", auth.UpdateHandler(db))
+	HandleWithRole("/logout", households.ROLE_VIEWER, auth.LogoutHandler)
+	HandleWithRole("/update", households.ROLE_VIEWER, auth.UpdateHandler(db))
 
 	HandlePublic("/login-form", auth.LoginForm)
 	HandlePublic("/register-form", func(w http.ResponseWriter, r *http.Request) {
@@ -59,11 +93,21 @@ func authRoutes(db auth.AuthDatabase) {
 	})
 }
 
+func householdRoutes(db households.HouseholdDatabase) {
+	Handle("/household", households.PageHandler(db))
+	HandleWithRole("/household/switch/{id}", households.ROLE_VIEWER, households.SwitchHandler(db))
+
+	// API
+	HandleWithRole("/api/v1/households", households.ROLE_VIEWER, households.HouseholdsHandler(db))
+	HandleWithRole("/api/v1/household/members", households.ROLE_OWNER, households.MembersHandler(db))
+	HandleWithRole("/api/v1/household/members/{id}", households.ROLE_OWNER, households.MembersHandler(db))
+}
+
 func itemsRoutes(db items.ItemDatabase) {
 	Handle("/items", items.ItemsHandler(db))
 	Handle("/item/{id}", items.PreviewTemplate(db))
-	Handle("/item/create", items.CreateTemplate())
-	Handle("/item/update/{id}", items.UpdateTemplate(db))
+	Handle("/item/create", items.CreateTemplate(db))
+	Handle("/item/{id}/update", items.UpdateTemplate(db))
 
 	// Move multiple items from list.
 	Handle("/items/moveto/{thing}", common.ListPageMovePicker(common.THING_ITEM, db))
@@ -83,6 +127,68 @@ func itemsRoutes(db items.ItemDatabase) {
 	Handle("/api/v1/delete/item/{id}", items.ItemHandler(db))
 }
 
+func expiryRoutes(db expiry.ExpiryDatabase) {
+	Handle("/items/expired", expiry.ListPage(db, expiry.STATUS_EXPIRED))
+	Handle("/items/expiring", expiry.ListPage(db, expiry.STATUS_EXPIRING))
+}
+
+func stockRoutes(db stock.StockDatabase) {
+	Handle("/items/low-stock", stock.LowStockPage(db))
+	Handle("/item/{id}/consume", stock.ChangeHandler(db, stock.ACTION_CONSUME))
+	Handle("/item/{id}/restock", stock.ChangeHandler(db, stock.ACTION_RESTOCK))
+	Handle("/api/v1/item/{id}/consume", stock.ChangeHandler(db, stock.ACTION_CONSUME))
+	Handle("/api/v1/item/{id}/restock", stock.ChangeHandler(db, stock.ACTION_RESTOCK))
+}
+
+func shoppingRoutes(db shopping.ShoppingDatabase) {
+	Handle("/shopping-list", shopping.ListHandler(db))
+	Handle("/shopping-list/generate", shopping.GenerateHandler(db))
+	Handle("/shopping-list/put-away", shopping.PutAwayHandler(db))
+	Handle("/shopping-list/export", shopping.ExportHandler(db))
+	Handle("/shopping-list/{id}", shopping.EntryHandler(db))
+	Handle("/shopping-list/{id}/check", shopping.CheckHandler(db))
+	Handle("/api/v1/shopping-list", shopping.ListHandler(db))
+	Handle("/api/v1/shopping-list/generate", shopping.GenerateHandler(db))
+	Handle("/api/v1/shopping-list/put-away", shopping.PutAwayHandler(db))
+	Handle("/api/v1/shopping-list/{id}", shopping.EntryHandler(db))
+	Handle("/api/v1/shopping-list/{id}/check", shopping.CheckHandler(db))
+}
+
+func lendingRoutes(db lending.LendingDatabase) {
+	Handle("/loans", lending.LoansHandler(db))
+	Handle("/loans/{id}/return", lending.ReturnHandler(db))
+	Handle("/item/{id}/lend", lending.LendHandler(db, "item"))
+	Handle("/box/{id}/lend", lending.LendHandler(db, "box"))
+	Handle("/api/v1/loans", lending.LoansHandler(db))
+	Handle("/api/v1/loans/{id}/return", lending.ReturnHandler(db))
+	Handle("/api/v1/item/{id}/lend", lending.LendHandler(db, "item"))
+	Handle("/api/v1/box/{id}/lend", lending.LendHandler(db, "box"))
+}
+
+func insuranceRoutes(db insurance.InsuranceDatabase) {
+	Handle("/reports/insurance", insurance.ReportHandler(db))
+	Handle("/api/v1/reports/insurance", insurance.ReportHandler(db))
+}
+
+func pictureRoutes(db pictures.PictureDatabase) {
+	for _, thing := range pictures.Things {
+		Handle("/"+thing+"/{id}/pictures", pictures.GalleryHandler(db, thing))
+		Handle("/api/v1/"+thing+"/{id}/pictures", pictures.GalleryHandler(db, thing))
+	}
+	Handle("/pictures/{id}", pictures.PictureHandler(db))
+	Handle("/api/v1/pictures/{id}", pictures.PictureHandler(db))
+	for _, action := range []string{pictures.ACTION_COVER, pictures.DIRECTION_UP, pictures.DIRECTION_DOWN} {
+		Handle("/pictures/{id}/"+action, pictures.ActionHandler(db, action))
+		Handle("/api/v1/pictures/{id}/"+action, pictures.ActionHandler(db, action))
+	}
+	Handle("/picture/{thing}/{id}/{size}", pictures.RenditionHandler(db))
+}
+
+func qrCodeRoutes(db qrcodes.QRCodeDatabase) {
+	Handle("/qr/{thing}/{id}", qrcodes.Handler(db))
+	HandleWithLoginRedirect(qrcodes.PATH_PREFIX+"{code}", qrcodes.ResolveHandler(db))
+}
+
 func boxesRoutes(db *database.DB) {
 	boxes.RegisterDBInstance(db)
 	// Box templates
@@ -105,7 +211,7 @@ func boxesRoutes(db *database.DB) {
 	Handle("/api/v1/box/{id}/move/{toid}", func(w http.ResponseWriter, r *http.Request) {
 		id := uuid.FromStringOrNil(r.PathValue("id"))
 		moveToBoxID := uuid.FromStringOrNil(r.PathValue("toid"))
-		err := db.MoveBoxToBox(id, moveToBoxID)
+		err := db.MoveBoxToBox(r.Context(), id, moveToBoxID)
 		if err != nil {
 			server.WriteBadRequestError("can't move box", err, w, r)
 			logg.Err(err)
@@ -134,13 +240,13 @@ func boxesRoutes(db *database.DB) {
 
 		switch r.PathValue("thing") {
 		case "box":
-			notifications = server.MoveThingToThing(w, r, db.MoveBoxToBox)
+			notifications = common.MoveThings(w, r, db.MoveBoxToBox)
 			break
 		case "shelf":
-			notifications = server.MoveThingToThing(w, r, db.MoveBoxToShelf)
+			notifications = common.MoveThings(w, r, db.MoveBoxToShelf)
 			break
 		case "area":
-			notifications = server.MoveThingToThing(w, r, db.MoveBoxToArea)
+			notifications = common.MoveThings(w, r, db.MoveBoxToArea)
 			break
 		}
 		server.TriggerNotifications(w, notifications)
@@ -163,7 +269,7 @@ func shelvesRoutes(db shelves.ShelfDB) {
 	})
 
 	// Api
-	http.HandleFunc("/api/v1/create/shelf", shelves.ShelfHandler(db))
+	Handle("/api/v1/create/shelf", shelves.ShelfHandler(db))
 	Handle("/api/v1/delete/shelf", shelves.ShelfHandler(db))
 	Handle("/api/v1/update/shelf", shelves.ShelfHandler(db))
 	Handle("/api/v1/delete/shelves", shelves.DeleteShelves(db))
@@ -187,6 +293,57 @@ func areaRoutes(db *database.DB) {
 	Handle("/api/v1/areas", areas.AreasHandler(db))
 }
 
+func historyRoutes(db history.HistoryDatabase) {
+	Handle("/item/{id}/history", history.HistoryHandler(db, "item"))
+	Handle("/box/{id}/history", history.HistoryHandler(db, "box"))
+	Handle("/shelf/{id}/history", history.HistoryHandler(db, "shelf"))
+	Handle("/area/{id}/history", history.HistoryHandler(db, "area"))
+	Handle("/activity", history.ActivityHandler(db))
+}
+
+func searchRoutes(db search.SearchDatabase) {
+	Handle("/search", search.PageHandler(db))
+	Handle("/api/v1/search", search.APIHandler(db))
+}
+
+func tagRoutes(db tags.TagDatabase) {
+	Handle("/tags", tags.TagsHandler(db))
+	Handle("/tags/{id}", tags.TagHandler(db))
+	Handle("/api/v1/tags", tags.TagsHandler(db))
+	Handle("/api/v1/tags/{id}", tags.TagHandler(db))
+}
+
+func fieldRoutes(db fields.FieldDatabase) {
+	Handle("/fields", fields.FieldsHandler(db))
+	Handle("/fields/{id}", fields.FieldHandler(db))
+	Handle("/api/v1/fields", fields.FieldsHandler(db))
+	Handle("/api/v1/fields/{id}", fields.FieldHandler(db))
+}
+
+func categoryRoutes(db categories.CategoryDatabase) {
+	Handle("/categories", categories.CategoriesHandler(db))
+	Handle("/categories/{id}", categories.CategoryHandler(db))
+	Handle("/api/v1/categories", categories.CategoriesHandler(db))
+	Handle("/api/v1/categories/{id}", categories.CategoryHandler(db))
+}
+
+func trashRoutes(db trash.TrashDatabase) {
+	Handle("/trash", trash.PageHandler(db))
+	Handle("/api/v1/trash/{thing}/{id}/restore", trash.RestoreHandler(db))
+	Handle("/api/v1/trash/{thing}/{id}", trash.PurgeHandler(db))
+}
+
+func moveRoutes(db moves.MoveDatabase) {
+	Handle("/api/v1/moves/{id}/undo", moves.UndoHandler(db))
+}
+
+// Backups contain the things of all households, so only server admins can manage them.
+func backupRoutes(db backup.BackupDatabase) {
+	HandleWithServerAdmin("/settings/backups", SettingsPageBackups(db))
+	HandleWithServerAdmin("/api/v1/backups", BackupCreateHandler(db))
+	HandleWithServerAdmin("/api/v1/backups/{name}/restore", BackupRestoreHandler(db))
+}
+
 func experimentalRoutes(db *database.DB) {
 	Handle("/switch-debug-style", SwitchDebugStyle)
 	Handle("/notification-success", func(w http.ResponseWriter, r *http.Request) {
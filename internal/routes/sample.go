@@ -40,7 +40,7 @@ func SamplePage(w http.ResponseWriter, r *http.Request) {
 func handleSampleListTemplate(db *database.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// rows := []common.ListRow{{ID: database.BOX_VALID_UUID_1, Label: "THING 1"}}
-		boxes, _ := db.BoxFuzzyFinder("", 3, 1)
+		boxes, _ := db.BoxFuzzyFinder(r.Context(), "", 3, 1)
 		// boxes, _ := db.BoxFuzzyFinder(uuid.FromStringOrNil("17973d34-1942-4a15-bcba-80ddca1b29fc"))
 		boxesWithOpts := common.AddRowOptionsToListRows(boxes,
 			common.ListRowTemplateOptions{
@@ -9,11 +9,12 @@ import (
 )
 
 func SettingsPage(w http.ResponseWriter, r *http.Request) {
-	data := settingsPageData(r)
-	server.MustRender(w, r, "settings-page", data.Map())
+	server.MustRender(w, r, "settings-page", settingsPageData(r))
 }
 
-func settingsPageData(r *http.Request) templates.PageTemplate {
+// settingsPageData returns the data every settings page needs.
+// ServerAdmin shows the settings that affect all households, like backups.
+func settingsPageData(r *http.Request) map[string]any {
 	authenticated, _ := auth.Authenticated(r)
 	username, _ := auth.UserSessionData(r)
 	data := templates.NewPageTemplate()
@@ -22,5 +23,7 @@ func settingsPageData(r *http.Request) templates.PageTemplate {
 	data.Authenticated = authenticated
 	data.User = username
 
-	return data
+	out := data.Map()
+	out["ServerAdmin"] = auth.ServerAdmin(r)
+	return out
 }
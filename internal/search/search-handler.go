@@ -0,0 +1,89 @@
+package search
+
+import (
+	"basement/main/internal/auth"
+	"basement/main/internal/common"
+	"basement/main/internal/server"
+	"basement/main/internal/templates"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// RESULTS_PER_THING is the amount of matches shown for each type of thing.
+const RESULTS_PER_THING = 10
+
+// PageHandler renders the matches for the query parameter "query" in all items, boxes, shelves and areas.
+// The query supports the filters of common.ParseSearchQuery.
+//
+//	GET /search?query=drill
+func PageHandler(db SearchDatabase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Add("Allow", http.MethodGet)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+			return
+		}
+
+		query := strings.TrimSpace(r.FormValue("query"))
+		results, err := db.Search(r.Context(), query, RESULTS_PER_THING)
+		if err != nil {
+			server.WriteInternalServerError("can't search", err, w, r)
+			return
+		}
+
+		authenticated, _ := auth.Authenticated(r)
+		username, _ := auth.UserSessionData(r)
+		page := templates.NewPageTemplate()
+		page.Title = "Search"
+		page.RequestOrigin = "Search"
+		page.Authenticated = authenticated
+		page.User = username
+
+		data := page.Map()
+		data["Query"] = query
+		data["QueryError"] = common.SearchQueryErrorMessage(query)
+		data["Results"] = results
+		server.MustRender(w, r, "search-page", data)
+	}
+}
+
+// APIHandler writes the matches for the query parameter "query" in all items, boxes, shelves and areas as JSON.
+// The parameter "limit" sets the amount of matches for each type of thing, at most common.MAX_LIMIT.
+//
+//	GET /api/v1/search?query=drill&limit=5
+func APIHandler(db SearchDatabase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Add("Allow", http.MethodGet)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+			return
+		}
+
+		limit := RESULTS_PER_THING
+		if r.FormValue("limit") != "" {
+			var err error
+			limit, err = strconv.Atoi(r.FormValue("limit"))
+			if err != nil || limit < 1 {
+				server.WriteBadRequestError("limit must be a positive number", err, w, r)
+				return
+			}
+			limit = min(limit, common.MAX_LIMIT)
+		}
+
+		query := strings.TrimSpace(r.FormValue("query"))
+		if message := common.SearchQueryErrorMessage(query); message != "" {
+			server.WriteBadRequestError(message, nil, w, r)
+			return
+		}
+		results, err := db.Search(r.Context(), query, limit)
+		if err != nil {
+			server.WriteInternalServerError("can't search", err, w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		server.WriteJSON(w, results)
+	}
+}
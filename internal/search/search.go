@@ -0,0 +1,66 @@
+package search
+
+import (
+	"basement/main/internal/common"
+	"context"
+	"net/url"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+type SearchDatabase interface {
+	Search(ctx context.Context, query string, limit int) (Results, error)
+}
+
+// Things are the types of things a search looks through, in the order their groups are shown.
+var Things = []string{"item", "box", "shelf", "area"}
+
+// Results of a search through all things of the household.
+type Results struct {
+	Query  string  `json:"query"`
+	Total  int     `json:"total"`
+	Groups []Group `json:"groups"`
+}
+
+// Group holds the best matches of a single type of thing.
+type Group struct {
+	Thing   string   `json:"thing"` // "item", "box", "shelf" or "area"
+	Count   int      `json:"count"` // amount of all matches, Results holds at most the limit
+	Results []Result `json:"results"`
+}
+
+// Result is a single thing that matched a search.
+type Result struct {
+	Thing string `json:"thing"`
+	URL   string `json:"url"` // details page of the thing
+	common.ListRow
+}
+
+func NewResult(thing string, row common.ListRow) Result {
+	return Result{Thing: thing, URL: DetailsURL(thing, row.ID), ListRow: row}
+}
+
+// DetailsURL returns the path of the details page of a thing.
+func DetailsURL(thing string, id uuid.UUID) string {
+	return "/" + thing + "/" + id.String()
+}
+
+// More returns true if not all matches of the group are in Results.
+func (g Group) More() bool {
+	return g.Count > len(g.Results)
+}
+
+// ListURL returns the list page of the group filtered by query.
+func (g Group) ListURL(query string) string {
+	switch g.Thing {
+	case "item":
+		return "/items?query=" + url.QueryEscape(query)
+	case "box":
+		return "/boxes?query=" + url.QueryEscape(query)
+	case "shelf":
+		return "/shelves?query=" + url.QueryEscape(query)
+	case "area":
+		return "/areas?query=" + url.QueryEscape(query)
+	}
+	return ""
+}
@@ -9,9 +9,17 @@ import (
 )
 
 type event struct {
-	Type     string `json:"type"`
-	Message  string `json:"message"`
-	Duration int    `json:"duration"`
+	Type     string              `json:"type"`
+	Message  string              `json:"message"`
+	Duration int                 `json:"duration"`
+	Action   *NotificationAction `json:"action,omitempty"`
+}
+
+// NotificationAction is a button inside of a notification that sends a request when clicked.
+type NotificationAction struct {
+	Label  string `json:"label"`
+	Method string `json:"method"` // "POST", "PUT", "DELETE", ...
+	URL    string `json:"url"`
 }
 
 func (e event) toJSONString() string {
@@ -47,6 +55,13 @@ func (e *Notifications) AddSingleError(msg string) {
 	e.Add(msg, "single-error", 10000)
 }
 
+// AddSuccessWithAction adds a success notification with a button, like "Undo".
+// It is shown longer than other success notifications so there is time to click it.
+func (e *Notifications) AddSuccessWithAction(msg string, action NotificationAction) {
+	e.Add(msg, "success", 10000)
+	e.ServerNotificationEvents[len(e.ServerNotificationEvents)-1].Action = &action
+}
+
 // Add adds a server notification that is triggered on the client.
 //
 // `notificationType` can be "" (info), "success", "warning", "error".
@@ -3,6 +3,7 @@ package server
 import (
 	"basement/main/internal/logg"
 	"basement/main/internal/templates"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -33,6 +34,19 @@ func WriteBadRequestError(message string, err error, w http.ResponseWriter, r *h
 	}
 }
 
+// WriteForbiddenError sets forbidden status code 403, triggers an error notification and writes message to client.
+func WriteForbiddenError(message string, w http.ResponseWriter, r *http.Request) {
+	n := Notifications{}
+	n.AddSingleError(message)
+	data, err := json.Marshal(n.ServerNotificationEvents)
+	if err == nil {
+		w.Header().Set("HX-Trigger", string(data))
+	}
+	w.WriteHeader(http.StatusForbidden)
+	fmt.Fprint(w, message)
+	logg.Infof(`%s "%s": %s`, r.Method, r.URL.String(), message)
+}
+
 // WriteNotFoundError sets not found status code 404, logs error and writes error message to client.
 func WriteNotFoundError(message string, err error, w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNotFound)
@@ -170,7 +184,7 @@ func ParseIDsFromFormWithKey(form url.Values, key string) ([]uuid.UUID, error) {
 	return ids, nil
 }
 
-func DeleteThingsFromList(w http.ResponseWriter, r *http.Request, deleteFunc func(id uuid.UUID) error, listPageHandler http.HandlerFunc) {
+func DeleteThingsFromList(w http.ResponseWriter, r *http.Request, deleteFunc func(ctx context.Context, id uuid.UUID) error, listPageHandler http.HandlerFunc) {
 	r.ParseForm()
 
 	parseIDs, _ := ParseIDsFromFormWithKey(r.Form, "delete")
@@ -184,7 +198,7 @@ func DeleteThingsFromList(w http.ResponseWriter, r *http.Request, deleteFunc fun
 		for i, v := range parseIDs {
 			logg.Debug("deleting " + v.String())
 			ids[i] = v
-			err := deleteFunc(v)
+			err := deleteFunc(r.Context(), v)
 			if err != nil {
 				notifications.AddError(`can't delete: "` + v.String() + `"`)
 				logg.Err(logg.WrapErr(err))
@@ -200,7 +214,7 @@ func DeleteThingsFromList(w http.ResponseWriter, r *http.Request, deleteFunc fun
 	listPageHandler.ServeHTTP(w, r)
 }
 
-func MoveThingToThing(w http.ResponseWriter, r *http.Request, moveFunc func(id1 uuid.UUID, id2 uuid.UUID) error) Notifications {
+func MoveThingToThing(w http.ResponseWriter, r *http.Request, moveFunc func(ctx context.Context, id1 uuid.UUID, id2 uuid.UUID) error) Notifications {
 	r.ParseForm()
 	moveToBoxID := ValidID(w, r, "can't move things invalid id")
 	if moveToBoxID == uuid.Nil {
@@ -217,7 +231,7 @@ func MoveThingToThing(w http.ResponseWriter, r *http.Request, moveFunc func(id1
 		logg.Debug(v)
 		id := uuid.FromStringOrNil(v)
 		ids[i] = id
-		err := moveFunc(id, moveToBoxID)
+		err := moveFunc(r.Context(), id, moveToBoxID)
 		if err != nil {
 			notifications.AddError("can't move \"" + ids[i].String() + "\" to \"" + moveToBoxID.String() + "\"")
 			logg.Err(err)
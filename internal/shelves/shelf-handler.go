@@ -24,7 +24,7 @@ func ShelfHandler(db ShelfDB) http.HandlerFunc {
 				return
 			}
 
-			shelf, err := db.Shelf(id)
+			shelf, err := db.Shelf(r.Context(), id)
 			if err != nil {
 				server.WriteNotFoundError(errMsgForUser, err, w, r)
 				return
@@ -63,14 +63,14 @@ func createShelf(w http.ResponseWriter, r *http.Request, db ShelfDB) {
 	if err != nil {
 		if err == validator.Err() {
 			logg.Warning("validation error while creating the Shelf: %v", validator.Messages.Map())
-			templates.Render(w, "shelf-create", validator.ShelfFormData(false))
+			templates.Render(w, "shelf-create", common.WithCustomFieldInputs(validator.ShelfFormData(false)))
 		} else {
 			logg.Debugf("error happened while creating the Shelf: %v", err)
 			server.TriggerSingleErrorNotification(w, "Error while creating the Shelf, please come back later")
 		}
 		return
 	}
-	err = db.CreateShelf(shelf)
+	err = db.CreateShelf(r.Context(), shelf)
 	if err != nil {
 		templates.RenderErrorNotification(w, "Error while creating a new shelf, please try again later")
 		return
@@ -83,7 +83,7 @@ func updateShelf(w http.ResponseWriter, r *http.Request, db ShelfDB) {
 	if err != nil {
 		if err == validator.Err() {
 			logg.Warning("validation error while Updating the Shelf: %v", validator.Messages.Map())
-			templates.Render(w, "shelf-details", validator.ShelfFormData(true))
+			templates.Render(w, "shelf-details", common.WithCustomFieldInputs(validator.ShelfFormData(true)))
 		} else {
 			logg.Debugf("error happened while Updating the Shelf: %v", err)
 			server.TriggerSingleErrorNotification(w, "Error while Updating the Shelf, please come back later")
@@ -100,7 +100,7 @@ func updateShelf(w http.ResponseWriter, r *http.Request, db ShelfDB) {
 		}
 	}
 
-	err = db.UpdateShelf(shelf, ignorePicture, pictureFormat)
+	err = db.UpdateShelf(r.Context(), shelf, ignorePicture, pictureFormat)
 	fmt.Print(err)
 	if err != nil {
 		server.WriteBadRequestError("Can't update shelf. "+logg.CleanLastError(err), err, w, r)
@@ -124,7 +124,7 @@ func deleteShelf(w http.ResponseWriter, r *http.Request, db ShelfDB) {
 		templates.RenderErrorNotification(w, "Invalid shelf ID")
 		return
 	}
-	label, err := db.DeleteShelf(id)
+	label, err := db.DeleteShelf(r.Context(), id)
 	if err != nil {
 		if err == db.ErrorNotEmpty() {
 			w.WriteHeader(http.StatusNotFound)
@@ -154,7 +154,7 @@ func DeleteShelves(db ShelfDB) http.HandlerFunc {
 		notifications := server.Notifications{}
 
 		for _, deleteId := range toDelete {
-			label, err := db.DeleteShelf(deleteId)
+			label, err := db.DeleteShelf(r.Context(), deleteId)
 			if err != nil {
 				if err == db.ErrorNotEmpty() {
 					logg.Debug("the Shelf with the label: " + label + " and id:" + deleteId.String() +
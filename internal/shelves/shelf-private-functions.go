@@ -5,6 +5,7 @@ import (
 	"basement/main/internal/env"
 	"basement/main/internal/logg"
 	"basement/main/internal/server"
+	"context"
 	"net/http"
 )
 
@@ -12,7 +13,7 @@ import (
 func getTemplateData(r *http.Request, db ShelfDB, w http.ResponseWriter) common.Data {
 	data := common.InitData(r, true)
 
-	count, err := db.ShelfListCounter(data.GetSearchInputValue())
+	count, err := db.ShelfListCounter(r.Context(), data.GetSearchInputValue())
 	if err != nil {
 		server.WriteInternalServerError("error shelves counter", err, w, r)
 	}
@@ -34,7 +35,7 @@ func getTemplateData(r *http.Request, db ShelfDB, w http.ResponseWriter) common.
 			HideBoxLabel:   true,
 			HideShelfLabel: true,
 		})
-		shelves, err = filledShelfRows(db, data)
+		shelves, err = filledShelfRows(r.Context(), db, data)
 		if err != nil {
 			server.WriteInternalServerError("cant query shelves please comeback later", err, w, r)
 		}
@@ -46,10 +47,10 @@ func getTemplateData(r *http.Request, db ShelfDB, w http.ResponseWriter) common.
 
 // filledShelfRows returns ListRows of Shelves with empty entries filled up to match limit.
 // count - The total number of records found from the search query.
-func filledShelfRows(db ShelfDB, data common.Data) ([]common.ListRow, error) {
+func filledShelfRows(ctx context.Context, db ShelfDB, data common.Data) ([]common.ListRow, error) {
 	limit := data.GetLimit()
 	shelvesMaps := make([]common.ListRow, limit)
-	shelves, err := db.ShelfListRows(data.GetSearchInputValue(), limit, data.GetPageNumber())
+	shelves, err := db.ShelfListRows(ctx, data.GetSearchInputValue(), limit, data.GetPageNumber())
 	if err != nil {
 		return nil, logg.WrapErr(err)
 	}
@@ -35,6 +35,12 @@ func CreateTemplate() http.HandlerFunc {
 		page.User = user
 
 		shelf := newShelf()
+		var err error
+		shelf.CustomFields, err = common.EmptyCustomFieldValues(r.Context(), "shelf")
+		if err != nil {
+			server.WriteInternalServerError("can't load the custom fields of shelves please comeback later", err, w, r)
+			return
+		}
 		data := page.Map()
 		maps.Copy(data, shelf.Map())
 
@@ -55,7 +61,7 @@ func DetailsTemplate(db ShelfDB) http.HandlerFunc {
 			return
 		}
 
-		shelf, err := db.Shelf(id)
+		shelf, err := db.Shelf(r.Context(), id)
 		if err != nil {
 			server.TriggerErrorNotification(w, errMsgForUser)
 		}
@@ -4,6 +4,7 @@ import (
 	"basement/main/internal/common"
 	"basement/main/internal/logg"
 	"basement/main/internal/validate"
+	"context"
 	"net/http"
 
 	"github.com/gofrs/uuid/v5"
@@ -38,29 +39,30 @@ type shelfTemplateDetailsData struct {
 }
 
 type ShelfDB interface {
-	Shelf(id uuid.UUID) (*Shelf, error)
-	CreateShelf(shelf *Shelf) error
-	UpdateShelf(shelf *Shelf, ignorePicture bool, pictureFormat string) error
-	DeleteShelf(id uuid.UUID) (label string, err error)
-	ShelfListRows(searchString string, limit int, pageNr int) (shelfRows []common.ListRow, err error)
-	ShelfListCounter(queryString string) (count int, err error)
+	Shelf(ctx context.Context, id uuid.UUID) (*Shelf, error)
+	CreateShelf(ctx context.Context, shelf *Shelf) error
+	UpdateShelf(ctx context.Context, shelf *Shelf, ignorePicture bool, pictureFormat string) error
+	DeleteShelf(ctx context.Context, id uuid.UUID) (label string, err error)
+	ShelfListRows(ctx context.Context, searchString string, limit int, pageNr int) (shelfRows []common.ListRow, err error)
+	ShelfListCounter(ctx context.Context, queryString string) (count int, err error)
 	ErrorNotEmpty() error
 
 	// required in common.Database interface
-	InnerListRowsFrom2(belongsToTable string, belongsToTableID uuid.UUID, listRowsTable string) ([]common.ListRow, error)
-	InnerListRowsPaginatedFrom(belongsToTable string, belongsToTableID uuid.UUID, listRowsTable string, searchQuery string, limit int, page int) (listRows []common.ListRow, err error)
-	InnerBoxInBoxListCounter(searchString string, inTable string, inTableID uuid.UUID) (count int, err error)
-	InnerShelfInTableListCounter(searchString string, inTable string, inTableID uuid.UUID) (count int, err error)
-	InnerThingInTableListCounter(searchString string, thing int, inTable string, inTableID uuid.UUID) (count int, err error)
-	MoveShelfToArea(shelfID uuid.UUID, toAreaID uuid.UUID) error
-	BoxListCounter(searchQuery string) (count int, err error)
-	AreaListCounter(searchQuery string) (count int, err error)
-	BoxListRows(searchQuery string, limit int, page int) ([]common.ListRow, error)
-	AreaListRows(searchQuery string, limit int, page int) (areaRows []common.ListRow, err error)
-	DeleteItem(itemID uuid.UUID) error
-	DeleteBox(boxID uuid.UUID) error
-	DeleteShelf2(id uuid.UUID) error
-	DeleteArea(areaID uuid.UUID) error
+	InnerListRowsFrom2(ctx context.Context, belongsToTable string, belongsToTableID uuid.UUID, listRowsTable string) ([]common.ListRow, error)
+	InnerListRowsPaginatedFrom(ctx context.Context, belongsToTable string, belongsToTableID uuid.UUID, listRowsTable string, searchQuery string, limit int, page int) (listRows []common.ListRow, err error)
+	InnerBoxInBoxListCounter(ctx context.Context, searchString string, inTable string, inTableID uuid.UUID) (count int, err error)
+	InnerShelfInTableListCounter(ctx context.Context, searchString string, inTable string, inTableID uuid.UUID) (count int, err error)
+	InnerThingInTableListCounter(ctx context.Context, searchString string, thing int, inTable string, inTableID uuid.UUID) (count int, err error)
+	MoveShelfToArea(ctx context.Context, shelfID uuid.UUID, toAreaID uuid.UUID) error
+	BoxListCounter(ctx context.Context, searchQuery string) (count int, err error)
+	AreaListCounter(ctx context.Context, searchQuery string) (count int, err error)
+	BoxListRows(ctx context.Context, searchQuery string, limit int, page int) ([]common.ListRow, error)
+	AreaListRows(ctx context.Context, searchQuery string, limit int, page int) (areaRows []common.ListRow, err error)
+	DeleteItem(ctx context.Context, itemID uuid.UUID) error
+	DeleteBox(ctx context.Context, boxID uuid.UUID) error
+	DeleteShelf2(ctx context.Context, id uuid.UUID) error
+	DeleteArea(ctx context.Context, areaID uuid.UUID) error
+	CustomFields(ctx context.Context, thing string) ([]common.CustomField, error)
 }
 
 //	type PaginationData struct {
@@ -85,20 +87,23 @@ const (
 // return the Shelf in type map
 func (s *Shelf) Map() map[string]any {
 	shelfMap := map[string]any{
-		"ID":             s.ID,
-		"Label":          s.Label,
-		"Description":    s.Description,
-		"Picture":        s.Picture,
-		"PreviewPicture": s.PreviewPicture,
-		"Height":         s.Height,
-		"Width":          s.Width,
-		"Depth":          s.Depth,
-		"Rows":           s.Rows,
-		"Cols":           s.Cols,
-		"AreaID":         s.AreaID,
-		"AreaLabel":      s.AreaLabel,
-		"InnerBoxesList": s.InnerBoxesList,
-		"InnerItemsList": s.InnerItemsList,
+		"ID":                s.ID,
+		"Label":             s.Label,
+		"Description":       s.Description,
+		"Picture":           s.Picture,
+		"PreviewPicture":    s.PreviewPicture,
+		"QRCode":            s.QRCode,
+		"Height":            s.Height,
+		"Width":             s.Width,
+		"Depth":             s.Depth,
+		"Rows":              s.Rows,
+		"Cols":              s.Cols,
+		"AreaID":            s.AreaID,
+		"AreaLabel":         s.AreaLabel,
+		"InnerBoxesList":    s.InnerBoxesList,
+		"InnerItemsList":    s.InnerItemsList,
+		"Tags":              s.Tags,
+		"CustomFieldInputs": s.CustomFieldInputs(),
 	}
 
 	return shelfMap
@@ -137,6 +142,10 @@ func ValidateShelf(w http.ResponseWriter, r *http.Request) (shelf *Shelf, valida
 		Cols:   validate.NewIntField(r.PostFormValue(COLS)),
 		AreaID: validate.NewUUIDField(r.PostFormValue(AREA_ID)),
 	}
+	vshelf.CustomFields, err = common.ParseCustomFields(r, "shelf")
+	if err != nil {
+		return shelf, validate.Validate{}, logg.WrapErr(err)
+	}
 	logg.Debug(vshelf)
 
 	validator = validate.Validate{Shelf: vshelf}
@@ -159,6 +168,8 @@ func ValidateShelf(w http.ResponseWriter, r *http.Request) (shelf *Shelf, valida
 			Picture:        vshelf.Picture.String(),
 			PreviewPicture: vshelf.PreviewPicture.String(),
 			QRCode:         vshelf.QRCode.String(),
+			Tags:           common.ParseTags(r),
+			CustomFields:   common.CustomFieldValues(vshelf.CustomFields),
 		},
 		Height: vshelf.Height.Float64(),
 		Width:  vshelf.Width.Float64(),
@@ -0,0 +1,346 @@
+package shopping
+
+import (
+	"basement/main/internal/auth"
+	"basement/main/internal/server"
+	"basement/main/internal/stock"
+	"basement/main/internal/templates"
+	"basement/main/internal/validate"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+const (
+	maxLabelLength = 100
+	maxNoteLength  = 100
+)
+
+// Export formats of the shopping list.
+const (
+	FORMAT_TEXT = "text"
+	FORMAT_JSON = "json"
+)
+
+// CheckResult is the JSON response of a checked off entry.
+type CheckResult struct {
+	Entry  Entry         `json:"entry"`
+	Change *stock.Change `json:"change"` // null for entries without an item
+}
+
+// ListHandler lists and adds the entries of the shopping list of the active household.
+//
+//	GET  /shopping-list        = shopping list page
+//	GET  /api/v1/shopping-list = all entries as JSON
+//	POST                       = add entry from form values "label", "quantity" (default 1), "note" and "item_id",
+//	                             entries with an item get the label of the item if "label" is empty
+func ListHandler(db ShoppingDatabase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			entries, err := db.ShoppingEntries(r.Context())
+			if err != nil {
+				server.WriteInternalServerError("can't query the shopping list", err, w, r)
+				return
+			}
+			if !server.WantsTemplateData(r) {
+				if entries == nil {
+					entries = []Entry{}
+				}
+				server.WriteJSON(w, entries)
+				return
+			}
+			pageHandler(w, r, entries, nil, nil)
+			break
+
+		case http.MethodPost:
+			entry, ok := validEntry(w, r)
+			if !ok {
+				return
+			}
+			entry, err := db.AddShoppingEntry(r.Context(), entry)
+			if err != nil {
+				server.WriteNotFoundError("can't add to the shopping list", err, w, r)
+				return
+			}
+			if !server.WantsTemplateData(r) {
+				w.WriteHeader(http.StatusCreated)
+				server.WriteJSON(w, entry)
+				return
+			}
+			server.RedirectWithSuccessNotification(w, "/shopping-list", `Added "`+entry.Label+`" to the shopping list`)
+			break
+
+		default:
+			w.Header().Add("Allow", http.MethodGet)
+			w.Header().Add("Allow", http.MethodPost)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+		}
+	}
+}
+
+// GenerateHandler puts every item below its minimum stock on the shopping list.
+//
+//	POST /shopping-list/generate
+//	POST /api/v1/shopping-list/generate = responds with the amount of added entries as JSON
+func GenerateHandler(db ShoppingDatabase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Add("Allow", http.MethodPost)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+			return
+		}
+		added, err := db.GenerateShoppingEntries(r.Context())
+		if err != nil {
+			server.WriteInternalServerError("can't add low stock items to the shopping list", err, w, r)
+			return
+		}
+		if !server.WantsTemplateData(r) {
+			server.WriteJSON(w, map[string]int{"added": added})
+			return
+		}
+		message := "All items below their minimum stock are on the list"
+		if added == 1 {
+			message = "Added 1 item below its minimum stock"
+		} else if added > 1 {
+			message = fmt.Sprintf("Added %d items below their minimum stock", added)
+		}
+		server.RedirectWithSuccessNotification(w, "/shopping-list", message)
+	}
+}
+
+// EntryHandler removes the entry with the path value "id" from the shopping list without buying it.
+//
+//	DELETE /shopping-list/{id}
+//	DELETE /api/v1/shopping-list/{id}
+func EntryHandler(db ShoppingDatabase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			w.Header().Add("Allow", http.MethodDelete)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+			return
+		}
+		id, ok := validEntryID(w, r)
+		if !ok {
+			return
+		}
+		err := db.DeleteShoppingEntry(r.Context(), id)
+		if err != nil {
+			server.WriteNotFoundError("can't remove the entry from the shopping list", err, w, r)
+			return
+		}
+		if !server.WantsTemplateData(r) {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		server.RedirectWithSuccessNotification(w, "/shopping-list", "Removed from the shopping list")
+	}
+}
+
+// CheckHandler checks off the bought entry with the path value "id".
+// The quantity of the entry is added to the stock of its item.
+//
+//	POST /shopping-list/{id}/check        = shopping list page offering to put the item away into its usual place
+//	POST /api/v1/shopping-list/{id}/check = responds with a CheckResult as JSON
+func CheckHandler(db ShoppingDatabase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Add("Allow", http.MethodPost)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+			return
+		}
+		id, ok := validEntryID(w, r)
+		if !ok {
+			return
+		}
+		entry, change, err := db.CheckShoppingEntry(r.Context(), id)
+		if err != nil {
+			server.WriteNotFoundError("can't check off the entry", err, w, r)
+			return
+		}
+		if !server.WantsTemplateData(r) {
+			server.WriteJSON(w, CheckResult{Entry: entry, Change: change})
+			return
+		}
+		message := fmt.Sprintf("Bought %d x %s", entry.Quantity, entry.Label)
+		if change == nil {
+			server.RedirectWithSuccessNotification(w, "/shopping-list", message)
+			return
+		}
+		entries, err := db.ShoppingEntries(r.Context())
+		if err != nil {
+			server.WriteInternalServerError("can't query the shopping list", err, w, r)
+			return
+		}
+		server.TriggerSuccessNotification(w, fmt.Sprintf("%s, now %d in stock", message, change.Quantity))
+		pageHandler(w, r, entries, &entry, change)
+	}
+}
+
+// PutAwayHandler moves the item with the form value "item_id" into the box, shelf or area
+// with the form values "thing" and "place_id", its usual place after it was bought.
+//
+//	POST /shopping-list/put-away
+//	POST /api/v1/shopping-list/put-away
+func PutAwayHandler(db ShoppingDatabase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Add("Allow", http.MethodPost)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+			return
+		}
+		itemID := validate.NewUUIDField(r.FormValue("item_id"))
+		placeID := validate.NewUUIDField(r.FormValue("place_id"))
+		if itemID.IsValid() != nil || placeID.IsValid() != nil || itemID.UUID() == uuid.Nil || placeID.UUID() == uuid.Nil {
+			writeInvalid(w, r, "The item or its place is invalid.")
+			return
+		}
+
+		var err error
+		thing := r.FormValue("thing")
+		switch thing {
+		case "box":
+			err = db.MoveItemToBox(r.Context(), itemID.UUID(), placeID.UUID())
+		case "shelf":
+			err = db.MoveItemToShelf(r.Context(), itemID.UUID(), placeID.UUID())
+		case "area":
+			err = db.MoveItemToArea(r.Context(), itemID.UUID(), placeID.UUID())
+		default:
+			writeInvalid(w, r, `Items can only be put away into a "box", "shelf" or "area".`)
+			return
+		}
+		if err != nil {
+			server.WriteNotFoundError("can't put the item away", err, w, r)
+			return
+		}
+		if !server.WantsTemplateData(r) {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		server.RedirectWithSuccessNotification(w, "/shopping-list", "Put away into the "+thing)
+	}
+}
+
+// ExportHandler downloads the shopping list as plain text or JSON.
+//
+//	GET /shopping-list/export?format=text = one entry per line, the default
+//	GET /shopping-list/export?format=json = all entries as JSON
+func ExportHandler(db ShoppingDatabase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Add("Allow", http.MethodGet)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+			return
+		}
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = FORMAT_TEXT
+		}
+		if format != FORMAT_TEXT && format != FORMAT_JSON {
+			server.WriteBadRequestError(`The format must be "text" or "json".`, nil, w, r)
+			return
+		}
+		entries, err := db.ShoppingEntries(r.Context())
+		if err != nil {
+			server.WriteInternalServerError("can't query the shopping list", err, w, r)
+			return
+		}
+
+		if format == FORMAT_JSON {
+			if entries == nil {
+				entries = []Entry{}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Disposition", `attachment; filename="shopping-list.json"`)
+			server.WriteJSON(w, entries)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="shopping-list.txt"`)
+		server.WriteFprint(w, Text(entries))
+	}
+}
+
+func pageHandler(w http.ResponseWriter, r *http.Request, entries []Entry, bought *Entry, change *stock.Change) {
+	authenticated, _ := auth.Authenticated(r)
+	username, _ := auth.UserSessionData(r)
+	page := templates.NewPageTemplate()
+	page.Title = "Shopping list"
+	page.RequestOrigin = "Shopping"
+	page.Authenticated = authenticated
+	page.User = username
+
+	data := page.Map()
+	data["Entries"] = entries
+	if bought != nil {
+		data["Bought"] = *bought
+		data["Change"] = change
+	}
+	server.MustRender(w, r, "shopping-list-page", data)
+}
+
+// validEntry returns the entry described by the form values "label", "quantity", "note" and "item_id".
+// Spaces in the label and note are collapsed. Writes an error and returns false if the entry is invalid.
+func validEntry(w http.ResponseWriter, r *http.Request) (Entry, bool) {
+	entry := Entry{
+		Label:    strings.Join(strings.Fields(r.FormValue("label")), " "),
+		Quantity: 1,
+		Note:     strings.Join(strings.Fields(r.FormValue("note")), " "),
+	}
+	message := ""
+	if input := r.FormValue("item_id"); input != "" {
+		itemID := validate.NewUUIDField(input)
+		if itemID.IsValid() != nil {
+			message = "The item is invalid."
+		}
+		entry.ItemID = itemID.UUID()
+	}
+	if input := strings.TrimSpace(r.FormValue("quantity")); input != "" {
+		quantity, err := strconv.ParseInt(input, 10, 64)
+		if err != nil || quantity <= 0 {
+			message = "The quantity must be a whole number above 0."
+		}
+		entry.Quantity = quantity
+	}
+	if entry.Label == "" && entry.ItemID == uuid.Nil {
+		message = "The entry needs a label or an item."
+	} else if utf8.RuneCountInString(entry.Label) > maxLabelLength {
+		message = "The label can't be longer than 100 characters."
+	} else if utf8.RuneCountInString(entry.Note) > maxNoteLength {
+		message = "The note can't be longer than 100 characters."
+	}
+	if message != "" {
+		writeInvalid(w, r, message)
+		return Entry{}, false
+	}
+	return entry, true
+}
+
+// validEntryID returns the path value "id" of an entry.
+// Writes an error and returns false if it is not a number.
+func validEntryID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		server.WriteBadRequestError("invalid shopping list entry", nil, w, r)
+		return 0, false
+	}
+	return id, true
+}
+
+func writeInvalid(w http.ResponseWriter, r *http.Request, message string) {
+	if server.WantsTemplateData(r) {
+		server.TriggerSingleErrorNotification(w, message)
+	} else {
+		server.WriteBadRequestError(message, nil, w, r)
+	}
+}
@@ -0,0 +1,59 @@
+package shopping
+
+import (
+	"basement/main/internal/stock"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+type ShoppingDatabase interface {
+	ShoppingEntries(ctx context.Context) ([]Entry, error)
+	AddShoppingEntry(ctx context.Context, entry Entry) (Entry, error)
+	GenerateShoppingEntries(ctx context.Context) (added int, err error)
+	CheckShoppingEntry(ctx context.Context, id int64) (Entry, *stock.Change, error)
+	DeleteShoppingEntry(ctx context.Context, id int64) error
+	MoveItemToBox(ctx context.Context, itemID uuid.UUID, boxID uuid.UUID) error
+	MoveItemToShelf(ctx context.Context, itemID uuid.UUID, shelfID uuid.UUID) error
+	MoveItemToArea(ctx context.Context, itemID uuid.UUID, areaID uuid.UUID) error
+}
+
+// Entry is a thing to buy, either an item of the household or anything typed by hand.
+type Entry struct {
+	ID        int64     `json:"id"`
+	ItemID    uuid.UUID `json:"itemId"` // uuid.Nil for entries without an item
+	Label     string    `json:"label"`
+	Quantity  int64     `json:"quantity"`
+	Note      string    `json:"note"`
+	Generated bool      `json:"generated"` // added because the item fell below its minimum stock
+	CreatedAt time.Time `json:"createdAt"`
+	Place     *Place    `json:"place,omitempty"` // where the item is put away, nil if it has no place
+}
+
+// Place is the box, shelf or area an item is usually kept in.
+type Place struct {
+	Thing string    `json:"thing"` // "box", "shelf" or "area"
+	ID    uuid.UUID `json:"id"`
+	Label string    `json:"label"`
+}
+
+// String returns the place like `box "Fridge"`.
+func (p Place) String() string {
+	return fmt.Sprintf(`%s "%s"`, p.Thing, p.Label)
+}
+
+// Text returns the entries as a plain text list with one entry per line, like "- 2 x Milk (lactose free)".
+func Text(entries []Entry) string {
+	var b strings.Builder
+	for _, e := range entries {
+		fmt.Fprintf(&b, "- %d x %s", e.Quantity, e.Label)
+		if e.Note != "" {
+			fmt.Fprintf(&b, " (%s)", e.Note)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
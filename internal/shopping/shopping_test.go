@@ -0,0 +1,20 @@
+package shopping
+
+import (
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestText(t *testing.T) {
+	entries := []Entry{
+		{Label: "Milk", Quantity: 2, Note: "lactose free"},
+		{Label: "Batteries AA", Quantity: 8},
+	}
+	assert.Equal(t, Text(entries), "- 2 x Milk (lactose free)\n- 8 x Batteries AA\n")
+	assert.Equal(t, Text(nil), "")
+}
+
+func TestPlaceString(t *testing.T) {
+	assert.Equal(t, Place{Thing: "box", Label: "Fridge"}.String(), `box "Fridge"`)
+}
@@ -12,7 +12,14 @@ const NotificationTypeInfo = "";
  * @property {string} message - The message text.
  * @property {NotificationTypeError | NotificationTypeSuccess | NotificationTypeWarning | NotificationTypeInfo | undefined} notificationType
  * @property {number | undefined} duration - How long is should display before removal. Default is 2000 (2 seconds).
- * @property {number | undefined} id - Add Id to HTML element: <div id="notification-{id}</div>. Will be random by default */
+ * @property {number | undefined} id - Add Id to HTML element: <div id="notification-{id}</div>. Will be random by default
+ * @property {NotificationAction | undefined} action - Optional button inside of the notification. */
+
+/**
+ * @typedef {Object} NotificationAction
+ * @property {string} label - Text of the button, like "Undo".
+ * @property {string} method - HTTP method of the request that is sent on click.
+ * @property {string} url - URL of the request that is sent on click. */
 
 //document.getElementById('registerButton').addEventListener('click', function(event) {
 //    var password = document.getElementById('password').value;
@@ -117,9 +124,13 @@ function errorResponseCallback(resInfo) {
  * @param {string} text - Message to display.
  * @param {NotificationTypeError | NotificationTypeInfo | NotificationTypeSuccess | NotificationTypeWarning | undefined } notificationType
  * @param {number | undefined} duration - How long is should display before removal. Default is 2000 (2 seconds).
- * @param {number | undefined} id - Add Id to HTML element: <div id="notification-{id}</div>. Will be random by default. */
-function createAndShowNotification(text, notificationType, duration = 2000, id) {
+ * @param {number | undefined} id - Add Id to HTML element: <div id="notification-{id}</div>. Will be random by default.
+ * @param {NotificationAction | undefined} action - Optional button inside of the notification. */
+function createAndShowNotification(text, notificationType, duration = 2000, id, action) {
     const snackbar = createNotification(text, notificationType, id);
+    if (action) {
+        addNotificationAction(snackbar, action);
+    }
     const snackbarElements = document.getElementById("notification-container");
     snackbarElements.appendChild(snackbar);
 
@@ -168,6 +179,21 @@ function createNotification(text, notificationType, id) {
     return snackbar;
 }
 
+/** addNotificationAction adds a button to the snackbar that sends the request of action and removes the snackbar.
+ * @param {HTMLDivElement} snackbar
+ * @param {NotificationAction} action */
+function addNotificationAction(snackbar, action) {
+    const button = document.createElement("button");
+    button.type = "button";
+    button.textContent = action.label;
+    button.addEventListener("click", () => {
+        button.disabled = true;
+        htmx.ajax(action.method, action.url, { swap: "none" })
+            .then(() => removeNotification(snackbar.id));
+    });
+    snackbar.appendChild(button);
+}
+
 /** showNotification creates notification snackbar with id and automatically removes after duration.
  * @param id {string} HTML Element id.
  * @param text {string | undefined} Message to display.
@@ -255,7 +281,7 @@ function serverNotificationsCallback(evt) {
  * @param {ServerNotification[]} notifications */
 function serverNotifications(notifications) {
     for (let i = 0; i < notifications.length; i++) {
-        createAndShowNotification(notifications[i].message, notifications[i].type, notifications[i].duration, notifications[i].id);
+        createAndShowNotification(notifications[i].message, notifications[i].type, notifications[i].duration, notifications[i].id, notifications[i].action);
     }
 }
 
@@ -0,0 +1,73 @@
+package stock
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	chartWidth  = 600
+	chartHeight = 150
+)
+
+// Chart is a step chart of the quantity of an item over time, drawn as an SVG polyline.
+type Chart struct {
+	Width        int
+	Height       int
+	Points       string  // points of the polyline, like "0,150 300,75 600,75"
+	ThresholdY   float64 // y of the minimum stock line, only drawn if HasThreshold
+	HasThreshold bool
+	MaxQuantity  int64     // quantity at the top of the chart
+	From         time.Time // time at the left edge
+	To           time.Time // time at the right edge
+}
+
+// NewChart returns the chart of changes, the oldest change first, up to now.
+// Returns an empty chart without points if there are no changes.
+func NewChart(changes []Change, now time.Time) Chart {
+	chart := Chart{Width: chartWidth, Height: chartHeight}
+	if len(changes) == 0 {
+		return chart
+	}
+	chart.From = changes[0].CreatedAt
+	chart.To = now
+	if !chart.To.After(chart.From) {
+		chart.To = chart.From.Add(time.Second)
+	}
+
+	last := changes[len(changes)-1]
+	chart.MaxQuantity = last.MinStock
+	for _, c := range changes {
+		chart.MaxQuantity = max(chart.MaxQuantity, c.Quantity)
+	}
+	chart.MaxQuantity = max(chart.MaxQuantity, 1)
+
+	x := func(t time.Time) float64 {
+		return float64(chartWidth) * float64(t.Sub(chart.From)) / float64(chart.To.Sub(chart.From))
+	}
+	y := func(quantity int64) float64 {
+		return float64(chartHeight) - float64(chartHeight)*float64(quantity)/float64(chart.MaxQuantity)
+	}
+
+	// The quantity stays the same until the next change.
+	var points []string
+	for i, c := range changes {
+		if i > 0 {
+			points = append(points, point(x(c.CreatedAt), y(changes[i-1].Quantity)))
+		}
+		points = append(points, point(x(c.CreatedAt), y(c.Quantity)))
+	}
+	points = append(points, point(chartWidth, y(last.Quantity)))
+	chart.Points = strings.Join(points, " ")
+
+	if last.MinStock > 0 {
+		chart.HasThreshold = true
+		chart.ThresholdY = y(last.MinStock)
+	}
+	return chart
+}
+
+func point(x float64, y float64) string {
+	return fmt.Sprintf("%.1f,%.1f", x, y)
+}
@@ -0,0 +1,33 @@
+package stock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewChart(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	changes := []Change{
+		{Quantity: 4, CreatedAt: start},
+		{Quantity: 2, MinStock: 2, CreatedAt: start.Add(time.Hour)},
+	}
+	chart := NewChart(changes, start.Add(2*time.Hour))
+
+	expected := "0.0,0.0 300.0,0.0 300.0,75.0 600.0,75.0"
+	if chart.Points != expected {
+		t.Errorf("got points %q, expected %q", chart.Points, expected)
+	}
+	if !chart.HasThreshold || chart.ThresholdY != 75 {
+		t.Errorf("got threshold %v at %.1f, expected it at 75", chart.HasThreshold, chart.ThresholdY)
+	}
+	if chart.MaxQuantity != 4 {
+		t.Errorf("got max quantity %d, expected 4", chart.MaxQuantity)
+	}
+}
+
+func TestNewChartWithoutChanges(t *testing.T) {
+	chart := NewChart(nil, time.Now())
+	if chart.Points != "" || chart.HasThreshold {
+		t.Errorf("expected an empty chart, got %+v", chart)
+	}
+}
@@ -0,0 +1,83 @@
+package stock
+
+import (
+	"basement/main/internal/auth"
+	"basement/main/internal/common"
+	"basement/main/internal/env"
+	"basement/main/internal/server"
+	"basement/main/internal/templates"
+	"maps"
+	"net/http"
+)
+
+// LowStockPage shows the items of the household below their minimum stock, the emptiest first.
+//
+//	GET /items/low-stock
+func LowStockPage(db StockDatabase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Add("Allow", http.MethodGet)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+			return
+		}
+
+		authenticated, _ := auth.Authenticated(r)
+		user, _ := auth.UserSessionData(r)
+
+		// page template
+		page := templates.NewPageTemplate()
+		page.Title = "Low stock"
+		page.RequestOrigin = "Items"
+		page.Authenticated = authenticated
+		page.User = user
+		data := page.Map()
+
+		// list template
+		listTmpl := common.ListTemplate{
+			FormHXGet:     "/items/low-stock",
+			PlaceHolder:   true,
+			ShowLimit:     env.CurrentConfig().ShowTableSize(),
+			HideMoveCol:   true,
+			RequestOrigin: common.ParseOrigin(r),
+		}
+
+		// search-input template
+		searchString := common.SearchString(r)
+		listTmpl.SearchInput = true
+		listTmpl.SearchInputLabel = "Search items"
+		listTmpl.SearchInputValue = searchString
+
+		count, err := db.LowStockListCounter(r.Context(), searchString)
+		if err != nil {
+			server.WriteInternalServerError("cant query low stock items", err, w, r)
+			return
+		}
+
+		// pagination
+		pageNr := common.ParsePageNumber(r)
+		limit := common.ParseLimit(r)
+		data = common.Pagination(data, count, limit, pageNr)
+		listTmpl.Pagination = true
+		listTmpl.CurrentPageNumber = data["PageNumber"].(int)
+		listTmpl.Limit = limit
+		listTmpl.PaginationButtons = data["Pages"].([]common.PaginationButton)
+
+		var rows []common.ListRow
+		if count > 0 {
+			rowTemplateOptions := common.ListRowTemplateOptions{
+				HideMoveCol: true,
+				RowHXGet:    "/item",
+			}
+			rows, err = common.FilledRows(r.Context(), db.LowStockListRows, searchString, limit, pageNr, count, rowTemplateOptions)
+			if err != nil {
+				server.WriteInternalServerError("cant query low stock items", err, w, r)
+				return
+			}
+		}
+		listTmpl.Rows = rows
+
+		maps.Copy(data, listTmpl.Map())
+		server.MustRender(w, r, "low-stock-page", data)
+	}
+}
@@ -0,0 +1,122 @@
+package stock
+
+import (
+	"basement/main/internal/logg"
+	"basement/main/internal/server"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// Actions that change the stock of an item.
+const (
+	ACTION_CONSUME = "consume"
+	ACTION_RESTOCK = "restock"
+)
+
+const maxReasonLength = 100
+
+// ChangeHandler consumes or restocks the item with the path value "id".
+// action is ACTION_CONSUME or ACTION_RESTOCK.
+//
+//	POST /item/{id}/consume        = take form value "amount" (default 1) with the optional form value "reason"
+//	POST /item/{id}/restock        = add form value "amount" (default 1) with the optional form value "reason"
+//	POST /api/v1/item/{id}/consume = same, responds with the recorded change as JSON
+//	POST /api/v1/item/{id}/restock
+func ChangeHandler(db StockDatabase, action string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Add("Allow", http.MethodPost)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+			return
+		}
+
+		id := server.ValidID(w, r, "can't change stock, invalid id")
+		if id == uuid.Nil {
+			return
+		}
+		amount, reason, ok := validChange(w, r)
+		if !ok {
+			return
+		}
+		delta := amount
+		if action == ACTION_CONSUME {
+			delta = -amount
+		}
+		if reason == "" && action == ACTION_CONSUME {
+			reason = REASON_CONSUMED
+		} else if reason == "" {
+			reason = REASON_RESTOCKED
+		}
+
+		change, err := db.ChangeStock(r.Context(), id, delta, reason)
+		if errors.Is(err, ErrNotEnoughStock) {
+			writeNotEnoughStock(w, r, amount, err)
+			return
+		}
+		if err != nil {
+			server.WriteNotFoundError("can't change stock", err, w, r)
+			return
+		}
+		if !server.WantsTemplateData(r) {
+			server.WriteJSON(w, change)
+			return
+		}
+
+		notifications := server.Notifications{}
+		if action == ACTION_CONSUME {
+			notifications.AddSuccess(fmt.Sprintf("Consumed %d, %d left", amount, change.Quantity))
+		} else {
+			notifications.AddSuccess(fmt.Sprintf("Restocked %d, now %d", amount, change.Quantity))
+		}
+		if change.Below() {
+			notifications.AddWarning(fmt.Sprintf("Below the minimum stock of %d", change.MinStock))
+		}
+		server.RedirectWithNotifications(w, "/item/"+id.String(), notifications)
+	}
+}
+
+// validChange returns the form values "amount" and "reason".
+// Writes an error and returns false if they are invalid.
+func validChange(w http.ResponseWriter, r *http.Request) (amount int64, reason string, ok bool) {
+	amount = 1
+	if input := strings.TrimSpace(r.FormValue("amount")); input != "" {
+		var err error
+		amount, err = strconv.ParseInt(input, 10, 64)
+		if err != nil || amount <= 0 {
+			writeInvalid(w, r, "The amount must be a whole number above 0.")
+			return 0, "", false
+		}
+	}
+	reason = strings.Join(strings.Fields(r.FormValue("reason")), " ")
+	if utf8.RuneCountInString(reason) > maxReasonLength {
+		writeInvalid(w, r, "The reason can't be longer than 100 characters.")
+		return 0, "", false
+	}
+	return amount, reason, true
+}
+
+func writeInvalid(w http.ResponseWriter, r *http.Request, message string) {
+	if server.WantsTemplateData(r) {
+		server.TriggerSingleErrorNotification(w, message)
+	} else {
+		server.WriteBadRequestError(message, nil, w, r)
+	}
+}
+
+func writeNotEnoughStock(w http.ResponseWriter, r *http.Request, amount int64, err error) {
+	message := fmt.Sprintf("There are less than %d left.", amount)
+	if server.WantsTemplateData(r) {
+		logg.Err(err)
+		server.TriggerSingleErrorNotification(w, message)
+		return
+	}
+	w.WriteHeader(http.StatusConflict)
+	server.WriteFprint(w, message)
+}
@@ -0,0 +1,45 @@
+package stock
+
+import (
+	"basement/main/internal/common"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+var ErrNotEnoughStock = errors.New("not enough stock")
+
+// Reasons of changes that are recorded without a reason typed by the user.
+const (
+	REASON_CREATED   = "Created"
+	REASON_EDITED    = "Edited"
+	REASON_CONSUMED  = "Consumed"
+	REASON_RESTOCKED = "Restocked"
+	REASON_BOUGHT    = "Bought" // checked off the shopping list
+)
+
+type StockDatabase interface {
+	ChangeStock(ctx context.Context, itemID uuid.UUID, delta int64, reason string) (Change, error)
+	StockChanges(ctx context.Context, itemID uuid.UUID) ([]Change, error)
+	LowStockListCounter(ctx context.Context, searchQuery string) (count int, err error)
+	LowStockListRows(ctx context.Context, searchQuery string, limit int, page int) ([]common.ListRow, error)
+}
+
+// Change is a recorded change of the quantity of an item.
+type Change struct {
+	ID        int64     `json:"id"`
+	ItemID    uuid.UUID `json:"itemId"`
+	Delta     int64     `json:"delta"`    // negative when consumed
+	Quantity  int64     `json:"quantity"` // quantity after the change
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"createdAt"`
+	ActorID   uuid.UUID `json:"actorId"`
+	MinStock  int64     `json:"minStock"` // threshold of the item after the change, 0 if it has none
+}
+
+// Below returns true if the quantity after the change is below the minimum stock of the item.
+func (c Change) Below() bool {
+	return c.Quantity < c.MinStock
+}
@@ -0,0 +1,200 @@
+package tags
+
+import (
+	"basement/main/internal/auth"
+	"basement/main/internal/common"
+	"basement/main/internal/logg"
+	"basement/main/internal/server"
+	"basement/main/internal/templates"
+	"errors"
+	"net/http"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// TagDetails is the JSON response of a single tag.
+type TagDetails struct {
+	Tag
+	Things []TaggedThing `json:"things"`
+}
+
+// TagsHandler lists and creates the tags of the active household.
+//
+//	GET  /tags         = tag management page
+//	GET  /api/v1/tags  = all tags as JSON
+//	POST               = create tag from form value "name"
+func TagsHandler(db TagDatabase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			tags, err := db.Tags(r.Context())
+			if err != nil {
+				server.WriteInternalServerError("can't query tags", err, w, r)
+				return
+			}
+			if !server.WantsTemplateData(r) {
+				server.WriteJSON(w, tags)
+				return
+			}
+			pageHandler(w, r, tags)
+			break
+
+		case http.MethodPost:
+			name, ok := validName(w, r)
+			if !ok {
+				return
+			}
+			id, err := db.CreateTag(r.Context(), name)
+			if errors.Is(err, ErrTagExists) {
+				writeTagExists(w, r, name, err)
+				return
+			}
+			if err != nil {
+				server.WriteInternalServerError("can't create tag", err, w, r)
+				return
+			}
+			if !server.WantsTemplateData(r) {
+				w.WriteHeader(http.StatusCreated)
+				server.WriteJSON(w, Tag{ID: id, Name: name})
+				return
+			}
+			server.RedirectWithSuccessNotification(w, "/tags", `Created tag "`+name+`"`)
+			break
+
+		default:
+			w.Header().Add("Allow", http.MethodGet)
+			w.Header().Add("Allow", http.MethodPost)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+		}
+	}
+}
+
+// TagHandler shows, renames and deletes the tag with the path value "id".
+//
+//	GET    /tags/{id}        = search page with all things that have the tag
+//	GET    /api/v1/tags/{id} = tag with all things that have it as JSON
+//	PUT    = rename tag to form value "name"
+//	DELETE = delete tag, things keep everything except the tag
+func TagHandler(db TagDatabase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			id := server.ValidID(w, r, "can't find tag, invalid id")
+			if id == uuid.Nil {
+				return
+			}
+			tag, err := db.Tag(r.Context(), id)
+			if err != nil {
+				server.WriteNotFoundError("can't find tag", err, w, r)
+				return
+			}
+			if server.WantsTemplateData(r) {
+				http.Redirect(w, r, tag.SearchURL(), http.StatusSeeOther)
+				return
+			}
+			things, err := db.TaggedThings(r.Context(), id)
+			if err != nil {
+				server.WriteInternalServerError("can't query tagged things", err, w, r)
+				return
+			}
+			server.WriteJSON(w, TagDetails{Tag: tag, Things: things})
+			break
+
+		case http.MethodPut:
+			id := server.ValidID(w, r, "can't rename tag, invalid id")
+			if id == uuid.Nil {
+				return
+			}
+			name, ok := validName(w, r)
+			if !ok {
+				return
+			}
+			err := db.RenameTag(r.Context(), id, name)
+			if errors.Is(err, ErrTagExists) {
+				writeTagExists(w, r, name, err)
+				return
+			}
+			if err != nil {
+				server.WriteNotFoundError("can't rename tag", err, w, r)
+				return
+			}
+			if !server.WantsTemplateData(r) {
+				server.WriteJSON(w, Tag{ID: id, Name: name})
+				return
+			}
+			server.RedirectWithSuccessNotification(w, "/tags", `Renamed tag to "`+name+`"`)
+			break
+
+		case http.MethodDelete:
+			id := server.ValidID(w, r, "can't delete tag, invalid id")
+			if id == uuid.Nil {
+				return
+			}
+			err := db.DeleteTag(r.Context(), id)
+			if err != nil {
+				server.WriteNotFoundError("can't delete tag", err, w, r)
+				return
+			}
+			if !server.WantsTemplateData(r) {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			server.RedirectWithSuccessNotification(w, "/tags", "Deleted tag")
+			break
+
+		default:
+			w.Header().Add("Allow", http.MethodGet)
+			w.Header().Add("Allow", http.MethodPut)
+			w.Header().Add("Allow", http.MethodDelete)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+		}
+	}
+}
+
+func pageHandler(w http.ResponseWriter, r *http.Request, tags []Tag) {
+	authenticated, _ := auth.Authenticated(r)
+	username, _ := auth.UserSessionData(r)
+	page := templates.NewPageTemplate()
+	page.Title = "Tags"
+	page.RequestOrigin = "Tags"
+	page.Authenticated = authenticated
+	page.User = username
+
+	data := page.Map()
+	data["Tags"] = tags
+	server.MustRender(w, r, "tags-page", data)
+}
+
+// validName returns the form value "name" cleaned up like the tags of a thing, see common.SplitTags.
+// Writes an error and returns false if it isn't a single tag name.
+func validName(w http.ResponseWriter, r *http.Request) (string, bool) {
+	names := common.SplitTags(r.FormValue("name"))
+	message := ""
+	if len(names) == 0 {
+		message = "The tag needs a name."
+	} else if len(names) > 1 {
+		message = "A tag name can't contain commas."
+	}
+	if message == "" {
+		return names[0], true
+	}
+	if server.WantsTemplateData(r) {
+		server.TriggerSingleErrorNotification(w, message)
+	} else {
+		server.WriteBadRequestError(message, nil, w, r)
+	}
+	return "", false
+}
+
+func writeTagExists(w http.ResponseWriter, r *http.Request, name string, err error) {
+	message := `A tag named "` + name + `" already exists.`
+	if server.WantsTemplateData(r) {
+		logg.Err(err)
+		server.TriggerSingleErrorNotification(w, message)
+		return
+	}
+	w.WriteHeader(http.StatusConflict)
+	server.WriteFprint(w, message)
+}
@@ -0,0 +1,43 @@
+package tags
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+var ErrTagExists = errors.New("tag already exists")
+
+type TagDatabase interface {
+	Tags(ctx context.Context) ([]Tag, error)
+	Tag(ctx context.Context, id uuid.UUID) (Tag, error)
+	CreateTag(ctx context.Context, name string) (uuid.UUID, error)
+	RenameTag(ctx context.Context, id uuid.UUID, name string) error
+	DeleteTag(ctx context.Context, id uuid.UUID) error
+	TaggedThings(ctx context.Context, id uuid.UUID) ([]TaggedThing, error)
+}
+
+// Tag of a household. Count is the amount of things the tag is assigned to.
+type Tag struct {
+	ID    uuid.UUID `json:"id"`
+	Name  string    `json:"name"`
+	Count int       `json:"count"`
+}
+
+// TaggedThing is an item, box, shelf or area that has a tag.
+type TaggedThing struct {
+	Thing string    `json:"thing"`
+	ID    uuid.UUID `json:"id"`
+	Label string    `json:"label"`
+}
+
+// URL returns the details page of the thing.
+func (t TaggedThing) URL() string {
+	return "/" + t.Thing + "/" + t.ID.String()
+}
+
+// SearchURL returns the search page with all things that have the tag.
+func (t Tag) SearchURL() string {
+	return `/search?query=tag:"` + t.Name + `"`
+}
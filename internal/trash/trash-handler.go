@@ -0,0 +1,105 @@
+package trash
+
+import (
+	"basement/main/internal/auth"
+	"basement/main/internal/env"
+	"basement/main/internal/server"
+	"basement/main/internal/templates"
+	"net/http"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// PageHandler renders all things of the household that are in the trash.
+func PageHandler(db TrashDatabase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Add("Allow", http.MethodGet)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+			return
+		}
+
+		things, err := db.TrashedThings(r.Context())
+		if err != nil {
+			server.WriteInternalServerError("can't query trash", err, w, r)
+			return
+		}
+
+		authenticated, _ := auth.Authenticated(r)
+		username, _ := auth.UserSessionData(r)
+		page := templates.NewPageTemplate()
+		page.Title = "Trash"
+		page.RequestOrigin = "Trash"
+		page.Authenticated = authenticated
+		page.User = username
+
+		data := page.Map()
+		data["Things"] = things
+		data["RetentionDays"] = env.CurrentConfig().TrashRetentionDays()
+		server.MustRender(w, r, "trash-page", data)
+	}
+}
+
+// RestoreHandler restores a thing from the trash to its previous location.
+//
+//	POST /api/v1/trash/{thing}/{id}/restore
+func RestoreHandler(db TrashDatabase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Add("Allow", http.MethodPost)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+			return
+		}
+
+		thing, id, ok := thingFromPath(w, r)
+		if !ok {
+			return
+		}
+		err := db.Restore(r.Context(), thing, id)
+		if err != nil {
+			server.WriteNotFoundError("can't restore "+thing, err, w, r)
+			return
+		}
+		server.RedirectWithSuccessNotification(w, "/trash", "Restored "+thing)
+	}
+}
+
+// PurgeHandler permanently removes a thing from the trash.
+//
+//	DELETE /api/v1/trash/{thing}/{id}
+func PurgeHandler(db TrashDatabase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			w.Header().Add("Allow", http.MethodDelete)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			server.WriteFprint(w, "Method:'"+r.Method+"' not allowed")
+			return
+		}
+
+		thing, id, ok := thingFromPath(w, r)
+		if !ok {
+			return
+		}
+		err := db.Purge(r.Context(), thing, id)
+		if err != nil {
+			server.WriteNotFoundError("can't purge "+thing, err, w, r)
+			return
+		}
+		server.RedirectWithSuccessNotification(w, "/trash", "Permanently deleted "+thing)
+	}
+}
+
+func thingFromPath(w http.ResponseWriter, r *http.Request) (thing string, id uuid.UUID, ok bool) {
+	thing = r.PathValue("thing")
+	if !ValidThing(thing) {
+		server.WriteBadRequestError(`"`+thing+`" can't be in the trash`, nil, w, r)
+		return "", uuid.Nil, false
+	}
+	id = server.ValidID(w, r, "invalid id")
+	if id == uuid.Nil {
+		return "", uuid.Nil, false
+	}
+	return thing, id, true
+}
@@ -0,0 +1,41 @@
+package trash
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+type TrashDatabase interface {
+	TrashedThings(ctx context.Context) ([]Thing, error)
+	Restore(ctx context.Context, thing string, id uuid.UUID) error
+	Purge(ctx context.Context, thing string, id uuid.UUID) error
+}
+
+// Thing is an item, box, shelf or area inside of the trash.
+type Thing struct {
+	Thing     string // "item", "box", "shelf" or "area"
+	ID        uuid.UUID
+	Label     string
+	DeletedAt time.Time
+	Location  string // the box, shelf or area the thing will be restored to
+}
+
+// PurgeAt returns the time the thing is permanently removed from the trash.
+// Returns the zero time if things are kept forever.
+func (t Thing) PurgeAt(retentionDays int) time.Time {
+	if retentionDays == 0 {
+		return time.Time{}
+	}
+	return t.DeletedAt.AddDate(0, 0, retentionDays)
+}
+
+// ValidThing returns true if thing is a type of thing that can be in the trash.
+func ValidThing(thing string) bool {
+	switch thing {
+	case "item", "box", "shelf", "area":
+		return true
+	}
+	return false
+}
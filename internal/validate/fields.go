@@ -192,3 +192,54 @@ func (u UUIDField) UUID() uuid.UUID { return u.Value }
 func (u UUIDField) IsValid() error  { return u.Err }
 func (u UUIDField) IsNil() bool     { return u.Value == uuid.Nil }
 func (s UUIDField) IsEmpty() bool   { return s.Input == "" }
+
+// Types of user defined custom fields.
+const (
+	FIELD_TYPE_TEXT    = "text"
+	FIELD_TYPE_NUMBER  = "number"
+	FIELD_TYPE_DATE    = "date"
+	FIELD_TYPE_BOOLEAN = "boolean"
+	FIELD_TYPE_ENUM    = "enum"
+)
+
+// FieldTypes lists all types a custom field can have.
+var FieldTypes = []string{FIELD_TYPE_TEXT, FIELD_TYPE_NUMBER, FIELD_TYPE_DATE, FIELD_TYPE_BOOLEAN, FIELD_TYPE_ENUM}
+
+// DATE_LAYOUT is the format of date custom fields, the same as the value of an HTML date input.
+const DATE_LAYOUT = "2006-01-02"
+
+// CustomFieldValidate is the form input for a user defined field of a thing.
+type CustomFieldValidate struct {
+	ID      string
+	Name    string
+	Type    string   // one of FieldTypes
+	Options []string // allowed values of FIELD_TYPE_ENUM
+	Input   StringField
+}
+
+func NewCustomField(id string, name string, fieldType string, options []string, input string) CustomFieldValidate {
+	return CustomFieldValidate{ID: id, Name: name, Type: fieldType, Options: options, Input: NewStringField(input)}
+}
+
+// Value returns the input in the form it is stored.
+// Numbers have no trailing zeros and booleans are "true" or "".
+// Invalid input is returned trimmed.
+func (f CustomFieldValidate) Value() string {
+	input := strings.TrimSpace(f.Input.Input)
+	switch f.Type {
+	case FIELD_TYPE_NUMBER:
+		number, err := strconv.ParseFloat(input, 64)
+		if err == nil {
+			return strconv.FormatFloat(number, 'f', -1, 64)
+		}
+	case FIELD_TYPE_BOOLEAN:
+		checked, err := strconv.ParseBool(input)
+		if err == nil && !checked {
+			return ""
+		}
+		if input == "on" || checked {
+			return "true"
+		}
+	}
+	return input
+}
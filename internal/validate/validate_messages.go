@@ -12,12 +12,25 @@ func (v *ValidateMessages) Map() map[string]any {
 		"PreviewPictureError": v.PreviewPictureError,
 		"QuantityError":       v.QuantityError,
 		"WeightError":         v.WeightError,
+		"BestBeforeError":     v.BestBeforeError,
+		"ExpiresAtError":      v.ExpiresAtError,
+		"MinStockError":       v.MinStockError,
+		"PurchaseDateError":   v.PurchaseDateError,
+		"PriceError":          v.PriceError,
+		"CurrencyError":       v.CurrencyError,
+		"VendorError":         v.VendorError,
+		"SerialNumberError":   v.SerialNumberError,
+		"WarrantyUntilError":  v.WarrantyUntilError,
 		"QRCodeError":         v.QRCodeError,
 		"HeightError":         v.HeightError,
 		"WidthError":          v.WidthError,
 		"DepthError":          v.DepthError,
 		"RowsError":           v.RowsError,
 		"ColsError":           v.ColsError,
+		"CustomFieldErrors":   v.CustomFieldErrors,
+		"FieldNameError":      v.FieldNameError,
+		"FieldTypeError":      v.FieldTypeError,
+		"FieldOptionsError":   v.FieldOptionsError,
 	}
 }
 
@@ -29,6 +42,7 @@ func (b BasicInfoValidate) Map() map[string]any {
 		"Picture":        b.Picture.String(),
 		"PreviewPicture": b.PreviewPicture.String(),
 		"QRCode":         b.QRCode.String(),
+		"CustomFields":   b.CustomFields,
 	}
 }
 
@@ -39,6 +53,16 @@ func (i ItemValidate) Map() map[string]any {
 	m["BoxID"] = i.BoxID.UUID()
 	m["ShelfID"] = i.ShelfID.UUID()
 	m["AreaID"] = i.AreaID.UUID()
+	m["CategoryID"] = i.CategoryID.UUID()
+	m["BestBefore"] = i.BestBefore.String()
+	m["ExpiresAt"] = i.ExpiresAt.String()
+	m["MinStock"] = i.MinStock.Int()
+	m["PurchaseDate"] = i.PurchaseDate.String()
+	m["Price"] = i.Price.Float64()
+	m["Currency"] = i.Currency.String()
+	m["Vendor"] = i.Vendor.String()
+	m["SerialNumber"] = i.SerialNumber.String()
+	m["WarrantyUntil"] = i.WarrantyUntil.String()
 	return m
 }
 
@@ -117,6 +141,9 @@ func (v *Validate) AreaFormData(edit bool) map[string]any {
 var ValidationError = errors.New("ValidationError")
 
 func (v *Validate) HasValidateErrors() bool {
+	if len(v.Messages.CustomFieldErrors) > 0 {
+		return true
+	}
 	for _, val := range v.Messages.Map() {
 		if str, ok := val.(string); ok && str != "" {
 			return true
@@ -24,15 +24,27 @@ type BasicInfoValidate struct {
 	Picture        StringField
 	PreviewPicture StringField
 	QRCode         StringField
+	CustomFields   []CustomFieldValidate // nil if the form has no custom fields
 }
 
 type ItemValidate struct {
 	BasicInfoValidate
-	Quantity IntField
-	Weight   FloatField
-	BoxID    UUIDField
-	ShelfID  UUIDField
-	AreaID   UUIDField
+	Quantity   IntField
+	Weight     FloatField
+	BoxID      UUIDField
+	ShelfID    UUIDField
+	AreaID     UUIDField
+	CategoryID UUIDField
+	BestBefore StringField // date in DATE_LAYOUT, empty if the item has none
+	ExpiresAt  StringField // date in DATE_LAYOUT, empty if the item has none
+	MinStock   IntField    // the item is low on stock below this quantity, empty or 0 for no threshold
+	// Purchase details, all of them may be empty.
+	PurchaseDate  StringField // date in DATE_LAYOUT
+	Price         FloatField
+	Currency      StringField // 3 letter ISO 4217 code, upper case
+	Vendor        StringField
+	SerialNumber  StringField
+	WarrantyUntil StringField // date in DATE_LAYOUT
 }
 
 type BoxValidate struct {
@@ -56,6 +68,13 @@ type AreaValidate struct {
 	BasicInfoValidate
 }
 
+// CustomFieldDefinitionValidate is the form input of a new or changed custom field.
+type CustomFieldDefinitionValidate struct {
+	Name    StringField
+	Type    StringField
+	Options []string
+}
+
 type ValidateMessages struct {
 	LabelError          string
 	DescriptionError    string
@@ -63,10 +82,23 @@ type ValidateMessages struct {
 	PreviewPictureError string
 	QuantityError       string
 	WeightError         string
+	BestBeforeError     string
+	ExpiresAtError      string
+	MinStockError       string
+	PurchaseDateError   string
+	PriceError          string
+	CurrencyError       string
+	VendorError         string
+	SerialNumberError   string
+	WarrantyUntilError  string
 	QRCodeError         string
 	HeightError         string
 	WidthError          string
 	DepthError          string
 	RowsError           string
 	ColsError           string
+	CustomFieldErrors   map[string]string // error messages by custom field id
+	FieldNameError      string
+	FieldTypeError      string
+	FieldOptionsError   string
 }
@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"math"
 	"net/http"
+	"slices"
+	"strings"
+	"time"
 )
 
 func (v *Validate) ValidateID(w http.ResponseWriter, field UUIDField, required bool) error {
@@ -117,6 +120,77 @@ func (v *Validate) ValidateWeight(f FloatField) {
 	}
 }
 
+// ValidateMinStock allows an empty minimum stock, which means the item has no threshold.
+func (v *Validate) ValidateMinStock(i IntField) {
+	if i.IsEmpty() {
+		return
+	}
+	if err := i.IsZeroOrPositive(); err != nil {
+		v.Messages.MinStockError = "Minimum stock must be a number of at least 0"
+	}
+}
+
+func (v *Validate) ValidateBestBefore(s StringField) {
+	if !s.IsEmpty() && !isDate(s.String()) {
+		v.Messages.BestBeforeError = "Best before must be a date like 2024-12-31"
+	}
+}
+
+func (v *Validate) ValidateExpiresAt(s StringField) {
+	if !s.IsEmpty() && !isDate(s.String()) {
+		v.Messages.ExpiresAtError = "Expiry date must be a date like 2024-12-31"
+	}
+}
+
+func (v *Validate) ValidatePurchaseDate(s StringField) {
+	if !s.IsEmpty() && !isDate(s.String()) {
+		v.Messages.PurchaseDateError = "Purchase date must be a date like 2024-12-31"
+	}
+}
+
+func (v *Validate) ValidateWarrantyUntil(s StringField) {
+	if !s.IsEmpty() && !isDate(s.String()) {
+		v.Messages.WarrantyUntilError = "Warranty must end on a date like 2024-12-31"
+	}
+}
+
+// ValidatePrice allows an empty price, which means the price is unknown.
+func (v *Validate) ValidatePrice(f FloatField) {
+	if f.IsEmpty() {
+		return
+	}
+	if f.Err != nil || math.IsNaN(f.Float64()) || math.IsInf(f.Float64(), 0) {
+		v.Messages.PriceError = "Price must be a valid number"
+		return
+	}
+	if err := f.IsZeroOrPositive(); err != nil {
+		v.Messages.PriceError = "Price must not be negative"
+	}
+}
+
+func (v *Validate) ValidateCurrency(s StringField) {
+	if !s.IsEmpty() && s.MatchesRegexCustom(`^[A-Z]{3}$`) != nil {
+		v.Messages.CurrencyError = "Currency must be a code of 3 letters like EUR"
+	}
+}
+
+func (v *Validate) ValidateVendor(s StringField) {
+	if s.MaxLengthCustom(100) != nil {
+		v.Messages.VendorError = "Vendor must be at most 100 characters long"
+	}
+}
+
+func (v *Validate) ValidateSerialNumber(s StringField) {
+	if s.MaxLengthCustom(100) != nil {
+		v.Messages.SerialNumberError = "Serial number must be at most 100 characters long"
+	}
+}
+
+func isDate(s string) bool {
+	_, err := time.Parse(DATE_LAYOUT, s)
+	return err == nil
+}
+
 func (v *Validate) ValidateHeight(f FloatField) {
 	if f.Err != nil {
 		v.Messages.HeightError = "Height must be a valid number"
@@ -177,6 +251,77 @@ func (v *Validate) ValidateCols(i IntField) {
 	}
 }
 
+// ValidateCustomFields checks the values of user defined fields.
+// Empty values are always valid, they remove the value of the field.
+func (v *Validate) ValidateCustomFields(fields []CustomFieldValidate) {
+	for _, f := range fields {
+		message := customFieldError(f)
+		if message == "" {
+			continue
+		}
+		if v.Messages.CustomFieldErrors == nil {
+			v.Messages.CustomFieldErrors = map[string]string{}
+		}
+		v.Messages.CustomFieldErrors[f.ID] = message
+	}
+}
+
+func customFieldError(f CustomFieldValidate) string {
+	value := f.Value()
+	if value == "" {
+		return ""
+	}
+	switch f.Type {
+	case FIELD_TYPE_TEXT:
+		if err := f.Input.MaxLength(); err != nil {
+			return fmt.Sprintf("%s must be at most %d characters long", f.Name, f.Input.DefaultMaxLength)
+		}
+	case FIELD_TYPE_NUMBER:
+		if err := NewFloatField(value).Err; err != nil {
+			return f.Name + " must be a valid number"
+		}
+	case FIELD_TYPE_DATE:
+		if _, err := time.Parse(DATE_LAYOUT, value); err != nil {
+			return f.Name + " must be a date like 2024-12-31"
+		}
+	case FIELD_TYPE_BOOLEAN:
+		if value != "true" {
+			return f.Name + " must be yes or no"
+		}
+	case FIELD_TYPE_ENUM:
+		if !slices.Contains(f.Options, value) {
+			return f.Name + " must be one of " + strings.Join(f.Options, ", ")
+		}
+	}
+	return ""
+}
+
+// ValidateCustomFieldDefinition checks the name, type and options of a custom field.
+// Names are used as search filters like "voltage>200", so they must start with a letter
+// and only contain letters, numbers, spaces and _.
+func (v *Validate) ValidateCustomFieldDefinition(field CustomFieldDefinitionValidate) {
+	if field.Name.IsEmpty() {
+		v.Messages.FieldNameError = "Name is required"
+	} else if err := field.Name.MaxLengthCustom(50); err != nil {
+		v.Messages.FieldNameError = "Name must be at most 50 characters long"
+	} else if err := field.Name.MatchesRegexCustom(`^\p{L}[\p{L}\p{N} _]*$`); err != nil {
+		v.Messages.FieldNameError = "Name must start with a letter and can only contain letters, numbers, spaces and _"
+	}
+
+	if !slices.Contains(FieldTypes, field.Type.String()) {
+		v.Messages.FieldTypeError = "Type must be one of " + strings.Join(FieldTypes, ", ")
+	}
+
+	if field.Type.String() == FIELD_TYPE_ENUM && len(field.Options) == 0 {
+		v.Messages.FieldOptionsError = "A choice needs at least one option"
+	}
+	for _, option := range field.Options {
+		if len(option) > 100 {
+			v.Messages.FieldOptionsError = "Options must be at most 100 characters long"
+		}
+	}
+}
+
 func (v *Validate) ValidateItem(w http.ResponseWriter, item ItemValidate) (err error) {
 	if err := v.ValidateID(w, item.ID, true); err != nil {
 		return err
@@ -188,6 +333,16 @@ func (v *Validate) ValidateItem(w http.ResponseWriter, item ItemValidate) (err e
 
 	v.ValidateQuantity(item.Quantity)
 	v.ValidateWeight(item.Weight)
+	v.ValidateMinStock(item.MinStock)
+	v.ValidateBestBefore(item.BestBefore)
+	v.ValidateExpiresAt(item.ExpiresAt)
+	v.ValidatePurchaseDate(item.PurchaseDate)
+	v.ValidatePrice(item.Price)
+	v.ValidateCurrency(item.Currency)
+	v.ValidateVendor(item.Vendor)
+	v.ValidateSerialNumber(item.SerialNumber)
+	v.ValidateWarrantyUntil(item.WarrantyUntil)
+	v.ValidateCustomFields(item.CustomFields)
 
 	if err := v.ValidateID(w, item.BoxID, false); err != nil {
 		return err
@@ -198,6 +353,9 @@ func (v *Validate) ValidateItem(w http.ResponseWriter, item ItemValidate) (err e
 	if err := v.ValidateID(w, item.ShelfID, false); err != nil {
 		return err
 	}
+	if err := v.ValidateID(w, item.CategoryID, false); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -209,6 +367,7 @@ func (v *Validate) ValidateBox(w http.ResponseWriter, box BoxValidate) (err erro
 	v.ValidateDescription(box.Description)
 	v.ValidatePicture(box.Picture)
 	v.ValidatePreviewPicture(box.PreviewPicture)
+	v.ValidateCustomFields(box.CustomFields)
 
 	if err := v.ValidateID(w, box.OuterBoxID, false); err != nil {
 		return err
@@ -235,6 +394,7 @@ func (v *Validate) ValidateShelf(w http.ResponseWriter, shelf ShelfValidate) (er
 	v.ValidateDepth(shelf.Depth)
 	v.ValidateRows(shelf.Rows)
 	v.ValidateCols(shelf.Cols)
+	v.ValidateCustomFields(shelf.CustomFields)
 	return nil
 }
 
@@ -246,5 +406,6 @@ func (v *Validate) ValidateArea(w http.ResponseWriter, area AreaValidate) error
 	v.ValidateDescription(area.Description)
 	v.ValidatePicture(area.Picture)
 	v.ValidatePreviewPicture(area.PreviewPicture)
+	v.ValidateCustomFields(area.CustomFields)
 	return nil
 }
@@ -3,9 +3,10 @@ package validate_test
 import (
 	"basement/main/internal/validate"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
-	"github.com/google/uuid"
+	"github.com/gofrs/uuid/v5"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -20,7 +21,7 @@ func TestValidateID_RequiredEmpty(t *testing.T) {
 func TestValidateID_ValidUUID(t *testing.T) {
 	v := validate.Validate{}
 	rr := httptest.NewRecorder()
-	validUUID := validate.NewUUIDField(uuid.New().String())
+	validUUID := validate.NewUUIDField(uuid.Must(uuid.NewV4()).String())
 	err := v.ValidateID(rr, validUUID, true)
 	assert.NoError(t, err)
 }
@@ -46,6 +47,56 @@ func TestValidateWeight_InvalidParse(t *testing.T) {
 	assert.Equal(t, "Weight must be a valid number", v.Messages.WeightError)
 }
 
+func TestValidateMinStock(t *testing.T) {
+	v := validate.Validate{}
+	v.ValidateMinStock(validate.NewIntField(""))
+	assert.Equal(t, "", v.Messages.MinStockError)
+	v.ValidateMinStock(validate.NewIntField("-1"))
+	assert.Equal(t, "Minimum stock must be a number of at least 0", v.Messages.MinStockError)
+}
+
+func TestValidateBestBefore_Invalid(t *testing.T) {
+	v := validate.Validate{}
+	v.ValidateBestBefore(validate.NewStringField("31.12.2024"))
+	assert.Equal(t, "Best before must be a date like 2024-12-31", v.Messages.BestBeforeError)
+}
+
+func TestValidateExpiresAt_Empty(t *testing.T) {
+	v := validate.Validate{}
+	v.ValidateExpiresAt(validate.NewStringField(""))
+	assert.Equal(t, "", v.Messages.ExpiresAtError)
+}
+
+func TestValidatePrice(t *testing.T) {
+	v := validate.Validate{}
+	v.ValidatePrice(validate.NewFloatField(""))
+	assert.Equal(t, "", v.Messages.PriceError)
+	v.ValidatePrice(validate.NewFloatField("0"))
+	assert.Equal(t, "", v.Messages.PriceError)
+	v.ValidatePrice(validate.NewFloatField("-0.5"))
+	assert.Equal(t, "Price must not be negative", v.Messages.PriceError)
+	v.ValidatePrice(validate.NewFloatField("12,50"))
+	assert.Equal(t, "Price must be a valid number", v.Messages.PriceError)
+}
+
+func TestValidateCurrency(t *testing.T) {
+	v := validate.Validate{}
+	v.ValidateCurrency(validate.NewStringField("EUR"))
+	assert.Equal(t, "", v.Messages.CurrencyError)
+	v.ValidateCurrency(validate.NewStringField("€"))
+	assert.Equal(t, "Currency must be a code of 3 letters like EUR", v.Messages.CurrencyError)
+}
+
+func TestValidatePurchaseDetails_TooLong(t *testing.T) {
+	v := validate.Validate{}
+	v.ValidatePurchaseDate(validate.NewStringField("2024-02-30"))
+	v.ValidateVendor(validate.NewStringField(strings.Repeat("v", 101)))
+	v.ValidateSerialNumber(validate.NewStringField(strings.Repeat("s", 100)))
+	assert.Equal(t, "Purchase date must be a date like 2024-12-31", v.Messages.PurchaseDateError)
+	assert.Equal(t, "Vendor must be at most 100 characters long", v.Messages.VendorError)
+	assert.Equal(t, "", v.Messages.SerialNumberError)
+}
+
 func TestValidateDescription_Empty(t *testing.T) {
 	v := validate.Validate{}
 	field := validate.NewStringField("")
@@ -79,7 +130,7 @@ func TestValidateItem_Valid(t *testing.T) {
 	rr := httptest.NewRecorder()
 	item := validate.ItemValidate{
 		BasicInfoValidate: validate.BasicInfoValidate{
-			ID:             validate.NewUUIDField(uuid.New().String()),
+			ID:             validate.NewUUIDField(uuid.Must(uuid.NewV4()).String()),
 			Label:          validate.NewStringField("Item 1"),
 			Description:    validate.NewStringField("Valid desc"),
 			Picture:        validate.NewStringField("image/png"),
@@ -87,8 +138,8 @@ func TestValidateItem_Valid(t *testing.T) {
 		},
 		Quantity: validate.NewIntField("10"),
 		Weight:   validate.NewFloatField("2.5"),
-		BoxID:    validate.NewUUIDField(uuid.New().String()),
-		ShelfID:  validate.NewUUIDField(uuid.New().String()),
+		BoxID:    validate.NewUUIDField(uuid.Must(uuid.NewV4()).String()),
+		ShelfID:  validate.NewUUIDField(uuid.Must(uuid.NewV4()).String()),
 	}
 	err := v.ValidateItem(rr, item)
 	assert.NoError(t, err)
@@ -99,14 +150,14 @@ func TestValidateBox_Valid(t *testing.T) {
 	rr := httptest.NewRecorder()
 	box := validate.BoxValidate{
 		BasicInfoValidate: validate.BasicInfoValidate{
-			ID:             validate.NewUUIDField(uuid.New().String()),
+			ID:             validate.NewUUIDField(uuid.Must(uuid.NewV4()).String()),
 			Label:          validate.NewStringField("Box A"),
 			Description:    validate.NewStringField("desc"),
 			Picture:        validate.NewStringField("image/png"),
 			PreviewPicture: validate.NewStringField("image/jpeg"),
 		},
-		ShelfID:    validate.NewUUIDField(uuid.New().String()),
-		OuterBoxID: validate.NewUUIDField(uuid.New().String()),
+		ShelfID:    validate.NewUUIDField(uuid.Must(uuid.NewV4()).String()),
+		OuterBoxID: validate.NewUUIDField(uuid.Must(uuid.NewV4()).String()),
 	}
 	err := v.ValidateBox(rr, box)
 	assert.NoError(t, err)
@@ -117,7 +168,7 @@ func TestValidateShelf_Valid(t *testing.T) {
 	rr := httptest.NewRecorder()
 	shelf := validate.ShelfValidate{
 		BasicInfoValidate: validate.BasicInfoValidate{
-			ID:             validate.NewUUIDField(uuid.New().String()),
+			ID:             validate.NewUUIDField(uuid.Must(uuid.NewV4()).String()),
 			Label:          validate.NewStringField("Shelf X"),
 			Description:    validate.NewStringField("desc"),
 			Picture:        validate.NewStringField("image/png"),
@@ -127,3 +178,52 @@ func TestValidateShelf_Valid(t *testing.T) {
 	err := v.ValidateShelf(rr, shelf)
 	assert.NoError(t, err)
 }
+
+func TestValidateCustomFields(t *testing.T) {
+	v := validate.Validate{}
+	v.ValidateCustomFields([]validate.CustomFieldValidate{
+		validate.NewCustomField("1", "Voltage", validate.FIELD_TYPE_NUMBER, nil, " 230.0 "),
+		validate.NewCustomField("2", "Bought", validate.FIELD_TYPE_DATE, nil, "2024-12-31"),
+		validate.NewCustomField("3", "Cordless", validate.FIELD_TYPE_BOOLEAN, nil, "true"),
+		validate.NewCustomField("4", "Color", validate.FIELD_TYPE_ENUM, []string{"red", "blue"}, "red"),
+		validate.NewCustomField("5", "Serial", validate.FIELD_TYPE_TEXT, nil, ""),
+	})
+	assert.Empty(t, v.Messages.CustomFieldErrors)
+	assert.False(t, v.HasValidateErrors())
+}
+
+func TestValidateCustomFields_Invalid(t *testing.T) {
+	v := validate.Validate{}
+	v.ValidateCustomFields([]validate.CustomFieldValidate{
+		validate.NewCustomField("1", "Voltage", validate.FIELD_TYPE_NUMBER, nil, "high"),
+		validate.NewCustomField("2", "Bought", validate.FIELD_TYPE_DATE, nil, "31.12.2024"),
+		validate.NewCustomField("3", "Color", validate.FIELD_TYPE_ENUM, []string{"red", "blue"}, "green"),
+	})
+	assert.Equal(t, "Voltage must be a valid number", v.Messages.CustomFieldErrors["1"])
+	assert.Equal(t, "Bought must be a date like 2024-12-31", v.Messages.CustomFieldErrors["2"])
+	assert.Equal(t, "Color must be one of red, blue", v.Messages.CustomFieldErrors["3"])
+	assert.True(t, v.HasValidateErrors())
+}
+
+func TestCustomFieldValue(t *testing.T) {
+	assert.Equal(t, "230", validate.NewCustomField("1", "Voltage", validate.FIELD_TYPE_NUMBER, nil, " 230.0 ").Value())
+	assert.Equal(t, "true", validate.NewCustomField("2", "Cordless", validate.FIELD_TYPE_BOOLEAN, nil, "on").Value())
+	assert.Equal(t, "", validate.NewCustomField("2", "Cordless", validate.FIELD_TYPE_BOOLEAN, nil, "false").Value())
+}
+
+func TestValidateCustomFieldDefinition(t *testing.T) {
+	v := validate.Validate{}
+	v.ValidateCustomFieldDefinition(validate.CustomFieldDefinitionValidate{
+		Name: validate.NewStringField("Serial number"),
+		Type: validate.NewStringField(validate.FIELD_TYPE_TEXT),
+	})
+	assert.False(t, v.HasValidateErrors())
+
+	v = validate.Validate{}
+	v.ValidateCustomFieldDefinition(validate.CustomFieldDefinitionValidate{
+		Name: validate.NewStringField("2nd-hand"),
+		Type: validate.NewStringField(validate.FIELD_TYPE_ENUM),
+	})
+	assert.Contains(t, v.Messages.FieldNameError, "start with a letter")
+	assert.Equal(t, "A choice needs at least one option", v.Messages.FieldOptionsError)
+}
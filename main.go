@@ -1,12 +1,16 @@
 package main
 
 import (
+	"basement/main/internal/auth"
 	"basement/main/internal/database"
 	"basement/main/internal/env"
+	"basement/main/internal/expiry"
 	"basement/main/internal/logg"
 	"basement/main/internal/routes"
 	"basement/main/internal/templates"
 	"net/http"
+	"os"
+	"time"
 )
 
 func main() {
@@ -18,8 +22,17 @@ func main() {
 
 	db := &database.DB{}
 
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(db, os.Args[1:]))
+	}
+
 	db.Connect()
 	defer db.Sql.Close()
+	go db.PurgeExpiredTrashEvery(24 * time.Hour)
+	go db.BackupEvery(time.Hour)
+	expiryChecker := expiry.NewChecker(db)
+	go expiryChecker.CheckEvery(time.Hour)
+	auth.RegisterLoginNotifier(expiryChecker.LoginNotifications)
 
 	routes.RegisterRoutes(db)
 	err = templates.InitTemplates(env.CurrentConfig().TemplatePath())